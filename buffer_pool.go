@@ -6,6 +6,20 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
+// A packetBuffer is a buffer of protocol.MaxPacketBufferSize bytes, taken from a sync.Pool.
+// Buffers are used for packing, sending and receiving packets.
+// Large buffers of protocol.MaxLargePacketBufferSize bytes are taken from a separate pool,
+// and are only used for packets larger than protocol.MaxPacketBufferSize (on paths that support jumbo frames).
+//
+// Ownership rules:
+//   - A buffer returned by getPacketBuffer is owned by the caller.
+//   - On the send path, ownership is passed to the send queue, which releases the buffer once the packet was written.
+//     Retransmissions are built from the frames (tracked by the sent packet handler), never from the buffer,
+//     so a buffer must not be referenced after it was handed to the send queue.
+//   - On the receive path, the buffer is released once all (coalesced) packets in it were processed.
+//     Data that needs to outlive the packet (e.g. STREAM and DATAGRAM frame data) is copied.
+//   - Buffers that need to be kept around indefinitely (e.g. the CONNECTION_CLOSE packet of a closed session)
+//     are simply never released.
 type packetBuffer struct {
 	Data []byte
 
@@ -56,13 +70,17 @@ func (b *packetBuffer) Len() protocol.ByteCount {
 }
 
 func (b *packetBuffer) putBack() {
-	if cap(b.Data) != int(protocol.MaxPacketBufferSize) {
+	switch cap(b.Data) {
+	case int(protocol.MaxPacketBufferSize):
+		bufferPool.Put(b)
+	case int(protocol.MaxLargePacketBufferSize):
+		largeBufferPool.Put(b)
+	default:
 		panic("putPacketBuffer called with packet of wrong size!")
 	}
-	bufferPool.Put(b)
 }
 
-var bufferPool sync.Pool
+var bufferPool, largeBufferPool sync.Pool
 
 func getPacketBuffer() *packetBuffer {
 	buf := bufferPool.Get().(*packetBuffer)
@@ -71,10 +89,30 @@ func getPacketBuffer() *packetBuffer {
 	return buf
 }
 
+func getLargePacketBuffer() *packetBuffer {
+	buf := largeBufferPool.Get().(*packetBuffer)
+	buf.refCount = 1
+	buf.Data = buf.Data[:0]
+	return buf
+}
+
+// getPacketBufferForSize returns a packet buffer that can hold a packet of size bytes.
+func getPacketBufferForSize(size protocol.ByteCount) *packetBuffer {
+	if size > protocol.MaxPacketBufferSize {
+		return getLargePacketBuffer()
+	}
+	return getPacketBuffer()
+}
+
 func init() {
 	bufferPool.New = func() interface{} {
 		return &packetBuffer{
 			Data: make([]byte, 0, protocol.MaxPacketBufferSize),
 		}
 	}
+	largeBufferPool.New = func() interface{} {
+		return &packetBuffer{
+			Data: make([]byte, 0, protocol.MaxLargePacketBufferSize),
+		}
+	}
 }
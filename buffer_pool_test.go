@@ -1,6 +1,9 @@
 package quic
 
 import (
+	"testing"
+
+	"github.com/lucas-clemente/quic-go/integrationtests/tools/israce"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 
 	. "github.com/onsi/ginkgo"
@@ -13,6 +16,17 @@ var _ = Describe("Buffer Pool", func() {
 		Expect(buf.Data).To(HaveCap(int(protocol.MaxPacketBufferSize)))
 	})
 
+	It("returns large buffers", func() {
+		buf := getLargePacketBuffer()
+		Expect(buf.Data).To(HaveCap(int(protocol.MaxLargePacketBufferSize)))
+		buf.Release()
+	})
+
+	It("returns buffers that can hold a packet of the requested size", func() {
+		Expect(getPacketBufferForSize(protocol.MaxPacketBufferSize).Data).To(HaveCap(int(protocol.MaxPacketBufferSize)))
+		Expect(getPacketBufferForSize(protocol.MaxPacketBufferSize + 1).Data).To(HaveCap(int(protocol.MaxLargePacketBufferSize)))
+	})
+
 	It("releases buffers", func() {
 		buf := getPacketBuffer()
 		buf.Release()
@@ -24,6 +38,19 @@ var _ = Describe("Buffer Pool", func() {
 		Expect(buf.Len()).To(BeEquivalentTo(6))
 	})
 
+	It("reuses buffers", func() {
+		if israce.Enabled {
+			Skip("sync.Pool drops buffers randomly when the race detector is enabled")
+		}
+		getPacketBuffer().Release() // make sure there's a buffer in the pool
+		allocs := testing.AllocsPerRun(100, func() {
+			buf := getPacketBuffer()
+			buf.Data = append(buf.Data, []byte("foobar")...)
+			buf.Release()
+		})
+		Expect(allocs).To(BeZero())
+	})
+
 	It("panics if wrong-sized buffers are passed", func() {
 		buf := getPacketBuffer()
 		buf.Data = make([]byte, 10)
@@ -53,3 +80,12 @@ var _ = Describe("Buffer Pool", func() {
 		Expect(func() { buf.Decrement() }).To(Panic())
 	})
 })
+
+func BenchmarkPacketBuffer(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf := getPacketBuffer()
+		buf.Data = buf.Data[:protocol.MaxPacketBufferSize]
+		buf.Release()
+	}
+}
@@ -193,10 +193,18 @@ func dialContext(
 		return nil, err
 	}
 	config = populateClientConfig(config, createdPacketConn)
+	if config.DSCP != 0 {
+		if err := setDSCP(pconn, uint8(config.DSCP)); err != nil {
+			return nil, err
+		}
+	}
 	packetHandlers, err := getMultiplexer().AddConn(pconn, config.ConnectionIDLength, config.StatelessResetKey, config.Tracer)
 	if err != nil {
 		return nil, err
 	}
+	if config.usesLargePackets() {
+		packetHandlers.EnableLargePackets()
+	}
 	c, err := newClient(pconn, remoteAddr, config, tlsConf, host, use0RTT, createdPacketConn)
 	if err != nil {
 		return nil, err
@@ -254,7 +262,7 @@ func newClient(
 		}
 	}
 
-	srcConnID, err := generateConnectionID(config.ConnectionIDLength)
+	srcConnID, err := config.generateConnectionID()
 	if err != nil {
 		return nil, err
 	}
@@ -265,7 +273,7 @@ func newClient(
 	c := &client{
 		srcConnID:         srcConnID,
 		destConnID:        destConnID,
-		conn:              newSendPconn(pconn, remoteAddr),
+		conn:              newSendPconn(pconn, remoteAddr, uint8(config.DSCP)),
 		createdPacketConn: createdPacketConn,
 		use0RTT:           use0RTT,
 		tlsConf:           tlsConf,
@@ -280,9 +288,13 @@ func newClient(
 func (c *client) dial(ctx context.Context) error {
 	c.logger.Infof("Starting new connection to %s (%s -> %s), source connection ID %s, destination connection ID %s, version %s", c.tlsConf.ServerName, c.conn.LocalAddr(), c.conn.RemoteAddr(), c.srcConnID, c.destConnID, c.version)
 
+	var runner sessionRunner = c.packetHandlers
+	if c.srcConnID.Len() == 0 {
+		runner = newZeroLengthConnIDRunner(c.packetHandlers, c.conn.RemoteAddr())
+	}
 	c.session = newClientSession(
 		c.conn,
-		c.packetHandlers,
+		runner,
 		c.destConnID,
 		c.srcConnID,
 		c.config,
@@ -295,7 +307,7 @@ func (c *client) dial(ctx context.Context) error {
 		c.logger,
 		c.version,
 	)
-	c.packetHandlers.Add(c.srcConnID, c.session)
+	runner.Add(c.srcConnID, c.session)
 
 	errorChan := make(chan error, 1)
 	go func() {
@@ -65,7 +65,7 @@ var _ = Describe("Client", func() {
 			srcConnID:  connID,
 			destConnID: connID,
 			version:    protocol.VersionTLS,
-			conn:       newSendPconn(packetConn, addr),
+			conn:       newSendPconn(packetConn, addr, 0),
 			tracer:     tracer,
 			logger:     utils.DefaultLogger,
 		}
@@ -445,7 +445,7 @@ var _ = Describe("Client", func() {
 			Eventually(sessionCreated).Should(BeClosed())
 
 			// check that the connection is not closed
-			Expect(conn.Write([]byte("foobar"))).To(Succeed())
+			Expect(conn.Write([]byte("foobar"), protocol.ECNNon)).To(Succeed())
 
 			manager.EXPECT().Destroy()
 			close(run)
@@ -563,6 +563,50 @@ var _ = Describe("Client", func() {
 			Expect(conf.Versions).To(Equal(config.Versions))
 		})
 
+		It("registers sessions using zero-length connection IDs for the remote address", func() {
+			manager := NewMockPacketHandlerManager(mockCtrl)
+			manager.EXPECT().Add(zeroLengthConnIDKey(addr.String()), gomock.Any())
+			mockMultiplexer.EXPECT().AddConn(packetConn, 0, gomock.Any(), gomock.Any()).Return(manager, nil)
+
+			var runner sessionRunner
+			newClientSession = func(
+				_ sendConn,
+				r sessionRunner,
+				_ protocol.ConnectionID,
+				_ protocol.ConnectionID,
+				_ *Config,
+				_ *tls.Config,
+				_ protocol.PacketNumber,
+				_ bool,
+				_ bool,
+				_ logging.ConnectionTracer,
+				_ uint64,
+				_ utils.Logger,
+				_ protocol.VersionNumber,
+			) quicSession {
+				runner = r
+				sess := NewMockQuicSession(mockCtrl)
+				sess.EXPECT().run()
+				sess.EXPECT().HandshakeComplete().Return(context.Background())
+				return sess
+			}
+			_, err := Dial(
+				packetConn,
+				addr,
+				"localhost:1337",
+				tlsConf,
+				&Config{
+					Versions:              []protocol.VersionNumber{protocol.VersionTLS},
+					ConnectionIDGenerator: &prefixConnIDGenerator{connIDLen: 0},
+				},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(runner).To(Equal(newZeroLengthConnIDRunner(manager, addr)))
+			// the session removes its connection ID when it is closed
+			manager.EXPECT().Remove(zeroLengthConnIDKey(addr.String()))
+			runner.Remove(protocol.ConnectionID{})
+		})
+
 		It("creates a new session after version negotiation", func() {
 			manager := NewMockPacketHandlerManager(mockCtrl)
 			manager.EXPECT().Add(connID, gomock.Any()).Times(2)
@@ -2,6 +2,7 @@ package quic
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -18,6 +19,33 @@ func (c *Config) handshakeTimeout() time.Duration {
 	return utils.MaxDuration(protocol.DefaultHandshakeTimeout, 2*c.HandshakeIdleTimeout)
 }
 
+// usesLargePackets says if packets larger than protocol.MaxPacketBufferSize are sent and received.
+func (c *Config) usesLargePackets() bool {
+	return protocol.ByteCount(c.MaxPathMTUProbeSize) > protocol.MaxPacketBufferSize
+}
+
+// maxAckDelayInclGranularity is the max_ack_delay advertised to the peer.
+// It includes the timer granularity, since the ACK timer might fire late.
+func (c *Config) maxAckDelayInclGranularity() time.Duration {
+	return utils.MinDuration(c.MaxAckDelay+protocol.TimerGranularity, protocol.MaxMaxAckDelay)
+}
+
+// generateConnectionID generates a connection ID using the ConnectionIDGenerator, if set,
+// or a random connection ID otherwise.
+func (c *Config) generateConnectionID() (protocol.ConnectionID, error) {
+	if c.ConnectionIDGenerator == nil {
+		return generateConnectionID(c.ConnectionIDLength)
+	}
+	connID, err := c.ConnectionIDGenerator.GenerateConnectionID()
+	if err != nil {
+		return nil, err
+	}
+	if len(connID) != c.ConnectionIDLength {
+		return nil, fmt.Errorf("ConnectionIDGenerator generated a connection ID of length %d, expected %d", len(connID), c.ConnectionIDLength)
+	}
+	return protocol.ConnectionID(connID), nil
+}
+
 func validateConfig(config *Config) error {
 	if config == nil {
 		return nil
@@ -28,6 +56,42 @@ func validateConfig(config *Config) error {
 	if config.MaxIncomingUniStreams > 1<<60 {
 		return errors.New("invalid value for Config.MaxIncomingUniStreams")
 	}
+	if config.MaxStreamsIncrement > 1<<60 {
+		return errors.New("invalid value for Config.MaxStreamsIncrement")
+	}
+	if config.DatagramSendQueueLen < 0 {
+		return errors.New("invalid value for Config.DatagramSendQueueLen")
+	}
+	if config.DSCP < 0 || config.DSCP > 63 {
+		return errors.New("invalid value for Config.DSCP")
+	}
+	if config.MaxAckDelay < 0 || config.MaxAckDelay > protocol.MaxMaxAckDelay {
+		return errors.New("invalid value for Config.MaxAckDelay")
+	}
+	if config.PTOMultiplier != 0 && config.PTOMultiplier < 1 {
+		return errors.New("invalid value for Config.PTOMultiplier")
+	}
+	if config.PacketReorderingThreshold != 0 && protocol.PacketNumber(config.PacketReorderingThreshold) < protocol.DefaultPacketReorderingThreshold {
+		return errors.New("invalid value for Config.PacketReorderingThreshold")
+	}
+	if config.MaxPathMTUProbeSize != 0 && (config.MaxPathMTUProbeSize < protocol.MinInitialPacketSize || protocol.ByteCount(config.MaxPathMTUProbeSize) > protocol.MaxLargePacketBufferSize) {
+		return errors.New("invalid value for Config.MaxPathMTUProbeSize")
+	}
+	if config.ConnectionIDGenerator != nil {
+		l := config.ConnectionIDGenerator.ConnectionIDLen()
+		if l < 0 || (l > 0 && l < 4) || l > protocol.MaxConnIDLen {
+			return errors.New("invalid value for Config.ConnectionIDGenerator.ConnectionIDLen")
+		}
+		if config.ConnectionIDLength != 0 && config.ConnectionIDLength != l {
+			return errors.New("Config.ConnectionIDLength doesn't match the length of the Config.ConnectionIDGenerator")
+		}
+	}
+	if config.Congestion.CubicBeta < 0 || config.Congestion.CubicBeta >= 1 {
+		return errors.New("invalid value for Config.Congestion.CubicBeta")
+	}
+	if config.Congestion.RenoBeta < 0 || config.Congestion.RenoBeta >= 1 {
+		return errors.New("invalid value for Config.Congestion.RenoBeta")
+	}
 	return nil
 }
 
@@ -35,7 +99,7 @@ func validateConfig(config *Config) error {
 // it may be called with nil
 func populateServerConfig(config *Config) *Config {
 	config = populateConfig(config)
-	if config.ConnectionIDLength == 0 {
+	if config.ConnectionIDLength == 0 && config.ConnectionIDGenerator == nil {
 		config.ConnectionIDLength = protocol.DefaultConnectionIDLength
 	}
 	if config.AcceptToken == nil {
@@ -48,7 +112,7 @@ func populateServerConfig(config *Config) *Config {
 // it may be called with nil
 func populateClientConfig(config *Config, createdPacketConn bool) *Config {
 	config = populateConfig(config)
-	if config.ConnectionIDLength == 0 && !createdPacketConn {
+	if config.ConnectionIDLength == 0 && config.ConnectionIDGenerator == nil && !createdPacketConn {
 		config.ConnectionIDLength = protocol.DefaultConnectionIDLength
 	}
 	return config
@@ -98,26 +162,77 @@ func populateConfig(config *Config) *Config {
 	} else if maxIncomingUniStreams < 0 {
 		maxIncomingUniStreams = 0
 	}
+	maxAckDelay := config.MaxAckDelay
+	if maxAckDelay == 0 {
+		maxAckDelay = protocol.MaxAckDelay
+	}
+	ptoMultiplier := config.PTOMultiplier
+	if ptoMultiplier == 0 {
+		ptoMultiplier = 1
+	}
+	packetReorderingThreshold := config.PacketReorderingThreshold
+	if packetReorderingThreshold == 0 {
+		packetReorderingThreshold = uint32(protocol.DefaultPacketReorderingThreshold)
+	}
+	connIDLength := config.ConnectionIDLength
+	if config.ConnectionIDGenerator != nil {
+		connIDLength = config.ConnectionIDGenerator.ConnectionIDLen()
+	}
+	initialPacketSize := config.InitialPacketSize
+	if initialPacketSize != 0 && initialPacketSize < protocol.MinInitialPacketSize {
+		initialPacketSize = protocol.MinInitialPacketSize
+	}
+	if initialPacketSize > uint16(protocol.MaxPacketBufferSize) {
+		initialPacketSize = uint16(protocol.MaxPacketBufferSize)
+	}
+	congestionOptions := config.Congestion
+	if config.DisablePacing {
+		congestionOptions.DisablePacing = true
+	}
 
 	return &Config{
 		Versions:                         versions,
 		HandshakeIdleTimeout:             handshakeIdleTimeout,
 		MaxIdleTimeout:                   idleTimeout,
 		AcceptToken:                      config.AcceptToken,
+		RequireAddressValidation:         config.RequireAddressValidation,
+		TokenProtector:                   config.TokenProtector,
+		Allow0RTT:                        config.Allow0RTT,
 		KeepAlive:                        config.KeepAlive,
+		KeepAlivePeriod:                  config.KeepAlivePeriod,
+		ApplicationIdleTimeout:           config.ApplicationIdleTimeout,
+		OnApplicationIdle:                config.OnApplicationIdle,
 		InitialStreamReceiveWindow:       initialStreamReceiveWindow,
 		MaxStreamReceiveWindow:           maxStreamReceiveWindow,
 		InitialConnectionReceiveWindow:   initialConnectionReceiveWindow,
 		MaxConnectionReceiveWindow:       maxConnectionReceiveWindow,
 		MaxIncomingStreams:               maxIncomingStreams,
 		MaxIncomingUniStreams:            maxIncomingUniStreams,
-		ConnectionIDLength:               config.ConnectionIDLength,
+		MaxStreamsIncrement:              config.MaxStreamsIncrement,
+		ConnectionIDLength:               connIDLength,
+		ConnectionIDGenerator:            config.ConnectionIDGenerator,
 		StatelessResetKey:                config.StatelessResetKey,
 		TokenStore:                       config.TokenStore,
+		ClientSessionCache:               config.ClientSessionCache,
 		EnableDatagrams:                  config.EnableDatagrams,
+		DatagramSendQueueLen:             config.DatagramSendQueueLen,
+		EnableAckFrequency:               config.EnableAckFrequency,
+		MaxAckDelay:                      maxAckDelay,
 		DisablePathMTUDiscovery:          config.DisablePathMTUDiscovery,
+		MaxPathMTUProbeSize:              config.MaxPathMTUProbeSize,
+		InitialPacketSize:                initialPacketSize,
+		Disable1RTTCoalescing:            config.Disable1RTTCoalescing,
 		DisableVersionNegotiationPackets: config.DisableVersionNegotiationPackets,
-		Congestion:                       config.Congestion,
+		EnableActiveMigration:            config.EnableActiveMigration,
+		DisableSpinBit:                   config.DisableSpinBit,
+		DisablePacing:                    config.DisablePacing,
+		DisableGSO:                       config.DisableGSO,
+		PTOMultiplier:                    ptoMultiplier,
+		PacketReorderingThreshold:        packetReorderingThreshold,
+		EnableECN:                        config.EnableECN,
+		DSCP:                             config.DSCP,
+		Congestion:                       congestionOptions,
+		NewCongestionControl:             config.NewCongestionControl,
 		Tracer:                           config.Tracer,
 	}
 }
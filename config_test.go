@@ -1,18 +1,52 @@
 package quic
 
 import (
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"net"
 	"reflect"
 	"time"
 
+	"github.com/lucas-clemente/quic-go/congestion"
 	mocklogging "github.com/lucas-clemente/quic-go/internal/mocks/logging"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/logging"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
+type prefixConnIDGenerator struct {
+	prefix    byte
+	connIDLen int
+	err       error
+}
+
+func (g *prefixConnIDGenerator) GenerateConnectionID() ([]byte, error) {
+	if g.err != nil {
+		return nil, g.err
+	}
+	b := make([]byte, g.connIDLen)
+	if len(b) == 0 {
+		return b, nil
+	}
+	b[0] = g.prefix
+	_, err := rand.Read(b[1:])
+	return b, err
+}
+
+func (g *prefixConnIDGenerator) ConnectionIDLen() int { return g.connIDLen }
+
+type mapClientSessionCache map[string][]byte
+
+func (c mapClientSessionCache) Get(key string) ([]byte, bool) {
+	session, ok := c[key]
+	return session, ok
+}
+
+func (c mapClientSessionCache) Put(key string, session []byte) { c[key] = session }
+
 var _ = Describe("Config", func() {
 	Context("validating", func() {
 		It("validates a nil config", func() {
@@ -30,6 +64,66 @@ var _ = Describe("Config", func() {
 		It("errors on too large values for MaxIncomingUniStreams", func() {
 			Expect(validateConfig(&Config{MaxIncomingUniStreams: 1<<60 + 1})).To(MatchError("invalid value for Config.MaxIncomingUniStreams"))
 		})
+
+		It("errors on too large values for MaxStreamsIncrement", func() {
+			Expect(validateConfig(&Config{MaxStreamsIncrement: 1<<60 + 1})).To(MatchError("invalid value for Config.MaxStreamsIncrement"))
+		})
+
+		It("errors on negative datagram send queue lengths", func() {
+			Expect(validateConfig(&Config{DatagramSendQueueLen: 10})).To(Succeed())
+			Expect(validateConfig(&Config{DatagramSendQueueLen: -1})).To(MatchError("invalid value for Config.DatagramSendQueueLen"))
+		})
+
+		It("errors on invalid DSCP values", func() {
+			Expect(validateConfig(&Config{DSCP: 63})).To(Succeed())
+			Expect(validateConfig(&Config{DSCP: 64})).To(MatchError("invalid value for Config.DSCP"))
+			Expect(validateConfig(&Config{DSCP: -1})).To(MatchError("invalid value for Config.DSCP"))
+		})
+
+		It("errors on invalid max ack delays", func() {
+			Expect(validateConfig(&Config{MaxAckDelay: 100 * time.Millisecond})).To(Succeed())
+			Expect(validateConfig(&Config{MaxAckDelay: -time.Millisecond})).To(MatchError("invalid value for Config.MaxAckDelay"))
+			Expect(validateConfig(&Config{MaxAckDelay: 1 << 14 * time.Millisecond})).To(MatchError("invalid value for Config.MaxAckDelay"))
+		})
+
+		It("errors on PTO multipliers smaller than 1", func() {
+			Expect(validateConfig(&Config{PTOMultiplier: 1.5})).To(Succeed())
+			Expect(validateConfig(&Config{PTOMultiplier: 0.5})).To(MatchError("invalid value for Config.PTOMultiplier"))
+		})
+
+		It("errors on packet reordering thresholds smaller than 3", func() {
+			Expect(validateConfig(&Config{PacketReorderingThreshold: 3})).To(Succeed())
+			Expect(validateConfig(&Config{PacketReorderingThreshold: 10})).To(Succeed())
+			Expect(validateConfig(&Config{PacketReorderingThreshold: 2})).To(MatchError("invalid value for Config.PacketReorderingThreshold"))
+		})
+
+		It("errors on MTU probe sizes smaller than 1200 or larger than the largest packet buffer", func() {
+			Expect(validateConfig(&Config{MaxPathMTUProbeSize: 1200})).To(Succeed())
+			Expect(validateConfig(&Config{MaxPathMTUProbeSize: 1199})).To(MatchError("invalid value for Config.MaxPathMTUProbeSize"))
+			Expect(validateConfig(&Config{MaxPathMTUProbeSize: 8952})).To(Succeed())
+			Expect(validateConfig(&Config{MaxPathMTUProbeSize: 8953})).To(MatchError("invalid value for Config.MaxPathMTUProbeSize"))
+		})
+
+		It("validates the length of the connection ID generator", func() {
+			Expect(validateConfig(&Config{ConnectionIDGenerator: &prefixConnIDGenerator{connIDLen: 0}})).To(Succeed())
+			Expect(validateConfig(&Config{ConnectionIDGenerator: &prefixConnIDGenerator{connIDLen: 20}})).To(Succeed())
+			Expect(validateConfig(&Config{ConnectionIDGenerator: &prefixConnIDGenerator{connIDLen: 3}})).To(MatchError("invalid value for Config.ConnectionIDGenerator.ConnectionIDLen"))
+			Expect(validateConfig(&Config{ConnectionIDGenerator: &prefixConnIDGenerator{connIDLen: 21}})).To(MatchError("invalid value for Config.ConnectionIDGenerator.ConnectionIDLen"))
+		})
+
+		It("errors if the connection ID length doesn't match the connection ID generator", func() {
+			Expect(validateConfig(&Config{ConnectionIDLength: 8, ConnectionIDGenerator: &prefixConnIDGenerator{connIDLen: 8}})).To(Succeed())
+			Expect(validateConfig(&Config{ConnectionIDLength: 7, ConnectionIDGenerator: &prefixConnIDGenerator{connIDLen: 8}})).To(MatchError("Config.ConnectionIDLength doesn't match the length of the Config.ConnectionIDGenerator"))
+		})
+
+		It("errors on CUBIC beta values outside of (0,1)", func() {
+			Expect(validateConfig(&Config{Congestion: congestion.CongestionOptions{CubicBeta: 0.5}})).To(Succeed())
+			Expect(validateConfig(&Config{Congestion: congestion.CongestionOptions{CubicBeta: 1}})).To(MatchError("invalid value for Config.Congestion.CubicBeta"))
+			Expect(validateConfig(&Config{Congestion: congestion.CongestionOptions{CubicBeta: -0.1}})).To(MatchError("invalid value for Config.Congestion.CubicBeta"))
+			Expect(validateConfig(&Config{Congestion: congestion.CongestionOptions{RenoBeta: 0.7}})).To(Succeed())
+			Expect(validateConfig(&Config{Congestion: congestion.CongestionOptions{RenoBeta: 1}})).To(MatchError("invalid value for Config.Congestion.RenoBeta"))
+			Expect(validateConfig(&Config{Congestion: congestion.CongestionOptions{RenoBeta: -0.1}})).To(MatchError("invalid value for Config.Congestion.RenoBeta"))
+		})
 	})
 
 	configWithNonZeroNonFunctionFields := func() *Config {
@@ -45,18 +139,26 @@ var _ = Describe("Config", func() {
 			}
 
 			switch fn := typ.Field(i).Name; fn {
-			case "AcceptToken", "GetLogWriter":
+			case "AcceptToken", "RequireAddressValidation", "Allow0RTT", "GetLogWriter", "NewCongestionControl", "OnApplicationIdle":
 				// Can't compare functions.
 			case "Versions":
 				f.Set(reflect.ValueOf([]VersionNumber{1, 2, 3}))
 			case "ConnectionIDLength":
 				f.Set(reflect.ValueOf(8))
+			case "ConnectionIDGenerator":
+				f.Set(reflect.ValueOf(&prefixConnIDGenerator{connIDLen: 8}))
 			case "HandshakeIdleTimeout":
 				f.Set(reflect.ValueOf(time.Second))
 			case "MaxIdleTimeout":
 				f.Set(reflect.ValueOf(time.Hour))
 			case "TokenStore":
 				f.Set(reflect.ValueOf(NewLRUTokenStore(2, 3)))
+			case "ClientSessionCache":
+				f.Set(reflect.ValueOf(mapClientSessionCache{"foo": []byte("bar")}))
+			case "TokenProtector":
+				tp, err := NewHMACTokenProtector(make([]byte, 32))
+				Expect(err).ToNot(HaveOccurred())
+				f.Set(reflect.ValueOf(tp))
 			case "InitialStreamReceiveWindow":
 				f.Set(reflect.ValueOf(uint64(1234)))
 			case "MaxStreamReceiveWindow":
@@ -69,16 +171,52 @@ var _ = Describe("Config", func() {
 				f.Set(reflect.ValueOf(int64(11)))
 			case "MaxIncomingUniStreams":
 				f.Set(reflect.ValueOf(int64(12)))
+			case "MaxStreamsIncrement":
+				f.Set(reflect.ValueOf(int64(6)))
 			case "StatelessResetKey":
 				f.Set(reflect.ValueOf([]byte{1, 2, 3, 4}))
 			case "KeepAlive":
 				f.Set(reflect.ValueOf(true))
+			case "KeepAlivePeriod":
+				f.Set(reflect.ValueOf(15 * time.Second))
+			case "ApplicationIdleTimeout":
+				f.Set(reflect.ValueOf(time.Minute))
 			case "EnableDatagrams":
 				f.Set(reflect.ValueOf(true))
+			case "DatagramSendQueueLen":
+				f.Set(reflect.ValueOf(32))
+			case "EnableAckFrequency":
+				f.Set(reflect.ValueOf(true))
+			case "MaxAckDelay":
+				f.Set(reflect.ValueOf(50 * time.Millisecond))
 			case "DisableVersionNegotiationPackets":
 				f.Set(reflect.ValueOf(true))
+			case "EnableActiveMigration":
+				f.Set(reflect.ValueOf(true))
+			case "DisableSpinBit":
+				f.Set(reflect.ValueOf(true))
 			case "DisablePathMTUDiscovery":
 				f.Set(reflect.ValueOf(true))
+			case "MaxPathMTUProbeSize":
+				f.Set(reflect.ValueOf(uint16(1400)))
+			case "InitialPacketSize":
+				f.Set(reflect.ValueOf(uint16(1300)))
+			case "Disable1RTTCoalescing":
+				f.Set(reflect.ValueOf(true))
+			case "DisablePacing":
+				f.Set(reflect.ValueOf(true))
+			case "DisableGSO":
+				f.Set(reflect.ValueOf(true))
+			case "PTOMultiplier":
+				f.Set(reflect.ValueOf(2.5))
+			case "PacketReorderingThreshold":
+				f.Set(reflect.ValueOf(uint32(5)))
+			case "EnableECN":
+				f.Set(reflect.ValueOf(true))
+			case "DSCP":
+				f.Set(reflect.ValueOf(46))
+			case "Congestion":
+				f.Set(reflect.ValueOf(congestion.CongestionOptions{ControlType: congestion.BbrControlType, Hystart: congestion.HystartTypePlusPlus, DisablePacing: true}))
 			case "Tracer":
 				f.Set(reflect.ValueOf(mocklogging.NewMockTracer(mockCtrl)))
 			default:
@@ -139,6 +277,47 @@ var _ = Describe("Config", func() {
 			Expect(calledAcceptToken).To(BeTrue())
 		})
 
+		It("populates the application idle callback", func() {
+			var called bool
+			c1 := &Config{
+				ApplicationIdleTimeout: time.Minute,
+				OnApplicationIdle:      func(Session) { called = true },
+			}
+			c2 := populateConfig(c1)
+			Expect(c2.ApplicationIdleTimeout).To(Equal(time.Minute))
+			c2.OnApplicationIdle(nil)
+			Expect(called).To(BeTrue())
+		})
+
+		It("populates the address validation callback", func() {
+			var called bool
+			c1 := &Config{RequireAddressValidation: func(net.Addr) bool { called = true; return true }}
+			c2 := populateConfig(c1)
+			Expect(c2.RequireAddressValidation(&net.UDPAddr{})).To(BeTrue())
+			Expect(called).To(BeTrue())
+		})
+
+		It("populates the 0-RTT callback", func() {
+			var called bool
+			c1 := &Config{Allow0RTT: func(net.Addr) bool { called = true; return false }}
+			c2 := populateConfig(c1)
+			Expect(c2.Allow0RTT(&net.UDPAddr{})).To(BeFalse())
+			Expect(called).To(BeTrue())
+		})
+
+		It("populates the congestion control factory", func() {
+			var called bool
+			c1 := &Config{
+				NewCongestionControl: func(*congestion.RTTStats, congestion.ByteCount, logging.ConnectionTracer) congestion.SendAlgorithmWithDebugInfos {
+					called = true
+					return nil
+				},
+			}
+			c2 := populateConfig(c1)
+			c2.NewCongestionControl(nil, 0, nil)
+			Expect(called).To(BeTrue())
+		})
+
 		It("copies non-function fields", func() {
 			c := configWithNonZeroNonFunctionFields()
 			Expect(populateConfig(c)).To(Equal(c))
@@ -155,7 +334,31 @@ var _ = Describe("Config", func() {
 			Expect(c.MaxIncomingStreams).To(BeEquivalentTo(protocol.DefaultMaxIncomingStreams))
 			Expect(c.MaxIncomingUniStreams).To(BeEquivalentTo(protocol.DefaultMaxIncomingUniStreams))
 			Expect(c.DisableVersionNegotiationPackets).To(BeFalse())
+			Expect(c.EnableActiveMigration).To(BeFalse())
+			Expect(c.DisableSpinBit).To(BeFalse())
 			Expect(c.DisablePathMTUDiscovery).To(BeFalse())
+			Expect(c.MaxAckDelay).To(Equal(25 * time.Millisecond))
+			Expect(c.PTOMultiplier).To(Equal(1.0))
+			Expect(c.PacketReorderingThreshold).To(BeEquivalentTo(protocol.DefaultPacketReorderingThreshold))
+		})
+
+		It("advertises the max ack delay including the timer granularity", func() {
+			Expect(populateConfig(&Config{}).maxAckDelayInclGranularity()).To(Equal(26 * time.Millisecond))
+			Expect(populateConfig(&Config{MaxAckDelay: 100 * time.Millisecond}).maxAckDelayInclGranularity()).To(Equal(101 * time.Millisecond))
+			Expect(populateConfig(&Config{MaxAckDelay: protocol.MaxMaxAckDelay}).maxAckDelayInclGranularity()).To(Equal(protocol.MaxMaxAckDelay))
+		})
+
+		It("disables pacing in the congestion options", func() {
+			c := populateConfig(&Config{DisablePacing: true})
+			Expect(c.DisablePacing).To(BeTrue())
+			Expect(c.Congestion.DisablePacing).To(BeTrue())
+		})
+
+		It("clamps the initial packet size", func() {
+			Expect(populateConfig(&Config{}).InitialPacketSize).To(BeZero())
+			Expect(populateConfig(&Config{InitialPacketSize: 1000}).InitialPacketSize).To(BeEquivalentTo(protocol.MinInitialPacketSize))
+			Expect(populateConfig(&Config{InitialPacketSize: 1300}).InitialPacketSize).To(BeEquivalentTo(1300))
+			Expect(populateConfig(&Config{InitialPacketSize: 9000}).InitialPacketSize).To(BeEquivalentTo(protocol.MaxPacketBufferSize))
 		})
 
 		It("populates empty fields with default values, for the server", func() {
@@ -173,5 +376,47 @@ var _ = Describe("Config", func() {
 			c := populateClientConfig(&Config{}, true)
 			Expect(c.ConnectionIDLength).To(BeZero())
 		})
+
+		It("uses the length of the connection ID generator", func() {
+			c := populateServerConfig(&Config{ConnectionIDGenerator: &prefixConnIDGenerator{connIDLen: 12}})
+			Expect(c.ConnectionIDLength).To(Equal(12))
+			c = populateClientConfig(&Config{ConnectionIDGenerator: &prefixConnIDGenerator{connIDLen: 0}}, false)
+			Expect(c.ConnectionIDLength).To(BeZero())
+		})
+	})
+
+	Context("generating connection IDs", func() {
+		It("generates random connection IDs", func() {
+			c := populateServerConfig(&Config{ConnectionIDLength: 7})
+			connID1, err := c.generateConnectionID()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connID1).To(HaveLen(7))
+			connID2, err := c.generateConnectionID()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connID2).ToNot(Equal(connID1))
+		})
+
+		It("uses the connection ID generator", func() {
+			c := populateServerConfig(&Config{ConnectionIDGenerator: &prefixConnIDGenerator{prefix: 0x42, connIDLen: 10}})
+			connID, err := c.generateConnectionID()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connID).To(HaveLen(10))
+			Expect(connID[0]).To(Equal(byte(0x42)))
+		})
+
+		It("returns the error of the connection ID generator", func() {
+			testErr := errors.New("test error")
+			c := populateServerConfig(&Config{ConnectionIDGenerator: &prefixConnIDGenerator{connIDLen: 10, err: testErr}})
+			_, err := c.generateConnectionID()
+			Expect(err).To(MatchError(testErr))
+		})
+
+		It("errors if the connection ID generator returns a connection ID of the wrong length", func() {
+			gen := &prefixConnIDGenerator{connIDLen: 10}
+			c := populateServerConfig(&Config{ConnectionIDGenerator: gen})
+			gen.connIDLen = 9
+			_, err := c.generateConnectionID()
+			Expect(err).To(MatchError("ConnectionIDGenerator generated a connection ID of length 9, expected 10"))
+		})
 	})
 })
@@ -0,0 +1,86 @@
+// Package congestion defines the types needed to plug a custom congestion controller into quic-go,
+// using Config.NewCongestionControl.
+// New hooks are added as optional interfaces, so that existing congestion controllers keep compiling.
+package congestion
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/congestion"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+type (
+	// A ByteCount is used to count bytes.
+	ByteCount = protocol.ByteCount
+	// The PacketNumber is the packet number of a packet.
+	PacketNumber = protocol.PacketNumber
+	// The RTTStats contain the RTT estimates of the connection.
+	RTTStats = utils.RTTStats
+	// Bandwidth is a bandwidth, in bits per second.
+	Bandwidth = congestion.Bandwidth
+
+	// A CongestionControlType is one of the built-in congestion control algorithms.
+	CongestionControlType = congestion.CongestionControlType
+	// A HystartControlType is a hybrid slow start variant.
+	HystartControlType = congestion.HystartControlType
+	// The CongestionOptions select and tune one of the built-in congestion controllers.
+	CongestionOptions = congestion.CongestionOptions
+	// The HystartOptions are the tuning parameters of the hybrid slow start.
+	HystartOptions = congestion.HystartOptions
+
+	// A SendAlgorithm performs congestion control.
+	SendAlgorithm = congestion.SendAlgorithm
+	// A SendAlgorithmWithDebugInfos is a SendAlgorithm that exposes some debug infos.
+	// This is the interface a congestion controller returned by Config.NewCongestionControl has to implement.
+	SendAlgorithmWithDebugInfos = congestion.SendAlgorithmWithDebugInfos
+
+	// A CongestionEventHandler reacts to ECN congestion signals.
+	CongestionEventHandler = congestion.CongestionEventHandler
+	// A SpuriousLossHandler reacts to spurious losses.
+	SpuriousLossHandler = congestion.SpuriousLossHandler
+	// An AppLimitedHandler is notified when the connection is application-limited.
+	AppLimitedHandler = congestion.AppLimitedHandler
+	// A BandwidthEstimator estimates the bandwidth of the path.
+	BandwidthEstimator = congestion.BandwidthEstimator
+	// A ControlTypeReporter reports which algorithm it implements.
+	ControlTypeReporter = congestion.ControlTypeReporter
+)
+
+const (
+	// BitsPerSecond is 1 bit per second
+	BitsPerSecond Bandwidth = congestion.BitsPerSecond
+	// BytesPerSecond is 1 byte per second
+	BytesPerSecond Bandwidth = congestion.BytesPerSecond
+)
+
+const (
+	// NewRenoControlType is NewReno
+	NewRenoControlType CongestionControlType = congestion.NewRenoControlType
+	// CubicControlType is CUBIC
+	CubicControlType CongestionControlType = congestion.CubicControlType
+	// BbrControlType is BBR
+	BbrControlType CongestionControlType = congestion.BbrControlType
+	// Bbr2ControlType is BBRv2
+	Bbr2ControlType CongestionControlType = congestion.Bbr2ControlType
+)
+
+const (
+	// HystartTypeStandard is the standard hybrid slow start
+	HystartTypeStandard HystartControlType = congestion.HystartTypeStandard
+	// HystartTypePlusPlus is Hystart++
+	HystartTypePlusPlus HystartControlType = congestion.HystartTypePlusPlus
+	// HystartTypeNone disables the hybrid slow start
+	HystartTypeNone HystartControlType = congestion.HystartTypeNone
+)
+
+// ParseCongestionControl parses the name of a congestion control algorithm.
+// It accepts the names returned by CongestionControlType.String, ignoring case.
+func ParseCongestionControl(s string) (CongestionControlType, error) {
+	return congestion.ParseCongestionControl(s)
+}
+
+// ParseHystart parses the name of a hybrid slow start variant.
+// It accepts the names returned by HystartControlType.String (and "++" for Hystart++), ignoring case.
+func ParseHystart(s string) (HystartControlType, error) {
+	return congestion.ParseHystart(s)
+}
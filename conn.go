@@ -3,10 +3,10 @@ package quic
 import (
 	"io"
 	"net"
+	"sync/atomic"
 	"syscall"
 	"time"
 
-	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
@@ -14,9 +14,44 @@ type connection interface {
 	ReadPacket() (*receivedPacket, error)
 	WritePacket(b []byte, addr net.Addr, oob []byte) (int, error)
 	LocalAddr() net.Addr
+	// EnableLargePackets makes ReadPacket read packets of up to protocol.MaxLargePacketBufferSize bytes.
+	// By default, packets larger than protocol.MaxPacketBufferSize are truncated.
+	EnableLargePackets()
 	io.Closer
 }
 
+// largePackets records if large packets were enabled on a connection.
+// It is safe for concurrent use.
+type largePackets struct {
+	enabled uint32
+}
+
+func (l *largePackets) EnableLargePackets() {
+	atomic.StoreUint32(&l.enabled, 1)
+}
+
+func (l *largePackets) largePacketsEnabled() bool {
+	return atomic.LoadUint32(&l.enabled) == 1
+}
+
+// getReadBuffer returns a packet buffer of its full length, to read a packet into.
+func (l *largePackets) getReadBuffer() *packetBuffer {
+	var buffer *packetBuffer
+	if l.largePacketsEnabled() {
+		buffer = getLargePacketBuffer()
+	} else {
+		buffer = getPacketBuffer()
+	}
+	buffer.Data = buffer.Data[:cap(buffer.Data)]
+	return buffer
+}
+
+// A gsoCapableConn is a connection that might be able to send multiple packets in a single syscall,
+// using UDP generic segmentation offload (GSO).
+type gsoCapableConn interface {
+	supportsGSO() bool
+}
+
 // If the PacketConn passed to Dial or Listen satisfies this interface, quic-go will read the ECN bits from the IP header.
 // In this case, ReadMsgUDP() will be used instead of ReadFrom() to read packets.
 type OOBCapablePacketConn interface {
@@ -39,15 +74,15 @@ func wrapConn(pc net.PacketConn) (connection, error) {
 
 type basicConn struct {
 	net.PacketConn
+	largePackets
 }
 
 var _ connection = &basicConn{}
 
 func (c *basicConn) ReadPacket() (*receivedPacket, error) {
-	buffer := getPacketBuffer()
-	// The packet size should not exceed protocol.MaxPacketBufferSize bytes
+	// The packet size should not exceed the size of the buffer.
 	// If it does, we only read a truncated packet, which will then end up undecryptable
-	buffer.Data = buffer.Data[:protocol.MaxPacketBufferSize]
+	buffer := c.getReadBuffer()
 	n, addr, err := c.PacketConn.ReadFrom(buffer.Data)
 	if err != nil {
 		return nil, err
@@ -3,7 +3,11 @@
 
 package quic
 
-import "net"
+import (
+	"net"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
 
 func newConn(c net.PacketConn) (connection, error) {
 	return &basicConn{PacketConn: c}, nil
@@ -14,3 +18,7 @@ func inspectReadBuffer(interface{}) (int, error) {
 }
 
 func (i *packetInfo) OOB() []byte { return nil }
+
+func appendECNControlMessage(b []byte, _ protocol.ECN, _ uint8, _ net.Addr) []byte { return b }
+
+func setDSCP(net.PacketConn, uint8) error { return nil }
@@ -3,7 +3,15 @@
 
 package quic
 
-import "golang.org/x/sys/unix"
+import (
+	"errors"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// the size of the data of the IP_TOS control message used to set the ECN bits when sending
+const ecnIPv4DataLen = 1
 
 const msgTypeIPTOS = unix.IP_RECVTOS
 
@@ -18,3 +26,7 @@ const (
 )
 
 const batchSize = 8
+
+func setGRO(syscall.RawConn, bool) error { return errors.New("GRO not supported") }
+
+func parseGROSegmentSize(unix.SocketControlMessage) (int, bool) { return 0, false }
@@ -3,7 +3,17 @@
 
 package quic
 
-import "golang.org/x/sys/unix"
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// the size of the data of the IP_TOS control message used to set the ECN bits when sending
+const ecnIPv4DataLen = 1
 
 const msgTypeIPTOS = unix.IP_TOS
 
@@ -18,3 +28,68 @@ const (
 )
 
 const batchSize = 8 // needs to smaller than MaxUint8 (otherwise the type of oobConn.readPos has to be changed)
+
+// UDP_SEGMENT, see include/uapi/linux/udp.h
+const udpSegment = 103
+
+// UDP_GRO, see include/uapi/linux/udp.h
+const udpGRO = 104
+
+// setGRO enables or disables UDP generic receive offload (GRO) on the socket.
+// With GRO, the kernel may coalesce multiple packets from the same sender, and return them in a single read.
+func setGRO(conn syscall.RawConn, enable bool) error {
+	var val int
+	if enable {
+		val = 1
+	}
+	var serr error
+	if err := conn.Control(func(fd uintptr) {
+		serr = unix.SetsockoptInt(int(fd), unix.IPPROTO_UDP, udpGRO, val)
+	}); err != nil {
+		return err
+	}
+	return serr
+}
+
+// parseGROSegmentSize parses the UDP_GRO control message, which contains the size of the coalesced packets.
+func parseGROSegmentSize(ctrlMsg unix.SocketControlMessage) (int, bool) {
+	if ctrlMsg.Header.Level != unix.IPPROTO_UDP || ctrlMsg.Header.Type != udpGRO || len(ctrlMsg.Data) < 4 {
+		return 0, false
+	}
+	// the segment size is an int (in host byte order)
+	return int(*(*int32)(unsafe.Pointer(&ctrlMsg.Data[0]))), true
+}
+
+// isGSOSupported checks if the kernel supports UDP generic segmentation offload (GSO) on this socket.
+func isGSOSupported(conn syscall.RawConn) bool {
+	var serr error
+	if err := conn.Control(func(fd uintptr) {
+		_, serr = unix.GetsockoptInt(int(fd), unix.IPPROTO_UDP, udpSegment)
+	}); err != nil {
+		return false
+	}
+	return serr == nil
+}
+
+// appendUDPSegmentSizeMsg appends a control message setting the GSO segment size.
+func appendUDPSegmentSizeMsg(b []byte, size uint16) []byte {
+	const dataLen = 2 // the segment size is a uint16
+	startLen := len(b)
+	b = append(b, make([]byte, unix.CmsgSpace(dataLen))...)
+	h := (*unix.Cmsghdr)(unsafe.Pointer(&b[startLen]))
+	h.Level = unix.IPPROTO_UDP
+	h.Type = udpSegment
+	h.SetLen(unix.CmsgLen(dataLen))
+	*(*uint16)(unsafe.Pointer(&b[startLen+unix.CmsgSpace(0)])) = size
+	return b
+}
+
+// isGSOError checks if sending failed because the network interface doesn't support GSO.
+// The kernel returns EIO if checksum offloading is not available.
+func isGSOError(err error) bool {
+	var serr *os.SyscallError
+	if errors.As(err, &serr) {
+		return serr.Err == unix.EIO
+	}
+	return false
+}
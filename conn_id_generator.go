@@ -1,6 +1,7 @@
 package quic
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -10,12 +11,14 @@ import (
 )
 
 type connIDGenerator struct {
-	connIDLen  int
-	highestSeq uint64
+	connIDLen     int
+	highestSeq    uint64
+	retirePriorTo uint64
 
 	activeSrcConnIDs        map[uint64]protocol.ConnectionID
 	initialClientDestConnID protocol.ConnectionID
 
+	generateConnectionID   func() (protocol.ConnectionID, error)
 	addConnectionID        func(protocol.ConnectionID)
 	getStatelessResetToken func(protocol.ConnectionID) protocol.StatelessResetToken
 	removeConnectionID     func(protocol.ConnectionID)
@@ -29,6 +32,7 @@ type connIDGenerator struct {
 func newConnIDGenerator(
 	initialConnectionID protocol.ConnectionID,
 	initialClientDestConnID protocol.ConnectionID, // nil for the client
+	generateConnectionID func() (protocol.ConnectionID, error),
 	addConnectionID func(protocol.ConnectionID),
 	getStatelessResetToken func(protocol.ConnectionID) protocol.StatelessResetToken,
 	removeConnectionID func(protocol.ConnectionID),
@@ -40,6 +44,7 @@ func newConnIDGenerator(
 	m := &connIDGenerator{
 		connIDLen:              initialConnectionID.Len(),
 		activeSrcConnIDs:       make(map[uint64]protocol.ConnectionID),
+		generateConnectionID:   generateConnectionID,
 		addConnectionID:        addConnectionID,
 		getStatelessResetToken: getStatelessResetToken,
 		removeConnectionID:     removeConnectionID,
@@ -99,7 +104,7 @@ func (m *connIDGenerator) Retire(seq uint64, sentWithDestConnID protocol.Connect
 }
 
 func (m *connIDGenerator) issueNewConnID() error {
-	connID, err := protocol.GenerateConnectionID(m.connIDLen)
+	connID, err := m.generateConnectionID()
 	if err != nil {
 		return err
 	}
@@ -107,6 +112,7 @@ func (m *connIDGenerator) issueNewConnID() error {
 	m.addConnectionID(connID)
 	m.queueControlFrame(&wire.NewConnectionIDFrame{
 		SequenceNumber:      m.highestSeq + 1,
+		RetirePriorTo:       m.retirePriorTo,
 		ConnectionID:        connID,
 		StatelessResetToken: m.getStatelessResetToken(connID),
 	})
@@ -114,6 +120,18 @@ func (m *connIDGenerator) issueNewConnID() error {
 	return nil
 }
 
+// Rotate issues a new connection ID, and asks the peer to retire all connection IDs issued before.
+// The peer retires the old connection IDs before adding the new one (RFC 9000, Section 5.1.2),
+// so this doesn't exceed its active_connection_id_limit.
+// Replacements for the retired connection IDs are issued when the peer sends the RETIRE_CONNECTION_ID frames.
+func (m *connIDGenerator) Rotate() error {
+	if m.connIDLen == 0 {
+		return errors.New("can't rotate zero-length connection IDs")
+	}
+	m.retirePriorTo = m.highestSeq + 1
+	return m.issueNewConnID()
+}
+
 func (m *connIDGenerator) SetHandshakeComplete() {
 	if m.initialClientDestConnID != nil {
 		m.retireConnectionID(m.initialClientDestConnID)
@@ -130,6 +148,18 @@ func (m *connIDGenerator) RemoveAll() {
 	}
 }
 
+// ConnectionIDs returns all active connection IDs.
+func (m *connIDGenerator) ConnectionIDs() []protocol.ConnectionID {
+	connIDs := make([]protocol.ConnectionID, 0, len(m.activeSrcConnIDs)+1)
+	if m.initialClientDestConnID != nil {
+		connIDs = append(connIDs, m.initialClientDestConnID)
+	}
+	for _, connID := range m.activeSrcConnIDs {
+		connIDs = append(connIDs, connID)
+	}
+	return connIDs
+}
+
 func (m *connIDGenerator) ReplaceWithClosed(handler packetHandler) {
 	if m.initialClientDestConnID != nil {
 		m.replaceWithClosed(m.initialClientDestConnID, handler)
@@ -1,6 +1,7 @@
 package quic
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -36,6 +37,7 @@ var _ = Describe("Connection ID Generator", func() {
 		g = newConnIDGenerator(
 			initialConnID,
 			initialClientDestConnID,
+			func() (protocol.ConnectionID, error) { return protocol.GenerateConnectionID(initialConnID.Len()) },
 			func(c protocol.ConnectionID) { addedConnIDs = append(addedConnIDs, c) },
 			connIDToToken,
 			func(c protocol.ConnectionID) { removedConnIDs = append(removedConnIDs, c) },
@@ -64,6 +66,23 @@ var _ = Describe("Connection ID Generator", func() {
 		}
 	})
 
+	It("uses the connection ID generation function", func() {
+		g.generateConnectionID = func() (protocol.ConnectionID, error) {
+			return protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad, 0, 0, byte(len(addedConnIDs))}, nil
+		}
+		Expect(g.SetMaxActiveConnIDs(3)).To(Succeed())
+		Expect(addedConnIDs).To(Equal([]protocol.ConnectionID{
+			{0xde, 0xca, 0xfb, 0xad, 0, 0, 0},
+			{0xde, 0xca, 0xfb, 0xad, 0, 0, 1},
+		}))
+	})
+
+	It("returns the error when generating a connection ID fails", func() {
+		testErr := errors.New("test error")
+		g.generateConnectionID = func() (protocol.ConnectionID, error) { return nil, testErr }
+		Expect(g.SetMaxActiveConnIDs(4)).To(MatchError(testErr))
+	})
+
 	It("limits the number of connection IDs that it issues", func() {
 		Expect(g.SetMaxActiveConnIDs(9999999)).To(Succeed())
 		Expect(retiredConnIDs).To(BeEmpty())
@@ -152,6 +171,45 @@ var _ = Describe("Connection ID Generator", func() {
 		Expect(queuedFrames).To(HaveLen(1))
 	})
 
+	Context("rotating connection IDs", func() {
+		It("issues a new connection ID and asks the peer to retire all others", func() {
+			Expect(g.SetMaxActiveConnIDs(4)).To(Succeed())
+			queuedFrames = nil
+			Expect(g.Rotate()).To(Succeed())
+			Expect(queuedFrames).To(HaveLen(1))
+			Expect(queuedFrames[0]).To(BeAssignableToTypeOf(&wire.NewConnectionIDFrame{}))
+			nf := queuedFrames[0].(*wire.NewConnectionIDFrame)
+			Expect(nf.SequenceNumber).To(BeEquivalentTo(4))
+			Expect(nf.RetirePriorTo).To(BeEquivalentTo(4))
+			Expect(nf.ConnectionID).To(Equal(addedConnIDs[len(addedConnIDs)-1]))
+			Expect(retiredConnIDs).To(BeEmpty())
+		})
+
+		It("replaces the connection IDs retired by the peer, without exceeding the limit", func() {
+			Expect(g.SetMaxActiveConnIDs(4)).To(Succeed())
+			Expect(g.Rotate()).To(Succeed())
+			queuedFrames = nil
+			for seq := uint64(0); seq < 4; seq++ {
+				Expect(g.Retire(seq, protocol.ConnectionID{})).To(Succeed())
+			}
+			Expect(retiredConnIDs).To(HaveLen(4))
+			// no replacement is issued for the initial connection ID
+			Expect(queuedFrames).To(HaveLen(3))
+			for i, f := range queuedFrames {
+				nf := f.(*wire.NewConnectionIDFrame)
+				Expect(nf.SequenceNumber).To(BeEquivalentTo(5 + i))
+				Expect(nf.RetirePriorTo).To(BeEquivalentTo(4))
+			}
+			Expect(g.ConnectionIDs()).To(HaveLen(4 + 1)) // including the client's initial destination connection ID
+		})
+
+		It("doesn't rotate zero-length connection IDs", func() {
+			g.connIDLen = 0
+			Expect(g.Rotate()).To(MatchError("can't rotate zero-length connection IDs"))
+			Expect(queuedFrames).To(BeEmpty())
+		})
+	})
+
 	It("retires the client's initial destination connection ID when the handshake completes", func() {
 		g.SetHandshakeComplete()
 		Expect(retiredConnIDs).To(HaveLen(1))
@@ -171,6 +229,18 @@ var _ = Describe("Connection ID Generator", func() {
 		}
 	})
 
+	It("returns all connection IDs", func() {
+		Expect(g.SetMaxActiveConnIDs(5)).To(Succeed())
+		Expect(queuedFrames).To(HaveLen(4))
+		connIDs := g.ConnectionIDs()
+		Expect(connIDs).To(HaveLen(6)) // initial conn ID, initial client dest conn id, and newly issued ones
+		Expect(connIDs).To(ContainElement(initialConnID))
+		Expect(connIDs).To(ContainElement(initialClientDestConnID))
+		for _, f := range queuedFrames {
+			Expect(connIDs).To(ContainElement(f.(*wire.NewConnectionIDFrame).ConnectionID))
+		}
+	})
+
 	It("replaces with a closed session for all connection IDs", func() {
 		Expect(g.SetMaxActiveConnIDs(5)).To(Succeed())
 		Expect(queuedFrames).To(HaveLen(4))
@@ -202,6 +202,25 @@ func (h *connIDManager) Get() protocol.ConnectionID {
 	return h.activeConnectionID
 }
 
+// ActiveStatelessResetToken returns the stateless reset token of the active connection ID, if any.
+func (h *connIDManager) ActiveStatelessResetToken() *protocol.StatelessResetToken {
+	return h.activeStatelessResetToken
+}
+
+// SwitchForMigration switches to a connection ID that hasn't been used before.
+// It is called when migrating to a new path, since a connection ID must not be used from more than one local address.
+// It returns false if the peer didn't provide an unused connection ID (unless the peer uses zero-length connection IDs).
+func (h *connIDManager) SwitchForMigration() bool {
+	if h.activeConnectionID.Len() == 0 {
+		return true
+	}
+	if h.queue.Len() == 0 {
+		return false
+	}
+	h.updateConnectionID()
+	return true
+}
+
 func (h *connIDManager) SetHandshakeComplete() {
 	h.handshakeComplete = true
 }
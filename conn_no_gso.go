@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package quic
+
+import "syscall"
+
+func isGSOSupported(syscall.RawConn) bool { return false }
+
+func appendUDPSegmentSizeMsg(b []byte, _ uint16) []byte { return b }
+
+func isGSOError(error) bool { return false }
@@ -10,6 +10,7 @@ import (
 	"net"
 	"syscall"
 	"time"
+	"unsafe"
 
 	"golang.org/x/net/ipv4"
 	"golang.org/x/net/ipv6"
@@ -22,6 +23,8 @@ import (
 const (
 	ecnMask       = 0x3
 	oobBufferSize = 128
+	// the size of the buffers used to read packets coalesced by GRO
+	groBufferSize = 1 << 16
 )
 
 // Contrary to what the naming suggests, the ipv{4,6}.Message is not dependent on the IP version.
@@ -54,14 +57,52 @@ func inspectReadBuffer(c interface{}) (int, error) {
 	return size, serr
 }
 
+// setDSCP sets the DSCP of the packets sent on the packet conn, using the IP_TOS / IPV6_TCLASS socket option.
+// It is a no-op if the packet conn doesn't provide access to the underlying socket.
+func setDSCP(c net.PacketConn, dscp uint8) error {
+	conn, ok := c.(interface {
+		SyscallConn() (syscall.RawConn, error)
+	})
+	if !ok {
+		utils.DefaultLogger.Debugf("Not setting the DSCP, since the PacketConn doesn't have a SyscallConn.")
+		return nil
+	}
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return fmt.Errorf("couldn't get syscall.RawConn: %w", err)
+	}
+	// We don't know if this a IPv4-only, IPv6-only or a IPv4-and-IPv6 connection.
+	// Try setting the DSCP for both IP versions. We expect at least one of those syscalls to succeed.
+	var errIPv4, errIPv6 error
+	if err := rawConn.Control(func(fd uintptr) {
+		errIPv4 = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_TOS, int(dscp<<2))
+		errIPv6 = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_TCLASS, int(dscp<<2))
+	}); err != nil {
+		return err
+	}
+	if errIPv4 != nil && errIPv6 != nil {
+		return fmt.Errorf("setting the DSCP failed for both IPv4 (%s) and IPv6 (%s)", errIPv4, errIPv6)
+	}
+	return nil
+}
+
 type oobConn struct {
 	OOBCapablePacketConn
+	largePackets
 	batchConn batchConn
+	// the kernel supports UDP generic segmentation offload (GSO) on this socket
+	gso bool
+	// UDP generic receive offload (GRO) is enabled on this socket.
+	// Multiple packets from the same sender might then be returned in a single message.
+	gro bool
 
 	readPos uint8
 	// Packets received from the kernel, but not yet returned by ReadPacket().
 	messages []ipv4.Message
 	buffers  [batchSize]*packetBuffer
+	// Packets split from a message received using GRO, but not yet returned by ReadPacket().
+	groPackets []*receivedPacket
+	groPos     int
 }
 
 var _ connection = &oobConn{}
@@ -123,27 +164,49 @@ func newConn(c OOBCapablePacketConn) (*oobConn, error) {
 		bc = ipv4.NewPacketConn(c)
 	}
 
+	gso := isGSOSupported(rawConn)
+	if gso {
+		utils.DefaultLogger.Debugf("Activating UDP generic segmentation offload (GSO).")
+	}
+	gro := setGRO(rawConn, true) == nil
+	if gro {
+		utils.DefaultLogger.Debugf("Activating UDP generic receive offload (GRO).")
+	}
+
 	oobConn := &oobConn{
 		OOBCapablePacketConn: c,
 		batchConn:            bc,
+		gso:                  gso,
+		gro:                  gro,
 		messages:             make([]ipv4.Message, batchSize),
 		readPos:              batchSize,
 	}
 	for i := 0; i < batchSize; i++ {
 		oobConn.messages[i].OOB = make([]byte, oobBufferSize)
+		if gro {
+			// The packets are copied out of these buffers, so they can be reused for every read.
+			oobConn.messages[i].Buffers = [][]byte{make([]byte, groBufferSize)}
+		}
 	}
 	return oobConn, nil
 }
 
 func (c *oobConn) ReadPacket() (*receivedPacket, error) {
+	if c.groPos < len(c.groPackets) {
+		p := c.groPackets[c.groPos]
+		c.groPackets[c.groPos] = nil
+		c.groPos++
+		return p, nil
+	}
 	if len(c.messages) == int(c.readPos) { // all messages read. Read the next batch of messages.
 		c.messages = c.messages[:batchSize]
 		// replace buffers data buffers up to the packet that has been consumed during the last ReadBatch call
-		for i := uint8(0); i < c.readPos; i++ {
-			buffer := getPacketBuffer()
-			buffer.Data = buffer.Data[:protocol.MaxPacketBufferSize]
-			c.buffers[i] = buffer
-			c.messages[i].Buffers = [][]byte{c.buffers[i].Data}
+		if !c.gro {
+			for i := uint8(0); i < c.readPos; i++ {
+				buffer := c.getReadBuffer()
+				c.buffers[i] = buffer
+				c.messages[i].Buffers = [][]byte{c.buffers[i].Data}
+			}
 		}
 		c.readPos = 0
 
@@ -164,7 +227,12 @@ func (c *oobConn) ReadPacket() (*receivedPacket, error) {
 	var ecn protocol.ECN
 	var destIP net.IP
 	var ifIndex uint32
+	var segmentSize int
 	for _, ctrlMsg := range ctrlMsgs {
+		if size, ok := parseGROSegmentSize(ctrlMsg); ok {
+			segmentSize = size
+			continue
+		}
 		if ctrlMsg.Header.Level == unix.IPPROTO_IP {
 			switch ctrlMsg.Header.Type {
 			case msgTypeIPTOS:
@@ -212,6 +280,14 @@ func (c *oobConn) ReadPacket() (*receivedPacket, error) {
 			ifIndex: ifIndex,
 		}
 	}
+	if c.gro {
+		return c.splitGROMessage(msg.Buffers[0][:msg.N], segmentSize, receivedPacket{
+			remoteAddr: msg.Addr,
+			rcvTime:    time.Now(),
+			ecn:        ecn,
+			info:       info,
+		}), nil
+	}
 	return &receivedPacket{
 		remoteAddr: msg.Addr,
 		rcvTime:    time.Now(),
@@ -222,11 +298,55 @@ func (c *oobConn) ReadPacket() (*receivedPacket, error) {
 	}, nil
 }
 
+// splitGROMessage splits a message received using GRO into packets of segmentSize bytes.
+// The last packet may be shorter. If segmentSize is 0, the message contains a single packet.
+// The packets are copied into packet buffers, such that the read buffer can be reused.
+func (c *oobConn) splitGROMessage(data []byte, segmentSize int, p receivedPacket) *receivedPacket {
+	if segmentSize == 0 {
+		segmentSize = len(data)
+	}
+	c.groPackets = c.groPackets[:0]
+	c.groPos = 0
+	for len(data) > 0 {
+		n := utils.Min(segmentSize, len(data))
+		var buffer *packetBuffer
+		if c.largePacketsEnabled() {
+			buffer = getPacketBufferForSize(protocol.ByteCount(n))
+		} else {
+			buffer = getPacketBuffer()
+		}
+		// The packet size should not exceed the size of the buffer.
+		// If it does, we only read a truncated packet, which will then end up undecryptable
+		l := copy(buffer.Data[:cap(buffer.Data)], data[:n])
+		packet := p
+		packet.data = buffer.Data[:l]
+		packet.buffer = buffer
+		c.groPackets = append(c.groPackets, &packet)
+		data = data[n:]
+	}
+	if len(c.groPackets) == 0 {
+		// an empty UDP datagram
+		buffer := getPacketBuffer()
+		packet := p
+		packet.data = buffer.Data[:0]
+		packet.buffer = buffer
+		return &packet
+	}
+	packet := c.groPackets[0]
+	c.groPackets[0] = nil
+	c.groPos = 1
+	return packet
+}
+
 func (c *oobConn) WritePacket(b []byte, addr net.Addr, oob []byte) (n int, err error) {
 	n, _, err = c.OOBCapablePacketConn.WriteMsgUDP(b, oob, addr.(*net.UDPAddr))
 	return n, err
 }
 
+func (c *oobConn) supportsGSO() bool {
+	return c.gso
+}
+
 func (info *packetInfo) OOB() []byte {
 	if info == nil {
 		return nil
@@ -255,3 +375,35 @@ func (info *packetInfo) OOB() []byte {
 	}
 	return nil
 }
+
+// appendECNControlMessage appends a control message setting the ECN bits of the IP header.
+// The control message overrides the IP_TOS / IPV6_TCLASS socket option, so it also sets the DSCP.
+// The IP version is determined from the remote address.
+func appendECNControlMessage(b []byte, ecn protocol.ECN, dscp uint8, remote net.Addr) []byte {
+	udpAddr, ok := remote.(*net.UDPAddr)
+	if !ok {
+		return b
+	}
+	tos := dscp<<2 | byte(ecn)
+	if udpAddr.IP.To4() != nil {
+		return appendControlMessage(b, unix.IPPROTO_IP, unix.IP_TOS, ecnIPv4DataLen, tos)
+	}
+	return appendControlMessage(b, unix.IPPROTO_IPV6, unix.IPV6_TCLASS, 4, tos)
+}
+
+func appendControlMessage(b []byte, level, typ int32, dataLen int, val byte) []byte {
+	startLen := len(b)
+	b = append(b, make([]byte, unix.CmsgSpace(dataLen))...)
+	h := (*unix.Cmsghdr)(unsafe.Pointer(&b[startLen]))
+	h.Level = level
+	h.Type = typ
+	h.SetLen(unix.CmsgLen(dataLen))
+	// The value is a single byte on some platforms, and an int (in host byte order) on others.
+	offset := startLen + unix.CmsgSpace(0)
+	if dataLen == 4 {
+		*(*int32)(unsafe.Pointer(&b[offset])) = int32(val)
+	} else {
+		b[offset] = val
+	}
+	return b
+}
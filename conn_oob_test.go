@@ -6,6 +6,7 @@ package quic
 import (
 	"fmt"
 	"net"
+	"testing"
 	"time"
 
 	"golang.org/x/net/ipv4"
@@ -19,6 +20,25 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+// disableGRO disables GRO, such that packets are read into packet buffers directly.
+func disableGRO(c *oobConn) error {
+	if !c.gro {
+		return nil
+	}
+	rawConn, err := c.SyscallConn()
+	if err != nil {
+		return err
+	}
+	if err := setGRO(rawConn, false); err != nil {
+		return err
+	}
+	c.gro = false
+	for i := range c.messages {
+		c.messages[i].Buffers = nil
+	}
+	return nil
+}
+
 var _ = Describe("OOB Conn Test", func() {
 	runServer := func(network, address string) (*net.UDPConn, <-chan *receivedPacket) {
 		addr, err := net.ResolveUDPAddr(network, address)
@@ -129,6 +149,210 @@ var _ = Describe("OOB Conn Test", func() {
 			Expect(utils.IsIPv4(p.remoteAddr.(*net.UDPAddr).IP)).To(BeFalse())
 			Expect(p.ecn).To(Equal(protocol.ECT1))
 		})
+
+		It("sends ECN-marked packets", func() {
+			conn, packetChan := runServer("udp", "0.0.0.0:0")
+			defer conn.Close()
+			port := conn.LocalAddr().(*net.UDPAddr).Port
+
+			for _, remote := range []*net.UDPAddr{
+				{IP: net.IPv4(127, 0, 0, 1), Port: port},
+				{IP: net.IPv6loopback, Port: port},
+			} {
+				network := "udp4"
+				if remote.IP.To4() == nil {
+					network = "udp6"
+				}
+				udpConn, err := net.ListenUDP(network, nil)
+				Expect(err).ToNot(HaveOccurred())
+				defer udpConn.Close()
+				c, err := newConn(udpConn)
+				Expect(err).ToNot(HaveOccurred())
+				sconn := newSendConn(c, remote, nil, 0)
+				for _, ecn := range []protocol.ECN{protocol.ECT0, protocol.ECNCE, protocol.ECNNon} {
+					Expect(sconn.Write([]byte("foobar"), ecn)).To(Succeed())
+					var p *receivedPacket
+					Eventually(packetChan).Should(Receive(&p))
+					Expect(p.data).To(Equal([]byte("foobar")))
+					Expect(p.ecn).To(Equal(ecn))
+				}
+			}
+		})
+	})
+
+	Context("GSO", func() {
+		It("sends multiple packets in a single syscall", func() {
+			conn, packetChan := runServer("udp", "localhost:0")
+			defer conn.Close()
+
+			udpConn, err := net.ListenUDP("udp", nil)
+			Expect(err).ToNot(HaveOccurred())
+			defer udpConn.Close()
+			c, err := newConn(udpConn)
+			Expect(err).ToNot(HaveOccurred())
+			sconn := newSendConn(c, conn.LocalAddr(), nil, 0)
+			if !sconn.SupportsGSO() {
+				Skip("GSO not supported")
+			}
+			Expect(sconn.WriteGSO([]byte("foobarraboofoo"), 6, protocol.ECT0)).To(Succeed())
+			for _, data := range []string{"foobar", "raboof", "oo"} {
+				var p *receivedPacket
+				Eventually(packetChan).Should(Receive(&p))
+				Expect(p.data).To(Equal([]byte(data)))
+				Expect(p.ecn).To(Equal(protocol.ECT0))
+			}
+		})
+	})
+
+	Context("GRO", func() {
+		It("receives packets coalesced by GRO", func() {
+			conn, packetChan := runServer("udp", "localhost:0")
+			defer conn.Close()
+
+			udpConn, err := net.ListenUDP("udp", nil)
+			Expect(err).ToNot(HaveOccurred())
+			defer udpConn.Close()
+			c, err := newConn(udpConn)
+			Expect(err).ToNot(HaveOccurred())
+			if !c.gro {
+				Skip("GRO not supported")
+			}
+			sconn := newSendConn(c, conn.LocalAddr(), nil, 0)
+			// On the loopback interface, packets sent using GSO are not split by the kernel if GRO is enabled.
+			if !sconn.SupportsGSO() {
+				Skip("GSO not supported")
+			}
+			Expect(sconn.WriteGSO([]byte("foobarraboofoo"), 6, protocol.ECT1)).To(Succeed())
+			for _, data := range []string{"foobar", "raboof", "oo"} {
+				var p *receivedPacket
+				Eventually(packetChan).Should(Receive(&p))
+				Expect(p.data).To(Equal([]byte(data)))
+				Expect(p.ecn).To(Equal(protocol.ECT1))
+			}
+		})
+
+		It("splits messages received using GRO", func() {
+			udpConn, err := net.ListenUDP("udp", nil)
+			Expect(err).ToNot(HaveOccurred())
+			defer udpConn.Close()
+			c, err := newConn(udpConn)
+			Expect(err).ToNot(HaveOccurred())
+			remoteAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1234}
+			p := c.splitGROMessage([]byte("foobarraboofoo"), 6, receivedPacket{remoteAddr: remoteAddr, ecn: protocol.ECT0})
+			Expect(p.data).To(Equal([]byte("foobar")))
+			Expect(p.remoteAddr).To(Equal(remoteAddr))
+			Expect(p.ecn).To(Equal(protocol.ECT0))
+			for _, data := range []string{"raboof", "oo"} {
+				p, err := c.ReadPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.data).To(Equal([]byte(data)))
+				Expect(p.remoteAddr).To(Equal(remoteAddr))
+				Expect(p.ecn).To(Equal(protocol.ECT0))
+				Expect(p.buffer).ToNot(BeNil())
+			}
+			// messages that were not coalesced contain a single packet
+			p = c.splitGROMessage([]byte("foobar"), 0, receivedPacket{remoteAddr: remoteAddr})
+			Expect(p.data).To(Equal([]byte("foobar")))
+			Expect(c.groPos).To(Equal(len(c.groPackets)))
+		})
+
+		It("splits messages containing large packets received using GRO", func() {
+			udpConn, err := net.ListenUDP("udp", nil)
+			Expect(err).ToNot(HaveOccurred())
+			defer udpConn.Close()
+			c, err := newConn(udpConn)
+			Expect(err).ToNot(HaveOccurred())
+			data := make([]byte, 2*8000)
+			// large packets are truncated, unless they are enabled
+			p := c.splitGROMessage(data, 8000, receivedPacket{})
+			Expect(p.data).To(HaveLen(int(protocol.MaxPacketBufferSize)))
+			c.EnableLargePackets()
+			p = c.splitGROMessage(data, 8000, receivedPacket{})
+			Expect(p.data).To(HaveLen(8000))
+			p, err = c.ReadPacket()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.data).To(HaveLen(8000))
+		})
+	})
+
+	Context("DSCP", func() {
+		getTOS := func(conn *net.UDPConn, level, opt int) int {
+			rawConn, err := conn.SyscallConn()
+			ExpectWithOffset(1, err).ToNot(HaveOccurred())
+			var tos int
+			var serr error
+			ExpectWithOffset(1, rawConn.Control(func(fd uintptr) {
+				tos, serr = unix.GetsockoptInt(int(fd), level, opt)
+			})).To(Succeed())
+			ExpectWithOffset(1, serr).ToNot(HaveOccurred())
+			return tos
+		}
+
+		// receiveTOS receives a packet, and returns the value of the TOS / Traffic Class field of the IP header
+		receiveTOS := func(conn *net.UDPConn) byte {
+			b := make([]byte, 1500)
+			oob := make([]byte, oobBufferSize)
+			ExpectWithOffset(1, conn.SetReadDeadline(time.Now().Add(scaleDuration(time.Second)))).To(Succeed())
+			n, oobn, _, _, err := conn.ReadMsgUDP(b, oob)
+			ExpectWithOffset(1, err).ToNot(HaveOccurred())
+			ExpectWithOffset(1, b[:n]).To(Equal([]byte("foobar")))
+			ctrlMsgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+			ExpectWithOffset(1, err).ToNot(HaveOccurred())
+			for _, ctrlMsg := range ctrlMsgs {
+				if (ctrlMsg.Header.Level == unix.IPPROTO_IP && ctrlMsg.Header.Type == msgTypeIPTOS) ||
+					(ctrlMsg.Header.Level == unix.IPPROTO_IPV6 && ctrlMsg.Header.Type == unix.IPV6_TCLASS) {
+					return ctrlMsg.Data[0]
+				}
+			}
+			Fail("didn't receive the TOS")
+			return 0
+		}
+
+		It("sets the DSCP on IPv4 sockets", func() {
+			conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.Close()
+			Expect(setDSCP(conn, 46)).To(Succeed())
+			Expect(getTOS(conn, unix.IPPROTO_IP, unix.IP_TOS)).To(Equal(46 << 2))
+		})
+
+		It("sets the DSCP on IPv6 sockets", func() {
+			conn, err := net.ListenUDP("udp6", &net.UDPAddr{IP: net.IPv6loopback})
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.Close()
+			Expect(setDSCP(conn, 46)).To(Succeed())
+			Expect(getTOS(conn, unix.IPPROTO_IPV6, unix.IPV6_TCLASS)).To(Equal(46 << 2))
+		})
+
+		It("doesn't set the DSCP on packet conns that don't have a SyscallConn", func() {
+			Expect(setDSCP(NewMockPacketConn(mockCtrl), 46)).To(Succeed())
+		})
+
+		It("sends packets marked with the DSCP, with and without ECN marks", func() {
+			for _, network := range []string{"udp4", "udp6"} {
+				ip := net.IPv4(127, 0, 0, 1)
+				if network == "udp6" {
+					ip = net.IPv6loopback
+				}
+				server, err := net.ListenUDP(network, &net.UDPAddr{IP: ip})
+				Expect(err).ToNot(HaveOccurred())
+				defer server.Close()
+				_, err = newConn(server) // enables receiving of the TOS field
+				Expect(err).ToNot(HaveOccurred())
+
+				udpConn, err := net.ListenUDP(network, &net.UDPAddr{IP: ip})
+				Expect(err).ToNot(HaveOccurred())
+				defer udpConn.Close()
+				Expect(setDSCP(udpConn, 46)).To(Succeed())
+				c, err := newConn(udpConn)
+				Expect(err).ToNot(HaveOccurred())
+				sconn := newSendConn(c, server.LocalAddr(), nil, 46)
+				for _, ecn := range []protocol.ECN{protocol.ECNNon, protocol.ECT0, protocol.ECNCE} {
+					Expect(sconn.Write([]byte("foobar"), ecn)).To(Succeed())
+					Expect(receiveTOS(server)).To(Equal(byte(46<<2) | byte(ecn)))
+				}
+			}
+		})
 	})
 
 	Context("Packet Info conn", func() {
@@ -231,6 +455,7 @@ var _ = Describe("OOB Conn Test", func() {
 			Expect(err).ToNot(HaveOccurred())
 			oobConn, err := newConn(udpConn)
 			Expect(err).ToNot(HaveOccurred())
+			Expect(disableGRO(oobConn)).To(Succeed())
 			oobConn.batchConn = batchConn
 
 			for i := 0; i < batchSize+1; i++ {
@@ -241,3 +466,84 @@ var _ = Describe("OOB Conn Test", func() {
 		})
 	})
 })
+
+// BenchmarkReadPacket measures how many packets per second can be read from a UDP socket,
+// using a single syscall per packet, using recvmmsg, and using recvmmsg and GRO.
+// Run it using go test -run='^$' -bench=ReadPacket.
+func BenchmarkReadPacket(b *testing.B) {
+	for _, tc := range []string{"single reads", "batched reads", "GRO"} {
+		b.Run(tc, func(b *testing.B) {
+			udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer udpConn.Close()
+			var conn connection
+			switch tc {
+			case "single reads":
+				conn = &basicConn{PacketConn: udpConn}
+			default:
+				c, err := newConn(udpConn)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if tc == "GRO" && !c.gro {
+					b.Skip("GRO not supported")
+				}
+				if tc == "batched reads" {
+					if err := disableGRO(c); err != nil {
+						b.Fatal(err)
+					}
+				}
+				conn = c
+			}
+
+			// send packets using GSO (if supported), as fast as possible
+			senderConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer senderConn.Close()
+			sc, err := newConn(senderConn)
+			if err != nil {
+				b.Fatal(err)
+			}
+			sender := newSendConn(sc, udpConn.LocalAddr(), nil, 0)
+			done := make(chan struct{})
+			senderStopped := make(chan struct{})
+			go func() {
+				defer close(senderStopped)
+				const packetSize = 1200
+				data := make([]byte, 10*packetSize)
+				for {
+					select {
+					case <-done:
+						return
+					default:
+					}
+					if sender.SupportsGSO() {
+						sender.WriteGSO(data, packetSize, protocol.ECNNon)
+						continue
+					}
+					for i := 0; i < 10; i++ {
+						sender.Write(data[:packetSize], protocol.ECNNon)
+					}
+				}
+			}()
+
+			b.ResetTimer()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				p, err := conn.ReadPacket()
+				if err != nil {
+					b.Fatal(err)
+				}
+				p.buffer.Release()
+			}
+			b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "packets/s")
+			b.StopTimer()
+			close(done)
+			<-senderStopped
+		})
+	}
+}
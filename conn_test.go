@@ -30,4 +30,21 @@ var _ = Describe("Basic Conn Test", func() {
 		Expect(p.rcvTime).To(BeTemporally("~", time.Now(), scaleDuration(100*time.Millisecond)))
 		Expect(p.remoteAddr).To(Equal(addr))
 	})
+
+	It("reads large packets, if enabled", func() {
+		c := NewMockPacketConn(mockCtrl)
+		addr := &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234}
+		c.EXPECT().ReadFrom(gomock.Any()).DoAndReturn(func(b []byte) (int, net.Addr, error) {
+			Expect(b).To(HaveLen(int(protocol.MaxLargePacketBufferSize)))
+			return copy(b, make([]byte, 8000)), addr, nil
+		})
+
+		conn, err := wrapConn(c)
+		Expect(err).ToNot(HaveOccurred())
+		conn.EnableLargePackets()
+		p, err := conn.ReadPacket()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.data).To(HaveLen(8000))
+		p.buffer.Release()
+	})
 })
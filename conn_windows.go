@@ -10,6 +10,8 @@ import (
 	"syscall"
 
 	"golang.org/x/sys/windows"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
 const IP_DONTFRAGMENT = 14
@@ -52,3 +54,8 @@ func inspectReadBuffer(c net.PacketConn) (int, error) {
 }
 
 func (i *packetInfo) OOB() []byte { return nil }
+
+func appendECNControlMessage(b []byte, _ protocol.ECN, _ uint8, _ net.Addr) []byte { return b }
+
+// setDSCP is a no-op on Windows, which ignores the IP_TOS socket option.
+func setDSCP(net.PacketConn, uint8) error { return nil }
@@ -0,0 +1,13 @@
+//go:build go1.20
+// +build go1.20
+
+package quic
+
+import "context"
+
+// withCancelCause returns a context that records the error passed to the cancel function,
+// such that it can be retrieved using context.Cause.
+func withCancelCause(parent context.Context) (context.Context, func(error)) {
+	ctx, cancel := context.WithCancelCause(parent)
+	return ctx, cancel
+}
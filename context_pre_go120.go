@@ -0,0 +1,13 @@
+//go:build !go1.20
+// +build !go1.20
+
+package quic
+
+import "context"
+
+// withCancelCause returns a context that is cancelled by the cancel function.
+// context.Cause was only added in Go 1.20, so the error passed to the cancel function is discarded.
+func withCancelCause(parent context.Context) (context.Context, func(error)) {
+	ctx, cancel := context.WithCancel(parent)
+	return ctx, func(error) { cancel() }
+}
@@ -0,0 +1,10 @@
+//go:build !go1.20
+// +build !go1.20
+
+package quic
+
+import "context"
+
+const contextCauseSupported = false
+
+func contextCause(ctx context.Context) error { return ctx.Err() }
@@ -1,15 +1,24 @@
 package quic
 
 import (
+	"context"
+	"sync"
+
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
 type datagramQueue struct {
-	sendQueue chan *wire.DatagramFrame
+	sendQueue chan *wire.DatagramFrame // used by AddAndWait
 	rcvQueue  chan []byte
 
+	// used by Add, if the send queue is bounded
+	mutex       sync.Mutex
+	queue       []*wire.DatagramFrame
+	maxQueueLen int
+	numDropped  uint64
+
 	closeErr error
 	closed   chan struct{}
 
@@ -20,17 +29,45 @@ type datagramQueue struct {
 	logger utils.Logger
 }
 
-func newDatagramQueue(hasData func(), logger utils.Logger) *datagramQueue {
+// newDatagramQueue creates a new datagramQueue.
+// If maxQueueLen is larger than 0, datagrams are sent using Add, and at most maxQueueLen datagrams are queued.
+func newDatagramQueue(hasData func(), maxQueueLen int, logger utils.Logger) *datagramQueue {
 	return &datagramQueue{
-		hasData:   hasData,
-		sendQueue: make(chan *wire.DatagramFrame, 1),
-		rcvQueue:  make(chan []byte, protocol.DatagramRcvQueueLen),
-		dequeued:  make(chan struct{}),
-		closed:    make(chan struct{}),
-		logger:    logger,
+		hasData:     hasData,
+		sendQueue:   make(chan *wire.DatagramFrame, 1),
+		rcvQueue:    make(chan []byte, protocol.DatagramRcvQueueLen),
+		maxQueueLen: maxQueueLen,
+		dequeued:    make(chan struct{}),
+		closed:      make(chan struct{}),
+		logger:      logger,
 	}
 }
 
+// Add queues a new DATAGRAM frame for sending, without blocking.
+// If the queue is full, the oldest queued frame is dropped, and ErrDatagramQueueOverflow is returned.
+func (h *datagramQueue) Add(f *wire.DatagramFrame) error {
+	select {
+	case <-h.closed:
+		return h.closeErr
+	default:
+	}
+
+	h.mutex.Lock()
+	var err error
+	if len(h.queue) >= h.maxQueueLen {
+		h.logger.Debugf("DATAGRAM send queue full. Dropping the oldest DATAGRAM frame (%d bytes payload)", len(h.queue[0].Data))
+		h.queue[0] = nil
+		h.queue = h.queue[1:]
+		h.numDropped++
+		err = ErrDatagramQueueOverflow
+	}
+	h.queue = append(h.queue, f)
+	h.mutex.Unlock()
+
+	h.hasData()
+	return err
+}
+
 // AddAndWait queues a new DATAGRAM frame for sending.
 // It blocks until the frame has been dequeued.
 func (h *datagramQueue) AddAndWait(f *wire.DatagramFrame) error {
@@ -51,6 +88,19 @@ func (h *datagramQueue) AddAndWait(f *wire.DatagramFrame) error {
 
 // Get dequeues a DATAGRAM frame for sending.
 func (h *datagramQueue) Get() *wire.DatagramFrame {
+	if h.maxQueueLen > 0 {
+		h.mutex.Lock()
+		defer h.mutex.Unlock()
+
+		if len(h.queue) == 0 {
+			return nil
+		}
+		f := h.queue[0]
+		h.queue[0] = nil
+		h.queue = h.queue[1:]
+		return f
+	}
+
 	select {
 	case f := <-h.sendQueue:
 		h.dequeued <- struct{}{}
@@ -60,6 +110,23 @@ func (h *datagramQueue) Get() *wire.DatagramFrame {
 	}
 }
 
+// QueueLen returns the number of DATAGRAM frames waiting to be sent.
+func (h *datagramQueue) QueueLen() int {
+	if h.maxQueueLen > 0 {
+		h.mutex.Lock()
+		defer h.mutex.Unlock()
+		return len(h.queue)
+	}
+	return len(h.sendQueue)
+}
+
+// NumDropped returns the number of DATAGRAM frames that were dropped because the send queue was full.
+func (h *datagramQueue) NumDropped() uint64 {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.numDropped
+}
+
 // HandleDatagramFrame handles a received DATAGRAM frame.
 func (h *datagramQueue) HandleDatagramFrame(f *wire.DatagramFrame) {
 	data := make([]byte, len(f.Data))
@@ -72,12 +139,14 @@ func (h *datagramQueue) HandleDatagramFrame(f *wire.DatagramFrame) {
 }
 
 // Receive gets a received DATAGRAM frame.
-func (h *datagramQueue) Receive() ([]byte, error) {
+func (h *datagramQueue) Receive(ctx context.Context) ([]byte, error) {
 	select {
 	case data := <-h.rcvQueue:
 		return data, nil
 	case <-h.closed:
 		return nil, h.closeErr
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
@@ -1,6 +1,7 @@
 package quic
 
 import (
+	"context"
 	"errors"
 
 	"github.com/lucas-clemente/quic-go/internal/utils"
@@ -18,7 +19,7 @@ var _ = Describe("Datagram Queue", func() {
 		queued = make(chan struct{}, 100)
 		queue = newDatagramQueue(func() {
 			queued <- struct{}{}
-		}, utils.DefaultLogger)
+		}, 0, utils.DefaultLogger)
 	})
 
 	Context("sending", func() {
@@ -56,14 +57,52 @@ var _ = Describe("Datagram Queue", func() {
 		})
 	})
 
+	Context("sending, with a bounded queue", func() {
+		BeforeEach(func() {
+			queue = newDatagramQueue(func() {
+				queued <- struct{}{}
+			}, 3, utils.DefaultLogger)
+		})
+
+		It("queues datagrams without blocking", func() {
+			Expect(queue.Add(&wire.DatagramFrame{Data: []byte("foo")})).To(Succeed())
+			Expect(queue.Add(&wire.DatagramFrame{Data: []byte("bar")})).To(Succeed())
+			Expect(queued).To(HaveLen(2))
+			Expect(queue.QueueLen()).To(Equal(2))
+			Expect(queue.Get().Data).To(Equal([]byte("foo")))
+			Expect(queue.Get().Data).To(Equal([]byte("bar")))
+			Expect(queue.Get()).To(BeNil())
+			Expect(queue.QueueLen()).To(BeZero())
+		})
+
+		It("drops the oldest datagram when the queue is full", func() {
+			for i := 0; i < 3; i++ {
+				Expect(queue.Add(&wire.DatagramFrame{Data: []byte{byte(i)}})).To(Succeed())
+			}
+			Expect(queue.Add(&wire.DatagramFrame{Data: []byte{3}})).To(MatchError(ErrDatagramQueueOverflow))
+			Expect(queue.Add(&wire.DatagramFrame{Data: []byte{4}})).To(MatchError(ErrDatagramQueueOverflow))
+			Expect(queue.QueueLen()).To(Equal(3))
+			Expect(queue.NumDropped()).To(BeEquivalentTo(2))
+			Expect(queue.Get().Data).To(Equal([]byte{2}))
+			Expect(queue.Get().Data).To(Equal([]byte{3}))
+			Expect(queue.Get().Data).To(Equal([]byte{4}))
+			Expect(queue.Get()).To(BeNil())
+		})
+
+		It("closes", func() {
+			queue.CloseWithError(errors.New("test error"))
+			Expect(queue.Add(&wire.DatagramFrame{Data: []byte("foobar")})).To(MatchError("test error"))
+		})
+	})
+
 	Context("receiving", func() {
 		It("receives DATAGRAM frames", func() {
 			queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("foo")})
 			queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("bar")})
-			data, err := queue.Receive()
+			data, err := queue.Receive(context.Background())
 			Expect(err).ToNot(HaveOccurred())
 			Expect(data).To(Equal([]byte("foo")))
-			data, err = queue.Receive()
+			data, err = queue.Receive(context.Background())
 			Expect(err).ToNot(HaveOccurred())
 			Expect(data).To(Equal([]byte("bar")))
 		})
@@ -72,7 +111,7 @@ var _ = Describe("Datagram Queue", func() {
 			c := make(chan []byte, 1)
 			go func() {
 				defer GinkgoRecover()
-				data, err := queue.Receive()
+				data, err := queue.Receive(context.Background())
 				Expect(err).ToNot(HaveOccurred())
 				c <- data
 			}()
@@ -82,11 +121,25 @@ var _ = Describe("Datagram Queue", func() {
 			Eventually(c).Should(Receive(Equal([]byte("foobar"))))
 		})
 
+		It("unblocks when the context is canceled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			errChan := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				_, err := queue.Receive(ctx)
+				errChan <- err
+			}()
+
+			Consistently(errChan).ShouldNot(Receive())
+			cancel()
+			Eventually(errChan).Should(Receive(Equal(context.Canceled)))
+		})
+
 		It("closes", func() {
 			errChan := make(chan error, 1)
 			go func() {
 				defer GinkgoRecover()
-				_, err := queue.Receive()
+				_, err := queue.Receive(context.Background())
 				errChan <- err
 			}()
 
@@ -6,7 +6,6 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"flag"
-	"fmt"
 	"io"
 	"log"
 	"net/http"
@@ -26,6 +25,7 @@ func main() {
 	keyLogFile := flag.String("keylog", "", "key log file")
 	insecure := flag.Bool("insecure", false, "skip certificate verification")
 	enableQlog := flag.Bool("qlog", false, "output a qlog (in the same directory)")
+	altSvc := flag.Bool("altsvc", false, "connect using TCP, and switch to HTTP/3 once the server advertises it using Alt-Svc")
 	flag.Parse()
 	urls := flag.Args()
 
@@ -55,8 +55,8 @@ func main() {
 
 	var qconf quic.Config
 	if *enableQlog {
-		qconf.Tracer = qlog.NewTracer(func(_ logging.Perspective, connID []byte) io.WriteCloser {
-			filename := fmt.Sprintf("client_%x.qlog", connID)
+		qconf.Tracer = qlog.NewTracer(func(p logging.Perspective, connID []byte) io.WriteCloser {
+			filename := qlog.FileName(p, connID)
 			f, err := os.Create(filename)
 			if err != nil {
 				log.Fatal(err)
@@ -73,6 +73,9 @@ func main() {
 		},
 		QuicConfig: &qconf,
 	}
+	if *altSvc {
+		roundTripper.Fallback = &http.Transport{TLSClientConfig: roundTripper.TLSClientConfig.Clone()}
+	}
 	defer roundTripper.Close()
 	hclient := &http.Client{
 		Transport: roundTripper,
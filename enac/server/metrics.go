@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metricsSampleInterval is the interval at which the congestion state of the active sessions is sampled
+const metricsSampleInterval = time.Second
+
+var (
+	// bucket boundaries of the congestion window histogram, in bytes
+	congestionWindowBuckets = []float64{16 << 10, 32 << 10, 64 << 10, 128 << 10, 256 << 10, 512 << 10, 1 << 20, 2 << 20, 4 << 20, 8 << 20, 16 << 20}
+	// bucket boundaries of the smoothed RTT histogram, in seconds
+	rttBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}
+)
+
+// metrics collects metrics of the QUIC connections of the server,
+// and exposes them in the Prometheus text format.
+type metrics struct {
+	handler http.Handler
+
+	activeConns   prometheus.Gauge
+	handshakes    prometheus.Counter
+	bytesSent     prometheus.Counter
+	bytesReceived prometheus.Counter
+	// number of streams blocked by flow control at the last sample
+	blockedSendStreams    prometheus.Gauge
+	blockedReceiveStreams prometheus.Gauge
+	// labeled by the congestion control algorithm used by the session
+	congestionWindow *prometheus.HistogramVec
+	smoothedRTT      *prometheus.HistogramVec
+
+	mutex    sync.Mutex
+	sessions map[quic.Session]struct{}
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		activeConns: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "quic_connections_active",
+			Help: "Number of active QUIC connections.",
+		}),
+		handshakes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "quic_handshakes_total",
+			Help: "Number of completed QUIC handshakes.",
+		}),
+		bytesSent: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "quic_sent_bytes_total",
+			Help: "Number of bytes sent in QUIC packets.",
+		}),
+		bytesReceived: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "quic_received_bytes_total",
+			Help: "Number of bytes received in QUIC packets.",
+		}),
+		blockedSendStreams: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "quic_streams_send_blocked",
+			Help: "Number of streams that can't send data due to the peer's flow control limit.",
+		}),
+		blockedReceiveStreams: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "quic_streams_receive_blocked",
+			Help: "Number of streams on which the peer exhausted our flow control limit.",
+		}),
+		congestionWindow: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "quic_congestion_window_bytes",
+			Help:    "Congestion window of the active QUIC connections, sampled every second.",
+			Buckets: congestionWindowBuckets,
+		}, []string{"algorithm"}),
+		smoothedRTT: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "quic_smoothed_rtt_seconds",
+			Help:    "Smoothed RTT of the active QUIC connections, sampled every second.",
+			Buckets: rttBuckets,
+		}, []string{"algorithm"}),
+		sessions: make(map[quic.Session]struct{}),
+	}
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		m.activeConns,
+		m.handshakes,
+		m.bytesSent,
+		m.bytesReceived,
+		m.blockedSendStreams,
+		m.blockedReceiveStreams,
+		m.congestionWindow,
+		m.smoothedRTT,
+	)
+	m.handler = promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	go m.run()
+	return m
+}
+
+// AddSession adds a session, whose congestion state is sampled until the session is closed.
+func (m *metrics) AddSession(sess quic.Session) {
+	m.mutex.Lock()
+	m.sessions[sess] = struct{}{}
+	m.mutex.Unlock()
+	go func() {
+		<-sess.Context().Done()
+		m.mutex.Lock()
+		delete(m.sessions, sess)
+		m.mutex.Unlock()
+	}()
+}
+
+func (m *metrics) run() {
+	ticker := time.NewTicker(metricsSampleInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		m.sample()
+	}
+}
+
+func (m *metrics) sample() {
+	// Don't hold the mutex while querying the sessions.
+	// Closed sessions are removed by AddSession, which would otherwise be blocked.
+	m.mutex.Lock()
+	sessions := make([]quic.Session, 0, len(m.sessions))
+	for sess := range m.sessions {
+		sessions = append(sessions, sess)
+	}
+	m.mutex.Unlock()
+
+	var blockedSendStreams, blockedReceiveStreams int
+	for _, sess := range sessions {
+		// unsupported algorithms silently fall back to Cubic, so use the algorithm the session actually uses
+		congState := sess.CongestionState()
+		m.congestionWindow.WithLabelValues(congState.CongestionControl).Observe(float64(congState.CongestionWindow))
+		m.smoothedRTT.WithLabelValues(congState.CongestionControl).Observe(sess.RTTStats().SmoothedRTT.Seconds())
+		for _, stats := range sess.StreamStats() {
+			if stats.BlockedOnFlowControl {
+				blockedSendStreams++
+			}
+			// the client can't send more data on this stream, e.g. an upload that isn't read fast enough
+			if stats.ReceiveWindow > 0 && stats.BytesReceived >= stats.ReceiveWindow {
+				blockedReceiveStreams++
+			}
+		}
+	}
+	m.blockedSendStreams.Set(float64(blockedSendStreams))
+	m.blockedReceiveStreams.Set(float64(blockedReceiveStreams))
+}
+
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.handler.ServeHTTP(w, r)
+}
+
+// Tracer returns a tracer that counts connections, handshakes and bytes.
+func (m *metrics) Tracer() logging.Tracer {
+	return &metricsTracer{metrics: m}
+}
+
+type metricsTracer struct {
+	metrics *metrics
+}
+
+var _ logging.Tracer = &metricsTracer{}
+
+func (t *metricsTracer) TracerForConnection(context.Context, logging.Perspective, logging.ConnectionID) logging.ConnectionTracer {
+	return &metricsConnTracer{metrics: t.metrics}
+}
+
+func (t *metricsTracer) SentPacket(net.Addr, *logging.Header, logging.ByteCount, []logging.Frame) {}
+func (t *metricsTracer) DroppedPacket(net.Addr, logging.PacketType, logging.ByteCount, logging.PacketDropReason) {
+}
+
+type metricsConnTracer struct {
+	metrics *metrics
+	started bool
+}
+
+var _ logging.ConnectionTracer = &metricsConnTracer{}
+
+func (t *metricsConnTracer) StartedConnection(local, remote net.Addr, srcConnID, destConnID logging.ConnectionID) {
+	t.started = true
+	t.metrics.activeConns.Inc()
+}
+
+func (t *metricsConnTracer) NegotiatedVersion(chosen logging.VersionNumber, clientVersions, serverVersions []logging.VersionNumber) {
+}
+func (t *metricsConnTracer) ClosedConnection(error)                                   {}
+func (t *metricsConnTracer) SentTransportParameters(*logging.TransportParameters)     {}
+func (t *metricsConnTracer) ReceivedTransportParameters(*logging.TransportParameters) {}
+func (t *metricsConnTracer) RestoredTransportParameters(*logging.TransportParameters) {}
+func (t *metricsConnTracer) SentPacket(_ *logging.ExtendedHeader, size logging.ByteCount, _ *logging.AckFrame, _ []logging.Frame) {
+	t.metrics.bytesSent.Add(float64(size))
+}
+func (t *metricsConnTracer) ReceivedVersionNegotiationPacket(*logging.Header, []logging.VersionNumber) {
+}
+func (t *metricsConnTracer) ReceivedRetry(*logging.Header) {}
+func (t *metricsConnTracer) ReceivedPacket(_ *logging.ExtendedHeader, size logging.ByteCount, _ []logging.Frame) {
+	t.metrics.bytesReceived.Add(float64(size))
+}
+func (t *metricsConnTracer) BufferedPacket(logging.PacketType) {}
+func (t *metricsConnTracer) DroppedPacket(logging.PacketType, logging.ByteCount, logging.PacketDropReason) {
+}
+func (t *metricsConnTracer) UpdatedMetrics(*logging.RTTStats, logging.ByteCount, logging.ByteCount, int) {
+}
+func (t *metricsConnTracer) UpdatedCongestionMetrics(*logging.RTTStats, logging.ByteCount, logging.ByteCount, logging.ByteCount) {
+}
+func (t *metricsConnTracer) AcknowledgedPacket(logging.EncryptionLevel, logging.PacketNumber) {}
+func (t *metricsConnTracer) LostPacket(logging.EncryptionLevel, logging.PacketNumber, logging.PacketLossReason) {
+}
+func (t *metricsConnTracer) UpdatedCongestionState(logging.CongestionState)                 {}
+func (t *metricsConnTracer) UpdatedCongestionControl(string, string)                        {}
+func (t *metricsConnTracer) UpdatedPTOCount(uint32)                                         {}
+func (t *metricsConnTracer) UpdatedMTU(logging.ByteCount)                                   {}
+func (t *metricsConnTracer) UpdatedSpinBitRTT(time.Duration)                                {}
+func (t *metricsConnTracer) UpdatedKeyFromTLS(logging.EncryptionLevel, logging.Perspective) {}
+func (t *metricsConnTracer) UpdatedKey(logging.KeyPhase, bool)                              {}
+
+// The Handshake keys are dropped when the handshake is confirmed.
+func (t *metricsConnTracer) DroppedEncryptionLevel(encLevel logging.EncryptionLevel) {
+	if encLevel == logging.EncryptionHandshake {
+		t.metrics.handshakes.Inc()
+	}
+}
+func (t *metricsConnTracer) DroppedKey(logging.KeyPhase)                                        {}
+func (t *metricsConnTracer) SetLossTimer(logging.TimerType, logging.EncryptionLevel, time.Time) {}
+func (t *metricsConnTracer) LossTimerExpired(logging.TimerType, logging.EncryptionLevel)        {}
+func (t *metricsConnTracer) LossTimerCanceled()                                                 {}
+
+func (t *metricsConnTracer) Close() {
+	if t.started {
+		t.metrics.activeConns.Dec()
+	}
+}
+
+func (t *metricsConnTracer) Debug(name, msg string) {}
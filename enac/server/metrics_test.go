@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+	"github.com/lucas-clemente/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type sample struct {
+	name   string
+	labels map[string]string
+	value  float64
+}
+
+type metricFamily struct {
+	typ     string
+	help    string
+	samples []sample
+}
+
+var (
+	sampleRegexp = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)(?:\{(.*)\})? (\S+)$`)
+	labelRegexp  = regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_]*)="((?:[^"\\]|\\.)*)"$`)
+)
+
+// parseMetrics parses the Prometheus text format.
+// It fails if the output is malformed, or if a sample doesn't belong to a metric family declared by a TYPE line.
+func parseMetrics(r io.Reader) map[string]*metricFamily {
+	families := make(map[string]*metricFamily)
+	getFamily := func(name string) *metricFamily {
+		if _, ok := families[name]; !ok {
+			families[name] = &metricFamily{}
+		}
+		return families[name]
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "# ") {
+			fields := strings.SplitN(line, " ", 4)
+			ExpectWithOffset(1, fields).To(HaveLen(4), fmt.Sprintf("malformed line: %q", line))
+			switch fields[1] {
+			case "HELP":
+				getFamily(fields[2]).help = fields[3]
+			case "TYPE":
+				ExpectWithOffset(1, fields[3]).To(BeElementOf("counter", "gauge", "histogram"))
+				f := getFamily(fields[2])
+				ExpectWithOffset(1, f.samples).To(BeEmpty(), fmt.Sprintf("TYPE of %s declared after its samples", fields[2]))
+				f.typ = fields[3]
+			default:
+				Fail(fmt.Sprintf("unexpected comment: %q", line))
+			}
+			continue
+		}
+		m := sampleRegexp.FindStringSubmatch(line)
+		ExpectWithOffset(1, m).ToNot(BeNil(), fmt.Sprintf("malformed line: %q", line))
+		s := sample{name: m[1], labels: make(map[string]string)}
+		if len(m[2]) > 0 {
+			for _, l := range strings.Split(m[2], ",") {
+				lm := labelRegexp.FindStringSubmatch(l)
+				ExpectWithOffset(1, lm).ToNot(BeNil(), fmt.Sprintf("malformed label: %q", l))
+				s.labels[lm[1]] = lm[2]
+			}
+		}
+		var err error
+		s.value, err = strconv.ParseFloat(m[3], 64)
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		familyName := s.name
+		for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+			if f, ok := families[strings.TrimSuffix(s.name, suffix)]; ok && f.typ == "histogram" {
+				familyName = strings.TrimSuffix(s.name, suffix)
+			}
+		}
+		f, ok := families[familyName]
+		ExpectWithOffset(1, ok && len(f.typ) > 0).To(BeTrue(), fmt.Sprintf("no TYPE declared for %s", s.name))
+		f.samples = append(f.samples, s)
+	}
+	ExpectWithOffset(1, scanner.Err()).ToNot(HaveOccurred())
+	return families
+}
+
+// checkHistogram checks that the buckets of a histogram are cumulative,
+// and that they are consistent with the count.
+// It returns the bucket counts, indexed by the upper bound.
+func checkHistogram(f *metricFamily) map[float64]float64 {
+	ExpectWithOffset(1, f.typ).To(Equal("histogram"))
+	buckets := make(map[float64]float64)
+	lastBound := math.Inf(-1)
+	var lastCount, count float64
+	var haveCount, haveSum bool
+	for _, s := range f.samples {
+		switch {
+		case strings.HasSuffix(s.name, "_bucket"):
+			bound, err := strconv.ParseFloat(s.labels["le"], 64)
+			ExpectWithOffset(1, err).ToNot(HaveOccurred())
+			ExpectWithOffset(1, bound).To(BeNumerically(">", lastBound))
+			ExpectWithOffset(1, s.value).To(BeNumerically(">=", lastCount))
+			buckets[bound] = s.value
+			lastBound = bound
+			lastCount = s.value
+		case strings.HasSuffix(s.name, "_count"):
+			count = s.value
+			haveCount = true
+		case strings.HasSuffix(s.name, "_sum"):
+			haveSum = true
+		}
+	}
+	ExpectWithOffset(1, haveCount).To(BeTrue())
+	ExpectWithOffset(1, haveSum).To(BeTrue())
+	ExpectWithOffset(1, lastBound).To(Equal(math.Inf(1)))
+	ExpectWithOffset(1, lastCount).To(Equal(count))
+	return buckets
+}
+
+var _ = Describe("Metrics", func() {
+	var (
+		m        *metrics
+		mockCtrl *gomock.Controller
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		m = newMetrics()
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	scrape := func() map[string]*metricFamily {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(HavePrefix("text/plain; version=0.0.4"))
+		return parseMetrics(rec.Body)
+	}
+
+	It("exposes all metrics, with help texts", func() {
+		sess := mockquic.NewMockEarlySession(mockCtrl)
+		sess.EXPECT().Context().Return(context.Background()).AnyTimes()
+		sess.EXPECT().CongestionState().Return(quic.CongestionState{CongestionControl: "cubic"}).AnyTimes()
+		sess.EXPECT().RTTStats().Return(quic.RTTStats{}).AnyTimes()
+		sess.EXPECT().StreamStats().AnyTimes()
+		m.AddSession(sess)
+		m.sample()
+		families := scrape()
+		Expect(families).To(HaveLen(8))
+		for name, f := range families {
+			Expect(f.help).ToNot(BeEmpty(), fmt.Sprintf("no help text for %s", name))
+			Expect(f.samples).ToNot(BeEmpty(), fmt.Sprintf("no samples for %s", name))
+		}
+		Expect(families["quic_connections_active"].typ).To(Equal("gauge"))
+		Expect(families["quic_handshakes_total"].typ).To(Equal("counter"))
+		Expect(families["quic_sent_bytes_total"].typ).To(Equal("counter"))
+		Expect(families["quic_received_bytes_total"].typ).To(Equal("counter"))
+		Expect(families["quic_streams_send_blocked"].typ).To(Equal("gauge"))
+		Expect(families["quic_streams_receive_blocked"].typ).To(Equal("gauge"))
+		Expect(checkHistogram(families["quic_congestion_window_bytes"])).To(HaveLen(len(congestionWindowBuckets) + 1))
+		Expect(checkHistogram(families["quic_smoothed_rtt_seconds"])).To(HaveLen(len(rttBuckets) + 1))
+	})
+
+	It("counts connections, handshakes and bytes", func() {
+		tracer := m.Tracer().TracerForConnection(context.Background(), logging.PerspectiveServer, nil)
+		tracer.StartedConnection(nil, nil, nil, nil)
+		tracer.SentPacket(nil, 1200, nil, nil)
+		tracer.SentPacket(nil, 1000, nil, nil)
+		tracer.ReceivedPacket(nil, 500, nil)
+		tracer.DroppedEncryptionLevel(logging.EncryptionInitial)
+		tracer.DroppedEncryptionLevel(logging.EncryptionHandshake)
+		families := scrape()
+		Expect(families["quic_connections_active"].samples).To(Equal([]sample{{name: "quic_connections_active", labels: map[string]string{}, value: 1}}))
+		Expect(families["quic_handshakes_total"].samples[0].value).To(Equal(1.))
+		Expect(families["quic_sent_bytes_total"].samples[0].value).To(Equal(2200.))
+		Expect(families["quic_received_bytes_total"].samples[0].value).To(Equal(500.))
+		tracer.Close()
+		Expect(scrape()["quic_connections_active"].samples[0].value).To(BeZero())
+	})
+
+	It("samples the congestion state and the flow control state of the sessions", func() {
+		sess := mockquic.NewMockEarlySession(mockCtrl)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		sess.EXPECT().Context().Return(ctx).AnyTimes()
+		sess.EXPECT().CongestionState().Return(quic.CongestionState{CongestionControl: "bbr", CongestionWindow: 100 << 10}).AnyTimes()
+		sess.EXPECT().RTTStats().Return(quic.RTTStats{SmoothedRTT: 20 * time.Millisecond}).AnyTimes()
+		sess.EXPECT().StreamStats().Return([]quic.StreamStats{
+			{StreamID: 0, BlockedOnFlowControl: true},
+			{StreamID: 4, BytesReceived: 1000, ReceiveWindow: 1000},
+			{StreamID: 8, BytesReceived: 500, ReceiveWindow: 1000},
+		}).AnyTimes()
+		m.AddSession(sess)
+		m.sample()
+
+		families := scrape()
+		Expect(families["quic_streams_send_blocked"].samples[0].value).To(Equal(1.))
+		Expect(families["quic_streams_receive_blocked"].samples[0].value).To(Equal(1.))
+		cwnd := families["quic_congestion_window_bytes"]
+		Expect(checkHistogram(cwnd)).To(Equal(map[float64]float64{
+			16 << 10: 0, 32 << 10: 0, 64 << 10: 0, 128 << 10: 1, 256 << 10: 1, 512 << 10: 1,
+			1 << 20: 1, 2 << 20: 1, 4 << 20: 1, 8 << 20: 1, 16 << 20: 1, math.Inf(1): 1,
+		}))
+		for _, s := range cwnd.samples {
+			Expect(s.labels).To(HaveKeyWithValue("algorithm", "bbr"))
+			if strings.HasSuffix(s.name, "_sum") {
+				Expect(s.value).To(Equal(float64(100 << 10)))
+			}
+		}
+		rtt := checkHistogram(families["quic_smoothed_rtt_seconds"])
+		Expect(rtt[0.01]).To(BeZero())
+		Expect(rtt[0.025]).To(Equal(1.))
+
+		// closed sessions are not sampled any more
+		cancel()
+		Eventually(func() int {
+			m.mutex.Lock()
+			defer m.mutex.Unlock()
+			return len(m.sessions)
+		}).Should(BeZero())
+		m.sample()
+		Expect(checkHistogram(scrape()["quic_congestion_window_bytes"])[math.Inf(1)]).To(Equal(1.))
+	})
+})
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"crypto/md5"
 	"crypto/tls"
 	"errors"
@@ -13,30 +14,22 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	_ "net/http/pprof"
 
 	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/congestion"
 	"github.com/lucas-clemente/quic-go/http3"
-	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/logging"
 	"github.com/lucas-clemente/quic-go/qlog"
-)
-
-var (
-	congestionMap = map[string]congestion.CongestionControlType{
-		"newreno": congestion.NewRenoControlType,
-		"cubic":   congestion.CubicControlType,
-	}
-	hystartMap = map[string]congestion.HystartControlType{
-		"standard": congestion.HystartTypeStandard,
-		"plusplus": congestion.HystartTypePlusPlus,
-		"none":     congestion.HystartTypeNone,
-	}
+	"github.com/lucas-clemente/quic-go/webtransport"
 )
 
 type binds []string
@@ -50,9 +43,16 @@ func (b *binds) Set(v string) error {
 	return nil
 }
 
-// Size is needed by the /demo/upload handler to determine the size of the uploaded file
-type Size interface {
-	Size() int64
+// files is a list of file paths, given by repeating a flag
+type files []string
+
+func (f files) String() string {
+	return strings.Join(f, ",")
+}
+
+func (f *files) Set(v string) error {
+	*f = append(*f, v)
+	return nil
 }
 
 // See https://en.wikipedia.org/wiki/Lehmer_random_number_generator
@@ -66,11 +66,135 @@ func generatePRData(l int) []byte {
 	return res
 }
 
-func setupHandler(www string) http.Handler {
+// pushResources pushes the given resources when / is requested, if the client supports server push.
+func pushResources(resources []string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if pusher, ok := w.(http.Pusher); ok && r.URL.Path == "/" {
+			for _, res := range resources {
+				if err := pusher.Push(res, nil); err != nil {
+					utils.DefaultLogger.Debugf("Not pushing %s: %s", res, err)
+					break
+				}
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// echoWebTransport echoes all streams and datagrams of a WebTransport session, until the session is closed.
+func echoWebTransport(sess *webtransport.Session) {
+	ctx := sess.Context()
+	go func() {
+		for {
+			str, err := sess.AcceptStream(ctx)
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(str, str)
+				str.Close()
+			}()
+		}
+	}()
+	go func() {
+		for {
+			rstr, err := sess.AcceptUniStream(ctx)
+			if err != nil {
+				return
+			}
+			go func() {
+				str, err := sess.OpenUniStreamSync(ctx)
+				if err != nil {
+					return
+				}
+				io.Copy(str, rstr)
+				str.Close()
+			}()
+		}
+	}()
+	for {
+		data, err := sess.ReceiveDatagram(ctx)
+		if err != nil {
+			utils.DefaultLogger.Infof("WebTransport session closed: %s", err)
+			return
+		}
+		sess.SendDatagram(data)
+	}
+}
+
+// sessionTracker tracks the open sessions of an HTTP/3 server.
+type sessionTracker struct {
+	mutex    sync.Mutex
+	sessions map[quic.Session]struct{}
+}
+
+func newSessionTracker() *sessionTracker {
+	return &sessionTracker{sessions: make(map[quic.Session]struct{})}
+}
+
+// Add adds a session. It is removed once it is closed.
+func (t *sessionTracker) Add(sess quic.Session) {
+	t.mutex.Lock()
+	t.sessions[sess] = struct{}{}
+	t.mutex.Unlock()
+	go func() {
+		<-sess.Context().Done()
+		t.mutex.Lock()
+		delete(t.sessions, sess)
+		t.mutex.Unlock()
+	}()
+}
+
+// Len returns the number of open sessions.
+func (t *sessionTracker) Len() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return len(t.sessions)
+}
+
+// logRequests logs every request handled by h, if debug logging is enabled.
+func logRequests(h http.Handler) http.Handler {
+	logger := utils.DefaultLogger
+	if !logger.Debug() {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		h.ServeHTTP(w, r)
+		duration := time.Since(start)
+
+		sess, ok := r.Context().Value(http3.SessionContextKey).(quic.Session)
+		if !ok {
+			var alpn string
+			if r.TLS != nil {
+				alpn = r.TLS.NegotiatedProtocol
+			}
+			logger.Debugf("%s %s (%s over TCP, ALPN: %q) from %s, took %s", r.Method, r.RequestURI, r.Proto, alpn, r.RemoteAddr, duration)
+			return
+		}
+		streamID, _ := r.Context().Value(http3.StreamIDContextKey).(quic.StreamID)
+		// log the congestion controller actually in use, since unsupported algorithms silently fall back to Cubic
+		congState := sess.CongestionState()
+		// Requests received in 0-RTT might be handled before the handshake is confirmed.
+		// The HandshakeConfirmed context is also cancelled when the session is closed.
+		handshakeConfirmed := sess.HandshakeConfirmed().Err() != nil && sess.Context().Err() == nil
+		// Don't wait for the handshake to complete, since that would delay the response to requests received in 0-RTT.
+		connState, err := sess.ConnectionStateErr()
+		if errors.Is(err, quic.ErrHandshakeNotComplete) {
+			logger.Debugf("%s %s (%s over QUIC, 0-RTT, handshake not complete, congestion control: %s (hystart: %s), stream %d) from %s, took %s",
+				r.Method, r.RequestURI, r.Proto, congState.CongestionControl, congState.Hystart, streamID, r.RemoteAddr, duration)
+			return
+		}
+		logger.Debugf("%s %s (%s over QUIC %s, ALPN: %q, 0-RTT: %t, handshake confirmed: %t, congestion control: %s (hystart: %s), stream %d) from %s, took %s",
+			r.Method, r.RequestURI, r.Proto, connState.Version, connState.TLS.NegotiatedProtocol, connState.Used0RTT, handshakeConfirmed, congState.CongestionControl, congState.Hystart, streamID, r.RemoteAddr, duration)
+	})
+}
+
+func setupHandler(www string, push []string, maxUpload int64, wtServer *webtransport.Server) http.Handler {
 	mux := http.NewServeMux()
 
 	if len(www) > 0 {
-		mux.Handle("/", http.FileServer(http.Dir(www)))
+		mux.Handle("/", pushResources(push, http.FileServer(http.Dir(www))))
 	} else {
 		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 			fmt.Printf("%#v\n", r)
@@ -85,26 +209,33 @@ func setupHandler(www string) http.Handler {
 	}
 
 	// accept file uploads and return the MD5 of the uploaded file
-	// maximum accepted file size is 1 GB
 	mux.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
-			err := r.ParseMultipartForm(1 << 30) // 1 GB
+			if r.ContentLength > maxUpload {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, maxUpload)
+			// files larger than 32 MB are stored in temporary files
+			err := r.ParseMultipartForm(32 << 20)
 			if err == nil {
+				defer r.MultipartForm.RemoveAll()
 				var file multipart.File
 				file, _, err = r.FormFile("uploadfile")
 				if err == nil {
-					var size int64
-					if sizeInterface, ok := file.(Size); ok {
-						size = sizeInterface.Size()
-						b := make([]byte, size)
-						file.Read(b)
-						md5 := md5.Sum(b)
-						fmt.Fprintf(w, "%x", md5)
+					defer file.Close()
+					hash := md5.New()
+					if _, err = io.Copy(hash, file); err == nil {
+						fmt.Fprintf(w, "%x", hash.Sum(nil))
 						return
 					}
-					err = errors.New("couldn't get uploaded file size")
 				}
 			}
+			// The length of the body is not known in advance if it is sent in chunks.
+			if isRequestBodyTooLarge(err) {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
 			utils.DefaultLogger.Infof("Error receiving upload: %#v", err)
 		}
 		io.WriteString(w, `<html><body><form action="/demo/upload" method="post" enctype="multipart/form-data">
@@ -113,21 +244,47 @@ func setupHandler(www string) http.Handler {
 			</form></body></html>`)
 	})
 
+	// echo server for WebTransport streams and datagrams
+	mux.HandleFunc("/wt", func(w http.ResponseWriter, r *http.Request) {
+		sess, err := wtServer.Upgrade(w, r)
+		if err != nil {
+			utils.DefaultLogger.Infof("WebTransport upgrade failed: %s", err)
+			return
+		}
+		go echoWebTransport(sess)
+	})
+
 	return mux
 }
 
-func ListenAndServe(addr, certFile, keyFile, www string, quicConf *quic.Config) error {
-	// Load certs
-	var err error
-	certs := make([]tls.Certificate, 1)
-	certs[0], err = tls.LoadX509KeyPair(certFile, keyFile)
-	if err != nil {
-		return err
+// isRequestBodyTooLarge says if err was caused by reading more than the limit of a http.MaxBytesReader.
+// http.MaxBytesError was only added in Go 1.19, and the error might be wrapped by the multipart reader.
+func isRequestBodyTooLarge(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "http: request body too large")
+}
+
+// selectCertificate selects the first certificate that is valid for the server name requested by the client (using SNI).
+// If there's no such certificate, the first certificate is used.
+func selectCertificate(certs []tls.Certificate, chi *tls.ClientHelloInfo) *tls.Certificate {
+	for i := range certs {
+		if chi.SupportsCertificate(&certs[i]) == nil {
+			return &certs[i]
+		}
 	}
+	return &certs[0]
+}
+
+// ListenAndServe serves HTTP/1.1, HTTP/2 and HTTP/3 (or only HTTP/3, if h3Only is set) on addr until stop is closed.
+// The servers are then shut down gracefully. Connections that are still open after drainTimeout are closed.
+func ListenAndServe(addr string, h3Only bool, certs []tls.Certificate, www string, push []string, maxUpload int64, quicConf *quic.Config, keyLog io.Writer, m *metrics, stop <-chan struct{}, drainTimeout time.Duration) error {
 	// We currently only use the cert-related stuff from tls.Config,
 	// so we don't need to make a full copy.
+	// The same config is used for the TCP and the QUIC listener.
 	tlsConfig := &tls.Config{
-		Certificates: certs,
+		GetCertificate: func(chi *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return selectCertificate(certs, chi), nil
+		},
+		KeyLogWriter: keyLog,
 	}
 
 	// Open the listeners
@@ -141,18 +298,21 @@ func ListenAndServe(addr, certFile, keyFile, www string, quicConf *quic.Config)
 	}
 	defer udpConn.Close()
 
-	tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
-	if err != nil {
-		return err
-	}
-	tcpConn, err := net.ListenTCP("tcp", tcpAddr)
-	if err != nil {
-		return err
-	}
-	defer tcpConn.Close()
+	var tlsConn net.Listener
+	if !h3Only {
+		tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
+		if err != nil {
+			return err
+		}
+		tcpConn, err := net.ListenTCP("tcp", tcpAddr)
+		if err != nil {
+			return err
+		}
+		defer tcpConn.Close()
 
-	tlsConn := tls.NewListener(tcpConn, tlsConfig)
-	defer tlsConn.Close()
+		tlsConn = tls.NewListener(tcpConn, tlsConfig)
+		defer tlsConn.Close()
+	}
 
 	// Start the servers
 	httpServer := &http.Server{
@@ -160,24 +320,42 @@ func ListenAndServe(addr, certFile, keyFile, www string, quicConf *quic.Config)
 		TLSConfig: tlsConfig,
 	}
 
-	quicServer := &http3.Server{
-		Server:     httpServer,
-		QuicConfig: quicConf,
+	wtServer := &webtransport.Server{
+		H3: http3.Server{
+			Server:     httpServer,
+			QuicConfig: quicConf,
+		},
+	}
+	quicServer := &wtServer.H3
+	// This is the only hook that exposes the sessions of the HTTP/3 server.
+	sessions := newSessionTracker()
+	quicServer.OnSettingsReceived = func(sess quic.Session, _ map[uint64]uint64) {
+		sessions.Add(sess)
+		if m != nil {
+			m.AddSession(sess)
+		}
 	}
 
-	handler := setupHandler(www)
-	httpServer.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		quicServer.SetQuicHeaders(w.Header())
-		handler.ServeHTTP(w, r)
-	})
+	handler := logRequests(setupHandler(www, push, maxUpload, wtServer))
+	if h3Only {
+		httpServer.Handler = handler
+	} else {
+		httpServer.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			quicServer.SetQuicHeaders(w.Header())
+			handler.ServeHTTP(w, r)
+		})
+	}
 
-	hErr := make(chan error)
-	qErr := make(chan error)
-	go func() {
-		hErr <- httpServer.Serve(tlsConn)
-	}()
+	var hErr chan error // stays nil if only HTTP/3 is served
+	qErr := make(chan error, 1)
+	if !h3Only {
+		hErr = make(chan error, 1)
+		go func() {
+			hErr <- httpServer.Serve(tlsConn)
+		}()
+	}
 	go func() {
-		qErr <- quicServer.Serve(udpConn)
+		qErr <- wtServer.Serve(udpConn)
 	}()
 
 	select {
@@ -185,9 +363,60 @@ func ListenAndServe(addr, certFile, keyFile, www string, quicConf *quic.Config)
 		quicServer.Close()
 		return err
 	case err := <-qErr:
-		// Cannot close the HTTP server or wait for requests to complete properly :/
+		if !h3Only {
+			httpServer.Close()
+		}
+		return err
+	case <-stop:
+	}
+
+	open := sessions.Len()
+	utils.DefaultLogger.Infof("Shutting down server on %s, draining %d connections\n", addr, open)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	forcedChan := make(chan int, 1)
+	timer := time.AfterFunc(drainTimeout, func() {
+		// Count the connections before they are closed by canceling the context.
+		forcedChan <- sessions.Len()
+		cancel()
+	})
+	if !h3Only {
+		go httpServer.Shutdown(ctx)
+	}
+	err = quicServer.Shutdown(ctx)
+	var forced int
+	if timer.Stop() {
+		cancel()
+	} else {
+		forced = <-forcedChan
+	}
+	utils.DefaultLogger.Infof("Server on %s shut down: %d connections drained, %d forcibly closed\n", addr, open-forced, forced)
+	if err != nil && !errors.Is(err, context.Canceled) {
 		return err
 	}
+	return nil
+}
+
+// createQlogFile creates a qlog file.
+// If appendToFile is set, an existing file is appended to instead of being truncated.
+func createQlogFile(filename string, compress, appendToFile bool) (io.WriteCloser, error) {
+	if compress {
+		filename += ".gz"
+	}
+	flags := os.O_RDWR | os.O_CREATE | os.O_TRUNC
+	if appendToFile {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	f, err := os.OpenFile(filename, flags, 0o666)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Creating qlog file %s.\n", filename)
+	w := utils.NewBufferedWriteCloser(bufio.NewWriter(f), f)
+	if compress {
+		return qlog.NewGzipWriteCloser(w), nil
+	}
+	return w, nil
 }
 
 func main() {
@@ -195,11 +424,24 @@ func main() {
 	bs := binds{}
 	flag.Var(&bs, "bind", "bind to")
 	www := flag.String("www", "", "www data")
+	push := flag.String("push", "", "comma-separated list of resources to push when / is requested (requires -www)")
 	enableQlog := flag.Bool("qlog", false, "output a qlog (in the same directory)")
-	certFile := flag.String("cert", "", "cert path")
-	keyFile := flag.String("key", "", "key path")
-	congestionAlgo := flag.String("congestion", "newreno", "congestion algorithm")
-	hystart := flag.String("hystart", "standard", "hystart algorithm")
+	qlogMaxSize := flag.Int64("qlog-max-size", 0, "start a new qlog file once a file exceeds this (uncompressed) size in bytes (0: no limit)")
+	qlogGzip := flag.Bool("qlog-gzip", false, "compress qlog files using gzip")
+	qlogSingleFile := flag.Bool("qlog-single-file", false, "write the qlogs of all connections into a single file (server.qlog), appending to it if it already exists")
+	qlogCategories := flag.String("qlog-categories", "", "comma-separated list of qlog event categories to record (connectivity, transport, security, recovery), all if empty")
+	h3Only := flag.Bool("h3-only", false, "only serve HTTP/3 (no TCP listener)")
+	maxUpload := flag.Int64("max-upload", 1<<30, "maximum size of a file uploaded to /upload, in bytes")
+	var certFiles, keyFiles files
+	flag.Var(&certFiles, "cert", "cert path (can be repeated, the certificate is then selected based on the SNI)")
+	flag.Var(&keyFiles, "key", "key path (repeated for every -cert)")
+	keyLogFile := flag.String("keylog", os.Getenv("SSLKEYLOGFILE"), "key log file, for decrypting captured traffic (default: $SSLKEYLOGFILE)")
+	congestionAlgo := flag.String("congestion", "newreno", "congestion algorithm (newreno, cubic, bbr or bbr2)")
+	hystart := flag.String("hystart", "standard", "hystart algorithm (standard, plusplus or none)")
+	maxStreams := flag.Int64("max-streams", 0, "maximum number of concurrent bidirectional streams a client may open (0: default, negative: none)")
+	maxUniStreams := flag.Int64("max-uni-streams", 0, "maximum number of concurrent unidirectional streams a client may open (0: default, negative: none)")
+	drainTimeout := flag.Duration("drain-timeout", 10*time.Second, "on SIGINT or SIGTERM, wait this long for running requests to complete before closing connections")
+	metricsAddr := flag.String("metrics-addr", "", "serve Prometheus metrics on http://<addr>/metrics (disabled if empty)")
 	flag.Parse()
 
 	logger := utils.DefaultLogger
@@ -211,35 +453,65 @@ func main() {
 	}
 	logger.SetLogTimeFormat("")
 
-	if *certFile == "" {
+	if len(certFiles) == 0 {
 		logger.Errorf("cert argument is required\n")
 		os.Exit(1)
-	} else if _, err := os.Stat(*certFile); os.IsNotExist(err) {
-		logger.Errorf("cert file %s does not exist\n", *certFile)
+	}
+	if len(keyFiles) != len(certFiles) {
+		logger.Errorf("got %d cert arguments, but %d key arguments\n", len(certFiles), len(keyFiles))
 		os.Exit(1)
 	}
+	certs := make([]tls.Certificate, len(certFiles))
+	for i, certFile := range certFiles {
+		keyFile := keyFiles[i]
+		if _, err := os.Stat(certFile); os.IsNotExist(err) {
+			logger.Errorf("cert file %s does not exist\n", certFile)
+			os.Exit(1)
+		}
+		if _, err := os.Stat(keyFile); os.IsNotExist(err) {
+			logger.Errorf("key file %s does not exist\n", keyFile)
+			os.Exit(1)
+		}
+		var err error
+		certs[i], err = tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			logger.Errorf("loading cert %s with key %s failed: %s\n", certFile, keyFile, err)
+			os.Exit(1)
+		}
+	}
 
-	if *keyFile == "" {
-		logger.Errorf("key argument is required\n")
-		os.Exit(1)
-	} else if _, err := os.Stat(*keyFile); os.IsNotExist(err) {
-		logger.Errorf("key file %s does not exist\n", *keyFile)
-		os.Exit(1)
+	var pushList []string
+	if len(*push) > 0 {
+		pushList = strings.Split(*push, ",")
 	}
 
 	if len(bs) == 0 {
 		bs = binds{"localhost:6121"}
 	}
 
-	quicConf := &quic.Config{}
-	congestionControl, found := congestionMap[*congestionAlgo]
-	if !found {
-		logger.Errorf("congestion control algo %s is not found\n", *congestionAlgo)
+	var keyLog io.Writer
+	if len(*keyLogFile) > 0 {
+		f, err := os.Create(*keyLogFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		logger.Infof("Writing TLS secrets to %s\n", *keyLogFile)
+		keyLog = f
+	}
+
+	quicConf := &quic.Config{
+		MaxIncomingStreams:    *maxStreams,
+		MaxIncomingUniStreams: *maxUniStreams,
+	}
+	congestionControl, err := congestion.ParseCongestionControl(*congestionAlgo)
+	if err != nil {
+		logger.Errorf("%s\n", err)
 		os.Exit(1)
 	}
-	hystartControl, found := hystartMap[*hystart]
-	if !found {
-		logger.Errorf("hystart algo %s is not found\n", *hystart)
+	hystartControl, err := congestion.ParseHystart(*hystart)
+	if err != nil {
+		logger.Errorf("%s\n", err)
 		os.Exit(1)
 	}
 	quicConf.Congestion = congestion.CongestionOptions{
@@ -248,17 +520,78 @@ func main() {
 	}
 
 	if *enableQlog {
-		quicConf.Tracer = qlog.NewTracer(func(_ logging.Perspective, connID []byte) io.WriteCloser {
-			filename := fmt.Sprintf("server_%x.qlog", connID)
-			f, err := os.Create(filename)
+		var qlogOpts qlog.Options
+		if len(*qlogCategories) > 0 {
+			for _, name := range strings.Split(*qlogCategories, ",") {
+				category, err := qlog.ParseCategory(name)
+				if err != nil {
+					logger.Errorf("%s\n", err)
+					os.Exit(1)
+				}
+				qlogOpts.Categories = append(qlogOpts.Categories, category)
+			}
+		}
+		if *qlogSingleFile {
+			// Every file written by the rotating writer would start with the trace of the first connection.
+			if *qlogMaxSize > 0 {
+				logger.Errorf("-qlog-max-size can't be used with -qlog-single-file\n")
+				os.Exit(1)
+			}
+			f, err := createQlogFile("server.qlog", *qlogGzip, true)
 			if err != nil {
 				log.Fatal(err)
 			}
-			log.Printf("Creating qlog file %s.\n", filename)
-			return utils.NewBufferedWriteCloser(bufio.NewWriter(f), f)
-		})
+			// The file is closed once the traces of all connections are complete.
+			shared := qlog.NewSharedWriter(f)
+			defer shared.Close()
+			quicConf.Tracer = qlog.NewTracerWithOptions(func(_ logging.Perspective, connID []byte) io.WriteCloser {
+				return shared.Writer(connID)
+			}, &qlogOpts)
+		} else {
+			quicConf.Tracer = qlog.NewTracerWithOptions(func(p logging.Perspective, connID []byte) io.WriteCloser {
+				filename := qlog.FileName(p, connID)
+				if *qlogMaxSize > 0 {
+					return qlog.NewRotatingWriter(func(index int) (io.WriteCloser, error) {
+						return createQlogFile(fmt.Sprintf("%s_%d.qlog", strings.TrimSuffix(filename, ".qlog"), index), *qlogGzip, false)
+					}, *qlogMaxSize)
+				}
+				f, err := createQlogFile(filename, *qlogGzip, false)
+				if err != nil {
+					log.Fatal(err)
+				}
+				return f
+			}, &qlogOpts)
+		}
+	}
+
+	var m *metrics
+	if len(*metricsAddr) > 0 {
+		m = newMetrics()
+		if quicConf.Tracer != nil {
+			quicConf.Tracer = logging.NewMultiplexedTracer(quicConf.Tracer, m.Tracer())
+		} else {
+			quicConf.Tracer = m.Tracer()
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", m)
+		go func() {
+			logger.Infof("Serving metrics on %s\n", *metricsAddr)
+			if err := http.ListenAndServe(*metricsAddr, mux); err != nil {
+				logger.Errorf("Serving metrics failed: %s\n", err)
+			}
+		}()
 	}
 
+	stop := make(chan struct{})
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-sigs
+		logger.Infof("Received %s, shutting down\n", sig)
+		signal.Stop(sigs)
+		close(stop)
+	}()
+
 	var wg sync.WaitGroup
 	wg.Add(len(bs))
 	for _, b := range bs {
@@ -267,7 +600,7 @@ func main() {
 			var err error
 
 			logger.Infof("Start server on %s\n", bCap)
-			err = ListenAndServe(b, *certFile, *keyFile, *www, quicConf)
+			err = ListenAndServe(bCap, *h3Only, certs, *www, pushList, *maxUpload, quicConf, keyLog, m, stop, *drainTimeout)
 			if err != nil {
 				fmt.Println(err)
 			}
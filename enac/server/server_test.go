@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/http3"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Request logging", func() {
+	var (
+		mockCtrl *gomock.Controller
+		buf      *bytes.Buffer
+		sess     *mockquic.MockEarlySession
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		sess = mockquic.NewMockEarlySession(mockCtrl)
+		buf = &bytes.Buffer{}
+		log.SetOutput(buf)
+		utils.DefaultLogger.SetLogLevel(utils.LogLevelDebug)
+	})
+
+	AfterEach(func() {
+		log.SetOutput(os.Stderr)
+		utils.DefaultLogger.SetLogLevel(utils.LogLevelNothing)
+		mockCtrl.Finish()
+	})
+
+	handle := func(h http.Handler) {
+		req := httptest.NewRequest(http.MethodGet, "/hello", nil)
+		ctx := context.WithValue(req.Context(), http3.SessionContextKey, quic.Session(sess))
+		ctx = context.WithValue(ctx, http3.StreamIDContextKey, quic.StreamID(4))
+		logRequests(h).ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+	}
+
+	It("logs requests after the handshake completed", func() {
+		confirmed, confirm := context.WithCancel(context.Background())
+		confirm()
+		sess.EXPECT().CongestionState().Return(quic.CongestionState{})
+		sess.EXPECT().HandshakeConfirmed().Return(confirmed)
+		sess.EXPECT().Context().Return(context.Background())
+		sess.EXPECT().ConnectionStateErr().Return(quic.ConnectionState{Version: quic.Version1}, nil)
+		handle(http.NotFoundHandler())
+		Expect(buf.String()).To(ContainSubstring("GET /hello (HTTP/1.1 over QUIC v1"))
+		Expect(buf.String()).To(ContainSubstring("handshake confirmed: true"))
+	})
+
+	It("doesn't wait for the handshake to complete", func() {
+		sess.EXPECT().CongestionState().Return(quic.CongestionState{})
+		sess.EXPECT().HandshakeConfirmed().Return(context.Background())
+		sess.EXPECT().ConnectionStateErr().Return(quic.ConnectionState{}, quic.ErrHandshakeNotComplete)
+		handle(http.NotFoundHandler())
+		Expect(buf.String()).To(ContainSubstring("GET /hello (HTTP/1.1 over QUIC, 0-RTT, handshake not complete"))
+	})
+})
+
+var _ = Describe("Uploads", func() {
+	const maxUpload = 1000
+
+	upload := func(size int, hideLength bool) *httptest.ResponseRecorder {
+		body := &bytes.Buffer{}
+		mw := multipart.NewWriter(body)
+		fw, err := mw.CreateFormFile("uploadfile", "file.dat")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = fw.Write(bytes.Repeat([]byte{'a'}, size))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mw.Close()).To(Succeed())
+		var r io.Reader = body
+		if hideLength {
+			r = io.MultiReader(body) // the length of the body is unknown, as for chunked requests
+		}
+		req := httptest.NewRequest(http.MethodPost, "/upload", r)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		rec := httptest.NewRecorder()
+		setupHandler("", nil, maxUpload, nil).ServeHTTP(rec, req)
+		return rec
+	}
+
+	It("returns the MD5 of the uploaded file", func() {
+		rec := upload(100, true)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(Equal(fmt.Sprintf("%x", md5.Sum(bytes.Repeat([]byte{'a'}, 100)))))
+	})
+
+	It("rejects uploads that are too large", func() {
+		Expect(upload(2*maxUpload, false).Code).To(Equal(http.StatusRequestEntityTooLarge))
+	})
+
+	It("rejects uploads that are too large, if the length of the body is unknown", func() {
+		rec := upload(2*maxUpload, true)
+		Expect(rec.Code).To(Equal(http.StatusRequestEntityTooLarge))
+	})
+})
@@ -1,6 +1,7 @@
 package quic
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/lucas-clemente/quic-go/internal/qerr"
@@ -22,23 +23,24 @@ type (
 )
 
 const (
-	NoError                   = qerr.NoError
-	InternalError             = qerr.InternalError
-	ConnectionRefused         = qerr.ConnectionRefused
-	FlowControlError          = qerr.FlowControlError
-	StreamLimitError          = qerr.StreamLimitError
-	StreamStateError          = qerr.StreamStateError
-	FinalSizeError            = qerr.FinalSizeError
-	FrameEncodingError        = qerr.FrameEncodingError
-	TransportParameterError   = qerr.TransportParameterError
-	ConnectionIDLimitError    = qerr.ConnectionIDLimitError
-	ProtocolViolation         = qerr.ProtocolViolation
-	InvalidToken              = qerr.InvalidToken
-	ApplicationErrorErrorCode = qerr.ApplicationErrorErrorCode
-	CryptoBufferExceeded      = qerr.CryptoBufferExceeded
-	KeyUpdateError            = qerr.KeyUpdateError
-	AEADLimitReached          = qerr.AEADLimitReached
-	NoViablePathError         = qerr.NoViablePathError
+	NoError                          = qerr.NoError
+	InternalError                    = qerr.InternalError
+	ConnectionRefused                = qerr.ConnectionRefused
+	FlowControlError                 = qerr.FlowControlError
+	StreamLimitError                 = qerr.StreamLimitError
+	StreamStateError                 = qerr.StreamStateError
+	FinalSizeError                   = qerr.FinalSizeError
+	FrameEncodingError               = qerr.FrameEncodingError
+	TransportParameterError          = qerr.TransportParameterError
+	ConnectionIDLimitError           = qerr.ConnectionIDLimitError
+	ProtocolViolation                = qerr.ProtocolViolation
+	InvalidToken                     = qerr.InvalidToken
+	ApplicationErrorErrorCode        = qerr.ApplicationErrorErrorCode
+	CryptoBufferExceeded             = qerr.CryptoBufferExceeded
+	KeyUpdateError                   = qerr.KeyUpdateError
+	AEADLimitReached                 = qerr.AEADLimitReached
+	NoViablePathError                = qerr.NoViablePathError
+	VersionNegotiationErrorErrorCode = qerr.VersionNegotiationErrorErrorCode
 )
 
 // A StreamError is used for Stream.CancelRead and Stream.CancelWrite.
@@ -56,3 +58,18 @@ func (e *StreamError) Is(target error) bool {
 func (e *StreamError) Error() string {
 	return fmt.Sprintf("stream %d canceled with error code %d", e.StreamID, e.ErrorCode)
 }
+
+// ErrDatagramQueueOverflow is returned by Session.SendDatagram if the datagram send queue was full
+// (see Config.DatagramSendQueueLen).
+// The datagram was queued nevertheless, but the oldest queued datagram was dropped.
+var ErrDatagramQueueOverflow = errors.New("datagram send queue full: dropped the oldest datagram")
+
+// A DatagramTooLargeError is returned by Session.SendDatagram if the payload is too large to be sent in a single DATAGRAM frame.
+type DatagramTooLargeError struct {
+	// MaxDataLen is the maximum payload size that can be sent.
+	MaxDataLen int64
+}
+
+func (e *DatagramTooLargeError) Error() string {
+	return fmt.Sprintf("DATAGRAM frame too large (max payload size: %d bytes)", e.MaxDataLen)
+}
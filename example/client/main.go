@@ -6,7 +6,6 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"flag"
-	"fmt"
 	"io"
 	"log"
 	"net/http"
@@ -57,8 +56,8 @@ func main() {
 
 	var qconf quic.Config
 	if *enableQlog {
-		qconf.Tracer = qlog.NewTracer(func(_ logging.Perspective, connID []byte) io.WriteCloser {
-			filename := fmt.Sprintf("client_%x.qlog", connID)
+		qconf.Tracer = qlog.NewTracer(func(p logging.Perspective, connID []byte) io.WriteCloser {
+			filename := qlog.FileName(p, connID)
 			f, err := os.Create(filename)
 			if err != nil {
 				log.Fatal(err)
@@ -132,6 +132,15 @@ func setupHandler(www string) http.Handler {
 	return mux
 }
 
+func createQlogFile(filename string) (io.WriteCloser, error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Creating qlog file %s.\n", filename)
+	return utils.NewBufferedWriteCloser(bufio.NewWriter(f), f), nil
+}
+
 func main() {
 	// defer profile.Start().Stop()
 	go func() {
@@ -145,6 +154,7 @@ func main() {
 	www := flag.String("www", "", "www data")
 	tcp := flag.Bool("tcp", false, "also listen on TCP")
 	enableQlog := flag.Bool("qlog", false, "output a qlog (in the same directory)")
+	qlogMaxSize := flag.Int64("qlog-max-size", 0, "start a new qlog file once a file exceeds this size in bytes (0: no limit)")
 	flag.Parse()
 
 	logger := utils.DefaultLogger
@@ -163,14 +173,18 @@ func main() {
 	handler := setupHandler(*www)
 	quicConf := &quic.Config{}
 	if *enableQlog {
-		quicConf.Tracer = qlog.NewTracer(func(_ logging.Perspective, connID []byte) io.WriteCloser {
-			filename := fmt.Sprintf("server_%x.qlog", connID)
-			f, err := os.Create(filename)
+		quicConf.Tracer = qlog.NewTracer(func(p logging.Perspective, connID []byte) io.WriteCloser {
+			filename := qlog.FileName(p, connID)
+			if *qlogMaxSize > 0 {
+				return qlog.NewRotatingWriter(func(index int) (io.WriteCloser, error) {
+					return createQlogFile(fmt.Sprintf("%s_%d.qlog", strings.TrimSuffix(filename, ".qlog"), index))
+				}, *qlogMaxSize)
+			}
+			f, err := createQlogFile(filename)
 			if err != nil {
 				log.Fatal(err)
 			}
-			log.Printf("Creating qlog file %s.\n", filename)
-			return utils.NewBufferedWriteCloser(bufio.NewWriter(f), f)
+			return f
 		})
 	}
 
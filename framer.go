@@ -18,6 +18,8 @@ type framer interface {
 
 	AddActiveStream(protocol.StreamID)
 	AppendStreamFrames([]ackhandler.Frame, protocol.ByteCount) ([]ackhandler.Frame, protocol.ByteCount)
+	SetStreamPriority(id protocol.StreamID, weight int, incremental bool)
+	RemoveStream(protocol.StreamID)
 
 	Handle0RTTRejection() error
 }
@@ -30,6 +32,8 @@ type framerI struct {
 
 	activeStreams map[protocol.StreamID]struct{}
 	streamQueue   []protocol.StreamID
+	// priorities of the streams that don't use the default priority
+	priorities map[protocol.StreamID]streamPriority
 
 	controlFrameMutex sync.Mutex
 	controlFrames     []wire.Frame
@@ -37,6 +41,18 @@ type framerI struct {
 
 var _ framer = &framerI{}
 
+// The streamPriority determines the order in which STREAM frames are packed.
+// Streams with a higher weight are sent first.
+// Streams of the same weight are interleaved if they are incremental,
+// otherwise they are sent one after the other.
+type streamPriority struct {
+	weight      int
+	incremental bool
+}
+
+// by default, all streams share the available bandwidth equally
+var defaultStreamPriority = streamPriority{incremental: true}
+
 func newFramer(
 	streamGetter streamGetter,
 	v protocol.VersionNumber,
@@ -44,6 +60,7 @@ func newFramer(
 	return &framerI{
 		streamGetter:  streamGetter,
 		activeStreams: make(map[protocol.StreamID]struct{}),
+		priorities:    make(map[protocol.StreamID]streamPriority),
 		version:       v,
 	}
 }
@@ -103,8 +120,13 @@ func (f *framerI) AppendStreamFrames(frames []ackhandler.Frame, maxLen protocol.
 		if protocol.MinStreamFrameSize+length > maxLen {
 			break
 		}
-		id := f.streamQueue[0]
-		f.streamQueue = f.streamQueue[1:]
+		idx := f.nextStreamIndex()
+		id := f.streamQueue[idx]
+		if idx == 0 {
+			f.streamQueue = f.streamQueue[1:]
+		} else {
+			f.streamQueue = append(f.streamQueue[:idx], f.streamQueue[idx+1:]...)
+		}
 		// This should never return an error. Better check it anyway.
 		// The stream will only be in the streamQueue, if it enqueued itself there.
 		str, err := f.streamGetter.GetOrOpenSendStream(id)
@@ -119,8 +141,12 @@ func (f *framerI) AppendStreamFrames(frames []ackhandler.Frame, maxLen protocol.
 		// the STREAM frame (which will always have the DataLen set).
 		remainingLen += quicvarint.Len(uint64(remainingLen))
 		frame, hasMoreData := str.popStreamFrame(remainingLen)
-		if hasMoreData { // put the stream back in the queue (at the end)
-			f.streamQueue = append(f.streamQueue, id)
+		if hasMoreData {
+			if f.priority(id).incremental { // put the stream back in the queue (at the end)
+				f.streamQueue = append(f.streamQueue, id)
+			} else { // continue sending this stream, before moving on to other streams of the same weight
+				f.streamQueue = append([]protocol.StreamID{id}, f.streamQueue...)
+			}
 		} else { // no more data to send. Stream is not active any more
 			delete(f.activeStreams, id)
 		}
@@ -144,6 +170,50 @@ func (f *framerI) AppendStreamFrames(frames []ackhandler.Frame, maxLen protocol.
 	return frames, length
 }
 
+// nextStreamIndex returns the index of the stream in the streamQueue that is sent next:
+// the first stream with the highest weight.
+// It must be called with the mutex held.
+func (f *framerI) nextStreamIndex() int {
+	if len(f.priorities) == 0 {
+		return 0
+	}
+	var idx int
+	maxWeight := f.priority(f.streamQueue[0]).weight
+	for i := 1; i < len(f.streamQueue); i++ {
+		if w := f.priority(f.streamQueue[i]).weight; w > maxWeight {
+			idx = i
+			maxWeight = w
+		}
+	}
+	return idx
+}
+
+// must be called with the mutex held
+func (f *framerI) priority(id protocol.StreamID) streamPriority {
+	if p, ok := f.priorities[id]; ok {
+		return p
+	}
+	return defaultStreamPriority
+}
+
+func (f *framerI) SetStreamPriority(id protocol.StreamID, weight int, incremental bool) {
+	p := streamPriority{weight: weight, incremental: incremental}
+	f.mutex.Lock()
+	if p == defaultStreamPriority {
+		delete(f.priorities, id)
+	} else {
+		f.priorities[id] = p
+	}
+	f.mutex.Unlock()
+}
+
+// RemoveStream is called when a stream is completed.
+func (f *framerI) RemoveStream(id protocol.StreamID) {
+	f.mutex.Lock()
+	delete(f.priorities, id)
+	f.mutex.Unlock()
+}
+
 func (f *framerI) Handle0RTTRejection() error {
 	f.mutex.Lock()
 	defer f.mutex.Unlock()
@@ -153,6 +223,9 @@ func (f *framerI) Handle0RTTRejection() error {
 	for id := range f.activeStreams {
 		delete(f.activeStreams, id)
 	}
+	for id := range f.priorities {
+		delete(f.priorities, id)
+	}
 	var j int
 	for i, frame := range f.controlFrames {
 		switch frame.(type) {
@@ -382,4 +382,80 @@ var _ = Describe("Framer", func() {
 			Expect(length).To(BeZero())
 		})
 	})
+
+	Context("prioritizing streams", func() {
+		It("pops STREAM frames from streams with a higher weight first", func() {
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil)
+			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil)
+			f1 := &wire.StreamFrame{StreamID: id1, Data: []byte("foobar")}
+			f2 := &wire.StreamFrame{StreamID: id2, Data: []byte("raboof")}
+			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f1}, false)
+			stream2.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f2}, false)
+			framer.SetStreamPriority(id2, 10, true)
+			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id2)
+			frames, _ := framer.AppendStreamFrames(nil, protocol.MinStreamFrameSize)
+			Expect(frames).To(HaveLen(1))
+			Expect(frames[0].Frame).To(Equal(f2))
+			frames, _ = framer.AppendStreamFrames(nil, protocol.MinStreamFrameSize)
+			Expect(frames).To(HaveLen(1))
+			Expect(frames[0].Frame).To(Equal(f1))
+		})
+
+		It("keeps sending a stream with a higher weight, as long as it has data", func() {
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil).Times(2)
+			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil)
+			f11 := &wire.StreamFrame{StreamID: id1, Data: []byte("foobar")}
+			f12 := &wire.StreamFrame{StreamID: id1, Data: []byte("foobaz")}
+			f2 := &wire.StreamFrame{StreamID: id2, Data: []byte("raboof")}
+			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f11}, true)
+			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f12}, false)
+			stream2.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f2}, false)
+			framer.SetStreamPriority(id1, 1, true)
+			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id2)
+			for _, f := range []*wire.StreamFrame{f11, f12, f2} {
+				frames, _ := framer.AppendStreamFrames(nil, protocol.MinStreamFrameSize)
+				Expect(frames).To(HaveLen(1))
+				Expect(frames[0].Frame).To(Equal(f))
+			}
+		})
+
+		It("sends non-incremental streams of the same weight one after the other", func() {
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil).Times(2)
+			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil)
+			f11 := &wire.StreamFrame{StreamID: id1, Data: []byte("foobar")}
+			f12 := &wire.StreamFrame{StreamID: id1, Data: []byte("foobaz")}
+			f2 := &wire.StreamFrame{StreamID: id2, Data: []byte("raboof")}
+			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f11}, true)
+			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f12}, false)
+			stream2.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f2}, false)
+			framer.SetStreamPriority(id1, 0, false)
+			framer.SetStreamPriority(id2, 0, false)
+			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id2)
+			for _, f := range []*wire.StreamFrame{f11, f12, f2} {
+				frames, _ := framer.AppendStreamFrames(nil, protocol.MinStreamFrameSize)
+				Expect(frames).To(HaveLen(1))
+				Expect(frames[0].Frame).To(Equal(f))
+			}
+		})
+
+		It("forgets the priority when a stream is removed", func() {
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil)
+			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil)
+			f1 := &wire.StreamFrame{StreamID: id1, Data: []byte("foobar")}
+			f2 := &wire.StreamFrame{StreamID: id2, Data: []byte("raboof")}
+			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f1}, false)
+			stream2.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f2}, false)
+			framer.SetStreamPriority(id2, 10, true)
+			framer.RemoveStream(id2)
+			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id2)
+			frames, _ := framer.AppendStreamFrames(nil, 1000)
+			Expect(frames).To(HaveLen(2))
+			Expect(frames[0].Frame).To(Equal(f1))
+			Expect(frames[1].Frame).To(Equal(f2))
+		})
+	})
 })
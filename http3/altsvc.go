@@ -0,0 +1,182 @@
+package http3
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the max-age of an Alt-Svc advertisement without a ma parameter, see section 3.1 of RFC 7838
+const defaultAltSvcMaxAge = 24 * time.Hour
+
+// An AltSvc is an alternative service advertised by an origin in the Alt-Svc header field (RFC 7838).
+type AltSvc struct {
+	// Protocol is the ALPN protocol ID, e.g. "h3".
+	Protocol string
+	// Host is the host of the alternative service.
+	// If empty, the alternative service is located on the host of the origin.
+	Host string
+	// Port is the UDP port of the alternative service.
+	Port uint16
+	// Expires is the time when the advertisement expires, as specified by the ma parameter.
+	Expires time.Time
+}
+
+// An AltSvcCache stores the alternative services advertised by origins, keyed by the authority (host:port) of the origin.
+// It is used by the RoundTripper to decide which requests can be sent using HTTP/3.
+// Applications can use their own implementation, e.g. to persist the alternative services across restarts.
+// Implementations must be safe for concurrent use.
+type AltSvcCache interface {
+	// Get returns the alternative services advertised by the origin.
+	// Expired alternative services may be returned, they are ignored by the RoundTripper.
+	Get(origin string) []AltSvc
+	// Put replaces the alternative services of the origin.
+	// If svcs is empty, the alternative services of the origin are removed.
+	Put(origin string, svcs []AltSvc)
+}
+
+type memoryAltSvcCache struct {
+	mutex   sync.Mutex
+	entries map[string][]AltSvc
+}
+
+var _ AltSvcCache = &memoryAltSvcCache{}
+
+// NewAltSvcCache creates an in-memory AltSvcCache.
+// Alternative services are removed once they expire.
+func NewAltSvcCache() AltSvcCache {
+	return &memoryAltSvcCache{entries: make(map[string][]AltSvc)}
+}
+
+func (c *memoryAltSvcCache) Get(origin string) []AltSvc {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	svcs := c.entries[origin]
+	now := time.Now()
+	valid := svcs[:0]
+	for _, svc := range svcs {
+		if now.Before(svc.Expires) {
+			valid = append(valid, svc)
+		}
+	}
+	if len(valid) == 0 {
+		delete(c.entries, origin)
+		return nil
+	}
+	c.entries[origin] = valid
+	return append([]AltSvc{}, valid...)
+}
+
+func (c *memoryAltSvcCache) Put(origin string, svcs []AltSvc) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if len(svcs) == 0 {
+		delete(c.entries, origin)
+		return
+	}
+	c.entries[origin] = append([]AltSvc{}, svcs...)
+}
+
+// parseAltSvc parses the value of the Alt-Svc header field, see section 3 of RFC 7838.
+// It returns clear = true if the origin invalidated all alternative services.
+// Invalid alternatives are skipped, as are invalid and unknown parameters.
+func parseAltSvc(value string, now time.Time) (svcs []AltSvc, clear bool) {
+	if strings.TrimSpace(value) == "clear" {
+		return nil, true
+	}
+	for _, alternative := range splitQuoted(value, ',') {
+		params := splitQuoted(alternative, ';')
+		i := strings.IndexByte(params[0], '=')
+		if i < 0 {
+			continue
+		}
+		protocol, err := url.PathUnescape(strings.TrimSpace(params[0][:i]))
+		if err != nil || protocol == "" {
+			continue
+		}
+		authority, ok := unquote(strings.TrimSpace(params[0][i+1:]))
+		if !ok {
+			continue
+		}
+		host, portStr, err := net.SplitHostPort(authority)
+		if err != nil {
+			continue
+		}
+		port, err := strconv.ParseUint(portStr, 10, 16)
+		if err != nil || port == 0 {
+			continue
+		}
+		svc := AltSvc{
+			Protocol: protocol,
+			Host:     host,
+			Port:     uint16(port),
+			Expires:  now.Add(defaultAltSvcMaxAge),
+		}
+		for _, param := range params[1:] {
+			i := strings.IndexByte(param, '=')
+			if i < 0 || strings.TrimSpace(param[:i]) != "ma" {
+				continue
+			}
+			v, ok := unquote(strings.TrimSpace(param[i+1:]))
+			if !ok {
+				continue
+			}
+			if ma, err := strconv.ParseUint(v, 10, 32); err == nil {
+				svc.Expires = now.Add(time.Duration(ma) * time.Second)
+			}
+		}
+		svcs = append(svcs, svc)
+	}
+	return svcs, false
+}
+
+// splitQuoted splits s at every sep that is not part of a quoted-string.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	var quoted, escaped bool
+	var start int
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && s[i] == '\\':
+			escaped = true
+		case s[i] == '"':
+			quoted = !quoted
+		case !quoted && s[i] == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unquote returns the value of a token or a quoted-string.
+// It returns false for an empty value, and for a malformed quoted-string.
+func unquote(s string) (string, bool) {
+	if len(s) == 0 {
+		return "", false
+	}
+	if s[0] != '"' {
+		return s, true
+	}
+	if len(s) < 2 || s[len(s)-1] != '"' {
+		return "", false
+	}
+	var b strings.Builder
+	for i := 1; i < len(s)-1; i++ {
+		if s[i] == '\\' {
+			i++
+			if i == len(s)-1 {
+				return "", false
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String(), true
+}
@@ -0,0 +1,135 @@
+package http3
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Alt-Svc", func() {
+	Context("parsing", func() {
+		now := time.Now()
+
+		It("parses an alternative service", func() {
+			svcs, clear := parseAltSvc(`h3=":443"`, now)
+			Expect(clear).To(BeFalse())
+			Expect(svcs).To(Equal([]AltSvc{{Protocol: "h3", Port: 443, Expires: now.Add(24 * time.Hour)}}))
+		})
+
+		It("parses the host", func() {
+			svcs, _ := parseAltSvc(`h3="alt.example.org:8443"`, now)
+			Expect(svcs).To(HaveLen(1))
+			Expect(svcs[0].Host).To(Equal("alt.example.org"))
+			Expect(svcs[0].Port).To(BeEquivalentTo(8443))
+		})
+
+		It("parses IPv6 hosts", func() {
+			svcs, _ := parseAltSvc(`h3="[::1]:443"`, now)
+			Expect(svcs).To(HaveLen(1))
+			Expect(svcs[0].Host).To(Equal("::1"))
+		})
+
+		It("parses the ma parameter", func() {
+			svcs, _ := parseAltSvc(`h3=":443"; ma=3600`, now)
+			Expect(svcs).To(HaveLen(1))
+			Expect(svcs[0].Expires).To(Equal(now.Add(time.Hour)))
+		})
+
+		It("parses a quoted ma parameter", func() {
+			svcs, _ := parseAltSvc(`h3=":443"; persist=1; ma="60"`, now)
+			Expect(svcs).To(HaveLen(1))
+			Expect(svcs[0].Expires).To(Equal(now.Add(time.Minute)))
+		})
+
+		It("ignores an invalid ma parameter", func() {
+			svcs, _ := parseAltSvc(`h3=":443"; ma=-1`, now)
+			Expect(svcs).To(HaveLen(1))
+			Expect(svcs[0].Expires).To(Equal(now.Add(24 * time.Hour)))
+		})
+
+		It("parses multiple alternative services", func() {
+			svcs, _ := parseAltSvc(`h3=":443"; ma=60, h3-29="alt.example.org:443", h2=":443"`, now)
+			Expect(svcs).To(Equal([]AltSvc{
+				{Protocol: "h3", Port: 443, Expires: now.Add(time.Minute)},
+				{Protocol: "h3-29", Host: "alt.example.org", Port: 443, Expires: now.Add(24 * time.Hour)},
+				{Protocol: "h2", Port: 443, Expires: now.Add(24 * time.Hour)},
+			}))
+		})
+
+		It("doesn't split at commas and semicolons in quoted strings", func() {
+			svcs, _ := parseAltSvc(`h3=":443"; foo="a,b;c", h3-29=":443"`, now)
+			Expect(svcs).To(HaveLen(2))
+			Expect(svcs[1].Protocol).To(Equal("h3-29"))
+		})
+
+		It("decodes percent-encoded protocol IDs", func() {
+			svcs, _ := parseAltSvc(`w%3Dx%3Ay=":443"`, now)
+			Expect(svcs).To(HaveLen(1))
+			Expect(svcs[0].Protocol).To(Equal("w=x:y"))
+		})
+
+		It("skips invalid alternative services", func() {
+			svcs, _ := parseAltSvc(`h3, h3=":0", h3="foo", h3=":http", =":443", h3-29=":443"`, now)
+			Expect(svcs).To(HaveLen(1))
+			Expect(svcs[0].Protocol).To(Equal("h3-29"))
+		})
+
+		It("skips alternative services with malformed quoted strings", func() {
+			svcs, _ := parseAltSvc(`h3=":443`, now)
+			Expect(svcs).To(BeEmpty())
+			svcs, _ = parseAltSvc(`h3=":443\"`, now)
+			Expect(svcs).To(BeEmpty())
+		})
+
+		It("parses clear", func() {
+			svcs, clear := parseAltSvc(" clear ", now)
+			Expect(clear).To(BeTrue())
+			Expect(svcs).To(BeEmpty())
+		})
+	})
+
+	Context("in-memory cache", func() {
+		var cache AltSvcCache
+
+		BeforeEach(func() {
+			cache = NewAltSvcCache()
+		})
+
+		It("stores alternative services per origin", func() {
+			svc := AltSvc{Protocol: "h3", Port: 443, Expires: time.Now().Add(time.Hour)}
+			cache.Put("example.org:443", []AltSvc{svc})
+			Expect(cache.Get("example.org:443")).To(Equal([]AltSvc{svc}))
+			Expect(cache.Get("example.com:443")).To(BeEmpty())
+		})
+
+		It("removes alternative services", func() {
+			cache.Put("example.org:443", []AltSvc{{Protocol: "h3", Port: 443, Expires: time.Now().Add(time.Hour)}})
+			cache.Put("example.org:443", nil)
+			Expect(cache.Get("example.org:443")).To(BeEmpty())
+		})
+
+		It("doesn't return expired alternative services", func() {
+			valid := AltSvc{Protocol: "h3", Port: 443, Expires: time.Now().Add(time.Hour)}
+			cache.Put("example.org:443", []AltSvc{
+				{Protocol: "h3-29", Port: 443, Expires: time.Now().Add(50 * time.Millisecond)},
+				valid,
+			})
+			Expect(cache.Get("example.org:443")).To(HaveLen(2))
+			Eventually(func() []AltSvc { return cache.Get("example.org:443") }).Should(Equal([]AltSvc{valid}))
+		})
+
+		It("removes origins once all alternative services expired", func() {
+			cache.Put("example.org:443", []AltSvc{{Protocol: "h3", Port: 443, Expires: time.Now().Add(-time.Second)}})
+			Expect(cache.Get("example.org:443")).To(BeEmpty())
+			Expect(cache.(*memoryAltSvcCache).entries).To(BeEmpty())
+		})
+
+		It("copies the alternative services", func() {
+			svcs := []AltSvc{{Protocol: "h3", Port: 443, Expires: time.Now().Add(time.Hour)}}
+			cache.Put("example.org:443", svcs)
+			svcs[0].Port = 1337
+			Expect(cache.Get("example.org:443")[0].Port).To(BeEquivalentTo(443))
+		})
+	})
+})
@@ -1,6 +1,7 @@
 package http3
 
 import (
+	"context"
 	"fmt"
 	"io"
 
@@ -19,10 +20,20 @@ type body struct {
 
 	onFrameError func()
 
+	// only set for the http.Response
+	// It parses the trailers, i.e. a HEADERS frame following the DATA frames.
+	parseTrailers func(*headersFrame) error
+	trailersRead  bool
+
+	datagrams *streamDatagrams // nil if HTTP datagrams are not enabled
+
 	bytesRemainingInFrame uint64
 }
 
-var _ io.ReadCloser = &body{}
+var (
+	_ io.ReadCloser = &body{}
+	_ Datagrammer   = &body{}
+)
 
 func newRequestBody(str quic.Stream, onFrameError func()) *body {
 	return &body{
@@ -51,13 +62,28 @@ func (r *body) readImpl(b []byte) (int, error) {
 	if r.bytesRemainingInFrame == 0 {
 	parseLoop:
 		for {
-			frame, err := parseNextFrame(r.str)
+			frame, err := parseNextFrame(r.str, nil)
 			if err != nil {
 				return 0, err
 			}
+			if r.trailersRead {
+				// the trailers must be the last frame on the stream
+				r.onFrameError()
+				return 0, fmt.Errorf("peer sent a frame after the trailers: %T", frame)
+			}
 			switch f := frame.(type) {
 			case *headersFrame:
-				// skip HEADERS frames
+				if r.parseTrailers == nil {
+					// skip HEADERS frames
+					if _, err := io.CopyN(io.Discard, r.str, int64(f.Length)); err != nil {
+						return 0, err
+					}
+					continue
+				}
+				if err := r.parseTrailers(f); err != nil {
+					return 0, err
+				}
+				r.trailersRead = true
 				continue
 			case *dataFrame:
 				r.bytesRemainingInFrame = f.Length
@@ -96,3 +122,17 @@ func (r *body) Close() error {
 	r.str.CancelRead(quic.StreamErrorCode(errorRequestCanceled))
 	return nil
 }
+
+func (r *body) SendDatagram(data []byte) error {
+	if r.datagrams == nil {
+		return errDatagramsNotEnabled
+	}
+	return r.datagrams.SendDatagram(data)
+}
+
+func (r *body) ReceiveDatagram(ctx context.Context) ([]byte, error) {
+	if r.datagrams == nil {
+		return nil, errDatagramsNotEnabled
+	}
+	return r.datagrams.ReceiveDatagram(ctx)
+}
@@ -2,6 +2,7 @@ package http3
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 
@@ -142,6 +143,39 @@ var _ = Describe("Body", func() {
 				Expect(b).To(Equal([]byte("foobar")))
 			})
 
+			It("parses trailers", func() {
+				var trailers *headersFrame
+				rb.parseTrailers = func(hf *headersFrame) error {
+					trailers = hf
+					_, err := io.CopyN(io.Discard, str, int64(hf.Length))
+					return err
+				}
+				buf.Write(getDataFrame([]byte("foobar")))
+				(&headersFrame{Length: 3}).Write(buf)
+				buf.Write([]byte("bar"))
+				data, err := io.ReadAll(rb)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte("foobar")))
+				Expect(trailers).To(Equal(&headersFrame{Length: 3}))
+			})
+
+			It("errors when the trailers are followed by another frame", func() {
+				rb.parseTrailers = func(hf *headersFrame) error { return nil }
+				(&headersFrame{}).Write(buf)
+				buf.Write(getDataFrame([]byte("foobar")))
+				_, err := rb.Read(make([]byte, 6))
+				Expect(err).To(MatchError("peer sent a frame after the trailers: *http3.dataFrame"))
+				Expect(errorCbCalled).To(BeTrue())
+			})
+
+			It("returns the error from parsing the trailers", func() {
+				testErr := errors.New("test error")
+				rb.parseTrailers = func(hf *headersFrame) error { return testErr }
+				(&headersFrame{}).Write(buf)
+				_, err := rb.Read(make([]byte, 6))
+				Expect(err).To(MatchError(testErr))
+			})
+
 			It("errors when it can't parse the frame", func() {
 				buf.Write([]byte("invalid"))
 				_, err := rb.Read([]byte{0})
@@ -7,8 +7,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/lucas-clemente/quic-go"
@@ -21,6 +23,8 @@ import (
 
 // MethodGet0RTT allows a GET request to be sent using 0-RTT.
 // Note that 0-RTT data doesn't provide replay protection.
+//
+// Deprecated: GET requests are sent using 0-RTT by default, if the session can be resumed.
 const MethodGet0RTT = "GET_0RTT"
 
 const (
@@ -36,10 +40,29 @@ var defaultQuicConfig = &quic.Config{
 
 var dialAddr = quic.DialAddrEarly
 
+// errGoAway is returned for requests that weren't processed by the server, because it is shutting down the connection.
+// These requests are retried by the RoundTripper on a new connection.
+var errGoAway = errors.New("http3: server sent GOAWAY")
+
+// errNoStreamAvailable is returned by tryRoundTrip if the server's stream limit is reached.
+// The request wasn't sent, and the RoundTripper sends it on a different connection.
+var errNoStreamAvailable = errors.New("http3: no stream available")
+
+// A dialError is returned if dialing the QUIC connection failed.
+// The request wasn't sent.
+type dialError struct {
+	err error
+}
+
+func (e *dialError) Error() string { return e.err.Error() }
+func (e *dialError) Unwrap() error { return e.err }
+
 type roundTripperOpts struct {
 	DisableCompression bool
 	EnableDatagram     bool
 	MaxHeaderBytes     int64
+	AdditionalSettings map[uint64]uint64
+	OnSettingsReceived func(quic.Session, map[uint64]uint64)
 }
 
 // client is a HTTP3 client doing requests
@@ -56,8 +79,18 @@ type client struct {
 
 	decoder *qpack.Decoder
 
-	hostname string
-	session  quic.EarlySession
+	hostname  string
+	addr      string // the address that is dialed, usually the hostname, but it might be an alternative service
+	session   quic.EarlySession
+	datagrams *datagramManager // nil if HTTP datagrams are not enabled
+
+	mutex          sync.Mutex
+	activeRequests int
+	goAwayReceived bool
+	goAwayID       quic.StreamID // requests on streams with this ID and higher won't be processed by the server
+	closing        bool          // set when the RoundTripper closes the idle connection
+
+	onIdle func() // called when the last request in flight completes, may be nil
 
 	logger utils.Logger
 }
@@ -90,8 +123,10 @@ func newClient(
 	// Replace existing ALPNs by H3
 	tlsConf.NextProtos = []string{versionToALPN(quicConfig.Versions[0])}
 
+	hostname = authorityAddr("https", hostname)
 	return &client{
-		hostname:      authorityAddr("https", hostname),
+		hostname:      hostname,
+		addr:          hostname,
 		tlsConf:       tlsConf,
 		requestWriter: newRequestWriter(logger),
 		decoder:       qpack.NewDecoder(func(hf qpack.HeaderField) {}),
@@ -105,23 +140,31 @@ func newClient(
 func (c *client) dial() error {
 	var err error
 	if c.dialer != nil {
-		c.session, err = c.dialer("udp", c.hostname, c.tlsConf, c.config)
+		c.session, err = c.dialer("udp", c.addr, c.tlsConf, c.config)
 	} else {
-		c.session, err = dialAddr(c.hostname, c.tlsConf, c.config)
+		c.session, err = dialAddr(c.addr, c.tlsConf, c.config)
 	}
 	if err != nil {
 		return err
 	}
 
+	if c.opts.EnableDatagram {
+		c.datagrams = newDatagramManager(c.session, c.logger)
+		go c.datagrams.run()
+	}
+
 	// send the SETTINGs frame, using 0-RTT data, if possible
+	setupDone := make(chan struct{})
 	go func() {
-		if err := c.setupSession(); err != nil {
+		defer close(setupDone)
+		// If 0-RTT is rejected, the control stream is opened again by handleUnidirectionalStreams.
+		if err := c.setupSession(); err != nil && err != quic.Err0RTTRejected {
 			c.logger.Debugf("Setting up session failed: %s", err)
 			c.session.CloseWithError(quic.ApplicationErrorCode(errorInternalError), "")
 		}
 	}()
 
-	go c.handleUnidirectionalStreams()
+	go c.handleUnidirectionalStreams(setupDone)
 	return nil
 }
 
@@ -134,14 +177,26 @@ func (c *client) setupSession() error {
 	buf := &bytes.Buffer{}
 	quicvarint.Write(buf, streamTypeControlStream)
 	// send the SETTINGS frame
-	(&settingsFrame{Datagram: c.opts.EnableDatagram}).Write(buf)
+	(&settingsFrame{Datagram: c.opts.EnableDatagram, other: c.opts.AdditionalSettings}).Write(buf)
 	_, err = str.Write(buf.Bytes())
 	return err
 }
 
-func (c *client) handleUnidirectionalStreams() {
+func (c *client) handleUnidirectionalStreams(setupDone <-chan struct{}) {
 	for {
 		str, err := c.session.AcceptUniStream(context.Background())
+		if err == quic.Err0RTTRejected {
+			// All streams opened during 0-RTT were lost, including our control stream.
+			// Once the handshake completes, open it again.
+			<-setupDone
+			c.session.NextSession()
+			if err := c.setupSession(); err != nil {
+				c.logger.Debugf("Setting up session failed: %s", err)
+				c.session.CloseWithError(quic.ApplicationErrorCode(errorInternalError), "")
+				return
+			}
+			continue
+		}
 		if err != nil {
 			c.logger.Debugf("accepting unidirectional stream failed: %s", err)
 			return
@@ -168,7 +223,7 @@ func (c *client) handleUnidirectionalStreams() {
 				str.CancelRead(quic.StreamErrorCode(errorStreamCreationError))
 				return
 			}
-			f, err := parseNextFrame(str)
+			f, err := parseNextFrame(str, nil)
 			if err != nil {
 				c.session.CloseWithError(quic.ApplicationErrorCode(errorFrameError), "")
 				return
@@ -178,19 +233,147 @@ func (c *client) handleUnidirectionalStreams() {
 				c.session.CloseWithError(quic.ApplicationErrorCode(errorMissingSettings), "")
 				return
 			}
-			if !sf.Datagram {
-				return
+			if c.datagrams != nil {
+				c.datagrams.handleSettings(sf.Datagram)
 			}
 			// If datagram support was enabled on our side as well as on the server side,
 			// we can expect it to have been negotiated both on the transport and on the HTTP/3 layer.
-			// Note: ConnectionState() will block until the handshake is complete (relevant when using 0-RTT).
-			if c.opts.EnableDatagram && !c.session.ConnectionState().SupportsDatagrams {
-				c.session.CloseWithError(quic.ApplicationErrorCode(errorSettingsError), "missing QUIC Datagram support")
+			if sf.Datagram && c.opts.EnableDatagram {
+				connState, err := connectionState(c.session)
+				if err != nil {
+					return
+				}
+				if !connState.SupportsDatagrams {
+					c.session.CloseWithError(quic.ApplicationErrorCode(errorSettingsError), "missing QUIC Datagram support")
+					return
+				}
+			}
+			if c.opts.OnSettingsReceived != nil {
+				c.opts.OnSettingsReceived(c.session, sf.other)
 			}
+			c.handleControlStreamFrames(str)
 		}()
 	}
 }
 
+// connectionState returns the connection state of the session.
+// When using 0-RTT, frames might be received before the handshake completes.
+// In that case, it waits for the handshake to complete.
+func connectionState(sess quic.EarlySession) (quic.ConnectionState, error) {
+	state, err := sess.ConnectionStateErr()
+	if !errors.Is(err, quic.ErrHandshakeNotComplete) {
+		return state, err
+	}
+	select {
+	case <-sess.HandshakeComplete().Done():
+	case <-sess.Context().Done():
+	}
+	return sess.ConnectionStateErr()
+}
+
+// handleControlStreamFrames handles the frames following the SETTINGS frame on the server's control stream.
+func (c *client) handleControlStreamFrames(str quic.ReceiveStream) {
+	for {
+		f, err := parseNextFrame(str, nil)
+		if err != nil {
+			c.logger.Debugf("reading from the control stream failed: %s", err)
+			return
+		}
+		switch f := f.(type) {
+		case *goAwayFrame:
+			if err := c.handleGoAway(quic.StreamID(f.StreamID)); err != nil {
+				c.session.CloseWithError(quic.ApplicationErrorCode(errorIDError), err.Error())
+				return
+			}
+		default:
+			c.session.CloseWithError(quic.ApplicationErrorCode(errorFrameUnexpected), "")
+			return
+		}
+	}
+}
+
+// handleGoAway is called when a GOAWAY frame is received.
+// The server may send multiple GOAWAY frames, but it must not increase the stream ID.
+// Once all requests have completed, the connection is closed.
+func (c *client) handleGoAway(id quic.StreamID) error {
+	if id%4 != 0 {
+		return fmt.Errorf("GOAWAY for invalid stream %d", id)
+	}
+	c.mutex.Lock()
+	if c.goAwayReceived && id > c.goAwayID {
+		c.mutex.Unlock()
+		return fmt.Errorf("GOAWAY increased the stream ID from %d to %d", c.goAwayID, id)
+	}
+	c.goAwayReceived = true
+	c.goAwayID = id
+	idle := c.activeRequests == 0
+	c.mutex.Unlock()
+	if idle {
+		c.session.CloseWithError(quic.ApplicationErrorCode(errorNoError), "")
+	}
+	return nil
+}
+
+// startRequest is called before opening a request stream.
+// It returns false if a GOAWAY frame was received, since no new requests must be sent after that,
+// or if the connection is being closed.
+func (c *client) startRequest() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.goAwayReceived || c.closing {
+		return false
+	}
+	c.activeRequests++
+	return true
+}
+
+// requestDone is called when a request completes.
+// After receiving a GOAWAY frame, the connection is closed when the last request completes.
+func (c *client) requestDone() {
+	c.mutex.Lock()
+	c.activeRequests--
+	idle := c.activeRequests == 0
+	goAway := c.goAwayReceived
+	c.mutex.Unlock()
+	if !idle {
+		return
+	}
+	if goAway {
+		c.session.CloseWithError(quic.ApplicationErrorCode(errorNoError), "")
+		return
+	}
+	if c.onIdle != nil {
+		c.onIdle()
+	}
+}
+
+// closeIfIdle closes the connection, if no requests are in flight.
+// Requests started afterwards fail with errGoAway, and are retried by the RoundTripper on a new connection.
+func (c *client) closeIfIdle() bool {
+	c.mutex.Lock()
+	if c.activeRequests > 0 {
+		c.mutex.Unlock()
+		return false
+	}
+	c.closing = true
+	c.mutex.Unlock()
+	c.Close()
+	return true
+}
+
+// wasRejected says if the request sent on the stream wasn't processed by the server,
+// either because the stream ID is above the GOAWAY threshold, or because the server rejected it.
+// Such requests can safely be retried on a new connection.
+func (c *client) wasRejected(str quic.Stream, err error) bool {
+	var serr *quic.StreamError
+	if errors.As(err, &serr) && serr.ErrorCode == quic.StreamErrorCode(errorRequestRejected) {
+		return true
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.goAwayReceived && str.StreamID() >= c.goAwayID
+}
+
 func (c *client) Close() error {
 	if c.session == nil {
 		return nil
@@ -207,6 +390,16 @@ func (c *client) maxHeaderBytes() uint64 {
 
 // RoundTrip executes a request and returns a response
 func (c *client) RoundTrip(req *http.Request) (*http.Response, error) {
+	return c.roundTripOpt(req, true)
+}
+
+// tryRoundTrip is like RoundTrip, but it doesn't wait for a stream to become available.
+// If the server's stream limit is reached, it returns errNoStreamAvailable, and the request is not sent.
+func (c *client) tryRoundTrip(req *http.Request) (*http.Response, error) {
+	return c.roundTripOpt(req, false)
+}
+
+func (c *client) roundTripOpt(req *http.Request, wait bool) (*http.Response, error) {
 	if authorityAddr("https", hostnameFromRequest(req)) != c.hostname {
 		return nil, fmt.Errorf("http3 client BUG: RoundTrip called for the wrong client (expected %s, got %s)", c.hostname, req.Host)
 	}
@@ -216,12 +409,16 @@ func (c *client) RoundTrip(req *http.Request) (*http.Response, error) {
 	})
 
 	if c.handshakeErr != nil {
-		return nil, c.handshakeErr
+		return nil, &dialError{err: c.handshakeErr}
 	}
 
-	// Immediately send out this request, if this is a 0-RTT request.
+	// Immediately send out this request, if it can be sent using 0-RTT.
+	var early bool
 	if req.Method == MethodGet0RTT {
 		req.Method = http.MethodGet
+		early = true
+	} else if isReplayable(req) {
+		early = true
 	} else {
 		// wait for the handshake to complete
 		select {
@@ -231,14 +428,66 @@ func (c *client) RoundTrip(req *http.Request) (*http.Response, error) {
 		}
 	}
 
-	str, err := c.session.OpenStreamSync(req.Context())
+	rsp, err := c.roundTrip(req, wait)
+	if !early || !errors.Is(err, quic.Err0RTTRejected) {
+		return rsp, err
+	}
+	// The server rejected 0-RTT, and didn't process the request.
+	// Send it again once the handshake completes.
+	c.session.NextSession()
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return nil, err
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		newReq := *req
+		newReq.Body = body
+		req = &newReq
+	}
+	// The request body might already have been consumed, so we can't hand the request back to the RoundTripper.
+	return c.roundTrip(req, true)
+}
+
+// isReplayable says if a request can be sent using 0-RTT.
+// 0-RTT data can be replayed by an attacker, so only requests using safe methods are sent in 0-RTT.
+// Applications can opt in for other requests by setting the Idempotency-Key or X-Idempotency-Key header,
+// following the same rules that net/http uses to decide if a request can be retried.
+// In both cases, the request body must either be empty, or it must be possible to rewind it using GetBody.
+func isReplayable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	if _, ok := req.Header["Idempotency-Key"]; ok {
+		return true
+	}
+	_, ok := req.Header["X-Idempotency-Key"]
+	return ok
+}
+
+func (c *client) roundTrip(req *http.Request, wait bool) (*http.Response, error) {
+	if !c.startRequest() {
+		return nil, errGoAway
+	}
+	str, err := c.openRequestStream(req.Context(), wait)
 	if err != nil {
+		c.requestDone()
 		return nil, err
 	}
 
 	// Request Cancellation:
 	// This go routine keeps running even after RoundTrip() returns.
 	// It is shut down when the application is done processing the body.
+	var datagrams *streamDatagrams
+	if c.datagrams != nil {
+		datagrams = c.datagrams.register(str.StreamID())
+	}
 	reqDone := make(chan struct{})
 	go func() {
 		select {
@@ -247,9 +496,13 @@ func (c *client) RoundTrip(req *http.Request) (*http.Response, error) {
 			str.CancelRead(quic.StreamErrorCode(errorRequestCanceled))
 		case <-reqDone:
 		}
+		if datagrams != nil {
+			c.datagrams.unregister(datagrams)
+		}
+		c.requestDone()
 	}()
 
-	rsp, rerr := c.doRequest(req, str, reqDone)
+	rsp, rerr := c.doRequest(req, str, datagrams, reqDone)
 	if rerr.err != nil { // if any error occurred
 		close(reqDone)
 		if rerr.streamErr != 0 { // if it was a stream error
@@ -262,13 +515,36 @@ func (c *client) RoundTrip(req *http.Request) (*http.Response, error) {
 			}
 			c.session.CloseWithError(quic.ApplicationErrorCode(rerr.connErr), reason)
 		}
+		if c.wasRejected(str, rerr.err) {
+			return nil, errGoAway
+		}
 	}
 	return rsp, rerr.err
 }
 
+// openRequestStream opens the stream for a request.
+// If wait is false, it returns errNoStreamAvailable instead of blocking when the server's stream limit is reached.
+// The stream limit is only known once the handshake completes (unless 0-RTT is used),
+// so until then, it waits for the stream in any case.
+func (c *client) openRequestStream(ctx context.Context, wait bool) (quic.Stream, error) {
+	if !wait {
+		select {
+		case <-c.session.HandshakeComplete().Done():
+			str, err := c.session.OpenStream()
+			if nerr, ok := err.(net.Error); ok && nerr.Temporary() {
+				return nil, errNoStreamAvailable
+			}
+			return str, err
+		default:
+		}
+	}
+	return c.session.OpenStreamSync(ctx)
+}
+
 func (c *client) doRequest(
 	req *http.Request,
 	str quic.Stream,
+	datagrams *streamDatagrams,
 	reqDone chan struct{},
 ) (*http.Response, requestError) {
 	var requestGzip bool
@@ -279,7 +555,7 @@ func (c *client) doRequest(
 		return nil, newStreamError(errorInternalError, err)
 	}
 
-	frame, err := parseNextFrame(str)
+	frame, err := parseNextFrame(str, nil)
 	if err != nil {
 		return nil, newStreamError(errorFrameError, err)
 	}
@@ -300,7 +576,11 @@ func (c *client) doRequest(
 		return nil, newConnError(errorGeneralProtocolError, err)
 	}
 
-	connState := qtls.ToTLSConnectionState(c.session.ConnectionState().TLS)
+	quicConnState, err := connectionState(c.session)
+	if err != nil {
+		return nil, newStreamError(errorInternalError, err)
+	}
+	connState := qtls.ToTLSConnectionState(quicConnState.TLS)
 	res := &http.Response{
 		Proto:      "HTTP/3",
 		ProtoMajor: 3,
@@ -323,6 +603,41 @@ func (c *client) doRequest(
 	respBody := newResponseBody(str, reqDone, func() {
 		c.session.CloseWithError(quic.ApplicationErrorCode(errorFrameUnexpected), "")
 	})
+	respBody.datagrams = datagrams
+	// The trailers announced in the Trailer header are added with nil values, as done by net/http.
+	// Their values are set when the trailers are received after the response body.
+	for _, v := range res.Header["Trailer"] {
+		for _, k := range strings.Split(v, ",") {
+			if k = http.CanonicalHeaderKey(strings.TrimSpace(k)); k != "" {
+				if res.Trailer == nil {
+					res.Trailer = make(http.Header)
+				}
+				res.Trailer[k] = nil
+			}
+		}
+	}
+	respBody.parseTrailers = func(hf *headersFrame) error {
+		if hf.Length > c.maxHeaderBytes() {
+			str.CancelRead(quic.StreamErrorCode(errorFrameError))
+			return fmt.Errorf("trailers HEADERS frame too large: %d bytes (max: %d)", hf.Length, c.maxHeaderBytes())
+		}
+		headerBlock := make([]byte, hf.Length)
+		if _, err := io.ReadFull(str, headerBlock); err != nil {
+			return err
+		}
+		hfs, err := c.decoder.DecodeFull(headerBlock)
+		if err != nil {
+			c.session.CloseWithError(quic.ApplicationErrorCode(errorGeneralProtocolError), "")
+			return err
+		}
+		if res.Trailer == nil {
+			res.Trailer = make(http.Header, len(hfs))
+		}
+		for _, hf := range hfs {
+			res.Trailer.Add(hf.Name, hf.Value)
+		}
+		return nil
+	}
 
 	// Rules for when to set Content-Length are defined in https://tools.ietf.org/html/rfc7230#section-3.3.2.
 	_, hasTransferEncoding := res.Header["Transfer-Encoding"]
@@ -10,6 +10,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/golang/mock/gomock"
@@ -25,6 +26,13 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+// temporaryError is the error returned when opening a stream is blocked by the peer's stream limit.
+type temporaryError struct{}
+
+func (temporaryError) Error() string   { return "too many open streams" }
+func (temporaryError) Timeout() bool   { return false }
+func (temporaryError) Temporary() bool { return true }
+
 var _ = Describe("Client", func() {
 	var (
 		client       *client
@@ -167,6 +175,53 @@ var _ = Describe("Client", func() {
 		Expect(client.Close()).To(Succeed())
 	})
 
+	It("considers requests above the GOAWAY threshold as rejected", func() {
+		testErr := errors.New("connection closed")
+		str := mockquic.NewMockStream(mockCtrl)
+		Expect(client.wasRejected(str, testErr)).To(BeFalse())
+		Expect(client.wasRejected(str, &quic.StreamError{ErrorCode: quic.StreamErrorCode(errorRequestRejected)})).To(BeTrue())
+		Expect(client.startRequest()).To(BeTrue())
+		Expect(client.handleGoAway(8)).To(Succeed())
+		str.EXPECT().StreamID().Return(quic.StreamID(8))
+		Expect(client.wasRejected(str, testErr)).To(BeTrue())
+		str.EXPECT().StreamID().Return(quic.StreamID(4))
+		Expect(client.wasRejected(str, testErr)).To(BeFalse())
+	})
+
+	It("closes the connection when the last request completes after receiving a GOAWAY frame", func() {
+		sess := mockquic.NewMockEarlySession(mockCtrl)
+		client.session = sess
+		Expect(client.startRequest()).To(BeTrue())
+		Expect(client.startRequest()).To(BeTrue())
+		Expect(client.handleGoAway(8)).To(Succeed())
+		Expect(client.startRequest()).To(BeFalse())
+		client.requestDone()
+		sess.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorNoError), "")
+		client.requestDone()
+	})
+
+	It("notifies the RoundTripper when the last request completes", func() {
+		var idle int
+		client.onIdle = func() { idle++ }
+		Expect(client.startRequest()).To(BeTrue())
+		Expect(client.startRequest()).To(BeTrue())
+		client.requestDone()
+		Expect(idle).To(BeZero())
+		client.requestDone()
+		Expect(idle).To(Equal(1))
+	})
+
+	It("only closes connections without requests in flight when closing idle connections", func() {
+		sess := mockquic.NewMockEarlySession(mockCtrl)
+		client.session = sess
+		Expect(client.startRequest()).To(BeTrue())
+		Expect(client.closeIfIdle()).To(BeFalse())
+		client.requestDone()
+		sess.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorNoError), "")
+		Expect(client.closeIfIdle()).To(BeTrue())
+		Expect(client.startRequest()).To(BeFalse())
+	})
+
 	Context("validating the address", func() {
 		It("refuses to do requests for the wrong host", func() {
 			req, err := http.NewRequest("https", "https://quic.clemente.io:1336/foobar.html", nil)
@@ -192,6 +247,7 @@ var _ = Describe("Client", func() {
 			request              *http.Request
 			sess                 *mockquic.MockEarlySession
 			settingsFrameWritten chan struct{}
+			controlStrData       []byte // written to our control stream, can be read once settingsFrameWritten is closed
 		)
 		testDone := make(chan struct{})
 
@@ -200,11 +256,11 @@ var _ = Describe("Client", func() {
 			controlStr := mockquic.NewMockStream(mockCtrl)
 			controlStr.EXPECT().Write(gomock.Any()).Do(func(b []byte) {
 				defer GinkgoRecover()
+				controlStrData = append([]byte{}, b...)
 				close(settingsFrameWritten)
 			})
 			sess = mockquic.NewMockEarlySession(mockCtrl)
 			sess.EXPECT().OpenUniStream().Return(controlStr, nil)
-			sess.EXPECT().HandshakeComplete().Return(handshakeCtx)
 			sess.EXPECT().OpenStreamSync(gomock.Any()).Return(nil, errors.New("done"))
 			dialAddr = func(hostname string, _ *tls.Config, _ *quic.Config) (quic.EarlySession, error) { return sess, nil }
 			var err error
@@ -235,6 +291,49 @@ var _ = Describe("Client", func() {
 			time.Sleep(scaleDuration(20 * time.Millisecond)) // don't EXPECT any calls to sess.CloseWithError
 		})
 
+		It("sends additional settings", func() {
+			client.opts.AdditionalSettings = map[uint64]uint64{0x1337: 42}
+			sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-testDone
+				return nil, errors.New("test done")
+			})
+			_, err := client.RoundTrip(request)
+			Expect(err).To(MatchError("done"))
+			Eventually(settingsFrameWritten).Should(BeClosed())
+			r := bytes.NewReader(controlStrData)
+			streamType, err := quicvarint.Read(r)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(streamType).To(BeEquivalentTo(streamTypeControlStream))
+			f, err := parseNextFrame(r, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(f).To(BeAssignableToTypeOf(&settingsFrame{}))
+			Expect(f.(*settingsFrame).other).To(Equal(map[uint64]uint64{0x1337: 42}))
+		})
+
+		It("passes the server's additional settings to the callback", func() {
+			received := make(chan map[uint64]uint64, 1)
+			client.opts.OnSettingsReceived = func(s quic.Session, settings map[uint64]uint64) {
+				defer GinkgoRecover()
+				Expect(s).To(Equal(sess))
+				received <- settings
+			}
+			buf := &bytes.Buffer{}
+			quicvarint.Write(buf, streamTypeControlStream)
+			(&settingsFrame{other: map[uint64]uint64{0x1337: 42, 0xdead: 0xbeef}}).Write(buf)
+			controlStr := mockquic.NewMockStream(mockCtrl)
+			controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+			sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				return controlStr, nil
+			})
+			sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-testDone
+				return nil, errors.New("test done")
+			})
+			_, err := client.RoundTrip(request)
+			Expect(err).To(MatchError("done"))
+			Eventually(received).Should(Receive(Equal(map[uint64]uint64{0x1337: 42, 0xdead: 0xbeef})))
+		})
+
 		for _, t := range []uint64{streamTypeQPACKEncoderStream, streamTypeQPACKDecoderStream} {
 			streamType := t
 			name := "encoder"
@@ -333,6 +432,133 @@ var _ = Describe("Client", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		It("stops sending requests after receiving a GOAWAY frame", func() {
+			buf := &bytes.Buffer{}
+			quicvarint.Write(buf, streamTypeControlStream)
+			(&settingsFrame{}).Write(buf)
+			(&goAwayFrame{StreamID: 8}).Write(buf)
+			controlStr := mockquic.NewMockStream(mockCtrl)
+			controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+			sendGoAway := make(chan struct{})
+			sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-sendGoAway
+				return controlStr, nil
+			})
+			sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-testDone
+				return nil, errors.New("test done")
+			})
+			// there are no active requests, so the connection is closed right away
+			closed := make(chan struct{})
+			sess.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorNoError), "").Do(func(quic.ApplicationErrorCode, string) { close(closed) })
+			_, err := client.RoundTrip(request)
+			Expect(err).To(MatchError("done"))
+			close(sendGoAway)
+			Eventually(closed).Should(BeClosed())
+			_, err = client.RoundTrip(request)
+			Expect(err).To(MatchError(errGoAway))
+		})
+
+		It("opens a new control stream when 0-RTT is rejected", func() {
+			written := make(chan struct{})
+			controlStr := mockquic.NewMockStream(mockCtrl)
+			controlStr.EXPECT().Write(gomock.Any()).DoAndReturn(func(b []byte) (int, error) {
+				close(written)
+				return len(b), nil
+			})
+			gomock.InOrder(
+				sess.EXPECT().AcceptUniStream(gomock.Any()).Return(nil, quic.Err0RTTRejected),
+				sess.EXPECT().NextSession(),
+				sess.EXPECT().OpenUniStream().Return(controlStr, nil),
+				sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+					<-testDone
+					return nil, errors.New("test done")
+				}),
+			)
+			_, err := client.RoundTrip(request)
+			Expect(err).To(MatchError("done"))
+			Eventually(written).Should(BeClosed())
+		})
+
+		It("errors when the server increases the stream ID in the GOAWAY frame", func() {
+			buf := &bytes.Buffer{}
+			quicvarint.Write(buf, streamTypeControlStream)
+			(&settingsFrame{}).Write(buf)
+			(&goAwayFrame{StreamID: 8}).Write(buf)
+			(&goAwayFrame{StreamID: 4}).Write(buf)
+			(&goAwayFrame{StreamID: 12}).Write(buf)
+			controlStr := mockquic.NewMockStream(mockCtrl)
+			controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+			sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				return controlStr, nil
+			})
+			sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-testDone
+				return nil, errors.New("test done")
+			})
+			sess.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorNoError), "").Times(2) // for the valid GOAWAY frames
+			done := make(chan struct{})
+			sess.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorIDError), gomock.Any()).Do(func(code quic.ApplicationErrorCode, reason string) {
+				defer GinkgoRecover()
+				Expect(reason).To(Equal("GOAWAY increased the stream ID from 4 to 12"))
+				close(done)
+			})
+			_, err := client.RoundTrip(request)
+			Expect(err).To(MatchError("done"))
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("errors when the GOAWAY frame contains an invalid stream ID", func() {
+			buf := &bytes.Buffer{}
+			quicvarint.Write(buf, streamTypeControlStream)
+			(&settingsFrame{}).Write(buf)
+			(&goAwayFrame{StreamID: 3}).Write(buf)
+			controlStr := mockquic.NewMockStream(mockCtrl)
+			controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+			sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				return controlStr, nil
+			})
+			sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-testDone
+				return nil, errors.New("test done")
+			})
+			done := make(chan struct{})
+			sess.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(code quic.ApplicationErrorCode, reason string) {
+				defer GinkgoRecover()
+				Expect(code).To(BeEquivalentTo(errorIDError))
+				Expect(reason).To(Equal("GOAWAY for invalid stream 3"))
+				close(done)
+			})
+			_, err := client.RoundTrip(request)
+			Expect(err).To(MatchError("done"))
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("errors when the server sends an unexpected frame on the control stream", func() {
+			buf := &bytes.Buffer{}
+			quicvarint.Write(buf, streamTypeControlStream)
+			(&settingsFrame{}).Write(buf)
+			(&maxPushIDFrame{PushID: 10}).Write(buf)
+			controlStr := mockquic.NewMockStream(mockCtrl)
+			controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+			sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				return controlStr, nil
+			})
+			sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-testDone
+				return nil, errors.New("test done")
+			})
+			done := make(chan struct{})
+			sess.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(code quic.ApplicationErrorCode, _ string) {
+				defer GinkgoRecover()
+				Expect(code).To(BeEquivalentTo(errorFrameUnexpected))
+				close(done)
+			})
+			_, err := client.RoundTrip(request)
+			Expect(err).To(MatchError("done"))
+			Eventually(done).Should(BeClosed())
+		})
+
 		It("errors when parsing the server opens a push stream", func() {
 			buf := &bytes.Buffer{}
 			quicvarint.Write(buf, streamTypePushStream)
@@ -358,6 +584,7 @@ var _ = Describe("Client", func() {
 
 		It("errors when the server advertises datagram support (and we enabled support for it)", func() {
 			client.opts.EnableDatagram = true
+			sess.EXPECT().ReceiveDatagram(gomock.Any()).Return(nil, errors.New("closed")).AnyTimes()
 			buf := &bytes.Buffer{}
 			quicvarint.Write(buf, streamTypeControlStream)
 			(&settingsFrame{Datagram: true}).Write(buf)
@@ -370,7 +597,7 @@ var _ = Describe("Client", func() {
 				<-testDone
 				return nil, errors.New("test done")
 			})
-			sess.EXPECT().ConnectionState().Return(quic.ConnectionState{SupportsDatagrams: false})
+			sess.EXPECT().ConnectionStateErr().Return(quic.ConnectionState{SupportsDatagrams: false}, nil)
 			done := make(chan struct{})
 			sess.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(code quic.ApplicationErrorCode, reason string) {
 				defer GinkgoRecover()
@@ -410,7 +637,7 @@ var _ = Describe("Client", func() {
 			fields := make(map[string]string)
 			decoder := qpack.NewDecoder(nil)
 
-			frame, err := parseNextFrame(str)
+			frame, err := parseNextFrame(str, nil)
 			ExpectWithOffset(1, err).ToNot(HaveOccurred())
 			ExpectWithOffset(1, frame).To(BeAssignableToTypeOf(&headersFrame{}))
 			headersFrame := frame.(*headersFrame)
@@ -468,11 +695,26 @@ var _ = Describe("Client", func() {
 			testErr := errors.New("stream open error")
 			sess.EXPECT().OpenStreamSync(context.Background()).Return(nil, testErr)
 			sess.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).MaxTimes(1)
-			sess.EXPECT().HandshakeComplete().Return(handshakeCtx)
 			_, err := client.RoundTrip(request)
 			Expect(err).To(MatchError(testErr))
 		})
 
+		It("doesn't wait for a stream if the stream limit is reached", func() {
+			sess.EXPECT().HandshakeComplete().Return(handshakeCtx)
+			sess.EXPECT().OpenStream().Return(nil, temporaryError{})
+			_, err := client.tryRoundTrip(request)
+			Expect(err).To(MatchError(errNoStreamAvailable))
+			Expect(client.activeRequests).To(BeZero())
+		})
+
+		It("waits for a stream if the stream limit isn't known before the handshake completes", func() {
+			testErr := errors.New("stream open error")
+			sess.EXPECT().HandshakeComplete().Return(context.Background())
+			sess.EXPECT().OpenStreamSync(context.Background()).Return(nil, testErr)
+			_, err := client.tryRoundTrip(request)
+			Expect(err).To(MatchError(testErr))
+		})
+
 		It("performs a 0-RTT request", func() {
 			testErr := errors.New("stream open error")
 			request.Method = MethodGet0RTT
@@ -490,12 +732,86 @@ var _ = Describe("Client", func() {
 			Expect(decodeHeader(buf)).To(HaveKeyWithValue(":method", "GET"))
 		})
 
+		It("waits for the handshake to complete before sending requests that can't be replayed", func() {
+			request.Method = http.MethodPost
+			ctx, handshakeDone := context.WithCancel(context.Background())
+			sess.EXPECT().HandshakeComplete().Return(ctx)
+			opened := make(chan struct{})
+			sess.EXPECT().OpenStreamSync(context.Background()).DoAndReturn(func(context.Context) (quic.Stream, error) {
+				close(opened)
+				return nil, errors.New("test done")
+			})
+			errChan := make(chan error)
+			go func() {
+				_, err := client.RoundTrip(request)
+				errChan <- err
+			}()
+			Consistently(opened).ShouldNot(BeClosed())
+			handshakeDone()
+			Eventually(errChan).Should(Receive(MatchError("test done")))
+		})
+
+		It("sends requests with an Idempotency-Key header using 0-RTT", func() {
+			request.Method = http.MethodPost
+			request.Header.Set("Idempotency-Key", "foobar")
+			// don't EXPECT any calls to HandshakeComplete()
+			sess.EXPECT().OpenStreamSync(context.Background()).Return(nil, errors.New("test done"))
+			_, err := client.RoundTrip(request)
+			Expect(err).To(MatchError("test done"))
+		})
+
+		It("resends the request after the handshake when 0-RTT is rejected", func() {
+			rspBuf := bytes.NewBuffer(getResponse(418))
+			gomock.InOrder(
+				sess.EXPECT().OpenStreamSync(context.Background()).Return(nil, quic.Err0RTTRejected),
+				sess.EXPECT().NextSession(),
+				sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil),
+				sess.EXPECT().ConnectionStateErr().Return(quic.ConnectionState{}, nil),
+			)
+			str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+			str.EXPECT().Close()
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+			rsp, err := client.RoundTrip(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.StatusCode).To(Equal(418))
+		})
+
+		It("rewinds the request body when resending the request", func() {
+			req, err := http.NewRequest(http.MethodPut, "https://quic.clemente.io:1337/upload", strings.NewReader("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			req.Header.Set("Idempotency-Key", "foobar")
+			getBody := req.GetBody
+			var rewound bool
+			req.GetBody = func() (io.ReadCloser, error) {
+				rewound = true
+				return getBody()
+			}
+			gomock.InOrder(
+				sess.EXPECT().OpenStreamSync(context.Background()).Return(nil, quic.Err0RTTRejected),
+				sess.EXPECT().NextSession(),
+				sess.EXPECT().OpenStreamSync(context.Background()).Return(nil, errors.New("test done")),
+			)
+			_, err = client.RoundTrip(req)
+			Expect(err).To(MatchError("test done"))
+			Expect(rewound).To(BeTrue())
+		})
+
+		It("doesn't resend the request if the body can't be rewound", func() {
+			request.Method = MethodGet0RTT
+			request.Body = &mockBody{}
+			gomock.InOrder(
+				sess.EXPECT().OpenStreamSync(context.Background()).Return(nil, quic.Err0RTTRejected),
+				sess.EXPECT().NextSession(),
+			)
+			_, err := client.RoundTrip(request)
+			Expect(err).To(MatchError(quic.Err0RTTRejected))
+		})
+
 		It("returns a response", func() {
 			rspBuf := bytes.NewBuffer(getResponse(418))
 			gomock.InOrder(
-				sess.EXPECT().HandshakeComplete().Return(handshakeCtx),
 				sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil),
-				sess.EXPECT().ConnectionState().Return(quic.ConnectionState{}),
+				sess.EXPECT().ConnectionStateErr().Return(quic.ConnectionState{}, nil),
 			)
 			str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
 			str.EXPECT().Close()
@@ -507,6 +823,65 @@ var _ = Describe("Client", func() {
 			Expect(rsp.StatusCode).To(Equal(418))
 		})
 
+		It("waits for the handshake to complete before returning a response received in 0-RTT", func() {
+			rspBuf := bytes.NewBuffer(getResponse(418))
+			handshakeCtx, handshakeDone := context.WithCancel(context.Background())
+			gomock.InOrder(
+				sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil),
+				sess.EXPECT().ConnectionStateErr().Return(quic.ConnectionState{}, quic.ErrHandshakeNotComplete),
+				sess.EXPECT().HandshakeComplete().Return(handshakeCtx),
+				sess.EXPECT().ConnectionStateErr().Return(quic.ConnectionState{Used0RTT: true}, nil),
+			)
+			sess.EXPECT().Context().Return(context.Background())
+			str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+			str.EXPECT().Close()
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				rsp, err := client.RoundTrip(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(418))
+			}()
+			Consistently(done).ShouldNot(BeClosed())
+			handshakeDone()
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("populates the trailers", func() {
+			rspBuf := &bytes.Buffer{}
+			rspBuf.Write(getHeadersFrame(map[string]string{
+				":status": "200",
+				"trailer": "Foo, bar, Baz",
+			}))
+			(&dataFrame{Length: 6}).Write(rspBuf)
+			rspBuf.Write([]byte("foobar"))
+			rspBuf.Write(getHeadersFrame(map[string]string{"foo": "1", "bar": "2"}))
+			sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil)
+			sess.EXPECT().ConnectionStateErr().Return(quic.ConnectionState{}, nil)
+			str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+			str.EXPECT().Close()
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+			rsp, err := client.RoundTrip(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.Trailer).To(Equal(http.Header{"Foo": nil, "Bar": nil, "Baz": nil}))
+			data, err := io.ReadAll(rsp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foobar")))
+			Expect(rsp.Trailer).To(Equal(http.Header{"Foo": []string{"1"}, "Bar": []string{"2"}, "Baz": nil}))
+		})
+
+		It("returns errGoAway when the server rejects the request", func() {
+			sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil)
+			str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+			str.EXPECT().Close()
+			str.EXPECT().CancelWrite(gomock.Any())
+			str.EXPECT().Read(gomock.Any()).Return(0, &quic.StreamError{ErrorCode: quic.StreamErrorCode(errorRequestRejected)})
+			_, err := client.RoundTrip(request)
+			Expect(err).To(MatchError(errGoAway))
+		})
+
 		Context("requests containing a Body", func() {
 			var strBuf *bytes.Buffer
 
@@ -571,7 +946,7 @@ var _ = Describe("Client", func() {
 				(&dataFrame{Length: 0x6}).Write(buf)
 				buf.Write([]byte("foobar"))
 				str.EXPECT().Close().Do(func() { close(done) })
-				sess.EXPECT().ConnectionState().Return(quic.ConnectionState{})
+				sess.EXPECT().ConnectionStateErr().Return(quic.ConnectionState{}, nil)
 				str.EXPECT().CancelWrite(gomock.Any()).MaxTimes(1) // when reading the response errors
 				// the response body is sent asynchronously, while already reading the response
 				str.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
@@ -610,6 +985,7 @@ var _ = Describe("Client", func() {
 			It("cancels a request while waiting for the handshake to complete", func() {
 				ctx, cancel := context.WithCancel(context.Background())
 				req := request.WithContext(ctx)
+				req.Method = http.MethodPost
 				sess.EXPECT().HandshakeComplete().Return(context.Background())
 
 				errChan := make(chan error)
@@ -625,7 +1001,6 @@ var _ = Describe("Client", func() {
 			It("cancels a request while the request is still in flight", func() {
 				ctx, cancel := context.WithCancel(context.Background())
 				req := request.WithContext(ctx)
-				sess.EXPECT().HandshakeComplete().Return(handshakeCtx)
 				sess.EXPECT().OpenStreamSync(ctx).Return(str, nil)
 				buf := &bytes.Buffer{}
 				str.EXPECT().Close().MaxTimes(1)
@@ -654,9 +1029,8 @@ var _ = Describe("Client", func() {
 
 				ctx, cancel := context.WithCancel(context.Background())
 				req := request.WithContext(ctx)
-				sess.EXPECT().HandshakeComplete().Return(handshakeCtx)
 				sess.EXPECT().OpenStreamSync(ctx).Return(str, nil)
-				sess.EXPECT().ConnectionState().Return(quic.ConnectionState{})
+				sess.EXPECT().ConnectionStateErr().Return(quic.ConnectionState{}, nil)
 				buf := &bytes.Buffer{}
 				str.EXPECT().Close().MaxTimes(1)
 
@@ -673,10 +1047,6 @@ var _ = Describe("Client", func() {
 		})
 
 		Context("gzip compression", func() {
-			BeforeEach(func() {
-				sess.EXPECT().HandshakeComplete().Return(handshakeCtx)
-			})
-
 			It("adds the gzip header to requests", func() {
 				sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil)
 				buf := &bytes.Buffer{}
@@ -711,7 +1081,7 @@ var _ = Describe("Client", func() {
 
 			It("decompresses the response", func() {
 				sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil)
-				sess.EXPECT().ConnectionState().Return(quic.ConnectionState{})
+				sess.EXPECT().ConnectionStateErr().Return(quic.ConnectionState{}, nil)
 				buf := &bytes.Buffer{}
 				rstr := mockquic.NewMockStream(mockCtrl)
 				rstr.EXPECT().Write(gomock.Any()).Do(buf.Write).AnyTimes()
@@ -737,7 +1107,7 @@ var _ = Describe("Client", func() {
 
 			It("only decompresses the response if the response contains the right content-encoding header", func() {
 				sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil)
-				sess.EXPECT().ConnectionState().Return(quic.ConnectionState{})
+				sess.EXPECT().ConnectionStateErr().Return(quic.ConnectionState{}, nil)
 				buf := &bytes.Buffer{}
 				rstr := mockquic.NewMockStream(mockCtrl)
 				rstr.EXPECT().Write(gomock.Any()).Do(buf.Write).AnyTimes()
@@ -757,4 +1127,31 @@ var _ = Describe("Client", func() {
 			})
 		})
 	})
+
+	It("decides which requests can be sent using 0-RTT", func() {
+		newRequest := func(method string, body io.Reader) *http.Request {
+			req, err := http.NewRequest(method, "https://quic.clemente.io:1337/", body)
+			Expect(err).ToNot(HaveOccurred())
+			return req
+		}
+		Expect(isReplayable(newRequest(http.MethodGet, nil))).To(BeTrue())
+		Expect(isReplayable(newRequest(http.MethodHead, nil))).To(BeTrue())
+		Expect(isReplayable(newRequest(http.MethodOptions, nil))).To(BeTrue())
+		Expect(isReplayable(newRequest(http.MethodPost, nil))).To(BeFalse())
+		Expect(isReplayable(newRequest(http.MethodPut, nil))).To(BeFalse())
+		// requests with a body that can be rewound
+		Expect(isReplayable(newRequest(http.MethodGet, strings.NewReader("foobar")))).To(BeTrue())
+		Expect(isReplayable(newRequest(http.MethodPost, strings.NewReader("foobar")))).To(BeFalse())
+		// requests with a body that can't be rewound
+		req := newRequest(http.MethodGet, strings.NewReader("foobar"))
+		req.GetBody = nil
+		Expect(isReplayable(req)).To(BeFalse())
+		// the application can opt in for non-idempotent requests
+		req = newRequest(http.MethodPost, nil)
+		req.Header.Set("Idempotency-Key", "foo")
+		Expect(isReplayable(req)).To(BeTrue())
+		req = newRequest(http.MethodPost, nil)
+		req.Header["X-Idempotency-Key"] = nil
+		Expect(isReplayable(req)).To(BeTrue())
+	})
 })
@@ -0,0 +1,163 @@
+package http3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+// Datagrammer is used to send and receive HTTP Datagrams (RFC 9297) associated with a request stream.
+// It is implemented by the http.ResponseWriter passed to server handlers,
+// and by the http.Response.Body returned by the RoundTripper (unless the body was transparently decompressed).
+// Datagrams can only be used if both peers enabled datagram support.
+type Datagrammer interface {
+	// SendDatagram sends an HTTP Datagram associated with the request stream.
+	// It blocks until the peer's SETTINGS frame was received.
+	SendDatagram([]byte) error
+	// ReceiveDatagram receives an HTTP Datagram associated with the request stream.
+	ReceiveDatagram(context.Context) ([]byte, error)
+}
+
+// the number of datagrams that are queued for every request stream,
+// before we start dropping datagrams
+const maxQueuedDatagrams = 32
+
+var (
+	errDatagramsNotEnabled    = errors.New("HTTP datagrams not enabled")
+	errDatagramsNotNegotiated = errors.New("peer didn't enable HTTP datagrams")
+)
+
+// The datagramManager demultiplexes the QUIC DATAGRAM frames received on a session to the request streams,
+// using the quarter stream ID that's prepended to every HTTP Datagram.
+type datagramManager struct {
+	sess quic.Session
+
+	settingsOnce     sync.Once
+	settingsReceived chan struct{} // closed when the peer's SETTINGS frame is received
+	peerEnabled      bool          // only valid after settingsReceived was closed
+
+	mutex   sync.Mutex
+	streams map[quic.StreamID]*streamDatagrams
+
+	logger utils.Logger
+}
+
+func newDatagramManager(sess quic.Session, logger utils.Logger) *datagramManager {
+	return &datagramManager{
+		sess:             sess,
+		settingsReceived: make(chan struct{}),
+		streams:          make(map[quic.StreamID]*streamDatagrams),
+		logger:           logger,
+	}
+}
+
+// handleSettings is called with the value of the peer's H3_DATAGRAM setting.
+// Only the first call has an effect.
+func (m *datagramManager) handleSettings(enabled bool) {
+	m.settingsOnce.Do(func() {
+		m.peerEnabled = enabled
+		close(m.settingsReceived)
+	})
+}
+
+// run reads datagrams from the session until the session is closed.
+func (m *datagramManager) run() {
+	for {
+		data, err := m.sess.ReceiveDatagram(context.Background())
+		if err != nil {
+			return
+		}
+		b := bytes.NewReader(data)
+		qsid, err := quicvarint.Read(b)
+		if err != nil {
+			m.logger.Debugf("Failed to parse quarter stream ID of HTTP datagram: %s", err)
+			continue
+		}
+		id := quic.StreamID(qsid * 4)
+		m.mutex.Lock()
+		str, ok := m.streams[id]
+		m.mutex.Unlock()
+		if !ok {
+			m.logger.Debugf("Dropping HTTP datagram for unknown stream %d", id)
+			continue
+		}
+		str.receivedDatagram(data[len(data)-b.Len():])
+	}
+}
+
+func (m *datagramManager) register(id quic.StreamID) *streamDatagrams {
+	str := &streamDatagrams{
+		manager: m,
+		id:      id,
+		queue:   make(chan []byte, maxQueuedDatagrams),
+		closed:  make(chan struct{}),
+	}
+	m.mutex.Lock()
+	m.streams[id] = str
+	m.mutex.Unlock()
+	return str
+}
+
+func (m *datagramManager) unregister(str *streamDatagrams) {
+	m.mutex.Lock()
+	if m.streams[str.id] == str {
+		delete(m.streams, str.id)
+	}
+	m.mutex.Unlock()
+	str.closeOnce.Do(func() { close(str.closed) })
+}
+
+func (m *datagramManager) send(id quic.StreamID, data []byte) error {
+	select {
+	case <-m.settingsReceived:
+	case <-m.sess.Context().Done():
+		return m.sess.Context().Err()
+	}
+	if !m.peerEnabled {
+		return errDatagramsNotNegotiated
+	}
+	buf := &bytes.Buffer{}
+	quicvarint.Write(buf, uint64(id/4))
+	buf.Write(data)
+	return m.sess.SendDatagram(buf.Bytes())
+}
+
+// streamDatagrams are the HTTP Datagrams associated with a single request stream.
+type streamDatagrams struct {
+	manager *datagramManager
+	id      quic.StreamID
+
+	queue     chan []byte
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+var _ Datagrammer = &streamDatagrams{}
+
+func (s *streamDatagrams) receivedDatagram(data []byte) {
+	select {
+	case s.queue <- data:
+	default:
+		s.manager.logger.Debugf("Dropping HTTP datagram for stream %d: queue full", s.id)
+	}
+}
+
+func (s *streamDatagrams) SendDatagram(data []byte) error {
+	return s.manager.send(s.id, data)
+}
+
+func (s *streamDatagrams) ReceiveDatagram(ctx context.Context) ([]byte, error) {
+	select {
+	case data := <-s.queue:
+		return data, nil
+	case <-s.closed:
+		return nil, errors.New("request stream closed")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,162 @@
+package http3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+
+	"github.com/golang/mock/gomock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HTTP Datagrams", func() {
+	var (
+		sess *mockquic.MockEarlySession
+		m    *datagramManager
+	)
+
+	datagram := func(id quic.StreamID, data []byte) []byte {
+		buf := &bytes.Buffer{}
+		quicvarint.Write(buf, uint64(id/4))
+		buf.Write(data)
+		return buf.Bytes()
+	}
+
+	BeforeEach(func() {
+		sess = mockquic.NewMockEarlySession(mockCtrl)
+		m = newDatagramManager(sess, utils.DefaultLogger)
+	})
+
+	Context("sending", func() {
+		var sessCtx context.Context
+
+		BeforeEach(func() {
+			sessCtx = context.Background()
+			sess.EXPECT().Context().DoAndReturn(func() context.Context { return sessCtx }).AnyTimes()
+		})
+
+		It("sends datagrams with the quarter stream ID", func() {
+			m.handleSettings(true)
+			str := m.register(8)
+			sess.EXPECT().SendDatagram(datagram(8, []byte("foobar")))
+			Expect(str.SendDatagram([]byte("foobar"))).To(Succeed())
+		})
+
+		It("waits for the peer's SETTINGS frame", func() {
+			str := m.register(4)
+			sent := make(chan struct{})
+			sess.EXPECT().SendDatagram(datagram(4, []byte("foobar"))).Do(func([]byte) { close(sent) })
+			go func() {
+				defer GinkgoRecover()
+				Expect(str.SendDatagram([]byte("foobar"))).To(Succeed())
+			}()
+			Consistently(sent).ShouldNot(BeClosed())
+			m.handleSettings(true)
+			Eventually(sent).Should(BeClosed())
+		})
+
+		It("errors if the peer didn't enable datagrams", func() {
+			m.handleSettings(false)
+			m.handleSettings(true) // only the first SETTINGS frame counts
+			Expect(m.register(0).SendDatagram([]byte("foobar"))).To(MatchError(errDatagramsNotNegotiated))
+		})
+
+		It("errors if the session is closed before the SETTINGS frame is received", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			sessCtx = ctx
+			Expect(m.register(0).SendDatagram([]byte("foobar"))).To(MatchError(context.Canceled))
+		})
+	})
+
+	Context("receiving", func() {
+		var received chan []byte
+
+		BeforeEach(func() {
+			received = make(chan []byte, 10)
+			sess.EXPECT().ReceiveDatagram(gomock.Any()).DoAndReturn(func(context.Context) ([]byte, error) {
+				data, ok := <-received
+				if !ok {
+					return nil, errors.New("session closed")
+				}
+				return data, nil
+			}).AnyTimes()
+		})
+
+		It("demultiplexes datagrams to the request streams", func() {
+			str1 := m.register(0)
+			str2 := m.register(4)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				m.run()
+			}()
+			received <- datagram(4, []byte("foo"))
+			received <- datagram(0, []byte("bar"))
+			received <- datagram(40, []byte("unknown stream"))
+			received <- []byte{0x40} // invalid quarter stream ID
+			received <- datagram(4, []byte("baz"))
+			close(received)
+			Eventually(done).Should(BeClosed())
+
+			data, err := str1.ReceiveDatagram(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("bar")))
+			data, err = str2.ReceiveDatagram(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foo")))
+			data, err = str2.ReceiveDatagram(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("baz")))
+		})
+
+		It("drops datagrams if the queue is full", func() {
+			str := m.register(0)
+			for i := 0; i < maxQueuedDatagrams+1; i++ {
+				str.receivedDatagram([]byte{byte(i)})
+			}
+			for i := 0; i < maxQueuedDatagrams; i++ {
+				data, err := str.ReceiveDatagram(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte{byte(i)}))
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(10*time.Millisecond))
+			defer cancel()
+			_, err := str.ReceiveDatagram(ctx)
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+		})
+
+		It("stops receiving when the stream is unregistered", func() {
+			str := m.register(0)
+			errChan := make(chan error, 1)
+			go func() {
+				_, err := str.ReceiveDatagram(context.Background())
+				errChan <- err
+			}()
+			Consistently(errChan).ShouldNot(Receive())
+			m.unregister(str)
+			Eventually(errChan).Should(Receive(MatchError("request stream closed")))
+		})
+	})
+
+	It("errors if datagrams are not enabled", func() {
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().Write(gomock.Any()).AnyTimes()
+		rw := newResponseWriter(str, utils.DefaultLogger)
+		Expect(rw.SendDatagram([]byte("foobar"))).To(MatchError(errDatagramsNotEnabled))
+		_, err := rw.ReceiveDatagram(context.Background())
+		Expect(err).To(MatchError(errDatagramsNotEnabled))
+		b := newResponseBody(str, nil, func() {})
+		Expect(b.SendDatagram([]byte("foobar"))).To(MatchError(errDatagramsNotEnabled))
+		_, err = b.ReceiveDatagram(context.Background())
+		Expect(err).To(MatchError(errDatagramsNotEnabled))
+	})
+})
@@ -2,22 +2,45 @@ package http3
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 
+	"github.com/lucas-clemente/quic-go"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/quicvarint"
 )
 
+// FrameType is the frame type of a HTTP/3 frame
+type FrameType uint64
+
+// errHijacked is returned by parseNextFrame when the stream was taken over by an unknownFrameHandlerFunc.
+var errHijacked = errors.New("hijacked")
+
+// An unknownFrameHandlerFunc is called for frame types that are not defined in RFC 9114.
+// It is called right after the frame type was read from the stream.
+// If it returns true, the stream was taken over, and must not be used any more.
+type unknownFrameHandlerFunc func(FrameType) (hijacked bool, err error)
+
 type frame interface{}
 
-func parseNextFrame(r io.Reader) (frame, error) {
+func parseNextFrame(r io.Reader, unknownFrameHandler unknownFrameHandlerFunc) (frame, error) {
 	qr := quicvarint.NewReader(r)
 	t, err := quicvarint.Read(qr)
 	if err != nil {
 		return nil, err
 	}
+	// Frames defined by extensions (like WebTransport) don't necessarily use a length field.
+	if t > 0xd && unknownFrameHandler != nil {
+		hijacked, err := unknownFrameHandler(FrameType(t))
+		if err != nil {
+			return nil, err
+		}
+		if hijacked {
+			return nil, errHijacked
+		}
+	}
 	l, err := quicvarint.Read(qr)
 	if err != nil {
 		return nil, err
@@ -30,14 +53,24 @@ func parseNextFrame(r io.Reader) (frame, error) {
 		return &headersFrame{Length: l}, nil
 	case 0x4:
 		return parseSettingsFrame(r, l)
+	case 0x5:
+		return parsePushPromiseFrame(qr, l)
+	case 0x7:
+		id, err := parseVarIntFramePayload(r, l, "GOAWAY")
+		if err != nil {
+			return nil, err
+		}
+		return &goAwayFrame{StreamID: quic.StreamID(id)}, nil
+	case 0xd:
+		id, err := parseVarIntFramePayload(r, l, "MAX_PUSH_ID")
+		if err != nil {
+			return nil, err
+		}
+		return &maxPushIDFrame{PushID: id}, nil
+	case frameTypePriorityUpdate, frameTypePriorityUpdatePush:
+		return parsePriorityUpdateFrame(r, l, t == frameTypePriorityUpdatePush)
 	case 0x3: // CANCEL_PUSH
 		fallthrough
-	case 0x5: // PUSH_PROMISE
-		fallthrough
-	case 0x7: // GOAWAY
-		fallthrough
-	case 0xd: // MAX_PUSH_ID
-		fallthrough
 	case 0xe: // DUPLICATE_PUSH
 		fallthrough
 	default:
@@ -45,7 +78,7 @@ func parseNextFrame(r io.Reader) (frame, error) {
 		if _, err := io.CopyN(ioutil.Discard, qr, int64(l)); err != nil {
 			return nil, err
 		}
-		return parseNextFrame(qr)
+		return parseNextFrame(qr, unknownFrameHandler)
 	}
 }
 
@@ -67,7 +100,8 @@ func (f *headersFrame) Write(b *bytes.Buffer) {
 	quicvarint.Write(b, f.Length)
 }
 
-const settingDatagram = 0x276
+// SETTINGS_H3_DATAGRAM, see section 2.1.1 of RFC 9297
+const settingDatagram = 0x33
 
 type settingsFrame struct {
 	Datagram bool
@@ -140,3 +174,119 @@ func (f *settingsFrame) Write(b *bytes.Buffer) {
 		quicvarint.Write(b, val)
 	}
 }
+
+// parseVarIntFramePayload parses the payload of frames that consist of a single variable-length integer.
+func parseVarIntFramePayload(r io.Reader, l uint64, name string) (uint64, error) {
+	if l > 8 {
+		return 0, fmt.Errorf("unexpected size for %s frame: %d", name, l)
+	}
+	buf := make([]byte, l)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, io.EOF
+		}
+		return 0, err
+	}
+	b := bytes.NewReader(buf)
+	val, err := quicvarint.Read(b)
+	if err != nil {
+		return 0, err
+	}
+	if b.Len() > 0 {
+		return 0, fmt.Errorf("unexpected size for %s frame: %d", name, l)
+	}
+	return val, nil
+}
+
+func writeVarIntFrame(b *bytes.Buffer, t, val uint64) {
+	quicvarint.Write(b, t)
+	quicvarint.Write(b, uint64(quicvarint.Len(val)))
+	quicvarint.Write(b, val)
+}
+
+type goAwayFrame struct {
+	StreamID quic.StreamID
+}
+
+func (f *goAwayFrame) Write(b *bytes.Buffer) {
+	writeVarIntFrame(b, 0x7, uint64(f.StreamID))
+}
+
+type maxPushIDFrame struct {
+	PushID uint64
+}
+
+func (f *maxPushIDFrame) Write(b *bytes.Buffer) {
+	writeVarIntFrame(b, 0xd, f.PushID)
+}
+
+// A pushPromiseFrame is followed by the encoded request header fields.
+// Length is the length of the header field section, not the length of the frame.
+type pushPromiseFrame struct {
+	PushID uint64
+	Length uint64
+}
+
+func parsePushPromiseFrame(r quicvarint.Reader, l uint64) (*pushPromiseFrame, error) {
+	id, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	idLen := uint64(quicvarint.Len(id))
+	if idLen > l {
+		return nil, fmt.Errorf("unexpected size for PUSH_PROMISE frame: %d", l)
+	}
+	return &pushPromiseFrame{PushID: id, Length: l - idLen}, nil
+}
+
+func (f *pushPromiseFrame) Write(b *bytes.Buffer) {
+	quicvarint.Write(b, 0x5)
+	quicvarint.Write(b, uint64(quicvarint.Len(f.PushID))+f.Length)
+	quicvarint.Write(b, f.PushID)
+}
+
+// PRIORITY_UPDATE frame types, see section 7.2 of RFC 9218
+const (
+	frameTypePriorityUpdate     = 0xf0700 // for request streams
+	frameTypePriorityUpdatePush = 0xf0701 // for push streams
+)
+
+type priorityUpdateFrame struct {
+	Push               bool   // true if ElementID is a push ID, false if it is a request stream ID
+	ElementID          uint64 // the stream ID or push ID of the prioritized element
+	PriorityFieldValue string // the value of the priority header field
+}
+
+func parsePriorityUpdateFrame(r io.Reader, l uint64, push bool) (*priorityUpdateFrame, error) {
+	if l > 1<<10 {
+		return nil, fmt.Errorf("unexpected size for PRIORITY_UPDATE frame: %d", l)
+	}
+	buf := make([]byte, l)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	b := bytes.NewReader(buf)
+	id, err := quicvarint.Read(b)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected size for PRIORITY_UPDATE frame: %d", l)
+	}
+	return &priorityUpdateFrame{
+		Push:               push,
+		ElementID:          id,
+		PriorityFieldValue: string(buf[len(buf)-b.Len():]),
+	}, nil
+}
+
+func (f *priorityUpdateFrame) Write(b *bytes.Buffer) {
+	t := uint64(frameTypePriorityUpdate)
+	if f.Push {
+		t = frameTypePriorityUpdatePush
+	}
+	quicvarint.Write(b, t)
+	quicvarint.Write(b, uint64(quicvarint.Len(f.ElementID))+uint64(len(f.PriorityFieldValue)))
+	quicvarint.Write(b, f.ElementID)
+	b.WriteString(f.PriorityFieldValue)
+}
@@ -24,7 +24,7 @@ var _ = Describe("Frames", func() {
 		data = append(data, make([]byte, 0x42)...)
 		buf := bytes.NewBuffer(data)
 		(&dataFrame{Length: 0x1234}).Write(buf)
-		frame, err := parseNextFrame(buf)
+		frame, err := parseNextFrame(buf, nil)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(frame).To(BeAssignableToTypeOf(&dataFrame{}))
 		Expect(frame.(*dataFrame).Length).To(Equal(uint64(0x1234)))
@@ -34,7 +34,7 @@ var _ = Describe("Frames", func() {
 		It("parses", func() {
 			data := appendVarInt(nil, 0) // type byte
 			data = appendVarInt(data, 0x1337)
-			frame, err := parseNextFrame(bytes.NewReader(data))
+			frame, err := parseNextFrame(bytes.NewReader(data), nil)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(frame).To(BeAssignableToTypeOf(&dataFrame{}))
 			Expect(frame.(*dataFrame).Length).To(Equal(uint64(0x1337)))
@@ -43,7 +43,7 @@ var _ = Describe("Frames", func() {
 		It("writes", func() {
 			buf := &bytes.Buffer{}
 			(&dataFrame{Length: 0xdeadbeef}).Write(buf)
-			frame, err := parseNextFrame(buf)
+			frame, err := parseNextFrame(buf, nil)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(err).ToNot(HaveOccurred())
 			Expect(frame).To(BeAssignableToTypeOf(&dataFrame{}))
@@ -55,7 +55,7 @@ var _ = Describe("Frames", func() {
 		It("parses", func() {
 			data := appendVarInt(nil, 1) // type byte
 			data = appendVarInt(data, 0x1337)
-			frame, err := parseNextFrame(bytes.NewReader(data))
+			frame, err := parseNextFrame(bytes.NewReader(data), nil)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(frame).To(BeAssignableToTypeOf(&headersFrame{}))
 			Expect(frame.(*headersFrame).Length).To(Equal(uint64(0x1337)))
@@ -64,7 +64,7 @@ var _ = Describe("Frames", func() {
 		It("writes", func() {
 			buf := &bytes.Buffer{}
 			(&headersFrame{Length: 0xdeadbeef}).Write(buf)
-			frame, err := parseNextFrame(buf)
+			frame, err := parseNextFrame(buf, nil)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(err).ToNot(HaveOccurred())
 			Expect(frame).To(BeAssignableToTypeOf(&headersFrame{}))
@@ -81,7 +81,7 @@ var _ = Describe("Frames", func() {
 			data := appendVarInt(nil, 4) // type byte
 			data = appendVarInt(data, uint64(len(settings)))
 			data = append(data, settings...)
-			frame, err := parseNextFrame(bytes.NewReader(data))
+			frame, err := parseNextFrame(bytes.NewReader(data), nil)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(frame).To(BeAssignableToTypeOf(&settingsFrame{}))
 			sf := frame.(*settingsFrame)
@@ -97,7 +97,7 @@ var _ = Describe("Frames", func() {
 			data := appendVarInt(nil, 4) // type byte
 			data = appendVarInt(data, uint64(len(settings)))
 			data = append(data, settings...)
-			_, err := parseNextFrame(bytes.NewReader(data))
+			_, err := parseNextFrame(bytes.NewReader(data), nil)
 			Expect(err).To(MatchError("duplicate setting: 13"))
 		})
 
@@ -109,7 +109,7 @@ var _ = Describe("Frames", func() {
 			}}
 			buf := &bytes.Buffer{}
 			sf.Write(buf)
-			frame, err := parseNextFrame(buf)
+			frame, err := parseNextFrame(buf, nil)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(frame).To(Equal(sf))
 		})
@@ -123,13 +123,13 @@ var _ = Describe("Frames", func() {
 			sf.Write(buf)
 
 			data := buf.Bytes()
-			_, err := parseNextFrame(bytes.NewReader(data))
+			_, err := parseNextFrame(bytes.NewReader(data), nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			for i := range data {
 				b := make([]byte, i)
 				copy(b, data[:i])
-				_, err := parseNextFrame(bytes.NewReader(b))
+				_, err := parseNextFrame(bytes.NewReader(b), nil)
 				Expect(err).To(MatchError(io.EOF))
 			}
 		})
@@ -141,7 +141,7 @@ var _ = Describe("Frames", func() {
 				data := appendVarInt(nil, 4) // type byte
 				data = appendVarInt(data, uint64(len(settings)))
 				data = append(data, settings...)
-				f, err := parseNextFrame(bytes.NewReader(data))
+				f, err := parseNextFrame(bytes.NewReader(data), nil)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(f).To(BeAssignableToTypeOf(&settingsFrame{}))
 				sf := f.(*settingsFrame)
@@ -156,7 +156,7 @@ var _ = Describe("Frames", func() {
 				data := appendVarInt(nil, 4) // type byte
 				data = appendVarInt(data, uint64(len(settings)))
 				data = append(data, settings...)
-				_, err := parseNextFrame(bytes.NewReader(data))
+				_, err := parseNextFrame(bytes.NewReader(data), nil)
 				Expect(err).To(MatchError(fmt.Sprintf("duplicate setting: %d", settingDatagram)))
 			})
 
@@ -166,7 +166,7 @@ var _ = Describe("Frames", func() {
 				data := appendVarInt(nil, 4) // type byte
 				data = appendVarInt(data, uint64(len(settings)))
 				data = append(data, settings...)
-				_, err := parseNextFrame(bytes.NewReader(data))
+				_, err := parseNextFrame(bytes.NewReader(data), nil)
 				Expect(err).To(MatchError("invalid value for H3_DATAGRAM: 1337"))
 			})
 
@@ -174,10 +174,144 @@ var _ = Describe("Frames", func() {
 				sf := &settingsFrame{Datagram: true}
 				buf := &bytes.Buffer{}
 				sf.Write(buf)
-				frame, err := parseNextFrame(buf)
+				frame, err := parseNextFrame(buf, nil)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(frame).To(Equal(sf))
 			})
 		})
 	})
+
+	Context("GOAWAY frames", func() {
+		It("parses", func() {
+			data := appendVarInt(nil, 7) // type byte
+			data = appendVarInt(data, uint64(quicvarint.Len(100)))
+			data = appendVarInt(data, 100)
+			frame, err := parseNextFrame(bytes.NewReader(data), nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&goAwayFrame{StreamID: 100}))
+		})
+
+		It("writes", func() {
+			buf := &bytes.Buffer{}
+			(&goAwayFrame{StreamID: 0x1337}).Write(buf)
+			frame, err := parseNextFrame(buf, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&goAwayFrame{StreamID: 0x1337}))
+		})
+
+		It("errors on frames that are too long", func() {
+			data := appendVarInt(nil, 7) // type byte
+			data = appendVarInt(data, 2)
+			data = appendVarInt(data, 4)
+			data = append(data, 0)
+			_, err := parseNextFrame(bytes.NewReader(data), nil)
+			Expect(err).To(MatchError("unexpected size for GOAWAY frame: 2"))
+		})
+
+		It("errors on EOF", func() {
+			buf := &bytes.Buffer{}
+			(&goAwayFrame{StreamID: 0xdeadbeef}).Write(buf)
+			data := buf.Bytes()
+			for i := range data {
+				_, err := parseNextFrame(bytes.NewReader(data[:i]), nil)
+				Expect(err).To(MatchError(io.EOF))
+			}
+		})
+	})
+
+	Context("MAX_PUSH_ID frames", func() {
+		It("parses", func() {
+			data := appendVarInt(nil, 0xd) // type byte
+			data = appendVarInt(data, uint64(quicvarint.Len(1337)))
+			data = appendVarInt(data, 1337)
+			frame, err := parseNextFrame(bytes.NewReader(data), nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&maxPushIDFrame{PushID: 1337}))
+		})
+
+		It("writes", func() {
+			buf := &bytes.Buffer{}
+			(&maxPushIDFrame{PushID: 42}).Write(buf)
+			frame, err := parseNextFrame(buf, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&maxPushIDFrame{PushID: 42}))
+		})
+	})
+
+	Context("PUSH_PROMISE frames", func() {
+		It("parses", func() {
+			data := appendVarInt(nil, 5) // type byte
+			data = appendVarInt(data, uint64(quicvarint.Len(1337))+0x42)
+			data = appendVarInt(data, 1337)
+			frame, err := parseNextFrame(bytes.NewReader(data), nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&pushPromiseFrame{PushID: 1337, Length: 0x42}))
+		})
+
+		It("writes", func() {
+			buf := &bytes.Buffer{}
+			(&pushPromiseFrame{PushID: 0xdeadbeef, Length: 0x1337}).Write(buf)
+			frame, err := parseNextFrame(buf, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&pushPromiseFrame{PushID: 0xdeadbeef, Length: 0x1337}))
+		})
+
+		It("errors if the frame is too short to contain the push ID", func() {
+			data := appendVarInt(nil, 5) // type byte
+			data = appendVarInt(data, 1)
+			data = appendVarInt(data, 1337)
+			_, err := parseNextFrame(bytes.NewReader(data), nil)
+			Expect(err).To(MatchError("unexpected size for PUSH_PROMISE frame: 1"))
+		})
+	})
+
+	Context("PRIORITY_UPDATE frames", func() {
+		It("parses", func() {
+			data := appendVarInt(nil, 0xf0700) // type byte
+			data = appendVarInt(data, uint64(quicvarint.Len(1337))+7)
+			data = appendVarInt(data, 1337)
+			data = append(data, []byte("u=1, i")...)
+			data = append(data, ' ')
+			frame, err := parseNextFrame(bytes.NewReader(data), nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&priorityUpdateFrame{ElementID: 1337, PriorityFieldValue: "u=1, i "}))
+		})
+
+		for _, p := range []bool{false, true} {
+			push := p
+
+			It(fmt.Sprintf("writes (push: %t)", push), func() {
+				buf := &bytes.Buffer{}
+				f := &priorityUpdateFrame{Push: push, ElementID: 0xdeadbeef, PriorityFieldValue: "u=5"}
+				f.Write(buf)
+				frame, err := parseNextFrame(buf, nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame).To(Equal(f))
+			})
+		}
+
+		It("allows an empty priority field value", func() {
+			buf := &bytes.Buffer{}
+			(&priorityUpdateFrame{ElementID: 4}).Write(buf)
+			frame, err := parseNextFrame(buf, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&priorityUpdateFrame{ElementID: 4}))
+		})
+
+		It("errors if the frame is too short to contain the element ID", func() {
+			data := appendVarInt(nil, 0xf0700) // type byte
+			data = appendVarInt(data, 1)
+			data = appendVarInt(data, 1337)
+			_, err := parseNextFrame(bytes.NewReader(data), nil)
+			Expect(err).To(MatchError("unexpected size for PRIORITY_UPDATE frame: 1"))
+		})
+
+		It("errors on EOF", func() {
+			buf := &bytes.Buffer{}
+			(&priorityUpdateFrame{ElementID: 4, PriorityFieldValue: "u=1"}).Write(buf)
+			data := buf.Bytes()
+			_, err := parseNextFrame(bytes.NewReader(data[:len(data)-1]), nil)
+			Expect(err).To(MatchError(io.EOF))
+		})
+	})
 })
@@ -0,0 +1,231 @@
+package http3
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+// A Priority is the priority of a response, as defined by the Extensible Priorities scheme (RFC 9218).
+type Priority struct {
+	// Urgency is a value between 0 and 7. Lower values are more urgent.
+	Urgency uint8
+	// Incremental responses can be processed by the client before they're fully received,
+	// so they're interleaved with other incremental responses of the same urgency.
+	Incremental bool
+}
+
+// DefaultPriority is the priority of requests that don't carry a priority signal.
+var DefaultPriority = Priority{Urgency: 3}
+
+// the maximum urgency value
+const maxUrgency = 7
+
+// ParsePriority parses the value of a priority header field, or of a PRIORITY_UPDATE frame.
+// Unknown and invalid parameters are ignored, and the respective default values are used instead.
+func ParsePriority(s string) Priority {
+	p := DefaultPriority
+	for _, member := range strings.Split(s, ",") {
+		member = strings.TrimSpace(member)
+		// parameters of dictionary members are not used by RFC 9218
+		if i := strings.IndexByte(member, ';'); i >= 0 {
+			member = member[:i]
+		}
+		// a dictionary member without a value is a boolean true
+		key, value := member, "?1"
+		if i := strings.IndexByte(member, '='); i >= 0 {
+			key, value = member[:i], member[i+1:]
+		}
+		switch key {
+		case "u":
+			if u, err := strconv.ParseUint(value, 10, 8); err == nil && u <= maxUrgency {
+				p.Urgency = uint8(u)
+			}
+		case "i":
+			switch value {
+			case "?1":
+				p.Incremental = true
+			case "?0":
+				p.Incremental = false
+			}
+		}
+	}
+	return p
+}
+
+// String encodes the priority as the value of a priority header field.
+// Default values are omitted.
+func (p Priority) String() string {
+	var params []string
+	if p.Urgency != DefaultPriority.Urgency {
+		params = append(params, "u="+strconv.Itoa(int(p.Urgency)))
+	}
+	if p.Incremental {
+		params = append(params, "i")
+	}
+	return strings.Join(params, ", ")
+}
+
+// PrioritySetter is implemented by the http.ResponseWriter passed to server handlers.
+// SetPriority sets the priority used to schedule the response,
+// overriding the priority signaled by the client in the request and in PRIORITY_UPDATE frames.
+type PrioritySetter interface {
+	SetPriority(Priority)
+}
+
+// the maximum amount of data that is written at once, before checking if a more urgent response needs to be sent
+const schedulerChunkSize = 16 << 10
+
+// the maximum number of PRIORITY_UPDATE frames for requests that haven't been received yet
+const maxPendingPriorityUpdates = 64
+
+// The writeScheduler orders the writes of the responses on a connection by their priority (see section 10 of RFC 9218).
+// A response is only written if no more urgent response is currently being written.
+// Non-incremental responses of the same urgency are written one after the other, in the order of their stream IDs,
+// incremental responses of the same urgency are interleaved.
+type writeScheduler struct {
+	mutex        sync.Mutex
+	cond         sync.Cond
+	streams      map[quic.StreamID]*prioritizedStream
+	nextStreamID quic.StreamID              // the lowest stream ID that wasn't added yet
+	pending      map[quic.StreamID]Priority // PRIORITY_UPDATEs for requests that weren't added yet
+}
+
+func newWriteScheduler() *writeScheduler {
+	s := &writeScheduler{
+		streams: make(map[quic.StreamID]*prioritizedStream),
+		pending: make(map[quic.StreamID]Priority),
+	}
+	s.cond.L = &s.mutex
+	return s
+}
+
+// add is called when a request is received.
+// If a PRIORITY_UPDATE frame was received for this request before, it takes precedence over the priority header field.
+func (s *writeScheduler) add(str quic.Stream, p Priority) *prioritizedStream {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	id := str.StreamID()
+	if update, ok := s.pending[id]; ok {
+		p = update
+		delete(s.pending, id)
+	}
+	if id >= s.nextStreamID {
+		s.nextStreamID = id + 4
+	}
+	ps := &prioritizedStream{scheduler: s, str: str, id: id, priority: p}
+	s.streams[id] = ps
+	return ps
+}
+
+// remove is called when the response is complete.
+func (s *writeScheduler) remove(ps *prioritizedStream) {
+	s.mutex.Lock()
+	delete(s.streams, ps.id)
+	s.cond.Broadcast()
+	s.mutex.Unlock()
+}
+
+// update is called when a PRIORITY_UPDATE frame is received.
+func (s *writeScheduler) update(id quic.StreamID, p Priority) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if ps, ok := s.streams[id]; ok {
+		if !ps.prioritySet {
+			ps.priority = p
+			s.cond.Broadcast()
+		}
+		return
+	}
+	// ignore PRIORITY_UPDATE frames for requests that already completed
+	if id < s.nextStreamID || len(s.pending) >= maxPendingPriorityUpdates {
+		return
+	}
+	s.pending[id] = p
+}
+
+// preempts says if the response written on ps takes precedence over the response written on other.
+func (ps *prioritizedStream) preempts(other *prioritizedStream) bool {
+	if ps.priority.Urgency != other.priority.Urgency {
+		return ps.priority.Urgency < other.priority.Urgency
+	}
+	if ps.priority.Incremental || other.priority.Incremental {
+		return false
+	}
+	return ps.id < other.id
+}
+
+// waitForTurn blocks until no more urgent response is being written.
+// It must be called with the mutex held.
+func (s *writeScheduler) waitForTurn(ps *prioritizedStream) {
+	for {
+		var blocked bool
+		for _, other := range s.streams {
+			if other != ps && other.writing && other.preempts(ps) {
+				blocked = true
+				break
+			}
+		}
+		if !blocked {
+			return
+		}
+		s.cond.Wait()
+	}
+}
+
+// A prioritizedStream is the io.Writer used to write a response, such that it is scheduled according to its priority.
+type prioritizedStream struct {
+	scheduler *writeScheduler
+	str       io.Writer
+	id        quic.StreamID
+
+	// all fields below are protected by the scheduler's mutex
+	priority    Priority
+	prioritySet bool // set when the handler set the priority, PRIORITY_UPDATE frames are ignored afterwards
+	writing     bool // set while a Write call is in progress
+}
+
+var _ io.Writer = &prioritizedStream{}
+
+func (ps *prioritizedStream) Write(p []byte) (int, error) {
+	s := ps.scheduler
+	s.mutex.Lock()
+	ps.writing = true
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		ps.writing = false
+		s.cond.Broadcast()
+		s.mutex.Unlock()
+	}()
+
+	var n int
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > schedulerChunkSize {
+			chunk = chunk[:schedulerChunkSize]
+		}
+		s.mutex.Lock()
+		s.waitForTurn(ps)
+		s.mutex.Unlock()
+		written, err := ps.str.Write(chunk)
+		n += written
+		if err != nil {
+			return n, err
+		}
+		p = p[written:]
+	}
+	return n, nil
+}
+
+func (ps *prioritizedStream) setPriority(p Priority) {
+	s := ps.scheduler
+	s.mutex.Lock()
+	ps.priority = p
+	ps.prioritySet = true
+	s.cond.Broadcast()
+	s.mutex.Unlock()
+}
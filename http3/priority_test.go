@@ -0,0 +1,223 @@
+package http3
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Priorities", func() {
+	Context("parsing", func() {
+		It("uses the default priority for empty values", func() {
+			Expect(ParsePriority("")).To(Equal(DefaultPriority))
+		})
+
+		It("parses the urgency", func() {
+			Expect(ParsePriority("u=0")).To(Equal(Priority{Urgency: 0}))
+			Expect(ParsePriority("u=7")).To(Equal(Priority{Urgency: 7}))
+		})
+
+		It("parses the incremental parameter", func() {
+			Expect(ParsePriority("i")).To(Equal(Priority{Urgency: 3, Incremental: true}))
+			Expect(ParsePriority("i=?1")).To(Equal(Priority{Urgency: 3, Incremental: true}))
+			Expect(ParsePriority("i=?0")).To(Equal(Priority{Urgency: 3}))
+		})
+
+		It("parses both parameters", func() {
+			Expect(ParsePriority("u=5, i")).To(Equal(Priority{Urgency: 5, Incremental: true}))
+			Expect(ParsePriority("i,u=1")).To(Equal(Priority{Urgency: 1, Incremental: true}))
+		})
+
+		It("ignores invalid values", func() {
+			Expect(ParsePriority("u=8")).To(Equal(DefaultPriority))
+			Expect(ParsePriority("u=-1")).To(Equal(DefaultPriority))
+			Expect(ParsePriority("u=foo")).To(Equal(DefaultPriority))
+			Expect(ParsePriority("i=1")).To(Equal(DefaultPriority))
+		})
+
+		It("ignores unknown parameters and dictionary member parameters", func() {
+			Expect(ParsePriority("foo=bar, u=2;x=y, baz")).To(Equal(Priority{Urgency: 2}))
+		})
+
+		It("uses the last value if a parameter is repeated", func() {
+			Expect(ParsePriority("u=1, u=6")).To(Equal(Priority{Urgency: 6}))
+		})
+
+		It("encodes priorities", func() {
+			Expect(DefaultPriority.String()).To(BeEmpty())
+			Expect(Priority{Urgency: 1}.String()).To(Equal("u=1"))
+			Expect(Priority{Urgency: 3, Incremental: true}.String()).To(Equal("i"))
+			p := Priority{Urgency: 6, Incremental: true}
+			Expect(ParsePriority(p.String())).To(Equal(p))
+		})
+	})
+
+	Context("scheduling", func() {
+		var (
+			scheduler *writeScheduler
+			mutex     sync.Mutex
+			written   []quic.StreamID
+		)
+
+		BeforeEach(func() {
+			scheduler = newWriteScheduler()
+			written = nil
+		})
+
+		getWritten := func() []quic.StreamID {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return append([]quic.StreamID{}, written...)
+		}
+
+		// newStream creates a stream that records the writes.
+		// If block is non-nil, writes block until it is closed.
+		newStream := func(id quic.StreamID, block <-chan struct{}) quic.Stream {
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().StreamID().Return(id).AnyTimes()
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(b []byte) (int, error) {
+				if block != nil {
+					<-block
+				}
+				mutex.Lock()
+				written = append(written, id)
+				mutex.Unlock()
+				return len(b), nil
+			}).AnyTimes()
+			return str
+		}
+
+		isWriting := func(ps *prioritizedStream) func() bool {
+			return func() bool {
+				scheduler.mutex.Lock()
+				defer scheduler.mutex.Unlock()
+				return ps.writing
+			}
+		}
+
+		write := func(ps *prioritizedStream, data []byte) <-chan struct{} {
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				n, err := ps.Write(data)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(len(data)))
+			}()
+			return done
+		}
+
+		It("writes concurrent responses in the order of their urgency", func() {
+			unblock := make(chan struct{})
+			ps0 := scheduler.add(newStream(0, unblock), Priority{Urgency: 0})
+			ps4 := scheduler.add(newStream(4, nil), Priority{Urgency: 5})
+			ps8 := scheduler.add(newStream(8, nil), Priority{Urgency: 1})
+			ps12 := scheduler.add(newStream(12, nil), Priority{Urgency: 3})
+			done0 := write(ps0, []byte("foo"))
+			Eventually(isWriting(ps0)).Should(BeTrue())
+			done4 := write(ps4, []byte("foo"))
+			done8 := write(ps8, []byte("foo"))
+			done12 := write(ps12, []byte("foo"))
+			Eventually(isWriting(ps4)).Should(BeTrue())
+			Eventually(isWriting(ps8)).Should(BeTrue())
+			Eventually(isWriting(ps12)).Should(BeTrue())
+			Consistently(getWritten).Should(BeEmpty())
+			close(unblock)
+			for _, done := range []<-chan struct{}{done0, done4, done8, done12} {
+				Eventually(done).Should(BeClosed())
+			}
+			Expect(getWritten()).To(Equal([]quic.StreamID{0, 8, 12, 4}))
+		})
+
+		It("writes non-incremental responses of the same urgency in the order of their stream IDs", func() {
+			unblock := make(chan struct{})
+			ps0 := scheduler.add(newStream(0, unblock), DefaultPriority)
+			ps4 := scheduler.add(newStream(4, nil), DefaultPriority)
+			done0 := write(ps0, []byte("foo"))
+			Eventually(isWriting(ps0)).Should(BeTrue())
+			done4 := write(ps4, []byte("foo"))
+			Consistently(getWritten).Should(BeEmpty())
+			close(unblock)
+			Eventually(done0).Should(BeClosed())
+			Eventually(done4).Should(BeClosed())
+			Expect(getWritten()).To(Equal([]quic.StreamID{0, 4}))
+		})
+
+		It("interleaves incremental responses of the same urgency", func() {
+			unblock := make(chan struct{})
+			ps0 := scheduler.add(newStream(0, unblock), Priority{Urgency: 3, Incremental: true})
+			ps4 := scheduler.add(newStream(4, nil), Priority{Urgency: 3, Incremental: true})
+			done0 := write(ps0, []byte("foo"))
+			Eventually(isWriting(ps0)).Should(BeTrue())
+			done4 := write(ps4, []byte("foo"))
+			Eventually(done4).Should(BeClosed())
+			Expect(getWritten()).To(Equal([]quic.StreamID{4}))
+			close(unblock)
+			Eventually(done0).Should(BeClosed())
+		})
+
+		It("lets more urgent responses preempt large writes", func() {
+			block := make(chan struct{}, 10)
+			ps0 := scheduler.add(newStream(0, block), Priority{Urgency: 5})
+			ps4 := scheduler.add(newStream(4, nil), Priority{Urgency: 1})
+			block <- struct{}{}
+			done0 := write(ps0, bytes.Repeat([]byte{'a'}, 3*schedulerChunkSize))
+			Eventually(getWritten).Should(HaveLen(1))
+			// ps0 is now blocked writing the second chunk
+			done4 := write(ps4, []byte("foo"))
+			Eventually(done4).Should(BeClosed())
+			block <- struct{}{}
+			block <- struct{}{}
+			Eventually(done0).Should(BeClosed())
+			Expect(getWritten()).To(Equal([]quic.StreamID{0, 4, 0, 0}))
+		})
+
+		It("applies PRIORITY_UPDATEs", func() {
+			unblock := make(chan struct{})
+			ps0 := scheduler.add(newStream(0, unblock), Priority{Urgency: 1})
+			ps4 := scheduler.add(newStream(4, nil), Priority{Urgency: 5})
+			scheduler.update(4, Priority{Urgency: 0})
+			done0 := write(ps0, []byte("foo"))
+			Eventually(isWriting(ps0)).Should(BeTrue())
+			done4 := write(ps4, []byte("foo"))
+			Eventually(done4).Should(BeClosed())
+			close(unblock)
+			Eventually(done0).Should(BeClosed())
+			Expect(getWritten()).To(Equal([]quic.StreamID{4, 0}))
+		})
+
+		It("applies PRIORITY_UPDATEs received before the request", func() {
+			scheduler.update(8, Priority{Urgency: 6})
+			ps := scheduler.add(newStream(8, nil), Priority{Urgency: 1})
+			Expect(ps.priority).To(Equal(Priority{Urgency: 6}))
+		})
+
+		It("ignores PRIORITY_UPDATEs for completed requests", func() {
+			ps := scheduler.add(newStream(8, nil), Priority{Urgency: 1})
+			scheduler.remove(ps)
+			scheduler.update(4, Priority{Urgency: 6})
+			scheduler.update(8, Priority{Urgency: 6})
+			Expect(scheduler.pending).To(BeEmpty())
+		})
+
+		It("limits the number of pending PRIORITY_UPDATEs", func() {
+			for i := 0; i < 2*maxPendingPriorityUpdates; i++ {
+				scheduler.update(quic.StreamID(4*i), Priority{Urgency: 6})
+			}
+			Expect(scheduler.pending).To(HaveLen(maxPendingPriorityUpdates))
+		})
+
+		It("ignores PRIORITY_UPDATEs after the priority was set by the handler", func() {
+			ps := scheduler.add(newStream(0, nil), DefaultPriority)
+			ps.setPriority(Priority{Urgency: 1})
+			scheduler.update(0, Priority{Urgency: 6})
+			Expect(ps.priority).To(Equal(Priority{Urgency: 1}))
+		})
+	})
+})
@@ -0,0 +1,159 @@
+package http3
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+	"github.com/marten-seemann/qpack"
+)
+
+var _ http.Pusher = &responseWriter{}
+
+// Push implements the http.Pusher interface.
+// It returns http.ErrNotSupported if the client didn't enable server push (by sending a MAX_PUSH_ID frame),
+// or if the client doesn't allow any more pushes.
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if w.push == nil {
+		return http.ErrNotSupported
+	}
+	return w.push(target, opts)
+}
+
+// push sends a PUSH_PROMISE frame on the request stream, and serves the promised request on a new push stream.
+func (s *Server) push(conn *serverConn, w *responseWriter, req *http.Request, target string, opts *http.PushOptions) error {
+	if opts == nil {
+		opts = &http.PushOptions{}
+	}
+	method := opts.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	if method != http.MethodGet && method != http.MethodHead {
+		return fmt.Errorf("method %q must be GET or HEAD", method)
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "" {
+		if !strings.HasPrefix(target, "/") {
+			return fmt.Errorf("target must be an absolute URL or an absolute path: %q", target)
+		}
+		u.Scheme = "https"
+		u.Host = req.Host
+	} else {
+		if u.Scheme != "https" {
+			return fmt.Errorf("cannot push URL with scheme %q from request with scheme https", u.Scheme)
+		}
+		if u.Host == "" {
+			return errors.New("URL must have a host")
+		}
+	}
+	for k := range opts.Header {
+		if strings.HasPrefix(k, ":") {
+			return fmt.Errorf("promised request headers cannot include pseudo header %q", k)
+		}
+	}
+
+	pushID, ok := conn.getPushID()
+	if !ok {
+		return http.ErrNotSupported
+	}
+
+	fields := []qpack.HeaderField{
+		{Name: ":method", Value: method},
+		{Name: ":scheme", Value: u.Scheme},
+		{Name: ":authority", Value: u.Host},
+		{Name: ":path", Value: u.RequestURI()},
+	}
+	for k, vv := range opts.Header {
+		for _, v := range vv {
+			fields = append(fields, qpack.HeaderField{Name: strings.ToLower(k), Value: v})
+		}
+	}
+	pushedReq, err := requestFromHeaders(fields)
+	if err != nil {
+		return err
+	}
+	pushedReq.RemoteAddr = req.RemoteAddr
+	pushedReq.Body = http.NoBody
+	ctx := context.WithValue(context.Background(), ServerContextKey, s)
+	ctx = context.WithValue(ctx, SessionContextKey, conn.sess)
+	ctx = context.WithValue(ctx, http.LocalAddrContextKey, conn.sess.LocalAddr())
+	pushedReq = pushedReq.WithContext(ctx)
+
+	var headers bytes.Buffer
+	enc := qpack.NewEncoder(&headers)
+	for _, f := range fields {
+		enc.WriteField(f)
+	}
+	buf := &bytes.Buffer{}
+	(&pushPromiseFrame{PushID: pushID, Length: uint64(headers.Len())}).Write(buf)
+	buf.Write(headers.Bytes())
+	if _, err := w.bufferedStream.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	w.Flush()
+
+	str, err := conn.sess.OpenUniStream()
+	if err != nil {
+		return err
+	}
+	buf.Reset()
+	quicvarint.Write(buf, streamTypePushStream)
+	quicvarint.Write(buf, pushID)
+	if _, err := str.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	s.logger.Debugf("Pushing %s (push ID %d)", u, pushID)
+	s.addRequest()
+	go func() {
+		defer s.removeRequest()
+		s.handlePushedRequest(str, pushedReq)
+	}()
+	return nil
+}
+
+func (s *Server) handlePushedRequest(str quic.SendStream, req *http.Request) {
+	req = req.WithContext(context.WithValue(req.Context(), StreamIDContextKey, str.StreamID()))
+	r := &responseWriter{
+		header:         http.Header{},
+		bufferedStream: bufio.NewWriter(str),
+		logger:         s.logger,
+	}
+	if s.serveHTTP(&pushResponseWriter{w: r}, req) {
+		str.CancelWrite(quic.StreamErrorCode(errorInternalError))
+		return
+	}
+	if !r.headerWritten {
+		r.WriteHeader(200)
+	}
+	r.writeTrailers()
+	r.Flush()
+	str.Close()
+}
+
+// pushResponseWriter is the http.ResponseWriter used for pushed responses.
+// Push streams are unidirectional and can't be used to push more resources,
+// so it doesn't implement DataStreamer and http.Pusher.
+type pushResponseWriter struct {
+	w *responseWriter
+}
+
+var (
+	_ http.ResponseWriter = &pushResponseWriter{}
+	_ http.Flusher        = &pushResponseWriter{}
+)
+
+func (w *pushResponseWriter) Header() http.Header         { return w.w.Header() }
+func (w *pushResponseWriter) WriteHeader(status int)      { w.w.WriteHeader(status) }
+func (w *pushResponseWriter) Write(p []byte) (int, error) { return w.w.Write(p) }
+func (w *pushResponseWriter) Flush()                      { w.w.Flush() }
@@ -0,0 +1,196 @@
+package http3
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/lucas-clemente/quic-go"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+
+	"github.com/golang/mock/gomock"
+	"github.com/marten-seemann/qpack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Server Push", func() {
+	var (
+		s      *Server
+		sess   *mockquic.MockEarlySession
+		conn   *serverConn
+		rw     *responseWriter
+		strBuf *bytes.Buffer
+		req    *http.Request
+	)
+
+	BeforeEach(func() {
+		s = &Server{
+			Server: &http.Server{},
+			logger: utils.DefaultLogger,
+		}
+		sess = mockquic.NewMockEarlySession(mockCtrl)
+		sess.EXPECT().LocalAddr().Return(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 443}).AnyTimes()
+		conn = &serverConn{sess: sess}
+		strBuf = &bytes.Buffer{}
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().Write(gomock.Any()).DoAndReturn(strBuf.Write).AnyTimes()
+		rw = newResponseWriter(str, utils.DefaultLogger)
+		var err error
+		req, err = http.NewRequest(http.MethodGet, "https://www.example.com/", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.RemoteAddr = "127.0.0.1:1337"
+		rw.push = func(target string, opts *http.PushOptions) error {
+			return s.push(conn, rw, req, target, opts)
+		}
+	})
+
+	decodeHeaderBlock := func(r io.Reader, l uint64) map[string][]string {
+		data := make([]byte, l)
+		_, err := io.ReadFull(r, data)
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		hfs, err := qpack.NewDecoder(nil).DecodeFull(data)
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		fields := make(map[string][]string)
+		for _, p := range hfs {
+			fields[p.Name] = append(fields[p.Name], p.Value)
+		}
+		return fields
+	}
+
+	It("doesn't push if pushing is not possible on this stream", func() {
+		Expect(newResponseWriter(mockquic.NewMockStream(mockCtrl), utils.DefaultLogger).Push("/foo", nil)).To(MatchError(http.ErrNotSupported))
+	})
+
+	It("doesn't push if the client didn't send a MAX_PUSH_ID frame", func() {
+		Expect(rw.Push("/foo", nil)).To(MatchError(http.ErrNotSupported))
+	})
+
+	It("respects the maximum push ID", func() {
+		Expect(conn.handleMaxPushID(1)).To(Succeed())
+		id, ok := conn.getPushID()
+		Expect(ok).To(BeTrue())
+		Expect(id).To(BeZero())
+		id, ok = conn.getPushID()
+		Expect(ok).To(BeTrue())
+		Expect(id).To(Equal(uint64(1)))
+		_, ok = conn.getPushID()
+		Expect(ok).To(BeFalse())
+		// the client increases the maximum push ID
+		Expect(conn.handleMaxPushID(2)).To(Succeed())
+		id, ok = conn.getPushID()
+		Expect(ok).To(BeTrue())
+		Expect(id).To(Equal(uint64(2)))
+		Expect(conn.handleMaxPushID(1)).To(MatchError("MAX_PUSH_ID reduced the maximum push ID from 2 to 1"))
+	})
+
+	It("pushes", func() {
+		Expect(conn.handleMaxPushID(10)).To(Succeed())
+		handlerCalled := make(chan *http.Request, 1)
+		s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handlerCalled <- r
+			w.Write([]byte("body { color: red; }"))
+		})
+		pushStrBuf := &bytes.Buffer{}
+		pushStr := mockquic.NewMockStream(mockCtrl)
+		pushStr.EXPECT().Write(gomock.Any()).DoAndReturn(pushStrBuf.Write).AnyTimes()
+		pushStr.EXPECT().StreamID().Return(quic.StreamID(15)).AnyTimes()
+		closed := make(chan struct{})
+		pushStr.EXPECT().Close().Do(func() { close(closed) })
+		sess.EXPECT().OpenUniStream().Return(pushStr, nil)
+
+		Expect(rw.Push("/style.css", &http.PushOptions{Header: http.Header{"Accept-Encoding": []string{"gzip"}}})).To(Succeed())
+
+		// the PUSH_PROMISE frame is sent on the request stream
+		frame, err := parseNextFrame(strBuf, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(BeAssignableToTypeOf(&pushPromiseFrame{}))
+		ppf := frame.(*pushPromiseFrame)
+		Expect(ppf.PushID).To(BeZero())
+		fields := decodeHeaderBlock(strBuf, ppf.Length)
+		Expect(fields).To(HaveKeyWithValue(":method", []string{"GET"}))
+		Expect(fields).To(HaveKeyWithValue(":scheme", []string{"https"}))
+		Expect(fields).To(HaveKeyWithValue(":authority", []string{"www.example.com"}))
+		Expect(fields).To(HaveKeyWithValue(":path", []string{"/style.css"}))
+		Expect(fields).To(HaveKeyWithValue("accept-encoding", []string{"gzip"}))
+
+		var pushedReq *http.Request
+		Eventually(handlerCalled).Should(Receive(&pushedReq))
+		Expect(pushedReq.Host).To(Equal("www.example.com"))
+		Expect(pushedReq.URL.Path).To(Equal("/style.css"))
+		Expect(pushedReq.RemoteAddr).To(Equal("127.0.0.1:1337"))
+		Expect(pushedReq.Context().Value(ServerContextKey)).To(Equal(s))
+		Expect(pushedReq.Context().Value(SessionContextKey)).To(Equal(sess))
+		Expect(pushedReq.Context().Value(StreamIDContextKey)).To(Equal(quic.StreamID(15)))
+		Eventually(closed).Should(BeClosed())
+
+		// the response is sent on the push stream
+		r := quicvarint.NewReader(pushStrBuf)
+		streamType, err := quicvarint.Read(r)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(streamType).To(BeEquivalentTo(streamTypePushStream))
+		pushID, err := quicvarint.Read(r)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pushID).To(BeZero())
+		frame, err = parseNextFrame(pushStrBuf, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(BeAssignableToTypeOf(&headersFrame{}))
+		Expect(decodeHeaderBlock(pushStrBuf, frame.(*headersFrame).Length)).To(HaveKeyWithValue(":status", []string{"200"}))
+		frame, err = parseNextFrame(pushStrBuf, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(BeAssignableToTypeOf(&dataFrame{}))
+		body := make([]byte, frame.(*dataFrame).Length)
+		_, err = io.ReadFull(pushStrBuf, body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(Equal("body { color: red; }"))
+	})
+
+	It("doesn't allow pushing from a pushed response", func() {
+		Expect(conn.handleMaxPushID(10)).To(Succeed())
+		isPusher := make(chan bool, 1)
+		s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, ok := w.(http.Pusher)
+			isPusher <- ok
+		})
+		pushStr := mockquic.NewMockStream(mockCtrl)
+		pushStr.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) { return len(p), nil }).AnyTimes()
+		pushStr.EXPECT().StreamID().AnyTimes()
+		closed := make(chan struct{})
+		pushStr.EXPECT().Close().Do(func() { close(closed) })
+		sess.EXPECT().OpenUniStream().Return(pushStr, nil)
+		Expect(rw.Push("/style.css", nil)).To(Succeed())
+		Eventually(isPusher).Should(Receive(BeFalse()))
+		Eventually(closed).Should(BeClosed())
+	})
+
+	It("resets the push stream if the handler panics", func() {
+		Expect(conn.handleMaxPushID(10)).To(Succeed())
+		s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("foobar")
+		})
+		pushStr := mockquic.NewMockStream(mockCtrl)
+		pushStr.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) { return len(p), nil }).AnyTimes()
+		pushStr.EXPECT().StreamID().AnyTimes()
+		reset := make(chan struct{})
+		pushStr.EXPECT().CancelWrite(quic.StreamErrorCode(errorInternalError)).Do(func(quic.StreamErrorCode) { close(reset) })
+		sess.EXPECT().OpenUniStream().Return(pushStr, nil)
+		Expect(rw.Push("/style.css", nil)).To(Succeed())
+		Eventually(reset).Should(BeClosed())
+	})
+
+	It("rejects invalid push requests", func() {
+		Expect(conn.handleMaxPushID(10)).To(Succeed())
+		Expect(rw.Push("/foo", &http.PushOptions{Method: http.MethodPost})).To(MatchError(`method "POST" must be GET or HEAD`))
+		Expect(rw.Push("foo", nil)).To(MatchError(`target must be an absolute URL or an absolute path: "foo"`))
+		Expect(rw.Push("http://www.example.com/foo", nil)).To(MatchError(`cannot push URL with scheme "http" from request with scheme https`))
+		Expect(rw.Push("/foo", &http.PushOptions{Header: http.Header{":path": []string{"/bar"}}})).To(MatchError(`promised request headers cannot include pseudo header ":path"`))
+		// none of the invalid requests used up a push ID
+		id, ok := conn.getPushID()
+		Expect(ok).To(BeTrue())
+		Expect(id).To(BeZero())
+	})
+})
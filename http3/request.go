@@ -12,7 +12,7 @@ import (
 )
 
 func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
-	var path, authority, method, contentLengthStr string
+	var path, authority, method, protocol, scheme, contentLengthStr string
 	httpHeaders := http.Header{}
 
 	for _, h := range headers {
@@ -23,6 +23,10 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 			method = h.Value
 		case ":authority":
 			authority = h.Value
+		case ":protocol":
+			protocol = h.Value
+		case ":scheme":
+			scheme = h.Value
 		case "content-length":
 			contentLengthStr = h.Value
 		default:
@@ -38,7 +42,13 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 	}
 
 	isConnect := method == http.MethodConnect
-	if isConnect {
+	// Extended CONNECT, see RFC 8441, section 4 and RFC 9220
+	isExtendedConnect := isConnect && protocol != ""
+	if isExtendedConnect {
+		if scheme == "" || path == "" || authority == "" {
+			return nil, errors.New("extended CONNECT: :scheme, :path and :authority must not be empty")
+		}
+	} else if isConnect {
 		if path != "" || authority == "" {
 			return nil, errors.New(":path must be empty and :authority must not be empty")
 		}
@@ -50,7 +60,7 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 	var requestURI string
 	var err error
 
-	if isConnect {
+	if isConnect && !isExtendedConnect {
 		u = &url.URL{Host: authority}
 		requestURI = authority
 	} else {
@@ -69,10 +79,15 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 		}
 	}
 
+	proto := "HTTP/3"
+	if isExtendedConnect {
+		proto = protocol
+	}
+
 	return &http.Request{
 		Method:        method,
 		URL:           u,
-		Proto:         "HTTP/3",
+		Proto:         proto,
 		ProtoMajor:    3,
 		ProtoMinor:    0,
 		Header:        httpHeaders,
@@ -137,6 +137,35 @@ var _ = Describe("Request", func() {
 		Expect(err).To(MatchError(":path must be empty and :authority must not be empty"))
 	})
 
+	It("handles extended CONNECT", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":path", Value: "/wt?foo=bar"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":method", Value: http.MethodConnect},
+			{Name: ":protocol", Value: "webtransport"},
+			{Name: ":scheme", Value: "https"},
+		}
+		req, err := requestFromHeaders(headers)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(req.Method).To(Equal(http.MethodConnect))
+		Expect(req.Proto).To(Equal("webtransport"))
+		Expect(req.URL.Path).To(Equal("/wt"))
+		Expect(req.URL.RawQuery).To(Equal("foo=bar"))
+		Expect(req.Host).To(Equal("quic.clemente.io"))
+		Expect(req.RequestURI).To(Equal("/wt?foo=bar"))
+	})
+
+	It("errors with missing scheme in extended CONNECT", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":path", Value: "/wt"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":method", Value: http.MethodConnect},
+			{Name: ":protocol", Value: "webtransport"},
+		}
+		_, err := requestFromHeaders(headers)
+		Expect(err).To(MatchError("extended CONNECT: :scheme, :path and :authority must not be empty"))
+	})
+
 	Context("extracting the hostname from a request", func() {
 		var url *url.URL
 
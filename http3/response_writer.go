@@ -3,6 +3,8 @@ package http3
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -22,14 +24,29 @@ type DataStreamer interface {
 	DataStream() quic.Stream
 }
 
+// Hijacker gives access to the QUIC session that a request was received on.
+// This is used by protocols layered on top of HTTP/3 that open their own streams, e.g. WebTransport.
+type Hijacker interface {
+	Session() quic.Session
+}
+
 type responseWriter struct {
-	stream         quic.Stream // needed for DataStream()
+	sess           quic.Session // needed for Session(), nil for pushed responses
+	stream         quic.Stream  // needed for DataStream()
 	bufferedStream *bufio.Writer
 
 	header         http.Header
-	status         int // status code passed to WriteHeader
+	trailers       map[string]struct{} // the trailers announced in the Trailer header
+	status         int                 // status code passed to WriteHeader
 	headerWritten  bool
-	dataStreamUsed bool // set when DataSteam() is called
+	dataStreamUsed bool // set when DataStream() or Tunnel() is called
+
+	connectBody *body // the body of a CONNECT request, needed for Tunnel()
+
+	prioritized *prioritizedStream // nil if the response is not scheduled, e.g. for pushed responses
+
+	push      func(target string, opts *http.PushOptions) error // nil if pushing is not possible
+	datagrams *streamDatagrams                                  // nil if HTTP datagrams are not enabled
 
 	logger utils.Logger
 }
@@ -38,6 +55,10 @@ var (
 	_ http.ResponseWriter = &responseWriter{}
 	_ http.Flusher        = &responseWriter{}
 	_ DataStreamer        = &responseWriter{}
+	_ Datagrammer         = &responseWriter{}
+	_ Hijacker            = &responseWriter{}
+	_ PrioritySetter      = &responseWriter{}
+	_ Tunneler            = &responseWriter{}
 )
 
 func newResponseWriter(stream quic.Stream, logger utils.Logger) *responseWriter {
@@ -60,6 +81,7 @@ func (w *responseWriter) WriteHeader(status int) {
 
 	if status < 100 || status >= 200 {
 		w.headerWritten = true
+		w.declareTrailers()
 	}
 	w.status = status
 
@@ -68,6 +90,9 @@ func (w *responseWriter) WriteHeader(status int) {
 	enc.WriteField(qpack.HeaderField{Name: ":status", Value: strconv.Itoa(status)})
 
 	for k, v := range w.header {
+		if w.isTrailer(k) {
+			continue
+		}
 		for index := range v {
 			enc.WriteField(qpack.HeaderField{Name: strings.ToLower(k), Value: v[index]})
 		}
@@ -103,6 +128,63 @@ func (w *responseWriter) Write(p []byte) (int, error) {
 	return w.bufferedStream.Write(p)
 }
 
+// declareTrailers records the trailers announced in the Trailer header.
+// It is called when the header is written, see the documentation of http.ResponseWriter.
+func (w *responseWriter) declareTrailers() {
+	for _, v := range w.header["Trailer"] {
+		for _, k := range strings.Split(v, ",") {
+			k = http.CanonicalHeaderKey(strings.TrimSpace(k))
+			if k == "" {
+				continue
+			}
+			if w.trailers == nil {
+				w.trailers = make(map[string]struct{})
+			}
+			w.trailers[k] = struct{}{}
+		}
+	}
+}
+
+// isTrailer says if a header field is sent in the trailers, instead of the header.
+func (w *responseWriter) isTrailer(k string) bool {
+	if strings.HasPrefix(k, http.TrailerPrefix) {
+		return true
+	}
+	_, ok := w.trailers[k]
+	return ok
+}
+
+// writeTrailers writes the trailers in a HEADERS frame following the response body.
+// Trailers are the header fields announced in the Trailer header before the header was written,
+// as well as header fields prefixed with http.TrailerPrefix.
+func (w *responseWriter) writeTrailers() {
+	var headers bytes.Buffer
+	enc := qpack.NewEncoder(&headers)
+	var hasTrailers bool
+	for k, v := range w.header {
+		if !w.isTrailer(k) {
+			continue
+		}
+		name := strings.ToLower(strings.TrimPrefix(k, http.TrailerPrefix))
+		for index := range v {
+			enc.WriteField(qpack.HeaderField{Name: name, Value: v[index]})
+			hasTrailers = true
+		}
+	}
+	if !hasTrailers {
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	(&headersFrame{Length: uint64(headers.Len())}).Write(buf)
+	if _, err := w.bufferedStream.Write(buf.Bytes()); err != nil {
+		w.logger.Errorf("could not write trailers frame: %s", err.Error())
+	}
+	if _, err := w.bufferedStream.Write(headers.Bytes()); err != nil {
+		w.logger.Errorf("could not write trailers frame payload: %s", err.Error())
+	}
+}
+
 func (w *responseWriter) Flush() {
 	if err := w.bufferedStream.Flush(); err != nil {
 		w.logger.Errorf("could not flush to stream: %s", err.Error())
@@ -119,6 +201,45 @@ func (w *responseWriter) DataStream() quic.Stream {
 	return w.stream
 }
 
+func (w *responseWriter) Tunnel() (TunnelConn, error) {
+	if w.connectBody == nil {
+		return nil, errNotConnect
+	}
+	if !w.headerWritten {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.status < 200 || w.status >= 300 {
+		return nil, fmt.Errorf("http3: cannot tunnel after sending a %d response", w.status)
+	}
+	w.dataStreamUsed = true
+	w.Flush()
+	return newTunnelConn(w.sess, w.stream, w.connectBody), nil
+}
+
+func (w *responseWriter) Session() quic.Session {
+	return w.sess
+}
+
+func (w *responseWriter) SetPriority(p Priority) {
+	if w.prioritized != nil {
+		w.prioritized.setPriority(p)
+	}
+}
+
+func (w *responseWriter) SendDatagram(data []byte) error {
+	if w.datagrams == nil {
+		return errDatagramsNotEnabled
+	}
+	return w.datagrams.SendDatagram(data)
+}
+
+func (w *responseWriter) ReceiveDatagram(ctx context.Context) ([]byte, error) {
+	if w.datagrams == nil {
+		return nil, errDatagramsNotEnabled
+	}
+	return w.datagrams.ReceiveDatagram(ctx)
+}
+
 // copied from http2/http2.go
 // bodyAllowedForStatus reports whether a given response status code
 // permits a body. See RFC 2616, section 4.4.
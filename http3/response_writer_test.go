@@ -33,7 +33,7 @@ var _ = Describe("Response Writer", func() {
 		fields := make(map[string][]string)
 		decoder := qpack.NewDecoder(nil)
 
-		frame, err := parseNextFrame(str)
+		frame, err := parseNextFrame(str, nil)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(frame).To(BeAssignableToTypeOf(&headersFrame{}))
 		headersFrame := frame.(*headersFrame)
@@ -49,7 +49,7 @@ var _ = Describe("Response Writer", func() {
 	}
 
 	getData := func(str io.Reader) []byte {
-		frame, err := parseNextFrame(str)
+		frame, err := parseNextFrame(str, nil)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(frame).To(BeAssignableToTypeOf(&dataFrame{}))
 		df := frame.(*dataFrame)
@@ -147,4 +147,43 @@ var _ = Describe("Response Writer", func() {
 		Expect(n).To(BeZero())
 		Expect(err).To(MatchError(http.ErrBodyNotAllowed))
 	})
+
+	It("writes trailers announced in the Trailer header", func() {
+		rw.Header().Set("Trailer", "Foo, bar")
+		rw.Header().Set("Foo", "ignored")
+		rw.WriteHeader(http.StatusOK)
+		rw.Write([]byte("foobar"))
+		rw.Header().Set("Foo", "1")
+		rw.Header().Set("Bar", "2")
+		rw.Header().Set("Baz", "not a trailer")
+		rw.writeTrailers()
+		fields := decodeHeader(strBuf)
+		Expect(fields).To(HaveKeyWithValue("trailer", []string{"Foo, bar"}))
+		Expect(fields).ToNot(HaveKey("foo"))
+		Expect(getData(strBuf)).To(Equal([]byte("foobar")))
+		fields = decodeHeader(strBuf)
+		Expect(fields).To(HaveLen(2))
+		Expect(fields).To(HaveKeyWithValue("foo", []string{"1"}))
+		Expect(fields).To(HaveKeyWithValue("bar", []string{"2"}))
+	})
+
+	It("writes trailers using the TrailerPrefix", func() {
+		rw.Write([]byte("foobar"))
+		rw.Header().Set(http.TrailerPrefix+"Foo", "1")
+		rw.writeTrailers()
+		fields := decodeHeader(strBuf)
+		Expect(fields).To(HaveKeyWithValue(":status", []string{"200"}))
+		Expect(getData(strBuf)).To(Equal([]byte("foobar")))
+		fields = decodeHeader(strBuf)
+		Expect(fields).To(HaveLen(1))
+		Expect(fields).To(HaveKeyWithValue("foo", []string{"1"}))
+	})
+
+	It("doesn't write a HEADERS frame if there are no trailers", func() {
+		rw.Header().Set("Trailer", "Foo")
+		rw.WriteHeader(http.StatusOK)
+		rw.writeTrailers()
+		decodeHeader(strBuf)
+		Expect(strBuf.Len()).To(BeZero())
+	})
 })
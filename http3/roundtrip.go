@@ -5,11 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	quic "github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 
 	"golang.org/x/net/http/httpguts"
 )
@@ -17,9 +21,27 @@ import (
 type roundTripCloser interface {
 	http.RoundTripper
 	io.Closer
+	// tryRoundTrip is like RoundTrip, but it doesn't wait for a stream to become available.
+	// If the server's stream limit is reached, it returns errNoStreamAvailable, and the request is not sent.
+	tryRoundTrip(*http.Request) (*http.Response, error)
+	// closeIfIdle closes the connection, if no requests are in flight.
+	closeIfIdle() bool
 }
 
 // RoundTripper implements the http.RoundTripper interface
+//
+// When resuming a session with a server that accepts 0-RTT, requests are sent before the handshake completes,
+// saving a round trip. Since 0-RTT data can be replayed by an attacker, this is only done for requests that are
+// safe to repeat: requests using the GET, HEAD, OPTIONS and TRACE methods, and requests carrying an Idempotency-Key
+// or X-Idempotency-Key header. Other requests, e.g. POST requests, are sent once the handshake has completed.
+// In any case, the request body must be empty, or rewindable using Request.GetBody.
+// If the server rejects 0-RTT, these requests are automatically sent again after the handshake.
+//
+// Requests to the same host are multiplexed on a single QUIC connection. Only once the server's stream limit
+// on all connections to a host is reached, a new connection is dialed (up to MaxConnsPerHost connections).
+//
+// If a Fallback is set, HTTP/3 is only used for origins that advertised an HTTP/3 alternative service
+// in the Alt-Svc header field (RFC 7838) of a previous response. All other requests are sent using the Fallback.
 type RoundTripper struct {
 	mutex sync.Mutex
 
@@ -35,6 +57,9 @@ type RoundTripper struct {
 
 	// TLSClientConfig specifies the TLS configuration to use with
 	// tls.Client. If nil, the default configuration is used.
+	// The session tickets issued by servers are stored in its ClientSessionCache,
+	// and are used to resume sessions using 0-RTT.
+	// If no ClientSessionCache is set, a cache shared by all connections of this RoundTripper is used.
 	TLSClientConfig *tls.Config
 
 	// QuicConfig is the quic.Config used for dialing new connections.
@@ -43,9 +68,20 @@ type RoundTripper struct {
 
 	// Enable support for HTTP/3 datagrams.
 	// If set to true, QuicConfig.EnableDatagram will be set.
-	// See https://www.ietf.org/archive/id/draft-schinazi-masque-h3-datagram-02.html.
+	// Datagrams are sent and received using the Datagrammer interface.
+	// See RFC 9297.
 	EnableDatagrams bool
 
+	// The SETTINGS frame sent on every connection contains these settings, in addition to the settings defined by HTTP/3.
+	// This is used by protocols layered on top of HTTP/3, e.g. WebTransport.
+	AdditionalSettings map[uint64]uint64
+
+	// OnSettingsReceived, when set, is called when the SETTINGS frame is received on the server's control stream.
+	// It is passed the settings that aren't defined by HTTP/3, e.g. the settings of protocols layered on top of HTTP/3.
+	// Settings that neither HTTP/3 nor the application understands are ignored, as required by the specification.
+	// The control stream is blocked until the callback returns.
+	OnSettingsReceived func(quic.Session, map[uint64]uint64)
+
 	// Dial specifies an optional dial function for creating QUIC
 	// connections for requests.
 	// If Dial is nil, quic.DialAddrEarly will be used.
@@ -56,7 +92,45 @@ type RoundTripper struct {
 	// Zero means to use a default limit.
 	MaxResponseHeaderBytes int64
 
-	clients map[string]roundTripCloser
+	// MaxConnsPerHost limits the number of connections per host.
+	// Once the limit is reached, and the server's stream limit is reached on all of them,
+	// requests wait until a stream becomes available.
+	// Zero means no limit.
+	MaxConnsPerHost int
+
+	// MaxIdleConns limits the number of idle connections, across all hosts.
+	// A connection is idle if no requests are in flight.
+	// If the limit is exceeded, the connection that has been idle for the longest time is closed.
+	// Zero means no limit.
+	MaxIdleConns int
+
+	// IdleConnTimeout is the maximum amount of time an idle connection remains open.
+	// Zero means no limit.
+	IdleConnTimeout time.Duration
+
+	// Fallback is used for requests to origins that didn't advertise an HTTP/3 alternative service,
+	// e.g. a http.Transport. The Alt-Svc header field of its responses is stored in the AltSvcCache.
+	// Requests are also sent using the Fallback if the connection to the alternative service can't be established.
+	// If nil, all requests are sent using HTTP/3, and the Alt-Svc header field is ignored.
+	Fallback http.RoundTripper
+
+	// AltSvcCache stores the alternative services advertised by origins.
+	// It is only used if a Fallback is set.
+	// If nil, an in-memory cache is used.
+	AltSvcCache AltSvcCache
+
+	clients      map[string][]roundTripCloser // the connections to each host, in the order they were dialed
+	idle         []*idleClient                // the idle connections, the least recently used first
+	sessionCache tls.ClientSessionCache       // used if TLSClientConfig doesn't set a ClientSessionCache
+	altSvcCache  AltSvcCache                  // used if no AltSvcCache is set
+	altSvcBroken map[string]time.Time         // alternative services that couldn't be dialed, and when to try them again
+}
+
+// An idleClient is a connection without any requests in flight.
+type idleClient struct {
+	hostname string
+	client   roundTripCloser
+	timer    *time.Timer // nil if no IdleConnTimeout is set
 }
 
 // RoundTripOpt are options for the Transport.RoundTripOpt method.
@@ -69,13 +143,29 @@ type RoundTripOpt struct {
 	SkipSchemeCheck bool
 }
 
-var _ roundTripCloser = &RoundTripper{}
+var (
+	_ http.RoundTripper = &RoundTripper{}
+	_ io.Closer         = &RoundTripper{}
+)
 
 // ErrNoCachedConn is returned when RoundTripper.OnlyCachedConn is set
 var ErrNoCachedConn = errors.New("http3: no cached connection was available")
 
+// an alternative service that couldn't be dialed isn't used for this long
+const altSvcBrokenDuration = 5 * time.Minute
+
 // RoundTripOpt is like RoundTrip, but takes options.
 func (r *RoundTripper) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.Response, error) {
+	rsp, err := r.roundTripOpt(req, opt, "")
+	if derr, ok := err.(*dialError); ok {
+		return nil, derr.err
+	}
+	return rsp, err
+}
+
+// roundTripOpt sends the request to addr. If addr is empty, it is sent to the host of the request.
+// If the connection couldn't be established, a *dialError is returned.
+func (r *RoundTripper) roundTripOpt(req *http.Request, opt RoundTripOpt, addr string) (*http.Response, error) {
 	if req.URL == nil {
 		closeRequestBody(req)
 		return nil, errors.New("http3: nil Request.URL")
@@ -111,58 +201,365 @@ func (r *RoundTripper) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.
 	}
 
 	hostname := authorityAddr("https", hostnameFromRequest(req))
-	cl, err := r.getClient(hostname, opt.OnlyCachedConn)
+	if addr == "" {
+		addr = hostname
+	}
+	rsp, err := r.roundTrip(req, hostname, addr, opt.OnlyCachedConn)
+	if err != errGoAway {
+		return rsp, err
+	}
+	// The server is shutting down the connection, and didn't process the request.
+	// Requests that are still in flight on the old connection are allowed to complete,
+	// so we don't close it here. New requests are sent on a new connection.
+	if opt.OnlyCachedConn {
+		return nil, ErrNoCachedConn
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return nil, err
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		newReq := *req
+		newReq.Body = body
+		req = &newReq
+	}
+	return r.roundTrip(req, hostname, addr, false)
+}
+
+// roundTrip sends the request on the first connection to hostname that doesn't block on the server's stream limit.
+// If there's no such connection, a new connection is dialed to addr, unless MaxConnsPerHost is reached.
+// If a connection was shut down by the server, or couldn't be established, it is removed.
+func (r *RoundTripper) roundTrip(req *http.Request, hostname, addr string, onlyCached bool) (*http.Response, error) {
+	key := poolKey(hostname, addr)
+	for _, cl := range r.getClients(key) {
+		r.useClient(cl)
+		rsp, err := cl.tryRoundTrip(req)
+		if err == errNoStreamAvailable {
+			continue
+		}
+		r.checkClient(key, cl, err)
+		return rsp, err
+	}
+	cl, err := r.getClient(hostname, addr, onlyCached)
 	if err != nil {
 		return nil, err
 	}
-	return cl.RoundTrip(req)
+	r.useClient(cl)
+	rsp, err := cl.RoundTrip(req)
+	r.checkClient(key, cl, err)
+	return rsp, err
+}
+
+// checkClient removes a connection that can't be used for any more requests.
+func (r *RoundTripper) checkClient(key string, cl roundTripCloser, err error) {
+	if _, ok := err.(*dialError); ok || err == errGoAway {
+		r.removeClient(key, cl)
+	}
+}
+
+// poolKey returns the key of the connections to hostname that are dialed to addr.
+func poolKey(hostname, addr string) string {
+	if addr == hostname {
+		return hostname
+	}
+	return hostname + " via " + addr
 }
 
 // RoundTrip does a round trip.
 func (r *RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.Fallback != nil {
+		return r.roundTripAltSvc(req)
+	}
 	return r.RoundTripOpt(req, RoundTripOpt{})
 }
 
-func (r *RoundTripper) getClient(hostname string, onlyCached bool) (http.RoundTripper, error) {
+// roundTripAltSvc sends the request using HTTP/3 if the origin advertised an alternative service,
+// and using the Fallback otherwise.
+func (r *RoundTripper) roundTripAltSvc(req *http.Request) (*http.Response, error) {
+	if req.URL == nil || req.URL.Scheme != "https" || req.URL.Host == "" {
+		return r.Fallback.RoundTrip(req)
+	}
+	origin := authorityAddr("https", hostnameFromRequest(req))
+	cache := r.getAltSvcCache()
+	if addr, ok := r.getAltSvc(cache, origin); ok {
+		rsp, err := r.roundTripOpt(req, RoundTripOpt{}, addr)
+		derr, ok := err.(*dialError)
+		if !ok {
+			if err != nil {
+				return nil, err
+			}
+			r.saveAltSvc(cache, origin, rsp)
+			return rsp, nil
+		}
+		// The alternative service can't be used. Until it is advertised again, requests are sent to the origin.
+		utils.DefaultLogger.Debugf("Dialing alternative service %s for %s failed: %s", addr, origin, derr.err)
+		r.mutex.Lock()
+		if r.altSvcBroken == nil {
+			r.altSvcBroken = make(map[string]time.Time)
+		}
+		r.altSvcBroken[addr] = time.Now().Add(altSvcBrokenDuration)
+		r.mutex.Unlock()
+		cache.Put(origin, nil)
+	}
+	rsp, err := r.Fallback.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	r.saveAltSvc(cache, origin, rsp)
+	return rsp, nil
+}
+
+func (r *RoundTripper) getAltSvcCache() AltSvcCache {
+	if r.AltSvcCache != nil {
+		return r.AltSvcCache
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.altSvcCache == nil {
+		r.altSvcCache = NewAltSvcCache()
+	}
+	return r.altSvcCache
+}
+
+// getAltSvc returns the address of an HTTP/3 alternative service of the origin, if there is one that can be used.
+func (r *RoundTripper) getAltSvc(cache AltSvcCache, origin string) (string, bool) {
+	version := defaultQuicConfig.Versions[0]
+	if r.QuicConfig != nil && len(r.QuicConfig.Versions) > 0 {
+		version = r.QuicConfig.Versions[0]
+	}
+	protocol := versionToALPN(version)
+	svcs := cache.Get(origin)
+	now := time.Now()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, svc := range svcs {
+		if svc.Protocol != protocol || !now.Before(svc.Expires) {
+			continue
+		}
+		host := svc.Host
+		if host == "" {
+			host, _, _ = net.SplitHostPort(origin)
+		}
+		addr := net.JoinHostPort(host, strconv.Itoa(int(svc.Port)))
+		if t, ok := r.altSvcBroken[addr]; ok {
+			if now.Before(t) {
+				continue
+			}
+			delete(r.altSvcBroken, addr)
+		}
+		return addr, true
+	}
+	return "", false
+}
+
+// saveAltSvc stores the alternative services advertised in the Alt-Svc header field of the response.
+func (r *RoundTripper) saveAltSvc(cache AltSvcCache, origin string, rsp *http.Response) {
+	values := rsp.Header.Values("Alt-Svc")
+	if len(values) == 0 {
+		return
+	}
+	svcs, clear := parseAltSvc(strings.Join(values, ","), time.Now())
+	if clear || len(svcs) > 0 {
+		cache.Put(origin, svcs)
+	}
+}
+
+// getClients returns the connections to hostname.
+func (r *RoundTripper) getClients(hostname string) []roundTripCloser {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.clients[hostname]
+}
+
+// useClient is called before a connection is used for a request.
+// The connection is no longer idle.
+func (r *RoundTripper) useClient(cl roundTripCloser) {
+	r.mutex.Lock()
+	r.removeIdle(cl)
+	r.mutex.Unlock()
+}
+
+// getClient returns the connection used for a request if all existing connections to hostname reached the server's
+// stream limit. A new connection to addr is created, unless MaxConnsPerHost is reached. In that case, the request
+// is sent on the oldest connection, once a stream becomes available.
+func (r *RoundTripper) getClient(hostname, addr string, onlyCached bool) (roundTripCloser, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
 	if r.clients == nil {
-		r.clients = make(map[string]roundTripCloser)
-	}
-
-	client, ok := r.clients[hostname]
-	if !ok {
-		if onlyCached {
-			return nil, ErrNoCachedConn
-		}
-		var err error
-		client, err = newClient(
-			hostname,
-			r.TLSClientConfig,
-			&roundTripperOpts{
-				EnableDatagram:     r.EnableDatagrams,
-				DisableCompression: r.DisableCompression,
-				MaxHeaderBytes:     r.MaxResponseHeaderBytes,
-			},
-			r.QuicConfig,
-			r.Dial,
-		)
-		if err != nil {
-			return nil, err
+		r.clients = make(map[string][]roundTripCloser)
+	}
+
+	key := poolKey(hostname, addr)
+	clients := r.clients[key]
+	if len(clients) > 0 && (onlyCached || (r.MaxConnsPerHost > 0 && len(clients) >= r.MaxConnsPerHost)) {
+		return clients[0], nil
+	}
+	if onlyCached {
+		return nil, ErrNoCachedConn
+	}
+	tlsConf := r.TLSClientConfig
+	if tlsConf == nil || tlsConf.ClientSessionCache == nil {
+		if r.sessionCache == nil {
+			r.sessionCache = tls.NewLRUClientSessionCache(0)
 		}
-		r.clients[hostname] = client
+		if tlsConf == nil {
+			tlsConf = &tls.Config{}
+		} else {
+			tlsConf = tlsConf.Clone()
+		}
+		tlsConf.ClientSessionCache = r.sessionCache
+	}
+	if addr != hostname && tlsConf.ServerName == "" {
+		// the certificate of the alternative service must be valid for the origin
+		tlsConf = tlsConf.Clone()
+		tlsConf.ServerName, _, _ = net.SplitHostPort(hostname)
 	}
+	client, err := newClient(
+		hostname,
+		tlsConf,
+		&roundTripperOpts{
+			EnableDatagram:     r.EnableDatagrams,
+			DisableCompression: r.DisableCompression,
+			MaxHeaderBytes:     r.MaxResponseHeaderBytes,
+			AdditionalSettings: r.AdditionalSettings,
+			OnSettingsReceived: r.OnSettingsReceived,
+		},
+		r.QuicConfig,
+		r.Dial,
+	)
+	if err != nil {
+		return nil, err
+	}
+	client.addr = addr
+	client.onIdle = func() { r.clientIdle(key, client) }
+	r.clients[key] = append(clients, client)
 	return client, nil
 }
 
+// removeClient removes the client, if it is still used for this hostname.
+func (r *RoundTripper) removeClient(hostname string, cl roundTripCloser) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.removeClientLocked(hostname, cl)
+}
+
+// removeClientLocked is like removeClient, but must be called with the mutex held.
+func (r *RoundTripper) removeClientLocked(hostname string, cl roundTripCloser) {
+	r.removeIdle(cl)
+	clients := r.clients[hostname]
+	for i, c := range clients {
+		if c != cl {
+			continue
+		}
+		clients = append(clients[:i:i], clients[i+1:]...)
+		if len(clients) == 0 {
+			delete(r.clients, hostname)
+		} else {
+			r.clients[hostname] = clients
+		}
+		return
+	}
+}
+
+// clientIdle is called when the last request in flight on a connection completes.
+func (r *RoundTripper) clientIdle(hostname string, cl roundTripCloser) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !r.hasClient(hostname, cl) {
+		return
+	}
+	r.removeIdle(cl)
+	ic := &idleClient{hostname: hostname, client: cl}
+	if r.IdleConnTimeout > 0 {
+		ic.timer = time.AfterFunc(r.IdleConnTimeout, func() { r.closeIdleClient(ic) })
+	}
+	r.idle = append(r.idle, ic)
+	if r.MaxIdleConns > 0 && len(r.idle) > r.MaxIdleConns {
+		r.closeIdleClientLocked(r.idle[0])
+	}
+}
+
+// closeIdleClient is called when the IdleConnTimeout of a connection expires.
+func (r *RoundTripper) closeIdleClient(ic *idleClient) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, c := range r.idle {
+		if c == ic {
+			r.closeIdleClientLocked(ic)
+			return
+		}
+	}
+}
+
+// closeIdleClientLocked closes an idle connection.
+// If a request was started on the connection in the meantime, the connection is kept.
+// It must be called with the mutex held.
+func (r *RoundTripper) closeIdleClientLocked(ic *idleClient) {
+	r.removeIdle(ic.client)
+	if ic.client.closeIfIdle() {
+		r.removeClientLocked(ic.hostname, ic.client)
+	}
+}
+
+// removeIdle removes a connection from the list of idle connections.
+// It must be called with the mutex held.
+func (r *RoundTripper) removeIdle(cl roundTripCloser) {
+	for i, ic := range r.idle {
+		if ic.client != cl {
+			continue
+		}
+		if ic.timer != nil {
+			ic.timer.Stop()
+		}
+		r.idle = append(r.idle[:i:i], r.idle[i+1:]...)
+		return
+	}
+}
+
+// hasClient says if the connection is used for this hostname.
+// It must be called with the mutex held.
+func (r *RoundTripper) hasClient(hostname string, cl roundTripCloser) bool {
+	for _, c := range r.clients[hostname] {
+		if c == cl {
+			return true
+		}
+	}
+	return false
+}
+
+// CloseIdleConnections closes all connections that don't have any requests in flight.
+// It doesn't interrupt any connections currently in use.
+func (r *RoundTripper) CloseIdleConnections() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for len(r.idle) > 0 {
+		r.closeIdleClientLocked(r.idle[0])
+	}
+}
+
 // Close closes the QUIC connections that this RoundTripper has used
 func (r *RoundTripper) Close() error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
-	for _, client := range r.clients {
-		if err := client.Close(); err != nil {
-			return err
+	for _, ic := range r.idle {
+		if ic.timer != nil {
+			ic.timer.Stop()
+		}
+	}
+	r.idle = nil
+	for _, clients := range r.clients {
+		for _, client := range clients {
+			if err := client.Close(); err != nil {
+				return err
+			}
 		}
 	}
 	r.clients = nil
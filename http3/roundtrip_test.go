@@ -17,13 +17,41 @@ import (
 )
 
 type mockClient struct {
-	closed bool
+	closed   bool
+	rtErr    error // returned by RoundTrip, if set
+	rtCalls  int
+	full     bool // if set, tryRoundTrip returns errNoStreamAvailable
+	tryCalls int
+	busy     bool // if set, closeIfIdle doesn't close the client
 }
 
 func (m *mockClient) RoundTrip(req *http.Request) (*http.Response, error) {
+	m.rtCalls++
+	if m.rtErr != nil {
+		return nil, m.rtErr
+	}
+	return &http.Response{Request: req}, nil
+}
+
+func (m *mockClient) tryRoundTrip(req *http.Request) (*http.Response, error) {
+	m.tryCalls++
+	if m.full {
+		return nil, errNoStreamAvailable
+	}
+	if m.rtErr != nil {
+		return nil, m.rtErr
+	}
 	return &http.Response{Request: req}, nil
 }
 
+func (m *mockClient) closeIfIdle() bool {
+	if m.busy {
+		return false
+	}
+	m.closed = true
+	return true
+}
+
 func (m *mockClient) Close() error {
 	m.closed = true
 	return nil
@@ -31,6 +59,23 @@ func (m *mockClient) Close() error {
 
 var _ roundTripCloser = &mockClient{}
 
+type mockFallback struct {
+	header http.Header // sent in every response
+	calls  int
+}
+
+func (m *mockFallback) RoundTrip(req *http.Request) (*http.Response, error) {
+	m.calls++
+	return &http.Response{Request: req, Header: m.header.Clone()}, nil
+}
+
+type mockAltSvcCache struct {
+	svcs []AltSvc
+}
+
+func (m *mockAltSvcCache) Get(string) []AltSvc         { return m.svcs }
+func (m *mockAltSvcCache) Put(_ string, svcs []AltSvc) { m.svcs = svcs }
+
 type mockBody struct {
 	reader   bytes.Reader
 	readErr  error
@@ -99,7 +144,6 @@ var _ = Describe("RoundTripper", func() {
 			req, err := http.NewRequest("GET", "https://quic.clemente.io/foobar.html", nil)
 			Expect(err).ToNot(HaveOccurred())
 			session.EXPECT().OpenUniStream().AnyTimes().Return(nil, testErr)
-			session.EXPECT().HandshakeComplete().Return(handshakeCtx)
 			session.EXPECT().OpenStreamSync(context.Background()).Return(nil, testErr)
 			session.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
 				<-closed
@@ -137,12 +181,47 @@ var _ = Describe("RoundTripper", func() {
 			Expect(dialed).To(BeTrue())
 		})
 
+		It("uses a session cache shared by all connections", func() {
+			var tlsConfs []*tls.Config
+			rt.Dial = func(_, _ string, tlsConf *tls.Config, _ *quic.Config) (quic.EarlySession, error) {
+				tlsConfs = append(tlsConfs, tlsConf)
+				return nil, errors.New("handshake error")
+			}
+			rt.TLSClientConfig = &tls.Config{ServerName: "foo.bar"}
+			_, err := rt.RoundTrip(req1)
+			Expect(err).To(MatchError("handshake error"))
+			req2, err := http.NewRequest("GET", "https://example.com/foobar.html", nil)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rt.RoundTrip(req2)
+			Expect(err).To(MatchError("handshake error"))
+			Expect(tlsConfs).To(HaveLen(2))
+			Expect(tlsConfs[0].ServerName).To(Equal("foo.bar"))
+			Expect(tlsConfs[0].ClientSessionCache).ToNot(BeNil())
+			Expect(tlsConfs[0].ClientSessionCache).To(BeIdenticalTo(tlsConfs[1].ClientSessionCache))
+			Expect(rt.TLSClientConfig.ClientSessionCache).To(BeNil())
+		})
+
+		It("uses the session cache from the tls.Config, if provided", func() {
+			cache := tls.NewLRUClientSessionCache(1)
+			var tlsConf *tls.Config
+			rt.Dial = func(_, _ string, tlsCfg *tls.Config, _ *quic.Config) (quic.EarlySession, error) {
+				tlsConf = tlsCfg
+				return nil, errors.New("handshake error")
+			}
+			rt.TLSClientConfig = &tls.Config{ClientSessionCache: cache}
+			_, err := rt.RoundTrip(req1)
+			Expect(err).To(MatchError("handshake error"))
+			Expect(tlsConf.ClientSessionCache).To(BeIdenticalTo(cache))
+		})
+
 		It("reuses existing clients", func() {
 			closed := make(chan struct{})
 			testErr := errors.New("test err")
 			session.EXPECT().OpenUniStream().AnyTimes().Return(nil, testErr)
-			session.EXPECT().HandshakeComplete().Return(handshakeCtx).Times(2)
-			session.EXPECT().OpenStreamSync(context.Background()).Return(nil, testErr).Times(2)
+			session.EXPECT().OpenStreamSync(context.Background()).Return(nil, testErr)
+			// the second request is sent on the existing connection, without waiting for a stream
+			session.EXPECT().HandshakeComplete().Return(handshakeCtx)
+			session.EXPECT().OpenStream().Return(nil, testErr)
 			session.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
 				<-closed
 				return nil, errors.New("test done")
@@ -161,12 +240,400 @@ var _ = Describe("RoundTripper", func() {
 			Eventually(closed).Should(BeClosed())
 		})
 
+		Context("retrying after GOAWAY", func() {
+			var goingAway *mockClient
+
+			BeforeEach(func() {
+				goingAway = &mockClient{rtErr: errGoAway}
+				rt.clients = map[string][]roundTripCloser{"quic.clemente.io:443": {goingAway}}
+			})
+
+			It("retries requests on a new connection", func() {
+				closed := make(chan struct{})
+				testErr := errors.New("test err")
+				session.EXPECT().OpenUniStream().AnyTimes().Return(nil, testErr)
+				session.EXPECT().HandshakeComplete().Return(handshakeCtx)
+				session.EXPECT().OpenStreamSync(gomock.Any()).Return(nil, testErr)
+				session.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+					<-closed
+					return nil, errors.New("test done")
+				}).MaxTimes(1)
+				session.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(quic.ApplicationErrorCode, string) { close(closed) })
+				req, err := http.NewRequest("POST", "https://quic.clemente.io/foobar.html", bytes.NewReader([]byte("foobar")))
+				Expect(err).ToNot(HaveOccurred())
+				getBody := req.GetBody
+				var rewound bool
+				req.GetBody = func() (io.ReadCloser, error) {
+					rewound = true
+					return getBody()
+				}
+				_, err = rt.RoundTrip(req)
+				Expect(err).To(MatchError(testErr))
+				Expect(goingAway.tryCalls).To(Equal(1))
+				Expect(rewound).To(BeTrue())
+				// the client is replaced, but not closed, since requests might still be in flight
+				Expect(rt.clients).To(HaveLen(1))
+				Expect(rt.clients["quic.clemente.io:443"]).To(HaveLen(1))
+				Expect(rt.clients["quic.clemente.io:443"][0]).ToNot(Equal(goingAway))
+				Expect(goingAway.closed).To(BeFalse())
+				Eventually(closed).Should(BeClosed())
+			})
+
+			It("doesn't retry requests with a body that can't be rewound", func() {
+				req, err := http.NewRequest("POST", "https://quic.clemente.io/foobar.html", &mockBody{})
+				Expect(err).ToNot(HaveOccurred())
+				_, err = rt.RoundTrip(req)
+				Expect(err).To(MatchError(errGoAway))
+				Expect(rt.clients).To(BeEmpty())
+			})
+
+			It("doesn't dial a new connection if RoundTripOpt.OnlyCachedConn is set", func() {
+				req, err := http.NewRequest("GET", "https://quic.clemente.io/foobar.html", nil)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = rt.RoundTripOpt(req, RoundTripOpt{OnlyCachedConn: true})
+				Expect(err).To(MatchError(ErrNoCachedConn))
+				Expect(rt.clients).To(BeEmpty())
+			})
+		})
+
 		It("doesn't create new clients if RoundTripOpt.OnlyCachedConn is set", func() {
 			req, err := http.NewRequest("GET", "https://quic.clemente.io/foobar.html", nil)
 			Expect(err).ToNot(HaveOccurred())
 			_, err = rt.RoundTripOpt(req, RoundTripOpt{OnlyCachedConn: true})
 			Expect(err).To(MatchError(ErrNoCachedConn))
 		})
+
+		Context("pooling connections", func() {
+			const hostname = "quic.clemente.io:443"
+			var req *http.Request
+
+			BeforeEach(func() {
+				var err error
+				req, err = http.NewRequest("GET", "https://quic.clemente.io/foobar.html", nil)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("sends requests on the first connection that has a stream available", func() {
+				cl1 := &mockClient{full: true}
+				cl2 := &mockClient{}
+				cl3 := &mockClient{}
+				rt.clients = map[string][]roundTripCloser{hostname: {cl1, cl2, cl3}}
+				_, err := rt.RoundTrip(req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cl1.tryCalls).To(Equal(1))
+				Expect(cl2.tryCalls).To(Equal(1))
+				Expect(cl3.tryCalls).To(BeZero())
+				Expect(rt.clients[hostname]).To(HaveLen(3))
+			})
+
+			It("keeps connections idle that weren't used for a request", func() {
+				cl1 := &mockClient{}
+				cl2 := &mockClient{}
+				rt.clients = map[string][]roundTripCloser{hostname: {cl1, cl2}}
+				rt.clientIdle(hostname, cl1)
+				rt.clientIdle(hostname, cl2)
+				_, err := rt.RoundTrip(req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cl1.tryCalls).To(Equal(1))
+				Expect(rt.idle).To(HaveLen(1))
+				Expect(rt.idle[0].client).To(Equal(cl2))
+			})
+
+			It("dials a new connection if the stream limit is reached on all connections", func() {
+				var dialed int
+				dialAddr = func(string, *tls.Config, *quic.Config) (quic.EarlySession, error) {
+					dialed++
+					return nil, errors.New("handshake error")
+				}
+				cl := &mockClient{full: true}
+				rt.clients = map[string][]roundTripCloser{hostname: {cl}}
+				_, err := rt.RoundTrip(req)
+				Expect(err).To(MatchError("handshake error"))
+				Expect(dialed).To(Equal(1))
+				Expect(cl.rtCalls).To(BeZero())
+			})
+
+			It("removes connections that couldn't be established", func() {
+				var dialed int
+				dialAddr = func(string, *tls.Config, *quic.Config) (quic.EarlySession, error) {
+					dialed++
+					return nil, errors.New("handshake error")
+				}
+				_, err := rt.RoundTrip(req)
+				Expect(err).To(MatchError("handshake error"))
+				Expect(err).ToNot(BeAssignableToTypeOf(&dialError{}))
+				Expect(rt.clients).To(BeEmpty())
+				_, err = rt.RoundTrip(req)
+				Expect(err).To(MatchError("handshake error"))
+				Expect(dialed).To(Equal(2))
+			})
+
+			It("waits for a stream on the oldest connection once MaxConnsPerHost is reached", func() {
+				dialAddr = func(string, *tls.Config, *quic.Config) (quic.EarlySession, error) {
+					Fail("didn't expect any dial")
+					return nil, nil
+				}
+				rt.MaxConnsPerHost = 2
+				cl1 := &mockClient{full: true}
+				cl2 := &mockClient{full: true}
+				rt.clients = map[string][]roundTripCloser{hostname: {cl1, cl2}}
+				_, err := rt.RoundTrip(req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cl1.rtCalls).To(Equal(1))
+				Expect(cl2.rtCalls).To(BeZero())
+				Expect(rt.clients[hostname]).To(HaveLen(2))
+			})
+
+			It("waits for a stream on a cached connection if RoundTripOpt.OnlyCachedConn is set", func() {
+				cl := &mockClient{full: true}
+				rt.clients = map[string][]roundTripCloser{hostname: {cl}}
+				_, err := rt.RoundTripOpt(req, RoundTripOpt{OnlyCachedConn: true})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cl.rtCalls).To(Equal(1))
+				Expect(rt.clients[hostname]).To(HaveLen(1))
+			})
+
+			It("removes connections that received a GOAWAY", func() {
+				goingAway := &mockClient{rtErr: errGoAway}
+				cl := &mockClient{}
+				rt.clients = map[string][]roundTripCloser{hostname: {goingAway, cl}}
+				_, err := rt.RoundTrip(req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cl.tryCalls).To(Equal(1))
+				Expect(rt.clients[hostname]).To(Equal([]roundTripCloser{cl}))
+			})
+		})
+	})
+
+	Context("idle connections", func() {
+		// getClients returns the connections, and the idle connections.
+		// The mutex is needed since the idle timeout fires on a different Go routine.
+		getClients := func() (map[string][]roundTripCloser, []*idleClient) {
+			rt.mutex.Lock()
+			defer rt.mutex.Unlock()
+			clients := make(map[string][]roundTripCloser, len(rt.clients))
+			for hostname, cls := range rt.clients {
+				clients[hostname] = append([]roundTripCloser{}, cls...)
+			}
+			return clients, append([]*idleClient{}, rt.idle...)
+		}
+
+		It("closes connections after the IdleConnTimeout", func() {
+			rt.IdleConnTimeout = 50 * time.Millisecond
+			cl1 := &mockClient{}
+			cl2 := &mockClient{}
+			rt.clients = map[string][]roundTripCloser{"foo.bar:443": {cl1, cl2}}
+			rt.clientIdle("foo.bar:443", cl1)
+			Eventually(func() []roundTripCloser {
+				clients, _ := getClients()
+				return clients["foo.bar:443"]
+			}).Should(Equal([]roundTripCloser{cl2}))
+			Expect(cl1.closed).To(BeTrue())
+			Expect(cl2.closed).To(BeFalse())
+			_, idle := getClients()
+			Expect(idle).To(BeEmpty())
+		})
+
+		It("doesn't close connections that are used again", func() {
+			rt.IdleConnTimeout = 50 * time.Millisecond
+			cl := &mockClient{}
+			rt.clients = map[string][]roundTripCloser{"foo.bar:443": {cl}}
+			rt.clientIdle("foo.bar:443", cl)
+			Expect(rt.idle).To(HaveLen(1))
+			rt.useClient(cl)
+			Expect(rt.idle).To(BeEmpty())
+			Consistently(func() map[string][]roundTripCloser {
+				clients, _ := getClients()
+				return clients
+			}, 150*time.Millisecond).Should(HaveKey("foo.bar:443"))
+			Expect(cl.closed).To(BeFalse())
+		})
+
+		It("closes the least recently used connection when MaxIdleConns is exceeded", func() {
+			rt.MaxIdleConns = 2
+			cl1 := &mockClient{}
+			cl2 := &mockClient{}
+			cl3 := &mockClient{}
+			rt.clients = map[string][]roundTripCloser{"foo.bar:443": {cl1, cl2}, "quic.clemente.io:443": {cl3}}
+			rt.clientIdle("foo.bar:443", cl2)
+			rt.clientIdle("quic.clemente.io:443", cl3)
+			Expect(cl2.closed).To(BeFalse())
+			rt.clientIdle("foo.bar:443", cl1)
+			Expect(cl2.closed).To(BeTrue())
+			Expect(cl1.closed).To(BeFalse())
+			Expect(cl3.closed).To(BeFalse())
+			Expect(rt.clients).To(Equal(map[string][]roundTripCloser{
+				"foo.bar:443":          {cl1},
+				"quic.clemente.io:443": {cl3},
+			}))
+			Expect(rt.idle).To(HaveLen(2))
+		})
+
+		It("ignores connections that were already removed", func() {
+			cl := &mockClient{}
+			rt.clientIdle("foo.bar:443", cl)
+			Expect(rt.idle).To(BeEmpty())
+		})
+
+		It("closes idle connections", func() {
+			idle := &mockClient{}
+			inUse := &mockClient{}
+			startedRequest := &mockClient{busy: true} // a request was started after the connection became idle
+			rt.clients = map[string][]roundTripCloser{"foo.bar:443": {idle, inUse, startedRequest}}
+			rt.clientIdle("foo.bar:443", idle)
+			rt.clientIdle("foo.bar:443", startedRequest)
+			rt.CloseIdleConnections()
+			Expect(idle.closed).To(BeTrue())
+			Expect(inUse.closed).To(BeFalse())
+			Expect(startedRequest.closed).To(BeFalse())
+			Expect(rt.clients["foo.bar:443"]).To(Equal([]roundTripCloser{inUse, startedRequest}))
+			Expect(rt.idle).To(BeEmpty())
+		})
+	})
+
+	Context("alternative services", func() {
+		const origin = "www.example.org:443"
+		var (
+			fallback      *mockFallback
+			cache         AltSvcCache
+			origDialAddr  = dialAddr
+			h3ALPN        = versionToALPN(defaultQuicConfig.Versions[0])
+			advertisement = h3ALPN + `=":8443"; ma=3600`
+		)
+
+		BeforeEach(func() {
+			fallback = &mockFallback{header: http.Header{}}
+			cache = NewAltSvcCache()
+			rt.Fallback = fallback
+			rt.AltSvcCache = cache
+			origDialAddr = dialAddr
+		})
+
+		AfterEach(func() {
+			dialAddr = origDialAddr
+		})
+
+		It("uses the fallback for origins that didn't advertise an alternative service", func() {
+			fallback.header.Set("Alt-Svc", advertisement)
+			rsp, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.Request).To(Equal(req1))
+			Expect(fallback.calls).To(Equal(1))
+			svcs := cache.Get(origin)
+			Expect(svcs).To(HaveLen(1))
+			Expect(svcs[0].Protocol).To(Equal(h3ALPN))
+			Expect(svcs[0].Port).To(BeEquivalentTo(8443))
+			Expect(svcs[0].Expires).To(BeTemporally("~", time.Now().Add(time.Hour), time.Second))
+		})
+
+		It("uses the fallback for non-https requests", func() {
+			req, err := http.NewRequest("GET", "http://www.example.org/file1.html", nil)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rt.RoundTrip(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fallback.calls).To(Equal(1))
+		})
+
+		It("uses an in-memory cache, if no AltSvcCache is set", func() {
+			rt.AltSvcCache = nil
+			fallback.header.Set("Alt-Svc", advertisement)
+			_, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rt.altSvcCache.Get(origin)).To(HaveLen(1))
+		})
+
+		It("sends requests to an advertised alternative service", func() {
+			cache.Put(origin, []AltSvc{{Protocol: h3ALPN, Host: "alt.example.org", Port: 8443, Expires: time.Now().Add(time.Hour)}})
+			cl := &mockClient{}
+			rt.clients = map[string][]roundTripCloser{poolKey(origin, "alt.example.org:8443"): {cl}}
+			_, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cl.tryCalls).To(Equal(1))
+			Expect(fallback.calls).To(BeZero())
+		})
+
+		It("dials the alternative service using the origin as the server name", func() {
+			cache.Put(origin, []AltSvc{{Protocol: h3ALPN, Port: 8443, Expires: time.Now().Add(time.Hour)}})
+			var dialedAddr, serverName string
+			dialAddr = func(addr string, tlsConf *tls.Config, _ *quic.Config) (quic.EarlySession, error) {
+				dialedAddr = addr
+				serverName = tlsConf.ServerName
+				return nil, errors.New("handshake error")
+			}
+			_, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dialedAddr).To(Equal("www.example.org:8443"))
+			Expect(serverName).To(Equal("www.example.org"))
+		})
+
+		It("ignores expired alternative services, and other protocols", func() {
+			cache = &mockAltSvcCache{svcs: []AltSvc{
+				{Protocol: h3ALPN, Port: 8443, Expires: time.Now().Add(-time.Second)},
+				{Protocol: "h2", Port: 8443, Expires: time.Now().Add(time.Hour)},
+			}}
+			rt.AltSvcCache = cache
+			dialAddr = func(string, *tls.Config, *quic.Config) (quic.EarlySession, error) {
+				Fail("didn't expect any dial")
+				return nil, nil
+			}
+			_, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fallback.calls).To(Equal(1))
+		})
+
+		It("uses the fallback if the alternative service can't be dialed", func() {
+			cache.Put(origin, []AltSvc{{Protocol: h3ALPN, Port: 8443, Expires: time.Now().Add(time.Hour)}})
+			var dialed int
+			dialAddr = func(string, *tls.Config, *quic.Config) (quic.EarlySession, error) {
+				dialed++
+				return nil, errors.New("handshake error")
+			}
+			_, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dialed).To(Equal(1))
+			Expect(fallback.calls).To(Equal(1))
+			Expect(cache.Get(origin)).To(BeEmpty())
+			Expect(rt.clients).To(BeEmpty())
+			// the alternative service isn't used again, even if it is advertised again
+			fallback.header.Set("Alt-Svc", advertisement)
+			_, err = rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cache.Get(origin)).To(HaveLen(1))
+			_, err = rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dialed).To(Equal(1))
+			Expect(fallback.calls).To(Equal(3))
+			// ... until it becomes usable again
+			rt.altSvcBroken["www.example.org:8443"] = time.Now().Add(-time.Second)
+			_, err = rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dialed).To(Equal(2))
+			Expect(rt.altSvcBroken).To(HaveKey("www.example.org:8443"))
+		})
+
+		It("returns errors that occur after the connection was established", func() {
+			cache.Put(origin, []AltSvc{{Protocol: h3ALPN, Port: 8443, Expires: time.Now().Add(time.Hour)}})
+			testErr := errors.New("test err")
+			rt.clients = map[string][]roundTripCloser{poolKey(origin, "www.example.org:8443"): {&mockClient{rtErr: testErr}}}
+			_, err := rt.RoundTrip(req1)
+			Expect(err).To(MatchError(testErr))
+			Expect(fallback.calls).To(BeZero())
+		})
+
+		It("removes alternative services when the origin clears them", func() {
+			cache.Put(origin, []AltSvc{{Protocol: "h2", Port: 8443, Expires: time.Now().Add(time.Hour)}})
+			fallback.header.Set("Alt-Svc", "clear")
+			_, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cache.Get(origin)).To(BeEmpty())
+		})
+
+		It("keeps alternative services if the response doesn't contain an Alt-Svc header field", func() {
+			cache.Put(origin, []AltSvc{{Protocol: "h2", Port: 8443, Expires: time.Now().Add(time.Hour)}})
+			_, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cache.Get(origin)).To(HaveLen(1))
+		})
 	})
 
 	Context("validating request", func() {
@@ -242,9 +709,9 @@ var _ = Describe("RoundTripper", func() {
 
 	Context("closing", func() {
 		It("closes", func() {
-			rt.clients = make(map[string]roundTripCloser)
+			rt.clients = make(map[string][]roundTripCloser)
 			cl := &mockClient{}
-			rt.clients["foo.bar"] = cl
+			rt.clients["foo.bar"] = []roundTripCloser{cl}
 			err := rt.Close()
 			Expect(err).ToNot(HaveOccurred())
 			Expect(len(rt.clients)).To(BeZero())
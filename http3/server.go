@@ -1,6 +1,7 @@
 package http3
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"crypto/tls"
@@ -34,6 +35,9 @@ const (
 	nextProtoH3        = "h3"
 )
 
+// StreamType is the stream type of a unidirectional stream.
+type StreamType uint64
+
 const (
 	streamTypeControlStream      = 0
 	streamTypePushStream         = 1
@@ -42,7 +46,7 @@ const (
 )
 
 func versionToALPN(v protocol.VersionNumber) string {
-	if v == protocol.Version1 {
+	if v == protocol.Version1 || v == protocol.Version2 {
 		return nextProtoH3
 	}
 	if v == protocol.VersionTLS || v == protocol.VersionDraft29 {
@@ -65,6 +69,18 @@ func (k *contextKey) String() string { return "quic-go/http3 context value " + k
 // type *http3.Server.
 var ServerContextKey = &contextKey{"http3-server"}
 
+// SessionContextKey is a context key. It can be used in HTTP
+// handlers with Context.Value to access the QUIC session that
+// the request was received on. The associated value will be of
+// type quic.Session.
+var SessionContextKey = &contextKey{"http3-session"}
+
+// StreamIDContextKey is a context key. It can be used in HTTP
+// handlers with Context.Value to access the ID of the stream that
+// the request was received on (or the response is pushed on). The
+// associated value will be of type quic.StreamID.
+var StreamIDContextKey = &contextKey{"http3-stream-id"}
+
 type requestError struct {
 	err       error
 	streamErr errorCode
@@ -85,18 +101,50 @@ type Server struct {
 
 	// By providing a quic.Config, it is possible to set parameters of the QUIC connection.
 	// If nil, it uses reasonable default values.
+	// The server issues session tickets that allow clients to resume the session using 0-RTT.
+	// Requests sent in 0-RTT are processed before the handshake completes, and can be replayed by an attacker.
+	// Well-behaved clients only send requests that are safe to repeat in 0-RTT, but applications can't rely on that.
+	// QuicConfig.Allow0RTT can be used to restrict (or disable) 0-RTT.
 	QuicConfig *quic.Config
 
 	// Enable support for HTTP/3 datagrams.
 	// If set to true, QuicConfig.EnableDatagram will be set.
-	// See https://www.ietf.org/archive/id/draft-schinazi-masque-h3-datagram-02.html.
+	// Datagrams are sent and received using the Datagrammer interface.
+	// See RFC 9297.
 	EnableDatagrams bool
 
+	// The SETTINGS frame sent on every connection contains these settings, in addition to the settings defined by HTTP/3.
+	// This is used by protocols layered on top of HTTP/3, e.g. WebTransport.
+	AdditionalSettings map[uint64]uint64
+
+	// OnSettingsReceived, when set, is called when the SETTINGS frame is received on the client's control stream.
+	// It is passed the settings that aren't defined by HTTP/3, e.g. the settings of protocols layered on top of HTTP/3.
+	// Settings that neither HTTP/3 nor the application understands are ignored, as required by the specification.
+	// The control stream is blocked until the callback returns.
+	OnSettingsReceived func(quic.Session, map[uint64]uint64)
+
+	// StreamHijacker, when set, is called for the first unknown frame parsed on a bidirectional stream.
+	// It is called right after the frame type was read, so the stream is positioned right after the frame type.
+	// If it returns true, the stream is taken over, and the server doesn't use it any more.
+	// If an error is returned, the stream is reset.
+	StreamHijacker func(FrameType, quic.Session, quic.Stream) (hijacked bool, err error)
+
+	// UniStreamHijacker, when set, is called for unknown unidirectional stream types.
+	// It is called right after the stream type was read.
+	// If it returns true, the stream is taken over, and the server doesn't use it any more.
+	// Otherwise, the stream is reset.
+	UniStreamHijacker func(StreamType, quic.Session, quic.ReceiveStream) (hijacked bool)
+
 	port uint32 // used atomically
 
-	mutex     sync.Mutex
-	listeners map[*quic.EarlyListener]struct{}
-	closed    utils.AtomicBool
+	mutex          sync.Mutex
+	listeners      map[*quic.EarlyListener]struct{}
+	conns          map[*serverConn]struct{}
+	activeRequests int
+	drained        chan struct{} // closed during Shutdown, when all requests completed and all connections were closed
+	acceptCtx      context.Context
+	stopAccepting  context.CancelFunc
+	closed         utils.AtomicBool
 
 	loggerOnce sync.Once
 	logger     utils.Logger
@@ -151,7 +199,7 @@ func (s *Server) serveImpl(tlsConf *tls.Config, conn net.PacketConn) error {
 			// determine the ALPN from the QUIC version used
 			proto := nextProtoH3Draft29
 			if qconn, ok := ch.Conn.(handshake.ConnWithVersion); ok {
-				if qconn.GetQUICVersion() == protocol.Version1 {
+				if v := qconn.GetQUICVersion(); v == protocol.Version1 || v == protocol.Version2 {
 					proto = nextProtoH3
 				}
 			}
@@ -196,17 +244,31 @@ func (s *Server) serveImpl(tlsConf *tls.Config, conn net.PacketConn) error {
 		return err
 	}
 	s.addListener(&ln)
-	defer s.removeListener(&ln)
 
+	ctx := s.acceptContext()
 	for {
-		sess, err := ln.Accept(context.Background())
+		sess, err := ln.Accept(ctx)
 		if err != nil {
+			// The listener is closed by Close or Shutdown.
+			if s.closed.Get() {
+				return http.ErrServerClosed
+			}
+			s.removeListener(&ln)
 			return err
 		}
 		go s.handleConn(sess)
 	}
 }
 
+func (s *Server) acceptContext() context.Context {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.acceptCtx == nil {
+		s.acceptCtx, s.stopAccepting = context.WithCancel(context.Background())
+	}
+	return s.acceptCtx
+}
+
 // We store a pointer to interface in the map set. This is safe because we only
 // call trackListener via Serve and can track+defer untrack the same pointer to
 // local variable there. We never need to compare a Listener from another caller.
@@ -225,6 +287,110 @@ func (s *Server) removeListener(l *quic.EarlyListener) {
 	s.mutex.Unlock()
 }
 
+// A serverConn tracks the request streams of a connection, such that a GOAWAY frame can be sent on Shutdown,
+// and the push IDs that the client allowed us to use.
+type serverConn struct {
+	sess          quic.EarlySession
+	controlStream quic.SendStream
+	datagrams     *datagramManager // nil if HTTP datagrams are not enabled
+	scheduler     *writeScheduler
+
+	mutex        sync.Mutex
+	nextStreamID quic.StreamID // the lowest stream ID that wasn't accepted yet
+	goAwaySent   bool
+	pushEnabled  bool // set when the client sends a MAX_PUSH_ID frame
+	maxPushID    uint64
+	nextPushID   uint64
+}
+
+// startRequest is called for every request stream.
+// It returns false if the stream was opened after a GOAWAY frame, and must be rejected.
+func (c *serverConn) startRequest(id quic.StreamID) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.goAwaySent && id >= c.nextStreamID {
+		return false
+	}
+	if id >= c.nextStreamID {
+		c.nextStreamID = id + 4
+	}
+	return true
+}
+
+func (c *serverConn) handleMaxPushID(id uint64) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.pushEnabled && id < c.maxPushID {
+		return fmt.Errorf("MAX_PUSH_ID reduced the maximum push ID from %d to %d", c.maxPushID, id)
+	}
+	c.pushEnabled = true
+	c.maxPushID = id
+	return nil
+}
+
+// getPushID returns the next push ID.
+// It returns false if the client doesn't accept any more pushes.
+func (c *serverConn) getPushID() (uint64, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.pushEnabled || c.nextPushID > c.maxPushID {
+		return 0, false
+	}
+	id := c.nextPushID
+	c.nextPushID++
+	return id, true
+}
+
+func (c *serverConn) sendGoAway() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.goAwaySent {
+		return
+	}
+	c.goAwaySent = true
+	buf := &bytes.Buffer{}
+	(&goAwayFrame{StreamID: c.nextStreamID}).Write(buf)
+	c.controlStream.Write(buf.Bytes())
+}
+
+func (s *Server) addConn(c *serverConn) {
+	s.mutex.Lock()
+	if s.conns == nil {
+		s.conns = make(map[*serverConn]struct{})
+	}
+	s.conns[c] = struct{}{}
+	s.mutex.Unlock()
+}
+
+func (s *Server) removeConn(c *serverConn) {
+	s.mutex.Lock()
+	delete(s.conns, c)
+	s.checkDrained()
+	s.mutex.Unlock()
+}
+
+func (s *Server) addRequest() {
+	s.mutex.Lock()
+	s.activeRequests++
+	s.mutex.Unlock()
+}
+
+func (s *Server) removeRequest() {
+	s.mutex.Lock()
+	s.activeRequests--
+	s.checkDrained()
+	s.mutex.Unlock()
+}
+
+// checkDrained closes the drained channel when all requests have completed and all connections were closed.
+// It must be called with the mutex held.
+func (s *Server) checkDrained() {
+	if s.activeRequests == 0 && len(s.conns) == 0 && s.drained != nil {
+		close(s.drained)
+		s.drained = nil
+	}
+}
+
 func (s *Server) handleConn(sess quic.EarlySession) {
 	decoder := qpack.NewDecoder(nil)
 
@@ -236,10 +402,22 @@ func (s *Server) handleConn(sess quic.EarlySession) {
 	}
 	buf := &bytes.Buffer{}
 	quicvarint.Write(buf, streamTypeControlStream) // stream type
-	(&settingsFrame{Datagram: s.EnableDatagrams}).Write(buf)
+	(&settingsFrame{Datagram: s.EnableDatagrams, other: s.AdditionalSettings}).Write(buf)
 	str.Write(buf.Bytes())
 
-	go s.handleUnidirectionalStreams(sess)
+	conn := &serverConn{sess: sess, controlStream: str, scheduler: newWriteScheduler()}
+	if s.EnableDatagrams {
+		conn.datagrams = newDatagramManager(sess, s.logger)
+		go conn.datagrams.run()
+	}
+	s.addConn(conn)
+	defer s.removeConn(conn)
+	// If Shutdown was called while this connection was being set up, tell the client right away.
+	if s.closed.Get() {
+		conn.sendGoAway()
+	}
+
+	go s.handleUnidirectionalStreams(conn)
 
 	// Process all requests immediately.
 	// It's the client's responsibility to decide which requests are eligible for 0-RTT.
@@ -249,10 +427,21 @@ func (s *Server) handleConn(sess quic.EarlySession) {
 			s.logger.Debugf("Accepting stream failed: %s", err)
 			return
 		}
+		if !conn.startRequest(str.StreamID()) {
+			s.logger.Debugf("Rejecting stream %d, opened after GOAWAY.", str.StreamID())
+			str.CancelRead(quic.StreamErrorCode(errorRequestRejected))
+			str.CancelWrite(quic.StreamErrorCode(errorRequestRejected))
+			continue
+		}
+		s.addRequest()
 		go func() {
-			rerr := s.handleRequest(sess, str, decoder, func() {
+			defer s.removeRequest()
+			rerr := s.handleRequest(sess, conn, str, decoder, func() {
 				sess.CloseWithError(quic.ApplicationErrorCode(errorFrameUnexpected), "")
 			})
+			if rerr.err == errHijacked {
+				return
+			}
 			if rerr.err != nil || rerr.streamErr != 0 || rerr.connErr != 0 {
 				s.logger.Debugf("Handling request failed: %s", err)
 				if rerr.streamErr != 0 {
@@ -272,7 +461,8 @@ func (s *Server) handleConn(sess quic.EarlySession) {
 	}
 }
 
-func (s *Server) handleUnidirectionalStreams(sess quic.EarlySession) {
+func (s *Server) handleUnidirectionalStreams(conn *serverConn) {
+	sess := conn.sess
 	for {
 		str, err := sess.AcceptUniStream(context.Background())
 		if err != nil {
@@ -297,10 +487,13 @@ func (s *Server) handleUnidirectionalStreams(sess quic.EarlySession) {
 				sess.CloseWithError(quic.ApplicationErrorCode(errorStreamCreationError), "")
 				return
 			default:
+				if s.UniStreamHijacker != nil && s.UniStreamHijacker(StreamType(streamType), sess, str) {
+					return
+				}
 				str.CancelRead(quic.StreamErrorCode(errorStreamCreationError))
 				return
 			}
-			f, err := parseNextFrame(str)
+			f, err := parseNextFrame(str, nil)
 			if err != nil {
 				sess.CloseWithError(quic.ApplicationErrorCode(errorFrameError), "")
 				return
@@ -310,19 +503,63 @@ func (s *Server) handleUnidirectionalStreams(sess quic.EarlySession) {
 				sess.CloseWithError(quic.ApplicationErrorCode(errorMissingSettings), "")
 				return
 			}
-			if !sf.Datagram {
-				return
-			}
 			// If datagram support was enabled on our side as well as on the client side,
 			// we can expect it to have been negotiated both on the transport and on the HTTP/3 layer.
-			// Note: ConnectionState() will block until the handshake is complete (relevant when using 0-RTT).
-			if s.EnableDatagrams && !sess.ConnectionState().SupportsDatagrams {
-				sess.CloseWithError(quic.ApplicationErrorCode(errorSettingsError), "missing QUIC Datagram support")
+			if sf.Datagram && s.EnableDatagrams {
+				connState, err := connectionState(sess)
+				if err != nil {
+					return
+				}
+				if !connState.SupportsDatagrams {
+					sess.CloseWithError(quic.ApplicationErrorCode(errorSettingsError), "missing QUIC Datagram support")
+					return
+				}
+			}
+			if conn.datagrams != nil {
+				conn.datagrams.handleSettings(sf.Datagram)
 			}
+			if s.OnSettingsReceived != nil {
+				s.OnSettingsReceived(sess, sf.other)
+			}
+			s.handleControlStreamFrames(conn, str)
 		}(str)
 	}
 }
 
+// handleControlStreamFrames handles the frames following the SETTINGS frame on the client's control stream.
+func (s *Server) handleControlStreamFrames(conn *serverConn, str quic.ReceiveStream) {
+	for {
+		f, err := parseNextFrame(str, nil)
+		if err != nil {
+			s.logger.Debugf("reading from the control stream failed: %s", err)
+			return
+		}
+		switch f := f.(type) {
+		case *maxPushIDFrame:
+			if err := conn.handleMaxPushID(f.PushID); err != nil {
+				conn.sess.CloseWithError(quic.ApplicationErrorCode(errorIDError), err.Error())
+				return
+			}
+		case *priorityUpdateFrame:
+			if f.Push {
+				// We don't schedule pushed responses.
+				break
+			}
+			if f.ElementID%4 != 0 {
+				conn.sess.CloseWithError(quic.ApplicationErrorCode(errorIDError), fmt.Sprintf("PRIORITY_UPDATE for invalid stream %d", f.ElementID))
+				return
+			}
+			conn.scheduler.update(quic.StreamID(f.ElementID), ParsePriority(f.PriorityFieldValue))
+		case *goAwayFrame:
+			// The client's GOAWAY frame limits the pushes we're allowed to send.
+			// We don't need to handle it, since the client cancels all pushes above the push ID anyway.
+		default:
+			conn.sess.CloseWithError(quic.ApplicationErrorCode(errorFrameUnexpected), "")
+			return
+		}
+	}
+}
+
 func (s *Server) maxHeaderBytes() uint64 {
 	if s.Server.MaxHeaderBytes <= 0 {
 		return http.DefaultMaxHeaderBytes
@@ -330,8 +567,19 @@ func (s *Server) maxHeaderBytes() uint64 {
 	return uint64(s.Server.MaxHeaderBytes)
 }
 
-func (s *Server) handleRequest(sess quic.Session, str quic.Stream, decoder *qpack.Decoder, onFrameError func()) requestError {
-	frame, err := parseNextFrame(str)
+// handleRequest handles a request.
+// Pushes are only possible if conn is non-nil.
+// If the stream was taken over, either by the StreamHijacker or by the handler calling DataStream() or Tunnel(),
+// the error is errHijacked.
+func (s *Server) handleRequest(sess quic.Session, conn *serverConn, str quic.Stream, decoder *qpack.Decoder, onFrameError func()) requestError {
+	var ufh unknownFrameHandlerFunc
+	if s.StreamHijacker != nil {
+		ufh = func(ft FrameType) (bool, error) { return s.StreamHijacker(ft, sess, str) }
+	}
+	frame, err := parseNextFrame(str, ufh)
+	if err == errHijacked {
+		return requestError{err: errHijacked}
+	}
 	if err != nil {
 		return newStreamError(errorRequestIncomplete, err)
 	}
@@ -358,7 +606,8 @@ func (s *Server) handleRequest(sess quic.Session, str quic.Stream, decoder *qpac
 	}
 
 	req.RemoteAddr = sess.RemoteAddr().String()
-	req.Body = newRequestBody(str, onFrameError)
+	reqBody := newRequestBody(str, onFrameError)
+	req.Body = reqBody
 
 	if s.logger.Debug() {
 		s.logger.Infof("%s %s%s, on stream %d", req.Method, req.Host, req.RequestURI, str.StreamID())
@@ -368,44 +617,74 @@ func (s *Server) handleRequest(sess quic.Session, str quic.Stream, decoder *qpac
 
 	ctx := str.Context()
 	ctx = context.WithValue(ctx, ServerContextKey, s)
+	ctx = context.WithValue(ctx, SessionContextKey, sess)
+	ctx = context.WithValue(ctx, StreamIDContextKey, str.StreamID())
 	ctx = context.WithValue(ctx, http.LocalAddrContextKey, sess.LocalAddr())
 	req = req.WithContext(ctx)
 	r := newResponseWriter(str, s.logger)
+	r.sess = sess
+	// Extended CONNECT requests (e.g. for WebTransport) use the stream differently.
+	if req.Method == http.MethodConnect && req.Proto == "HTTP/3" {
+		r.connectBody = reqBody
+	}
+	if conn != nil {
+		ps := conn.scheduler.add(str, ParsePriority(strings.Join(req.Header.Values("Priority"), ",")))
+		defer conn.scheduler.remove(ps)
+		r.bufferedStream = bufio.NewWriter(ps)
+		r.prioritized = ps
+		r.push = func(target string, opts *http.PushOptions) error {
+			return s.push(conn, r, req, target, opts)
+		}
+		if conn.datagrams != nil {
+			r.datagrams = conn.datagrams.register(str.StreamID())
+			// If the stream was taken over, the datagrams can still be used after the handler returned.
+			defer func() {
+				if !r.usedDataStream() {
+					conn.datagrams.unregister(r.datagrams)
+				}
+			}()
+		}
+	}
 	defer func() {
 		if !r.usedDataStream() {
 			r.Flush()
 		}
 	}()
-	handler := s.Handler
-	if handler == nil {
-		handler = http.DefaultServeMux
-	}
-
-	var panicked bool
-	func() {
-		defer func() {
-			if p := recover(); p != nil {
-				// Copied from net/http/server.go
-				const size = 64 << 10
-				buf := make([]byte, size)
-				buf = buf[:runtime.Stack(buf, false)]
-				s.logger.Errorf("http: panic serving: %v\n%s", p, buf)
-				panicked = true
-			}
-		}()
-		handler.ServeHTTP(r, req)
-	}()
+	panicked := s.serveHTTP(r, req)
 
 	if !r.usedDataStream() {
 		if panicked {
 			r.WriteHeader(500)
 		} else {
 			r.WriteHeader(200)
+			r.writeTrailers()
 		}
 		// If the EOF was read by the handler, CancelRead() is a no-op.
 		str.CancelRead(quic.StreamErrorCode(errorNoError))
+		return requestError{}
+	}
+	return requestError{err: errHijacked}
+}
+
+// serveHTTP calls the handler. It returns true if the handler panicked.
+func (s *Server) serveHTTP(w http.ResponseWriter, req *http.Request) (panicked bool) {
+	handler := s.Handler
+	if handler == nil {
+		handler = http.DefaultServeMux
 	}
-	return requestError{}
+
+	defer func() {
+		if p := recover(); p != nil {
+			// Copied from net/http/server.go
+			const size = 64 << 10
+			buf := make([]byte, size)
+			buf = buf[:runtime.Stack(buf, false)]
+			s.logger.Errorf("http: panic serving: %v\n%s", p, buf)
+			panicked = true
+		}
+	}()
+	handler.ServeHTTP(w, req)
+	return false
 }
 
 // Close the server immediately, aborting requests and sending CONNECTION_CLOSE frames to connected clients.
@@ -416,25 +695,84 @@ func (s *Server) Close() error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	return s.closeListeners()
+}
+
+// closeListeners closes all listeners, which closes all connections accepted by them.
+// It must be called with the mutex held.
+func (s *Server) closeListeners() error {
 	var err error
 	for ln := range s.listeners {
 		if cerr := (*ln).Close(); cerr != nil && err == nil {
 			err = cerr
 		}
+		delete(s.listeners, ln)
 	}
 	return err
 }
 
+// Shutdown shuts down the server gracefully.
+// It stops accepting new connections and sends a GOAWAY frame on all existing connections,
+// such that clients stop sending new requests. It then waits for all running requests to complete
+// and for the clients to close their connections, or for ctx to be canceled, whichever happens first.
+// Clients close the connection once their last request completed, such that no response data is lost.
+// Connections that are still open when ctx is canceled are closed, and ctx.Err() is returned.
+// Shutdown in combination with ListenAndServe() (instead of Serve()) may race if it is called before a UDP socket is established.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.closed.Set(true)
+	s.acceptContext()
+
+	s.mutex.Lock()
+	s.stopAccepting()
+	conns := make([]*serverConn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mutex.Unlock()
+
+	for _, c := range conns {
+		c.sendGoAway()
+	}
+
+	s.mutex.Lock()
+	var drained chan struct{}
+	if s.activeRequests > 0 || len(s.conns) > 0 {
+		if s.drained == nil {
+			s.drained = make(chan struct{})
+		}
+		drained = s.drained
+	}
+	s.mutex.Unlock()
+
+	var ctxErr error
+	if drained != nil {
+		select {
+		case <-drained:
+		case <-ctx.Done():
+			ctxErr = ctx.Err()
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.closeListeners(); err != nil {
+		return err
+	}
+	return ctxErr
+}
+
 // CloseGracefully shuts down the server gracefully. The server sends a GOAWAY frame first, then waits for either timeout to trigger, or for all running requests to complete.
 // CloseGracefully in combination with ListenAndServe() (instead of Serve()) may race if it is called before a UDP socket is established.
 func (s *Server) CloseGracefully(timeout time.Duration) error {
-	// TODO: implement
-	return nil
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return s.Shutdown(ctx)
 }
 
 // SetQuicHeaders can be used to set the proper headers that announce that this server supports QUIC.
 // The values that are set depend on the port information from s.Server.Addr, and currently look like this (if Addr has port 443):
-//  Alt-Svc: quic=":443"; ma=2592000; v="33,32,31,30"
+//  Alt-Svc: h3=":443"; ma=2592000,h3-29=":443"; ma=2592000
+// A RoundTripper with a Fallback uses this header to switch to HTTP/3.
 func (s *Server) SetQuicHeaders(hdr http.Header) error {
 	port := atomic.LoadUint32(&s.port)
 
@@ -458,11 +796,14 @@ func (s *Server) SetQuicHeaders(hdr http.Header) error {
 		supportedVersions = s.QuicConfig.Versions
 	}
 	altSvc := make([]string, 0, len(supportedVersions))
+	alpns := make(map[string]struct{}, len(supportedVersions))
 	for _, version := range supportedVersions {
 		v := versionToALPN(version)
-		if len(v) > 0 {
-			altSvc = append(altSvc, fmt.Sprintf(`%s=":%d"; ma=2592000`, v, port))
+		if _, ok := alpns[v]; ok || len(v) == 0 {
+			continue
 		}
+		alpns[v] = struct{}{}
+		altSvc = append(altSvc, fmt.Sprintf(`%s=":%d"; ma=2592000`, v, port))
 	}
 	hdr.Add("Alt-Svc", strings.Join(altSvc, ","))
 	return nil
@@ -72,7 +72,7 @@ var _ = Describe("Server", func() {
 			fields := make(map[string][]string)
 			decoder := qpack.NewDecoder(nil)
 
-			frame, err := parseNextFrame(str)
+			frame, err := parseNextFrame(str, nil)
 			ExpectWithOffset(1, err).ToNot(HaveOccurred())
 			ExpectWithOffset(1, frame).To(BeAssignableToTypeOf(&headersFrame{}))
 			headersFrame := frame.(*headersFrame)
@@ -118,6 +118,7 @@ var _ = Describe("Server", func() {
 
 			qpackDecoder = qpack.NewDecoder(nil)
 			str = mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().StreamID().AnyTimes()
 
 			sess = mockquic.NewMockEarlySession(mockCtrl)
 			addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
@@ -138,12 +139,14 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			Expect(s.handleRequest(sess, str, qpackDecoder, nil)).To(Equal(requestError{}))
+			Expect(s.handleRequest(sess, nil, str, qpackDecoder, nil)).To(Equal(requestError{}))
 			var req *http.Request
 			Eventually(requestChan).Should(Receive(&req))
 			Expect(req.Host).To(Equal("www.example.com"))
 			Expect(req.RemoteAddr).To(Equal("127.0.0.1:1337"))
 			Expect(req.Context().Value(ServerContextKey)).To(Equal(s))
+			Expect(req.Context().Value(SessionContextKey)).To(Equal(sess))
+			Expect(req.Context().Value(StreamIDContextKey)).To(Equal(quic.StreamID(0)))
 		})
 
 		It("returns 200 with an empty handler", func() {
@@ -155,7 +158,7 @@ var _ = Describe("Server", func() {
 			str.EXPECT().Write(gomock.Any()).DoAndReturn(responseBuf.Write).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			serr := s.handleRequest(sess, str, qpackDecoder, nil)
+			serr := s.handleRequest(sess, nil, str, qpackDecoder, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			hfs := decodeHeader(responseBuf)
 			Expect(hfs).To(HaveKeyWithValue(":status", []string{"200"}))
@@ -172,7 +175,7 @@ var _ = Describe("Server", func() {
 			str.EXPECT().Write(gomock.Any()).DoAndReturn(responseBuf.Write).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			serr := s.handleRequest(sess, str, qpackDecoder, nil)
+			serr := s.handleRequest(sess, nil, str, qpackDecoder, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			hfs := decodeHeader(responseBuf)
 			Expect(hfs).To(HaveKeyWithValue(":status", []string{"500"}))
@@ -189,8 +192,151 @@ var _ = Describe("Server", func() {
 			str.EXPECT().Write([]byte("foobar"))
 			// don't EXPECT CancelRead()
 
-			serr := s.handleRequest(sess, str, qpackDecoder, nil)
+			serr := s.handleRequest(sess, nil, str, qpackDecoder, nil)
+			Expect(serr.err).To(MatchError(errHijacked))
+		})
+
+		It("doesn't close the stream if the handler tunnels a CONNECT request", func() {
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.Host).To(Equal("www.example.com:443"))
+				conn, err := w.(Tunneler).Tunnel()
+				Expect(err).ToNot(HaveOccurred())
+				conn.Write([]byte("foobar"))
+			})
+
+			req, err := http.NewRequest(http.MethodConnect, "https://www.example.com:443", nil)
+			Expect(err).ToNot(HaveOccurred())
+			setRequest(encodeRequest(req))
+			responseBuf := &bytes.Buffer{}
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(responseBuf.Write).AnyTimes()
+			// don't EXPECT CancelRead()
+
+			serr := s.handleRequest(sess, nil, str, qpackDecoder, nil)
+			Expect(serr.err).To(MatchError(errHijacked))
+			hfs := decodeHeader(responseBuf)
+			Expect(hfs).To(HaveKeyWithValue(":status", []string{"200"}))
+			frame, err := parseNextFrame(responseBuf, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&dataFrame{Length: 6}))
+			Expect(responseBuf.Bytes()).To(Equal([]byte("foobar")))
+		})
+
+		It("refuses to tunnel requests other than CONNECT", func() {
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				_, err := w.(Tunneler).Tunnel()
+				Expect(err).To(MatchError(errNotConnect))
+			})
+
+			setRequest(encodeRequest(exampleGetRequest))
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return len(p), nil
+			}).AnyTimes()
+			str.EXPECT().CancelRead(gomock.Any())
+
+			serr := s.handleRequest(sess, nil, str, qpackDecoder, nil)
+			Expect(serr.err).ToNot(HaveOccurred())
+		})
+
+		It("gives the handler access to the QUIC session", func() {
+			sessChan := make(chan quic.Session, 1)
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				sessChan <- w.(Hijacker).Session()
+			})
+
+			setRequest(encodeRequest(exampleGetRequest))
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return len(p), nil
+			}).AnyTimes()
+			str.EXPECT().CancelRead(gomock.Any())
+
+			serr := s.handleRequest(sess, nil, str, qpackDecoder, nil)
+			Expect(serr.err).ToNot(HaveOccurred())
+			Expect(sessChan).To(Receive(Equal(sess)))
+		})
+
+		It("schedules the response using the priority signaled by the client, and the one set by the handler", func() {
+			conn := &serverConn{sess: sess, scheduler: newWriteScheduler()}
+			prioChan := make(chan Priority, 2)
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ps := w.(*responseWriter).prioritized
+				prioChan <- ps.priority
+				w.(PrioritySetter).SetPriority(Priority{Urgency: 6})
+				prioChan <- ps.priority
+			})
+
+			exampleGetRequest.Header.Set("Priority", "u=1, i")
+			setRequest(encodeRequest(exampleGetRequest))
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return len(p), nil
+			}).AnyTimes()
+			str.EXPECT().CancelRead(gomock.Any())
+
+			serr := s.handleRequest(sess, conn, str, qpackDecoder, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
+			Expect(prioChan).To(Receive(Equal(Priority{Urgency: 1, Incremental: true})))
+			Expect(prioChan).To(Receive(Equal(Priority{Urgency: 6})))
+			// the stream is removed from the scheduler when the response is complete
+			Expect(conn.scheduler.streams).To(BeEmpty())
+		})
+
+		Context("hijacking bidirectional streams", func() {
+			hijackData := func() []byte {
+				buf := &bytes.Buffer{}
+				quicvarint.Write(buf, 0x41)
+				quicvarint.Write(buf, 1337)
+				return buf.Bytes()
+			}
+
+			It("hijacks a stream starting with an unknown frame", func() {
+				s.StreamHijacker = func(ft FrameType, qsess quic.Session, qstr quic.Stream) (bool, error) {
+					defer GinkgoRecover()
+					Expect(ft).To(Equal(FrameType(0x41)))
+					Expect(qsess).To(Equal(sess))
+					// the stream is positioned right after the frame type
+					id, err := quicvarint.Read(quicvarint.NewReader(qstr))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(id).To(BeEquivalentTo(1337))
+					return true, nil
+				}
+				setRequest(hijackData())
+				// don't EXPECT any calls to str.CancelRead or str.Close
+				serr := s.handleRequest(sess, nil, str, qpackDecoder, nil)
+				Expect(serr.err).To(MatchError(errHijacked))
+			})
+
+			It("resets the stream if hijacking fails", func() {
+				s.StreamHijacker = func(FrameType, quic.Session, quic.Stream) (bool, error) {
+					return false, errors.New("hijacking failed")
+				}
+				setRequest(hijackData())
+				serr := s.handleRequest(sess, nil, str, qpackDecoder, nil)
+				Expect(serr.err).To(MatchError("hijacking failed"))
+				Expect(serr.streamErr).To(Equal(errorRequestIncomplete))
+			})
+
+			It("doesn't use the hijacker for frames defined by HTTP/3", func() {
+				s.StreamHijacker = func(FrameType, quic.Session, quic.Stream) (bool, error) {
+					Fail("didn't expect the hijacker to be called")
+					return false, nil
+				}
+				handlerCalled := make(chan struct{})
+				s.Handler = http.HandlerFunc(func(http.ResponseWriter, *http.Request) { close(handlerCalled) })
+				setRequest(encodeRequest(exampleGetRequest))
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return len(p), nil
+				}).AnyTimes()
+				str.EXPECT().CancelRead(gomock.Any())
+				serr := s.handleRequest(sess, nil, str, qpackDecoder, nil)
+				Expect(serr.err).ToNot(HaveOccurred())
+				Expect(handlerCalled).To(BeClosed())
+			})
 		})
 
 		Context("control stream handling", func() {
@@ -226,6 +372,25 @@ var _ = Describe("Server", func() {
 				time.Sleep(scaleDuration(20 * time.Millisecond)) // don't EXPECT any calls to sess.CloseWithError
 			})
 
+			It("passes the client's additional settings to the callback", func() {
+				received := make(chan map[uint64]uint64, 1)
+				s.OnSettingsReceived = func(_ quic.Session, settings map[uint64]uint64) { received <- settings }
+				buf := &bytes.Buffer{}
+				quicvarint.Write(buf, streamTypeControlStream)
+				(&settingsFrame{other: map[uint64]uint64{0x1337: 42}}).Write(buf)
+				controlStr := mockquic.NewMockStream(mockCtrl)
+				controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+				sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+					return controlStr, nil
+				})
+				sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+					<-testDone
+					return nil, errors.New("test done")
+				})
+				s.handleConn(sess)
+				Eventually(received).Should(Receive(Equal(map[uint64]uint64{0x1337: 42})))
+			})
+
 			for _, t := range []uint64{streamTypeQPACKEncoderStream, streamTypeQPACKDecoderStream} {
 				streamType := t
 				name := "encoder"
@@ -272,6 +437,33 @@ var _ = Describe("Server", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			It("lets the hijacker take over unidirectional streams of unknown type", func() {
+				buf := &bytes.Buffer{}
+				quicvarint.Write(buf, 0x54)
+				str := mockquic.NewMockStream(mockCtrl)
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+				hijacked := make(chan struct{})
+				s.UniStreamHijacker = func(st StreamType, qsess quic.Session, qstr quic.ReceiveStream) bool {
+					defer GinkgoRecover()
+					Expect(st).To(Equal(StreamType(0x54)))
+					Expect(qsess).To(Equal(sess))
+					Expect(qstr).To(Equal(str))
+					close(hijacked)
+					return true
+				}
+
+				sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+					return str, nil
+				})
+				sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+					<-testDone
+					return nil, errors.New("test done")
+				})
+				s.handleConn(sess)
+				Eventually(hijacked).Should(BeClosed())
+				time.Sleep(scaleDuration(20 * time.Millisecond)) // don't EXPECT any calls to str.CancelRead
+			})
+
 			It("errors when the first frame on the control stream is not a SETTINGS frame", func() {
 				buf := &bytes.Buffer{}
 				quicvarint.Write(buf, streamTypeControlStream)
@@ -295,6 +487,83 @@ var _ = Describe("Server", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			It("errors when the client reduces the maximum push ID", func() {
+				buf := &bytes.Buffer{}
+				quicvarint.Write(buf, streamTypeControlStream)
+				(&settingsFrame{}).Write(buf)
+				(&maxPushIDFrame{PushID: 10}).Write(buf)
+				(&maxPushIDFrame{PushID: 5}).Write(buf)
+				controlStr := mockquic.NewMockStream(mockCtrl)
+				controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+				sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+					return controlStr, nil
+				})
+				sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+					<-testDone
+					return nil, errors.New("test done")
+				})
+				done := make(chan struct{})
+				sess.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(code quic.ApplicationErrorCode, _ string) {
+					defer GinkgoRecover()
+					Expect(code).To(BeEquivalentTo(errorIDError))
+					close(done)
+				})
+				s.handleConn(sess)
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("errors when the client sends a PRIORITY_UPDATE frame for an invalid stream", func() {
+				buf := &bytes.Buffer{}
+				quicvarint.Write(buf, streamTypeControlStream)
+				(&settingsFrame{}).Write(buf)
+				(&priorityUpdateFrame{ElementID: 4, PriorityFieldValue: "u=1"}).Write(buf)
+				(&priorityUpdateFrame{Push: true, ElementID: 3, PriorityFieldValue: "u=1"}).Write(buf)
+				(&priorityUpdateFrame{ElementID: 2, PriorityFieldValue: "u=1"}).Write(buf)
+				controlStr := mockquic.NewMockStream(mockCtrl)
+				controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+				sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+					return controlStr, nil
+				})
+				sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+					<-testDone
+					return nil, errors.New("test done")
+				})
+				done := make(chan struct{})
+				sess.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(code quic.ApplicationErrorCode, reason string) {
+					defer GinkgoRecover()
+					Expect(code).To(BeEquivalentTo(errorIDError))
+					Expect(reason).To(Equal("PRIORITY_UPDATE for invalid stream 2"))
+					close(done)
+				})
+				s.handleConn(sess)
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("errors when the client sends an unexpected frame on the control stream", func() {
+				buf := &bytes.Buffer{}
+				quicvarint.Write(buf, streamTypeControlStream)
+				(&settingsFrame{}).Write(buf)
+				(&maxPushIDFrame{PushID: 10}).Write(buf)
+				(&dataFrame{}).Write(buf)
+				controlStr := mockquic.NewMockStream(mockCtrl)
+				controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+				sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+					return controlStr, nil
+				})
+				sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+					<-testDone
+					return nil, errors.New("test done")
+				})
+				done := make(chan struct{})
+				sess.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(code quic.ApplicationErrorCode, _ string) {
+					defer GinkgoRecover()
+					Expect(code).To(BeEquivalentTo(errorFrameUnexpected))
+					close(done)
+				})
+				s.handleConn(sess)
+				Eventually(done).Should(BeClosed())
+			})
+
 			It("errors when parsing the frame on the control stream fails", func() {
 				buf := &bytes.Buffer{}
 				quicvarint.Write(buf, streamTypeControlStream)
@@ -345,6 +614,7 @@ var _ = Describe("Server", func() {
 
 			It("errors when the client advertises datagram support (and we enabled support for it)", func() {
 				s.EnableDatagrams = true
+				sess.EXPECT().ReceiveDatagram(gomock.Any()).Return(nil, errors.New("closed")).AnyTimes()
 				buf := &bytes.Buffer{}
 				quicvarint.Write(buf, streamTypeControlStream)
 				(&settingsFrame{Datagram: true}).Write(buf)
@@ -357,7 +627,7 @@ var _ = Describe("Server", func() {
 					<-testDone
 					return nil, errors.New("test done")
 				})
-				sess.EXPECT().ConnectionState().Return(quic.ConnectionState{SupportsDatagrams: false})
+				sess.EXPECT().ConnectionStateErr().Return(quic.ConnectionState{SupportsDatagrams: false}, nil)
 				done := make(chan struct{})
 				sess.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(code quic.ApplicationErrorCode, reason string) {
 					defer GinkgoRecover()
@@ -498,6 +768,120 @@ var _ = Describe("Server", func() {
 			})
 		})
 
+		Context("graceful shutdown", func() {
+			var (
+				controlStrWrites chan []byte
+				connDone         chan struct{}
+			)
+
+			BeforeEach(func() {
+				controlStrWrites = make(chan []byte, 2)
+				connDone = make(chan struct{})
+				controlStr := mockquic.NewMockStream(mockCtrl)
+				controlStr.EXPECT().Write(gomock.Any()).DoAndReturn(func(b []byte) (int, error) {
+					controlStrWrites <- append([]byte{}, b...)
+					return len(b), nil
+				}).AnyTimes()
+				sess.EXPECT().OpenUniStream().Return(controlStr, nil)
+				done := connDone
+				sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+					<-done
+					return nil, errors.New("test done")
+				})
+				sess.EXPECT().AcceptStream(gomock.Any()).Return(str, nil)
+			})
+
+			AfterEach(func() { close(connDone) })
+
+			// blockingHandler returns a handler that blocks until unblock is closed
+			blockingHandler := func(started, unblock chan struct{}) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					close(started)
+					<-unblock
+				})
+			}
+
+			// expectRequest returns a channel that is closed when the request completes
+			expectRequest := func() <-chan struct{} {
+				setRequest(encodeRequest(exampleGetRequest))
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return len(p), nil
+				}).AnyTimes()
+				str.EXPECT().CancelRead(gomock.Any())
+				done := make(chan struct{})
+				str.EXPECT().Close().Do(func() { close(done) })
+				return done
+			}
+
+			It("sends a GOAWAY frame, rejects new requests, and waits for running requests to complete and connections to be closed", func() {
+				started := make(chan struct{})
+				unblock := make(chan struct{})
+				s.Handler = blockingHandler(started, unblock)
+				expectRequest()
+				acceptNext := make(chan struct{})
+				rejected := make(chan struct{})
+				str2 := mockquic.NewMockStream(mockCtrl)
+				str2.EXPECT().StreamID().Return(quic.StreamID(4)).AnyTimes()
+				str2.EXPECT().CancelRead(quic.StreamErrorCode(errorRequestRejected))
+				str2.EXPECT().CancelWrite(quic.StreamErrorCode(errorRequestRejected)).Do(func(quic.StreamErrorCode) { close(rejected) })
+				sess.EXPECT().AcceptStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.Stream, error) {
+					<-acceptNext
+					return str2, nil
+				})
+				clientClosed := make(chan struct{})
+				sess.EXPECT().AcceptStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.Stream, error) {
+					<-clientClosed
+					return nil, errors.New("connection closed")
+				})
+
+				go s.handleConn(sess)
+				Eventually(started).Should(BeClosed())
+				Eventually(controlStrWrites).Should(Receive()) // the SETTINGS frame
+
+				shutdownErr := make(chan error, 1)
+				go func() { shutdownErr <- s.Shutdown(context.Background()) }()
+				var data []byte
+				Eventually(controlStrWrites).Should(Receive(&data))
+				frame, err := parseNextFrame(bytes.NewReader(data), nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame).To(Equal(&goAwayFrame{StreamID: 4}))
+
+				// requests sent after the GOAWAY frame are rejected
+				close(acceptNext)
+				Eventually(rejected).Should(BeClosed())
+
+				Consistently(shutdownErr).ShouldNot(Receive())
+				close(unblock)
+				// the client closes the connection once it received the response
+				Consistently(shutdownErr).ShouldNot(Receive())
+				close(clientClosed)
+				Eventually(shutdownErr).Should(Receive(&err))
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns when the context is canceled before all requests complete", func() {
+				started := make(chan struct{})
+				unblock := make(chan struct{})
+				s.Handler = blockingHandler(started, unblock)
+				requestDone := expectRequest()
+				done := connDone
+				sess.EXPECT().AcceptStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.Stream, error) {
+					<-done
+					return nil, errors.New("test done")
+				})
+
+				go s.handleConn(sess)
+				Eventually(started).Should(BeClosed())
+
+				ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(20*time.Millisecond))
+				defer cancel()
+				Expect(s.Shutdown(ctx)).To(MatchError(context.DeadlineExceeded))
+				close(unblock)
+				Eventually(requestDone).Should(BeClosed())
+			})
+		})
+
 		It("resets the stream when the body of POST request is not read, and the request handler replaces the request.Body", func() {
 			handlerCalled := make(chan struct{})
 			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -515,7 +899,7 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(quic.StreamErrorCode(errorNoError))
 
-			serr := s.handleRequest(sess, str, qpackDecoder, nil)
+			serr := s.handleRequest(sess, nil, str, qpackDecoder, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			Eventually(handlerCalled).Should(BeClosed())
 		})
@@ -538,7 +922,7 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(quic.StreamErrorCode(errorNoError))
 
-			serr := s.handleRequest(sess, str, qpackDecoder, nil)
+			serr := s.handleRequest(sess, nil, str, qpackDecoder, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			Eventually(handlerCalled).Should(BeClosed())
 		})
@@ -591,6 +975,14 @@ var _ = Describe("Server", func() {
 			Expect(s.SetQuicHeaders(hdr)).To(Succeed())
 			Expect(hdr).To(Equal(http.Header{"Alt-Svc": {`h3=":443"; ma=2592000,h3-29=":443"; ma=2592000`}}))
 		})
+
+		It("only advertises every ALPN once", func() {
+			s.Server.Addr = ":443"
+			s.QuicConfig.Versions = []quic.VersionNumber{quic.Version1, quic.Version2, quic.VersionDraft29}
+			hdr := http.Header{}
+			Expect(s.SetQuicHeaders(hdr)).To(Succeed())
+			Expect(hdr).To(Equal(http.Header{"Alt-Svc": {`h3=":443"; ma=2592000,h3-29=":443"; ma=2592000`}}))
+		})
 	})
 
 	It("errors when ListenAndServe is called with s.Server nil", func() {
@@ -694,6 +1086,26 @@ var _ = Describe("Server", func() {
 			Eventually(done1).Should(BeClosed())
 			Eventually(done2).Should(BeClosed())
 		})
+		It("stops accepting connections on Shutdown", func() {
+			ln := mockquic.NewMockEarlyListener(mockCtrl)
+			quicListen = func(net.PacketConn, *tls.Config, *quic.Config) (quic.EarlyListener, error) { return ln, nil }
+
+			s := &Server{Server: &http.Server{}}
+			s.TLSConfig = &tls.Config{}
+
+			ln.EXPECT().Accept(gomock.Any()).DoAndReturn(func(ctx context.Context) (quic.EarlySession, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			})
+			serveErr := make(chan error, 1)
+			go func() { serveErr <- s.Serve(&net.UDPConn{}) }()
+
+			Consistently(serveErr).ShouldNot(Receive())
+			ln.EXPECT().Close()
+			Expect(s.Shutdown(context.Background())).To(Succeed())
+			Eventually(serveErr).Should(Receive(Equal(http.ErrServerClosed)))
+			Expect(s.Serve(&net.UDPConn{})).To(MatchError(http.ErrServerClosed))
+		})
 	})
 
 	Context("ListenAndServe", func() {
@@ -0,0 +1,103 @@
+package http3
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+// A Tunneler is implemented by the http.ResponseWriter passed to server handlers.
+// It is used to implement a forward proxy, which handles CONNECT requests (see section 4.4 of RFC 9114).
+//
+// Tunnel hijacks the stream of a CONNECT request. Unless the handler already sent a 2xx response,
+// it sends a 200 response. It then returns the tunnel to the client, and the handler proxies the data
+// between the tunnel and the TCP connection to the target (the Host of the request).
+// Calling Tunnel for other methods, or after sending a non-2xx response, returns an error.
+//
+// After a call to Tunnel, the HTTP server library will not do anything else with the stream,
+// and neither the http.ResponseWriter nor the original Request.Body must be used anymore.
+// It becomes the caller's responsibility to close the tunnel, even after the handler returned.
+type Tunneler interface {
+	Tunnel() (TunnelConn, error)
+}
+
+// A TunnelConn is the bidirectional byte stream of a CONNECT tunnel.
+// The data is sent and received in DATA frames on the request stream.
+//
+// The half-closes of the TCP connection map to the FIN on the stream:
+// Read returns io.EOF when the client closed its side of the tunnel, at which point the proxy should call
+// CloseWrite on the TCP connection. When the target closed its side, the proxy should call CloseWrite on the tunnel.
+// Data is neither buffered when reading nor when writing, so that stream flow control applies backpressure:
+// Write blocks while the client doesn't read, and the client is blocked while the proxy doesn't Read.
+//
+// If the client aborts the tunnel, Read and Write return a quic.StreamError.
+// The proxy should then reset the TCP connection.
+type TunnelConn interface {
+	net.Conn
+	// CloseWrite closes the sending side of the tunnel.
+	// Data can still be read from the tunnel.
+	CloseWrite() error
+	// Abort abruptly terminates the tunnel with an H3_CONNECT_ERROR.
+	// It is used to signal an error on the TCP connection, e.g. the receipt of a TCP RST, to the client.
+	Abort()
+}
+
+var errNotConnect = errors.New("http3: Tunnel is only supported for CONNECT requests")
+
+type tunnelConn struct {
+	sess quic.Session
+	str  quic.Stream
+	body *body
+}
+
+var _ TunnelConn = &tunnelConn{}
+
+func newTunnelConn(sess quic.Session, str quic.Stream, body *body) *tunnelConn {
+	// Only DATA frames are allowed once the CONNECT request completed.
+	body.parseTrailers = func(*headersFrame) error {
+		body.onFrameError()
+		return errors.New("peer sent a HEADERS frame on a CONNECT tunnel")
+	}
+	return &tunnelConn{sess: sess, str: str, body: body}
+}
+
+func (c *tunnelConn) Read(b []byte) (int, error) {
+	return c.body.Read(b)
+}
+
+func (c *tunnelConn) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	buf := &bytes.Buffer{}
+	(&dataFrame{Length: uint64(len(p))}).Write(buf)
+	if _, err := c.str.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return c.str.Write(p)
+}
+
+func (c *tunnelConn) CloseWrite() error {
+	return c.str.Close()
+}
+
+// Close closes both directions of the tunnel.
+func (c *tunnelConn) Close() error {
+	// If the EOF was read, CancelRead() is a no-op.
+	c.str.CancelRead(quic.StreamErrorCode(errorNoError))
+	return c.str.Close()
+}
+
+func (c *tunnelConn) Abort() {
+	c.str.CancelRead(quic.StreamErrorCode(errorConnectError))
+	c.str.CancelWrite(quic.StreamErrorCode(errorConnectError))
+}
+
+func (c *tunnelConn) LocalAddr() net.Addr                { return c.sess.LocalAddr() }
+func (c *tunnelConn) RemoteAddr() net.Addr               { return c.sess.RemoteAddr() }
+func (c *tunnelConn) SetDeadline(t time.Time) error      { return c.str.SetDeadline(t) }
+func (c *tunnelConn) SetReadDeadline(t time.Time) error  { return c.str.SetReadDeadline(t) }
+func (c *tunnelConn) SetWriteDeadline(t time.Time) error { return c.str.SetWriteDeadline(t) }
@@ -0,0 +1,174 @@
+package http3
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/marten-seemann/qpack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CONNECT tunnels", func() {
+	var (
+		str           *mockquic.MockStream
+		sess          *mockquic.MockEarlySession
+		rw            *responseWriter
+		in, out       *bytes.Buffer
+		errorCbCalled bool
+	)
+
+	getDataFrame := func(data []byte) []byte {
+		b := &bytes.Buffer{}
+		(&dataFrame{Length: uint64(len(data))}).Write(b)
+		b.Write(data)
+		return b.Bytes()
+	}
+
+	BeforeEach(func() {
+		in = &bytes.Buffer{}
+		out = &bytes.Buffer{}
+		errorCbCalled = false
+		str = mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().Write(gomock.Any()).DoAndReturn(out.Write).AnyTimes()
+		str.EXPECT().Read(gomock.Any()).DoAndReturn(in.Read).AnyTimes()
+		sess = mockquic.NewMockEarlySession(mockCtrl)
+		rw = newResponseWriter(str, utils.DefaultLogger)
+		rw.sess = sess
+		rw.connectBody = newRequestBody(str, func() { errorCbCalled = true })
+	})
+
+	It("refuses to tunnel requests other than CONNECT", func() {
+		rw.connectBody = nil
+		_, err := rw.Tunnel()
+		Expect(err).To(MatchError(errNotConnect))
+		Expect(rw.usedDataStream()).To(BeFalse())
+	})
+
+	readStatus := func() string {
+		frame, err := parseNextFrame(out, nil)
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		ExpectWithOffset(1, frame).To(BeAssignableToTypeOf(&headersFrame{}))
+		data := make([]byte, frame.(*headersFrame).Length)
+		_, err = io.ReadFull(out, data)
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		hfs, err := qpack.NewDecoder(nil).DecodeFull(data)
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		for _, hf := range hfs {
+			if hf.Name == ":status" {
+				return hf.Value
+			}
+		}
+		return ""
+	}
+
+	It("sends a 200 response", func() {
+		_, err := rw.Tunnel()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rw.usedDataStream()).To(BeTrue())
+		Expect(readStatus()).To(Equal("200"))
+		Expect(out.Len()).To(BeZero())
+	})
+
+	It("uses a 2xx response sent by the handler", func() {
+		rw.WriteHeader(http.StatusAccepted)
+		_, err := rw.Tunnel()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(readStatus()).To(Equal("202"))
+		Expect(out.Len()).To(BeZero())
+	})
+
+	It("refuses to tunnel after a non-2xx response", func() {
+		rw.WriteHeader(http.StatusBadGateway)
+		_, err := rw.Tunnel()
+		Expect(err).To(MatchError("http3: cannot tunnel after sending a 502 response"))
+		Expect(rw.usedDataStream()).To(BeFalse())
+	})
+
+	Context("using the tunnel", func() {
+		var conn TunnelConn
+
+		BeforeEach(func() {
+			var err error
+			conn, err = rw.Tunnel()
+			Expect(err).ToNot(HaveOccurred())
+			out.Reset()
+		})
+
+		It("sends data in DATA frames", func() {
+			n, err := conn.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(6))
+			Expect(out.Bytes()).To(Equal(getDataFrame([]byte("foobar"))))
+		})
+
+		It("doesn't send empty DATA frames", func() {
+			n, err := conn.Write(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(BeZero())
+			Expect(out.Len()).To(BeZero())
+		})
+
+		It("reads data from DATA frames", func() {
+			in.Write(getDataFrame([]byte("foo")))
+			in.Write(getDataFrame([]byte("bar")))
+			data, err := io.ReadAll(conn)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foobar")))
+		})
+
+		It("errors when the client sends a HEADERS frame", func() {
+			in.Write(getDataFrame([]byte("foo")))
+			(&headersFrame{Length: 0}).Write(in)
+			_, err := io.ReadAll(conn)
+			Expect(err).To(MatchError("peer sent a HEADERS frame on a CONNECT tunnel"))
+			Expect(errorCbCalled).To(BeTrue())
+		})
+
+		It("half-closes the tunnel", func() {
+			str.EXPECT().Close()
+			Expect(conn.CloseWrite()).To(Succeed())
+		})
+
+		It("closes the tunnel", func() {
+			gomock.InOrder(
+				str.EXPECT().CancelRead(quic.StreamErrorCode(errorNoError)),
+				str.EXPECT().Close(),
+			)
+			Expect(conn.Close()).To(Succeed())
+		})
+
+		It("aborts the tunnel", func() {
+			str.EXPECT().CancelRead(quic.StreamErrorCode(errorConnectError))
+			str.EXPECT().CancelWrite(quic.StreamErrorCode(errorConnectError))
+			conn.Abort()
+		})
+
+		It("returns the addresses of the session", func() {
+			local := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 443}
+			remote := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}
+			sess.EXPECT().LocalAddr().Return(local)
+			sess.EXPECT().RemoteAddr().Return(remote)
+			Expect(conn.LocalAddr()).To(Equal(local))
+			Expect(conn.RemoteAddr()).To(Equal(remote))
+		})
+
+		It("sets the deadlines on the stream", func() {
+			t := time.Now().Add(time.Hour)
+			str.EXPECT().SetDeadline(t)
+			str.EXPECT().SetReadDeadline(t)
+			str.EXPECT().SetWriteDeadline(t)
+			Expect(conn.SetDeadline(t)).To(Succeed())
+			Expect(conn.SetReadDeadline(t)).To(Succeed())
+			Expect(conn.SetWriteDeadline(t)).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,126 @@
+package self_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Connection Close", func() {
+	const (
+		errorCode    = quic.ApplicationErrorCode(0x1337)
+		errorMessage = "closing with a custom reason"
+	)
+
+	expectApplicationError := func(err error) {
+		ExpectWithOffset(1, err).To(HaveOccurred())
+		var appErr *quic.ApplicationError
+		ExpectWithOffset(1, errors.As(err, &appErr)).To(BeTrue())
+		ExpectWithOffset(1, appErr.Remote).To(BeTrue())
+		ExpectWithOffset(1, appErr.ErrorCode).To(Equal(errorCode))
+		ExpectWithOffset(1, appErr.ErrorMessage).To(Equal(errorMessage))
+	}
+
+	It("surfaces the error code and the reason to the peer", func() {
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			// wait until the client has read the data
+			_, err = str.Read(make([]byte, 1))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sess.CloseWithError(errorCode, errorMessage)).To(Succeed())
+		}()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := sess.AcceptStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		b := make([]byte, 6)
+		_, err = io.ReadFull(str, b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(Equal([]byte("foobar")))
+		_, err = str.Write([]byte{'a'})
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = str.Read(b)
+		expectApplicationError(err)
+		_, err = sess.AcceptStream(context.Background())
+		expectApplicationError(err)
+		Eventually(sess.Context().Done()).Should(BeClosed())
+	})
+
+	It("retransmits the CONNECTION_CLOSE if it is lost", func() {
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		var closing, numDropped int32
+		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr:  fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			DelayPacket: func(quicproxy.Direction, []byte) time.Duration { return 5 * time.Millisecond },
+			DropPacket: func(dir quicproxy.Direction, _ []byte) bool {
+				// drop the first packet sent by the server after it starts closing the connection
+				if dir == quicproxy.DirectionOutgoing && atomic.LoadInt32(&closing) == 1 {
+					return atomic.AddInt32(&numDropped, 1) == 1
+				}
+				return false
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Read(make([]byte, 1))
+			Expect(err).ToNot(HaveOccurred())
+			atomic.StoreInt32(&closing, 1)
+			Expect(sess.CloseWithError(errorCode, errorMessage)).To(Succeed())
+		}()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", proxy.LocalAddr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := sess.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		// Keep sending data, so that the server receives packets after closing the connection.
+		// This makes it retransmit the CONNECTION_CLOSE.
+		for {
+			if _, err = str.Write([]byte("foobar")); err != nil {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		expectApplicationError(err)
+		Expect(atomic.LoadInt32(&numDropped)).To(BeNumerically(">=", 1))
+	})
+})
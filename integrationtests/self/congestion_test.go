@@ -0,0 +1,96 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/congestion"
+	"github.com/lucas-clemente/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fixedWindowSender is a congestion controller with a fixed congestion window.
+// It only uses the public congestion package.
+type fixedWindowSender struct {
+	window       congestion.ByteCount
+	ackedPackets int64 // accessed atomically
+}
+
+var _ congestion.SendAlgorithmWithDebugInfos = &fixedWindowSender{}
+
+func (s *fixedWindowSender) TimeUntilSend(congestion.ByteCount) time.Time { return time.Time{} }
+func (s *fixedWindowSender) HasPacingBudget() bool                        { return true }
+func (s *fixedWindowSender) OnPacketSent(time.Time, congestion.ByteCount, congestion.PacketNumber, congestion.ByteCount, bool) {
+}
+
+func (s *fixedWindowSender) CanSend(bytesInFlight congestion.ByteCount) bool {
+	return bytesInFlight < s.window
+}
+func (s *fixedWindowSender) OnRttUpdated() {}
+func (s *fixedWindowSender) OnPacketAcked(congestion.PacketNumber, congestion.ByteCount, congestion.ByteCount, time.Time) {
+	atomic.AddInt64(&s.ackedPackets, 1)
+}
+func (s *fixedWindowSender) OnPacketLost(congestion.PacketNumber, congestion.ByteCount, congestion.ByteCount) {
+}
+func (s *fixedWindowSender) OnRetransmissionTimeout(bool)              {}
+func (s *fixedWindowSender) SetMaxDatagramSize(congestion.ByteCount)   {}
+func (s *fixedWindowSender) InSlowStart() bool                         { return false }
+func (s *fixedWindowSender) InRecovery() bool                          { return false }
+func (s *fixedWindowSender) GetCongestionWindow() congestion.ByteCount { return s.window }
+
+var _ = Describe("Custom congestion control", func() {
+	It("uses a congestion controller created by Config.NewCongestionControl", func() {
+		sender := &fixedWindowSender{window: 20 * 1200}
+		serverConf := getQuicConfig(&quic.Config{
+			NewCongestionControl: func(*congestion.RTTStats, congestion.ByteCount, logging.ConnectionTracer) congestion.SendAlgorithmWithDebugInfos {
+				return sender
+			},
+		})
+		data := GeneratePRData(500 << 10)
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), serverConf)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		serverSess := make(chan quic.Session, 1)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+			serverSess <- sess
+		}()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		str, err := sess.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		b, err := io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(Equal(data))
+
+		var s quic.Session
+		Eventually(serverSess).Should(Receive(&s))
+		Expect(atomic.LoadInt64(&sender.ackedPackets)).ToNot(BeZero())
+		state := s.CongestionState()
+		Expect(state.CongestionControl).To(Equal("custom"))
+		Expect(state.Hystart).To(Equal("unknown"))
+		Expect(state.CongestionWindow).To(Equal(congestion.ByteCount(20 * 1200)))
+		Expect(state.BandwidthEstimate).To(BeZero())
+	})
+})
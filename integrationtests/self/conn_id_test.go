@@ -6,14 +6,93 @@ import (
 	"io"
 	"math/rand"
 	"net"
+	"sync"
 
 	quic "github.com/lucas-clemente/quic-go"
+	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/logging"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
+type prefixConnIDGenerator struct {
+	prefix    byte
+	connIDLen int
+
+	mutex     sync.Mutex
+	generated int
+}
+
+var _ quic.ConnectionIDGenerator = &prefixConnIDGenerator{}
+
+func (g *prefixConnIDGenerator) GenerateConnectionID() ([]byte, error) {
+	g.mutex.Lock()
+	g.generated++
+	g.mutex.Unlock()
+	b := make([]byte, g.connIDLen)
+	if len(b) > 0 {
+		b[0] = g.prefix
+		rand.Read(b[1:])
+	}
+	return b, nil
+}
+
+func (g *prefixConnIDGenerator) ConnectionIDLen() int { return g.connIDLen }
+
+func (g *prefixConnIDGenerator) numGenerated() int {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.generated
+}
+
+// connIDRotationTracer records the NEW_CONNECTION_ID frames sent that retire older connection IDs,
+// and the sequence numbers of the connection IDs retired by the peer.
+type connIDRotationTracer struct {
+	connTracer
+
+	mutex         sync.Mutex
+	rotatedTo     *logging.NewConnectionIDFrame
+	retiredConnID map[uint64]struct{}
+}
+
+func (t *connIDRotationTracer) SentPacket(_ *logging.ExtendedHeader, _ logging.ByteCount, _ *logging.AckFrame, frames []logging.Frame) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, f := range frames {
+		if nf, ok := f.(*logging.NewConnectionIDFrame); ok && nf.RetirePriorTo > 0 && t.rotatedTo == nil {
+			t.rotatedTo = nf
+		}
+	}
+}
+
+func (t *connIDRotationTracer) ReceivedPacket(_ *logging.ExtendedHeader, _ logging.ByteCount, frames []logging.Frame) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, f := range frames {
+		if rf, ok := f.(*logging.RetireConnectionIDFrame); ok {
+			if t.retiredConnID == nil {
+				t.retiredConnID = make(map[uint64]struct{})
+			}
+			t.retiredConnID[rf.SequenceNumber] = struct{}{}
+		}
+	}
+}
+
+func (t *connIDRotationTracer) getRotatedTo() *logging.NewConnectionIDFrame {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.rotatedTo
+}
+
+func (t *connIDRotationTracer) isRetired(seq uint64) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	_, ok := t.retiredConnID[seq]
+	return ok
+}
+
 var _ = Describe("Connection ID lengths tests", func() {
 	randomConnIDLen := func() int {
 		return 4 + int(rand.Int31n(15))
@@ -87,4 +166,161 @@ var _ = Describe("Connection ID lengths tests", func() {
 		defer ln.Close()
 		runClient(ln.Addr(), clientConf)
 	})
+
+	It("downloads files from two servers on a shared packet conn using a 0-byte connection ID generator", func() {
+		serverConf := getQuicConfig(&quic.Config{
+			ConnectionIDLength: randomConnIDLen(),
+			Versions:           []protocol.VersionNumber{protocol.VersionTLS},
+		})
+		ln1 := runServer(serverConf)
+		defer ln1.Close()
+		ln2 := runServer(serverConf)
+		defer ln2.Close()
+
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		clientConf := getQuicConfig(&quic.Config{
+			ConnectionIDGenerator: &prefixConnIDGenerator{connIDLen: 0},
+			Versions:              []protocol.VersionNumber{protocol.VersionTLS},
+		})
+
+		var wg sync.WaitGroup
+		for _, ln := range []quic.Listener{ln1, ln2} {
+			wg.Add(1)
+			go func(addr net.Addr) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				sess, err := quic.Dial(
+					conn,
+					&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: addr.(*net.UDPAddr).Port},
+					"localhost",
+					getTLSClientConfig(),
+					clientConf,
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer sess.CloseWithError(0, "")
+				str, err := sess.AcceptStream(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				data, err := io.ReadAll(str)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal(PRData))
+			}(ln.Addr())
+		}
+		wg.Wait()
+	})
+
+	It("uses the connection ID generator", func() {
+		gen := &prefixConnIDGenerator{prefix: 0x42, connIDLen: 8}
+		serverConf := getQuicConfig(&quic.Config{
+			ConnectionIDGenerator: gen,
+			Versions:              []protocol.VersionNumber{protocol.VersionTLS},
+		})
+		ln := runServer(serverConf)
+		defer ln.Close()
+
+		var mutex sync.Mutex
+		var shortHeaderConnIDs [][]byte
+		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr: fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			DropPacket: func(dir quicproxy.Direction, data []byte) bool {
+				if dir == quicproxy.DirectionIncoming && data[0]&0x80 == 0 {
+					mutex.Lock()
+					shortHeaderConnIDs = append(shortHeaderConnIDs, append([]byte{}, data[1:1+gen.connIDLen]...))
+					mutex.Unlock()
+				}
+				return false
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		runClient(proxy.LocalAddr(), getQuicConfig(&quic.Config{Versions: []protocol.VersionNumber{protocol.VersionTLS}}))
+		// the server's initial connection ID, and the ones issued in NEW_CONNECTION_ID frames
+		Eventually(gen.numGenerated).Should(BeNumerically(">", 1))
+		mutex.Lock()
+		defer mutex.Unlock()
+		Expect(shortHeaderConnIDs).ToNot(BeEmpty())
+		for _, connID := range shortHeaderConnIDs {
+			Expect(connID[0]).To(Equal(gen.prefix))
+		}
+	})
+
+	It("rotates the connection ID", func() {
+		gen := &prefixConnIDGenerator{prefix: 0x42, connIDLen: 8}
+		tracer := &connIDRotationTracer{}
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(&quic.Config{
+			ConnectionIDGenerator: gen,
+			Tracer:                newTracer(func() logging.ConnectionTracer { return tracer }),
+		}))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		var mutex sync.Mutex
+		var lastShortHeaderConnID []byte
+		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr: fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			DropPacket: func(dir quicproxy.Direction, data []byte) bool {
+				if dir == quicproxy.DirectionIncoming && data[0]&0x80 == 0 {
+					mutex.Lock()
+					lastShortHeaderConnID = append([]byte{}, data[1:1+gen.connIDLen]...)
+					mutex.Unlock()
+				}
+				return false
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		serverSess := make(chan quic.Session, 1)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			serverSess <- sess
+			str, err := sess.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			// echo the data until the client closes the connection
+			io.Copy(str, str)
+		}()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", proxy.LocalAddr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		str, err := sess.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		echo := func() {
+			_, err := str.Write([]byte("foobar"))
+			ExpectWithOffset(1, err).ToNot(HaveOccurred())
+			b := make([]byte, 6)
+			_, err = io.ReadFull(str, b)
+			ExpectWithOffset(1, err).ToNot(HaveOccurred())
+			ExpectWithOffset(1, b).To(Equal([]byte("foobar")))
+		}
+		echo()
+
+		var server quic.Session
+		Eventually(serverSess).Should(Receive(&server))
+		Expect(server.RotateConnectionID()).To(Succeed())
+		Eventually(tracer.getRotatedTo).ShouldNot(BeNil())
+		rotatedTo := tracer.getRotatedTo()
+		Eventually(func() bool {
+			for seq := uint64(0); seq < rotatedTo.RetirePriorTo; seq++ {
+				if !tracer.isRetired(seq) {
+					return false
+				}
+			}
+			return true
+		}).Should(BeTrue())
+
+		// all short header packets sent by the client after retiring the old connection IDs use the new connection ID
+		echo()
+		mutex.Lock()
+		defer mutex.Unlock()
+		Expect(protocol.ConnectionID(lastShortHeaderConnID)).To(Equal(rotatedTo.ConnectionID))
+	})
 })
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/binary"
 	"fmt"
+	"io"
 	mrand "math/rand"
 	"net"
 	"sync"
@@ -11,6 +12,7 @@ import (
 	"time"
 
 	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/congestion"
 	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 
@@ -49,7 +51,7 @@ var _ = Describe("Datagram test", func() {
 					defer GinkgoRecover()
 					sess, err := ln.Accept(context.Background())
 					Expect(err).ToNot(HaveOccurred())
-					Expect(sess.ConnectionState().SupportsDatagrams).To(BeTrue())
+					Expect(connectionState(sess).SupportsDatagrams).To(BeTrue())
 
 					var wg sync.WaitGroup
 					wg.Add(num)
@@ -59,7 +61,7 @@ var _ = Describe("Datagram test", func() {
 							defer wg.Done()
 							b := make([]byte, 8)
 							binary.BigEndian.PutUint64(b, uint64(i))
-							Expect(sess.SendMessage(b)).To(Succeed())
+							Expect(sess.SendDatagram(b)).To(Succeed())
 						}(i)
 					}
 					wg.Wait()
@@ -113,14 +115,14 @@ var _ = Describe("Datagram test", func() {
 					}),
 				)
 				Expect(err).ToNot(HaveOccurred())
-				Expect(sess.ConnectionState().SupportsDatagrams).To(BeTrue())
+				Expect(connectionState(sess).SupportsDatagrams).To(BeTrue())
 				var counter int
 				for {
 					// Close the session if no message is received for 100 ms.
 					timer := time.AfterFunc(scaleDuration(100*time.Millisecond), func() {
 						sess.CloseWithError(0, "")
 					})
-					if _, err := sess.ReceiveMessage(); err != nil {
+					if _, err := sess.ReceiveDatagram(context.Background()); err != nil {
 						break
 					}
 					timer.Stop()
@@ -138,4 +140,96 @@ var _ = Describe("Datagram test", func() {
 			})
 		})
 	}
+
+	It("sends stream data while flooding datagrams, when limited by the congestion window", func() {
+		const dataLen = 100 << 10 // 100 KB
+		data := GeneratePRData(dataLen)
+		ln, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				EnableDatagrams:         true,
+				DatagramSendQueueLen:    16,
+				DisablePathMTUDiscovery: true,
+				// limit the congestion window to 10 packets
+				Congestion: congestion.CongestionOptions{
+					InitialCongestionWindow: 10 * 1252,
+					MaxCongestionWindow:     10 * 1252,
+				},
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		statsChan := make(chan quic.ConnectionStats, 1)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				_, err := str.Write(data)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+			}()
+			// Large datagrams don't leave enough space for a STREAM frame in the same packet.
+			datagram := make([]byte, 1150)
+			for {
+				select {
+				case <-done:
+					statsChan <- sess.ConnectionStats()
+					return
+				default:
+				}
+				if err := sess.SendDatagram(datagram); err != nil && err != quic.ErrDatagramQueueOverflow {
+					Fail(fmt.Sprintf("sending the datagram failed: %s", err))
+				}
+				time.Sleep(100 * time.Microsecond)
+			}
+		}()
+
+		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr:  fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			DelayPacket: func(quicproxy.Direction, []byte) time.Duration { return 5 * time.Millisecond },
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{EnableDatagrams: true}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		var numDatagrams int32
+		go func() {
+			for {
+				if _, err := sess.ReceiveDatagram(context.Background()); err != nil {
+					return
+				}
+				atomic.AddInt32(&numDatagrams, 1)
+			}
+		}()
+		str, err := sess.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		dataChan := make(chan []byte, 1)
+		go func() {
+			defer GinkgoRecover()
+			b, err := io.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			dataChan <- b
+		}()
+		Eventually(dataChan, 10*time.Second).Should(Receive(Equal(data)))
+		var stats quic.ConnectionStats
+		Eventually(statsChan).Should(Receive(&stats))
+		Expect(atomic.LoadInt32(&numDatagrams)).ToNot(BeZero())
+		// the datagrams were sent faster than the congestion controller allowed
+		Expect(stats.DatagramsDropped).ToNot(BeZero())
+		Expect(stats.DatagramSendQueueLen).To(BeNumerically("<=", 16))
+	})
 })
@@ -0,0 +1,129 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Flow control window auto-tuning", func() {
+	const (
+		rtt           = 80 * time.Millisecond
+		initialWindow = 64 << 10
+	)
+	data := GeneratePRData(2 << 20)
+
+	// download downloads data over a link with a high bandwidth-delay product,
+	// and returns the time it took.
+	download := func(clientConf *quic.Config) time.Duration {
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		go func() {
+			defer GinkgoRecover()
+			sess, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr:  fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			DelayPacket: func(quicproxy.Direction, []byte) time.Duration { return rtt / 2 },
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+			getTLSClientConfig(),
+			getQuicConfig(clientConf),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		start := time.Now()
+		str, err := sess.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		received, err := io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(received).To(Equal(data))
+		return time.Since(start)
+	}
+
+	It("downloads faster when the receive windows are auto-tuned", func() {
+		fixed := download(&quic.Config{
+			InitialStreamReceiveWindow:     initialWindow,
+			MaxStreamReceiveWindow:         initialWindow,
+			InitialConnectionReceiveWindow: initialWindow * 3 / 2,
+			MaxConnectionReceiveWindow:     initialWindow * 3 / 2,
+		})
+		autoTuned := download(&quic.Config{
+			InitialStreamReceiveWindow:     initialWindow,
+			InitialConnectionReceiveWindow: initialWindow * 3 / 2,
+		})
+		fmt.Fprintf(GinkgoWriter, "Download took %s with fixed windows, and %s with auto-tuned windows.\n", fixed, autoTuned)
+		// With fixed windows, the throughput is limited to one window per RTT.
+		Expect(fixed).To(BeNumerically(">=", time.Duration(len(data)/initialWindow)*rtt))
+		Expect(autoTuned).To(BeNumerically("<", fixed/2))
+	})
+})
+
+var _ = Describe("Blocked frames", func() {
+	It("counts the DATA_BLOCKED and STREAM_DATA_BLOCKED frames sent", func() {
+		const window = 16 << 10
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(&quic.Config{
+			InitialStreamReceiveWindow:     window,
+			MaxStreamReceiveWindow:         window,
+			InitialConnectionReceiveWindow: window,
+			MaxConnectionReceiveWindow:     window,
+		}))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		serverSess := make(chan quic.Session, 1)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			serverSess <- sess
+		}()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		stats := sess.ConnectionStats()
+		Expect(stats.DataBlockedFramesSent).To(BeZero())
+		Expect(stats.StreamDataBlockedFramesSent).To(BeZero())
+		Expect(stats.StreamsBlockedFramesSent).To(BeZero())
+
+		// The server never reads from the stream, so the client is blocked on flow control.
+		str, err := sess.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		go func() {
+			defer GinkgoRecover()
+			str.Write(make([]byte, 2*window))
+		}()
+		Eventually(func() uint64 { return sess.ConnectionStats().DataBlockedFramesSent }).ShouldNot(BeZero())
+		Eventually(func() uint64 { return sess.ConnectionStats().StreamDataBlockedFramesSent }).ShouldNot(BeZero())
+		Expect(sess.ConnectionStats().StreamsBlockedFramesSent).To(BeZero())
+		serverStats := (<-serverSess).ConnectionStats()
+		Expect(serverStats.DataBlockedFramesSent).To(BeZero())
+		Expect(serverStats.StreamDataBlockedFramesSent).To(BeZero())
+		Expect(serverStats.StreamsBlockedFramesSent).To(BeZero())
+	})
+})
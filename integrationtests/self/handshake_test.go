@@ -1,12 +1,14 @@
 package self_test
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"net"
+	"strings"
 	"time"
 
 	"github.com/lucas-clemente/quic-go"
@@ -138,6 +140,7 @@ var _ = Describe("Handshake tests", func() {
 				)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(sess.(versioner).GetVersion()).To(Equal(expectedVersion))
+				Expect(connectionState(sess).Version).To(Equal(expectedVersion))
 				Expect(sess.CloseWithError(0, "")).To(Succeed())
 				Expect(clientTracer.chosen).To(Equal(expectedVersion))
 				Expect(clientTracer.receivedVersionNegotiation).To(BeFalse())
@@ -178,6 +181,73 @@ var _ = Describe("Handshake tests", func() {
 				Expect(serverTracer.serverVersions).To(Equal(serverConfig.Versions))
 				Expect(serverTracer.clientVersions).To(BeEmpty())
 			})
+
+			It("negotiates QUIC v2 when the server doesn't support QUIC v1", func() {
+				serverConfig.Versions = []protocol.VersionNumber{protocol.Version2}
+				runServer(getTLSConfig())
+				defer server.Close()
+				clientTracer := &versionNegotiationTracer{}
+				sess, err := quic.DialAddr(
+					fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+					getTLSClientConfig(),
+					getQuicConfig(&quic.Config{
+						Versions: []protocol.VersionNumber{protocol.Version1, protocol.Version2},
+						Tracer:   newTracer(func() logging.ConnectionTracer { return clientTracer }),
+					}),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(sess.(versioner).GetVersion()).To(Equal(protocol.Version2))
+				Expect(sess.CloseWithError(0, "")).To(Succeed())
+				Expect(clientTracer.receivedVersionNegotiation).To(BeTrue())
+				Expect(clientTracer.chosen).To(Equal(protocol.Version2))
+			})
+
+			It("switches to a compatible version without Version Negotiation", func() {
+				serverConfig.Versions = []protocol.VersionNumber{protocol.Version2, protocol.Version1}
+				serverTracer := &versionNegotiationTracer{}
+				serverConfig.Tracer = newTracer(func() logging.ConnectionTracer { return serverTracer })
+				serverSessChan := make(chan quic.Session, 1)
+				var err error
+				server, err = quic.ListenAddr("localhost:0", getTLSConfig(), serverConfig)
+				Expect(err).ToNot(HaveOccurred())
+				go func() {
+					defer GinkgoRecover()
+					defer close(acceptStopped)
+					sess, err := server.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					serverSessChan <- sess
+				}()
+				clientTracer := &versionNegotiationTracer{}
+				sess, err := quic.DialAddr(
+					fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+					getTLSClientConfig(),
+					getQuicConfig(&quic.Config{
+						Versions: []protocol.VersionNumber{protocol.Version1, protocol.Version2},
+						Tracer:   newTracer(func() logging.ConnectionTracer { return clientTracer }),
+					}),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(sess.(versioner).GetVersion()).To(Equal(protocol.Version2))
+				Expect(connectionState(sess).Version).To(Equal(protocol.Version2))
+				var serverSess quic.Session
+				Eventually(serverSessChan).Should(Receive(&serverSess))
+				Expect(serverSess.(versioner).GetVersion()).To(Equal(protocol.Version2))
+				// make sure the connection works
+				str, err := sess.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = str.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+				serverStr, err := serverSess.AcceptUniStream(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				data, err := io.ReadAll(serverStr)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte("foobar")))
+				Expect(sess.CloseWithError(0, "")).To(Succeed())
+				Expect(clientTracer.receivedVersionNegotiation).To(BeFalse())
+				Expect(clientTracer.chosen).To(Equal(protocol.Version2))
+				Expect(serverTracer.chosen).To(Equal(protocol.Version2))
+			})
 		})
 	}
 
@@ -219,7 +289,7 @@ var _ = Describe("Handshake tests", func() {
 				data, err := io.ReadAll(str)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(data).To(Equal(PRData))
-				Expect(sess.ConnectionState().TLS.CipherSuite).To(Equal(suiteID))
+				Expect(connectionState(sess).TLS.CipherSuite).To(Equal(suiteID))
 				Expect(sess.CloseWithError(0, "")).To(Succeed())
 			})
 		}
@@ -440,7 +510,7 @@ var _ = Describe("Handshake tests", func() {
 				defer GinkgoRecover()
 				sess, err := ln.Accept(context.Background())
 				Expect(err).ToNot(HaveOccurred())
-				cs := sess.ConnectionState()
+				cs := connectionState(sess)
 				Expect(cs.TLS.NegotiatedProtocol).To(Equal(alpn))
 				close(done)
 			}()
@@ -452,7 +522,7 @@ var _ = Describe("Handshake tests", func() {
 			)
 			Expect(err).ToNot(HaveOccurred())
 			defer sess.CloseWithError(0, "")
-			cs := sess.ConnectionState()
+			cs := connectionState(sess)
 			Expect(cs.TLS.NegotiatedProtocol).To(Equal(alpn))
 			Eventually(done).Should(BeClosed())
 			Expect(ln.Close()).To(Succeed())
@@ -476,6 +546,104 @@ var _ = Describe("Handshake tests", func() {
 		})
 	})
 
+	Context("key logging", func() {
+		It("logs the traffic secrets in the NSS key log format", func() {
+			serverKeyLog := &syncedBuffer{Buffer: &bytes.Buffer{}}
+			tlsConf := getTLSConfig()
+			tlsConf.KeyLogWriter = serverKeyLog
+			ln, err := quic.ListenAddr("localhost:0", tlsConf, serverConfig)
+			Expect(err).ToNot(HaveOccurred())
+			defer ln.Close()
+
+			accepted := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				_, err := ln.Accept(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				close(accepted)
+			}()
+
+			clientKeyLog := &bytes.Buffer{}
+			clientConf := getTLSClientConfig()
+			clientConf.KeyLogWriter = clientKeyLog
+			sess, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+				clientConf,
+				getQuicConfig(nil),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			defer sess.CloseWithError(0, "")
+			Eventually(accepted).Should(BeClosed())
+
+			lines := strings.Split(strings.TrimSpace(clientKeyLog.String()), "\n")
+			Expect(lines).To(HaveLen(4))
+			var labels []string
+			for _, l := range lines {
+				fields := strings.Fields(l)
+				Expect(fields).To(HaveLen(3))
+				labels = append(labels, fields[0])
+				Expect(fields[1]).To(Equal(strings.Fields(lines[0])[1])) // the client random
+			}
+			Expect(labels).To(ConsistOf(
+				"CLIENT_HANDSHAKE_TRAFFIC_SECRET",
+				"SERVER_HANDSHAKE_TRAFFIC_SECRET",
+				"CLIENT_TRAFFIC_SECRET_0",
+				"SERVER_TRAFFIC_SECRET_0",
+			))
+			// client and server log the same secrets
+			Expect(strings.Split(strings.TrimSpace(string(serverKeyLog.Bytes())), "\n")).To(ConsistOf(lines))
+		})
+	})
+
+	Context("handshake confirmation", func() {
+		It("confirms the handshake before the first application data is received", func() {
+			ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), serverConfig)
+			Expect(err).ToNot(HaveOccurred())
+			defer ln.Close()
+
+			go func() {
+				defer GinkgoRecover()
+				sess, err := ln.Accept(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				// the server confirms the handshake as soon as it completes
+				Expect(sess.HandshakeConfirmed().Done()).To(BeClosed())
+				str, err := sess.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = str.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+			}()
+
+			sess, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+				getTLSClientConfig(),
+				getQuicConfig(nil),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			defer sess.CloseWithError(0, "")
+
+			// wait for the handshake confirmation from multiple goroutines
+			const num = 5
+			confirmed := make(chan struct{}, num)
+			for i := 0; i < num; i++ {
+				go func() {
+					<-sess.HandshakeConfirmed().Done()
+					confirmed <- struct{}{}
+				}()
+			}
+			str, err := sess.AcceptUniStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Read(make([]byte, 1))
+			Expect(err).ToNot(HaveOccurred())
+			// The server sends the HANDSHAKE_DONE frame before any stream data,
+			// so the handshake is confirmed by the time the first application data is received.
+			Expect(sess.HandshakeConfirmed().Done()).To(BeClosed())
+			for i := 0; i < num; i++ {
+				Eventually(confirmed).Should(Receive())
+			}
+		})
+	})
+
 	Context("using tokens", func() {
 		It("uses tokens provided in NEW_TOKEN frames", func() {
 			tokenChan := make(chan *quic.Token, 100)
@@ -11,13 +11,17 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/lucas-clemente/quic-go"
 	"github.com/lucas-clemente/quic-go/http3"
+	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/testdata"
+	"github.com/lucas-clemente/quic-go/internal/wire"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -313,6 +317,467 @@ var _ = Describe("HTTP tests", func() {
 				Expect(req.Body.Close()).To(Succeed())
 				Eventually(done).Should(BeClosed())
 			})
+
+			It("sends trailers", func() {
+				mux.HandleFunc("/trailers", func(w http.ResponseWriter, r *http.Request) {
+					defer GinkgoRecover()
+					w.Header().Set("Trailer", "Foo")
+					io.WriteString(w, "foobar")
+					w.(http.Flusher).Flush()
+					w.Header().Set("Foo", "1")
+					w.Header().Set(http.TrailerPrefix+"Bar", "2")
+				})
+
+				resp, err := client.Get("https://localhost:" + port + "/trailers")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(200))
+				Expect(resp.Trailer).To(Equal(http.Header{"Foo": nil}))
+				body, err := io.ReadAll(gbytes.TimeoutReader(resp.Body, 3*time.Second))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(body)).To(Equal("foobar"))
+				Expect(resp.Trailer).To(Equal(http.Header{"Foo": []string{"1"}, "Bar": []string{"2"}}))
+			})
+
+			It("tunnels CONNECT requests to a TCP server", func() {
+				// The target echoes the data, and closes its side of the connection once the client closed its side.
+				ln, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+				go func() {
+					defer GinkgoRecover()
+					conn, err := ln.AcceptTCP()
+					if err != nil {
+						return
+					}
+					defer conn.Close()
+					_, err = io.Copy(conn, conn)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(conn.CloseWrite()).To(Succeed())
+				}()
+
+				proxy := &http3.Server{
+					Server: &http.Server{
+						TLSConfig: testdata.GetTLSConfig(),
+						Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+							defer GinkgoRecover()
+							Expect(r.Method).To(Equal(http.MethodConnect))
+							upstream, err := net.Dial("tcp", r.Host)
+							Expect(err).ToNot(HaveOccurred())
+							defer upstream.Close()
+							tunnel, err := w.(http3.Tunneler).Tunnel()
+							Expect(err).ToNot(HaveOccurred())
+							defer tunnel.Close()
+							done := make(chan struct{})
+							go func() {
+								defer GinkgoRecover()
+								defer close(done)
+								_, err := io.Copy(upstream, tunnel)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(upstream.(*net.TCPConn).CloseWrite()).To(Succeed())
+							}()
+							_, err = io.Copy(tunnel, upstream)
+							Expect(err).ToNot(HaveOccurred())
+							Expect(tunnel.CloseWrite()).To(Succeed())
+							<-done
+						}),
+					},
+					QuicConfig: getQuicConfig(&quic.Config{Versions: versions}),
+				}
+				addr, err := net.ResolveUDPAddr("udp", "localhost:0")
+				Expect(err).ToNot(HaveOccurred())
+				conn, err := net.ListenUDP("udp", addr)
+				Expect(err).ToNot(HaveOccurred())
+				defer conn.Close()
+				go proxy.Serve(conn)
+				defer proxy.Close()
+
+				pr, pw := io.Pipe()
+				req := &http.Request{
+					Method: http.MethodConnect,
+					URL:    &url.URL{Scheme: "https", Host: "localhost:" + strconv.Itoa(conn.LocalAddr().(*net.UDPAddr).Port)},
+					Host:   ln.Addr().String(),
+					Header: http.Header{},
+					Body:   pr,
+				}
+				resp, err := client.Do(req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(200))
+				_, err = pw.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				b := make([]byte, 6)
+				_, err = io.ReadFull(gbytes.TimeoutReader(resp.Body, 3*time.Second), b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(b)).To(Equal("foobar"))
+				// closing our side of the tunnel makes the target close its side
+				_, err = pw.Write([]byte("lorem ipsum"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(pw.Close()).To(Succeed())
+				body, err := io.ReadAll(gbytes.TimeoutReader(resp.Body, 3*time.Second))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(body)).To(Equal("lorem ipsum"))
+			})
+
+			It("exchanges additional settings", func() {
+				const settingID = 0x1f00d // a vendor-specific setting
+				serverReceived := make(chan map[uint64]uint64, 1)
+				s := &http3.Server{
+					Server: &http.Server{
+						TLSConfig: testdata.GetTLSConfig(),
+						Handler:   mux,
+					},
+					QuicConfig:         getQuicConfig(&quic.Config{Versions: versions}),
+					AdditionalSettings: map[uint64]uint64{settingID: 1},
+					OnSettingsReceived: func(_ quic.Session, settings map[uint64]uint64) { serverReceived <- settings },
+				}
+				addr, err := net.ResolveUDPAddr("udp", "localhost:0")
+				Expect(err).ToNot(HaveOccurred())
+				conn, err := net.ListenUDP("udp", addr)
+				Expect(err).ToNot(HaveOccurred())
+				defer conn.Close()
+				go s.Serve(conn)
+				defer s.Close()
+
+				clientReceived := make(chan map[uint64]uint64, 1)
+				rt := client.Transport.(*http3.RoundTripper)
+				rt.AdditionalSettings = map[uint64]uint64{settingID: 2}
+				rt.OnSettingsReceived = func(_ quic.Session, settings map[uint64]uint64) { clientReceived <- settings }
+				resp, err := client.Get(fmt.Sprintf("https://localhost:%d/hello", conn.LocalAddr().(*net.UDPAddr).Port))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(200))
+				Eventually(serverReceived).Should(Receive(HaveKeyWithValue(uint64(settingID), uint64(2))))
+				Eventually(clientReceived).Should(Receive(HaveKeyWithValue(uint64(settingID), uint64(1))))
+			})
+
+			It("switches to HTTP/3 once the origin advertises it in the Alt-Svc header field", func() {
+				mux.HandleFunc("/proto", func(w http.ResponseWriter, r *http.Request) {
+					defer GinkgoRecover()
+					io.WriteString(w, r.Proto)
+				})
+				server.Server.Addr = "localhost:" + port
+				ln, err := tls.Listen("tcp", "localhost:0", testdata.GetTLSConfig())
+				Expect(err).ToNot(HaveOccurred())
+				tcpServer := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					defer GinkgoRecover()
+					Expect(server.SetQuicHeaders(w.Header())).To(Succeed())
+					io.WriteString(w, r.Proto)
+				})}
+				go tcpServer.Serve(ln)
+				defer tcpServer.Close()
+
+				rt := client.Transport.(*http3.RoundTripper)
+				rt.Fallback = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: testdata.GetRootCA()}}
+				u := fmt.Sprintf("https://localhost:%d/proto", ln.Addr().(*net.TCPAddr).Port)
+				for _, proto := range []string{"HTTP/1.1", "HTTP/3", "HTTP/3"} {
+					resp, err := client.Get(u)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(resp.StatusCode).To(Equal(200))
+					body, err := io.ReadAll(gbytes.TimeoutReader(resp.Body, 3*time.Second))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(string(body)).To(Equal(proto))
+				}
+			})
+
+			It("pools connections", func() {
+				started := make(chan string, 3)
+				unblock := make(chan struct{}, 3)
+				mux.HandleFunc("/pooled", func(w http.ResponseWriter, r *http.Request) {
+					defer GinkgoRecover()
+					started <- r.RemoteAddr
+					<-unblock
+				})
+				// The server only allows a single request stream per connection at a time.
+				poolServer := &http3.Server{
+					Server:     &http.Server{Handler: mux, TLSConfig: testdata.GetTLSConfig()},
+					QuicConfig: getQuicConfig(&quic.Config{Versions: versions, MaxIncomingStreams: 1}),
+				}
+				addr, err := net.ResolveUDPAddr("udp", "localhost:0")
+				Expect(err).ToNot(HaveOccurred())
+				conn, err := net.ListenUDP("udp", addr)
+				Expect(err).ToNot(HaveOccurred())
+				defer conn.Close()
+				go poolServer.Serve(conn)
+				defer poolServer.Close()
+				poolURL := "https://localhost:" + strconv.Itoa(conn.LocalAddr().(*net.UDPAddr).Port) + "/pooled"
+
+				rt := client.Transport.(*http3.RoundTripper)
+				rt.MaxConnsPerHost = 2
+				get := func() <-chan struct{} {
+					done := make(chan struct{})
+					go func() {
+						defer GinkgoRecover()
+						defer close(done)
+						resp, err := client.Get(poolURL)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(resp.StatusCode).To(Equal(200))
+						_, err = io.ReadAll(resp.Body)
+						Expect(err).ToNot(HaveOccurred())
+					}()
+					return done
+				}
+				done1 := get()
+				var addr1 string
+				Eventually(started).Should(Receive(&addr1))
+				// the stream limit is reached on the first connection, so a second connection is dialed
+				done2 := get()
+				var addr2 string
+				Eventually(started).Should(Receive(&addr2))
+				Expect(addr2).ToNot(Equal(addr1))
+				// MaxConnsPerHost is reached, so the third request waits for a stream on the first connection
+				done3 := get()
+				Consistently(started, 200*time.Millisecond).ShouldNot(Receive())
+				for i := 0; i < 3; i++ {
+					unblock <- struct{}{}
+				}
+				Eventually(started).Should(Receive(Equal(addr1)))
+				for _, done := range []<-chan struct{}{done1, done2, done3} {
+					Eventually(done).Should(BeClosed())
+				}
+
+				// all connections are idle now, so they're closed
+				rt.CloseIdleConnections()
+				done := get()
+				var addr3 string
+				Eventually(started).Should(Receive(&addr3))
+				Expect(addr3).ToNot(Equal(addr1))
+				Expect(addr3).ToNot(Equal(addr2))
+				unblock <- struct{}{}
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("completes in-flight requests after a GOAWAY, and retries new requests on a new connection", func() {
+				started := make(chan struct{})
+				unblock := make(chan struct{})
+				mux.HandleFunc("/blocking", func(w http.ResponseWriter, r *http.Request) {
+					defer GinkgoRecover()
+					close(started)
+					<-unblock
+					io.WriteString(w, "done")
+				})
+
+				// The first connection is dialed to a server that is shut down during the test.
+				// All other connections are dialed to the server used by the other tests.
+				goAwayServer := &http3.Server{
+					Server:     &http.Server{Handler: mux, TLSConfig: testdata.GetTLSConfig()},
+					QuicConfig: getQuicConfig(&quic.Config{Versions: versions}),
+				}
+				addr, err := net.ResolveUDPAddr("udp", "localhost:0")
+				Expect(err).ToNot(HaveOccurred())
+				conn, err := net.ListenUDP("udp", addr)
+				Expect(err).ToNot(HaveOccurred())
+				defer conn.Close()
+				serverDone := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(serverDone)
+					goAwayServer.Serve(conn)
+				}()
+
+				var dials int32
+				rt := &http3.RoundTripper{
+					TLSClientConfig:    &tls.Config{RootCAs: testdata.GetRootCA()},
+					DisableCompression: true,
+					QuicConfig: getQuicConfig(&quic.Config{
+						Versions:       []protocol.VersionNumber{version},
+						MaxIdleTimeout: 10 * time.Second,
+					}),
+					Dial: func(_, _ string, tlsConf *tls.Config, conf *quic.Config) (quic.EarlySession, error) {
+						addr := "localhost:" + port
+						if atomic.AddInt32(&dials, 1) == 1 {
+							addr = fmt.Sprintf("localhost:%d", conn.LocalAddr().(*net.UDPAddr).Port)
+						}
+						return quic.DialAddrEarly(addr, tlsConf, conf)
+					},
+				}
+				defer rt.Close()
+				client := &http.Client{Transport: rt}
+
+				rspChan := make(chan *http.Response, 1)
+				go func() {
+					defer GinkgoRecover()
+					rsp, err := client.Get("https://localhost:" + port + "/blocking")
+					Expect(err).ToNot(HaveOccurred())
+					rspChan <- rsp
+				}()
+				Eventually(started).Should(BeClosed())
+
+				shutdownErr := make(chan error, 1)
+				go func() { shutdownErr <- goAwayServer.Shutdown(context.Background()) }()
+
+				// New requests are either rejected by the server, or not sent at all after the client received the GOAWAY.
+				// In both cases, they are retried on a new connection.
+				rsp, err := client.Get("https://localhost:" + port + "/hello")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(200))
+				body, err := io.ReadAll(gbytes.TimeoutReader(rsp.Body, 3*time.Second))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(body)).To(Equal("Hello, World!\n"))
+				Expect(atomic.LoadInt32(&dials)).To(BeEquivalentTo(2))
+
+				// The in-flight request is still served on the old connection.
+				Consistently(shutdownErr).ShouldNot(Receive())
+				close(unblock)
+				Eventually(rspChan).Should(Receive(&rsp))
+				Expect(rsp.StatusCode).To(Equal(200))
+				body, err = io.ReadAll(gbytes.TimeoutReader(rsp.Body, 3*time.Second))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(body)).To(Equal("done"))
+				Eventually(shutdownErr).Should(Receive(BeNil()))
+				Eventually(serverDone).Should(BeClosed())
+			})
+
+			It("sends GET requests using 0-RTT when resuming a session", func() {
+				var num0RTTPackets uint32 // to be used as an atomic
+				proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+					RemoteAddr: "localhost:" + port,
+					DelayPacket: func(_ quicproxy.Direction, data []byte) time.Duration {
+						for len(data) > 0 {
+							hdr, _, rest, err := wire.ParsePacket(data, 0)
+							if err != nil {
+								break
+							}
+							if hdr.Type == protocol.PacketType0RTT {
+								atomic.AddUint32(&num0RTTPackets, 1)
+								break
+							}
+							data = rest
+						}
+						return 0
+					},
+				})
+				Expect(err).ToNot(HaveOccurred())
+				defer proxy.Close()
+
+				url := fmt.Sprintf("https://localhost:%d/hello", proxy.LocalPort())
+				get := func() {
+					resp, err := client.Get(url)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(resp.StatusCode).To(Equal(200))
+					body, err := io.ReadAll(gbytes.TimeoutReader(resp.Body, 3*time.Second))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(string(body)).To(Equal("Hello, World!\n"))
+				}
+				get()
+				Expect(atomic.LoadUint32(&num0RTTPackets)).To(BeZero())
+				// close the connection, the next request will resume the session
+				Expect(client.Transport.(*http3.RoundTripper).Close()).To(Succeed())
+				get()
+				Expect(atomic.LoadUint32(&num0RTTPackets)).ToNot(BeZero())
+			})
+
+			It("resends GET requests when the server rejects 0-RTT", func() {
+				var reject0RTT int32 // to be used as an atomic
+				rejectingServer := &http3.Server{
+					Server: &http.Server{Handler: mux, TLSConfig: testdata.GetTLSConfig()},
+					QuicConfig: getQuicConfig(&quic.Config{
+						Versions:  versions,
+						Allow0RTT: func(net.Addr) bool { return atomic.LoadInt32(&reject0RTT) == 0 },
+					}),
+				}
+				addr, err := net.ResolveUDPAddr("udp", "localhost:0")
+				Expect(err).ToNot(HaveOccurred())
+				conn, err := net.ListenUDP("udp", addr)
+				Expect(err).ToNot(HaveOccurred())
+				defer conn.Close()
+				serverDone := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(serverDone)
+					rejectingServer.Serve(conn)
+				}()
+				defer func() {
+					Expect(rejectingServer.Close()).To(Succeed())
+					Eventually(serverDone).Should(BeClosed())
+				}()
+
+				url := fmt.Sprintf("https://localhost:%d/hello", conn.LocalAddr().(*net.UDPAddr).Port)
+				get := func() {
+					resp, err := client.Get(url)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(resp.StatusCode).To(Equal(200))
+					body, err := io.ReadAll(gbytes.TimeoutReader(resp.Body, 3*time.Second))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(string(body)).To(Equal("Hello, World!\n"))
+				}
+				get()
+				Expect(client.Transport.(*http3.RoundTripper).Close()).To(Succeed())
+				atomic.StoreInt32(&reject0RTT, 1)
+				get()
+				// the control stream was opened again after the rejection, so the connection is still usable
+				get()
+			})
+
+			It("sends and receives HTTP datagrams", func() {
+				dgServer := &http3.Server{
+					Server: &http.Server{
+						Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+							defer GinkgoRecover()
+							w.WriteHeader(200)
+							w.(http.Flusher).Flush()
+							dg := w.(http3.Datagrammer)
+							for {
+								data, err := dg.ReceiveDatagram(r.Context())
+								if err != nil {
+									return
+								}
+								Expect(dg.SendDatagram(append([]byte("echo: "), data...))).To(Succeed())
+							}
+						}),
+						TLSConfig: testdata.GetTLSConfig(),
+					},
+					QuicConfig:      getQuicConfig(&quic.Config{Versions: versions}),
+					EnableDatagrams: true,
+				}
+				addr, err := net.ResolveUDPAddr("udp", "localhost:0")
+				Expect(err).ToNot(HaveOccurred())
+				conn, err := net.ListenUDP("udp", addr)
+				Expect(err).ToNot(HaveOccurred())
+				defer conn.Close()
+				serverDone := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(serverDone)
+					dgServer.Serve(conn)
+				}()
+				defer func() {
+					Expect(dgServer.Close()).To(Succeed())
+					Eventually(serverDone).Should(BeClosed())
+				}()
+
+				rt := &http3.RoundTripper{
+					TLSClientConfig:    &tls.Config{RootCAs: testdata.GetRootCA()},
+					DisableCompression: true,
+					EnableDatagrams:    true,
+					QuicConfig: getQuicConfig(&quic.Config{
+						Versions:       []protocol.VersionNumber{version},
+						MaxIdleTimeout: 10 * time.Second,
+					}),
+				}
+				defer rt.Close()
+				r, w := io.Pipe()
+				defer w.Close()
+				req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("https://localhost:%d/datagrams", conn.LocalAddr().(*net.UDPAddr).Port), r)
+				Expect(err).ToNot(HaveOccurred())
+				rsp, err := rt.RoundTrip(req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(200))
+				dg, ok := rsp.Body.(http3.Datagrammer)
+				Expect(ok).To(BeTrue())
+
+				// Datagrams might be lost, so keep sending until we receive the echo.
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				for {
+					Expect(dg.SendDatagram([]byte("foobar"))).To(Succeed())
+					rctx, rcancel := context.WithTimeout(ctx, 100*time.Millisecond)
+					data, err := dg.ReceiveDatagram(rctx)
+					rcancel()
+					if err == nil {
+						Expect(data).To(Equal([]byte("echo: foobar")))
+						break
+					}
+					Expect(ctx.Err()).ToNot(HaveOccurred())
+				}
+			})
 		})
 	}
 })
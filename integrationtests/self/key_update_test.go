@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"sync"
 
 	quic "github.com/lucas-clemente/quic-go"
 	"github.com/lucas-clemente/quic-go/internal/handshake"
@@ -56,6 +57,28 @@ func (t *keyUpdateConnTracer) ReceivedPacket(hdr *logging.ExtendedHeader, size l
 	receivedHeaders = append(receivedHeaders, hdr)
 }
 
+type keyPhaseConnTracer struct {
+	connTracer
+
+	mutex        sync.Mutex
+	sentKeyPhase []protocol.KeyPhaseBit
+}
+
+func (t *keyPhaseConnTracer) SentPacket(hdr *logging.ExtendedHeader, _ logging.ByteCount, _ *logging.AckFrame, _ []logging.Frame) {
+	if hdr.IsLongHeader {
+		return
+	}
+	t.mutex.Lock()
+	t.sentKeyPhase = append(t.sentKeyPhase, hdr.KeyPhase)
+	t.mutex.Unlock()
+}
+
+func (t *keyPhaseConnTracer) getSentKeyPhases() []protocol.KeyPhaseBit {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return append([]protocol.KeyPhaseBit{}, t.sentKeyPhase...)
+}
+
 var _ = Describe("Key Update tests", func() {
 	var server quic.Listener
 
@@ -100,4 +123,31 @@ var _ = Describe("Key Update tests", func() {
 		Expect(keyPhasesReceived).To(BeNumerically(">", 10))
 		Expect(keyPhasesReceived).To(BeNumerically("~", keyPhasesSent, 2))
 	})
+
+	It("updates the keys when requested", func() {
+		runServer()
+		tracer := &keyPhaseConnTracer{}
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{Tracer: newTracer(func() logging.ConnectionTracer { return tracer })}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		// by the time the file has been downloaded, the handshake is confirmed
+		str, err := sess.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRDataLong))
+		Expect(tracer.getSentKeyPhases()).ToNot(ContainElement(protocol.KeyPhaseOne))
+
+		numSent := len(tracer.getSentKeyPhases())
+		Expect(sess.InitiateKeyUpdate()).To(Succeed())
+		Eventually(func() int { return len(tracer.getSentKeyPhases()) }).Should(BeNumerically(">", numSent))
+		// all packets sent after the key update use the new key phase
+		for _, kp := range tracer.getSentKeyPhases()[numSent:] {
+			Expect(kp).To(Equal(protocol.KeyPhaseOne))
+		}
+	})
 })
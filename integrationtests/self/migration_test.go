@@ -0,0 +1,121 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+
+	quic "github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Connection Migration", func() {
+	var server quic.Listener
+
+	runServer := func(conf *quic.Config) <-chan quic.Session {
+		var err error
+		server, err = quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(conf))
+		Expect(err).ToNot(HaveOccurred())
+		sessChan := make(chan quic.Session, 1)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			sessChan <- sess
+			for {
+				str, err := sess.AcceptStream(context.Background())
+				if err != nil {
+					return
+				}
+				go func() {
+					defer GinkgoRecover()
+					defer str.Close()
+					_, err := io.Copy(str, str)
+					Expect(err).ToNot(HaveOccurred())
+				}()
+			}
+		}()
+		return sessChan
+	}
+
+	AfterEach(func() {
+		Expect(server.Close()).To(Succeed())
+	})
+
+	listenUDP := func() *net.UDPConn {
+		addr, err := net.ResolveUDPAddr("udp", "localhost:0")
+		Expect(err).ToNot(HaveOccurred())
+		conn, err := net.ListenUDP("udp", addr)
+		Expect(err).ToNot(HaveOccurred())
+		return conn
+	}
+
+	dial := func(conn net.PacketConn) quic.Session {
+		sess, err := quic.Dial(
+			conn,
+			server.Addr(),
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		return sess
+	}
+
+	echo := func(sess quic.Session) {
+		str, err := sess.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write(PRData)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		data, err := io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRData))
+	}
+
+	It("migrates to a new socket", func() {
+		sessChan := runServer(&quic.Config{EnableActiveMigration: true})
+		conn1 := listenUDP()
+		defer conn1.Close()
+		conn2 := listenUDP()
+		defer conn2.Close()
+
+		sess := dial(conn1)
+		defer sess.CloseWithError(0, "")
+		var serverSess quic.Session
+		Eventually(sessChan).Should(Receive(&serverSess))
+		echo(sess)
+		// the handshake is confirmed when the client receives the HANDSHAKE_DONE frame
+		Eventually(func() error { return sess.MigrateTo(conn2) }).Should(Succeed())
+		Expect(sess.LocalAddr()).To(Equal(conn2.LocalAddr()))
+		echo(sess)
+		Eventually(func() net.Addr { return serverSess.RemoteAddr() }).Should(Equal(conn2.LocalAddr()))
+
+		// the session doesn't use the old socket any more
+		Expect(conn1.Close()).To(Succeed())
+		echo(sess)
+		Consistently(sess.Context().Done()).ShouldNot(BeClosed())
+	})
+
+	It("doesn't migrate if the server didn't enable active migration", func() {
+		runServer(nil)
+		conn1 := listenUDP()
+		defer conn1.Close()
+		conn2 := listenUDP()
+		defer conn2.Close()
+
+		sess := dial(conn1)
+		defer sess.CloseWithError(0, "")
+		echo(sess)
+		Eventually(func() error {
+			return sess.MigrateTo(conn2)
+		}).Should(MatchError("the peer disabled active connection migration"))
+		Expect(sess.LocalAddr()).To(Equal(conn1.LocalAddr()))
+		echo(sess)
+	})
+})
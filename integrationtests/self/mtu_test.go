@@ -0,0 +1,113 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// mtuTracer records the largest datagram size found by Path MTU Discovery.
+type mtuTracer struct {
+	connTracer
+
+	mutex sync.Mutex
+	mtu   logging.ByteCount
+}
+
+func (t *mtuTracer) UpdatedMTU(mtu logging.ByteCount) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if mtu > t.mtu {
+		t.mtu = mtu
+	}
+}
+
+func (t *mtuTracer) getMTU() logging.ByteCount {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.mtu
+}
+
+var _ = Describe("Path MTU Discovery", func() {
+	It("increases the datagram size on paths that support jumbo frames", func() {
+		// the MTU of jumbo ethernet frames, minus the IPv4 and UDP headers
+		const maxDatagramSize = 9000 - 28
+
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(&quic.Config{
+			MaxPathMTUProbeSize: uint16(protocol.MaxLargePacketBufferSize),
+		}))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		go func() {
+			defer GinkgoRecover()
+			sess, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			io.Copy(ioutil.Discard, str) // errors when the session is closed
+		}()
+
+		var largestDatagram int64
+		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr: fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			DropPacket: func(dir quicproxy.Direction, data []byte) bool {
+				if dir != quicproxy.DirectionIncoming {
+					return false
+				}
+				if len(data) > maxDatagramSize {
+					return true
+				}
+				if l := int64(len(data)); l > atomic.LoadInt64(&largestDatagram) {
+					atomic.StoreInt64(&largestDatagram, l)
+				}
+				return false
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		tracer := &mtuTracer{}
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", proxy.LocalAddr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{
+				MaxPathMTUProbeSize: uint16(protocol.MaxLargePacketBufferSize),
+				Tracer:              newTracer(func() logging.ConnectionTracer { return tracer }),
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		str, err := sess.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			data := make([]byte, 1<<16)
+			for tracer.getMTU() < protocol.MaxLargePacketBufferSize-30 {
+				if _, err := str.Write(data); err != nil {
+					return
+				}
+			}
+		}()
+		Eventually(tracer.getMTU, scaleDuration(5*time.Second)).Should(BeNumerically(">", protocol.MaxLargePacketBufferSize-30))
+		Eventually(done).Should(BeClosed())
+		Expect(tracer.getMTU()).To(BeNumerically("<=", protocol.MaxLargePacketBufferSize))
+		// STREAM data is sent in datagrams larger than the default packet size
+		Eventually(func() int64 { return atomic.LoadInt64(&largestDatagram) }).Should(BeNumerically(">", protocol.MaxPacketBufferSize))
+	})
+})
@@ -3,6 +3,7 @@ package self_test
 import (
 	"context"
 	"fmt"
+	"io"
 	"net"
 	"time"
 
@@ -119,4 +120,74 @@ var _ = Describe("Packetization", func() {
 			BeNumerically(">", numMsg*9/10),
 		))
 	})
+
+	// In this test, the server sends a few 1-byte messages, with a pause between them.
+	// Every message is sent in a single ack-eliciting packet, which is below the threshold
+	// for acknowledging packets right away, so the client delays the ACK by the configured max ack delay.
+	It("delays ACKs by the configured max ack delay", func() {
+		const maxAckDelay = 100 * time.Millisecond
+
+		serverTracer := newPacketTracer()
+		server, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				DisablePathMTUDiscovery: true,
+				Tracer:                  newTracer(func() logging.ConnectionTracer { return serverTracer }),
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			for i := 0; i < 5; i++ {
+				_, err := str.Write([]byte{uint8(i)})
+				Expect(err).ToNot(HaveOccurred())
+				time.Sleep(2 * maxAckDelay)
+			}
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{
+				DisablePathMTUDiscovery: true,
+				MaxAckDelay:             maxAckDelay,
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := sess.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte{0, 1, 2, 3, 4}))
+		Expect(sess.CloseWithError(0, "")).To(Succeed())
+
+		var maxDelay time.Duration
+		var numAcks int
+		for _, p := range serverTracer.getRcvdPackets() {
+			if p.hdr.IsLongHeader {
+				continue
+			}
+			for _, f := range p.frames {
+				if ack, ok := f.(*logging.AckFrame); ok {
+					numAcks++
+					if ack.DelayTime > maxDelay {
+						maxDelay = ack.DelayTime
+					}
+				}
+			}
+		}
+		Expect(numAcks).ToNot(BeZero())
+		Expect(maxDelay).To(And(
+			BeNumerically(">=", maxAckDelay-time.Millisecond),
+			BeNumerically("<", maxAckDelay+scaleDuration(50*time.Millisecond)),
+		))
+	})
 })
@@ -46,6 +46,48 @@ func (c *clientSessionCache) Put(sessionKey string, cs *tls.ClientSessionState)
 	c.mutex.Unlock()
 }
 
+// serializedSessionCache is a quic.ClientSessionCache that could be persisted to disk.
+type serializedSessionCache struct {
+	mutex    sync.Mutex
+	sessions map[string][]byte
+
+	puts chan<- string
+}
+
+var _ quic.ClientSessionCache = &serializedSessionCache{}
+
+func newSerializedSessionCache(sessions map[string][]byte, puts chan<- string) *serializedSessionCache {
+	if sessions == nil {
+		sessions = make(map[string][]byte)
+	}
+	return &serializedSessionCache{sessions: sessions, puts: puts}
+}
+
+func (c *serializedSessionCache) Get(sessionKey string) ([]byte, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	session, ok := c.sessions[sessionKey]
+	return session, ok
+}
+
+func (c *serializedSessionCache) Put(sessionKey string, session []byte) {
+	c.puts <- sessionKey
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.sessions[sessionKey] = session
+}
+
+// Sessions returns a copy of all stored sessions
+func (c *serializedSessionCache) Sessions() map[string][]byte {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	sessions := make(map[string][]byte, len(c.sessions))
+	for k, v := range c.sessions {
+		sessions[k] = append([]byte{}, v...)
+	}
+	return sessions
+}
+
 var _ = Describe("TLS session resumption", func() {
 	It("uses session resumption", func() {
 		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), nil)
@@ -65,11 +107,11 @@ var _ = Describe("TLS session resumption", func() {
 		Expect(err).ToNot(HaveOccurred())
 		var sessionKey string
 		Eventually(puts).Should(Receive(&sessionKey))
-		Expect(sess.ConnectionState().TLS.DidResume).To(BeFalse())
+		Expect(connectionState(sess).TLS.DidResume).To(BeFalse())
 
 		serverSess, err := server.Accept(context.Background())
 		Expect(err).ToNot(HaveOccurred())
-		Expect(serverSess.ConnectionState().TLS.DidResume).To(BeFalse())
+		Expect(connectionState(serverSess).TLS.DidResume).To(BeFalse())
 
 		sess, err = quic.DialAddr(
 			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
@@ -78,11 +120,11 @@ var _ = Describe("TLS session resumption", func() {
 		)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(gets).To(Receive(Equal(sessionKey)))
-		Expect(sess.ConnectionState().TLS.DidResume).To(BeTrue())
+		Expect(connectionState(sess).TLS.DidResume).To(BeTrue())
 
 		serverSess, err = server.Accept(context.Background())
 		Expect(err).ToNot(HaveOccurred())
-		Expect(serverSess.ConnectionState().TLS.DidResume).To(BeTrue())
+		Expect(connectionState(serverSess).TLS.DidResume).To(BeTrue())
 	})
 
 	It("doesn't use session resumption, if the config disables it", func() {
@@ -104,11 +146,11 @@ var _ = Describe("TLS session resumption", func() {
 		)
 		Expect(err).ToNot(HaveOccurred())
 		Consistently(puts).ShouldNot(Receive())
-		Expect(sess.ConnectionState().TLS.DidResume).To(BeFalse())
+		Expect(connectionState(sess).TLS.DidResume).To(BeFalse())
 
 		serverSess, err := server.Accept(context.Background())
 		Expect(err).ToNot(HaveOccurred())
-		Expect(serverSess.ConnectionState().TLS.DidResume).To(BeFalse())
+		Expect(connectionState(serverSess).TLS.DidResume).To(BeFalse())
 
 		sess, err = quic.DialAddr(
 			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
@@ -116,10 +158,10 @@ var _ = Describe("TLS session resumption", func() {
 			nil,
 		)
 		Expect(err).ToNot(HaveOccurred())
-		Expect(sess.ConnectionState().TLS.DidResume).To(BeFalse())
+		Expect(connectionState(sess).TLS.DidResume).To(BeFalse())
 
 		serverSess, err = server.Accept(context.Background())
 		Expect(err).ToNot(HaveOccurred())
-		Expect(serverSess.ConnectionState().TLS.DidResume).To(BeFalse())
+		Expect(connectionState(serverSess).TLS.DidResume).To(BeFalse())
 	})
 })
@@ -318,6 +318,13 @@ func scaleDuration(d time.Duration) time.Duration {
 	return time.Duration(scaleFactor) * d
 }
 
+// connectionState returns the connection state of a session whose handshake has completed.
+func connectionState(sess quic.Session) quic.ConnectionState {
+	state, err := sess.ConnectionStateErr()
+	ExpectWithOffset(1, err).ToNot(HaveOccurred())
+	return state
+}
+
 type tracer struct {
 	createNewConnTracer func() logging.ConnectionTracer
 }
@@ -359,11 +366,17 @@ func (t *connTracer) DroppedPacket(logging.PacketType, logging.ByteCount, loggin
 func (t *connTracer) UpdatedMetrics(rttStats *logging.RTTStats, cwnd, bytesInFlight logging.ByteCount, packetsInFlight int) {
 }
 
+func (t *connTracer) UpdatedCongestionMetrics(rttStats *logging.RTTStats, cwnd, ssthresh, bytesInFlight logging.ByteCount) {
+}
+
 func (t *connTracer) AcknowledgedPacket(logging.EncryptionLevel, logging.PacketNumber) {}
 func (t *connTracer) LostPacket(logging.EncryptionLevel, logging.PacketNumber, logging.PacketLossReason) {
 }
 func (t *connTracer) UpdatedCongestionState(logging.CongestionState)                     {}
+func (t *connTracer) UpdatedCongestionControl(string, string)                            {}
 func (t *connTracer) UpdatedPTOCount(value uint32)                                       {}
+func (t *connTracer) UpdatedMTU(logging.ByteCount)                                       {}
+func (t *connTracer) UpdatedSpinBitRTT(time.Duration)                                    {}
 func (t *connTracer) UpdatedKeyFromTLS(logging.EncryptionLevel, logging.Perspective)     {}
 func (t *connTracer) UpdatedKey(generation logging.KeyPhase, remote bool)                {}
 func (t *connTracer) DroppedEncryptionLevel(logging.EncryptionLevel)                     {}
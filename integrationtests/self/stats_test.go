@@ -0,0 +1,83 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Connection Stats", func() {
+	It("counts sent, received and lost packets", func() {
+		data := GeneratePRData(500 << 10)
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		serverSess := make(chan quic.Session, 1)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+			serverSess <- sess
+		}()
+
+		// drop every 10th packet sent by the server, once the handshake is done
+		var numPackets, numDropped int32
+		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr:  fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			DelayPacket: func(quicproxy.Direction, []byte) time.Duration { return 5 * time.Millisecond },
+			DropPacket: func(dir quicproxy.Direction, _ []byte) bool {
+				if dir != quicproxy.DirectionOutgoing {
+					return false
+				}
+				if n := atomic.AddInt32(&numPackets, 1); n > 10 && n%10 == 0 {
+					atomic.AddInt32(&numDropped, 1)
+					return true
+				}
+				return false
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		str, err := sess.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		b, err := io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(Equal(data))
+
+		var s quic.Session
+		Eventually(serverSess).Should(Receive(&s))
+		stats := s.ConnectionStats()
+		Expect(stats.PacketsSent).To(BeNumerically(">=", atomic.LoadInt32(&numPackets)))
+		Expect(stats.PacketsLost).ToNot(BeZero())
+		Expect(stats.PacketsLost).To(BeNumerically("<=", stats.PacketsSent))
+		Expect(stats.PacketsRetransmitted).To(BeNumerically(">=", stats.PacketsLost))
+		Expect(stats.PacketsReceived).ToNot(BeZero())
+
+		clientStats := sess.ConnectionStats()
+		Expect(clientStats.PacketsReceived).To(BeNumerically("<=", stats.PacketsSent-uint64(atomic.LoadInt32(&numDropped))))
+		Expect(clientStats.PacketsReceived).To(BeNumerically(">", stats.PacketsSent/2))
+	})
+})
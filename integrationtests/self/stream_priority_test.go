@@ -0,0 +1,104 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/congestion"
+	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stream Priorities", func() {
+	const size = 200 << 10 // 200 KB
+
+	It("sends the data of the stream with the higher weight first, when limited by the congestion window", func() {
+		data := GeneratePRData(size)
+		// limit the congestion window to 10 packets, so that the streams compete for the bandwidth
+		const cwnd = 10 * 1252
+		server, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{Congestion: congestion.CongestionOptions{
+				InitialCongestionWindow: cwnd,
+				MaxCongestionWindow:     cwnd,
+			}}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			write := func(weight int) {
+				str, err := sess.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				str.SetPriority(weight, false)
+				go func() {
+					defer GinkgoRecover()
+					_, err := str.Write(data)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.Close()).To(Succeed())
+				}()
+			}
+			// the low priority stream starts sending first
+			write(0)
+			time.Sleep(scaleDuration(20 * time.Millisecond))
+			write(10)
+		}()
+
+		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr:  fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			DelayPacket: func(quicproxy.Direction, []byte) time.Duration { return 5 * time.Millisecond },
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+			getTLSClientConfig(),
+			// make sure that sending is limited by the congestion window, not by flow control
+			getQuicConfig(&quic.Config{
+				InitialStreamReceiveWindow:     2 * size,
+				InitialConnectionReceiveWindow: 4 * size,
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+
+		type result struct {
+			id       quic.StreamID
+			finished time.Time
+		}
+		results := make(chan result, 2)
+		for i := 0; i < 2; i++ {
+			str, err := sess.AcceptUniStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			go func() {
+				defer GinkgoRecover()
+				b, err := io.ReadAll(str)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b).To(Equal(data))
+				results <- result{id: str.StreamID(), finished: time.Now()}
+			}()
+		}
+		finished := make(map[quic.StreamID]time.Time)
+		for i := 0; i < 2; i++ {
+			var r result
+			Eventually(results, 10*time.Second).Should(Receive(&r))
+			finished[r.id] = r.finished
+		}
+		// the low priority stream was opened first
+		lowPrio, highPrio := finished[3], finished[7]
+		Expect(lowPrio).ToNot(BeZero())
+		Expect(highPrio).ToNot(BeZero())
+		Expect(highPrio).To(BeTemporally("<", lowPrio))
+	})
+})
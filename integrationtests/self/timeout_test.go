@@ -3,6 +3,7 @@ package self_test
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	mrand "math/rand"
@@ -74,6 +75,26 @@ var _ = Describe("Timeout tests", func() {
 		checkTimeoutError(err)
 	})
 
+	It("aborts a stalled handshake after the configured handshake idle timeout", func() {
+		const handshakeIdleTimeout = 600 * time.Millisecond
+		// a server that never responds
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+
+		start := time.Now()
+		_, err = quic.DialAddr(
+			conn.LocalAddr().String(),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{HandshakeIdleTimeout: handshakeIdleTimeout}),
+		)
+		checkTimeoutError(err)
+		Expect(time.Since(start)).To(And(
+			BeNumerically(">=", handshakeIdleTimeout),
+			BeNumerically("<", handshakeIdleTimeout*3/2),
+		))
+	})
+
 	It("returns the context error when the context expires", func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
 		defer cancel()
@@ -251,6 +272,41 @@ var _ = Describe("Timeout tests", func() {
 			Eventually(serverSessionClosed).Should(BeClosed())
 		})
 
+		It("closes the session with an idle timeout error after the configured idle timeout", func() {
+			server, err := quic.ListenAddr(
+				"localhost:0",
+				getTLSConfig(),
+				getQuicConfig(&quic.Config{DisablePathMTUDiscovery: true}),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			defer server.Close()
+
+			go func() {
+				defer GinkgoRecover()
+				_, err := server.Accept(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+			}()
+
+			sess, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+				getTLSClientConfig(),
+				getQuicConfig(&quic.Config{MaxIdleTimeout: idleTimeout, DisablePathMTUDiscovery: true}),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			startTime := time.Now()
+			errChan := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				_, err := sess.AcceptStream(context.Background())
+				errChan <- err
+			}()
+			var sessErr error
+			Eventually(errChan, 2*idleTimeout).Should(Receive(&sessErr))
+			Expect(time.Since(startTime)).To(BeNumerically(">=", idleTimeout))
+			var idleTimeoutErr *quic.IdleTimeoutError
+			Expect(errors.As(sessErr, &idleTimeoutErr)).To(BeTrue())
+		})
+
 		It("times out after sending a packet", func() {
 			server, err := quic.ListenAddr(
 				"localhost:0",
@@ -379,6 +435,109 @@ var _ = Describe("Timeout tests", func() {
 		Eventually(serverSessionClosed).Should(BeClosed())
 	})
 
+	It("does not time out if a keep-alive period is set", func() {
+		const idleTimeout = 100 * time.Millisecond
+
+		server, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{DisablePathMTUDiscovery: true}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		serverSessionClosed := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			sess.AcceptStream(context.Background()) // blocks until the session is closed
+			close(serverSessionClosed)
+		}()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{
+				MaxIdleTimeout:          idleTimeout,
+				KeepAlivePeriod:         idleTimeout / 4,
+				DisablePathMTUDiscovery: true,
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+
+		// wait longer than the idle timeout
+		time.Sleep(3 * idleTimeout)
+		str, err := sess.OpenUniStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Consistently(serverSessionClosed).ShouldNot(BeClosed())
+
+		Expect(sess.CloseWithError(0, "")).To(Succeed())
+		Expect(server.Close()).To(Succeed())
+		Eventually(serverSessionClosed).Should(BeClosed())
+	})
+
+	It("calls the application idle callback once, even though keep-alives are exchanged", func() {
+		const idleTimeout = 100 * time.Millisecond
+		appIdleTimeout := scaleDuration(200 * time.Millisecond)
+
+		var idleCount int32
+		server, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				DisablePathMTUDiscovery: true,
+				ApplicationIdleTimeout:  appIdleTimeout,
+				OnApplicationIdle:       func(quic.Session) { atomic.AddInt32(&idleCount, 1) },
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		serverSessionClosed := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.AcceptUniStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			io.Copy(io.Discard, str)
+			<-sess.Context().Done()
+			close(serverSessionClosed)
+		}()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{
+				MaxIdleTimeout:          idleTimeout,
+				KeepAlivePeriod:         idleTimeout / 4,
+				DisablePathMTUDiscovery: true,
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := sess.OpenUniStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+
+		getIdleCount := func() int32 { return atomic.LoadInt32(&idleCount) }
+		Eventually(getIdleCount, 5*appIdleTimeout).Should(BeEquivalentTo(1))
+		// keep-alives don't count as application activity, but keep the connection alive
+		Consistently(getIdleCount, 3*appIdleTimeout).Should(BeEquivalentTo(1))
+		Expect(serverSessionClosed).ToNot(BeClosed())
+
+		// after new stream activity, the callback is called again
+		_, err = str.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(getIdleCount, 5*appIdleTimeout).Should(BeEquivalentTo(2))
+
+		Expect(sess.CloseWithError(0, "")).To(Succeed())
+		Eventually(serverSessionClosed).Should(BeClosed())
+	})
+
 	Context("faulty packet conns", func() {
 		const handshakeTimeout = time.Second / 2
 
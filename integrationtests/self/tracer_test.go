@@ -60,11 +60,17 @@ func (t *customConnTracer) DroppedPacket(logging.PacketType, logging.ByteCount,
 func (t *customConnTracer) UpdatedMetrics(rttStats *logging.RTTStats, cwnd, bytesInFlight logging.ByteCount, packetsInFlight int) {
 }
 
+func (t *customConnTracer) UpdatedCongestionMetrics(rttStats *logging.RTTStats, cwnd, ssthresh, bytesInFlight logging.ByteCount) {
+}
+
 func (t *customConnTracer) AcknowledgedPacket(logging.EncryptionLevel, logging.PacketNumber) {}
 func (t *customConnTracer) LostPacket(logging.EncryptionLevel, logging.PacketNumber, logging.PacketLossReason) {
 }
 func (t *customConnTracer) UpdatedCongestionState(logging.CongestionState)                     {}
+func (t *customConnTracer) UpdatedCongestionControl(string, string)                            {}
 func (t *customConnTracer) UpdatedPTOCount(value uint32)                                       {}
+func (t *customConnTracer) UpdatedMTU(logging.ByteCount)                                       {}
+func (t *customConnTracer) UpdatedSpinBitRTT(time.Duration)                                    {}
 func (t *customConnTracer) UpdatedKeyFromTLS(logging.EncryptionLevel, logging.Perspective)     {}
 func (t *customConnTracer) UpdatedKey(generation logging.KeyPhase, remote bool)                {}
 func (t *customConnTracer) DroppedEncryptionLevel(logging.EncryptionLevel)                     {}
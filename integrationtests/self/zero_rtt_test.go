@@ -120,7 +120,8 @@ var _ = Describe("0-RTT", func() {
 					data, err := io.ReadAll(str)
 					Expect(err).ToNot(HaveOccurred())
 					Expect(data).To(Equal(testdata))
-					Expect(sess.ConnectionState().TLS.Used0RTT).To(BeTrue())
+					Eventually(sess.HandshakeComplete().Done()).Should(BeClosed())
+					Expect(connectionState(sess).TLS.Used0RTT).To(BeTrue())
 					Expect(sess.CloseWithError(0, "")).To(Succeed())
 					close(done)
 				}()
@@ -140,7 +141,9 @@ var _ = Describe("0-RTT", func() {
 				_, err = str.Write(testdata)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(str.Close()).To(Succeed())
-				Expect(sess.ConnectionState().TLS.Used0RTT).To(BeTrue())
+				Eventually(sess.HandshakeComplete().Done()).Should(BeClosed())
+				Expect(connectionState(sess).TLS.Used0RTT).To(BeTrue())
+				Expect(connectionState(sess).Used0RTT).To(BeTrue())
 				Eventually(done).Should(BeClosed())
 				Eventually(sess.Context().Done()).Should(BeClosed())
 			}
@@ -161,14 +164,16 @@ var _ = Describe("0-RTT", func() {
 				_, err = str.Write(make([]byte, 3000))
 				Expect(err).ToNot(HaveOccurred())
 				Expect(str.Close()).To(Succeed())
-				Expect(sess.ConnectionState().TLS.Used0RTT).To(BeFalse())
+				Eventually(sess.HandshakeComplete().Done()).Should(BeClosed())
+				Expect(connectionState(sess).TLS.Used0RTT).To(BeFalse())
 
 				// make sure the server doesn't process the data
 				ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(50*time.Millisecond))
 				defer cancel()
 				serverSess, err := ln.Accept(ctx)
 				Expect(err).ToNot(HaveOccurred())
-				Expect(serverSess.ConnectionState().TLS.Used0RTT).To(BeFalse())
+				Eventually(serverSess.HandshakeComplete().Done()).Should(BeClosed())
+				Expect(connectionState(serverSess).TLS.Used0RTT).To(BeFalse())
 				_, err = serverSess.AcceptUniStream(ctx)
 				Expect(err).To(Equal(context.DeadlineExceeded))
 				Expect(serverSess.CloseWithError(0, "")).To(Succeed())
@@ -243,6 +248,61 @@ var _ = Describe("0-RTT", func() {
 				})
 			}
 
+			It("transfers 0-RTT data, using a session restored from a serialized session cache", func() {
+				ln, err := quic.ListenAddrEarly(
+					"localhost:0",
+					getTLSConfig(),
+					getQuicConfig(&quic.Config{
+						Versions:    []protocol.VersionNumber{version},
+						AcceptToken: func(_ net.Addr, _ *quic.Token) bool { return true },
+					}),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+
+				proxy, num0RTTPackets := runCountingProxy(ln.Addr().(*net.UDPAddr).Port)
+				defer proxy.Close()
+
+				// dial the first session in order to receive a session ticket
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					sess, err := ln.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					<-sess.Context().Done()
+				}()
+				puts := make(chan string, 100)
+				cache := newSerializedSessionCache(nil, puts)
+				sess, err := quic.DialAddr(
+					fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+					getTLSClientConfig(),
+					getQuicConfig(&quic.Config{
+						Versions:           []protocol.VersionNumber{version},
+						ClientSessionCache: cache,
+					}),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				Eventually(puts).Should(Receive())
+				Expect(sess.CloseWithError(0, "")).To(Succeed())
+				Eventually(done).Should(BeClosed())
+				Expect(atomic.LoadUint32(num0RTTPackets)).To(BeZero())
+
+				// Simulate a restart of the client:
+				// Only the serialized sessions are kept, and a new tls.Config is used.
+				transfer0RTTData(
+					ln,
+					proxy.LocalPort(),
+					getTLSClientConfig(),
+					getQuicConfig(&quic.Config{
+						Versions:           []protocol.VersionNumber{version},
+						ClientSessionCache: newSerializedSessionCache(cache.Sessions(), make(chan string, 100)),
+					}),
+					PRData,
+				)
+				Expect(atomic.LoadUint32(num0RTTPackets)).ToNot(BeZero())
+			})
+
 			// Test that data intended to be sent with 1-RTT protection is not sent in 0-RTT packets.
 			It("waits until a session until the handshake is done", func() {
 				tlsConf, clientConf := dialAndReceiveSessionTicket(nil)
@@ -491,7 +551,8 @@ var _ = Describe("0-RTT", func() {
 				defer cancel()
 				_, err = sess.OpenUniStreamSync(ctx)
 				Expect(err).ToNot(HaveOccurred())
-				Expect(sess.ConnectionState().TLS.Used0RTT).To(BeTrue())
+				Eventually(sess.HandshakeComplete().Done()).Should(BeClosed())
+				Expect(connectionState(sess).TLS.Used0RTT).To(BeTrue())
 				Expect(sess.CloseWithError(0, "")).To(Succeed())
 			})
 
@@ -608,7 +669,8 @@ var _ = Describe("0-RTT", func() {
 					data, err := io.ReadAll(rstr)
 					Expect(err).ToNot(HaveOccurred())
 					Expect(data).To(Equal([]byte("foobar")))
-					Expect(serverSess.ConnectionState().TLS.Used0RTT).To(BeTrue())
+					Eventually(serverSess.HandshakeComplete().Done()).Should(BeClosed())
+					Expect(connectionState(serverSess).TLS.Used0RTT).To(BeTrue())
 					Expect(serverSess.CloseWithError(0, "")).To(Succeed())
 					Eventually(sess.Context().Done()).Should(BeClosed())
 
@@ -241,7 +241,8 @@ func (p *QuicProxy) newConnection(cliAddr *net.UDPAddr) (*connection, error) {
 // runProxy listens on the proxy address and handles incoming packets.
 func (p *QuicProxy) runProxy() error {
 	for {
-		buffer := make([]byte, protocol.MaxPacketBufferSize)
+		// use buffers large enough for jumbo datagrams, so that they're not truncated by the proxy
+		buffer := make([]byte, protocol.MaxLargePacketBufferSize)
 		n, cliaddr, err := p.conn.ReadFromUDP(buffer)
 		if err != nil {
 			return err
@@ -294,7 +295,7 @@ func (p *QuicProxy) runOutgoingConnection(conn *connection) error {
 	outgoingPackets := make(chan packetEntry, 10)
 	go func() {
 		for {
-			buffer := make([]byte, protocol.MaxPacketBufferSize)
+			buffer := make([]byte, protocol.MaxLargePacketBufferSize)
 			n, err := conn.ServerConn.Read(buffer)
 			if err != nil {
 				return
@@ -7,7 +7,7 @@ import (
 	"net"
 	"time"
 
-	"github.com/lucas-clemente/quic-go/internal/congestion"
+	"github.com/lucas-clemente/quic-go/congestion"
 	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/logging"
@@ -24,6 +24,8 @@ const (
 	VersionDraft29 = protocol.VersionDraft29
 	// Version1 is RFC 9000
 	Version1 = protocol.Version1
+	// Version2 is RFC 9369
+	Version2 = protocol.Version2
 )
 
 // A Token can be used to verify the ownership of the client address.
@@ -36,6 +38,22 @@ type Token struct {
 	SentTime     time.Time
 }
 
+// A TokenProtector protects the tokens that the server issues in Retry packets and in NEW_TOKEN frames.
+// It must make sure that a client can't forge a token, or modify a token it received.
+type TokenProtector = handshake.TokenProtector
+
+// NewHMACTokenProtector creates a TokenProtector that authenticates tokens using HMAC-SHA256.
+// Servers using the same key accept each other's tokens, e.g. multiple servers behind a load balancer.
+// The key should be chosen at random. It must be at least 32 bytes long.
+func NewHMACTokenProtector(key []byte) (TokenProtector, error) {
+	return handshake.NewHMACTokenProtector(key)
+}
+
+// A ClientSessionCache stores the sessions that the client uses for session resumption and 0-RTT.
+// Besides the TLS session, a serialized session contains the server's transport parameters,
+// since these are needed to send 0-RTT data.
+type ClientSessionCache = handshake.ClientSessionCache
+
 // A ClientToken is a token received by the client.
 // It can be used to skip address validation on future connection attempts.
 type ClientToken struct {
@@ -60,6 +78,9 @@ type TokenStore interface {
 // when the server rejects a 0-RTT connection attempt.
 var Err0RTTRejected = errors.New("0-RTT rejected")
 
+// ErrHandshakeNotComplete is returned by Session.ConnectionStateErr if the handshake hasn't completed yet.
+var ErrHandshakeNotComplete = errors.New("the handshake hasn't completed yet")
+
 // SessionTracingKey can be used to associate a ConnectionTracer with a Session.
 // It is set on the Session.Context() context,
 // as well as on the context passed to logging.Tracer.NewConnectionTracer.
@@ -136,6 +157,14 @@ type SendStream interface {
 	// some of the data was successfully written.
 	// A zero value for t means Write will not time out.
 	SetWriteDeadline(t time.Time) error
+	// SetPriority sets the priority used to schedule the data of this stream, relative to the other streams of the session.
+	// When the congestion controller (or flow control) limits the amount of data that can be sent,
+	// data of streams with a higher weight is sent first.
+	// Streams of the same weight are interleaved if they are incremental,
+	// otherwise they are sent one after the other, in the order they became ready to send.
+	// By default, streams have a weight of 0 and are incremental, i.e. all streams share the bandwidth equally.
+	// Warning: This API should not be considered stable and might change soon.
+	SetPriority(weight int, incremental bool)
 }
 
 // A Session is a QUIC connection between two peers.
@@ -187,17 +216,102 @@ type Session interface {
 	// The context is cancelled when the session is closed.
 	// Warning: This API should not be considered stable and might change soon.
 	Context() context.Context
+	// HandshakeConfirmed returns a context that is cancelled when the handshake is confirmed (RFC 9001, Section 4.1.2).
+	// For the server, this happens when the handshake completes.
+	// For the client, this happens when it receives a HANDSHAKE_DONE frame, or an acknowledgement for a 1-RTT packet.
+	// The context is also cancelled if the session is closed before the handshake is confirmed.
+	// Starting with Go 1.20, context.Cause then returns the error that closed the session.
+	// If the handshake was confirmed, context.Cause returns context.Canceled.
+	// Warning: This API should not be considered stable and might change soon.
+	HandshakeConfirmed() context.Context
 	// ConnectionState returns basic details about the QUIC connection.
 	// It blocks until the handshake completes.
+	// If the handshake failed, TLS.HandshakeComplete is false.
 	// Warning: This API should not be considered stable and might change soon.
 	ConnectionState() ConnectionState
+	// ConnectionStateErr is like ConnectionState, but it doesn't block.
+	// It returns ErrHandshakeNotComplete if the handshake hasn't completed yet.
+	// Use HandshakeComplete to wait for the handshake to complete.
+	// Warning: This API should not be considered stable and might change soon.
+	ConnectionStateErr() (ConnectionState, error)
+	// CongestionState returns a snapshot of the state of the congestion controller.
+	// It is safe to call it concurrently with sending and receiving data.
+	// Warning: This API should not be considered stable and might change soon.
+	CongestionState() CongestionState
+	// RTTStats returns a snapshot of the RTT statistics of the session.
+	// It is safe to call it concurrently with sending and receiving data.
+	// Warning: This API should not be considered stable and might change soon.
+	RTTStats() RTTStats
+	// StreamStats returns a snapshot of the flow control state of all open streams, sorted by stream ID.
+	// The snapshot is taken between processing packets, so it is consistent for all streams.
+	// It is safe to call it concurrently with sending and receiving data.
+	// It returns nil if the session is closed.
+	// Warning: This API should not be considered stable and might change soon.
+	StreamStats() []StreamStats
+	// ConnectionStats returns counters of events on the connection.
+	// It is safe to call it concurrently with sending and receiving data.
+	// Warning: This API should not be considered stable and might change soon.
+	ConnectionStats() ConnectionStats
+	// SetCongestionControl replaces the congestion controller of the session.
+	// The new controller starts with the current congestion window (unless an initial window is configured),
+	// and packets in flight remain accounted for.
+	// All other state of the current controller (e.g. whether it is in recovery) is discarded.
+	// It is safe to call it concurrently with sending and receiving data.
+	// It returns an error if the session is already closed,
+	// or if the congestion controller was created by Config.NewCongestionControl.
+	// Warning: This API should not be considered stable and might change soon.
+	SetCongestionControl(congestion.CongestionOptions) error
+	// SetSendRateLimit limits the rate at which the session sends packets, in bytes per second.
+	// The limit applies in addition to congestion control: the session never sends faster than the congestion controller allows.
+	// A value of 0 (or a negative value) removes the limit.
+	// It is safe to call it concurrently with sending and receiving data.
+	// Warning: This API should not be considered stable and might change soon.
+	SetSendRateLimit(bytesPerSec int64)
+	// BytesInFlight returns the number of bytes sent in ack-eliciting packets that were neither acknowledged nor declared lost yet.
+	// The congestion window is reported in the CongestionState.
+	// It is safe to call it concurrently with sending and receiving data.
+	// Warning: This API should not be considered stable and might change soon.
+	BytesInFlight() logging.ByteCount
+	// SetMaxBytesInFlight limits the number of bytes in flight.
+	// The limit applies in addition to congestion control: the session never sends more than the congestion window allows.
+	// Packets that only contain ACK frames, and PTO probe packets, are still sent when the limit is reached.
+	// A value of 0 removes the limit.
+	// It is safe to call it concurrently with sending and receiving data.
+	// Warning: This API should not be considered stable and might change soon.
+	SetMaxBytesInFlight(logging.ByteCount)
+	// InitiateKeyUpdate requests an update of the 1-RTT keys (RFC 9001, Section 6).
+	// The keys are updated with the next packet sent, as soon as this is allowed,
+	// i.e. after the handshake is confirmed and after a packet sent with the current keys was acknowledged.
+	// It is mainly useful for testing, since the session regularly updates its keys anyway.
+	// It returns an error if the session is already closed.
+	InitiateKeyUpdate() error
+	// RotateConnectionID issues a new connection ID to the peer,
+	// and asks it to retire all connection IDs issued before (RFC 9000, Section 5.1.2).
+	// The peer then uses the new connection ID when sending packets.
+	// Replacements for the retired connection IDs are issued, up to the peer's active_connection_id_limit.
+	// It returns an error if the handshake hasn't completed yet, if the session uses zero-length connection IDs,
+	// or if the session is already closed.
+	// Warning: This API should not be considered stable and might change soon.
+	RotateConnectionID() error
+	// MigrateTo migrates the connection to a new socket (RFC 9000, Section 9).
+	// It validates the new path using PATH_CHALLENGE and PATH_RESPONSE frames first,
+	// and returns once the connection switched to the new path, or an error if path validation failed.
+	// The packet conn is not closed when the session is closed.
+	// Only the client can migrate, after the handshake was confirmed,
+	// and only if the server didn't disable active migration.
+	// Warning: This API should not be considered stable and might change soon.
+	MigrateTo(net.PacketConn) error
 
-	// SendMessage sends a message as a datagram.
-	// See https://datatracker.ietf.org/doc/draft-pauly-quic-datagram/.
-	SendMessage([]byte) error
-	// ReceiveMessage gets a message received in a datagram.
-	// See https://datatracker.ietf.org/doc/draft-pauly-quic-datagram/.
-	ReceiveMessage() ([]byte, error)
+	// SendDatagram sends an unreliable datagram (RFC 9221).
+	// It returns an error if datagram support wasn't negotiated (see Config.EnableDatagrams),
+	// and a DatagramTooLargeError if the payload exceeds the maximum DATAGRAM frame size allowed by the peer.
+	// By default, it blocks until the datagram is packed into a packet.
+	// If Config.DatagramSendQueueLen is set, it queues the datagram and returns right away.
+	// If the queue is full, the oldest queued datagram is dropped, and ErrDatagramQueueOverflow is returned.
+	SendDatagram([]byte) error
+	// ReceiveDatagram gets a datagram received from the peer (RFC 9221).
+	// It blocks until a datagram is received, the context is canceled, or the session is closed.
+	ReceiveDatagram(context.Context) ([]byte, error)
 }
 
 // An EarlySession is a session that is handshaking.
@@ -215,6 +329,18 @@ type EarlySession interface {
 	NextSession() Session
 }
 
+// A ConnectionIDGenerator generates connection IDs.
+// It can be used to encode information into the connection IDs,
+// e.g. to allow a load balancer to route packets to the right server.
+type ConnectionIDGenerator interface {
+	// GenerateConnectionID generates a new connection ID.
+	// Connection IDs must be unique, and it should not be possible for an observer to correlate them.
+	GenerateConnectionID() ([]byte, error)
+	// ConnectionIDLen is the length of the connection IDs returned by GenerateConnectionID.
+	// It must not change, and can be 0, or any value between 4 and 20.
+	ConnectionIDLen() int
+}
+
 // Config contains all configuration data needed for a QUIC server or client.
 type Config struct {
 	// The QUIC versions that can be negotiated.
@@ -228,8 +354,14 @@ type Config struct {
 	// If used for a server, or dialing on a packet conn, a 4 byte connection ID will be used.
 	// When dialing on a packet conn, the ConnectionIDLength value must be the same for every Dial call.
 	ConnectionIDLength int
+	// ConnectionIDGenerator generates the connection IDs used by this endpoint.
+	// If set, the length of the connection IDs is determined by its ConnectionIDLen method,
+	// and ConnectionIDLength must either be 0 or match that length.
+	// If not set, random connection IDs are used.
+	ConnectionIDGenerator ConnectionIDGenerator
 	// HandshakeIdleTimeout is the idle timeout before completion of the handshake.
 	// Specifically, if we don't receive any packet from the peer within this time, the connection attempt is aborted.
+	// Independent of that, the handshake is aborted if it doesn't complete within twice this time (but at least 10 seconds).
 	// If this value is zero, the timeout is set to 5 seconds.
 	HandshakeIdleTimeout time.Duration
 	// MaxIdleTimeout is the maximum duration that may pass without any incoming network activity.
@@ -246,11 +378,36 @@ type Config struct {
 	//   * else, that it was issued within the last 24 hours.
 	// This option is only valid for the server.
 	AcceptToken func(clientAddr net.Addr, token *Token) bool
+	// RequireAddressValidation determines if a Retry packet is sent to a client whose token
+	// was not accepted by AcceptToken (or that didn't send a token).
+	// This allows the server to validate the client's address before spending resources on the handshake,
+	// at the cost of increasing the handshake latency by 1 RTT.
+	// If not set, a Retry packet is sent whenever the token is not accepted.
+	// This option is only valid for the server.
+	RequireAddressValidation func(clientAddr net.Addr) bool
+	// TokenProtector protects the tokens issued in Retry packets and in NEW_TOKEN frames.
+	// If not set, tokens are encrypted using a key that is randomly generated when the server is started.
+	// This option is only valid for the server.
+	TokenProtector TokenProtector
+	// Allow0RTT determines if a 0-RTT connection attempt from a client is accepted.
+	// It is only used for sessions accepted by an EarlyListener (see ListenEarly).
+	// If not set, 0-RTT is accepted for all clients.
+	// 0-RTT data is not protected against replay attacks: an attacker can capture 0-RTT packets
+	// and replay them on a new connection. Applications should only process requests received in 0-RTT
+	// if doing so multiple times doesn't have any unwanted side effects.
+	// This option is only valid for the server.
+	Allow0RTT func(clientAddr net.Addr) bool
 	// The TokenStore stores tokens received from the server.
 	// Tokens are used to skip address validation on future connection attempts.
 	// The key used to store tokens is the ServerName from the tls.Config, if set
 	// otherwise the token is associated with the server's IP address.
 	TokenStore TokenStore
+	// ClientSessionCache stores the sessions that are used to resume connections, and to send 0-RTT data.
+	// Sessions are passed to the cache in serialized form, such that they can be persisted,
+	// and used after the client was restarted.
+	// If set, it is used instead of the ClientSessionCache in the tls.Config.
+	// This option is only valid for the client.
+	ClientSessionCache ClientSessionCache
 	// InitialStreamReceiveWindow is the initial size of the stream-level flow control window for receiving data.
 	// If the application is consuming data quickly enough, the flow control auto-tuning algorithm
 	// will increase the window up to MaxStreamReceiveWindow.
@@ -277,30 +434,213 @@ type Config struct {
 	// If not set, it will default to 100.
 	// If set to a negative value, it doesn't allow any unidirectional streams.
 	MaxIncomingUniStreams int64
+	// MaxStreamsIncrement controls how stream credit is granted to the peer when it closes streams.
+	// A MAX_STREAMS frame is sent once the peer can open at least this many new streams
+	// (but never waiting for more streams than the stream limit).
+	// If not set, new credit is granted as soon as a single stream is closed.
+	// If set to a negative value, new credit is granted once half of the stream limit was consumed.
+	// Values above 2^60 are invalid.
+	MaxStreamsIncrement int64
 	// The StatelessResetKey is used to generate stateless reset tokens.
+	// The tokens are derived from the key and the connection ID, so servers using the same key
+	// (e.g. after a restart, or servers behind a load balancer) can reset each other's connections.
+	// The key must be kept secret, and should be 32 bytes long.
 	// If no key is configured, sending of stateless resets is disabled.
 	StatelessResetKey []byte
 	// KeepAlive defines whether this peer will periodically send a packet to keep the connection alive.
 	KeepAlive bool
+	// KeepAlivePeriod is the period after which a packet is sent to keep the connection alive,
+	// if no packet was received in that time.
+	// It is limited to half the idle timeout, to make sure the peer doesn't time out the connection.
+	// If set, it enables keep-alives, regardless of the value of KeepAlive.
+	// If not set, and KeepAlive is enabled, keep-alives are sent after half the idle timeout,
+	// but at least every 20s.
+	KeepAlivePeriod time.Duration
+	// ApplicationIdleTimeout is the duration after which OnApplicationIdle is called,
+	// if no stream data or datagrams were sent or received on a connection.
+	// Unlike the MaxIdleTimeout, it isn't reset by keep-alives or other control frames.
+	// This option is only valid for the server.
+	ApplicationIdleTimeout time.Duration
+	// OnApplicationIdle is called when a connection has been idle for the ApplicationIdleTimeout.
+	// It is called once per idle period: it is only called again after new stream data or datagrams were exchanged.
+	// It is called on a separate go routine, so it may close the session.
+	// This option is only valid for the server.
+	OnApplicationIdle func(Session)
 	// DisablePathMTUDiscovery disables Path MTU Discovery (RFC 8899).
 	// Packets will then be at most 1252 (IPv4) / 1232 (IPv6) bytes in size.
 	DisablePathMTUDiscovery bool
+	// MaxPathMTUProbeSize is the largest datagram size that Path MTU Discovery probes for.
+	// The search is also bounded by the peer's max_udp_payload_size transport parameter.
+	// It must not be smaller than 1200. If zero, it defaults to 1452 bytes.
+	// Values larger than 1452 bytes enable jumbo datagrams (up to 8952 bytes, for a 9000 byte MTU):
+	// packets of that size are then sent once the path was validated to support them,
+	// and received packets of that size are accepted (and advertised in the max_udp_payload_size transport parameter).
+	MaxPathMTUProbeSize uint16
+	// InitialPacketSize is the maximum size of packets sent before Path MTU Discovery raises it,
+	// including the (padded) Initial packets.
+	// It can be lowered to avoid IP fragmentation during the handshake on networks with a small MTU.
+	// It is clamped between 1200 and 1452 bytes. If zero, it defaults to 1252 (IPv4) / 1232 (IPv6) bytes.
+	InitialPacketSize uint16
+	// Disable1RTTCoalescing disables coalescing of 1-RTT packets with Initial and Handshake packets during the handshake.
+	// 1-RTT packets are then sent in separate datagrams, which helps on paths where middleboxes drop large coalesced datagrams.
+	// Initial packets are still padded to the minimum size, and 0-RTT packets are still coalesced with Initial packets.
+	Disable1RTTCoalescing bool
 	// DisableVersionNegotiationPackets disables the sending of Version Negotiation packets.
 	// This can be useful if version information is exchanged out-of-band.
 	// It has no effect for a client.
 	DisableVersionNegotiationPackets bool
+	// EnableActiveMigration allows the client to migrate the connection to a new address.
+	// By default, the server sends the disable_active_migration transport parameter.
+	// If enabled, the server follows a client that sends packets from a new address, and validates the new path.
+	// Until the path is validated, the server sends at most three times the amount of data received on it.
+	// It has no effect for a client.
+	EnableActiveMigration bool
+	// DisableSpinBit disables the latency spin bit (RFC 9000, Section 17.4).
+	// By default, the spin bit allows on-path observers to measure the RTT of the connection.
+	// If disabled, a random value is sent for the whole connection.
+	DisableSpinBit bool
 	// See https://datatracker.ietf.org/doc/draft-ietf-quic-datagram/.
 	// Datagrams will only be available when both peers enable datagram support.
 	EnableDatagrams bool
+	// DatagramSendQueueLen is the maximum number of datagrams that are queued for sending.
+	// If set, Session.SendDatagram doesn't block, and the oldest datagram is dropped when the queue is full.
+	// If not set, Session.SendDatagram blocks until the datagram is sent.
+	DatagramSendQueueLen int
+	// EnableAckFrequency enables support for the ACK frequency extension.
+	// The peer can then ask us to acknowledge packets less (or more) frequently.
+	// See https://datatracker.ietf.org/doc/draft-ietf-quic-ack-frequency/.
+	EnableAckFrequency bool
+	// MaxAckDelay is the maximum time by which we delay acknowledging ack-eliciting packets.
+	// Packets are acknowledged right away once a threshold of ack-eliciting packets was received (2 by default).
+	// It is advertised in the max_ack_delay transport parameter, so that the peer accounts for it when calculating the PTO.
+	// If the ACK frequency extension is enabled, the peer can request a different value.
+	// It must not exceed 2^14 milliseconds. If zero, the value recommended by RFC 9000 (25ms) is used.
+	MaxAckDelay time.Duration
+	// DisablePacing disables pacing of packets.
+	// Packets are still limited by the congestion window.
+	// This can reduce latency on fast local links, but might cause packet loss due to bursts on real networks.
+	DisablePacing bool
+	// DisableGSO disables UDP generic segmentation offload (GSO).
+	// On Linux, GSO is used to send multiple packets to the same peer in a single syscall, if the kernel supports it.
+	// It is disabled automatically if the network interface turns out not to support it.
+	DisableGSO bool
+	// PTOMultiplier scales the probe timeout (PTO).
+	// Values larger than 1 reduce the number of spurious probe packets on links with a high jitter.
+	// It must not be smaller than 1. If zero, the PTO as defined in RFC 9002 is used.
+	PTOMultiplier float64
+	// PacketReorderingThreshold is the number of packets that need to be acknowledged after a packet,
+	// before that packet is declared lost.
+	// Raising it reduces the number of spurious retransmissions on paths with significant reordering.
+	// It must not be smaller than 3. If zero, the value recommended by RFC 9002 (3) is used.
+	PacketReorderingThreshold uint32
+	// EnableECN enables sending of ECN-marked (ECT(0)) packets, after the handshake is confirmed.
+	// The path is validated as described in section 13.4.2 of RFC 9000, and marking is stopped if validation fails.
+	// ECN-CE marks reported by the peer are treated as a congestion signal.
+	// This is only supported on Linux, macOS and FreeBSD, if the connection is a net.UDPConn.
+	EnableECN bool
+	// DSCP is the Differentiated Services Code Point (RFC 2474) that outgoing packets are marked with.
+	// It must be between 0 and 63. It is set using the IP_TOS / IPV6_TCLASS socket option,
+	// so it applies to all packets sent on the packet conn, including the packets of other connections sharing it.
+	// If ECN is enabled, the ECN-marked packets carry the DSCP as well.
+	// This is only supported on Linux, macOS and FreeBSD, if the connection is a net.UDPConn.
+	// On other platforms, and for other packet conns, it is a no-op.
+	DSCP int
 	// Congestion Algorithm
 	Congestion congestion.CongestionOptions
-	Tracer     logging.Tracer
+	// NewCongestionControl creates the congestion controller used for a connection.
+	// If set, it takes precedence over the algorithm selected in Congestion.
+	// The controller can then not be replaced using Session.SetCongestionControl.
+	// It is only notified of ECN congestion events, spurious losses and application-limited periods
+	// if it implements the corresponding optional interfaces of the congestion package.
+	NewCongestionControl func(rttStats *congestion.RTTStats, initialMaxDatagramSize congestion.ByteCount, tracer logging.ConnectionTracer) congestion.SendAlgorithmWithDebugInfos
+	Tracer               logging.Tracer
 }
 
 // ConnectionState records basic details about a QUIC connection
 type ConnectionState struct {
+	// TLS is the state of the TLS handshake.
+	// The negotiated ALPN is TLS.NegotiatedProtocol.
 	TLS               handshake.ConnectionState
 	SupportsDatagrams bool
+	// Used0RTT says if 0-RTT was used on this connection.
+	Used0RTT bool
+	// Version is the QUIC version of the connection.
+	Version VersionNumber
+}
+
+// CongestionState records the state of the congestion controller of a QUIC connection
+type CongestionState struct {
+	// CongestionControl is the name of the congestion control algorithm in use, e.g. "cubic" or "bbr".
+	// If the configured algorithm is not supported, this is the algorithm that was used instead.
+	CongestionControl string
+	// Hystart is the name of the hybrid slow start variant, e.g. "standard".
+	// It is "none" for BBR and BBRv2, which don't use hystart.
+	Hystart          string
+	CongestionWindow logging.ByteCount
+	BytesInFlight    logging.ByteCount
+	SmoothedRTT      time.Duration
+	MinRTT           time.Duration
+	Phase            logging.CongestionState
+	// SpuriousLosses is the number of packets that were declared lost, but were acknowledged later.
+	SpuriousLosses uint64
+	// BandwidthEstimate is the estimated bandwidth of the path.
+	// It is zero if the congestion controller doesn't have an estimate yet.
+	BandwidthEstimate congestion.Bandwidth
+}
+
+// RTTStats records the RTT statistics of a QUIC connection
+type RTTStats struct {
+	SmoothedRTT time.Duration
+	// RTTVar is the mean deviation of the RTT samples
+	RTTVar    time.Duration
+	MinRTT    time.Duration
+	LatestRTT time.Duration
+}
+
+// StreamStats records the flow control state of a stream.
+// For unidirectional streams, only the fields for the direction of the stream are set.
+type StreamStats struct {
+	StreamID StreamID
+	// BytesSent is the number of bytes of stream data sent, not counting retransmissions.
+	BytesSent logging.ByteCount
+	// SendWindow is the offset up to which the peer allows us to send.
+	SendWindow logging.ByteCount
+	// BlockedOnFlowControl is true if there's data to send on the stream,
+	// but the stream-level flow control window is exhausted.
+	BlockedOnFlowControl bool
+	// BytesReceived is the highest offset of stream data received from the peer.
+	BytesReceived logging.ByteCount
+	// ReceiveWindow is the offset up to which the peer is allowed to send.
+	ReceiveWindow logging.ByteCount
+}
+
+// ConnectionStats records counters of a QUIC connection.
+// A high number of BLOCKED frames indicates that the connection is starved by the peer's flow control or stream limits.
+type ConnectionStats struct {
+	// DataBlockedFramesSent is the number of DATA_BLOCKED frames sent, including retransmissions.
+	DataBlockedFramesSent uint64
+	// StreamDataBlockedFramesSent is the number of STREAM_DATA_BLOCKED frames sent, including retransmissions.
+	StreamDataBlockedFramesSent uint64
+	// StreamsBlockedFramesSent is the number of STREAMS_BLOCKED frames sent, including retransmissions.
+	StreamsBlockedFramesSent uint64
+	// PacketsSent is the number of packets sent, including retransmissions.
+	PacketsSent uint64
+	// PacketsReceived is the number of packets received and successfully decrypted.
+	PacketsReceived uint64
+	// PacketsLost is the number of packets declared lost by the loss detection.
+	// Path MTU probe packets are not counted.
+	PacketsLost uint64
+	// PacketsRetransmitted is the number of packets whose frames were queued for retransmission,
+	// because the packet was declared lost, or a probe packet had to be sent.
+	PacketsRetransmitted uint64
+	// LossRate is the fraction of packets that were declared lost,
+	// of all packets acknowledged or declared lost during the last one to two RTTs.
+	LossRate float64
+	// DatagramSendQueueLen is the number of datagrams waiting to be sent.
+	DatagramSendQueueLen int
+	// DatagramsDropped is the number of datagrams that were dropped because the send queue was full
+	// (see Config.DatagramSendQueueLen).
+	DatagramsDropped uint64
 }
 
 // A Listener for incoming QUIC connections
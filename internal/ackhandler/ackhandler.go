@@ -1,6 +1,8 @@
 package ackhandler
 
 import (
+	"time"
+
 	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
@@ -16,8 +18,13 @@ func NewAckHandler(
 	tracer logging.ConnectionTracer,
 	logger utils.Logger,
 	version protocol.VersionNumber,
-	congestion congestion.CongestionOptions,
+	ptoMultiplier float64,
+	packetReorderingThreshold protocol.PacketNumber,
+	maxAckDelay time.Duration,
+	enableECN bool,
+	congestionOptions congestion.CongestionOptions,
+	newCongestionControl congestion.SendAlgorithmFactory,
 ) (SentPacketHandler, ReceivedPacketHandler) {
-	sph := newSentPacketHandler(initialPacketNumber, initialMaxDatagramSize, rttStats, pers, congestion, tracer, logger)
-	return sph, newReceivedPacketHandler(sph, rttStats, logger, version)
+	sph := newSentPacketHandler(congestion.DefaultClock{}, initialPacketNumber, initialMaxDatagramSize, rttStats, pers, ptoMultiplier, packetReorderingThreshold, enableECN, congestionOptions, newCongestionControl, tracer, logger)
+	return sph, newReceivedPacketHandler(congestion.DefaultClock{}, sph, rttStats, maxAckDelay, logger, version)
 }
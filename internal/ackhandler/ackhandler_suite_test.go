@@ -3,6 +3,7 @@ package ackhandler
 import (
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo"
@@ -16,6 +17,17 @@ func TestCrypto(t *testing.T) {
 
 var mockCtrl *gomock.Controller
 
+// mockClock is a clock that only advances when Advance is called.
+type mockClock time.Time
+
+func (c *mockClock) Now() time.Time {
+	return time.Time(*c)
+}
+
+func (c *mockClock) Advance(d time.Duration) {
+	*c = mockClock(time.Time(*c).Add(d))
+}
+
 var _ = BeforeSuite(func() {
 	rand.Seed(GinkgoRandomSeed())
 })
@@ -0,0 +1,159 @@
+package ackhandler
+
+import (
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+// The deferredTracer queues the events generated by the sentPacketHandler and the congestion controller.
+// These events are generated while holding the sentPacketHandler's mutex.
+// They are only passed on to the tracer after the mutex was released,
+// so that the tracer can call back into the session (e.g. to get the CongestionState) without deadlocking.
+// It is protected by the sentPacketHandler's mutex.
+type deferredTracer struct {
+	tracer logging.ConnectionTracer
+	events []func()
+}
+
+var _ logging.ConnectionTracer = &deferredTracer{}
+
+func newDeferredTracer(tracer logging.ConnectionTracer) *deferredTracer {
+	return &deferredTracer{tracer: tracer}
+}
+
+// PopEvents returns the queued events, in the order they were generated.
+func (t *deferredTracer) PopEvents() []func() {
+	events := t.events
+	t.events = nil
+	return events
+}
+
+func (t *deferredTracer) queue(f func()) {
+	t.events = append(t.events, f)
+}
+
+func (t *deferredTracer) StartedConnection(local, remote net.Addr, srcConnID, destConnID logging.ConnectionID) {
+	t.queue(func() { t.tracer.StartedConnection(local, remote, srcConnID, destConnID) })
+}
+
+func (t *deferredTracer) NegotiatedVersion(chosen logging.VersionNumber, clientVersions, serverVersions []logging.VersionNumber) {
+	t.queue(func() { t.tracer.NegotiatedVersion(chosen, clientVersions, serverVersions) })
+}
+
+func (t *deferredTracer) ClosedConnection(e error) {
+	t.queue(func() { t.tracer.ClosedConnection(e) })
+}
+
+func (t *deferredTracer) SentTransportParameters(tp *logging.TransportParameters) {
+	t.queue(func() { t.tracer.SentTransportParameters(tp) })
+}
+
+func (t *deferredTracer) ReceivedTransportParameters(tp *logging.TransportParameters) {
+	t.queue(func() { t.tracer.ReceivedTransportParameters(tp) })
+}
+
+func (t *deferredTracer) RestoredTransportParameters(tp *logging.TransportParameters) {
+	t.queue(func() { t.tracer.RestoredTransportParameters(tp) })
+}
+
+func (t *deferredTracer) SentPacket(hdr *logging.ExtendedHeader, size logging.ByteCount, ack *logging.AckFrame, frames []logging.Frame) {
+	t.queue(func() { t.tracer.SentPacket(hdr, size, ack, frames) })
+}
+
+func (t *deferredTracer) ReceivedVersionNegotiationPacket(hdr *logging.Header, versions []logging.VersionNumber) {
+	t.queue(func() { t.tracer.ReceivedVersionNegotiationPacket(hdr, versions) })
+}
+
+func (t *deferredTracer) ReceivedRetry(hdr *logging.Header) {
+	t.queue(func() { t.tracer.ReceivedRetry(hdr) })
+}
+
+func (t *deferredTracer) ReceivedPacket(hdr *logging.ExtendedHeader, size logging.ByteCount, frames []logging.Frame) {
+	t.queue(func() { t.tracer.ReceivedPacket(hdr, size, frames) })
+}
+
+func (t *deferredTracer) BufferedPacket(typ logging.PacketType) {
+	t.queue(func() { t.tracer.BufferedPacket(typ) })
+}
+
+func (t *deferredTracer) DroppedPacket(typ logging.PacketType, size logging.ByteCount, reason logging.PacketDropReason) {
+	t.queue(func() { t.tracer.DroppedPacket(typ, size, reason) })
+}
+
+// UpdatedMetrics copies the RTT stats, so that the tracer sees the values at the time the event was generated.
+func (t *deferredTracer) UpdatedMetrics(rttStats *logging.RTTStats, cwnd, bytesInFlight logging.ByteCount, packetsInFlight int) {
+	stats := *rttStats
+	t.queue(func() { t.tracer.UpdatedMetrics(&stats, cwnd, bytesInFlight, packetsInFlight) })
+}
+
+// UpdatedCongestionMetrics copies the RTT stats, so that the tracer sees the values at the time the event was generated.
+func (t *deferredTracer) UpdatedCongestionMetrics(rttStats *logging.RTTStats, cwnd, ssthresh, bytesInFlight logging.ByteCount) {
+	stats := *rttStats
+	t.queue(func() { t.tracer.UpdatedCongestionMetrics(&stats, cwnd, ssthresh, bytesInFlight) })
+}
+
+func (t *deferredTracer) AcknowledgedPacket(encLevel logging.EncryptionLevel, pn logging.PacketNumber) {
+	t.queue(func() { t.tracer.AcknowledgedPacket(encLevel, pn) })
+}
+
+func (t *deferredTracer) LostPacket(encLevel logging.EncryptionLevel, pn logging.PacketNumber, reason logging.PacketLossReason) {
+	t.queue(func() { t.tracer.LostPacket(encLevel, pn, reason) })
+}
+
+func (t *deferredTracer) UpdatedCongestionState(state logging.CongestionState) {
+	t.queue(func() { t.tracer.UpdatedCongestionState(state) })
+}
+
+func (t *deferredTracer) UpdatedCongestionControl(algorithm, hystart string) {
+	t.queue(func() { t.tracer.UpdatedCongestionControl(algorithm, hystart) })
+}
+
+func (t *deferredTracer) UpdatedPTOCount(value uint32) {
+	t.queue(func() { t.tracer.UpdatedPTOCount(value) })
+}
+
+func (t *deferredTracer) UpdatedMTU(mtu logging.ByteCount) {
+	t.queue(func() { t.tracer.UpdatedMTU(mtu) })
+}
+
+func (t *deferredTracer) UpdatedSpinBitRTT(rtt time.Duration) {
+	t.queue(func() { t.tracer.UpdatedSpinBitRTT(rtt) })
+}
+
+func (t *deferredTracer) UpdatedKeyFromTLS(encLevel logging.EncryptionLevel, pers logging.Perspective) {
+	t.queue(func() { t.tracer.UpdatedKeyFromTLS(encLevel, pers) })
+}
+
+func (t *deferredTracer) UpdatedKey(generation logging.KeyPhase, remote bool) {
+	t.queue(func() { t.tracer.UpdatedKey(generation, remote) })
+}
+
+func (t *deferredTracer) DroppedEncryptionLevel(encLevel logging.EncryptionLevel) {
+	t.queue(func() { t.tracer.DroppedEncryptionLevel(encLevel) })
+}
+
+func (t *deferredTracer) DroppedKey(generation logging.KeyPhase) {
+	t.queue(func() { t.tracer.DroppedKey(generation) })
+}
+
+func (t *deferredTracer) SetLossTimer(typ logging.TimerType, encLevel logging.EncryptionLevel, timeout time.Time) {
+	t.queue(func() { t.tracer.SetLossTimer(typ, encLevel, timeout) })
+}
+
+func (t *deferredTracer) LossTimerExpired(typ logging.TimerType, encLevel logging.EncryptionLevel) {
+	t.queue(func() { t.tracer.LossTimerExpired(typ, encLevel) })
+}
+
+func (t *deferredTracer) LossTimerCanceled() {
+	t.queue(func() { t.tracer.LossTimerCanceled() })
+}
+
+func (t *deferredTracer) Close() {
+	t.queue(func() { t.tracer.Close() })
+}
+
+func (t *deferredTracer) Debug(name, msg string) {
+	t.queue(func() { t.tracer.Debug(name, msg) })
+}
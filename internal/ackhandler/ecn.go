@@ -0,0 +1,156 @@
+package ackhandler
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+type ecnState uint8
+
+const (
+	ecnStateTesting ecnState = iota
+	ecnStateUnknown
+	ecnStateCapable
+	ecnStateFailed
+)
+
+// The number of packets that are sent with ECT(0) before we wait for the result of the validation.
+// See section 13.4.2 of RFC 9000.
+const numECNTestingPackets = 10
+
+// The ecnTracker performs the ECN validation of RFC 9000, section 13.4.2,
+// for packets sent in the application data packet number space.
+type ecnTracker struct {
+	state ecnState
+
+	numSentTesting, numLostTesting uint8
+	firstTestingPacket             protocol.PacketNumber
+	lastTestingPacket              protocol.PacketNumber
+
+	numSentECT0 int64
+	// the ECN counts reported by the peer in the last ACK frame
+	numAckedECT0, numAckedECT1, numAckedECNCE int64
+
+	logger utils.Logger
+}
+
+func newECNTracker(logger utils.Logger) *ecnTracker {
+	return &ecnTracker{
+		firstTestingPacket: protocol.InvalidPacketNumber,
+		lastTestingPacket:  protocol.InvalidPacketNumber,
+		logger:             logger,
+	}
+}
+
+// Mode returns the ECN codepoint to use for the next packet.
+func (e *ecnTracker) Mode() protocol.ECN {
+	switch e.state {
+	case ecnStateTesting, ecnStateCapable:
+		return protocol.ECT0
+	default:
+		return protocol.ECNNon
+	}
+}
+
+// SentPacket is called for every packet sent in the application data packet number space.
+// Only ack-eliciting packets count as testing packets, since we never learn about the fate of the other packets.
+func (e *ecnTracker) SentPacket(pn protocol.PacketNumber, ecn protocol.ECN, isAckEliciting bool) {
+	if ecn == protocol.ECNNon {
+		return
+	}
+	if ecn == protocol.ECT0 {
+		e.numSentECT0++
+	}
+	if e.state != ecnStateTesting || !isAckEliciting {
+		return
+	}
+	if e.numSentTesting == 0 {
+		e.firstTestingPacket = pn
+	}
+	e.lastTestingPacket = pn
+	e.numSentTesting++
+	if e.numSentTesting >= numECNTestingPackets {
+		e.logger.Debugf("Sent %d ECN testing packets. Waiting for the validation result.", e.numSentTesting)
+		e.state = ecnStateUnknown
+	}
+}
+
+// LostPacket is called when a packet is declared lost.
+// If all testing packets are lost, the path is probably dropping ECN-marked packets.
+func (e *ecnTracker) LostPacket(pn protocol.PacketNumber) {
+	if e.state != ecnStateTesting && e.state != ecnStateUnknown {
+		return
+	}
+	if !e.isTestingPacket(pn) {
+		return
+	}
+	e.numLostTesting++
+	if e.numLostTesting >= e.numSentTesting {
+		e.failValidation("all testing packets were lost")
+	}
+}
+
+// HandleNewlyAcked is called with the packets newly acknowledged by an ACK frame,
+// and the ECN counts contained in that ACK frame.
+// It returns true if the peer reported new ECN-CE marks.
+func (e *ecnTracker) HandleNewlyAcked(packets []*Packet, ect0, ect1, ecnce int64) (congested bool) {
+	if e.state == ecnStateFailed {
+		return false
+	}
+
+	// ECN counts must never decrease.
+	if ect0 < e.numAckedECT0 || ect1 < e.numAckedECT1 || ecnce < e.numAckedECNCE {
+		e.failValidation("ECN counts decreased")
+		return false
+	}
+	// We never send packets marked with ECT(1).
+	if ect1 > 0 {
+		e.failValidation("peer reported ECT(1) marks")
+		return false
+	}
+	// The peer can't have received more ECT(0) packets than we sent.
+	if ect0+ecnce > e.numSentECT0 {
+		e.failValidation("peer reported more ECN marks than packets sent")
+		return false
+	}
+
+	var newlyAckedECT0 int64
+	var ackedTestingPacket bool
+	for _, p := range packets {
+		if p.ECN == protocol.ECT0 {
+			newlyAckedECT0++
+		}
+		if e.isTestingPacket(p.PacketNumber) {
+			ackedTestingPacket = true
+		}
+	}
+	// Every newly acknowledged ECT(0) packet must be counted as either ECT(0) or ECN-CE.
+	// This also catches peers that don't report ECN counts at all.
+	if (ect0-e.numAckedECT0)+(ecnce-e.numAckedECNCE) < newlyAckedECT0 {
+		e.failValidation("ECN counts don't account for all newly acknowledged packets")
+		return false
+	}
+
+	congested = ecnce > e.numAckedECNCE
+	e.numAckedECT0 = ect0
+	e.numAckedECT1 = ect1
+	e.numAckedECNCE = ecnce
+
+	if ackedTestingPacket && (e.state == ecnStateTesting || e.state == ecnStateUnknown) {
+		e.logger.Debugf("ECN validation succeeded.")
+		e.state = ecnStateCapable
+	}
+	return congested
+}
+
+func (e *ecnTracker) isTestingPacket(pn protocol.PacketNumber) bool {
+	if e.firstTestingPacket == protocol.InvalidPacketNumber {
+		return false
+	}
+	return pn >= e.firstTestingPacket && pn <= e.lastTestingPacket
+}
+
+func (e *ecnTracker) failValidation(reason string) {
+	e.logger.Debugf("Disabling ECN: %s.", reason)
+	e.state = ecnStateFailed
+}
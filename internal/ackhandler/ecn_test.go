@@ -0,0 +1,128 @@
+package ackhandler
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ECN tracker", func() {
+	var e *ecnTracker
+
+	getAckedPackets := func(pns ...protocol.PacketNumber) []*Packet {
+		var packets []*Packet
+		for _, p := range pns {
+			packets = append(packets, &Packet{PacketNumber: p, ECN: protocol.ECT0})
+		}
+		return packets
+	}
+
+	sendTestingPackets := func() {
+		for i := 0; i < numECNTestingPackets; i++ {
+			Expect(e.Mode()).To(Equal(protocol.ECT0))
+			e.SentPacket(protocol.PacketNumber(i), protocol.ECT0, true)
+		}
+	}
+
+	BeforeEach(func() {
+		e = newECNTracker(utils.DefaultLogger)
+	})
+
+	It("sends testing packets, and waits for the validation result", func() {
+		sendTestingPackets()
+		Expect(e.Mode()).To(Equal(protocol.ECNNon))
+		Expect(e.state).To(Equal(ecnStateUnknown))
+	})
+
+	It("doesn't count non-ack-eliciting packets as testing packets", func() {
+		for i := 0; i < 2*numECNTestingPackets; i++ {
+			e.SentPacket(protocol.PacketNumber(i), protocol.ECT0, false)
+		}
+		Expect(e.state).To(Equal(ecnStateTesting))
+		Expect(e.Mode()).To(Equal(protocol.ECT0))
+	})
+
+	It("validates ECN", func() {
+		sendTestingPackets()
+		Expect(e.HandleNewlyAcked(getAckedPackets(0, 1, 2), 3, 0, 0)).To(BeFalse())
+		Expect(e.state).To(Equal(ecnStateCapable))
+		Expect(e.Mode()).To(Equal(protocol.ECT0))
+	})
+
+	It("validates ECN before all testing packets are sent", func() {
+		e.SentPacket(0, protocol.ECT0, true)
+		e.SentPacket(1, protocol.ECT0, true)
+		Expect(e.HandleNewlyAcked(getAckedPackets(0), 1, 0, 0)).To(BeFalse())
+		Expect(e.state).To(Equal(ecnStateCapable))
+	})
+
+	It("fails validation if the peer doesn't report ECN counts", func() {
+		sendTestingPackets()
+		Expect(e.HandleNewlyAcked(getAckedPackets(0, 1, 2), 0, 0, 0)).To(BeFalse())
+		Expect(e.state).To(Equal(ecnStateFailed))
+		Expect(e.Mode()).To(Equal(protocol.ECNNon))
+	})
+
+	It("fails validation if the ECN counts don't account for all newly acknowledged packets", func() {
+		sendTestingPackets()
+		Expect(e.HandleNewlyAcked(getAckedPackets(0, 1, 2), 1, 0, 1)).To(BeFalse())
+		Expect(e.state).To(Equal(ecnStateFailed))
+	})
+
+	It("fails validation if the peer reports ECT(1)", func() {
+		sendTestingPackets()
+		Expect(e.HandleNewlyAcked(getAckedPackets(0, 1, 2), 2, 1, 0)).To(BeFalse())
+		Expect(e.state).To(Equal(ecnStateFailed))
+	})
+
+	It("fails validation if the peer reports more marks than packets were sent", func() {
+		sendTestingPackets()
+		Expect(e.HandleNewlyAcked(getAckedPackets(0, 1, 2), numECNTestingPackets+1, 0, 0)).To(BeFalse())
+		Expect(e.state).To(Equal(ecnStateFailed))
+	})
+
+	It("fails validation if the ECN counts decrease", func() {
+		sendTestingPackets()
+		Expect(e.HandleNewlyAcked(getAckedPackets(0, 1, 2), 3, 0, 0)).To(BeFalse())
+		Expect(e.state).To(Equal(ecnStateCapable))
+		Expect(e.HandleNewlyAcked(getAckedPackets(3), 2, 0, 2)).To(BeFalse())
+		Expect(e.state).To(Equal(ecnStateFailed))
+	})
+
+	It("fails validation if all testing packets are lost", func() {
+		sendTestingPackets()
+		for i := 0; i < numECNTestingPackets-1; i++ {
+			e.LostPacket(protocol.PacketNumber(i))
+			Expect(e.state).To(Equal(ecnStateUnknown))
+		}
+		e.LostPacket(numECNTestingPackets - 1)
+		Expect(e.state).To(Equal(ecnStateFailed))
+	})
+
+	It("ignores the loss of packets sent after the testing phase", func() {
+		sendTestingPackets()
+		Expect(e.HandleNewlyAcked(getAckedPackets(0), 1, 0, 0)).To(BeFalse())
+		e.SentPacket(100, protocol.ECT0, true)
+		e.LostPacket(100)
+		Expect(e.state).To(Equal(ecnStateCapable))
+	})
+
+	It("reports new ECN-CE marks as a congestion event", func() {
+		sendTestingPackets()
+		Expect(e.HandleNewlyAcked(getAckedPackets(0, 1), 2, 0, 0)).To(BeFalse())
+		Expect(e.HandleNewlyAcked(getAckedPackets(2, 3), 3, 0, 1)).To(BeTrue())
+		// no new ECN-CE marks
+		Expect(e.HandleNewlyAcked(getAckedPackets(4), 4, 0, 1)).To(BeFalse())
+		Expect(e.HandleNewlyAcked(getAckedPackets(5, 6), 4, 0, 3)).To(BeTrue())
+		Expect(e.state).To(Equal(ecnStateCapable))
+	})
+
+	It("doesn't report congestion events after validation failed", func() {
+		sendTestingPackets()
+		Expect(e.HandleNewlyAcked(getAckedPackets(0), 0, 0, 0)).To(BeFalse())
+		Expect(e.state).To(Equal(ecnStateFailed))
+		Expect(e.HandleNewlyAcked(getAckedPackets(1), 0, 0, 1)).To(BeFalse())
+	})
+})
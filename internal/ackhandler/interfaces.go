@@ -3,8 +3,10 @@ package ackhandler
 import (
 	"time"
 
+	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
 )
 
 // A Packet is a packet
@@ -15,11 +17,13 @@ type Packet struct {
 	Length          protocol.ByteCount
 	EncryptionLevel protocol.EncryptionLevel
 	SendTime        time.Time
+	ECN             protocol.ECN // the ECN codepoint the packet was sent with
 
 	IsPathMTUProbePacket bool // We don't report the loss of Path MTU probe packets to the congestion controller.
 
 	includedInBytesInFlight bool
 	declaredLost            bool
+	lossDetected            bool // declared lost by the loss detection, as opposed to being declared lost when sending a PTO probe
 	skippedPacket           bool
 }
 
@@ -40,7 +44,13 @@ type SentPacketHandler interface {
 	TimeUntilSend() time.Time
 	// HasPacingBudget says if the pacer allows sending of a (full size) packet at this moment.
 	HasPacingBudget() bool
+	// OnAppLimited is called when there's no more data to send, although the SendMode allows sending.
+	// If the congestion window isn't full, the congestion controller treats the acknowledgements
+	// for the packets sent so far as application-limited.
+	OnAppLimited()
 	SetMaxDatagramSize(count protocol.ByteCount)
+	// ECNMode is the ECN codepoint that should be used for the next packet.
+	ECNMode() protocol.ECN
 
 	// only to be called once the handshake is complete
 	QueueProbePacket(protocol.EncryptionLevel) bool /* was a packet queued */
@@ -50,6 +60,61 @@ type SentPacketHandler interface {
 
 	GetLossDetectionTimeout() time.Time
 	OnLossDetectionTimeout() error
+
+	// OnConnectionMigration is called when the connection migrated to a new path.
+	// pathChanged is false if the network path is probably unchanged, e.g. after a NAT rebinding.
+	OnConnectionMigration(pathChanged bool)
+
+	// CongestionState, PacketStats, RTTStats, BytesInFlight, SetCongestionControl, SetSendRateLimit and SetMaxBytesInFlight
+	// may be called concurrently with all other methods.
+	CongestionState() CongestionState
+	PacketStats() PacketStats
+	RTTStats() RTTStats
+	BytesInFlight() protocol.ByteCount
+	SetCongestionControl(congestion.CongestionOptions)
+	// SetSendRateLimit limits the send rate, on top of the limits imposed by congestion control.
+	// A value of 0 removes the limit.
+	SetSendRateLimit(bytesPerSecond uint64)
+	// SetMaxBytesInFlight limits the number of bytes in flight, on top of the limit imposed by the congestion window.
+	// A value of 0 removes the limit.
+	SetMaxBytesInFlight(protocol.ByteCount)
+}
+
+// CongestionState is a snapshot of the state of the congestion controller
+type CongestionState struct {
+	// CongestionControl and Hystart are the names of the congestion control algorithm and the hystart variant.
+	CongestionControl string
+	Hystart           string
+	CongestionWindow  protocol.ByteCount
+	BytesInFlight     protocol.ByteCount
+	SmoothedRTT       time.Duration
+	MinRTT            time.Duration
+	Phase             logging.CongestionState
+	SpuriousLosses    uint64
+	// BandwidthEstimate is the bandwidth estimate of the congestion controller.
+	BandwidthEstimate congestion.Bandwidth
+}
+
+// PacketStats is a snapshot of the packet counters
+type PacketStats struct {
+	PacketsSent uint64
+	// PacketsLost is the number of packets declared lost by the loss detection.
+	// Path MTU probe packets are not counted.
+	PacketsLost uint64
+	// PacketsRetransmitted is the number of packets whose frames were queued for retransmission,
+	// because the packet was declared lost, or a probe packet had to be sent.
+	PacketsRetransmitted uint64
+	// LossRate is the fraction of packets that were declared lost,
+	// of all packets acknowledged or declared lost during the last one to two RTTs.
+	LossRate float64
+}
+
+// RTTStats is a snapshot of the RTT statistics
+type RTTStats struct {
+	SmoothedRTT time.Duration
+	RTTVar      time.Duration
+	MinRTT      time.Duration
+	LatestRTT   time.Duration
 }
 
 type sentPacketTracker interface {
@@ -65,4 +130,9 @@ type ReceivedPacketHandler interface {
 
 	GetAlarmTimeout() time.Time
 	GetAckFrame(encLevel protocol.EncryptionLevel, onlyIfQueued bool) *wire.AckFrame
+
+	// ReceivedAckFrequency and ReceivedImmediateAck implement the ACK frequency extension.
+	// They only apply to the application data packet number space.
+	ReceivedAckFrequency(*wire.AckFrequencyFrame)
+	ReceivedImmediateAck()
 }
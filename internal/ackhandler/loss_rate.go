@@ -0,0 +1,47 @@
+package ackhandler
+
+import "time"
+
+// The lossRateWindow calculates the loss rate over the last one to two RTTs.
+// It counts the packets that were acknowledged or declared lost in the current window,
+// which spans one RTT, as well as in the window before.
+type lossRateWindow struct {
+	start time.Time
+
+	numAcked, numLost         uint64
+	numPrevAcked, numPrevLost uint64
+}
+
+// update starts a new window, if the current window is older than rtt.
+func (w *lossRateWindow) update(now time.Time, rtt time.Duration) {
+	if w.start.IsZero() {
+		w.start = now
+		return
+	}
+	if rtt == 0 || now.Sub(w.start) < rtt {
+		return
+	}
+	if now.Sub(w.start) < 2*rtt {
+		w.numPrevAcked, w.numPrevLost = w.numAcked, w.numLost
+	} else {
+		// no packets were acknowledged or lost during the last RTT
+		w.numPrevAcked, w.numPrevLost = 0, 0
+	}
+	w.numAcked, w.numLost = 0, 0
+	w.start = now
+}
+
+func (w *lossRateWindow) Acked() { w.numAcked++ }
+func (w *lossRateWindow) Lost()  { w.numLost++ }
+
+// LossRate is the fraction of packets that were declared lost.
+// It first moves the window forward, so that the loss rate decays when no packets are acknowledged or lost.
+func (w *lossRateWindow) LossRate(now time.Time, rtt time.Duration) float64 {
+	w.update(now, rtt)
+	lost := w.numLost + w.numPrevLost
+	total := w.numAcked + w.numPrevAcked + lost
+	if total == 0 {
+		return 0
+	}
+	return float64(lost) / float64(total)
+}
@@ -0,0 +1,65 @@
+package ackhandler
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Loss Rate Window", func() {
+	const rtt = 100 * time.Millisecond
+	var (
+		w   *lossRateWindow
+		now time.Time
+	)
+
+	BeforeEach(func() {
+		w = &lossRateWindow{}
+		now = time.Now()
+		w.update(now, rtt)
+	})
+
+	It("returns 0 if no packets were acknowledged or lost", func() {
+		Expect(w.LossRate(now, rtt)).To(BeZero())
+	})
+
+	It("calculates the loss rate", func() {
+		for i := 0; i < 3; i++ {
+			w.Acked()
+		}
+		w.Lost()
+		Expect(w.LossRate(now, rtt)).To(Equal(0.25))
+	})
+
+	It("includes the previous window", func() {
+		w.Lost()
+		w.update(now.Add(rtt), rtt)
+		Expect(w.LossRate(now.Add(rtt), rtt)).To(Equal(1.0))
+		w.Acked()
+		Expect(w.LossRate(now.Add(rtt), rtt)).To(Equal(0.5))
+		w.update(now.Add(2*rtt), rtt)
+		Expect(w.LossRate(now.Add(2*rtt), rtt)).To(BeEquivalentTo(0))
+	})
+
+	It("discards the previous window if no packets were acknowledged or lost for more than one RTT", func() {
+		w.Lost()
+		w.update(now.Add(rtt*5/2), rtt)
+		Expect(w.LossRate(now.Add(rtt*5/2), rtt)).To(BeZero())
+	})
+
+	It("decays when it is read while no packets are acknowledged or lost", func() {
+		w.Lost()
+		Expect(w.LossRate(now.Add(rtt/2), rtt)).To(Equal(1.0))
+		Expect(w.LossRate(now.Add(rtt), rtt)).To(Equal(1.0))
+		Expect(w.LossRate(now.Add(2*rtt), rtt)).To(BeZero())
+	})
+
+	It("doesn't start a new window before one RTT has passed", func() {
+		w.Lost()
+		w.update(now.Add(rtt-time.Millisecond), rtt)
+		w.Acked()
+		w.update(now.Add(3*rtt), rtt)
+		Expect(w.LossRate(now.Add(3*rtt), rtt)).To(BeZero())
+	})
+})
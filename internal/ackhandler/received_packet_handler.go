@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
@@ -22,16 +23,18 @@ type receivedPacketHandler struct {
 var _ ReceivedPacketHandler = &receivedPacketHandler{}
 
 func newReceivedPacketHandler(
+	clock congestion.Clock,
 	sentPackets sentPacketTracker,
 	rttStats *utils.RTTStats,
+	maxAckDelay time.Duration,
 	logger utils.Logger,
 	version protocol.VersionNumber,
 ) ReceivedPacketHandler {
 	return &receivedPacketHandler{
 		sentPackets:      sentPackets,
-		initialPackets:   newReceivedPacketTracker(rttStats, logger, version),
-		handshakePackets: newReceivedPacketTracker(rttStats, logger, version),
-		appDataPackets:   newReceivedPacketTracker(rttStats, logger, version),
+		initialPackets:   newReceivedPacketTracker(clock, rttStats, maxAckDelay, logger, version),
+		handshakePackets: newReceivedPacketTracker(clock, rttStats, maxAckDelay, logger, version),
+		appDataPackets:   newReceivedPacketTracker(clock, rttStats, maxAckDelay, logger, version),
 		lowest1RTTPacket: protocol.InvalidPacketNumber,
 	}
 }
@@ -119,6 +122,14 @@ func (h *receivedPacketHandler) GetAckFrame(encLevel protocol.EncryptionLevel, o
 	return ack
 }
 
+func (h *receivedPacketHandler) ReceivedAckFrequency(f *wire.AckFrequencyFrame) {
+	h.appDataPackets.UpdateAckFrequency(f)
+}
+
+func (h *receivedPacketHandler) ReceivedImmediateAck() {
+	h.appDataPackets.QueueImmediateAck()
+}
+
 func (h *receivedPacketHandler) IsPotentiallyDuplicate(pn protocol.PacketNumber, encLevel protocol.EncryptionLevel) bool {
 	switch encLevel {
 	case protocol.EncryptionInitial:
@@ -5,6 +5,7 @@ import (
 
 	"github.com/golang/mock/gomock"
 
+	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
@@ -20,8 +21,10 @@ var _ = Describe("Received Packet Handler", func() {
 	BeforeEach(func() {
 		sentPackets = NewMockSentPacketTracker(mockCtrl)
 		handler = newReceivedPacketHandler(
+			congestion.DefaultClock{},
 			sentPackets,
 			&utils.RTTStats{},
+			protocol.MaxAckDelay,
 			utils.DefaultLogger,
 			protocol.VersionWhatever,
 		)
@@ -165,4 +168,25 @@ var _ = Describe("Received Packet Handler", func() {
 		Expect(handler.ReceivedPacket(4, protocol.ECNNon, protocol.Encryption1RTT, sendTime, true)).To(Succeed())
 		Expect(handler.IsPotentiallyDuplicate(4, protocol.Encryption1RTT)).To(BeTrue())
 	})
+
+	It("passes ACK_FREQUENCY and IMMEDIATE_ACK to the application data packet number space", func() {
+		sentPackets.EXPECT().ReceivedPacket(protocol.Encryption1RTT).Times(3)
+		sentPackets.EXPECT().GetLowestPacketNotConfirmedAcked().AnyTimes()
+		now := time.Now()
+		// the first packet is always acknowledged
+		Expect(handler.ReceivedPacket(1, protocol.ECNNon, protocol.Encryption1RTT, now, true)).To(Succeed())
+		Expect(handler.GetAckFrame(protocol.Encryption1RTT, true)).ToNot(BeNil())
+		handler.ReceivedAckFrequency(&wire.AckFrequencyFrame{
+			AckElicitingThreshold: 10,
+			RequestMaxAckDelay:    protocol.MaxAckDelay,
+			ReorderingThreshold:   1,
+		})
+		Expect(handler.ReceivedPacket(2, protocol.ECNNon, protocol.Encryption1RTT, now, true)).To(Succeed())
+		Expect(handler.ReceivedPacket(3, protocol.ECNNon, protocol.Encryption1RTT, now, true)).To(Succeed())
+		Expect(handler.GetAckFrame(protocol.Encryption1RTT, true)).To(BeNil())
+		handler.ReceivedImmediateAck()
+		ack := handler.GetAckFrame(protocol.Encryption1RTT, true)
+		Expect(ack).ToNot(BeNil())
+		Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(3)))
+	})
 })
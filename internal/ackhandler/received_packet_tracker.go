@@ -1,8 +1,10 @@
 package ackhandler
 
 import (
+	"math"
 	"time"
 
+	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
@@ -11,6 +13,10 @@ import (
 // number of ack-eliciting packets received before sending an ack.
 const packetsBeforeAck = 2
 
+// The reordering threshold used if the peer didn't request a different one (using an ACK_FREQUENCY frame).
+// This means that we instantly acknowledge a packet if it leaves a gap.
+const defaultReorderingThreshold = 1
+
 type receivedPacketTracker struct {
 	largestObserved             protocol.PacketNumber
 	ignoreBelow                 protocol.PacketNumber
@@ -21,6 +27,7 @@ type receivedPacketTracker struct {
 
 	maxAckDelay time.Duration
 	rttStats    *utils.RTTStats
+	clock       congestion.Clock
 
 	hasNewAck bool // true as soon as we received an ack-eliciting new packet
 	ackQueued bool // true once we received more than 2 (or later in the connection 10) ack-eliciting packets
@@ -29,22 +36,34 @@ type receivedPacketTracker struct {
 	ackAlarm                                time.Time
 	lastAck                                 *wire.AckFrame
 
+	// Parameters that can be changed by the peer using the ACK frequency extension.
+	packetsBeforeAck    int
+	reorderingThreshold protocol.PacketNumber // 0 means that reordering is ignored
+	// The lowest sequence number of an ACK_FREQUENCY frame that we still accept.
+	// ACK_FREQUENCY frames with lower sequence numbers are outdated.
+	nextAckFrequencySequenceNumber uint64
+
 	logger utils.Logger
 
 	version protocol.VersionNumber
 }
 
 func newReceivedPacketTracker(
+	clock congestion.Clock,
 	rttStats *utils.RTTStats,
+	maxAckDelay time.Duration,
 	logger utils.Logger,
 	version protocol.VersionNumber,
 ) *receivedPacketTracker {
 	return &receivedPacketTracker{
-		packetHistory: newReceivedPacketHistory(),
-		maxAckDelay:   protocol.MaxAckDelay,
-		rttStats:      rttStats,
-		logger:        logger,
-		version:       version,
+		clock:               clock,
+		packetHistory:       newReceivedPacketHistory(),
+		maxAckDelay:         maxAckDelay,
+		packetsBeforeAck:    packetsBeforeAck,
+		reorderingThreshold: defaultReorderingThreshold,
+		rttStats:            rttStats,
+		logger:              logger,
+		version:             version,
 	}
 }
 
@@ -97,12 +116,40 @@ func (h *receivedPacketTracker) isMissing(p protocol.PacketNumber) bool {
 	return p < h.lastAck.LargestAcked() && !h.lastAck.AcksPacket(p)
 }
 
+// UpdateAckFrequency applies the parameters requested by the peer in an ACK_FREQUENCY frame.
+func (h *receivedPacketTracker) UpdateAckFrequency(f *wire.AckFrequencyFrame) {
+	if f.SequenceNumber < h.nextAckFrequencySequenceNumber {
+		if h.logger.Debug() {
+			h.logger.Debugf("\tIgnoring outdated ACK_FREQUENCY frame (sequence number %d).", f.SequenceNumber)
+		}
+		return
+	}
+	h.nextAckFrequencySequenceNumber = f.SequenceNumber + 1
+	// The Ack-Eliciting Threshold is the number of ack-eliciting packets we may receive without sending an ACK.
+	h.packetsBeforeAck = int(utils.MinUint64(f.AckElicitingThreshold, math.MaxInt32-1)) + 1
+	h.maxAckDelay = f.RequestMaxAckDelay
+	h.reorderingThreshold = f.ReorderingThreshold
+	if h.logger.Debug() {
+		h.logger.Debugf("\tUpdated ACK frequency: sending an ACK every %d ack-eliciting packets, max ack delay %s, reordering threshold %d.", h.packetsBeforeAck, h.maxAckDelay, h.reorderingThreshold)
+	}
+}
+
+// QueueImmediateAck queues an ACK, as requested by the peer in an IMMEDIATE_ACK frame.
+func (h *receivedPacketTracker) QueueImmediateAck() {
+	if !h.ackQueued && h.logger.Debug() {
+		h.logger.Debugf("\tQueueing ACK because an IMMEDIATE_ACK frame was received.")
+	}
+	h.ackQueued = true
+	h.ackAlarm = time.Time{}
+}
+
 func (h *receivedPacketTracker) hasNewMissingPackets() bool {
-	if h.lastAck == nil {
+	if h.lastAck == nil || h.reorderingThreshold == 0 {
 		return false
 	}
 	highestRange := h.packetHistory.GetHighestAckRange()
-	return highestRange.Smallest > h.lastAck.LargestAcked()+1 && highestRange.Len() == 1
+	// Only report the gap once, as soon as reorderingThreshold packets were received after it.
+	return highestRange.Smallest > h.lastAck.LargestAcked()+1 && highestRange.Len() == h.reorderingThreshold
 }
 
 // maybeQueueAck queues an ACK, if necessary.
@@ -125,17 +172,18 @@ func (h *receivedPacketTracker) maybeQueueAck(pn protocol.PacketNumber, rcvTime
 	// Send an ACK if this packet was reported missing in an ACK sent before.
 	// Ack decimation with reordering relies on the timer to send an ACK, but if
 	// missing packets we reported in the previous ack, send an ACK immediately.
-	if wasMissing {
+	// If the peer asked us to ignore reordering, we don't do that.
+	if wasMissing && h.reorderingThreshold != 0 {
 		if h.logger.Debug() {
 			h.logger.Debugf("\tQueueing ACK because packet %d was missing before.", pn)
 		}
 		h.ackQueued = true
 	}
 
-	// send an ACK every 2 ack-eliciting packets
-	if h.ackElicitingPacketsReceivedSinceLastAck >= packetsBeforeAck {
+	// send an ACK every 2 ack-eliciting packets (unless the peer requested a different threshold)
+	if h.ackElicitingPacketsReceivedSinceLastAck >= h.packetsBeforeAck {
 		if h.logger.Debug() {
-			h.logger.Debugf("\tQueueing ACK because packet %d packets were received after the last ACK (using initial threshold: %d).", h.ackElicitingPacketsReceivedSinceLastAck, packetsBeforeAck)
+			h.logger.Debugf("\tQueueing ACK because packet %d packets were received after the last ACK (using threshold: %d).", h.ackElicitingPacketsReceivedSinceLastAck, h.packetsBeforeAck)
 		}
 		h.ackQueued = true
 	} else if h.ackAlarm.IsZero() {
@@ -161,7 +209,7 @@ func (h *receivedPacketTracker) GetAckFrame(onlyIfQueued bool) *wire.AckFrame {
 	if !h.hasNewAck {
 		return nil
 	}
-	now := time.Now()
+	now := h.clock.Now()
 	if onlyIfQueued {
 		if !h.ackQueued && (h.ackAlarm.IsZero() || h.ackAlarm.After(now)) {
 			return nil
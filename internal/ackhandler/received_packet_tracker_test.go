@@ -3,6 +3,7 @@ package ackhandler
 import (
 	"time"
 
+	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
@@ -19,7 +20,7 @@ var _ = Describe("Received Packet Tracker", func() {
 
 	BeforeEach(func() {
 		rttStats = &utils.RTTStats{}
-		tracker = newReceivedPacketTracker(rttStats, utils.DefaultLogger, protocol.VersionWhatever)
+		tracker = newReceivedPacketTracker(congestion.DefaultClock{}, rttStats, protocol.MaxAckDelay, utils.DefaultLogger, protocol.VersionWhatever)
 	})
 
 	Context("accepting packets", func() {
@@ -126,6 +127,25 @@ var _ = Describe("Received Packet Tracker", func() {
 				Expect(tracker.GetAlarmTimeout()).To(Equal(rcvTime.Add(protocol.MaxAckDelay)))
 			})
 
+			It("delays the ACK up to the configured max ack delay", func() {
+				clock := mockClock(time.Now())
+				tracker = newReceivedPacketTracker(&clock, rttStats, 50*time.Millisecond, utils.DefaultLogger, protocol.VersionWhatever)
+				tracker.ReceivedPacket(1, protocol.ECNNon, clock.Now(), true)
+				Expect(tracker.GetAckFrame(true)).ToNot(BeNil())
+				// a single ack-eliciting packet is below the threshold of 2 packets
+				rcvTime := clock.Now()
+				tracker.ReceivedPacket(2, protocol.ECNNon, rcvTime, true)
+				Expect(tracker.ackQueued).To(BeFalse())
+				Expect(tracker.GetAlarmTimeout()).To(Equal(rcvTime.Add(50 * time.Millisecond)))
+				clock.Advance(protocol.MaxAckDelay)
+				Expect(tracker.GetAckFrame(true)).To(BeNil())
+				clock.Advance(25 * time.Millisecond)
+				ack := tracker.GetAckFrame(true)
+				Expect(ack).ToNot(BeNil())
+				Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(2)))
+				Expect(ack.DelayTime).To(Equal(50 * time.Millisecond))
+			})
+
 			It("queues an ACK if it was reported missing before", func() {
 				receiveAndAck10Packets()
 				tracker.ReceivedPacket(11, protocol.ECNNon, time.Now(), true)
@@ -191,6 +211,136 @@ var _ = Describe("Received Packet Tracker", func() {
 			})
 		})
 
+		Context("ACK frequency", func() {
+			receiveAndAck10Packets := func() {
+				for i := 1; i <= 10; i++ {
+					tracker.ReceivedPacket(protocol.PacketNumber(i), protocol.ECNNon, time.Time{}, true)
+				}
+				Expect(tracker.GetAckFrame(true)).ToNot(BeNil())
+				Expect(tracker.ackQueued).To(BeFalse())
+			}
+
+			It("uses the requested ack-eliciting threshold", func() {
+				receiveAndAck10Packets()
+				tracker.UpdateAckFrequency(&wire.AckFrequencyFrame{
+					AckElicitingThreshold: 4,
+					RequestMaxAckDelay:    protocol.MaxAckDelay,
+					ReorderingThreshold:   1,
+				})
+				p := protocol.PacketNumber(11)
+				for i := 0; i < 5; i++ {
+					for j := 0; j < 4; j++ {
+						tracker.ReceivedPacket(p, protocol.ECNNon, time.Now(), true)
+						Expect(tracker.ackQueued).To(BeFalse())
+						p++
+					}
+					tracker.ReceivedPacket(p, protocol.ECNNon, time.Now(), true)
+					Expect(tracker.ackQueued).To(BeTrue())
+					p++
+					Expect(tracker.GetAckFrame(true)).ToNot(BeNil())
+				}
+			})
+
+			It("acknowledges every ack-eliciting packet, if the threshold is 0", func() {
+				receiveAndAck10Packets()
+				tracker.UpdateAckFrequency(&wire.AckFrequencyFrame{
+					RequestMaxAckDelay:  protocol.MaxAckDelay,
+					ReorderingThreshold: 1,
+				})
+				for p := protocol.PacketNumber(11); p < 20; p++ {
+					tracker.ReceivedPacket(p, protocol.ECNNon, time.Now(), true)
+					Expect(tracker.ackQueued).To(BeTrue())
+					Expect(tracker.GetAckFrame(true)).ToNot(BeNil())
+				}
+			})
+
+			It("uses the requested max ack delay", func() {
+				receiveAndAck10Packets()
+				tracker.UpdateAckFrequency(&wire.AckFrequencyFrame{
+					AckElicitingThreshold: 10,
+					RequestMaxAckDelay:    100 * time.Millisecond,
+					ReorderingThreshold:   1,
+				})
+				rcvTime := time.Now()
+				tracker.ReceivedPacket(11, protocol.ECNNon, rcvTime, true)
+				Expect(tracker.GetAlarmTimeout()).To(Equal(rcvTime.Add(100 * time.Millisecond)))
+			})
+
+			It("ignores outdated ACK_FREQUENCY frames", func() {
+				tracker.UpdateAckFrequency(&wire.AckFrequencyFrame{
+					SequenceNumber:        5,
+					AckElicitingThreshold: 10,
+					RequestMaxAckDelay:    100 * time.Millisecond,
+					ReorderingThreshold:   3,
+				})
+				tracker.UpdateAckFrequency(&wire.AckFrequencyFrame{
+					SequenceNumber:        4,
+					AckElicitingThreshold: 1,
+					RequestMaxAckDelay:    10 * time.Millisecond,
+					ReorderingThreshold:   1,
+				})
+				Expect(tracker.packetsBeforeAck).To(Equal(11))
+				Expect(tracker.maxAckDelay).To(Equal(100 * time.Millisecond))
+				Expect(tracker.reorderingThreshold).To(Equal(protocol.PacketNumber(3)))
+				tracker.UpdateAckFrequency(&wire.AckFrequencyFrame{
+					SequenceNumber:        6,
+					AckElicitingThreshold: 1,
+					RequestMaxAckDelay:    10 * time.Millisecond,
+					ReorderingThreshold:   1,
+				})
+				Expect(tracker.packetsBeforeAck).To(Equal(2))
+				Expect(tracker.maxAckDelay).To(Equal(10 * time.Millisecond))
+				Expect(tracker.reorderingThreshold).To(Equal(protocol.PacketNumber(1)))
+			})
+
+			It("ignores reordering, if the reordering threshold is 0", func() {
+				receiveAndAck10Packets()
+				tracker.UpdateAckFrequency(&wire.AckFrequencyFrame{
+					AckElicitingThreshold: 10,
+					RequestMaxAckDelay:    protocol.MaxAckDelay,
+					ReorderingThreshold:   0,
+				})
+				// 11 is missing
+				tracker.ReceivedPacket(12, protocol.ECNNon, time.Now(), true)
+				tracker.ReceivedPacket(13, protocol.ECNNon, time.Now(), true)
+				Expect(tracker.ackQueued).To(BeFalse())
+				ack := tracker.GetAckFrame(false) // ACK: 1-10, 12-13
+				Expect(ack.HasMissingRanges()).To(BeTrue())
+				// receiving the missing packet doesn't trigger an ACK either
+				tracker.ReceivedPacket(11, protocol.ECNNon, time.Now(), true)
+				Expect(tracker.ackQueued).To(BeFalse())
+			})
+
+			It("uses the requested reordering threshold", func() {
+				receiveAndAck10Packets()
+				tracker.UpdateAckFrequency(&wire.AckFrequencyFrame{
+					AckElicitingThreshold: 10,
+					RequestMaxAckDelay:    protocol.MaxAckDelay,
+					ReorderingThreshold:   3,
+				})
+				// 11 is missing
+				tracker.ReceivedPacket(12, protocol.ECNNon, time.Now(), true)
+				Expect(tracker.ackQueued).To(BeFalse())
+				tracker.ReceivedPacket(13, protocol.ECNNon, time.Now(), true)
+				Expect(tracker.ackQueued).To(BeFalse())
+				tracker.ReceivedPacket(14, protocol.ECNNon, time.Now(), true)
+				Expect(tracker.ackQueued).To(BeTrue())
+				ack := tracker.GetAckFrame(true)
+				Expect(ack.AckRanges).To(Equal([]wire.AckRange{{Smallest: 12, Largest: 14}, {Smallest: 1, Largest: 10}}))
+			})
+
+			It("queues an ACK when receiving an IMMEDIATE_ACK frame", func() {
+				receiveAndAck10Packets()
+				tracker.ReceivedPacket(11, protocol.ECNNon, time.Now(), true)
+				Expect(tracker.ackQueued).To(BeFalse())
+				Expect(tracker.GetAlarmTimeout()).ToNot(BeZero())
+				tracker.QueueImmediateAck()
+				Expect(tracker.ackQueued).To(BeTrue())
+				Expect(tracker.GetAlarmTimeout()).To(BeZero())
+				Expect(tracker.GetAckFrame(true)).ToNot(BeNil())
+			})
+		})
+
 		Context("ACK generation", func() {
 			It("generates an ACK for an ack-eliciting packet, if no ACK is queued yet", func() {
 				tracker.ReceivedPacket(1, protocol.ECNNon, time.Now(), true)
@@ -3,6 +3,7 @@ package ackhandler
 import (
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/congestion"
@@ -17,14 +18,16 @@ const (
 	// Maximum reordering in time space before time based loss detection considers a packet lost.
 	// Specified as an RTT multiplier.
 	timeThreshold = 9.0 / 8
-	// Maximum reordering in packets before packet threshold loss detection considers a packet lost.
-	packetThreshold = 3
-	// Before validating the client's address, the server won't send more than 3x bytes than it received.
-	amplificationFactor = 3
 	// We use Retry packets to derive an RTT estimate. Make sure we don't set the RTT to a super low value yet.
 	minRTTAfterRetry = 5 * time.Millisecond
 )
 
+// AmplificationFactor is the factor by which the server may exceed the number of bytes received,
+// before it validated the client's address.
+// It's a package-level variable to allow modifying it for testing purposes.
+// Values smaller than 3 (the factor required by RFC 9000) are ignored.
+var AmplificationFactor protocol.ByteCount = protocol.AmplificationFactor
+
 type packetNumberSpace struct {
 	history *sentPacketHistory
 	pns     packetNumberGenerator
@@ -52,6 +55,14 @@ func newPacketNumberSpace(initialPN protocol.PacketNumber, skipPNs bool, rttStat
 }
 
 type sentPacketHandler struct {
+	// mutex protects the congestion controller, bytesInFlight and the packet counters.
+	// Apart from CongestionState, PacketStats, BytesInFlight, SetCongestionControl, SetSendRateLimit and SetMaxBytesInFlight,
+	// all methods are only called from the session's run loop.
+	// Every method that accesses the congestion controller therefore has to hold the mutex,
+	// since the controller might be swapped out concurrently.
+	// Methods release the mutex using unlock, which passes the queued trace events to the tracer.
+	mutex sync.Mutex
+
 	initialPackets   *packetNumberSpace
 	handshakePackets *packetNumberSpace
 	appDataPackets   *packetNumberSpace
@@ -61,6 +72,7 @@ type sentPacketHandler struct {
 	peerCompletedAddressValidation bool
 	bytesReceived                  protocol.ByteCount
 	bytesSent                      protocol.ByteCount
+	amplificationFactor            protocol.ByteCount
 	// Have we validated the peer's address yet?
 	// Always true for the client.
 	peerAddressValidated bool
@@ -74,12 +86,34 @@ type sentPacketHandler struct {
 	lowestNotConfirmedAcked protocol.PacketNumber
 
 	ackedPackets []*Packet // to avoid allocations in detectAndRemoveAckedPackets
+	ackedFrames  []Frame   // frames of newly acknowledged packets, their OnAcked callbacks are called after releasing the mutex
 
 	bytesInFlight protocol.ByteCount
-
-	congestion congestion.SendAlgorithmWithDebugInfos
-	rttStats   *utils.RTTStats
-
+	// The number of packets that were declared lost by the loss detection, but were acknowledged later.
+	spuriousLosses uint64
+	// packet counters, reported by PacketStats
+	packetsSent          uint64
+	packetsLost          uint64
+	packetsRetransmitted uint64
+	lossRate             lossRateWindow
+
+	maxDatagramSize protocol.ByteCount
+	congestion      congestion.ExtendedSendAlgorithm
+	rttStats        *utils.RTTStats
+	// used to create a fresh congestion controller when the connection migrates to a new path
+	congestionOptions    congestion.CongestionOptions
+	newCongestionControl congestion.SendAlgorithmFactory
+	// nil if the application didn't limit the send rate
+	rateLimiter *congestion.RateLimiter
+	// 0 if the application didn't limit the number of bytes in flight
+	maxBytesInFlight protocol.ByteCount
+
+	// The PTO is multiplied by this value.
+	ptoMultiplier float64
+	// Maximum reordering in packets before packet threshold loss detection considers a packet lost.
+	packetThreshold protocol.PacketNumber
+	// nil if ECN is disabled
+	ecnTracker *ecnTracker
 	// The number of times a PTO has been sent without receiving an ack.
 	ptoCount uint32
 	ptoMode  SendMode
@@ -89,10 +123,16 @@ type sentPacketHandler struct {
 
 	// The alarm timeout
 	alarm time.Time
+	clock congestion.Clock
 
 	perspective protocol.Perspective
 
-	tracer logging.ConnectionTracer
+	// tracer is the deferredTracer if tracing is enabled, nil otherwise.
+	// Events are queued while holding the mutex, and passed to the tracer by unlock.
+	tracer              logging.ConnectionTracer
+	deferredTracer      *deferredTracer
+	flushingTraceEvents bool // set while unlock passes the queued events to the tracer
+
 	logger utils.Logger
 }
 
@@ -102,36 +142,93 @@ var (
 )
 
 func newSentPacketHandler(
+	clock congestion.Clock,
 	initialPN protocol.PacketNumber,
 	initialMaxDatagramSize protocol.ByteCount,
 	rttStats *utils.RTTStats,
 	pers protocol.Perspective,
+	ptoMultiplier float64,
+	packetThreshold protocol.PacketNumber,
+	enableECN bool,
 	congestionOptions congestion.CongestionOptions,
+	newCongestionControl congestion.SendAlgorithmFactory,
 	tracer logging.ConnectionTracer,
 	logger utils.Logger,
 ) *sentPacketHandler {
-	congestionHandler := congestion.NewCongestionHandler(
-		rttStats,
-		initialMaxDatagramSize,
-		congestionOptions,
-		tracer,
-	)
-
-	return &sentPacketHandler{
+	h := &sentPacketHandler{
+		clock:                          clock,
 		peerCompletedAddressValidation: pers == protocol.PerspectiveServer,
 		peerAddressValidated:           pers == protocol.PerspectiveClient,
+		amplificationFactor:            utils.MaxByteCount(AmplificationFactor, protocol.AmplificationFactor),
 		initialPackets:                 newPacketNumberSpace(initialPN, false, rttStats),
 		handshakePackets:               newPacketNumberSpace(0, false, rttStats),
 		appDataPackets:                 newPacketNumberSpace(0, true, rttStats),
 		rttStats:                       rttStats,
-		congestion:                     congestionHandler,
+		maxDatagramSize:                initialMaxDatagramSize,
+		ptoMultiplier:                  ptoMultiplier,
+		packetThreshold:                packetThreshold,
+		congestionOptions:              congestionOptions,
+		newCongestionControl:           newCongestionControl,
 		perspective:                    pers,
-		tracer:                         tracer,
 		logger:                         logger,
 	}
+	if tracer != nil {
+		h.deferredTracer = newDeferredTracer(tracer)
+		h.tracer = h.deferredTracer
+	}
+	if enableECN {
+		h.ecnTracker = newECNTracker(logger)
+	}
+	h.mutex.Lock()
+	h.congestion = h.newCongestionController()
+	h.traceCongestionControl()
+	h.unlock()
+	return h
+}
+
+// unlock releases the mutex, and then passes the events queued in the meantime to the tracer.
+// If another goroutine is currently passing events to the tracer, it also takes care of the events queued by this call.
+// This preserves the order of the events, and allows the tracer to call back into the sentPacketHandler.
+func (h *sentPacketHandler) unlock() {
+	if h.deferredTracer == nil || h.flushingTraceEvents {
+		h.mutex.Unlock()
+		return
+	}
+	h.flushingTraceEvents = true
+	for {
+		events := h.deferredTracer.PopEvents()
+		if len(events) == 0 {
+			break
+		}
+		h.mutex.Unlock()
+		for _, e := range events {
+			e()
+		}
+		h.mutex.Lock()
+	}
+	h.flushingTraceEvents = false
+	h.mutex.Unlock()
+}
+
+func (h *sentPacketHandler) newCongestionController() congestion.ExtendedSendAlgorithm {
+	if h.newCongestionControl != nil {
+		return congestion.Extend(h.newCongestionControl(h.rttStats, h.maxDatagramSize, h.tracer))
+	}
+	return congestion.NewCongestionHandlerWithClock(h.clock, h.rttStats, h.maxDatagramSize, h.congestionOptions, h.tracer)
+}
+
+func (h *sentPacketHandler) traceCongestionControl() {
+	if h.tracer == nil {
+		return
+	}
+	algorithm, hystart := h.congestion.ControlType()
+	h.tracer.UpdatedCongestionControl(algorithm.String(), hystart.String())
 }
 
 func (h *sentPacketHandler) DropPackets(encLevel protocol.EncryptionLevel) {
+	h.mutex.Lock()
+	defer h.unlock()
+
 	if h.perspective == protocol.PerspectiveClient && encLevel == protocol.EncryptionInitial {
 		// This function is called when the crypto setup seals a Handshake packet.
 		// If this Handshake packet is coalesced behind an Initial packet, we would drop the Initial packet number space
@@ -198,6 +295,9 @@ func (h *sentPacketHandler) dropPackets(encLevel protocol.EncryptionLevel) {
 }
 
 func (h *sentPacketHandler) ReceivedBytes(n protocol.ByteCount) {
+	h.mutex.Lock()
+	defer h.unlock()
+
 	wasAmplificationLimit := h.isAmplificationLimited()
 	h.bytesReceived += n
 	if wasAmplificationLimit && !h.isAmplificationLimited() {
@@ -206,6 +306,9 @@ func (h *sentPacketHandler) ReceivedBytes(n protocol.ByteCount) {
 }
 
 func (h *sentPacketHandler) ReceivedPacket(l protocol.EncryptionLevel) {
+	h.mutex.Lock()
+	defer h.unlock()
+
 	if h.perspective == protocol.PerspectiveServer && l == protocol.EncryptionHandshake && !h.peerAddressValidated {
 		h.peerAddressValidated = true
 		h.setLossDetectionTimer()
@@ -224,13 +327,20 @@ func (h *sentPacketHandler) packetsInFlight() int {
 }
 
 func (h *sentPacketHandler) SentPacket(packet *Packet) {
+	h.mutex.Lock()
+	defer h.unlock()
+
 	h.bytesSent += packet.Length
+	h.packetsSent++
 	// For the client, drop the Initial packet number space when the first Handshake packet is sent.
 	if h.perspective == protocol.PerspectiveClient && packet.EncryptionLevel == protocol.EncryptionHandshake && h.initialPackets != nil {
 		h.dropPackets(protocol.EncryptionInitial)
 	}
 	isAckEliciting := h.sentPacketImpl(packet)
 	h.getPacketNumberSpace(packet.EncryptionLevel).history.SentPacket(packet, isAckEliciting)
+	if h.ecnTracker != nil && packet.EncryptionLevel == protocol.Encryption1RTT {
+		h.ecnTracker.SentPacket(packet.PacketNumber, packet.ECN, isAckEliciting)
+	}
 	if h.tracer != nil && isAckEliciting {
 		h.tracer.UpdatedMetrics(h.rttStats, h.congestion.GetCongestionWindow(), h.bytesInFlight, h.packetsInFlight())
 	}
@@ -273,11 +383,28 @@ func (h *sentPacketHandler) sentPacketImpl(packet *Packet) bool /* is ack-elicit
 		}
 	}
 	h.congestion.OnPacketSent(packet.SendTime, h.bytesInFlight, packet.PacketNumber, packet.Length, isAckEliciting)
+	if h.rateLimiter != nil {
+		h.rateLimiter.SentPacket(packet.SendTime, packet.Length)
+	}
 
 	return isAckEliciting
 }
 
 func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.EncryptionLevel, rcvTime time.Time) (bool /* contained 1-RTT packet */, error) {
+	h.mutex.Lock()
+	acked1RTTPacket, err := h.receivedAck(ack, encLevel, rcvTime)
+	h.unlock()
+
+	// The OnAcked callbacks might call back into the sentPacketHandler
+	// (e.g. the MTU discoverer sets the maximum datagram size), so they must be called without holding the mutex.
+	for _, f := range h.ackedFrames {
+		f.OnAcked(f.Frame)
+	}
+	h.ackedFrames = h.ackedFrames[:0]
+	return acked1RTTPacket, err
+}
+
+func (h *sentPacketHandler) receivedAck(ack *wire.AckFrame, encLevel protocol.EncryptionLevel, rcvTime time.Time) (bool, error) {
 	pnSpace := h.getPacketNumberSpace(encLevel)
 
 	largestAcked := ack.LargestAcked()
@@ -288,6 +415,9 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 		}
 	}
 
+	// ECN counts are only processed for ACK frames that increase the largest acknowledged packet number,
+	// since reordered ACK frames might contain outdated counts.
+	isNewLargestAcked := largestAcked > pnSpace.largestAcked
 	pnSpace.largestAcked = utils.MaxPacketNumber(pnSpace.largestAcked, largestAcked)
 
 	// Servers complete address validation when a protected packet is received.
@@ -322,11 +452,31 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 	if err := h.detectLostPackets(rcvTime, encLevel); err != nil {
 		return false, err
 	}
+	if h.ecnTracker != nil && encLevel == protocol.Encryption1RTT && isNewLargestAcked {
+		if h.ecnTracker.HandleNewlyAcked(ackedPackets, int64(ack.ECT0), int64(ack.ECT1), int64(ack.ECNCE)) {
+			if h.logger.Debug() {
+				h.logger.Debugf("\tpeer reported ECN-CE marks (CE count: %d)", ack.ECNCE)
+			}
+			h.congestion.OnCongestionEvent(ackedPackets[len(ackedPackets)-1].PacketNumber, priorInFlight)
+		}
+	}
 	var acked1RTTPacket bool
 	for _, p := range ackedPackets {
 		if p.includedInBytesInFlight && !p.declaredLost {
 			h.congestion.OnPacketAcked(p.PacketNumber, p.Length, priorInFlight, rcvTime)
 		}
+		if !p.lossDetected && !p.IsPathMTUProbePacket {
+			h.lossRate.Acked()
+		}
+		if p.lossDetected {
+			h.spuriousLosses++
+			if h.logger.Debug() {
+				h.logger.Debugf("\tspurious loss of packet %d", p.PacketNumber)
+			}
+			if !p.IsPathMTUProbePacket {
+				h.congestion.OnSpuriousLoss(p.PacketNumber)
+			}
+		}
 		if p.EncryptionLevel == protocol.Encryption1RTT {
 			acked1RTTPacket = true
 		}
@@ -411,7 +561,7 @@ func (h *sentPacketHandler) detectAndRemoveAckedPackets(ack *wire.AckFrame, encL
 
 		for _, f := range p.Frames {
 			if f.OnAcked != nil {
-				f.OnAcked(f.Frame)
+				h.ackedFrames = append(h.ackedFrames, f)
 			}
 		}
 		if err := pnSpace.history.Remove(p.PacketNumber); err != nil {
@@ -452,7 +602,7 @@ func (h *sentPacketHandler) getPTOTimeAndSpace() (pto time.Time, encLevel protoc
 		if h.peerCompletedAddressValidation {
 			return
 		}
-		t := time.Now().Add(h.rttStats.PTO(false) << h.ptoCount)
+		t := h.clock.Now().Add(h.pto(false) << h.ptoCount)
 		if h.initialPackets != nil {
 			return t, protocol.EncryptionInitial, true
 		}
@@ -462,18 +612,18 @@ func (h *sentPacketHandler) getPTOTimeAndSpace() (pto time.Time, encLevel protoc
 	if h.initialPackets != nil {
 		encLevel = protocol.EncryptionInitial
 		if t := h.initialPackets.lastAckElicitingPacketTime; !t.IsZero() {
-			pto = t.Add(h.rttStats.PTO(false) << h.ptoCount)
+			pto = t.Add(h.pto(false) << h.ptoCount)
 		}
 	}
 	if h.handshakePackets != nil && !h.handshakePackets.lastAckElicitingPacketTime.IsZero() {
-		t := h.handshakePackets.lastAckElicitingPacketTime.Add(h.rttStats.PTO(false) << h.ptoCount)
+		t := h.handshakePackets.lastAckElicitingPacketTime.Add(h.pto(false) << h.ptoCount)
 		if pto.IsZero() || (!t.IsZero() && t.Before(pto)) {
 			pto = t
 			encLevel = protocol.EncryptionHandshake
 		}
 	}
 	if h.handshakeConfirmed && !h.appDataPackets.lastAckElicitingPacketTime.IsZero() {
-		t := h.appDataPackets.lastAckElicitingPacketTime.Add(h.rttStats.PTO(true) << h.ptoCount)
+		t := h.appDataPackets.lastAckElicitingPacketTime.Add(h.pto(true) << h.ptoCount)
 		if pto.IsZero() || (!t.IsZero() && t.Before(pto)) {
 			pto = t
 			encLevel = protocol.Encryption1RTT
@@ -482,6 +632,10 @@ func (h *sentPacketHandler) getPTOTimeAndSpace() (pto time.Time, encLevel protoc
 	return pto, encLevel, true
 }
 
+func (h *sentPacketHandler) pto(includeMaxAckDelay bool) time.Duration {
+	return time.Duration(float64(h.rttStats.PTO(includeMaxAckDelay)) * h.ptoMultiplier)
+}
+
 func (h *sentPacketHandler) hasOutstandingCryptoPackets() bool {
 	if h.initialPackets != nil && h.initialPackets.history.HasOutstandingPackets() {
 		return true
@@ -563,6 +717,8 @@ func (h *sentPacketHandler) detectLostPackets(now time.Time, encLevel protocol.E
 	// Packets sent before this time are deemed lost.
 	lostSendTime := now.Add(-lossDelay)
 
+	h.lossRate.update(now, h.rttStats.SmoothedRTT())
+
 	priorInFlight := h.bytesInFlight
 	return pnSpace.history.Iterate(func(p *Packet) (bool, error) {
 		if p.PacketNumber > pnSpace.largestAcked {
@@ -581,7 +737,7 @@ func (h *sentPacketHandler) detectLostPackets(now time.Time, encLevel protocol.E
 			if h.tracer != nil {
 				h.tracer.LostPacket(p.EncryptionLevel, p.PacketNumber, logging.PacketLossTimeThreshold)
 			}
-		} else if pnSpace.largestAcked >= p.PacketNumber+packetThreshold {
+		} else if pnSpace.largestAcked >= p.PacketNumber+h.packetThreshold {
 			packetLost = true
 			if h.logger.Debug() {
 				h.logger.Debugf("\tlost packet %d (reordering threshold)", p.PacketNumber)
@@ -599,11 +755,17 @@ func (h *sentPacketHandler) detectLostPackets(now time.Time, encLevel protocol.E
 		}
 		if packetLost {
 			p.declaredLost = true
+			p.lossDetected = true
 			// the bytes in flight need to be reduced no matter if the frames in this packet will be retransmitted
 			h.removeFromBytesInFlight(p)
 			h.queueFramesForRetransmission(p)
+			if h.ecnTracker != nil && encLevel == protocol.Encryption1RTT {
+				h.ecnTracker.LostPacket(p.PacketNumber)
+			}
 			if !p.IsPathMTUProbePacket {
 				h.congestion.OnPacketLost(p.PacketNumber, p.Length, priorInFlight)
+				h.packetsLost++
+				h.lossRate.Lost()
 			}
 		}
 		return true, nil
@@ -611,6 +773,9 @@ func (h *sentPacketHandler) detectLostPackets(now time.Time, encLevel protocol.E
 }
 
 func (h *sentPacketHandler) OnLossDetectionTimeout() error {
+	h.mutex.Lock()
+	defer h.unlock()
+
 	defer h.setLossDetectionTimer()
 	earliestLossTime, encLevel := h.getLossTimeAndSpace()
 	if !earliestLossTime.IsZero() {
@@ -621,7 +786,7 @@ func (h *sentPacketHandler) OnLossDetectionTimeout() error {
 			h.tracer.LossTimerExpired(logging.TimerTypeACK, encLevel)
 		}
 		// Early retransmit or time loss detection
-		return h.detectLostPackets(time.Now(), encLevel)
+		return h.detectLostPackets(h.clock.Now(), encLevel)
 	}
 
 	// PTO
@@ -696,7 +861,18 @@ func (h *sentPacketHandler) PopPacketNumber(encLevel protocol.EncryptionLevel) p
 	return h.getPacketNumberSpace(encLevel).pns.Pop()
 }
 
+func (h *sentPacketHandler) ECNMode() protocol.ECN {
+	// Only 1-RTT packets are marked, so don't mark packets before the handshake is confirmed.
+	if h.ecnTracker == nil || !h.handshakeConfirmed {
+		return protocol.ECNNon
+	}
+	return h.ecnTracker.Mode()
+}
+
 func (h *sentPacketHandler) SendMode() SendMode {
+	h.mutex.Lock()
+	defer h.unlock()
+
 	numTrackedPackets := h.appDataPackets.history.Len()
 	if h.initialPackets != nil {
 		numTrackedPackets += h.initialPackets.history.Len()
@@ -729,6 +905,12 @@ func (h *sentPacketHandler) SendMode() SendMode {
 		}
 		return SendAck
 	}
+	if h.maxBytesInFlight > 0 && h.bytesInFlight >= h.maxBytesInFlight {
+		if h.logger.Debug() {
+			h.logger.Debugf("Limited by the maximum bytes in flight: bytes in flight %d, maximum %d", h.bytesInFlight, h.maxBytesInFlight)
+		}
+		return SendAck
+	}
 	if numTrackedPackets >= protocol.MaxOutstandingSentPackets {
 		if h.logger.Debug() {
 			h.logger.Debugf("Max outstanding limited: tracking %d packets, maximum: %d", numTrackedPackets, protocol.MaxOutstandingSentPackets)
@@ -738,26 +920,60 @@ func (h *sentPacketHandler) SendMode() SendMode {
 	return SendAny
 }
 
+func (h *sentPacketHandler) OnAppLimited() {
+	h.mutex.Lock()
+	defer h.unlock()
+
+	// If the congestion window is full, sending is limited by congestion control, not by the application.
+	if !h.congestion.CanSend(h.bytesInFlight) {
+		return
+	}
+	h.congestion.OnAppLimited(h.bytesInFlight)
+}
+
 func (h *sentPacketHandler) TimeUntilSend() time.Time {
-	return h.congestion.TimeUntilSend(h.bytesInFlight)
+	h.mutex.Lock()
+	defer h.unlock()
+
+	t := h.congestion.TimeUntilSend(h.bytesInFlight)
+	if h.rateLimiter != nil {
+		t = utils.MaxTime(t, h.rateLimiter.TimeUntilSend())
+	}
+	return t
 }
 
 func (h *sentPacketHandler) HasPacingBudget() bool {
+	h.mutex.Lock()
+	defer h.unlock()
+
+	if h.rateLimiter != nil && !h.rateLimiter.HasBudget() {
+		return false
+	}
 	return h.congestion.HasPacingBudget()
 }
 
 func (h *sentPacketHandler) SetMaxDatagramSize(s protocol.ByteCount) {
+	h.mutex.Lock()
+	defer h.unlock()
+
+	h.maxDatagramSize = s
 	h.congestion.SetMaxDatagramSize(s)
+	if h.rateLimiter != nil {
+		h.rateLimiter.SetMaxDatagramSize(s)
+	}
 }
 
 func (h *sentPacketHandler) isAmplificationLimited() bool {
 	if h.peerAddressValidated {
 		return false
 	}
-	return h.bytesSent >= amplificationFactor*h.bytesReceived
+	return h.bytesSent >= h.amplificationFactor*h.bytesReceived
 }
 
 func (h *sentPacketHandler) QueueProbePacket(encLevel protocol.EncryptionLevel) bool {
+	h.mutex.Lock()
+	defer h.unlock()
+
 	pnSpace := h.getPacketNumberSpace(encLevel)
 	p := pnSpace.history.FirstOutstanding()
 	if p == nil {
@@ -768,6 +984,12 @@ func (h *sentPacketHandler) QueueProbePacket(encLevel protocol.EncryptionLevel)
 	// Keep track of acknowledged frames instead.
 	h.removeFromBytesInFlight(p)
 	p.declaredLost = true
+	if h.logger.Debug() {
+		h.logger.Debugf("\tlost packet %d (PTO)", p.PacketNumber)
+	}
+	if h.tracer != nil {
+		h.tracer.LostPacket(p.EncryptionLevel, p.PacketNumber, logging.PacketLossPTO)
+	}
 	return true
 }
 
@@ -775,6 +997,9 @@ func (h *sentPacketHandler) queueFramesForRetransmission(p *Packet) {
 	if len(p.Frames) == 0 {
 		panic("no frames")
 	}
+	if !p.IsPathMTUProbePacket {
+		h.packetsRetransmitted++
+	}
 	for _, f := range p.Frames {
 		f.OnLost(f.Frame)
 	}
@@ -782,6 +1007,9 @@ func (h *sentPacketHandler) queueFramesForRetransmission(p *Packet) {
 }
 
 func (h *sentPacketHandler) ResetForRetry() error {
+	h.mutex.Lock()
+	defer h.unlock()
+
 	h.bytesInFlight = 0
 	var firstPacketSendTime time.Time
 	h.initialPackets.history.Iterate(func(p *Packet) (bool, error) {
@@ -807,7 +1035,7 @@ func (h *sentPacketHandler) ResetForRetry() error {
 	// Otherwise, we don't know which Initial the Retry was sent in response to.
 	if h.ptoCount == 0 {
 		// Don't set the RTT to a value lower than 5ms here.
-		now := time.Now()
+		now := h.clock.Now()
 		h.rttStats.UpdateRTT(utils.MaxDuration(minRTTAfterRetry, now.Sub(firstPacketSendTime)), 0, now)
 		if h.logger.Debug() {
 			h.logger.Debugf("\tupdated RTT: %s (σ: %s)", h.rttStats.SmoothedRTT(), h.rttStats.MeanDeviation())
@@ -831,8 +1059,121 @@ func (h *sentPacketHandler) ResetForRetry() error {
 }
 
 func (h *sentPacketHandler) SetHandshakeConfirmed() {
+	h.mutex.Lock()
+	defer h.unlock()
+
 	h.handshakeConfirmed = true
 	// We don't send PTOs for application data packets before the handshake completes.
 	// Make sure the timer is armed now, if necessary.
 	h.setLossDetectionTimer()
 }
+
+func (h *sentPacketHandler) CongestionState() CongestionState {
+	h.mutex.Lock()
+	defer h.unlock()
+
+	phase := logging.CongestionStateCongestionAvoidance
+	if h.congestion.InRecovery() {
+		phase = logging.CongestionStateRecovery
+	} else if h.congestion.InSlowStart() {
+		phase = logging.CongestionStateSlowStart
+	}
+	algorithm, hystart := h.congestion.ControlType()
+	return CongestionState{
+		CongestionControl: algorithm.String(),
+		Hystart:           hystart.String(),
+		CongestionWindow:  h.congestion.GetCongestionWindow(),
+		BytesInFlight:     h.bytesInFlight,
+		SmoothedRTT:       h.rttStats.SmoothedRTT(),
+		MinRTT:            h.rttStats.MinRTT(),
+		Phase:             phase,
+		SpuriousLosses:    h.spuriousLosses,
+		BandwidthEstimate: h.congestion.BandwidthEstimate(),
+	}
+}
+
+func (h *sentPacketHandler) PacketStats() PacketStats {
+	h.mutex.Lock()
+	defer h.unlock()
+
+	return PacketStats{
+		PacketsSent:          h.packetsSent,
+		PacketsLost:          h.packetsLost,
+		PacketsRetransmitted: h.packetsRetransmitted,
+		LossRate:             h.lossRate.LossRate(h.clock.Now(), h.rttStats.SmoothedRTT()),
+	}
+}
+
+func (h *sentPacketHandler) RTTStats() RTTStats {
+	h.mutex.Lock()
+	defer h.unlock()
+
+	return RTTStats{
+		SmoothedRTT: h.rttStats.SmoothedRTT(),
+		RTTVar:      h.rttStats.MeanDeviation(),
+		MinRTT:      h.rttStats.MinRTT(),
+		LatestRTT:   h.rttStats.LatestRTT(),
+	}
+}
+
+func (h *sentPacketHandler) SetCongestionControl(opts congestion.CongestionOptions) {
+	h.mutex.Lock()
+	defer h.unlock()
+
+	h.congestionOptions = opts
+	h.newCongestionControl = nil
+	// Start the new congestion controller with the current congestion window,
+	// so that we don't burst out packets (or stall) right after switching.
+	// Packets that are still in flight stay accounted for in bytesInFlight.
+	if opts.InitialCongestionWindow == 0 {
+		opts.InitialCongestionWindow = h.congestion.GetCongestionWindow()
+	}
+	h.congestion = congestion.NewCongestionHandlerWithClock(h.clock, h.rttStats, h.maxDatagramSize, opts, h.tracer)
+	h.traceCongestionControl()
+}
+
+func (h *sentPacketHandler) SetSendRateLimit(bytesPerSecond uint64) {
+	h.mutex.Lock()
+	defer h.unlock()
+
+	if bytesPerSecond == 0 {
+		h.rateLimiter = nil
+		return
+	}
+	if h.rateLimiter != nil {
+		h.rateLimiter.SetRate(bytesPerSecond)
+		return
+	}
+	h.rateLimiter = congestion.NewRateLimiter(h.clock, bytesPerSecond, h.maxDatagramSize)
+}
+
+func (h *sentPacketHandler) SetMaxBytesInFlight(n protocol.ByteCount) {
+	h.mutex.Lock()
+	defer h.unlock()
+
+	h.maxBytesInFlight = n
+}
+
+func (h *sentPacketHandler) BytesInFlight() protocol.ByteCount {
+	h.mutex.Lock()
+	defer h.unlock()
+
+	return h.bytesInFlight
+}
+
+func (h *sentPacketHandler) OnConnectionMigration(pathChanged bool) {
+	h.mutex.Lock()
+	defer h.unlock()
+
+	if !pathChanged && h.congestionOptions.PreserveCwndOnMigration {
+		if h.logger.Debug() {
+			h.logger.Debugf("Connection migrated on the same path. Preserving congestion window (%d) and RTT estimates.", h.congestion.GetCongestionWindow())
+		}
+		return
+	}
+	// Be conservative, and start over as if this was a new connection.
+	// Packets that are still in flight stay accounted for in bytesInFlight.
+	h.rttStats.OnConnectionMigration()
+	h.congestion = h.newCongestionController()
+	h.traceCongestionControl()
+}
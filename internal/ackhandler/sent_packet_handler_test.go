@@ -8,10 +8,12 @@ import (
 
 	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
+	mocklogging "github.com/lucas-clemente/quic-go/internal/mocks/logging"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -23,6 +25,7 @@ var _ = Describe("SentPacketHandler", func() {
 		streamFrame wire.StreamFrame
 		lostPackets []protocol.PacketNumber
 		perspective protocol.Perspective
+		clock       mockClock
 	)
 
 	BeforeEach(func() { perspective = protocol.PerspectiveServer })
@@ -30,7 +33,8 @@ var _ = Describe("SentPacketHandler", func() {
 	JustBeforeEach(func() {
 		lostPackets = nil
 		rttStats := utils.NewRTTStats()
-		handler = newSentPacketHandler(42, protocol.InitialPacketSizeIPv4, rttStats, perspective, congestion.CongestionOptions{}, nil, utils.DefaultLogger)
+		clock = mockClock(time.Now())
+		handler = newSentPacketHandler(&clock, 42, protocol.InitialPacketSizeIPv4, rttStats, perspective, 1, protocol.DefaultPacketReorderingThreshold, false, congestion.CongestionOptions{}, nil, nil, utils.DefaultLogger)
 		streamFrame = wire.StreamFrame{
 			StreamID: 5,
 			Data:     []byte{0x13, 0x37},
@@ -290,6 +294,27 @@ var _ = Describe("SentPacketHandler", func() {
 				Expect(acked).To(BeTrue())
 			})
 
+			It("doesn't hold the mutex when calling the OnAcked callback", func() {
+				done := make(chan struct{})
+				handler.SentPacket(ackElicitingPacket(&Packet{
+					PacketNumber: 13,
+					Frames: []Frame{{
+						Frame: &wire.PingFrame{}, OnAcked: func(wire.Frame) {
+							// the MTU discoverer does this when an MTU probe packet is acknowledged
+							handler.SetMaxDatagramSize(1337)
+							close(done)
+						},
+					}},
+				}))
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 13, Largest: 13}}}
+				go func() {
+					defer GinkgoRecover()
+					_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+					Expect(err).ToNot(HaveOccurred())
+				}()
+				Eventually(done).Should(BeClosed())
+			})
+
 			It("handles an ACK frame with one missing packet range", func() {
 				ack := &wire.AckFrame{ // lose 4 and 5
 					AckRanges: []wire.AckRange{
@@ -484,13 +509,38 @@ var _ = Describe("SentPacketHandler", func() {
 	})
 
 	Context("congestion", func() {
-		var cong *mocks.MockSendAlgorithmWithDebugInfos
+		var cong *mocks.MockExtendedSendAlgorithm
 
 		JustBeforeEach(func() {
-			cong = mocks.NewMockSendAlgorithmWithDebugInfos(mockCtrl)
+			cong = mocks.NewMockExtendedSendAlgorithm(mockCtrl)
 			handler.congestion = cong
 		})
 
+		It("uses the congestion control factory, if set", func() {
+			rttStats := utils.NewRTTStats()
+			var calledWith protocol.ByteCount
+			h := newSentPacketHandler(
+				&clock,
+				0,
+				1234,
+				rttStats,
+				protocol.PerspectiveClient,
+				1,
+				protocol.DefaultPacketReorderingThreshold,
+				false,
+				congestion.CongestionOptions{},
+				func(r *utils.RTTStats, initialMaxDatagramSize protocol.ByteCount, _ logging.ConnectionTracer) congestion.SendAlgorithmWithDebugInfos {
+					Expect(r).To(Equal(rttStats))
+					calledWith = initialMaxDatagramSize
+					return cong
+				},
+				nil,
+				utils.DefaultLogger,
+			)
+			Expect(calledWith).To(Equal(protocol.ByteCount(1234)))
+			Expect(h.congestion).To(Equal(cong))
+		})
+
 		It("should call OnSent", func() {
 			cong.EXPECT().OnPacketSent(
 				gomock.Any(),
@@ -603,6 +653,20 @@ var _ = Describe("SentPacketHandler", func() {
 			handler.SendMode()
 		})
 
+		It("tells the congestion controller when the application runs out of data", func() {
+			handler.ReceivedPacket(protocol.EncryptionHandshake)
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), true)
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, Length: 42}))
+			cong.EXPECT().CanSend(protocol.ByteCount(42)).Return(true)
+			cong.EXPECT().OnAppLimited(protocol.ByteCount(42))
+			handler.OnAppLimited()
+		})
+
+		It("doesn't tell the congestion controller that the application ran out of data when the congestion window is full", func() {
+			cong.EXPECT().CanSend(gomock.Any()).Return(false)
+			handler.OnAppLimited()
+		})
+
 		It("allows sending of ACKs when congestion limited", func() {
 			handler.ReceivedPacket(protocol.EncryptionHandshake)
 			cong.EXPECT().CanSend(gomock.Any()).Return(true)
@@ -643,6 +707,190 @@ var _ = Describe("SentPacketHandler", func() {
 			cong.EXPECT().TimeUntilSend(gomock.Any()).Return(t)
 			Expect(handler.TimeUntilSend()).To(Equal(t))
 		})
+
+		It("limits the send rate", func() {
+			const rate = 10 * 1000 * 1000 / 8 // 10 Mbps
+			handler.SetSendRateLimit(rate)
+			cong.EXPECT().HasPacingBudget().Return(true).AnyTimes()
+			cong.EXPECT().TimeUntilSend(gomock.Any()).AnyTimes()
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			start := clock.Now()
+			var sent protocol.ByteCount
+			var pn protocol.PacketNumber
+			for clock.Now().Sub(start) < 2*time.Second {
+				if !handler.HasPacingBudget() {
+					t := handler.TimeUntilSend()
+					Expect(t.After(clock.Now())).To(BeTrue())
+					clock = mockClock(t)
+					continue
+				}
+				handler.SentPacket(nonAckElicitingPacket(&Packet{
+					PacketNumber: pn,
+					Length:       protocol.InitialPacketSizeIPv4,
+					SendTime:     clock.Now(),
+				}))
+				sent += protocol.InitialPacketSizeIPv4
+				pn++
+			}
+			// allow for the initial burst
+			Expect(sent).To(And(
+				BeNumerically("<=", 2*rate+10*protocol.InitialPacketSizeIPv4),
+				BeNumerically(">", 2*rate*95/100),
+			))
+		})
+
+		It("never sends faster than the congestion controller allows, when rate limited", func() {
+			handler.SetSendRateLimit(1 << 30)
+			cong.EXPECT().HasPacingBudget().Return(false)
+			Expect(handler.HasPacingBudget()).To(BeFalse())
+			t := clock.Now().Add(time.Hour)
+			cong.EXPECT().TimeUntilSend(gomock.Any()).Return(t)
+			Expect(handler.TimeUntilSend()).To(Equal(t))
+		})
+
+		It("removes the send rate limit", func() {
+			handler.SetSendRateLimit(1000)
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			for i := 0; i < 10; i++ {
+				handler.SentPacket(nonAckElicitingPacket(&Packet{PacketNumber: protocol.PacketNumber(i), Length: 1200, SendTime: clock.Now()}))
+			}
+			Expect(handler.HasPacingBudget()).To(BeFalse())
+			handler.SetSendRateLimit(0)
+			cong.EXPECT().HasPacingBudget().Return(true)
+			Expect(handler.HasPacingBudget()).To(BeTrue())
+		})
+
+		It("stops sending at the maximum bytes in flight, even if the congestion window is larger", func() {
+			handler.ReceivedPacket(protocol.EncryptionHandshake)
+			handler.SetMaxBytesInFlight(2500)
+			cong.EXPECT().CanSend(gomock.Any()).Return(true).AnyTimes()
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			var pn protocol.PacketNumber
+			for handler.SendMode() == SendAny {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: pn, Length: 1000}))
+				pn++
+			}
+			Expect(handler.SendMode()).To(Equal(SendAck))
+			Expect(pn).To(BeEquivalentTo(3))
+			Expect(handler.BytesInFlight()).To(Equal(protocol.ByteCount(3000)))
+			// raise the limit
+			handler.SetMaxBytesInFlight(3500)
+			Expect(handler.SendMode()).To(Equal(SendAny))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: pn, Length: 1000}))
+			Expect(handler.SendMode()).To(Equal(SendAck))
+			// remove the limit
+			handler.SetMaxBytesInFlight(0)
+			Expect(handler.SendMode()).To(Equal(SendAny))
+		})
+
+		It("returns the congestion state", func() {
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, Length: 1000}))
+			handler.rttStats.UpdateRTT(100*time.Millisecond, 0, time.Now())
+			cong.EXPECT().GetCongestionWindow().Return(protocol.ByteCount(12345)).AnyTimes()
+			cong.EXPECT().BandwidthEstimate().Return(congestion.Bandwidth(1e6)).AnyTimes()
+			cong.EXPECT().InRecovery().Return(false)
+			cong.EXPECT().InSlowStart().Return(true)
+			cong.EXPECT().ControlType().Return(congestion.CubicControlType, congestion.HystartTypeStandard).AnyTimes()
+			Expect(handler.CongestionState()).To(Equal(CongestionState{
+				CongestionControl: "cubic",
+				Hystart:           "standard",
+				CongestionWindow:  12345,
+				BytesInFlight:     1000,
+				SmoothedRTT:       100 * time.Millisecond,
+				MinRTT:            100 * time.Millisecond,
+				Phase:             logging.CongestionStateSlowStart,
+				BandwidthEstimate: 1e6,
+			}))
+			cong.EXPECT().InRecovery().Return(true)
+			Expect(handler.CongestionState().Phase).To(Equal(logging.CongestionStateRecovery))
+			cong.EXPECT().InRecovery().Return(false)
+			cong.EXPECT().InSlowStart().Return(false)
+			Expect(handler.CongestionState().Phase).To(Equal(logging.CongestionStateCongestionAvoidance))
+		})
+
+		It("returns the RTT statistics", func() {
+			now := time.Now()
+			handler.rttStats.UpdateRTT(100*time.Millisecond, 0, now)
+			handler.rttStats.UpdateRTT(200*time.Millisecond, 0, now)
+			Expect(handler.RTTStats()).To(Equal(RTTStats{
+				SmoothedRTT: handler.rttStats.SmoothedRTT(),
+				RTTVar:      handler.rttStats.MeanDeviation(),
+				MinRTT:      100 * time.Millisecond,
+				LatestRTT:   200 * time.Millisecond,
+			}))
+			Expect(handler.RTTStats().SmoothedRTT).To(BeNumerically(">", 100*time.Millisecond))
+			Expect(handler.RTTStats().RTTVar).ToNot(BeZero())
+		})
+
+		It("switches the congestion controller, keeping the congestion window", func() {
+			cong.EXPECT().GetCongestionWindow().Return(protocol.ByteCount(20 * 1200))
+			handler.SetCongestionControl(congestion.CongestionOptions{ControlType: congestion.BbrControlType})
+			Expect(handler.congestion).ToNot(Equal(cong))
+			Expect(handler.congestion.GetCongestionWindow()).To(Equal(protocol.ByteCount(20 * 1200)))
+		})
+
+		It("switches the congestion controller, using the configured initial congestion window", func() {
+			handler.SetCongestionControl(congestion.CongestionOptions{
+				ControlType:             congestion.NewRenoControlType,
+				InitialCongestionWindow: 15 * 1200,
+			})
+			Expect(handler.congestion).ToNot(Equal(cong))
+			Expect(handler.congestion.GetCongestionWindow()).To(Equal(protocol.ByteCount(15 * 1200)))
+		})
+
+		It("traces the congestion controller when switching it", func() {
+			tracer := mocklogging.NewMockConnectionTracer(mockCtrl)
+			handler.tracer = tracer
+			cong.EXPECT().GetCongestionWindow().Return(protocol.ByteCount(20 * 1200))
+			tracer.EXPECT().UpdatedCongestionState(gomock.Any()).AnyTimes()
+			tracer.EXPECT().UpdatedCongestionControl("bbr", "none")
+			handler.SetCongestionControl(congestion.CongestionOptions{ControlType: congestion.BbrControlType})
+		})
+
+		It("resets the congestion controller and the RTT estimates on connection migration", func() {
+			handler.rttStats.UpdateRTT(100*time.Millisecond, 0, time.Now())
+			handler.OnConnectionMigration(false)
+			Expect(handler.congestion).ToNot(Equal(cong))
+			Expect(handler.rttStats.SmoothedRTT()).To(BeZero())
+			Expect(handler.rttStats.MinRTT()).To(BeZero())
+		})
+
+		It("traces the congestion controller when resetting it on connection migration", func() {
+			tracer := mocklogging.NewMockConnectionTracer(mockCtrl)
+			handler.tracer = tracer
+			handler.congestionOptions.ControlType = congestion.BbrControlType
+			tracer.EXPECT().UpdatedCongestionState(gomock.Any()).AnyTimes()
+			tracer.EXPECT().UpdatedCongestionControl("bbr", "none")
+			handler.OnConnectionMigration(true)
+		})
+
+		It("preserves the congestion controller and the RTT estimates if the path didn't change", func() {
+			handler.congestionOptions.PreserveCwndOnMigration = true
+			handler.rttStats.UpdateRTT(100*time.Millisecond, 0, time.Now())
+			cong.EXPECT().GetCongestionWindow().Return(protocol.ByteCount(20 * 1200)).AnyTimes()
+			handler.OnConnectionMigration(false)
+			Expect(handler.congestion).To(BeIdenticalTo(cong))
+			Expect(handler.rttStats.SmoothedRTT()).To(Equal(100 * time.Millisecond))
+			Expect(handler.rttStats.MinRTT()).To(Equal(100 * time.Millisecond))
+		})
+
+		It("resets the congestion controller and the RTT estimates when migrating to a new path", func() {
+			handler.congestionOptions.PreserveCwndOnMigration = true
+			handler.rttStats.UpdateRTT(100*time.Millisecond, 0, time.Now())
+			handler.OnConnectionMigration(true)
+			Expect(handler.congestion).ToNot(Equal(cong))
+			Expect(handler.rttStats.SmoothedRTT()).To(BeZero())
+		})
+
+		It("uses the congestion control factory when resetting the congestion controller", func() {
+			newCong := mocks.NewMockExtendedSendAlgorithm(mockCtrl)
+			handler.newCongestionControl = func(*utils.RTTStats, protocol.ByteCount, logging.ConnectionTracer) congestion.SendAlgorithmWithDebugInfos {
+				return newCong
+			}
+			handler.OnConnectionMigration(true)
+			Expect(handler.congestion).To(BeIdenticalTo(newCong))
+		})
 	})
 
 	It("doesn't set an alarm if there are no outstanding packets", func() {
@@ -690,6 +938,26 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.GetLossDetectionTimeout().Sub(sendTime)).To(Equal(4 * timeout))
 		})
 
+		It("scales the PTO by the PTO multiplier", func() {
+			handler.peerAddressValidated = true
+			handler.SetHandshakeConfirmed()
+			now := time.Now()
+			handler.rttStats.UpdateRTT(100*time.Millisecond, 0, now)
+			handler.rttStats.UpdateRTT(300*time.Millisecond, 0, now)
+			handler.rttStats.UpdateRTT(150*time.Millisecond, 0, now)
+			sendTime := now.Add(-time.Hour)
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: sendTime}))
+			timeout := handler.GetLossDetectionTimeout().Sub(sendTime)
+			Expect(timeout).To(Equal(handler.rttStats.PTO(true)))
+			handler.ptoMultiplier = 2.5
+			handler.setLossDetectionTimer()
+			Expect(handler.GetLossDetectionTimeout().Sub(sendTime)).To(Equal(timeout * 5 / 2))
+			// the exponential backoff is applied on top of the multiplier
+			handler.ptoCount = 1
+			handler.setLossDetectionTimer()
+			Expect(handler.GetLossDetectionTimeout().Sub(sendTime)).To(Equal(timeout * 5))
+		})
+
 		It("reset the PTO count when receiving an ACK", func() {
 			handler.ReceivedPacket(protocol.EncryptionHandshake)
 			now := time.Now()
@@ -902,6 +1170,53 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.SendMode()).To(Equal(SendNone))
 		})
 
+		Context("configuring the amplification factor", func() {
+			var origAmplificationFactor protocol.ByteCount
+
+			BeforeEach(func() { origAmplificationFactor = AmplificationFactor })
+			AfterEach(func() { AmplificationFactor = origAmplificationFactor })
+
+			newHandler := func() *sentPacketHandler {
+				return newSentPacketHandler(&clock, 42, protocol.InitialPacketSizeIPv4, utils.NewRTTStats(), protocol.PerspectiveServer, 1, protocol.DefaultPacketReorderingThreshold, false, congestion.CongestionOptions{}, nil, nil, utils.DefaultLogger)
+			}
+
+			// sendUntilBlocked sends 100 byte packets until the handler is amplification limited, and returns the number of bytes sent.
+			sendUntilBlocked := func(h *sentPacketHandler, pn protocol.PacketNumber) (protocol.PacketNumber, protocol.ByteCount) {
+				var sent protocol.ByteCount
+				for h.SendMode() == SendAny {
+					h.SentPacket(initialPacket(&Packet{PacketNumber: pn, Length: 100}))
+					pn++
+					sent += 100
+				}
+				Expect(h.SendMode()).To(Equal(SendNone))
+				return pn, sent
+			}
+
+			for _, f := range []protocol.ByteCount{3, 5} {
+				factor := f
+
+				It(fmt.Sprintf("stops sending after %dx the bytes received, until more bytes are received", factor), func() {
+					AmplificationFactor = factor
+					h := newHandler()
+					h.ReceivedBytes(1000)
+					pn, sent := sendUntilBlocked(h, 1)
+					Expect(sent).To(Equal(factor * 1000))
+					h.ReceivedBytes(100)
+					Expect(h.SendMode()).To(Equal(SendAny))
+					_, sent = sendUntilBlocked(h, pn)
+					Expect(sent).To(Equal(factor * 100))
+				})
+			}
+
+			It("doesn't use an amplification factor smaller than 3", func() {
+				AmplificationFactor = 1
+				h := newHandler()
+				h.ReceivedBytes(1000)
+				_, sent := sendUntilBlocked(h, 1)
+				Expect(sent).To(Equal(protocol.ByteCount(3000)))
+			})
+		})
+
 		It("cancels the loss detection timer when it is amplification limited, and resets it when becoming unblocked", func() {
 			handler.ReceivedBytes(300)
 			handler.SentPacket(&Packet{
@@ -1058,6 +1373,302 @@ var _ = Describe("SentPacketHandler", func() {
 			expectInPacketHistory([]protocol.PacketNumber{4, 5}, protocol.Encryption1RTT)
 			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1, 2, 3}))
 		})
+
+		It("uses the configured packet reordering threshold", func() {
+			handler.packetThreshold = 5
+			now := time.Now()
+			for i := protocol.PacketNumber(1); i <= 10; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i, SendTime: now}))
+			}
+			// packets 2 to 5 arrive out of order, before packet 1
+			for i := protocol.PacketNumber(2); i <= 5; i++ {
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: i}}}
+				_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lostPackets).To(BeEmpty())
+			}
+			// packet 1 is now 5 packets behind the largest acknowledged packet
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 6}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1}))
+			expectInPacketHistory([]protocol.PacketNumber{7, 8, 9, 10}, protocol.Encryption1RTT)
+		})
+
+		It("undoes the congestion window reduction when an ACK for a lost packet arrives", func() {
+			now := clock.Now()
+			for i := protocol.PacketNumber(1); i <= 6; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i, SendTime: now}))
+			}
+			cwnd := handler.congestion.GetCongestionWindow()
+			clock.Advance(10 * time.Millisecond)
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 6}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, clock.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1}))
+			Expect(handler.congestion.GetCongestionWindow()).To(BeNumerically("<", cwnd))
+			Expect(handler.CongestionState().Phase).To(Equal(logging.CongestionStateRecovery))
+			Expect(handler.CongestionState().SpuriousLosses).To(BeZero())
+			// the ACK for packet 1 was reordered
+			clock.Advance(time.Millisecond)
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 6}}}
+			_, err = handler.ReceivedAck(ack, protocol.Encryption1RTT, clock.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.congestion.GetCongestionWindow()).To(Equal(cwnd))
+			Expect(handler.CongestionState().Phase).ToNot(Equal(logging.CongestionStateRecovery))
+			Expect(handler.CongestionState().SpuriousLosses).To(Equal(uint64(1)))
+		})
+
+		It("doesn't count packets declared lost for PTO probes as spurious losses", func() {
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2}))
+			Expect(handler.QueueProbePacket(protocol.Encryption1RTT)).To(BeTrue())
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 2}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.CongestionState().SpuriousLosses).To(BeZero())
+		})
+	})
+
+	Context("packet statistics", func() {
+		It("counts sent, lost and retransmitted packets", func() {
+			now := time.Now()
+			for i := protocol.PacketNumber(1); i <= 20; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i, SendTime: now}))
+			}
+			// packets 1, 2 and 3 are lost
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 4, Largest: 20}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now.Add(100*time.Millisecond))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1, 2, 3}))
+			Expect(handler.PacketStats()).To(Equal(PacketStats{
+				PacketsSent:          20,
+				PacketsLost:          3,
+				PacketsRetransmitted: 3,
+				LossRate:             3.0 / 20,
+			}))
+			// probe packets are counted as retransmissions, but not as losses
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 21, SendTime: now}))
+			Expect(handler.QueueProbePacket(protocol.Encryption1RTT)).To(BeTrue())
+			stats := handler.PacketStats()
+			Expect(stats.PacketsSent).To(BeEquivalentTo(21))
+			Expect(stats.PacketsLost).To(BeEquivalentTo(3))
+			Expect(stats.PacketsRetransmitted).To(BeEquivalentTo(4))
+		})
+
+		It("doesn't count lost Path MTU probe packets", func() {
+			now := time.Now()
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: now, IsPathMTUProbePacket: true}))
+			for i := protocol.PacketNumber(2); i <= 5; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i, SendTime: now}))
+			}
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 5}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now.Add(100*time.Millisecond))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1}))
+			stats := handler.PacketStats()
+			Expect(stats.PacketsLost).To(BeZero())
+			Expect(stats.PacketsRetransmitted).To(BeZero())
+			Expect(stats.LossRate).To(BeZero())
+		})
+
+		It("calculates the loss rate over the last RTTs", func() {
+			const rtt = 100 * time.Millisecond
+			now := time.Now()
+			sendAndAck := func(first, last, smallestAcked protocol.PacketNumber) {
+				for i := first; i <= last; i++ {
+					handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i, SendTime: now}))
+				}
+				now = now.Add(rtt)
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: smallestAcked, Largest: last}}}
+				_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+				ExpectWithOffset(1, err).ToNot(HaveOccurred())
+			}
+			// packet 1 is lost
+			sendAndAck(1, 10, 2)
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1}))
+			Expect(handler.PacketStats().LossRate).To(Equal(0.1))
+			// one RTT later, the loss is still accounted for
+			sendAndAck(11, 20, 11)
+			Expect(handler.PacketStats().LossRate).To(Equal(0.05))
+			// two RTTs later, it isn't
+			sendAndAck(21, 30, 21)
+			Expect(handler.PacketStats().LossRate).To(BeZero())
+			Expect(handler.PacketStats().PacketsLost).To(BeEquivalentTo(1))
+		})
+
+		It("decays the loss rate when the connection is idle", func() {
+			now := time.Now()
+			for i := protocol.PacketNumber(1); i <= 10; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i, SendTime: now}))
+			}
+			// packet 1 is lost
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 10}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now.Add(100*time.Millisecond))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1}))
+			clock = mockClock(now.Add(100 * time.Millisecond))
+			Expect(handler.PacketStats().LossRate).To(Equal(0.1))
+			// no packets are sent for a few RTTs
+			clock = mockClock(now.Add(time.Second))
+			Expect(handler.PacketStats().LossRate).To(BeZero())
+		})
+	})
+
+	Context("tracing lost packets", func() {
+		var tracer *mocklogging.MockConnectionTracer
+
+		JustBeforeEach(func() {
+			tracer = mocklogging.NewMockConnectionTracer(mockCtrl)
+			handler.tracer = tracer
+			tracer.EXPECT().AcknowledgedPacket(gomock.Any(), gomock.Any()).AnyTimes()
+			tracer.EXPECT().UpdatedMetrics(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			tracer.EXPECT().SetLossTimer(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			tracer.EXPECT().LossTimerCanceled().AnyTimes()
+		})
+
+		It("traces every lost packet exactly once, with the reason", func() {
+			now := time.Now()
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: now.Add(-time.Hour)}))
+			for i := protocol.PacketNumber(2); i <= 6; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i, SendTime: now}))
+			}
+			gomock.InOrder(
+				tracer.EXPECT().LostPacket(protocol.Encryption1RTT, protocol.PacketNumber(1), logging.PacketLossTimeThreshold),
+				tracer.EXPECT().LostPacket(protocol.Encryption1RTT, protocol.PacketNumber(2), logging.PacketLossReorderingThreshold),
+			)
+			_, err := handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 5, Largest: 5}}}, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			tracer.EXPECT().LostPacket(protocol.Encryption1RTT, protocol.PacketNumber(3), logging.PacketLossPTO)
+			Expect(handler.QueueProbePacket(protocol.Encryption1RTT)).To(BeTrue())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1, 2, 3}))
+			// packets that were already declared lost, or that are acknowledged, are not traced (again)
+			_, err = handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 6}}}, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.appDataPackets.history.HasOutstandingPackets()).To(BeFalse())
+		})
+
+		It("allows the tracer to call back into the sent packet handler", func() {
+			tracer := mocklogging.NewMockConnectionTracer(mockCtrl)
+			tracer.EXPECT().UpdatedCongestionControl(gomock.Any(), gomock.Any())
+			tracer.EXPECT().UpdatedCongestionState(gomock.Any()).AnyTimes()
+			tracer.EXPECT().AcknowledgedPacket(gomock.Any(), gomock.Any()).AnyTimes()
+			tracer.EXPECT().UpdatedMetrics(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			tracer.EXPECT().UpdatedCongestionMetrics(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			tracer.EXPECT().SetLossTimer(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			tracer.EXPECT().LossTimerCanceled().AnyTimes()
+			handler = newSentPacketHandler(&clock, 0, protocol.InitialPacketSizeIPv4, utils.NewRTTStats(), perspective, 1, protocol.DefaultPacketReorderingThreshold, false, congestion.CongestionOptions{}, nil, tracer, utils.DefaultLogger)
+			now := time.Now()
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: now.Add(-time.Hour)}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2, SendTime: now}))
+			var state CongestionState
+			tracer.EXPECT().LostPacket(protocol.Encryption1RTT, protocol.PacketNumber(1), logging.PacketLossTimeThreshold).Do(func(logging.EncryptionLevel, logging.PacketNumber, logging.PacketLossReason) {
+				state = handler.CongestionState()
+			})
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				_, err := handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 2}}}, protocol.Encryption1RTT, now)
+				Expect(err).ToNot(HaveOccurred())
+			}()
+			Eventually(done).Should(BeClosed())
+			Expect(state.Phase).To(Equal(logging.CongestionStateRecovery))
+		})
+	})
+
+	Context("ECN", func() {
+		JustBeforeEach(func() {
+			handler = newSentPacketHandler(&clock, 0, protocol.InitialPacketSizeIPv4, utils.NewRTTStats(), perspective, 1, protocol.DefaultPacketReorderingThreshold, true, congestion.CongestionOptions{}, nil, nil, utils.DefaultLogger)
+		})
+
+		sendECNPackets := func(from, to protocol.PacketNumber) {
+			for pn := from; pn <= to; pn++ {
+				ecn := handler.ECNMode()
+				Expect(ecn).To(Equal(protocol.ECT0))
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: pn, Length: 1200, ECN: ecn}))
+			}
+		}
+
+		It("only marks packets after the handshake is confirmed", func() {
+			Expect(handler.ECNMode()).To(Equal(protocol.ECNNon))
+			handler.SetHandshakeConfirmed()
+			Expect(handler.ECNMode()).To(Equal(protocol.ECT0))
+		})
+
+		It("doesn't mark packets if ECN is disabled", func() {
+			handler = newSentPacketHandler(&clock, 0, protocol.InitialPacketSizeIPv4, utils.NewRTTStats(), perspective, 1, protocol.DefaultPacketReorderingThreshold, false, congestion.CongestionOptions{}, nil, nil, utils.DefaultLogger)
+			handler.SetHandshakeConfirmed()
+			Expect(handler.ECNMode()).To(Equal(protocol.ECNNon))
+		})
+
+		It("stops marking packets if the peer doesn't report ECN counts", func() {
+			handler.SetHandshakeConfirmed()
+			sendECNPackets(1, 3)
+			_, err := handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 3}}}, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.ECNMode()).To(Equal(protocol.ECNNon))
+		})
+
+		It("reduces the congestion window on ECN-CE marks, without losing packets", func() {
+			handler.SetHandshakeConfirmed()
+			sendECNPackets(1, 10)
+			_, err := handler.ReceivedAck(&wire.AckFrame{
+				AckRanges: []wire.AckRange{{Smallest: 1, Largest: 5}},
+				ECT0:      5,
+			}, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.ecnTracker.state).To(Equal(ecnStateCapable))
+			Expect(handler.congestion.InRecovery()).To(BeFalse())
+			cwnd := handler.congestion.GetCongestionWindow()
+			// packets 7 and 9 were CE-marked by the network
+			_, err = handler.ReceivedAck(&wire.AckFrame{
+				AckRanges: []wire.AckRange{{Smallest: 1, Largest: 10}},
+				ECT0:      8,
+				ECNCE:     2,
+			}, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.congestion.GetCongestionWindow()).To(BeNumerically("<", cwnd))
+			Expect(lostPackets).To(BeEmpty())
+			Expect(handler.ECNMode()).To(Equal(protocol.ECT0))
+		})
+
+		It("reduces the congestion window only once per round trip", func() {
+			handler.SetHandshakeConfirmed()
+			sendECNPackets(1, 10)
+			_, err := handler.ReceivedAck(&wire.AckFrame{
+				AckRanges: []wire.AckRange{{Smallest: 1, Largest: 5}},
+				ECT0:      4,
+				ECNCE:     1,
+			}, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			cwnd := handler.congestion.GetCongestionWindow()
+			// packets 6 to 10 were sent before the congestion window was reduced
+			_, err = handler.ReceivedAck(&wire.AckFrame{
+				AckRanges: []wire.AckRange{{Smallest: 1, Largest: 10}},
+				ECT0:      7,
+				ECNCE:     3,
+			}, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.congestion.GetCongestionWindow()).To(Equal(cwnd))
+		})
+
+		It("ignores ECN counts in reordered ACK frames", func() {
+			handler.SetHandshakeConfirmed()
+			sendECNPackets(1, 5)
+			_, err := handler.ReceivedAck(&wire.AckFrame{
+				AckRanges: []wire.AckRange{{Smallest: 5, Largest: 5}, {Smallest: 1, Largest: 3}},
+				ECT0:      4,
+			}, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			// this ACK frame was sent before the one above
+			_, err = handler.ReceivedAck(&wire.AckFrame{
+				AckRanges: []wire.AckRange{{Smallest: 1, Largest: 4}},
+				ECT0:      3,
+			}, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.ecnTracker.state).To(Equal(ecnStateCapable))
+		})
 	})
 
 	Context("Delay-based loss detection", func() {
@@ -1099,6 +1710,27 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.SendMode()).To(Equal(SendAny))
 		})
 
+		It("uses the clock to decide when packets are lost", func() {
+			handler.ReceivedPacket(protocol.EncryptionHandshake)
+			handler.handshakeConfirmed = true
+			now := clock.Now()
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: now}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2, SendTime: now}))
+			clock.Advance(time.Second)
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 2}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, clock.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.GetLossDetectionTimeout()).To(Equal(now.Add(time.Second * 9 / 8)))
+
+			// the loss detection timer fired early
+			clock.Advance(time.Second / 16)
+			Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+			expectInPacketHistory([]protocol.PacketNumber{1}, protocol.Encryption1RTT)
+			clock.Advance(time.Second / 8)
+			Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+			expectInPacketHistory([]protocol.PacketNumber{}, protocol.Encryption1RTT)
+		})
+
 		It("sets the early retransmit alarm for crypto packets", func() {
 			handler.ReceivedBytes(1000)
 			now := time.Now()
@@ -0,0 +1,284 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// This bandwidth sampler follows the delivery rate estimation described in
+// https://datatracker.ietf.org/doc/html/draft-cheng-iccrg-delivery-rate-estimation.
+
+// maxTrackedPacketStates is the maximum number of per-packet states kept by the bandwidth sampler.
+// Packets of dropped packet number spaces are never acknowledged, so we need to bound the map.
+const maxTrackedPacketStates = protocol.MaxTrackedSentPackets
+
+// The state of the connection at the time a packet was sent.
+type sentPacketState struct {
+	sentTime      time.Time
+	size          protocol.ByteCount
+	delivered     protocol.ByteCount
+	deliveredTime time.Time
+	firstSentTime time.Time
+	isAppLimited  bool
+}
+
+// A bandwidthSample is a delivery rate sample obtained when a packet is acknowledged.
+type bandwidthSample struct {
+	bandwidth Bandwidth
+	rtt       time.Duration
+	// The value of delivered at the time the acknowledged packet was sent.
+	priorDelivered protocol.ByteCount
+	isAppLimited   bool
+}
+
+type bandwidthSampler struct {
+	// Total number of bytes acknowledged so far.
+	delivered protocol.ByteCount
+	// The time at which delivered was last updated.
+	deliveredTime time.Time
+	// The send time of the packet that was most recently acknowledged.
+	firstSentTime time.Time
+
+	// The value of delivered until which all acknowledgements are considered application-limited.
+	// Zero means not application-limited.
+	appLimitedUntil protocol.ByteCount
+
+	packets map[protocol.PacketNumber]*sentPacketState
+	// The packet numbers in the order the packets were sent.
+	// May contain packet numbers that were already removed from packets.
+	sentOrder []protocol.PacketNumber
+}
+
+func newBandwidthSampler() *bandwidthSampler {
+	return &bandwidthSampler{packets: make(map[protocol.PacketNumber]*sentPacketState)}
+}
+
+// OnPacketSent records the state of the connection when sending a packet.
+// bytesInFlight is the number of bytes in flight including this packet.
+func (s *bandwidthSampler) OnPacketSent(sentTime time.Time, packetNumber protocol.PacketNumber, bytes, bytesInFlight protocol.ByteCount) {
+	if bytesInFlight <= bytes {
+		// Nothing else is in flight. Start a new delivery interval.
+		s.firstSentTime = sentTime
+		s.deliveredTime = sentTime
+	}
+	if len(s.packets) >= maxTrackedPacketStates {
+		s.removeOldest()
+	}
+	s.sentOrder = append(s.sentOrder, packetNumber)
+	s.packets[packetNumber] = &sentPacketState{
+		sentTime:      sentTime,
+		size:          bytes,
+		delivered:     s.delivered,
+		deliveredTime: s.deliveredTime,
+		firstSentTime: s.firstSentTime,
+		isAppLimited:  s.appLimitedUntil != 0,
+	}
+}
+
+// OnPacketAcked updates the delivery state and returns a bandwidth sample.
+// If the packet is unknown, ok is false.
+func (s *bandwidthSampler) OnPacketAcked(packetNumber protocol.PacketNumber, ackTime time.Time) (sample bandwidthSample, ok bool) {
+	p, ok := s.packets[packetNumber]
+	if !ok {
+		return bandwidthSample{}, false
+	}
+	delete(s.packets, packetNumber)
+
+	s.delivered += p.size
+	s.deliveredTime = ackTime
+	s.firstSentTime = p.sentTime
+	if s.appLimitedUntil != 0 && s.delivered > s.appLimitedUntil {
+		s.appLimitedUntil = 0
+	}
+
+	sample = bandwidthSample{
+		rtt:            ackTime.Sub(p.sentTime),
+		priorDelivered: p.delivered,
+		isAppLimited:   p.isAppLimited,
+	}
+	// Use the longer of the send and the ack interval, to avoid overestimating the bandwidth
+	// when acknowledgements are compressed.
+	sendElapsed := p.sentTime.Sub(p.firstSentTime)
+	ackElapsed := s.deliveredTime.Sub(p.deliveredTime)
+	interval := sendElapsed
+	if ackElapsed > interval {
+		interval = ackElapsed
+	}
+	if interval <= 0 {
+		return sample, true
+	}
+	sample.bandwidth = BandwidthFromDelta(s.delivered-p.delivered, interval)
+	return sample, true
+}
+
+// OnPacketLost removes a lost packet.
+func (s *bandwidthSampler) OnPacketLost(packetNumber protocol.PacketNumber) {
+	delete(s.packets, packetNumber)
+}
+
+// OnAppLimited marks the current delivery interval as application-limited.
+// Samples taken from packets sent until bytesInFlight more bytes have been delivered
+// won't be able to reduce the bandwidth estimate.
+func (s *bandwidthSampler) OnAppLimited(bytesInFlight protocol.ByteCount) {
+	s.appLimitedUntil = s.delivered + bytesInFlight
+	if s.appLimitedUntil == 0 {
+		s.appLimitedUntil = 1
+	}
+}
+
+// TotalBytesAcked returns the total number of bytes acknowledged.
+func (s *bandwidthSampler) TotalBytesAcked() protocol.ByteCount {
+	return s.delivered
+}
+
+// Reset drops all per-packet state.
+func (s *bandwidthSampler) Reset() {
+	s.packets = make(map[protocol.PacketNumber]*sentPacketState)
+	s.sentOrder = nil
+	s.appLimitedUntil = 0
+}
+
+func (s *bandwidthSampler) removeOldest() {
+	for len(s.sentOrder) > 0 {
+		pn := s.sentOrder[0]
+		s.sentOrder = s.sentOrder[1:]
+		if _, ok := s.packets[pn]; ok {
+			delete(s.packets, pn)
+			break
+		}
+	}
+	// Get rid of packet numbers of packets that were already acknowledged or lost.
+	if len(s.sentOrder) > 2*maxTrackedPacketStates {
+		order := make([]protocol.PacketNumber, 0, len(s.packets))
+		for _, pn := range s.sentOrder {
+			if _, ok := s.packets[pn]; ok {
+				order = append(order, pn)
+			}
+		}
+		s.sentOrder = order
+	}
+}
+
+// A maxBandwidthFilter tracks the maximum bandwidth observed over a window of round trips.
+// It is based on the windowed min/max filter by Kathleen Nichols, as used in the Linux kernel (lib/win_minmax.c).
+type maxBandwidthFilter struct {
+	windowLength uint64
+	estimates    [3]bandwidthEstimate
+}
+
+type bandwidthEstimate struct {
+	sample Bandwidth
+	round  uint64
+}
+
+func newMaxBandwidthFilter(windowLength uint64) *maxBandwidthFilter {
+	return &maxBandwidthFilter{windowLength: windowLength}
+}
+
+// GetBest returns the maximum bandwidth in the window.
+func (f *maxBandwidthFilter) GetBest() Bandwidth {
+	return f.estimates[0].sample
+}
+
+// Update adds a new sample, taken in the given round.
+func (f *maxBandwidthFilter) Update(sample Bandwidth, round uint64) {
+	if f.estimates[0].sample == 0 || sample >= f.estimates[0].sample || round-f.estimates[2].round > f.windowLength {
+		f.Reset(sample, round)
+		return
+	}
+	if sample >= f.estimates[1].sample {
+		f.estimates[1] = bandwidthEstimate{sample: sample, round: round}
+		f.estimates[2] = f.estimates[1]
+	} else if sample >= f.estimates[2].sample {
+		f.estimates[2] = bandwidthEstimate{sample: sample, round: round}
+	}
+
+	// Expire and update estimates as necessary.
+	if round-f.estimates[0].round > f.windowLength {
+		// The best estimate hasn't been updated for an entire window, so promote second and third best.
+		f.estimates[0] = f.estimates[1]
+		f.estimates[1] = f.estimates[2]
+		f.estimates[2] = bandwidthEstimate{sample: sample, round: round}
+		// Need to iterate one more time. The new best might be too old as well.
+		if round-f.estimates[0].round > f.windowLength {
+			f.estimates[0] = f.estimates[1]
+			f.estimates[1] = f.estimates[2]
+		}
+		return
+	}
+	if f.estimates[1].sample == f.estimates[0].sample && round-f.estimates[1].round > f.windowLength>>2 {
+		// A quarter of the window has passed without a better sample, so the second best estimate is taken from the second quarter of the window.
+		f.estimates[1] = bandwidthEstimate{sample: sample, round: round}
+		f.estimates[2] = f.estimates[1]
+		return
+	}
+	if f.estimates[2].sample == f.estimates[1].sample && round-f.estimates[2].round > f.windowLength>>1 {
+		// We've passed a half of the window without a better estimate, so take a third best estimate from the second half of the window.
+		f.estimates[2] = bandwidthEstimate{sample: sample, round: round}
+	}
+}
+
+// Reset resets all estimates to the given sample.
+func (f *maxBandwidthFilter) Reset(sample Bandwidth, round uint64) {
+	e := bandwidthEstimate{sample: sample, round: round}
+	f.estimates = [3]bandwidthEstimate{e, e, e}
+}
+
+// deliveryRateWindowRounds is the number of round trips over which the deliveryRateEstimator takes the maximum.
+const deliveryRateWindowRounds = 10
+
+// A deliveryRateEstimator estimates the bottleneck bandwidth as the maximum delivery rate
+// measured over the last deliveryRateWindowRounds round trips.
+// It is used by congestion controllers that don't use the bandwidth estimate themselves.
+type deliveryRateEstimator struct {
+	sampler      *bandwidthSampler
+	maxBandwidth *maxBandwidthFilter
+
+	roundTripCount      uint64
+	currentRoundTripEnd protocol.ByteCount
+}
+
+func newDeliveryRateEstimator() *deliveryRateEstimator {
+	return &deliveryRateEstimator{
+		sampler:      newBandwidthSampler(),
+		maxBandwidth: newMaxBandwidthFilter(deliveryRateWindowRounds),
+	}
+}
+
+func (e *deliveryRateEstimator) OnPacketSent(sentTime time.Time, packetNumber protocol.PacketNumber, bytes, bytesInFlight protocol.ByteCount) {
+	e.sampler.OnPacketSent(sentTime, packetNumber, bytes, bytesInFlight)
+}
+
+func (e *deliveryRateEstimator) OnPacketAcked(packetNumber protocol.PacketNumber, ackTime time.Time) {
+	sample, ok := e.sampler.OnPacketAcked(packetNumber, ackTime)
+	if !ok {
+		return
+	}
+	if sample.priorDelivered >= e.currentRoundTripEnd {
+		e.roundTripCount++
+		e.currentRoundTripEnd = e.sampler.TotalBytesAcked()
+	}
+	if sample.bandwidth > 0 && (!sample.isAppLimited || sample.bandwidth >= e.maxBandwidth.GetBest()) {
+		e.maxBandwidth.Update(sample.bandwidth, e.roundTripCount)
+	}
+}
+
+func (e *deliveryRateEstimator) OnPacketLost(packetNumber protocol.PacketNumber) {
+	e.sampler.OnPacketLost(packetNumber)
+}
+
+func (e *deliveryRateEstimator) OnAppLimited(bytesInFlight protocol.ByteCount) {
+	e.sampler.OnAppLimited(bytesInFlight)
+}
+
+// Reset drops the bandwidth estimate, e.g. when the connection migrated to a new path.
+func (e *deliveryRateEstimator) Reset() {
+	e.sampler.Reset()
+	e.maxBandwidth.Reset(0, e.roundTripCount)
+}
+
+// BandwidthEstimate returns the maximum delivery rate. It is zero if no estimate is available yet.
+func (e *deliveryRateEstimator) BandwidthEstimate() Bandwidth {
+	return e.maxBandwidth.GetBest()
+}
@@ -0,0 +1,99 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bandwidth Sampler", func() {
+	var (
+		sampler *bandwidthSampler
+		now     time.Time
+	)
+
+	BeforeEach(func() {
+		sampler = newBandwidthSampler()
+		now = time.Now()
+	})
+
+	It("takes bandwidth samples", func() {
+		var inFlight protocol.ByteCount
+		for pn := protocol.PacketNumber(1); pn <= 10; pn++ {
+			inFlight += 1000
+			sampler.OnPacketSent(now, pn, 1000, inFlight)
+			now = now.Add(time.Millisecond)
+		}
+		now = now.Add(100 * time.Millisecond)
+		var sample bandwidthSample
+		for pn := protocol.PacketNumber(1); pn <= 10; pn++ {
+			now = now.Add(time.Millisecond)
+			var ok bool
+			sample, ok = sampler.OnPacketAcked(pn, now)
+			Expect(ok).To(BeTrue())
+		}
+		Expect(sampler.TotalBytesAcked()).To(Equal(protocol.ByteCount(10000)))
+		Expect(sample.isAppLimited).To(BeFalse())
+		Expect(sample.rtt).To(Equal(111 * time.Millisecond))
+		// the ack interval is longer than the send interval
+		Expect(sample.bandwidth).To(Equal(BandwidthFromDelta(10000, 120*time.Millisecond)))
+	})
+
+	It("ignores unknown and lost packets", func() {
+		sampler.OnPacketSent(now, 1, 1000, 1000)
+		sampler.OnPacketLost(1)
+		_, ok := sampler.OnPacketAcked(1, now.Add(time.Second))
+		Expect(ok).To(BeFalse())
+		_, ok = sampler.OnPacketAcked(2, now.Add(time.Second))
+		Expect(ok).To(BeFalse())
+		Expect(sampler.TotalBytesAcked()).To(BeZero())
+	})
+
+	It("marks samples as application-limited", func() {
+		sampler.OnPacketSent(now, 1, 1000, 1000)
+		sampler.OnAppLimited(1000)
+		sampler.OnPacketSent(now, 2, 1000, 2000)
+		sample, ok := sampler.OnPacketAcked(1, now.Add(time.Second))
+		Expect(ok).To(BeTrue())
+		Expect(sample.isAppLimited).To(BeFalse())
+		sample, ok = sampler.OnPacketAcked(2, now.Add(2*time.Second))
+		Expect(ok).To(BeTrue())
+		Expect(sample.isAppLimited).To(BeTrue())
+		// the app-limited period ended when packet 2 was acknowledged
+		sampler.OnPacketSent(now.Add(2*time.Second), 3, 1000, 1000)
+		sample, ok = sampler.OnPacketAcked(3, now.Add(3*time.Second))
+		Expect(ok).To(BeTrue())
+		Expect(sample.isAppLimited).To(BeFalse())
+	})
+
+	It("bounds the number of tracked packets", func() {
+		for pn := protocol.PacketNumber(0); pn < maxTrackedPacketStates+10; pn++ {
+			sampler.OnPacketSent(now.Add(time.Duration(pn)), pn, 1000, 1000)
+		}
+		Expect(sampler.packets).To(HaveLen(maxTrackedPacketStates))
+		_, ok := sampler.OnPacketAcked(0, now.Add(time.Hour))
+		Expect(ok).To(BeFalse())
+	})
+})
+
+var _ = Describe("Max Bandwidth Filter", func() {
+	It("tracks the maximum", func() {
+		f := newMaxBandwidthFilter(10)
+		f.Update(100, 1)
+		f.Update(50, 2)
+		Expect(f.GetBest()).To(Equal(Bandwidth(100)))
+		f.Update(200, 3)
+		Expect(f.GetBest()).To(Equal(Bandwidth(200)))
+	})
+
+	It("expires old samples", func() {
+		f := newMaxBandwidthFilter(10)
+		f.Update(200, 1)
+		for round := uint64(2); round <= 20; round++ {
+			f.Update(100, round)
+		}
+		Expect(f.GetBest()).To(Equal(Bandwidth(100)))
+	})
+})
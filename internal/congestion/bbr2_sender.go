@@ -0,0 +1,154 @@
+package congestion
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+// BBRv2 extends BBR by a response to packet loss and ECN, following
+// https://datatracker.ietf.org/doc/html/draft-cardwell-iccrg-bbr-congestion-control-02.
+// The data in flight is bounded by two limits:
+// * inflight_hi, a long-term bound, set when probing for bandwidth caused excessive loss
+// * inflight_lo, a short-term bound, reduced in every round trip with losses or ECN-CE marks,
+//   and reset when probing for bandwidth
+
+const (
+	// The maximum fraction of bytes that may be lost in a round trip, before the data in flight is considered too high.
+	bbr2LossThresh = 0.02
+	// The multiplicative decrease of the bounds on the data in flight.
+	bbr2Beta = 0.7
+	// The gain of the moving average of the fraction of round trips with ECN-CE marks.
+	bbr2ECNAlphaGain = 1.0 / 16
+	// The factor by which the ECN alpha reduces inflight_lo.
+	bbr2ECNFactor = 1.0 / 3
+	// Limits the exponential growth of inflight_hi when probing for bandwidth.
+	bbr2MaxProbeUpRounds = 10
+)
+
+type bbr2State struct {
+	// The bounds on the data in flight. Zero if not set.
+	inflightHi protocol.ByteCount
+	inflightLo protocol.ByteCount
+
+	// Bytes acknowledged and lost in the current round trip.
+	ackedInRound protocol.ByteCount
+	lostInRound  protocol.ByteCount
+	// Set if the peer reported ECN-CE marks in the current round trip.
+	ceInRound bool
+	// Set if inflight_hi was already reduced in the current round trip.
+	inflightTooHighInRound bool
+
+	// The moving average of the fraction of round trips with ECN-CE marks.
+	ecnAlpha float64
+	// The number of round trips inflight_hi has been grown for in the current bandwidth probe.
+	probeUpRounds int
+}
+
+// NewBbr2Sender makes a new BBRv2 sender
+func NewBbr2Sender(
+	clock Clock,
+	rttStats *utils.RTTStats,
+	initialMaxDatagramSize protocol.ByteCount,
+	options CongestionOptions,
+	tracer logging.ConnectionTracer,
+) *bbrSender {
+	b := NewBbrSender(clock, rttStats, initialMaxDatagramSize, options, tracer)
+	b.v2 = &bbr2State{}
+	return b
+}
+
+// boundCongestionWindow applies inflight_hi and inflight_lo to the congestion window.
+func (s *bbr2State) boundCongestionWindow(cwnd, minCwnd protocol.ByteCount) protocol.ByteCount {
+	if s.inflightHi != 0 {
+		cwnd = utils.MinByteCount(cwnd, s.inflightHi)
+	}
+	if s.inflightLo != 0 {
+		cwnd = utils.MinByteCount(cwnd, s.inflightLo)
+	}
+	return utils.MaxByteCount(cwnd, minCwnd)
+}
+
+// resetLowerBounds is called when starting to probe for bandwidth.
+func (s *bbr2State) resetLowerBounds() {
+	s.inflightLo = 0
+	s.probeUpRounds = 0
+}
+
+func (b *bbrSender) bbr2OnPacketAcked(isRoundStart bool, ackedBytes, priorInFlight protocol.ByteCount) {
+	s := b.v2
+	if isRoundStart {
+		b.bbr2AdaptLowerBounds()
+		if b.isProbingBandwidth() && s.inflightHi != 0 {
+			s.probeUpRounds = utils.Min(s.probeUpRounds+1, bbr2MaxProbeUpRounds)
+		}
+		s.ackedInRound = 0
+		s.lostInRound = 0
+		s.ceInRound = false
+		s.inflightTooHighInRound = false
+	}
+	s.ackedInRound += ackedBytes
+
+	// When probing for bandwidth, grow inflight_hi by 1, 2, 4, ... packets per round trip,
+	// as long as it limits the data in flight.
+	if b.isProbingBandwidth() && s.inflightHi != 0 && priorInFlight >= s.inflightHi {
+		growth := protocol.ByteCount(uint64(b.maxDatagramSize<<s.probeUpRounds) * uint64(ackedBytes) / uint64(s.inflightHi))
+		s.inflightHi = utils.MinByteCount(s.inflightHi+utils.MaxByteCount(growth, 1), b.maxCongestionWindow)
+	}
+}
+
+// bbr2AdaptLowerBounds is called at the end of every round trip.
+// It reduces inflight_lo if packets were lost, or ECN-CE marks were reported during the round trip.
+func (b *bbrSender) bbr2AdaptLowerBounds() {
+	s := b.v2
+	if s.ceInRound {
+		s.ecnAlpha = (1-bbr2ECNAlphaGain)*s.ecnAlpha + bbr2ECNAlphaGain
+	} else {
+		s.ecnAlpha = (1 - bbr2ECNAlphaGain) * s.ecnAlpha
+	}
+	if s.lostInRound == 0 && !s.ceInRound {
+		return
+	}
+	inflightLo := s.inflightLo
+	if inflightLo == 0 {
+		inflightLo = b.congestionWindow
+	}
+	if s.lostInRound > 0 {
+		inflightLo = utils.MaxByteCount(s.ackedInRound, protocol.ByteCount(bbr2Beta*float64(inflightLo)))
+	}
+	if s.ceInRound {
+		inflightLo = protocol.ByteCount((1 - bbr2ECNFactor*s.ecnAlpha) * float64(inflightLo))
+	}
+	s.inflightLo = utils.MaxByteCount(inflightLo, b.minCongestionWindow())
+}
+
+func (b *bbrSender) bbr2OnPacketLost(lostBytes, priorInFlight protocol.ByteCount) {
+	s := b.v2
+	s.lostInRound += lostBytes
+	if s.inflightTooHighInRound {
+		return
+	}
+	if float64(s.lostInRound) <= bbr2LossThresh*float64(s.ackedInRound+s.lostInRound) {
+		return
+	}
+	// The loss rate exceeded the threshold. Reduce inflight_hi, at most once per round trip.
+	s.inflightTooHighInRound = true
+	s.probeUpRounds = 0
+	if b.mode == bbrModeStartup {
+		// Excessive loss indicates that the pipe is full.
+		b.isAtFullBandwidth = true
+	}
+	s.inflightHi = utils.MaxByteCount(
+		protocol.ByteCount(bbr2Beta*float64(priorInFlight)),
+		b.targetCongestionWindow(bbr2Beta),
+	)
+}
+
+func (b *bbrSender) bbr2OnCongestionEvent() {
+	b.v2.ceInRound = true
+}
+
+// isProbingBandwidth says if the sender is in the ProbeBW phase that probes for more bandwidth.
+func (b *bbrSender) isProbingBandwidth() bool {
+	return b.mode == bbrModeProbeBW && b.pacingGain > 1
+}
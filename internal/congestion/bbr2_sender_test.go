@@ -0,0 +1,195 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BBRv2 Sender", func() {
+	const (
+		rtt              = 100 * time.Millisecond
+		transmissionTime = time.Millisecond
+	)
+	var (
+		sender        *bbrSender
+		clock         mockClock
+		bytesInFlight protocol.ByteCount
+		packetNumber  protocol.PacketNumber
+		rttStats      *utils.RTTStats
+	)
+
+	BeforeEach(func() {
+		bytesInFlight = 0
+		packetNumber = 1
+		clock = mockClock{}
+		clock.Advance(time.Hour)
+		rttStats = utils.NewRTTStats()
+		sender = NewBbr2Sender(&clock, rttStats, maxDatagramSize, CongestionOptions{MaxCongestionWindow: MaxCongestionWindow}, nil)
+	})
+
+	sendAvailableSendWindow := func() []protocol.PacketNumber {
+		var sent []protocol.PacketNumber
+		for sender.CanSend(bytesInFlight) {
+			bytesInFlight += maxDatagramSize
+			sender.OnPacketSent(clock.Now(), bytesInFlight, packetNumber, maxDatagramSize, true)
+			sent = append(sent, packetNumber)
+			packetNumber++
+		}
+		return sent
+	}
+
+	ackPacket := func(pn protocol.PacketNumber) {
+		rttStats.UpdateRTT(rtt, 0, clock.Now())
+		sender.OnRttUpdated()
+		sender.OnPacketAcked(pn, maxDatagramSize, bytesInFlight, clock.Now())
+		bytesInFlight -= maxDatagramSize
+	}
+
+	losePacket := func(pn protocol.PacketNumber) {
+		sender.OnPacketLost(pn, maxDatagramSize, bytesInFlight)
+		bytesInFlight -= maxDatagramSize
+	}
+
+	// simulate sends packets whenever the congestion window allows, over a link with a bandwidth of one packet per transmissionTime.
+	simulate := func(d time.Duration) {
+		type inFlightPacket struct {
+			pn      protocol.PacketNumber
+			ackTime time.Time
+		}
+		var (
+			queue       []inFlightPacket
+			lastAckTime time.Time
+		)
+		end := clock.Now().Add(d)
+		for clock.Now().Before(end) {
+			for _, pn := range sendAvailableSendWindow() {
+				ackTime := clock.Now().Add(rtt)
+				if next := lastAckTime.Add(transmissionTime); next.After(ackTime) {
+					ackTime = next
+				}
+				lastAckTime = ackTime
+				queue = append(queue, inFlightPacket{pn: pn, ackTime: ackTime})
+			}
+			if len(queue) == 0 {
+				break
+			}
+			p := queue[0]
+			queue = queue[1:]
+			clock = mockClock(p.ackTime)
+			ackPacket(p.pn)
+		}
+		for _, p := range queue {
+			clock = mockClock(utils.MaxTime(p.ackTime, clock.Now()))
+			ackPacket(p.pn)
+		}
+	}
+
+	It("reduces inflight_hi when the loss rate is too high", func() {
+		simulate(3 * time.Second)
+		Expect(sender.mode).To(Equal(bbrModeProbeBW))
+		Expect(sender.v2.inflightHi).To(BeZero())
+		cwnd := sender.GetCongestionWindow()
+		sent := sendAvailableSendWindow()
+		for _, pn := range sent[:len(sent)/2] {
+			losePacket(pn)
+		}
+		Expect(sender.v2.inflightHi).ToNot(BeZero())
+		Expect(sender.v2.inflightHi).To(BeNumerically("<", cwnd))
+		Expect(sender.v2.inflightHi).To(BeNumerically(">=", sender.minCongestionWindow()))
+		// inflight_hi is only reduced once per round trip
+		inflightHi := sender.v2.inflightHi
+		losePacket(sent[len(sent)-1])
+		Expect(sender.v2.inflightHi).To(Equal(inflightHi))
+		for _, pn := range sent[len(sent)/2 : len(sent)-1] {
+			clock.Advance(transmissionTime)
+			ackPacket(pn)
+		}
+		Expect(sender.GetCongestionWindow()).To(BeNumerically("<=", inflightHi))
+	})
+
+	It("doesn't reduce inflight_hi for low loss rates", func() {
+		simulate(3 * time.Second)
+		sent := sendAvailableSendWindow()
+		for _, pn := range sent[:len(sent)-1] {
+			clock.Advance(transmissionTime)
+			ackPacket(pn)
+		}
+		losePacket(sent[len(sent)-1])
+		Expect(sender.v2.inflightHi).To(BeZero())
+	})
+
+	It("exits startup on excessive loss", func() {
+		sent := sendAvailableSendWindow()
+		clock.Advance(rtt)
+		ackPacket(sent[0])
+		Expect(sender.InSlowStart()).To(BeTrue())
+		for _, pn := range sent[1:4] {
+			losePacket(pn)
+		}
+		Expect(sender.isAtFullBandwidth).To(BeTrue())
+		Expect(sender.v2.inflightHi).ToNot(BeZero())
+		for _, pn := range sent[4:] {
+			ackPacket(pn)
+		}
+		Expect(sender.InSlowStart()).To(BeFalse())
+	})
+
+	It("reduces inflight_lo after a round trip with losses", func() {
+		simulate(3 * time.Second)
+		Expect(sender.v2.inflightLo).To(BeZero())
+		cwnd := sender.GetCongestionWindow()
+		sender.v2.ackedInRound = 10 * maxDatagramSize
+		sender.v2.lostInRound = maxDatagramSize
+		sender.bbr2AdaptLowerBounds()
+		Expect(sender.v2.inflightLo).To(Equal(protocol.ByteCount(bbr2Beta * float64(cwnd))))
+		Expect(sender.GetCongestionWindow()).To(Equal(sender.v2.inflightLo))
+		// round trips without losses don't change inflight_lo
+		sender.v2.lostInRound = 0
+		sender.bbr2AdaptLowerBounds()
+		Expect(sender.GetCongestionWindow()).To(Equal(protocol.ByteCount(bbr2Beta * float64(cwnd))))
+	})
+
+	It("reduces inflight_lo after a round trip with ECN-CE marks", func() {
+		simulate(3 * time.Second)
+		cwnd := sender.GetCongestionWindow()
+		sender.OnCongestionEvent(packetNumber, bytesInFlight)
+		sender.bbr2AdaptLowerBounds()
+		Expect(sender.v2.ecnAlpha).To(Equal(bbr2ECNAlphaGain))
+		Expect(sender.v2.inflightLo).To(BeNumerically("<", cwnd))
+		Expect(sender.v2.inflightLo).To(BeNumerically(">=", sender.minCongestionWindow()))
+		// the ECN alpha decays in round trips without ECN-CE marks
+		sender.v2.ceInRound = false
+		sender.bbr2AdaptLowerBounds()
+		Expect(sender.v2.ecnAlpha).To(BeNumerically("<", bbr2ECNAlphaGain))
+	})
+
+	It("doesn't reduce the congestion window below the minimum", func() {
+		simulate(time.Second)
+		for i := 0; i < 10; i++ {
+			for _, pn := range sendAvailableSendWindow() {
+				losePacket(pn)
+			}
+			sender.bbr2AdaptLowerBounds()
+			Expect(sender.GetCongestionWindow()).To(BeNumerically(">=", bbrMinCongestionWindowPackets*maxDatagramSize))
+		}
+	})
+
+	It("resets inflight_lo and grows inflight_hi when probing for bandwidth", func() {
+		simulate(3 * time.Second)
+		for sender.isProbingBandwidth() {
+			simulate(rtt)
+		}
+		sender.v2.inflightLo = 50 * maxDatagramSize
+		sender.v2.inflightHi = 100 * maxDatagramSize
+		for !sender.isProbingBandwidth() {
+			simulate(rtt)
+		}
+		Expect(sender.v2.inflightLo).To(BeZero())
+		simulate(rtt)
+		Expect(sender.v2.inflightHi).To(BeNumerically(">", 100*maxDatagramSize))
+	})
+})
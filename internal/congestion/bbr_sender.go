@@ -0,0 +1,685 @@
+package congestion
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+// This BBR implementation is based on the one found in Chromium's QUIC
+// implementation, in the files net/third_party/quiche/src/quic/core/congestion_control/bbr_sender.{h,cc},
+// and on https://datatracker.ietf.org/doc/html/draft-cardwell-iccrg-bbr-congestion-control.
+
+type bbrMode int
+
+const (
+	// Startup phase of the connection.
+	bbrModeStartup bbrMode = iota
+	// After achieving the highest possible bandwidth during the startup, lower
+	// the pacing rate in order to drain the queue.
+	bbrModeDrain
+	// Cruising mode.
+	bbrModeProbeBW
+	// Temporarily slow down sending in order to empty the buffer and measure
+	// the real minimum RTT.
+	bbrModeProbeRTT
+)
+
+func (m bbrMode) String() string {
+	switch m {
+	case bbrModeStartup:
+		return "startup"
+	case bbrModeDrain:
+		return "drain"
+	case bbrModeProbeBW:
+		return "probe_bw"
+	case bbrModeProbeRTT:
+		return "probe_rtt"
+	}
+	return fmt.Sprintf("unknown BBR mode: %d", int(m))
+}
+
+type bbrRecoveryState int
+
+const (
+	// Do not limit.
+	bbrRecoveryStateNotInRecovery bbrRecoveryState = iota
+	// Allow an extra outstanding byte for each byte acknowledged.
+	bbrRecoveryStateConservation
+	// Allow two extra outstanding bytes for each byte acknowledged (slow start).
+	bbrRecoveryStateGrowth
+)
+
+const (
+	// The gain used for the startup, equal to 2/ln(2).
+	bbrHighGain = 2.885
+	// The gain used in the drain phase, the inverse of the startup gain.
+	bbrDrainGain = 1 / bbrHighGain
+	// The cwnd gain used in the ProbeBW phase.
+	bbrCwndGain = 2.0
+	// The length of the gain cycle.
+	bbrGainCycleLength = 8
+	// The size of the bandwidth filter window, in round trips.
+	bbrBandwidthWindowRounds = bbrGainCycleLength + 2
+	// The time after which the current min RTT value expires.
+	bbrMinRTTExpiry = 10 * time.Second
+	// The minimum time the connection can spend in ProbeRTT mode.
+	bbrProbeRTTTime = 200 * time.Millisecond
+	// The minimum congestion window, in packets.
+	bbrMinCongestionWindowPackets = 4
+	// If the bandwidth grew by less than this factor, the round didn't yield a bandwidth gain.
+	bbrStartupGrowthTarget = 1.25
+	// The number of round trips without a bandwidth gain after which the pipe is considered full.
+	bbrRoundTripsWithoutGrowthBeforeExitingStartup = 3
+)
+
+// The pacing gains used in the ProbeBW phase.
+var bbrPacingGainCycle = [bbrGainCycleLength]float64{1.25, 0.75, 1, 1, 1, 1, 1, 1}
+
+type bbrSender struct {
+	rttStats     *utils.RTTStats
+	clock        Clock
+	pacer        *pacer
+	sampler      *bandwidthSampler
+	maxBandwidth *maxBandwidthFilter
+	rand         utils.Rand
+
+	mode bbrMode
+
+	// The number of round trips since the start of the connection.
+	roundTripCount uint64
+	// The value of delivered at which the current round trip ends.
+	currentRoundTripEnd protocol.ByteCount
+
+	// The minimum RTT, measured over the bbrMinRTTExpiry interval.
+	minRTT          time.Duration
+	minRTTTimestamp time.Time
+
+	pacingGain    float64
+	cwndGain      float64
+	pacingRate    Bandwidth
+	disablePacing bool
+
+	congestionWindow        protocol.ByteCount
+	initialCongestionWindow protocol.ByteCount
+	maxCongestionWindow     protocol.ByteCount
+	maxDatagramSize         protocol.ByteCount
+	// The configured minimum congestion window. If zero, the default is used.
+	configuredMinCongestionWindow protocol.ByteCount
+
+	// Set when the bandwidth stopped growing during startup.
+	isAtFullBandwidth          bool
+	bandwidthAtLastRound       Bandwidth
+	roundsWithoutBandwidthGain int
+	lastSampleIsAppLimited     bool
+
+	// The index of the current gain cycle phase in ProbeBW mode.
+	cycleCurrentOffset int
+	// The time at which the last pacing gain cycle was started.
+	lastCycleStart time.Time
+
+	// The time at which ProbeRTT will be exited. Zero until it is scheduled.
+	probeRTTDoneTime    time.Time
+	probeRTTRoundPassed bool
+
+	largestSentPacketNumber  protocol.PacketNumber
+	largestAckedPacketNumber protocol.PacketNumber
+	// The largest packet number sent when entering recovery.
+	endRecoveryAt protocol.PacketNumber
+	// The packet whose loss caused entering recovery.
+	// Set to InvalidPacketNumber if recovery wasn't entered due to a loss.
+	recoveryPacketNumber protocol.PacketNumber
+	recoveryState        bbrRecoveryState
+	recoveryWindow       protocol.ByteCount
+
+	// Only set for BBRv2.
+	v2 *bbr2State
+
+	lastState                  logging.CongestionState
+	lastTracedCongestionWindow protocol.ByteCount
+	tracer                     logging.ConnectionTracer
+}
+
+var (
+	_ SendAlgorithm         = &bbrSender{}
+	_ ExtendedSendAlgorithm = &bbrSender{}
+)
+
+// NewBbrSender makes a new BBR sender
+func NewBbrSender(
+	clock Clock,
+	rttStats *utils.RTTStats,
+	initialMaxDatagramSize protocol.ByteCount,
+	options CongestionOptions,
+	tracer logging.ConnectionTracer,
+) *bbrSender {
+	maxCongestionWindow := protocol.MaxCongestionWindowPackets * initialMaxDatagramSize
+	if options.MaxCongestionWindow != 0 {
+		maxCongestionWindow = options.MaxCongestionWindow
+	}
+	minCongestionWindow := bbrMinCongestionWindowPackets * initialMaxDatagramSize
+	if options.MinCongestionWindow != 0 {
+		minCongestionWindow = options.MinCongestionWindow
+	}
+	return newBbrSender(
+		clock,
+		rttStats,
+		initialMaxDatagramSize,
+		initialCongestionWindowFromOptions(options, initialMaxDatagramSize, minCongestionWindow, maxCongestionWindow),
+		maxCongestionWindow,
+		options,
+		tracer,
+	)
+}
+
+func newBbrSender(
+	clock Clock,
+	rttStats *utils.RTTStats,
+	initialMaxDatagramSize,
+	initialCongestionWindow,
+	maxCongestionWindow protocol.ByteCount,
+	options CongestionOptions,
+	tracer logging.ConnectionTracer,
+) *bbrSender {
+	b := &bbrSender{
+		rttStats:                      rttStats,
+		clock:                         clock,
+		sampler:                       newBandwidthSampler(),
+		maxBandwidth:                  newMaxBandwidthFilter(bbrBandwidthWindowRounds),
+		congestionWindow:              initialCongestionWindow,
+		initialCongestionWindow:       initialCongestionWindow,
+		maxCongestionWindow:           maxCongestionWindow,
+		configuredMinCongestionWindow: options.MinCongestionWindow,
+		maxDatagramSize:               initialMaxDatagramSize,
+		disablePacing:                 options.DisablePacing,
+		largestSentPacketNumber:       protocol.InvalidPacketNumber,
+		largestAckedPacketNumber:      protocol.InvalidPacketNumber,
+		endRecoveryAt:                 protocol.InvalidPacketNumber,
+		recoveryPacketNumber:          protocol.InvalidPacketNumber,
+		tracer:                        tracer,
+	}
+	b.pacer = newPacerWithAdjustedBandwidth(func() uint64 {
+		// Bandwidth is in bits/s. We need the value in bytes/s.
+		return uint64(b.PacingRate() / BytesPerSecond)
+	})
+	b.pacer.SetMaxDatagramSize(initialMaxDatagramSize)
+	b.pacer.SetMaxBurstSize(options.MaxPacingBurst)
+	b.enterStartupMode()
+	if b.tracer != nil {
+		b.lastState = logging.CongestionStateSlowStart
+		b.tracer.UpdatedCongestionState(logging.CongestionStateSlowStart)
+	}
+	return b
+}
+
+// TimeUntilSend returns when the next packet should be sent.
+func (b *bbrSender) TimeUntilSend(_ protocol.ByteCount) time.Time {
+	if b.disablePacing {
+		return time.Time{}
+	}
+	return b.pacer.TimeUntilSend()
+}
+
+func (b *bbrSender) HasPacingBudget() bool {
+	if b.disablePacing {
+		return true
+	}
+	return b.pacer.Budget(b.clock.Now()) >= b.maxDatagramSize
+}
+
+func (b *bbrSender) OnPacketSent(
+	sentTime time.Time,
+	bytesInFlight protocol.ByteCount,
+	packetNumber protocol.PacketNumber,
+	bytes protocol.ByteCount,
+	isRetransmittable bool,
+) {
+	b.pacer.SentPacket(sentTime, bytes)
+	if !isRetransmittable {
+		return
+	}
+	b.largestSentPacketNumber = packetNumber
+	b.sampler.OnPacketSent(sentTime, packetNumber, bytes, bytesInFlight)
+}
+
+func (b *bbrSender) CanSend(bytesInFlight protocol.ByteCount) bool {
+	return bytesInFlight < b.GetCongestionWindow()
+}
+
+func (b *bbrSender) InRecovery() bool {
+	return b.largestAckedPacketNumber != protocol.InvalidPacketNumber && b.largestAckedPacketNumber <= b.endRecoveryAt
+}
+
+// InSlowStart returns true during the BBR startup phase.
+func (b *bbrSender) InSlowStart() bool {
+	return b.mode == bbrModeStartup
+}
+
+func (b *bbrSender) GetCongestionWindow() protocol.ByteCount {
+	if b.mode == bbrModeProbeRTT {
+		return b.probeRTTCongestionWindow()
+	}
+	cwnd := b.congestionWindow
+	if b.v2 != nil {
+		cwnd = b.v2.boundCongestionWindow(cwnd, b.minCongestionWindow())
+	}
+	if b.InRecovery() {
+		return utils.MinByteCount(cwnd, b.recoveryWindow)
+	}
+	return cwnd
+}
+
+func (b *bbrSender) OnRttUpdated() {}
+
+func (b *bbrSender) OnPacketAcked(
+	ackedPacketNumber protocol.PacketNumber,
+	ackedBytes protocol.ByteCount,
+	priorInFlight protocol.ByteCount,
+	eventTime time.Time,
+) {
+	b.largestAckedPacketNumber = utils.MaxPacketNumber(ackedPacketNumber, b.largestAckedPacketNumber)
+	var bytesInFlight protocol.ByteCount
+	if priorInFlight > ackedBytes {
+		bytesInFlight = priorInFlight - ackedBytes
+	}
+
+	var isRoundStart, minRTTExpired bool
+	if sample, ok := b.sampler.OnPacketAcked(ackedPacketNumber, eventTime); ok {
+		isRoundStart = b.updateRoundTripCounter(sample.priorDelivered)
+		b.lastSampleIsAppLimited = sample.isAppLimited
+		if sample.bandwidth > 0 && (!sample.isAppLimited || sample.bandwidth >= b.maxBandwidth.GetBest()) {
+			b.maxBandwidth.Update(sample.bandwidth, b.roundTripCount)
+		}
+		minRTTExpired = b.updateMinRTT(sample.rtt, eventTime)
+	}
+	if b.v2 != nil {
+		b.bbr2OnPacketAcked(isRoundStart, ackedBytes, priorInFlight)
+	}
+
+	if b.mode == bbrModeProbeBW {
+		b.updateGainCyclePhase(eventTime, priorInFlight)
+	}
+	if isRoundStart && !b.isAtFullBandwidth {
+		b.checkIfFullBandwidthReached()
+	}
+	b.maybeExitStartupOrDrain(eventTime, bytesInFlight)
+	b.maybeEnterOrExitProbeRTT(eventTime, isRoundStart, minRTTExpired, bytesInFlight)
+
+	b.updateRecoveryState(isRoundStart, ackedBytes, priorInFlight)
+	b.calculatePacingRate()
+	b.calculateCongestionWindow(ackedBytes)
+
+	if !b.isCwndLimited(priorInFlight) {
+		b.sampler.OnAppLimited(bytesInFlight)
+	}
+	b.maybeTraceStateChange()
+	b.maybeTraceMetricsChange(bytesInFlight)
+}
+
+func (b *bbrSender) OnPacketLost(packetNumber protocol.PacketNumber, lostBytes, priorInFlight protocol.ByteCount) {
+	b.sampler.OnPacketLost(packetNumber)
+	var bytesInFlight protocol.ByteCount
+	if priorInFlight > lostBytes {
+		bytesInFlight = priorInFlight - lostBytes
+	}
+	defer b.maybeTraceMetricsChange(bytesInFlight)
+	if b.v2 != nil {
+		b.bbr2OnPacketLost(lostBytes, priorInFlight)
+	}
+	if packetNumber <= b.endRecoveryAt {
+		// Further losses in the current recovery period reduce the recovery window.
+		if b.recoveryWindow > lostBytes {
+			b.recoveryWindow -= lostBytes
+		} else {
+			b.recoveryWindow = 0
+		}
+		b.recoveryWindow = utils.MaxByteCount(b.recoveryWindow, b.minCongestionWindow())
+		return
+	}
+	// Enter recovery. Start with packet conservation.
+	b.endRecoveryAt = b.largestSentPacketNumber
+	b.recoveryPacketNumber = packetNumber
+	b.recoveryState = bbrRecoveryStateConservation
+	b.recoveryWindow = utils.MaxByteCount(bytesInFlight, b.minCongestionWindow())
+	b.maybeTraceStateChange()
+}
+
+// OnSpuriousLoss is called when a packet that was declared lost is acknowledged.
+// If the loss of this packet caused entering recovery, recovery is exited.
+func (b *bbrSender) OnSpuriousLoss(packetNumber protocol.PacketNumber) {
+	if b.recoveryPacketNumber == protocol.InvalidPacketNumber || packetNumber != b.recoveryPacketNumber {
+		return
+	}
+	b.endRecoveryAt = protocol.InvalidPacketNumber
+	b.recoveryPacketNumber = protocol.InvalidPacketNumber
+	b.recoveryState = bbrRecoveryStateNotInRecovery
+	b.maybeTraceStateChange()
+}
+
+// OnAppLimited is called when the application doesn't have enough data to fill the congestion window.
+// The bandwidth samples taken until the packets sent so far are acknowledged are then marked as application-limited,
+// so that they don't reduce the bandwidth estimate.
+func (b *bbrSender) OnAppLimited(bytesInFlight protocol.ByteCount) {
+	b.sampler.OnAppLimited(bytesInFlight)
+}
+
+// OnCongestionEvent is called when the peer reports ECN-CE marks.
+// BBR doesn't use ECN as a congestion signal, BBRv2 reduces inflight_lo.
+func (b *bbrSender) OnCongestionEvent(protocol.PacketNumber, protocol.ByteCount) {
+	if b.v2 != nil {
+		b.bbr2OnCongestionEvent()
+	}
+}
+
+// OnRetransmissionTimeout is called on an retransmission timeout
+func (b *bbrSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
+	b.endRecoveryAt = protocol.InvalidPacketNumber
+	b.recoveryPacketNumber = protocol.InvalidPacketNumber
+	b.recoveryState = bbrRecoveryStateNotInRecovery
+	if !packetsRetransmitted {
+		return
+	}
+	b.sampler.Reset()
+}
+
+func (b *bbrSender) SetMaxDatagramSize(s protocol.ByteCount) {
+	if s < b.maxDatagramSize {
+		panic(fmt.Sprintf("congestion BUG: decreased max datagram size from %d to %d", b.maxDatagramSize, s))
+	}
+	cwndIsMinCwnd := b.congestionWindow == b.minCongestionWindow()
+	b.maxDatagramSize = s
+	if cwndIsMinCwnd {
+		b.congestionWindow = b.minCongestionWindow()
+	}
+	b.pacer.SetMaxDatagramSize(s)
+}
+
+// BandwidthEstimate returns the current estimate of the bottleneck bandwidth.
+func (b *bbrSender) BandwidthEstimate() Bandwidth {
+	return b.maxBandwidth.GetBest()
+}
+
+func (b *bbrSender) ControlType() (CongestionControlType, HystartControlType) {
+	if b.v2 != nil {
+		return Bbr2ControlType, HystartTypeNone
+	}
+	return BbrControlType, HystartTypeNone
+}
+
+// PacingRate returns the rate at which packets are currently paced.
+func (b *bbrSender) PacingRate() Bandwidth {
+	if b.pacingRate == 0 {
+		// We don't have a bandwidth sample yet. Pace the initial window over one RTT.
+		srtt := b.rttStats.SmoothedRTT()
+		if srtt == 0 {
+			return infBandwidth
+		}
+		return Bandwidth(bbrHighGain * float64(BandwidthFromDelta(b.initialCongestionWindow, srtt)))
+	}
+	return b.pacingRate
+}
+
+func (b *bbrSender) minCongestionWindow() protocol.ByteCount {
+	if b.configuredMinCongestionWindow != 0 {
+		return b.configuredMinCongestionWindow
+	}
+	return b.maxDatagramSize * bbrMinCongestionWindowPackets
+}
+
+func (b *bbrSender) probeRTTCongestionWindow() protocol.ByteCount {
+	return b.minCongestionWindow()
+}
+
+// bandwidthDelayProduct returns the estimated BDP for the given gain,
+// or 0 if there's no estimate.
+func (b *bbrSender) bandwidthDelayProduct(gain float64) protocol.ByteCount {
+	if b.minRTT == 0 {
+		return 0
+	}
+	bdp := uint64(b.maxBandwidth.GetBest()/BytesPerSecond) * uint64(b.minRTT) / uint64(time.Second)
+	return protocol.ByteCount(gain * float64(bdp))
+}
+
+func (b *bbrSender) targetCongestionWindow(gain float64) protocol.ByteCount {
+	cwnd := b.bandwidthDelayProduct(gain)
+	if cwnd == 0 {
+		cwnd = protocol.ByteCount(gain * float64(b.initialCongestionWindow))
+	}
+	return utils.MaxByteCount(cwnd, b.minCongestionWindow())
+}
+
+func (b *bbrSender) isCwndLimited(bytesInFlight protocol.ByteCount) bool {
+	congestionWindow := b.GetCongestionWindow()
+	if bytesInFlight >= congestionWindow {
+		return true
+	}
+	availableBytes := congestionWindow - bytesInFlight
+	slowStartLimited := b.InSlowStart() && bytesInFlight > congestionWindow/2
+	return slowStartLimited || availableBytes <= maxBurstPackets*b.maxDatagramSize
+}
+
+// updateRoundTripCounter returns true if a new round trip started.
+func (b *bbrSender) updateRoundTripCounter(priorDelivered protocol.ByteCount) bool {
+	if priorDelivered < b.currentRoundTripEnd {
+		return false
+	}
+	b.roundTripCount++
+	b.currentRoundTripEnd = b.sampler.TotalBytesAcked()
+	return true
+}
+
+// updateMinRTT returns true if the min RTT expired.
+func (b *bbrSender) updateMinRTT(sampleRTT time.Duration, now time.Time) bool {
+	minRTTExpired := b.minRTT != 0 && now.After(b.minRTTTimestamp.Add(bbrMinRTTExpiry))
+	if sampleRTT > 0 && (minRTTExpired || sampleRTT < b.minRTT || b.minRTT == 0) {
+		b.minRTT = sampleRTT
+		b.minRTTTimestamp = now
+	}
+	return minRTTExpired
+}
+
+func (b *bbrSender) enterStartupMode() {
+	b.mode = bbrModeStartup
+	b.pacingGain = bbrHighGain
+	b.cwndGain = bbrHighGain
+}
+
+func (b *bbrSender) enterProbeBandwidthMode(now time.Time) {
+	b.mode = bbrModeProbeBW
+	b.cwndGain = bbrCwndGain
+	// Pick a random offset for the gain cycle out of {0, 2..7} range. 1 is
+	// excluded because in that case increased gain and decreased gain would not
+	// follow each other.
+	b.cycleCurrentOffset = int(b.rand.Int31n(bbrGainCycleLength - 1))
+	if b.cycleCurrentOffset >= 1 {
+		b.cycleCurrentOffset++
+	}
+	b.lastCycleStart = now
+	b.pacingGain = bbrPacingGainCycle[b.cycleCurrentOffset]
+}
+
+func (b *bbrSender) updateGainCyclePhase(now time.Time, priorInFlight protocol.ByteCount) {
+	// In most cases, the cycle is advanced after an RTT passes.
+	shouldAdvanceGainCycling := now.Sub(b.lastCycleStart) > b.minRTT
+	// If the pacing gain is above 1.0, the connection is trying to probe the
+	// bandwidth by increasing the number of bytes in flight to at least
+	// pacing_gain * BDP. Make sure that it actually reaches the target, as long
+	// as there are no losses suggesting that the buffers are not able to hold
+	// that much.
+	if b.pacingGain > 1 && !b.InRecovery() && priorInFlight < b.targetCongestionWindow(b.pacingGain) {
+		shouldAdvanceGainCycling = false
+	}
+	// If pacing gain is below 1.0, the connection is trying to drain the extra
+	// queue which could have been incurred by probing prior to it. If the number
+	// of bytes in flight falls down to the estimated BDP value earlier, conclude
+	// that the queue has been successfully drained and exit this cycle early.
+	if b.pacingGain < 1 && priorInFlight <= b.targetCongestionWindow(1) {
+		shouldAdvanceGainCycling = true
+	}
+	if shouldAdvanceGainCycling {
+		b.cycleCurrentOffset = (b.cycleCurrentOffset + 1) % bbrGainCycleLength
+		b.lastCycleStart = now
+		b.pacingGain = bbrPacingGainCycle[b.cycleCurrentOffset]
+		if b.v2 != nil && b.pacingGain > 1 {
+			b.v2.resetLowerBounds()
+		}
+	}
+}
+
+func (b *bbrSender) checkIfFullBandwidthReached() {
+	if b.lastSampleIsAppLimited {
+		return
+	}
+	target := Bandwidth(bbrStartupGrowthTarget * float64(b.bandwidthAtLastRound))
+	if bw := b.maxBandwidth.GetBest(); bw >= target {
+		b.bandwidthAtLastRound = bw
+		b.roundsWithoutBandwidthGain = 0
+		return
+	}
+	b.roundsWithoutBandwidthGain++
+	if b.roundsWithoutBandwidthGain >= bbrRoundTripsWithoutGrowthBeforeExitingStartup {
+		b.isAtFullBandwidth = true
+	}
+}
+
+func (b *bbrSender) maybeExitStartupOrDrain(now time.Time, bytesInFlight protocol.ByteCount) {
+	if b.mode == bbrModeStartup && b.isAtFullBandwidth {
+		b.mode = bbrModeDrain
+		b.pacingGain = bbrDrainGain
+		b.cwndGain = bbrHighGain
+	}
+	if b.mode == bbrModeDrain && bytesInFlight <= b.targetCongestionWindow(1) {
+		b.enterProbeBandwidthMode(now)
+	}
+}
+
+func (b *bbrSender) maybeEnterOrExitProbeRTT(now time.Time, isRoundStart, minRTTExpired bool, bytesInFlight protocol.ByteCount) {
+	if minRTTExpired && b.mode != bbrModeProbeRTT {
+		b.mode = bbrModeProbeRTT
+		b.pacingGain = 1
+		// Do not decide on the time to exit ProbeRTT until the bytes in flight
+		// drop to the ProbeRTT congestion window.
+		b.probeRTTDoneTime = time.Time{}
+	}
+	if b.mode != bbrModeProbeRTT {
+		return
+	}
+	b.sampler.OnAppLimited(bytesInFlight)
+	if b.probeRTTDoneTime.IsZero() {
+		if bytesInFlight < b.probeRTTCongestionWindow()+b.maxDatagramSize {
+			b.probeRTTDoneTime = now.Add(bbrProbeRTTTime)
+			b.probeRTTRoundPassed = false
+		}
+		return
+	}
+	if isRoundStart {
+		b.probeRTTRoundPassed = true
+	}
+	if !now.Before(b.probeRTTDoneTime) && b.probeRTTRoundPassed {
+		b.minRTTTimestamp = now
+		if !b.isAtFullBandwidth {
+			b.enterStartupMode()
+		} else {
+			b.enterProbeBandwidthMode(now)
+		}
+	}
+}
+
+func (b *bbrSender) updateRecoveryState(isRoundStart bool, ackedBytes, priorInFlight protocol.ByteCount) {
+	if b.recoveryState == bbrRecoveryStateNotInRecovery {
+		return
+	}
+	if !b.InRecovery() {
+		// A packet sent after entering recovery was acknowledged.
+		b.recoveryState = bbrRecoveryStateNotInRecovery
+		return
+	}
+	if isRoundStart && b.recoveryState == bbrRecoveryStateConservation {
+		b.recoveryState = bbrRecoveryStateGrowth
+	}
+	switch b.recoveryState {
+	case bbrRecoveryStateConservation:
+		b.recoveryWindow = utils.MaxByteCount(b.recoveryWindow, priorInFlight)
+	case bbrRecoveryStateGrowth:
+		b.recoveryWindow += ackedBytes
+	}
+	b.recoveryWindow = utils.MaxByteCount(b.recoveryWindow, b.minCongestionWindow())
+}
+
+func (b *bbrSender) calculatePacingRate() {
+	bw := b.maxBandwidth.GetBest()
+	if bw == 0 {
+		return
+	}
+	targetRate := Bandwidth(b.pacingGain * float64(bw))
+	if b.isAtFullBandwidth {
+		b.pacingRate = targetRate
+		return
+	}
+	// Pace at the rate of initial_window / RTT as soon as RTT measurements are available.
+	if b.pacingRate == 0 && b.minRTT != 0 {
+		b.pacingRate = BandwidthFromDelta(b.initialCongestionWindow, b.minRTT)
+		return
+	}
+	// Do not decrease the pacing rate during startup.
+	if targetRate > b.pacingRate {
+		b.pacingRate = targetRate
+	}
+}
+
+func (b *bbrSender) calculateCongestionWindow(ackedBytes protocol.ByteCount) {
+	if b.mode == bbrModeProbeRTT {
+		return
+	}
+	targetWindow := b.targetCongestionWindow(b.cwndGain)
+	if b.isAtFullBandwidth {
+		// Slowly grow the congestion window towards the target.
+		b.congestionWindow = utils.MinByteCount(targetWindow, b.congestionWindow+ackedBytes)
+	} else if b.congestionWindow < targetWindow || b.sampler.TotalBytesAcked() < b.initialCongestionWindow {
+		// If the connection is not yet out of startup phase, do not decrease the window.
+		b.congestionWindow += ackedBytes
+	}
+	b.congestionWindow = utils.MaxByteCount(b.congestionWindow, b.minCongestionWindow())
+	b.congestionWindow = utils.MinByteCount(b.congestionWindow, b.maxCongestionWindow)
+}
+
+func (b *bbrSender) maybeTraceStateChange() {
+	if b.tracer == nil {
+		return
+	}
+	var new logging.CongestionState
+	switch {
+	case b.InRecovery():
+		new = logging.CongestionStateRecovery
+	case b.mode == bbrModeStartup:
+		new = logging.CongestionStateSlowStart
+	case b.mode == bbrModeDrain:
+		new = logging.CongestionStateBBRDrain
+	case b.mode == bbrModeProbeRTT:
+		new = logging.CongestionStateBBRProbeRTT
+	default:
+		new = logging.CongestionStateBBRProbeBW
+	}
+	if new == b.lastState {
+		return
+	}
+	b.tracer.UpdatedCongestionState(new)
+	b.lastState = new
+}
+
+// maybeTraceMetricsChange traces the congestion window, if it changed.
+// BBR doesn't use a slow start threshold.
+func (b *bbrSender) maybeTraceMetricsChange(bytesInFlight protocol.ByteCount) {
+	if b.tracer == nil {
+		return
+	}
+	cwnd := b.GetCongestionWindow()
+	if cwnd == b.lastTracedCongestionWindow {
+		return
+	}
+	b.tracer.UpdatedCongestionMetrics(b.rttStats, cwnd, 0, bytesInFlight)
+	b.lastTracedCongestionWindow = cwnd
+}
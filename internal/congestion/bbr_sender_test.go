@@ -0,0 +1,303 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mocklogging "github.com/lucas-clemente/quic-go/internal/mocks/logging"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/logging"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BBR Sender", func() {
+	const (
+		rtt = 100 * time.Millisecond
+		// the time it takes to send a single packet at the bottleneck bandwidth of ~9.6 Mbit/s
+		transmissionTime = time.Millisecond
+	)
+	var (
+		sender        *bbrSender
+		clock         mockClock
+		bytesInFlight protocol.ByteCount
+		packetNumber  protocol.PacketNumber
+		rttStats      *utils.RTTStats
+	)
+	bottleneckBandwidth := BandwidthFromDelta(maxDatagramSize, transmissionTime)
+
+	BeforeEach(func() {
+		bytesInFlight = 0
+		packetNumber = 1
+		clock = mockClock{}
+		clock.Advance(time.Hour)
+		rttStats = utils.NewRTTStats()
+		sender = newBbrSender(
+			&clock,
+			rttStats,
+			maxDatagramSize,
+			initialCongestionWindowPackets*maxDatagramSize,
+			MaxCongestionWindow,
+			CongestionOptions{},
+			nil,
+		)
+	})
+
+	sendAvailableSendWindow := func() []protocol.PacketNumber {
+		var sent []protocol.PacketNumber
+		for sender.CanSend(bytesInFlight) {
+			bytesInFlight += maxDatagramSize
+			sender.OnPacketSent(clock.Now(), bytesInFlight, packetNumber, maxDatagramSize, true)
+			sent = append(sent, packetNumber)
+			packetNumber++
+		}
+		return sent
+	}
+
+	ackPacket := func(pn protocol.PacketNumber) {
+		rttStats.UpdateRTT(rtt, 0, clock.Now())
+		sender.OnRttUpdated()
+		sender.OnPacketAcked(pn, maxDatagramSize, bytesInFlight, clock.Now())
+		bytesInFlight -= maxDatagramSize
+	}
+
+	// ackPackets acknowledges the packets one RTT later, spaced by the transmission time of the bottleneck link.
+	ackPackets := func(pns []protocol.PacketNumber) {
+		clock.Advance(rtt)
+		for _, pn := range pns {
+			clock.Advance(transmissionTime)
+			ackPacket(pn)
+		}
+	}
+
+	// simulate sends packets whenever the congestion window allows, over a link with the bottleneck bandwidth.
+	simulate := func(d time.Duration) {
+		type inFlightPacket struct {
+			pn      protocol.PacketNumber
+			ackTime time.Time
+		}
+		var (
+			queue       []inFlightPacket
+			lastAckTime time.Time
+		)
+		end := clock.Now().Add(d)
+		for clock.Now().Before(end) {
+			for _, pn := range sendAvailableSendWindow() {
+				ackTime := clock.Now().Add(rtt)
+				if next := lastAckTime.Add(transmissionTime); next.After(ackTime) {
+					ackTime = next
+				}
+				lastAckTime = ackTime
+				queue = append(queue, inFlightPacket{pn: pn, ackTime: ackTime})
+			}
+			if len(queue) == 0 {
+				break
+			}
+			p := queue[0]
+			queue = queue[1:]
+			clock = mockClock(p.ackTime)
+			ackPacket(p.pn)
+		}
+		for _, p := range queue {
+			clock = mockClock(utils.MaxTime(p.ackTime, clock.Now()))
+			ackPacket(p.pn)
+		}
+	}
+
+	It("has the right values at startup", func() {
+		Expect(sender.InSlowStart()).To(BeTrue())
+		Expect(sender.InRecovery()).To(BeFalse())
+		Expect(sender.GetCongestionWindow()).To(Equal(initialCongestionWindowPackets * maxDatagramSize))
+		Expect(sender.TimeUntilSend(0)).To(BeZero())
+		Expect(sender.CanSend(0)).To(BeTrue())
+		Expect(sendAvailableSendWindow()).To(HaveLen(initialCongestionWindowPackets))
+		Expect(sender.CanSend(bytesInFlight)).To(BeFalse())
+	})
+
+	It("uses the configured initial congestion window", func() {
+		sender = NewBbrSender(&clock, rttStats, maxDatagramSize, CongestionOptions{InitialCongestionWindow: 5 * maxDatagramSize}, nil)
+		Expect(sendAvailableSendWindow()).To(HaveLen(5))
+		sender = NewBbrSender(&clock, rttStats, maxDatagramSize, CongestionOptions{InitialCongestionWindow: 1}, nil)
+		Expect(sender.GetCongestionWindow()).To(Equal(bbrMinCongestionWindowPackets * maxDatagramSize))
+	})
+
+	It("grows the congestion window during startup", func() {
+		ackPackets(sendAvailableSendWindow())
+		Expect(sender.InSlowStart()).To(BeTrue())
+		Expect(sender.GetCongestionWindow()).To(Equal(2 * initialCongestionWindowPackets * maxDatagramSize))
+		Expect(sender.BandwidthEstimate()).ToNot(BeZero())
+	})
+
+	It("paces", func() {
+		ackPackets(sendAvailableSendWindow())
+		sendAvailableSendWindow()
+		Expect(sender.TimeUntilSend(bytesInFlight)).ToNot(BeZero())
+		Expect(sender.PacingRate()).To(BeNumerically(">", sender.BandwidthEstimate()))
+	})
+
+	It("doesn't pace if pacing is disabled", func() {
+		sender = NewBbrSender(&clock, rttStats, maxDatagramSize, CongestionOptions{DisablePacing: true}, nil)
+		ackPackets(sendAvailableSendWindow())
+		for sender.CanSend(bytesInFlight) {
+			Expect(sender.HasPacingBudget()).To(BeTrue())
+			Expect(sender.TimeUntilSend(bytesInFlight)).To(BeZero())
+			sendAvailableSendWindow()
+		}
+		Expect(bytesInFlight).To(BeNumerically(">=", sender.GetCongestionWindow()))
+	})
+
+	It("exits startup when the bandwidth stops growing", func() {
+		simulate(3 * time.Second)
+		Expect(sender.InSlowStart()).To(BeFalse())
+		Expect(sender.isAtFullBandwidth).To(BeTrue())
+		Expect(sender.mode).To(Equal(bbrModeProbeBW))
+		Expect(sender.BandwidthEstimate()).To(BeNumerically("~", bottleneckBandwidth, bottleneckBandwidth/10))
+		// the congestion window is a multiple of the bandwidth-delay product
+		bdp := protocol.ByteCount(bottleneckBandwidth/BytesPerSecond) * protocol.ByteCount(rtt/time.Millisecond) / 1000
+		Expect(sender.GetCongestionWindow()).To(BeNumerically(">=", bdp))
+		Expect(sender.GetCongestionWindow()).To(BeNumerically("<=", 3*bdp))
+	})
+
+	It("enters and exits recovery", func() {
+		simulate(time.Second)
+		sent := sendAvailableSendWindow()
+		sender.OnPacketLost(sent[0], maxDatagramSize, bytesInFlight)
+		bytesInFlight -= maxDatagramSize
+		ackPackets(sent[1:2])
+		Expect(sender.InRecovery()).To(BeTrue())
+		Expect(sender.GetCongestionWindow()).To(BeNumerically("<=", bytesInFlight+2*maxDatagramSize))
+		// losing another packet sent before entering recovery doesn't start a new recovery period
+		endRecoveryAt := sender.endRecoveryAt
+		sender.OnPacketLost(sent[2], maxDatagramSize, bytesInFlight)
+		bytesInFlight -= maxDatagramSize
+		Expect(sender.endRecoveryAt).To(Equal(endRecoveryAt))
+		ackPackets(sent[3:])
+		// acknowledging a packet sent after entering recovery ends the recovery period
+		ackPackets(sendAvailableSendWindow())
+		Expect(sender.InRecovery()).To(BeFalse())
+	})
+
+	It("exits recovery when the loss was spurious", func() {
+		simulate(time.Second)
+		sent := sendAvailableSendWindow()
+		sender.OnPacketLost(sent[0], maxDatagramSize, bytesInFlight)
+		bytesInFlight -= maxDatagramSize
+		ackPackets(sent[1:2])
+		Expect(sender.InRecovery()).To(BeTrue())
+		// only the packet that caused entering recovery can end it
+		sender.OnSpuriousLoss(sent[1])
+		Expect(sender.InRecovery()).To(BeTrue())
+		sender.OnSpuriousLoss(sent[0])
+		Expect(sender.InRecovery()).To(BeFalse())
+		Expect(sender.recoveryState).To(Equal(bbrRecoveryStateNotInRecovery))
+	})
+
+	It("traces changes of the congestion window", func() {
+		mockCtrl := gomock.NewController(GinkgoT())
+		defer mockCtrl.Finish()
+		tracer := mocklogging.NewMockConnectionTracer(mockCtrl)
+		tracer.EXPECT().UpdatedCongestionState(logging.CongestionStateSlowStart)
+		sender = newBbrSender(&clock, rttStats, maxDatagramSize, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, CongestionOptions{}, tracer)
+		sendAvailableSendWindow()
+		gomock.InOrder(
+			tracer.EXPECT().UpdatedCongestionMetrics(rttStats, 11*maxDatagramSize, protocol.ByteCount(0), 9*maxDatagramSize),
+			tracer.EXPECT().UpdatedCongestionMetrics(rttStats, 12*maxDatagramSize, protocol.ByteCount(0), 8*maxDatagramSize),
+		)
+		clock.Advance(rtt)
+		ackPacket(1)
+		ackPacket(2)
+		gomock.InOrder(
+			tracer.EXPECT().UpdatedCongestionState(logging.CongestionStateRecovery),
+			tracer.EXPECT().UpdatedCongestionMetrics(rttStats, 7*maxDatagramSize, protocol.ByteCount(0), 7*maxDatagramSize),
+			// further losses in the same round trip reduce the recovery window
+			tracer.EXPECT().UpdatedCongestionMetrics(rttStats, 6*maxDatagramSize, protocol.ByteCount(0), 6*maxDatagramSize),
+		)
+		sender.OnPacketLost(3, maxDatagramSize, bytesInFlight)
+		bytesInFlight -= maxDatagramSize
+		sender.OnPacketLost(4, maxDatagramSize, bytesInFlight)
+	})
+
+	It("traces the BBR phases", func() {
+		mockCtrl := gomock.NewController(GinkgoT())
+		defer mockCtrl.Finish()
+		tracer := mocklogging.NewMockConnectionTracer(mockCtrl)
+		tracer.EXPECT().UpdatedCongestionMetrics(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		tracer.EXPECT().UpdatedCongestionState(logging.CongestionStateSlowStart)
+		sender = newBbrSender(&clock, rttStats, maxDatagramSize, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, CongestionOptions{}, tracer)
+		gomock.InOrder(
+			tracer.EXPECT().UpdatedCongestionState(logging.CongestionStateBBRDrain),
+			tracer.EXPECT().UpdatedCongestionState(logging.CongestionStateBBRProbeBW),
+		)
+		simulate(3 * time.Second)
+		Expect(sender.mode).To(Equal(bbrModeProbeBW))
+		// states are only traced when they change
+		simulate(time.Second)
+		tracer.EXPECT().UpdatedCongestionState(logging.CongestionStateBBRProbeRTT)
+		clock.Advance(bbrMinRTTExpiry)
+		ackPackets(sendAvailableSendWindow()[:1])
+		Expect(sender.mode).To(Equal(bbrModeProbeRTT))
+	})
+
+	It("enters ProbeRTT when the min RTT expires", func() {
+		simulate(3 * time.Second)
+		Expect(sender.mode).To(Equal(bbrModeProbeBW))
+		clock.Advance(bbrMinRTTExpiry)
+		sent := sendAvailableSendWindow()
+		ackPackets(sent[:1])
+		Expect(sender.mode).To(Equal(bbrModeProbeRTT))
+		Expect(sender.GetCongestionWindow()).To(Equal(bbrMinCongestionWindowPackets * maxDatagramSize))
+		ackPackets(sent[1:])
+		// ProbeRTT lasts at least 200ms and one round trip
+		simulate(bbrProbeRTTTime / 2)
+		Expect(sender.mode).To(Equal(bbrModeProbeRTT))
+		simulate(time.Second)
+		Expect(sender.mode).To(Equal(bbrModeProbeBW))
+	})
+
+	It("doesn't decrease the congestion window below the minimum", func() {
+		simulate(time.Second)
+		for i := 0; i < 10; i++ {
+			sent := sendAvailableSendWindow()
+			for _, pn := range sent {
+				sender.OnPacketLost(pn, maxDatagramSize, bytesInFlight)
+				bytesInFlight -= maxDatagramSize
+			}
+			Expect(sender.GetCongestionWindow()).To(BeNumerically(">=", bbrMinCongestionWindowPackets*maxDatagramSize))
+		}
+	})
+
+	It("doesn't decrease the congestion window below the configured minimum", func() {
+		const minCwnd = 8 * maxDatagramSize
+		sender = NewBbrSender(&clock, rttStats, maxDatagramSize, CongestionOptions{MinCongestionWindow: minCwnd}, nil)
+		simulate(time.Second)
+		for i := 0; i < 10; i++ {
+			for _, pn := range sendAvailableSendWindow() {
+				sender.OnPacketLost(pn, maxDatagramSize, bytesInFlight)
+				bytesInFlight -= maxDatagramSize
+			}
+			Expect(sender.GetCongestionWindow()).To(BeNumerically(">=", minCwnd))
+		}
+	})
+
+	It("limits the congestion window to the maximum", func() {
+		sender = newBbrSender(
+			&clock,
+			rttStats,
+			maxDatagramSize,
+			initialCongestionWindowPackets*maxDatagramSize,
+			20*maxDatagramSize,
+			CongestionOptions{},
+			nil,
+		)
+		simulate(time.Second)
+		Expect(sender.GetCongestionWindow()).To(Equal(20 * maxDatagramSize))
+	})
+
+	It("uses the configured maximum congestion window", func() {
+		const maxCwnd = 20 * maxDatagramSize
+		sender = NewBbrSender(&clock, rttStats, maxDatagramSize, CongestionOptions{MaxCongestionWindow: maxCwnd}, nil)
+		simulate(time.Second)
+		Expect(sender.GetCongestionWindow()).To(Equal(maxCwnd))
+	})
+})
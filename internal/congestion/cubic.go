@@ -42,6 +42,13 @@ type Cubic struct {
 	// Number of connections to simulate.
 	numConnections int
 
+	// Multiplicative decrease factor applied on a loss event.
+	betaFactor float32
+
+	// Apply the additional back off to the last max congestion window on
+	// consecutive losses ("fast convergence").
+	fastConvergence bool
+
 	// Time when this cycle started, after last loss event.
 	epoch time.Time
 
@@ -69,8 +76,10 @@ type Cubic struct {
 // NewCubic returns a new Cubic instance
 func NewCubic(clock Clock) *Cubic {
 	c := &Cubic{
-		clock:          clock,
-		numConnections: defaultNumConnections,
+		clock:           clock,
+		numConnections:  defaultNumConnections,
+		betaFactor:      beta,
+		fastConvergence: true,
 	}
 	c.Reset()
 	return c
@@ -100,7 +109,7 @@ func (c *Cubic) beta() float32 {
 	// emulation, which emulates the effective backoff of an ensemble of N
 	// TCP-Reno connections on a single loss event. The effective multiplier is
 	// computed as:
-	return (float32(c.numConnections) - 1 + beta) / float32(c.numConnections)
+	return (float32(c.numConnections) - 1 + c.betaFactor) / float32(c.numConnections)
 }
 
 func (c *Cubic) betaLastMax() float32 {
@@ -129,7 +138,7 @@ func (c *Cubic) OnApplicationLimited() {
 // a loss event. Returns the new congestion window in packets. The new
 // congestion window is a multiplicative decrease of our current window.
 func (c *Cubic) CongestionWindowAfterPacketLoss(currentCongestionWindow protocol.ByteCount) protocol.ByteCount {
-	if currentCongestionWindow+maxDatagramSize < c.lastMaxCongestionWindow {
+	if c.fastConvergence && currentCongestionWindow+maxDatagramSize < c.lastMaxCongestionWindow {
 		// We never reached the old max, so assume we are competing with another
 		// flow. Use our extra back off factor to allow the other flow to go up.
 		c.lastMaxCongestionWindow = protocol.ByteCount(c.betaLastMax() * float32(currentCongestionWindow))
@@ -208,6 +217,22 @@ func (c *Cubic) CongestionWindowAfterAck(
 	return targetCongestionWindow
 }
 
+// SetFastConvergence enables or disables fast convergence.
+// It is enabled by default.
+func (c *Cubic) SetFastConvergence(enabled bool) {
+	c.fastConvergence = enabled
+}
+
+// SetBeta sets the multiplicative decrease factor.
+// It must be in (0,1). A value of 0 restores the default of 0.7.
+func (c *Cubic) SetBeta(b float64) {
+	if b == 0 {
+		c.betaFactor = beta
+		return
+	}
+	c.betaFactor = float32(b)
+}
+
 // SetNumConnections sets the number of emulated connections
 func (c *Cubic) SetNumConnections(n int) {
 	c.numConnections = n
@@ -19,6 +19,13 @@ const (
 	initialCongestionWindow    = 32
 	// hystart++ constants
 	lssDivisor = 0.25
+	// delay-based backoff constants
+	// The congestion window is reduced when the smoothed RTT exceeds the min RTT by this factor ...
+	delayBackoffRTTThreshold = 1.25
+	// ... for this many consecutive round trips.
+	delayBackoffRounds = 2
+	// The congestion window is reduced less than on packet loss.
+	delayBackoffBeta = 0.85
 )
 
 type cubicSender struct {
@@ -27,10 +34,27 @@ type cubicSender struct {
 	rttStats            *utils.RTTStats
 	cubic               *Cubic
 	pacer               *pacer
+	disablePacing       bool
 	clock               Clock
+	// Only used for debugging, the congestion window doesn't depend on the delivery rate.
+	deliveryRate *deliveryRateEstimator
 
 	lowSlowStart bool
 	reno         bool
+	// The multiplicative decrease factor, only used for Reno.
+	renoBeta   float64
+	lssDivisor float64
+
+	// Proportional Rate Reduction, only used if enabled
+	enablePRR bool
+	prr       prrSender
+
+	// Delay-based backoff, only used if enabled
+	delayBasedBackoff bool
+	// The current round trip ends when a packet sent after this packet is acknowledged.
+	delayRoundEnd protocol.PacketNumber
+	// The number of consecutive round trips that ended with the smoothed RTT above the threshold.
+	delayRoundsAboveThreshold int
 
 	// Track the largest packet that has been sent.
 	largestSentPacketNumber protocol.PacketNumber
@@ -38,9 +62,23 @@ type cubicSender struct {
 	// Track the largest packet that has been acked.
 	largestAckedPacketNumber protocol.PacketNumber
 
+	// Acknowledgements for packets up to this packet number are application-limited samples,
+	// which don't grow the congestion window.
+	// Set to InvalidPacketNumber if the application is not limiting the sending rate.
+	appLimitedUntil protocol.PacketNumber
+
 	// Track the largest packet number outstanding when a CWND cutback occurs.
 	largestSentAtLastCutback protocol.PacketNumber
 
+	// The packet whose loss caused the last CWND cutback, and the state before that cutback.
+	// Used to undo the cutback if the loss turns out to be spurious.
+	// Set to InvalidPacketNumber if the last cutback can't be undone.
+	lossCutbackPacketNumber protocol.PacketNumber
+	undoCongestionWindow    protocol.ByteCount
+	undoSlowStartThreshold  protocol.ByteCount
+	undoLowSlowStart        bool
+	undoCubic               Cubic
+
 	// Whether the last loss event caused us to exit slowstart.
 	// Used for stats collection of slowstartPacketsLost
 	lastCutbackExitedSlowstart bool
@@ -56,16 +94,25 @@ type cubicSender struct {
 
 	initialCongestionWindow    protocol.ByteCount
 	initialMaxCongestionWindow protocol.ByteCount
+	// The configured minimum and maximum congestion window. If zero, the default is used.
+	configuredMinCongestionWindow protocol.ByteCount
+	configuredMaxCongestionWindow protocol.ByteCount
 
 	maxDatagramSize protocol.ByteCount
 
-	lastState logging.CongestionState
-	tracer    logging.ConnectionTracer
+	// Bytes in flight, as reported by OnPacketSent, OnPacketAcked and OnPacketLost.
+	// Only used for tracing.
+	bytesInFlight protocol.ByteCount
+
+	lastState                    logging.CongestionState
+	lastTracedCongestionWindow   protocol.ByteCount
+	lastTracedSlowStartThreshold protocol.ByteCount
+	tracer                       logging.ConnectionTracer
 }
 
 var (
-	_ SendAlgorithm               = &cubicSender{}
-	_ SendAlgorithmWithDebugInfos = &cubicSender{}
+	_ SendAlgorithm         = &cubicSender{}
+	_ ExtendedSendAlgorithm = &cubicSender{}
 )
 
 // NewCubicSender makes a new cubic sender
@@ -74,17 +121,25 @@ func NewCubicSender(
 	rttStats *utils.RTTStats,
 	initialMaxDatagramSize protocol.ByteCount,
 	reno bool,
-	hystart HystartControlType,
+	options CongestionOptions,
 	tracer logging.ConnectionTracer,
 ) *cubicSender {
+	maxCongestionWindow := protocol.MaxCongestionWindowPackets * initialMaxDatagramSize
+	if options.MaxCongestionWindow != 0 {
+		maxCongestionWindow = options.MaxCongestionWindow
+	}
+	minCongestionWindow := minCongestionWindowPackets * initialMaxDatagramSize
+	if options.MinCongestionWindow != 0 {
+		minCongestionWindow = options.MinCongestionWindow
+	}
 	return newCubicSender(
 		clock,
 		rttStats,
 		reno,
 		initialMaxDatagramSize,
-		initialCongestionWindow*initialMaxDatagramSize,
-		protocol.MaxCongestionWindowPackets*initialMaxDatagramSize,
-		hystart,
+		initialCongestionWindowFromOptions(options, initialMaxDatagramSize, minCongestionWindow, maxCongestionWindow),
+		maxCongestionWindow,
+		options,
 		tracer,
 	)
 }
@@ -96,27 +151,48 @@ func newCubicSender(
 	initialMaxDatagramSize,
 	initialCongestionWindow,
 	initialMaxCongestionWindow protocol.ByteCount,
-	hystart HystartControlType,
+	options CongestionOptions,
 	tracer logging.ConnectionTracer,
 ) *cubicSender {
 	c := &cubicSender{
-		rttStats:                   rttStats,
-		largestSentPacketNumber:    protocol.InvalidPacketNumber,
-		largestAckedPacketNumber:   protocol.InvalidPacketNumber,
-		largestSentAtLastCutback:   protocol.InvalidPacketNumber,
-		initialCongestionWindow:    initialCongestionWindow,
-		initialMaxCongestionWindow: initialMaxCongestionWindow,
-		congestionWindow:           initialCongestionWindow,
-		slowStartThreshold:         protocol.MaxByteCount,
-		cubic:                      NewCubic(clock),
-		clock:                      clock,
-		reno:                       reno,
-		hybridSlowStartType:        hystart,
-		lowSlowStart:               false,
-		tracer:                     tracer,
-		maxDatagramSize:            initialMaxDatagramSize,
-	}
-	c.pacer = newPacer(c.BandwidthEstimate)
+		rttStats:                      rttStats,
+		largestSentPacketNumber:       protocol.InvalidPacketNumber,
+		largestAckedPacketNumber:      protocol.InvalidPacketNumber,
+		appLimitedUntil:               protocol.InvalidPacketNumber,
+		largestSentAtLastCutback:      protocol.InvalidPacketNumber,
+		lossCutbackPacketNumber:       protocol.InvalidPacketNumber,
+		delayRoundEnd:                 protocol.InvalidPacketNumber,
+		initialCongestionWindow:       initialCongestionWindow,
+		initialMaxCongestionWindow:    initialMaxCongestionWindow,
+		congestionWindow:              initialCongestionWindow,
+		slowStartThreshold:            protocol.MaxByteCount,
+		cubic:                         NewCubic(clock),
+		deliveryRate:                  newDeliveryRateEstimator(),
+		clock:                         clock,
+		reno:                          reno,
+		hybridSlowStartType:           options.Hystart,
+		lssDivisor:                    options.HystartOptions.populate().LSSDivisor,
+		configuredMinCongestionWindow: options.MinCongestionWindow,
+		configuredMaxCongestionWindow: options.MaxCongestionWindow,
+		lowSlowStart:                  false,
+		disablePacing:                 options.DisablePacing,
+		enablePRR:                     options.EnablePRR,
+		delayBasedBackoff:             options.DelayBasedBackoff,
+		tracer:                        tracer,
+		maxDatagramSize:               initialMaxDatagramSize,
+	}
+	if options.InitialSlowStartThreshold != 0 {
+		c.slowStartThreshold = options.InitialSlowStartThreshold
+	}
+	c.renoBeta = renoBeta
+	if options.RenoBeta != 0 {
+		c.renoBeta = options.RenoBeta
+	}
+	c.hybridSlowStart.SetOptions(options.HystartOptions)
+	c.cubic.SetFastConvergence(!options.DisableCubicFastConvergence)
+	c.cubic.SetBeta(options.CubicBeta)
+	c.pacer = newPacer(c.cwndBandwidth)
+	c.pacer.SetMaxBurstSize(options.MaxPacingBurst)
 	if c.tracer != nil {
 		c.lastState = logging.CongestionStateSlowStart
 		c.tracer.UpdatedCongestionState(logging.CongestionStateSlowStart)
@@ -126,32 +202,50 @@ func newCubicSender(
 
 // TimeUntilSend returns when the next packet should be sent.
 func (c *cubicSender) TimeUntilSend(_ protocol.ByteCount) time.Time {
+	if c.disablePacing {
+		return time.Time{}
+	}
 	return c.pacer.TimeUntilSend()
 }
 
 func (c *cubicSender) HasPacingBudget() bool {
+	if c.disablePacing {
+		return true
+	}
 	return c.pacer.Budget(c.clock.Now()) >= c.maxDatagramSize
 }
 
 func (c *cubicSender) maxCongestionWindow() protocol.ByteCount {
+	if c.configuredMaxCongestionWindow != 0 {
+		return c.configuredMaxCongestionWindow
+	}
 	return c.maxDatagramSize * protocol.MaxCongestionWindowPackets
 }
 
 func (c *cubicSender) minCongestionWindow() protocol.ByteCount {
+	if c.configuredMinCongestionWindow != 0 {
+		return c.configuredMinCongestionWindow
+	}
 	return c.maxDatagramSize * minCongestionWindowPackets
 }
 
 func (c *cubicSender) OnPacketSent(
 	sentTime time.Time,
-	_ protocol.ByteCount,
+	bytesInFlight protocol.ByteCount,
 	packetNumber protocol.PacketNumber,
 	bytes protocol.ByteCount,
 	isRetransmittable bool,
 ) {
+	c.bytesInFlight = bytesInFlight
 	c.pacer.SentPacket(sentTime, bytes)
 	if !isRetransmittable {
 		return
 	}
+	if c.enablePRR && c.InRecovery() {
+		// PRR is used when in recovery.
+		c.prr.OnPacketSent(bytes)
+	}
+	c.deliveryRate.OnPacketSent(sentTime, packetNumber, bytes, bytesInFlight)
 	c.largestSentPacketNumber = packetNumber
 	if c.hybridSlowStartType != HystartTypeNone {
 		c.hybridSlowStart.OnPacketSent(packetNumber)
@@ -159,6 +253,9 @@ func (c *cubicSender) OnPacketSent(
 }
 
 func (c *cubicSender) CanSend(bytesInFlight protocol.ByteCount) bool {
+	if c.enablePRR && c.InRecovery() {
+		return c.prr.CanSend(c.GetCongestionWindow(), bytesInFlight, c.slowStartThreshold, c.maxDatagramSize)
+	}
 	return bytesInFlight < c.GetCongestionWindow()
 }
 
@@ -171,6 +268,9 @@ func (c *cubicSender) InSlowStart() bool {
 }
 
 func (c *cubicSender) GetCongestionWindow() protocol.ByteCount {
+	if c.configuredMaxCongestionWindow != 0 {
+		return utils.MinByteCount(c.congestionWindow, c.configuredMaxCongestionWindow)
+	}
 	return c.congestionWindow
 }
 
@@ -185,6 +285,7 @@ func (c *cubicSender) OnRttUpdated() {
 				c.lowSlowStart = true
 				c.maybeTraceStateChange(logging.CongestionStateLowSlowStart)
 			}
+			c.maybeTraceMetricsChange()
 		}
 	}
 }
@@ -195,27 +296,95 @@ func (c *cubicSender) OnPacketAcked(
 	priorInFlight protocol.ByteCount,
 	eventTime time.Time,
 ) {
+	c.onBytesRemovedFromFlight(ackedBytes)
+	defer c.maybeTraceMetricsChange()
+
+	c.deliveryRate.OnPacketAcked(ackedPacketNumber, eventTime)
+	if !c.isCwndLimited(priorInFlight) && priorInFlight >= ackedBytes {
+		c.deliveryRate.OnAppLimited(priorInFlight - ackedBytes)
+	}
 	c.largestAckedPacketNumber = utils.MaxPacketNumber(ackedPacketNumber, c.largestAckedPacketNumber)
+	appLimited := c.isAppLimited(ackedPacketNumber)
 	if c.InRecovery() {
+		if c.enablePRR {
+			// PRR is used when in recovery.
+			c.prr.OnPacketAcked(ackedBytes)
+		}
 		return
 	}
-	c.maybeIncreaseCwnd(ackedPacketNumber, ackedBytes, priorInFlight, eventTime)
-	if c.InSlowStart() {
+	if c.delayBasedBackoff && c.maybeBackOffOnDelay(ackedPacketNumber) {
+		return
+	}
+	c.maybeIncreaseCwnd(ackedBytes, priorInFlight, appLimited, eventTime)
+	if c.InSlowStart() && c.hybridSlowStartType != HystartTypeNone {
 		c.hybridSlowStart.OnPacketAcked(ackedPacketNumber)
 	}
 }
 
 func (c *cubicSender) OnPacketLost(packetNumber protocol.PacketNumber, lostBytes, priorInFlight protocol.ByteCount) {
+	c.onBytesRemovedFromFlight(lostBytes)
+	c.deliveryRate.OnPacketLost(packetNumber)
+	defer c.maybeTraceMetricsChange()
+
 	// TCP NewReno (RFC6582) says that once a loss occurs, any losses in packets
 	// already sent should be treated as a single loss event, since it's expected.
 	if packetNumber <= c.largestSentAtLastCutback {
 		return
 	}
+	c.lossCutbackPacketNumber = packetNumber
+	c.undoCongestionWindow = c.congestionWindow
+	c.undoSlowStartThreshold = c.slowStartThreshold
+	c.undoLowSlowStart = c.lowSlowStart
+	c.undoCubic = *c.cubic
+	c.reduceCongestionWindow(priorInFlight)
+}
+
+// OnSpuriousLoss is called when a packet that was declared lost is acknowledged.
+// If the loss of this packet caused the last CWND cutback, the cutback is undone and recovery is exited.
+func (c *cubicSender) OnSpuriousLoss(packetNumber protocol.PacketNumber) {
+	if c.lossCutbackPacketNumber == protocol.InvalidPacketNumber || packetNumber != c.lossCutbackPacketNumber {
+		return
+	}
+	defer c.maybeTraceMetricsChange()
+
+	c.lossCutbackPacketNumber = protocol.InvalidPacketNumber
+	c.largestSentAtLastCutback = protocol.InvalidPacketNumber
+	c.congestionWindow = utils.MaxByteCount(c.congestionWindow, c.undoCongestionWindow)
+	c.slowStartThreshold = c.undoSlowStartThreshold
+	c.lowSlowStart = c.undoLowSlowStart
+	*c.cubic = c.undoCubic
+	if c.InSlowStart() {
+		c.maybeTraceStateChange(logging.CongestionStateSlowStart)
+	} else if c.lowSlowStart {
+		c.maybeTraceStateChange(logging.CongestionStateLowSlowStart)
+	} else {
+		c.maybeTraceStateChange(logging.CongestionStateCongestionAvoidance)
+	}
+}
+
+// OnCongestionEvent is called when the peer reports ECN-CE marks.
+// RFC 9002 treats this the same way as a packet loss, except that no bytes are removed from flight.
+func (c *cubicSender) OnCongestionEvent(packetNumber protocol.PacketNumber, priorInFlight protocol.ByteCount) {
+	defer c.maybeTraceMetricsChange()
+
+	// Only react once per round trip, as we do for packet loss.
+	if packetNumber <= c.largestSentAtLastCutback {
+		return
+	}
+	// ECN-CE marks are never spurious.
+	c.lossCutbackPacketNumber = protocol.InvalidPacketNumber
+	c.reduceCongestionWindow(priorInFlight)
+}
+
+func (c *cubicSender) reduceCongestionWindow(priorInFlight protocol.ByteCount) {
 	c.lastCutbackExitedSlowstart = c.InSlowStart()
 	c.maybeTraceStateChange(logging.CongestionStateRecovery)
+	if c.enablePRR {
+		c.prr.OnPacketLost(priorInFlight)
+	}
 
 	if c.reno {
-		c.congestionWindow = protocol.ByteCount(float64(c.congestionWindow) * renoBeta)
+		c.congestionWindow = protocol.ByteCount(float64(c.congestionWindow) * c.renoBeta)
 	} else {
 		c.congestionWindow = c.cubic.CongestionWindowAfterPacketLoss(c.congestionWindow)
 	}
@@ -231,17 +400,48 @@ func (c *cubicSender) OnPacketLost(packetNumber protocol.PacketNumber, lostBytes
 	c.lowSlowStart = false
 }
 
+// maybeBackOffOnDelay reduces the congestion window if the smoothed RTT stayed well above the min RTT
+// for multiple round trips, i.e. if a queue is building up at the bottleneck.
+// It is evaluated once per round trip, and returns true if the congestion window was reduced.
+func (c *cubicSender) maybeBackOffOnDelay(ackedPacketNumber protocol.PacketNumber) bool {
+	if c.delayRoundEnd != protocol.InvalidPacketNumber && ackedPacketNumber <= c.delayRoundEnd {
+		return false
+	}
+	c.delayRoundEnd = c.largestSentPacketNumber
+	minRTT := c.rttStats.MinRTT()
+	if minRTT == 0 || float64(c.rttStats.SmoothedRTT()) < delayBackoffRTTThreshold*float64(minRTT) {
+		c.delayRoundsAboveThreshold = 0
+		return false
+	}
+	c.delayRoundsAboveThreshold++
+	if c.delayRoundsAboveThreshold < delayBackoffRounds {
+		return false
+	}
+	c.delayRoundsAboveThreshold = 0
+	c.congestionWindow = utils.MaxByteCount(
+		c.minCongestionWindow(),
+		protocol.ByteCount(float64(c.congestionWindow)*delayBackoffBeta),
+	)
+	c.slowStartThreshold = c.congestionWindow
+	c.lowSlowStart = false
+	c.numAckedPackets = 0
+	// restart the cubic epoch from the reduced window
+	c.cubic.Reset()
+	c.maybeTraceStateChange(logging.CongestionStateCongestionAvoidance)
+	return true
+}
+
 // Called when we receive an ack. Normal TCP tracks how many packets one ack
 // represents, but quic has a separate ack for each packet.
 func (c *cubicSender) maybeIncreaseCwnd(
-	_ protocol.PacketNumber,
 	ackedBytes protocol.ByteCount,
 	priorInFlight protocol.ByteCount,
+	appLimited bool,
 	eventTime time.Time,
 ) {
 	// Do not increase the congestion window unless the sender is close to using
-	// the current window.
-	if !c.isCwndLimited(priorInFlight) {
+	// the current window, and the application was able to fill it.
+	if appLimited || !c.isCwndLimited(priorInFlight) {
 		c.cubic.OnApplicationLimited()
 		c.maybeTraceStateChange(logging.CongestionStateApplicationLimited)
 		return
@@ -252,6 +452,9 @@ func (c *cubicSender) maybeIncreaseCwnd(
 	if c.InSlowStart() {
 		// TCP slow start, exponential growth, increase by one for each ACK.
 		c.congestionWindow += c.maxDatagramSize
+		if c.configuredMaxCongestionWindow != 0 {
+			c.congestionWindow = utils.MinByteCount(c.congestionWindow, c.configuredMaxCongestionWindow)
+		}
 		c.maybeTraceStateChange(logging.CongestionStateSlowStart)
 		return
 	}
@@ -275,7 +478,7 @@ func (c *cubicSender) maybeIncreaseCwnd(
 	// if LSS is activated, take the max between CA cwnd and the LSS cwnd
 	if c.lowSlowStart {
 		// TCP low slow start activated by hystart++
-		K := float64(c.congestionWindow) / (lssDivisor * float64(c.slowStartThreshold))
+		K := float64(c.congestionWindow) / (c.lssDivisor * float64(c.slowStartThreshold))
 		newCwnd := c.congestionWindow + protocol.ByteCount(float64(c.maxDatagramSize)/K)
 		c.congestionWindow = utils.MaxByteCount(
 			caCwnd,
@@ -298,8 +501,44 @@ func (c *cubicSender) isCwndLimited(bytesInFlight protocol.ByteCount) bool {
 	return slowStartLimited || availableBytes <= maxBurstPackets*c.maxDatagramSize
 }
 
-// BandwidthEstimate returns the current bandwidth estimate
+// isAppLimited says if the acknowledgement for a packet is an application-limited sample.
+// The application-limited period ends once a packet sent after it is acknowledged.
+func (c *cubicSender) isAppLimited(ackedPacketNumber protocol.PacketNumber) bool {
+	if c.appLimitedUntil == protocol.InvalidPacketNumber {
+		return false
+	}
+	if ackedPacketNumber > c.appLimitedUntil {
+		c.appLimitedUntil = protocol.InvalidPacketNumber
+		return false
+	}
+	return true
+}
+
+// OnAppLimited is called when the application doesn't have enough data to fill the congestion window.
+// The acknowledgements for all packets sent so far then don't grow the congestion window.
+func (c *cubicSender) OnAppLimited(bytesInFlight protocol.ByteCount) {
+	if c.largestSentPacketNumber == protocol.InvalidPacketNumber {
+		return
+	}
+	c.appLimitedUntil = c.largestSentPacketNumber
+	c.deliveryRate.OnAppLimited(bytesInFlight)
+}
+
+// BandwidthEstimate returns the maximum delivery rate measured over the last few round trips.
+// It is zero if no estimate is available yet.
 func (c *cubicSender) BandwidthEstimate() Bandwidth {
+	return c.deliveryRate.BandwidthEstimate()
+}
+
+func (c *cubicSender) ControlType() (CongestionControlType, HystartControlType) {
+	if c.reno {
+		return NewRenoControlType, c.hybridSlowStartType
+	}
+	return CubicControlType, c.hybridSlowStartType
+}
+
+// cwndBandwidth is the bandwidth allowed by the congestion window. It is used for pacing.
+func (c *cubicSender) cwndBandwidth() Bandwidth {
 	srtt := c.rttStats.SmoothedRTT()
 	if srtt == 0 {
 		// If we haven't measured an rtt, the bandwidth estimate is unknown.
@@ -311,14 +550,19 @@ func (c *cubicSender) BandwidthEstimate() Bandwidth {
 // OnRetransmissionTimeout is called on an retransmission timeout
 func (c *cubicSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
 	c.largestSentAtLastCutback = protocol.InvalidPacketNumber
+	c.lossCutbackPacketNumber = protocol.InvalidPacketNumber
 	if !packetsRetransmitted {
 		return
 	}
 	c.hybridSlowStart.Restart()
 	c.cubic.Reset()
+	c.deliveryRate.sampler.Reset()
 	c.slowStartThreshold = c.congestionWindow / 2
 	c.lowSlowStart = false
+	c.delayRoundEnd = protocol.InvalidPacketNumber
+	c.delayRoundsAboveThreshold = 0
 	c.congestionWindow = c.minCongestionWindow()
+	c.maybeTraceMetricsChange()
 }
 
 // OnConnectionMigration is called when the connection is migrated (?)
@@ -326,13 +570,19 @@ func (c *cubicSender) OnConnectionMigration() {
 	c.hybridSlowStart.Restart()
 	c.largestSentPacketNumber = protocol.InvalidPacketNumber
 	c.largestAckedPacketNumber = protocol.InvalidPacketNumber
+	c.appLimitedUntil = protocol.InvalidPacketNumber
 	c.largestSentAtLastCutback = protocol.InvalidPacketNumber
+	c.lossCutbackPacketNumber = protocol.InvalidPacketNumber
 	c.lastCutbackExitedSlowstart = false
 	c.lowSlowStart = false
+	c.delayRoundEnd = protocol.InvalidPacketNumber
+	c.delayRoundsAboveThreshold = 0
 	c.cubic.Reset()
+	c.deliveryRate.Reset()
 	c.numAckedPackets = 0
 	c.congestionWindow = c.initialCongestionWindow
 	c.slowStartThreshold = c.initialMaxCongestionWindow
+	c.maybeTraceMetricsChange()
 }
 
 func (c *cubicSender) maybeTraceStateChange(new logging.CongestionState) {
@@ -343,6 +593,28 @@ func (c *cubicSender) maybeTraceStateChange(new logging.CongestionState) {
 	c.lastState = new
 }
 
+func (c *cubicSender) onBytesRemovedFromFlight(bytes protocol.ByteCount) {
+	if bytes > c.bytesInFlight {
+		c.bytesInFlight = 0
+		return
+	}
+	c.bytesInFlight -= bytes
+}
+
+// maybeTraceMetricsChange traces the congestion window and the slow start threshold, if they changed
+func (c *cubicSender) maybeTraceMetricsChange() {
+	if c.tracer == nil {
+		return
+	}
+	cwnd := c.GetCongestionWindow()
+	if cwnd == c.lastTracedCongestionWindow && c.slowStartThreshold == c.lastTracedSlowStartThreshold {
+		return
+	}
+	c.tracer.UpdatedCongestionMetrics(c.rttStats, cwnd, c.slowStartThreshold, c.bytesInFlight)
+	c.lastTracedCongestionWindow = cwnd
+	c.lastTracedSlowStartThreshold = c.slowStartThreshold
+}
+
 func (c *cubicSender) SetMaxDatagramSize(s protocol.ByteCount) {
 	if s < c.maxDatagramSize {
 		panic(fmt.Sprintf("congestion BUG: decreased max datagram size from %d to %d", c.maxDatagramSize, s))
@@ -353,4 +625,5 @@ func (c *cubicSender) SetMaxDatagramSize(s protocol.ByteCount) {
 		c.congestionWindow = c.minCongestionWindow()
 	}
 	c.pacer.SetMaxDatagramSize(s)
+	c.maybeTraceMetricsChange()
 }
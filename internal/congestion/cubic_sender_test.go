@@ -1,10 +1,14 @@
 package congestion
 
 import (
+	"fmt"
 	"time"
 
+	"github.com/golang/mock/gomock"
+	mocklogging "github.com/lucas-clemente/quic-go/internal/mocks/logging"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/logging"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -49,7 +53,7 @@ var _ = Describe("Cubic Sender", func() {
 			protocol.InitialPacketSizeIPv4,
 			initialCongestionWindowPackets*maxDatagramSize,
 			MaxCongestionWindow,
-			HystartTypeStandard,
+			CongestionOptions{Hystart: HystartTypeStandard},
 			nil,
 		)
 	})
@@ -108,6 +112,113 @@ var _ = Describe("Cubic Sender", func() {
 		Expect(sender.CanSend(bytesInFlight)).To(BeFalse())
 	})
 
+	It("uses the configured initial congestion window", func() {
+		sender = NewCubicSender(&clock, rttStats, maxDatagramSize, true, CongestionOptions{InitialCongestionWindow: 5 * maxDatagramSize}, nil)
+		Expect(sender.GetCongestionWindow()).To(Equal(5 * maxDatagramSize))
+		Expect(SendAvailableSendWindow()).To(Equal(5))
+		Expect(sender.CanSend(bytesInFlight)).To(BeFalse())
+	})
+
+	It("uses the default initial congestion window", func() {
+		sender = NewCubicSender(&clock, rttStats, maxDatagramSize, true, CongestionOptions{}, nil)
+		Expect(SendAvailableSendWindow()).To(Equal(initialCongestionWindow))
+	})
+
+	It("clamps the configured initial congestion window", func() {
+		sender = NewCubicSender(&clock, rttStats, maxDatagramSize, true, CongestionOptions{InitialCongestionWindow: 1}, nil)
+		Expect(sender.GetCongestionWindow()).To(Equal(minCongestionWindowPackets * maxDatagramSize))
+		sender = NewCubicSender(&clock, rttStats, maxDatagramSize, true, CongestionOptions{InitialCongestionWindow: protocol.MaxByteCount}, nil)
+		Expect(sender.GetCongestionWindow()).To(Equal(protocol.MaxCongestionWindowPackets * maxDatagramSize))
+	})
+
+	It("doesn't reduce the congestion window below the configured minimum", func() {
+		const minCwnd = 8 * maxDatagramSize
+		sender = newCubicSender(&clock, rttStats, true, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, CongestionOptions{MinCongestionWindow: minCwnd}, nil)
+		for i := 0; i < 10; i++ {
+			SendAvailableSendWindow()
+			LoseNPackets(1)
+			Expect(sender.GetCongestionWindow()).To(Equal(minCwnd))
+			AckNPackets(int(bytesInFlight / maxDatagramSize))
+			Expect(sender.GetCongestionWindow()).To(BeNumerically(">=", minCwnd))
+		}
+		sender.OnRetransmissionTimeout(true)
+		Expect(sender.GetCongestionWindow()).To(Equal(minCwnd))
+	})
+
+	It("doesn't grow the congestion window beyond the configured maximum", func() {
+		const maxCwnd = 50 * maxDatagramSize
+		for _, reno := range []bool{true, false} {
+			sender = NewCubicSender(&clock, rttStats, protocol.InitialPacketSizeIPv4, reno, CongestionOptions{MaxCongestionWindow: maxCwnd}, nil)
+			bytesInFlight = 0
+			packetNumber = 1
+			ackedPacketNumber = 0
+			// slow start
+			for i := 0; i < 10; i++ {
+				SendAvailableSendWindow()
+				AckNPackets(int(bytesInFlight / maxDatagramSize))
+				Expect(sender.GetCongestionWindow()).To(BeNumerically("<=", maxCwnd))
+			}
+			Expect(sender.GetCongestionWindow()).To(Equal(maxCwnd))
+			// congestion avoidance
+			LoseNPackets(1)
+			Expect(sender.InSlowStart()).To(BeFalse())
+			for i := 0; i < 100; i++ {
+				clock.Advance(100 * time.Millisecond)
+				SendAvailableSendWindow()
+				AckNPackets(int(bytesInFlight / maxDatagramSize))
+				Expect(sender.GetCongestionWindow()).To(BeNumerically("<=", maxCwnd))
+			}
+			Expect(sender.GetCongestionWindow()).To(Equal(maxCwnd))
+		}
+	})
+
+	It("uses the configured initial slow start threshold", func() {
+		const ssthresh = 50 * maxDatagramSize
+		sender = NewCubicSender(&clock, rttStats, maxDatagramSize, true, CongestionOptions{InitialSlowStartThreshold: ssthresh}, nil)
+		for sender.InSlowStart() {
+			Expect(sender.GetCongestionWindow()).To(BeNumerically("<", ssthresh))
+			SendAvailableSendWindow()
+			AckNPackets(1)
+		}
+		Expect(sender.GetCongestionWindow()).To(Equal(ssthresh))
+		// In congestion avoidance, Reno grows the congestion window by one packet per window.
+		SendAvailableSendWindow()
+		AckNPackets(int(bytesInFlight / maxDatagramSize))
+		Expect(sender.InSlowStart()).To(BeFalse())
+		Expect(sender.GetCongestionWindow()).To(Equal(ssthresh + maxDatagramSize))
+	})
+
+	It("doesn't exit slow start at a fixed threshold by default", func() {
+		sender = NewCubicSender(&clock, rttStats, maxDatagramSize, true, CongestionOptions{}, nil)
+		for i := 0; i < 5; i++ {
+			SendAvailableSendWindow()
+			AckNPackets(int(bytesInFlight / maxDatagramSize))
+		}
+		Expect(sender.GetCongestionWindow()).To(BeNumerically(">", 50*maxDatagramSize))
+		Expect(sender.InSlowStart()).To(BeTrue())
+	})
+
+	It("traces changes of the congestion window and the slow start threshold", func() {
+		mockCtrl := gomock.NewController(GinkgoT())
+		defer mockCtrl.Finish()
+		tracer := mocklogging.NewMockConnectionTracer(mockCtrl)
+		tracer.EXPECT().UpdatedCongestionState(logging.CongestionStateSlowStart)
+		sender = newCubicSender(&clock, rttStats, true, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, CongestionOptions{Hystart: HystartTypeStandard}, tracer)
+		SendAvailableSendWindow()
+		gomock.InOrder(
+			tracer.EXPECT().UpdatedCongestionMetrics(rttStats, 11*maxDatagramSize, protocol.MaxByteCount, 8*maxDatagramSize),
+			tracer.EXPECT().UpdatedCongestionMetrics(rttStats, 12*maxDatagramSize, protocol.MaxByteCount, 7*maxDatagramSize),
+		)
+		AckNPackets(2)
+		gomock.InOrder(
+			tracer.EXPECT().UpdatedCongestionState(logging.CongestionStateRecovery),
+			tracer.EXPECT().UpdatedCongestionMetrics(rttStats, 6*maxDatagramSize, 6*maxDatagramSize, 6*maxDatagramSize),
+		)
+		LoseNPackets(1)
+		// further losses in the same window don't change the congestion window
+		LoseNPackets(1)
+	})
+
 	It("paces", func() {
 		rttStats.UpdateRTT(10*time.Millisecond, 0, time.Now())
 		clock.Advance(time.Hour)
@@ -119,6 +230,31 @@ var _ = Describe("Cubic Sender", func() {
 		Expect(delay).ToNot(Equal(utils.InfDuration))
 	})
 
+	It("sends packets back-to-back if pacing is disabled", func() {
+		sender = newCubicSender(&clock, rttStats, true, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, CongestionOptions{DisablePacing: true}, nil)
+		rttStats.UpdateRTT(10*time.Millisecond, 0, time.Now())
+		clock.Advance(time.Hour)
+		SendAvailableSendWindow()
+		AckNPackets(4)
+		var sent int
+		for sender.CanSend(bytesInFlight) {
+			Expect(sender.HasPacingBudget()).To(BeTrue())
+			Expect(sender.TimeUntilSend(bytesInFlight)).To(BeZero())
+			sender.OnPacketSent(clock.Now(), bytesInFlight, packetNumber, maxDatagramSize, true)
+			packetNumber++
+			bytesInFlight += maxDatagramSize
+			sent++
+		}
+		Expect(sent).To(Equal(8))
+		// the congestion window still applies
+		Expect(bytesInFlight).To(Equal(sender.GetCongestionWindow()))
+	})
+
+	It("uses the configured pacing burst size", func() {
+		sender = NewCubicSender(&clock, rttStats, protocol.InitialPacketSizeIPv4, false, CongestionOptions{MaxPacingBurst: 20 * maxDatagramSize}, nil)
+		Expect(sender.pacer.Budget(clock.Now())).To(Equal(20 * maxDatagramSize))
+	})
+
 	It("application limited slow start", func() {
 		// Send exactly 10 packets and ensure the CWND ends at 14 packets.
 		const numberOfAcks = 5
@@ -136,12 +272,41 @@ var _ = Describe("Cubic Sender", func() {
 		Expect(bytesToSend).To(Equal(defaultWindowTCP + maxDatagramSize*2*2))
 	})
 
+	It("doesn't grow the congestion window for application-limited samples", func() {
+		// A trickle sender that uses a bit more than half of the congestion window every RTT.
+		// Without the application-limited signal, this would count as being cwnd-limited during slow start.
+		const numPackets = initialCongestionWindowPackets/2 + 2
+		for i := 0; i < 20; i++ {
+			for j := 0; j < numPackets; j++ {
+				Expect(sender.CanSend(bytesInFlight)).To(BeTrue())
+				sender.OnPacketSent(clock.Now(), bytesInFlight, packetNumber, maxDatagramSize, true)
+				packetNumber++
+				bytesInFlight += maxDatagramSize
+			}
+			sender.OnAppLimited(bytesInFlight)
+			AckNPackets(numPackets)
+		}
+		Expect(sender.GetCongestionWindow()).To(Equal(defaultWindowTCP))
+		Expect(sender.InSlowStart()).To(BeTrue())
+	})
+
+	It("grows the congestion window again once the application-limited period ends", func() {
+		SendAvailableSendWindow()
+		sender.OnAppLimited(bytesInFlight)
+		AckNPackets(initialCongestionWindowPackets)
+		Expect(sender.GetCongestionWindow()).To(Equal(defaultWindowTCP))
+		// Packets sent after the application-limited period are cwnd-limited again.
+		SendAvailableSendWindow()
+		AckNPackets(2)
+		Expect(sender.GetCongestionWindow()).To(Equal(defaultWindowTCP + 2*maxDatagramSize))
+	})
+
 	It("exponential slow start", func() {
 		const numberOfAcks = 20
 		// At startup make sure we can send.
 		Expect(sender.CanSend(0)).To(BeTrue())
 		Expect(sender.TimeUntilSend(0)).To(BeZero())
-		Expect(sender.BandwidthEstimate()).To(Equal(infBandwidth))
+		Expect(sender.cwndBandwidth()).To(Equal(infBandwidth))
 		// Make sure we can send.
 		Expect(sender.TimeUntilSend(0)).To(BeZero())
 
@@ -152,7 +317,20 @@ var _ = Describe("Cubic Sender", func() {
 		}
 		cwnd := sender.GetCongestionWindow()
 		Expect(cwnd).To(Equal(defaultWindowTCP + maxDatagramSize*2*numberOfAcks))
-		Expect(sender.BandwidthEstimate()).To(Equal(BandwidthFromDelta(cwnd, rttStats.SmoothedRTT())))
+		Expect(sender.cwndBandwidth()).To(Equal(BandwidthFromDelta(cwnd, rttStats.SmoothedRTT())))
+	})
+
+	It("estimates the bandwidth from the delivery rate", func() {
+		Expect(sender.BandwidthEstimate()).To(BeZero())
+		// Deliver one packet per millisecond, and keep the window full.
+		SendAvailableSendWindow()
+		clock.Advance(time.Millisecond)
+		for i := 0; i < 200; i++ {
+			AckNPackets(1)
+			SendAvailableSendWindow()
+		}
+		expected := BandwidthFromDelta(maxDatagramSize, time.Millisecond)
+		Expect(sender.BandwidthEstimate()).To(BeNumerically("~", expected, expected/10))
 	})
 
 	It("slow start packet loss", func() {
@@ -197,6 +375,74 @@ var _ = Describe("Cubic Sender", func() {
 		Expect(sender.hybridSlowStart.Started()).To(BeFalse())
 	})
 
+	It("reduces the congestion window on ECN-CE marks", func() {
+		const numberOfAcks = 10
+		for i := 0; i < numberOfAcks; i++ {
+			SendAvailableSendWindow()
+			AckNPackets(2)
+		}
+		SendAvailableSendWindow()
+		expectedSendWindow := defaultWindowTCP + (maxDatagramSize * 2 * numberOfAcks)
+		Expect(sender.GetCongestionWindow()).To(Equal(expectedSendWindow))
+
+		// The peer reports ECN-CE marks for the next acknowledged packet.
+		priorInFlight := bytesInFlight
+		AckNPackets(1)
+		expectedSendWindow += maxDatagramSize // we're still in slow start when acknowledging the packet
+		Expect(sender.GetCongestionWindow()).To(Equal(expectedSendWindow))
+		sender.OnCongestionEvent(ackedPacketNumber, priorInFlight)
+		expectedSendWindow = protocol.ByteCount(float32(expectedSendWindow) * renoBeta)
+		Expect(sender.GetCongestionWindow()).To(Equal(expectedSendWindow))
+		Expect(sender.InRecovery()).To(BeTrue())
+		Expect(sender.InSlowStart()).To(BeFalse())
+
+		// Further ECN-CE marks in the same window don't reduce the congestion window again.
+		AckNPackets(1)
+		sender.OnCongestionEvent(ackedPacketNumber, bytesInFlight)
+		Expect(sender.GetCongestionWindow()).To(Equal(expectedSendWindow))
+	})
+
+	It("undoes the congestion window reduction when a loss was spurious", func() {
+		const numberOfAcks = 10
+		for i := 0; i < numberOfAcks; i++ {
+			SendAvailableSendWindow()
+			AckNPackets(2)
+		}
+		SendAvailableSendWindow()
+		cwnd := sender.GetCongestionWindow()
+		Expect(cwnd).To(Equal(defaultWindowTCP + (maxDatagramSize * 2 * numberOfAcks)))
+
+		LoseNPackets(1)
+		lostPacketNumber := ackedPacketNumber
+		AckNPackets(1)
+		Expect(sender.GetCongestionWindow()).To(Equal(protocol.ByteCount(float32(cwnd) * renoBeta)))
+		Expect(sender.InRecovery()).To(BeTrue())
+		Expect(sender.InSlowStart()).To(BeFalse())
+
+		// Only the packet that caused the reduction can undo it.
+		sender.OnSpuriousLoss(lostPacketNumber + 1)
+		Expect(sender.GetCongestionWindow()).To(Equal(protocol.ByteCount(float32(cwnd) * renoBeta)))
+
+		sender.OnSpuriousLoss(lostPacketNumber)
+		Expect(sender.GetCongestionWindow()).To(Equal(cwnd))
+		Expect(sender.InRecovery()).To(BeFalse())
+		Expect(sender.InSlowStart()).To(BeTrue())
+		// The reduction is only undone once.
+		LoseNPackets(1)
+		sender.OnSpuriousLoss(lostPacketNumber)
+		Expect(sender.GetCongestionWindow()).To(Equal(protocol.ByteCount(float32(cwnd) * renoBeta)))
+	})
+
+	It("doesn't undo reductions caused by ECN-CE marks", func() {
+		SendAvailableSendWindow()
+		AckNPackets(2)
+		cwnd := sender.GetCongestionWindow()
+		sender.OnCongestionEvent(ackedPacketNumber, bytesInFlight)
+		Expect(sender.GetCongestionWindow()).To(Equal(protocol.ByteCount(float32(cwnd) * renoBeta)))
+		sender.OnSpuriousLoss(ackedPacketNumber)
+		Expect(sender.GetCongestionWindow()).To(Equal(protocol.ByteCount(float32(cwnd) * renoBeta)))
+	})
+
 	It("slow start packet loss PRR", func() {
 		// Test based on the first example in RFC6937.
 		// Ack 10 packets in 5 acks to raise the CWND to 20, as in the example.
@@ -319,7 +565,7 @@ var _ = Describe("Cubic Sender", func() {
 	It("tcp cubic reset epoch on quiescence", func() {
 		const maxCongestionWindow = 50
 		const maxCongestionWindowBytes = maxCongestionWindow * maxDatagramSize
-		sender = newCubicSender(&clock, rttStats, false, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, maxCongestionWindowBytes, HystartTypeStandard, nil)
+		sender = newCubicSender(&clock, rttStats, false, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, maxCongestionWindowBytes, CongestionOptions{Hystart: HystartTypeStandard}, nil)
 
 		numSent := SendAvailableSendWindow()
 
@@ -427,6 +673,168 @@ var _ = Describe("Cubic Sender", func() {
 		Expect(numSent).To(BeEquivalentTo(windowInPackets))
 	})
 
+	It("spreads out sending during recovery, if PRR is enabled", func() {
+		// returns the number of packets sent in response to every ack during recovery
+		recoveryEpisode := func(opts CongestionOptions) []int {
+			sender = newCubicSender(&clock, rttStats, true, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, opts, nil)
+			bytesInFlight = 0
+			packetNumber = 1
+			ackedPacketNumber = 0
+			// Ack 10 packets in 5 acks to raise the CWND to 20.
+			for i := 0; i < 5; i++ {
+				SendAvailableSendWindow()
+				AckNPackets(2)
+			}
+			SendAvailableSendWindow()
+			Expect(sender.GetCongestionWindow()).To(Equal(20 * maxDatagramSize))
+			LoseNPackets(1)
+			var sent []int
+			for sender.InRecovery() {
+				AckNPackets(1)
+				sent = append(sent, SendAvailableSendWindow())
+			}
+			return sent
+		}
+
+		withoutPRR := recoveryEpisode(CongestionOptions{})
+		// Without PRR, we don't send anything until bytes in flight drop below the reduced window.
+		Expect(withoutPRR[:9]).To(Equal(make([]int, 9)))
+		withPRR := recoveryEpisode(CongestionOptions{EnablePRR: true})
+		Expect(withPRR).To(HaveLen(len(withoutPRR)))
+		// With PRR, we send one packet on every other ack, starting with the first ack.
+		for i, n := range withPRR[:9] {
+			Expect(n).To(Equal((i + 1) % 2))
+		}
+		for _, n := range withPRR {
+			Expect(n).To(BeNumerically("<=", 1))
+		}
+	})
+
+	It("uses the configured CUBIC beta", func() {
+		for _, b := range []float64{0.5, 0.8} {
+			sender = newCubicSender(&clock, rttStats, false, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, CongestionOptions{CubicBeta: b}, nil)
+			bytesInFlight = 0
+			packetNumber = 1
+			ackedPacketNumber = 0
+			for i := 0; i < 5; i++ {
+				SendAvailableSendWindow()
+				AckNPackets(2)
+			}
+			SendAvailableSendWindow()
+			priorWindow := sender.GetCongestionWindow()
+			LoseNPackets(1)
+			Expect(sender.GetCongestionWindow()).To(BeNumerically("~", float64(priorWindow)*b, 1))
+		}
+	})
+
+	It("uses the configured Reno beta", func() {
+		for _, b := range []float64{0.3, 0.8} {
+			sender = newCubicSender(&clock, rttStats, true, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, CongestionOptions{RenoBeta: b}, nil)
+			bytesInFlight = 0
+			packetNumber = 1
+			ackedPacketNumber = 0
+			for i := 0; i < 5; i++ {
+				SendAvailableSendWindow()
+				AckNPackets(2)
+			}
+			SendAvailableSendWindow()
+			priorWindow := sender.GetCongestionWindow()
+			LoseNPackets(1)
+			Expect(sender.GetCongestionWindow()).To(Equal(protocol.ByteCount(float64(priorWindow) * b)))
+		}
+	})
+
+	Context("delay-based backoff", func() {
+		const (
+			minRTT = 60 * time.Millisecond
+			// the bandwidth-delay product of the path
+			bdp = 20 * maxDatagramSize
+			// packets are dropped once the queue at the bottleneck exceeds the buffer
+			bufferSize = bdp
+		)
+
+		// simulateRound sends a full congestion window, and acknowledges it after one round trip.
+		// Packets that don't fit into the path are queued at the bottleneck, which increases the RTT.
+		// It returns true if a packet was lost.
+		simulateRound := func() bool {
+			SendAvailableSendWindow()
+			rtt := minRTT
+			if bytesInFlight > bdp {
+				rtt = time.Duration(float64(minRTT) * float64(bytesInFlight) / float64(bdp))
+			}
+			var lost bool
+			n := int(bytesInFlight / maxDatagramSize)
+			if bytesInFlight > bdp+bufferSize {
+				LoseNPackets(1)
+				n--
+				lost = true
+			}
+			clock.Advance(rtt)
+			for i := 0; i < n; i++ {
+				rttStats.UpdateRTT(rtt, 0, clock.Now())
+				sender.OnRttUpdated()
+				ackedPacketNumber++
+				sender.OnPacketAcked(ackedPacketNumber, maxDatagramSize, bytesInFlight, clock.Now())
+				bytesInFlight -= maxDatagramSize
+			}
+			return lost
+		}
+
+		// firstBackoff returns the round trip in which the congestion window was reduced for the first time,
+		// and whether a packet was lost before that.
+		firstBackoff := func(reno bool, opts CongestionOptions) (round int, lost bool) {
+			rttStats = utils.NewRTTStats()
+			sender = newCubicSender(&clock, rttStats, reno, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, opts, nil)
+			bytesInFlight = 0
+			packetNumber = 1
+			ackedPacketNumber = 0
+			for round = 1; round <= 1000; round++ {
+				cwnd := sender.GetCongestionWindow()
+				if simulateRound() {
+					lost = true
+				}
+				if sender.GetCongestionWindow() < cwnd {
+					return round, lost
+				}
+			}
+			Fail("congestion window was never reduced")
+			return
+		}
+
+		for _, r := range []bool{true, false} {
+			reno := r
+			name := "Cubic"
+			if reno {
+				name = "Reno"
+			}
+
+			It(fmt.Sprintf("backs off before a packet is lost, using %s", name), func() {
+				lossRound, lost := firstBackoff(reno, CongestionOptions{})
+				Expect(lost).To(BeTrue())
+				delayRound, lost := firstBackoff(reno, CongestionOptions{DelayBasedBackoff: true})
+				Expect(lost).To(BeFalse())
+				Expect(delayRound).To(BeNumerically("<", lossRound))
+			})
+		}
+
+		It("doesn't back off if the RTT doesn't increase", func() {
+			sender = newCubicSender(&clock, rttStats, false, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, CongestionOptions{DelayBasedBackoff: true}, nil)
+			for i := 0; i < 20; i++ {
+				cwnd := sender.GetCongestionWindow()
+				SendAvailableSendWindow()
+				AckNPackets(int(bytesInFlight / maxDatagramSize))
+				Expect(sender.GetCongestionWindow()).To(BeNumerically(">=", cwnd))
+			}
+		})
+	})
+
+	It("disables fast convergence", func() {
+		sender = newCubicSender(&clock, rttStats, false, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, CongestionOptions{DisableCubicFastConvergence: true}, nil)
+		Expect(sender.cubic.fastConvergence).To(BeFalse())
+		sender = newCubicSender(&clock, rttStats, false, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, CongestionOptions{}, nil)
+		Expect(sender.cubic.fastConvergence).To(BeTrue())
+	})
+
 	It("reset after connection migration", func() {
 		Expect(sender.GetCongestionWindow()).To(Equal(defaultWindowTCP))
 		Expect(sender.slowStartThreshold).To(Equal(protocol.MaxByteCount))
@@ -460,7 +868,7 @@ var _ = Describe("Cubic Sender", func() {
 
 	It("slow starts up to the maximum congestion window", func() {
 		const initialMaxCongestionWindow = protocol.MaxCongestionWindowPackets * initialMaxDatagramSize
-		sender = newCubicSender(&clock, rttStats, true, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, initialMaxCongestionWindow, HystartTypeStandard, nil)
+		sender = newCubicSender(&clock, rttStats, true, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, initialMaxCongestionWindow, CongestionOptions{Hystart: HystartTypeStandard}, nil)
 
 		for i := 1; i < protocol.MaxCongestionWindowPackets; i++ {
 			sender.OnRttUpdated()
@@ -475,7 +883,7 @@ var _ = Describe("Cubic Sender", func() {
 
 	It("slow starts up to maximum congestion window, if larger packets are sent", func() {
 		const initialMaxCongestionWindow = protocol.MaxCongestionWindowPackets * initialMaxDatagramSize
-		sender = newCubicSender(&clock, rttStats, true, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, initialMaxCongestionWindow, HystartTypeStandard, nil)
+		sender = newCubicSender(&clock, rttStats, true, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, initialMaxCongestionWindow, CongestionOptions{Hystart: HystartTypeStandard}, nil)
 		const packetSize = initialMaxDatagramSize + 100
 		sender.SetMaxDatagramSize(packetSize)
 		for i := 1; i < protocol.MaxCongestionWindowPackets; i++ {
@@ -488,9 +896,75 @@ var _ = Describe("Cubic Sender", func() {
 		))
 	})
 
+	Context("hybrid slow start", func() {
+		// ackRound sends a full window and acknowledges all packets one RTT later
+		ackRound := func(rtt time.Duration) {
+			n := SendAvailableSendWindow()
+			clock.Advance(rtt)
+			for i := 0; i < n; i++ {
+				rttStats.UpdateRTT(rtt, 0, clock.Now())
+				sender.OnRttUpdated()
+				ackedPacketNumber++
+				sender.OnPacketAcked(ackedPacketNumber, maxDatagramSize, bytesInFlight, clock.Now())
+			}
+			bytesInFlight -= protocol.ByteCount(n) * maxDatagramSize
+		}
+
+		newSender := func(hystart HystartControlType) {
+			sender = newCubicSender(&clock, rttStats, false, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, CongestionOptions{Hystart: hystart}, nil)
+		}
+
+		It("exits slow start when the RTT increases", func() {
+			newSender(HystartTypeStandard)
+			rtt := 60 * time.Millisecond
+			for sender.InSlowStart() {
+				Expect(sender.GetCongestionWindow()).To(BeNumerically("<", MaxCongestionWindow))
+				rtt += 20 * time.Millisecond
+				ackRound(rtt)
+			}
+			Expect(sender.lowSlowStart).To(BeFalse())
+		})
+
+		It("switches to Conservative Slow Start when the RTT increases, with Hystart++", func() {
+			newSender(HystartTypePlusPlus)
+			rtt := 60 * time.Millisecond
+			for !sender.lowSlowStart {
+				Expect(sender.GetCongestionWindow()).To(BeNumerically("<", MaxCongestionWindow))
+				rtt += 20 * time.Millisecond
+				ackRound(rtt)
+			}
+			Expect(sender.InSlowStart()).To(BeFalse())
+		})
+
+		It("doubles the congestion window every RTT if disabled, even if the RTT increases", func() {
+			newSender(HystartTypeNone)
+			rtt := 60 * time.Millisecond
+			cwnd := sender.GetCongestionWindow()
+			for 2*cwnd <= MaxCongestionWindow {
+				rtt += 20 * time.Millisecond
+				ackRound(rtt)
+				Expect(sender.GetCongestionWindow()).To(Equal(2 * cwnd))
+				Expect(sender.InSlowStart()).To(BeTrue())
+				Expect(sender.lowSlowStart).To(BeFalse())
+				Expect(sender.hybridSlowStart.Started()).To(BeFalse())
+				cwnd = sender.GetCongestionWindow()
+			}
+		})
+
+		It("exits slow start on packet loss if disabled", func() {
+			newSender(HystartTypeNone)
+			ackRound(100 * time.Millisecond)
+			cwnd := sender.GetCongestionWindow()
+			SendAvailableSendWindow()
+			LoseNPackets(1)
+			Expect(sender.InSlowStart()).To(BeFalse())
+			Expect(sender.GetCongestionWindow()).To(BeNumerically("<", cwnd))
+		})
+	})
+
 	It("limit cwnd increase in congestion avoidance", func() {
 		// Enable Cubic.
-		sender = newCubicSender(&clock, rttStats, false, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, HystartTypeStandard, nil)
+		sender = newCubicSender(&clock, rttStats, false, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, CongestionOptions{Hystart: HystartTypeStandard}, nil)
 		numSent := SendAvailableSendWindow()
 
 		// Make sure we fall out of slow start.
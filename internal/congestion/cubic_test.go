@@ -214,6 +214,32 @@ var _ = Describe("Cubic", func() {
 		Expect(cubic.lastMaxCongestionWindow).To(Equal(expectedLastMax))
 	})
 
+	It("doesn't apply the additional back off on consecutive losses, if fast convergence is disabled", func() {
+		initialCwnd := 422 * maxDatagramSize
+		cwndAfterFirstLoss := protocol.ByteCount(float32(initialCwnd) * nConnectionBeta)
+		lossEvents := func() [2]protocol.ByteCount {
+			var lastMax [2]protocol.ByteCount
+			currentCwnd := initialCwnd
+			for i := range lastMax {
+				currentCwnd = cubic.CongestionWindowAfterPacketLoss(currentCwnd)
+				lastMax[i] = cubic.lastMaxCongestionWindow
+			}
+			return lastMax
+		}
+
+		withFastConvergence := lossEvents()
+		Expect(withFastConvergence[0]).To(Equal(initialCwnd))
+		Expect(withFastConvergence[1]).To(Equal(protocol.ByteCount(float32(cwndAfterFirstLoss) * nConnectionBetaLastMax)))
+
+		cubic.Reset()
+		cubic.SetFastConvergence(false)
+		withoutFastConvergence := lossEvents()
+		Expect(withoutFastConvergence[0]).To(Equal(initialCwnd))
+		// Without fast convergence, the last max is the window before the second loss.
+		Expect(withoutFastConvergence[1]).To(Equal(cwndAfterFirstLoss))
+		Expect(withoutFastConvergence[1]).To(BeNumerically(">", withFastConvergence[1]))
+	})
+
 	It("works below origin", func() {
 		// Concave growth.
 		rttMin := 100 * time.Millisecond
@@ -0,0 +1,50 @@
+package congestion
+
+import "github.com/lucas-clemente/quic-go/internal/protocol"
+
+// Extend turns a SendAlgorithmWithDebugInfos into an ExtendedSendAlgorithm.
+// Events for optional interfaces that the congestion controller doesn't implement are dropped.
+func Extend(s SendAlgorithmWithDebugInfos) ExtendedSendAlgorithm {
+	if e, ok := s.(ExtendedSendAlgorithm); ok {
+		return e
+	}
+	return &extendedSender{SendAlgorithmWithDebugInfos: s}
+}
+
+type extendedSender struct {
+	SendAlgorithmWithDebugInfos
+}
+
+var _ ExtendedSendAlgorithm = &extendedSender{}
+
+func (s *extendedSender) OnCongestionEvent(number protocol.PacketNumber, priorInFlight protocol.ByteCount) {
+	if h, ok := s.SendAlgorithmWithDebugInfos.(CongestionEventHandler); ok {
+		h.OnCongestionEvent(number, priorInFlight)
+	}
+}
+
+func (s *extendedSender) OnSpuriousLoss(number protocol.PacketNumber) {
+	if h, ok := s.SendAlgorithmWithDebugInfos.(SpuriousLossHandler); ok {
+		h.OnSpuriousLoss(number)
+	}
+}
+
+func (s *extendedSender) OnAppLimited(bytesInFlight protocol.ByteCount) {
+	if h, ok := s.SendAlgorithmWithDebugInfos.(AppLimitedHandler); ok {
+		h.OnAppLimited(bytesInFlight)
+	}
+}
+
+func (s *extendedSender) BandwidthEstimate() Bandwidth {
+	if e, ok := s.SendAlgorithmWithDebugInfos.(BandwidthEstimator); ok {
+		return e.BandwidthEstimate()
+	}
+	return 0
+}
+
+func (s *extendedSender) ControlType() (CongestionControlType, HystartControlType) {
+	if r, ok := s.SendAlgorithmWithDebugInfos.(ControlTypeReporter); ok {
+		return r.ControlType()
+	}
+	return customControlType, unknownHystartType
+}
@@ -0,0 +1,72 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// basicSender only implements SendAlgorithmWithDebugInfos, none of the optional interfaces
+type basicSender struct {
+	SendAlgorithmWithDebugInfos
+}
+
+type appLimitedSender struct {
+	basicSender
+	appLimited []protocol.ByteCount
+}
+
+func (s *appLimitedSender) OnAppLimited(bytesInFlight protocol.ByteCount) {
+	s.appLimited = append(s.appLimited, bytesInFlight)
+}
+
+var _ = Describe("Extending a SendAlgorithm", func() {
+	newSender := func() SendAlgorithmWithDebugInfos {
+		return NewCongestionHandler(&utils.RTTStats{}, protocol.InitialPacketSizeIPv4, CongestionOptions{}, nil)
+	}
+
+	It("doesn't wrap the built-in congestion controllers", func() {
+		s := newSender()
+		Expect(Extend(s)).To(BeIdenticalTo(s))
+	})
+
+	It("forwards calls to the congestion controller", func() {
+		s := newSender()
+		e := Extend(&basicSender{s})
+		Expect(e).ToNot(BeIdenticalTo(s))
+		Expect(e.InSlowStart()).To(BeTrue())
+		cwnd := e.GetCongestionWindow()
+		Expect(cwnd).To(Equal(s.GetCongestionWindow()))
+		e.OnPacketSent(time.Now(), 0, 1, 1000, true)
+		e.OnPacketLost(1, 1000, 1000)
+		Expect(s.GetCongestionWindow()).To(BeNumerically("<", cwnd))
+		Expect(e.GetCongestionWindow()).To(Equal(s.GetCongestionWindow()))
+	})
+
+	It("ignores the events for optional interfaces the congestion controller doesn't implement", func() {
+		s := newSender()
+		e := Extend(&basicSender{s})
+		cwnd := s.GetCongestionWindow()
+		e.OnPacketSent(time.Now(), 0, 1, 1000, true)
+		e.OnCongestionEvent(1, 1000)
+		Expect(s.GetCongestionWindow()).To(Equal(cwnd))
+		e.OnSpuriousLoss(1)
+		e.OnAppLimited(1000)
+		Expect(e.BandwidthEstimate()).To(BeZero())
+		algorithm, hystart := e.ControlType()
+		Expect(algorithm.String()).To(Equal("custom"))
+		Expect(hystart.String()).To(Equal("unknown"))
+	})
+
+	It("forwards the events for optional interfaces the congestion controller implements", func() {
+		s := &appLimitedSender{basicSender: basicSender{newSender()}}
+		e := Extend(s)
+		e.OnAppLimited(1337)
+		e.OnAppLimited(42)
+		Expect(s.appLimited).To(Equal([]protocol.ByteCount{1337, 42}))
+	})
+})
@@ -9,7 +9,7 @@ import (
 
 // Note(pwestin): the magic clamping numbers come from the original code in
 // tcp_cubic.c.
-const hybridStartLowWindow = protocol.ByteCount(16)
+const hybridStartLowWindow = uint32(16)
 
 // Number of delay samples for detecting the increase of delay.
 const hybridStartMinSamples = uint32(8)
@@ -18,8 +18,8 @@ const hybridStartMinSamples = uint32(8)
 const hybridStartDelayFactorExp = 3 // 2^3 = 8
 // The original paper specifies 2 and 8ms, but those have changed over time.
 const (
-	hybridStartDelayMinThresholdUs = int64(4000)
-	hybridStartDelayMaxThresholdUs = int64(16000)
+	hybridStartDelayMinThreshold = 4 * time.Millisecond
+	hybridStartDelayMaxThreshold = 16 * time.Millisecond
 )
 
 // HybridSlowStart implements the TCP hybrid slow start algorithm
@@ -30,6 +30,13 @@ type HybridSlowStart struct {
 	currentMinRTT        time.Duration
 	rttSampleCount       uint32
 	hystartFound         bool
+
+	options HystartOptions
+}
+
+// SetOptions sets the tuning parameters.
+func (s *HybridSlowStart) SetOptions(o HystartOptions) {
+	s.options = o
 }
 
 // StartReceiveRound is called for the start of each receive round (burst) in the slow start phase.
@@ -58,6 +65,7 @@ func (s *HybridSlowStart) ShouldExitSlowStart(latestRTT time.Duration, minRTT ti
 	if s.hystartFound {
 		return true
 	}
+	opts := s.options.populate()
 	// Second detection parameter - delay increase detection.
 	// Compare the minimum delay (s.currentMinRTT) of the current
 	// burst of packets relative to the minimum delay during the session.
@@ -65,18 +73,18 @@ func (s *HybridSlowStart) ShouldExitSlowStart(latestRTT time.Duration, minRTT ti
 	// only want to compare the lowest RTT of the burst relative to previous
 	// bursts.
 	s.rttSampleCount++
-	if s.rttSampleCount <= hybridStartMinSamples {
+	if s.rttSampleCount <= opts.NRTTSample {
 		if s.currentMinRTT == 0 || s.currentMinRTT > latestRTT {
 			s.currentMinRTT = latestRTT
 		}
 	}
 	// We only need to check this once per round.
-	if s.rttSampleCount == hybridStartMinSamples {
+	if s.rttSampleCount == opts.NRTTSample {
 		// Divide minRTT by 8 to get a rtt increase threshold for exiting.
 		minRTTincreaseThresholdUs := int64(minRTT / time.Microsecond >> hybridStartDelayFactorExp)
-		// Ensure the rtt threshold is never less than 2ms or more than 16ms.
-		minRTTincreaseThresholdUs = utils.MinInt64(minRTTincreaseThresholdUs, hybridStartDelayMaxThresholdUs)
-		minRTTincreaseThreshold := time.Duration(utils.MaxInt64(minRTTincreaseThresholdUs, hybridStartDelayMinThresholdUs)) * time.Microsecond
+		// Ensure the rtt threshold is never less than 4ms or more than 16ms (by default).
+		minRTTincreaseThresholdUs = utils.MinInt64(minRTTincreaseThresholdUs, int64(opts.MaxRTTThresh/time.Microsecond))
+		minRTTincreaseThreshold := time.Duration(utils.MaxInt64(minRTTincreaseThresholdUs, int64(opts.MinRTTThresh/time.Microsecond))) * time.Microsecond
 
 		if s.currentMinRTT > (minRTT + minRTTincreaseThreshold) {
 			s.hystartFound = true
@@ -84,7 +92,7 @@ func (s *HybridSlowStart) ShouldExitSlowStart(latestRTT time.Duration, minRTT ti
 	}
 	// Exit from slow start if the cwnd is greater than 16 and
 	// increasing delay is found.
-	return congestionWindow >= hybridStartLowWindow && s.hystartFound
+	return congestionWindow >= protocol.ByteCount(opts.LowCwnd) && s.hystartFound
 }
 
 // OnPacketSent is called when a packet was sent
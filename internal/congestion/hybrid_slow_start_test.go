@@ -69,4 +69,36 @@ var _ = Describe("Hybrid slow start", func() {
 		// RTT provided.
 		Expect(slowStart.ShouldExitSlowStart(rtt+10*time.Millisecond, rtt, 100)).To(BeTrue())
 	})
+	It("uses the configured number of samples and RTT thresholds", func() {
+		rtt := 60 * time.Millisecond
+		slowStart.SetOptions(HystartOptions{
+			NRTTSample:   4,
+			MinRTTThresh: 20 * time.Millisecond,
+			MaxRTTThresh: 40 * time.Millisecond,
+		})
+
+		endPacketNumber := protocol.PacketNumber(2)
+		slowStart.StartReceiveRound(endPacketNumber)
+		// An increase of 10ms doesn't exceed the (raised) minimum threshold.
+		for n := 0; n < 4; n++ {
+			Expect(slowStart.ShouldExitSlowStart(rtt+10*time.Millisecond, rtt, 100)).To(BeFalse())
+		}
+		endPacketNumber++
+		slowStart.StartReceiveRound(endPacketNumber)
+		for n := 1; n < 4; n++ {
+			Expect(slowStart.ShouldExitSlowStart(rtt+25*time.Millisecond, rtt, 100)).To(BeFalse())
+		}
+		// The 4th sample triggers the exit.
+		Expect(slowStart.ShouldExitSlowStart(rtt+25*time.Millisecond, rtt, 100)).To(BeTrue())
+	})
+
+	It("doesn't exit slow start below the configured low window", func() {
+		rtt := 60 * time.Millisecond
+		slowStart.SetOptions(HystartOptions{LowCwnd: 32})
+		slowStart.StartReceiveRound(1)
+		for n := 0; n < int(hybridStartMinSamples); n++ {
+			Expect(slowStart.ShouldExitSlowStart(rtt+20*time.Millisecond, rtt, 20)).To(BeFalse())
+		}
+		Expect(slowStart.ShouldExitSlowStart(rtt+20*time.Millisecond, rtt, 32)).To(BeTrue())
+	})
 })
@@ -1,32 +1,220 @@
 package congestion
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/logging"
 )
 
 type CongestionControlType int
 
 const (
 	NewRenoControlType CongestionControlType = iota
-	CubicControlType                         = iota
-	BbrControlType                           = iota
+	CubicControlType
+	BbrControlType
+	Bbr2ControlType
 )
 
+// customControlType is reported for congestion controllers that don't implement ControlTypeReporter.
+const customControlType CongestionControlType = -1
+
+func (t CongestionControlType) String() string {
+	switch t {
+	case NewRenoControlType:
+		return "newreno"
+	case CubicControlType:
+		return "cubic"
+	case BbrControlType:
+		return "bbr"
+	case Bbr2ControlType:
+		return "bbr2"
+	case customControlType:
+		return "custom"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseCongestionControl parses the name of a congestion control algorithm.
+// It accepts the names returned by CongestionControlType.String, ignoring case.
+func ParseCongestionControl(s string) (CongestionControlType, error) {
+	switch strings.ToLower(s) {
+	case "newreno":
+		return NewRenoControlType, nil
+	case "cubic":
+		return CubicControlType, nil
+	case "bbr":
+		return BbrControlType, nil
+	case "bbr2":
+		return Bbr2ControlType, nil
+	default:
+		return 0, fmt.Errorf("unknown congestion control algorithm: %s", s)
+	}
+}
+
+// HystartControlType selects how NewReno and Cubic exit slow start before a packet is lost.
+// BBR and BBRv2 ignore it.
 type HystartControlType int
 
 const (
+	// HystartTypeStandard exits slow start when the hybrid slow start detects an increase of the RTT.
 	HystartTypeStandard HystartControlType = iota
-	HystartTypePlusPlus                    = iota // Hystart++
-	HystartTypeNone                        = iota
+	// HystartTypePlusPlus (Hystart++) switches to Conservative Slow Start when it detects an increase of the RTT.
+	HystartTypePlusPlus
+	// HystartTypeNone disables the hybrid slow start.
+	// The congestion window then doubles every RTT during slow start, until a packet is lost,
+	// the peer reports ECN-CE marks, or the maximum congestion window is reached.
+	HystartTypeNone
 )
 
+// unknownHystartType is reported for congestion controllers that don't implement ControlTypeReporter.
+const unknownHystartType HystartControlType = -1
+
+func (t HystartControlType) String() string {
+	switch t {
+	case HystartTypeStandard:
+		return "standard"
+	case HystartTypePlusPlus:
+		return "plusplus"
+	case HystartTypeNone:
+		return "none"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseHystart parses the name of a hybrid slow start variant.
+// It accepts the names returned by HystartControlType.String (and "++" for Hystart++), ignoring case.
+func ParseHystart(s string) (HystartControlType, error) {
+	switch strings.ToLower(s) {
+	case "standard":
+		return HystartTypeStandard, nil
+	case "plusplus", "++":
+		return HystartTypePlusPlus, nil
+	case "none":
+		return HystartTypeNone, nil
+	default:
+		return 0, fmt.Errorf("unknown hystart type: %s", s)
+	}
+}
+
 type CongestionOptions struct {
 	ControlType CongestionControlType
 	Hystart     HystartControlType
+	HystartOptions
+	// InitialCongestionWindow is the initial congestion window in bytes.
+	// It is clamped between the minimum and the maximum congestion window.
+	// If zero, it defaults to 32 packets.
+	InitialCongestionWindow protocol.ByteCount
+	// MinCongestionWindow is the minimum congestion window in bytes.
+	// The congestion window is never reduced below this value, even after repeated losses.
+	// If zero, it defaults to 2 packets for NewReno and Cubic, and to 4 packets for BBR and BBRv2.
+	MinCongestionWindow protocol.ByteCount
+	// MaxCongestionWindow is a hard cap on the congestion window in bytes.
+	// It applies in slow start as well as in congestion avoidance.
+	// If zero, the congestion window is limited to 10000 packets.
+	MaxCongestionWindow protocol.ByteCount
+	// InitialSlowStartThreshold is the initial slow start threshold (ssthresh) in bytes.
+	// Slow start ends once the congestion window reaches this value, which avoids overshooting on a path with a known capacity.
+	// It only applies to NewReno and Cubic, and only at the start of the connection (not after a migration).
+	// If zero, slow start only ends when hystart detects an RTT increase, or when a packet is lost.
+	InitialSlowStartThreshold protocol.ByteCount
+	// DisablePacing disables pacing of packets.
+	// Packets are then only limited by the congestion window.
+	DisablePacing bool
+	// MaxPacingBurst is the maximum number of bytes the pacer allows to be sent in a burst,
+	// e.g. after an idle period.
+	// If zero, it defaults to 10 packets (or more, for high pacing rates).
+	MaxPacingBurst protocol.ByteCount
+	// EnablePRR enables Proportional Rate Reduction (RFC 6937) during loss recovery.
+	// It only applies to NewReno and Cubic.
+	EnablePRR bool
+	// DelayBasedBackoff makes NewReno and Cubic react to a growing queue before packets are lost.
+	// Once per round trip, the smoothed RTT is compared to the min RTT. If it stayed more than 25% above the min RTT
+	// for two consecutive round trips, the congestion window is reduced by 15%.
+	// This reduces the latency for interactive applications, but might reduce the throughput when competing
+	// with loss-based flows. It is disabled by default.
+	DelayBasedBackoff bool
+	// DisableCubicFastConvergence disables the CUBIC fast convergence heuristic,
+	// which reduces the last maximum congestion window when consecutive loss events occur.
+	DisableCubicFastConvergence bool
+	// CubicBeta is the multiplicative decrease factor of CUBIC.
+	// It must be in (0,1). If zero, it defaults to 0.7.
+	CubicBeta float64
+	// RenoBeta is the multiplicative decrease factor of NewReno.
+	// It must be in (0,1). If zero, it defaults to 0.5.
+	RenoBeta float64
+	// PreserveCwndOnMigration keeps the congestion window and the RTT estimates
+	// when the connection migrates, as long as the network path is probably unchanged
+	// (e.g. after a NAT rebinding). Migrations to a new path always reset them.
+	// Note that the congestion window is preserved in bytes. The maximum datagram size
+	// is never decreased, so if the new path supports a larger MTU, SetMaxDatagramSize grows
+	// the datagram size without changing the preserved window (unless it was at the minimum).
+	PreserveCwndOnMigration bool
+}
+
+// HystartOptions are the tuning parameters of the hybrid slow start.
+// Zero values are replaced by the defaults.
+type HystartOptions struct {
+	// LowCwnd is the congestion window, in packets, below which slow start is never exited.
+	// If zero, it defaults to 16 packets.
+	LowCwnd uint32
+	// MinRTTThresh and MaxRTTThresh bound the RTT increase that causes an exit from slow start.
+	// If zero, they default to 4ms and 16ms.
+	// They must not be negative, and MinRTTThresh must not exceed MaxRTTThresh.
+	MinRTTThresh time.Duration
+	MaxRTTThresh time.Duration
+	// NRTTSample is the number of RTT samples taken per round.
+	// If zero, it defaults to 8.
+	NRTTSample uint32
+	// LSSDivisor is the growth divisor used in the limited slow start
+	// (Conservative Slow Start) phase of Hystart++.
+	// If zero, it defaults to 0.25.
+	LSSDivisor float64
+}
+
+func (o HystartOptions) populate() HystartOptions {
+	if o.LowCwnd == 0 {
+		o.LowCwnd = hybridStartLowWindow
+	}
+	if o.MinRTTThresh == 0 {
+		o.MinRTTThresh = hybridStartDelayMinThreshold
+	}
+	if o.MaxRTTThresh == 0 {
+		o.MaxRTTThresh = hybridStartDelayMaxThreshold
+	}
+	if o.NRTTSample == 0 {
+		o.NRTTSample = hybridStartMinSamples
+	}
+	if o.LSSDivisor == 0 {
+		o.LSSDivisor = lssDivisor
+	}
+	return o
+}
+
+// validate checks the RTT thresholds, after replacing zero values by the defaults.
+func (o HystartOptions) validate() error {
+	if o.MinRTTThresh < 0 || o.MaxRTTThresh < 0 {
+		return fmt.Errorf("negative hystart RTT threshold: min %s, max %s", o.MinRTTThresh, o.MaxRTTThresh)
+	}
+	o = o.populate()
+	if o.MinRTTThresh > o.MaxRTTThresh {
+		return fmt.Errorf("hystart MinRTTThresh (%s) exceeds MaxRTTThresh (%s)", o.MinRTTThresh, o.MaxRTTThresh)
+	}
+	return nil
 }
 
+// A SendAlgorithmFactory creates the congestion controller for a new connection.
+type SendAlgorithmFactory func(
+	rttStats *utils.RTTStats,
+	initialMaxDatagramSize protocol.ByteCount,
+	tracer logging.ConnectionTracer,
+) SendAlgorithmWithDebugInfos
+
 // A SendAlgorithm performs congestion control
 type SendAlgorithm interface {
 	TimeUntilSend(bytesInFlight protocol.ByteCount) time.Time
@@ -47,3 +235,53 @@ type SendAlgorithmWithDebugInfos interface {
 	InRecovery() bool
 	GetCongestionWindow() protocol.ByteCount
 }
+
+// The following interfaces are optional.
+// They are implemented by all built-in congestion controllers.
+// A congestion controller created by a SendAlgorithmFactory only receives the events for the interfaces it implements.
+
+// A CongestionEventHandler reacts to ECN congestion signals.
+type CongestionEventHandler interface {
+	// OnCongestionEvent is called when the peer reports an increase of the ECN-CE counter.
+	// number is the largest packet number acknowledged by the ACK frame carrying the ECN counts.
+	OnCongestionEvent(number protocol.PacketNumber, priorInFlight protocol.ByteCount)
+}
+
+// A SpuriousLossHandler reacts to spurious losses.
+type SpuriousLossHandler interface {
+	// OnSpuriousLoss is called when a packet that was declared lost is acknowledged.
+	// It allows the congestion controller to undo the reaction to the loss.
+	OnSpuriousLoss(number protocol.PacketNumber)
+}
+
+// An AppLimitedHandler is notified when the connection is application-limited.
+type AppLimitedHandler interface {
+	// OnAppLimited is called when the application runs out of data to send before the congestion window is full.
+	// Acknowledgements for the packets sent so far are then application-limited samples.
+	OnAppLimited(bytesInFlight protocol.ByteCount)
+}
+
+// A BandwidthEstimator estimates the bandwidth of the path.
+type BandwidthEstimator interface {
+	// BandwidthEstimate returns the estimated bandwidth of the path.
+	// It is zero if no estimate is available yet.
+	BandwidthEstimate() Bandwidth
+}
+
+// A ControlTypeReporter reports which algorithm it implements.
+// Congestion controllers that don't implement it are reported as "custom", with an "unknown" hystart variant.
+type ControlTypeReporter interface {
+	// ControlType returns the congestion control algorithm, and the hystart variant used during slow start.
+	// BBR and BBRv2 don't use hystart, and return HystartTypeNone.
+	ControlType() (CongestionControlType, HystartControlType)
+}
+
+// An ExtendedSendAlgorithm is a SendAlgorithmWithDebugInfos that implements all optional interfaces.
+type ExtendedSendAlgorithm interface {
+	SendAlgorithmWithDebugInfos
+	CongestionEventHandler
+	SpuriousLossHandler
+	AppLimitedHandler
+	BandwidthEstimator
+	ControlTypeReporter
+}
@@ -0,0 +1,49 @@
+package congestion
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Congestion control types", func() {
+	It("has a string representation of the congestion control type", func() {
+		Expect(NewRenoControlType.String()).To(Equal("newreno"))
+		Expect(CubicControlType.String()).To(Equal("cubic"))
+		Expect(BbrControlType.String()).To(Equal("bbr"))
+		Expect(Bbr2ControlType.String()).To(Equal("bbr2"))
+		Expect(CongestionControlType(42).String()).To(Equal("unknown"))
+	})
+
+	It("parses congestion control types", func() {
+		for _, t := range []CongestionControlType{NewRenoControlType, CubicControlType, BbrControlType, Bbr2ControlType} {
+			parsed, err := ParseCongestionControl(t.String())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed).To(Equal(t))
+		}
+		parsed, err := ParseCongestionControl("BBR")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed).To(Equal(BbrControlType))
+		_, err = ParseCongestionControl("vegas")
+		Expect(err).To(MatchError("unknown congestion control algorithm: vegas"))
+	})
+
+	It("has a string representation of the hystart type", func() {
+		Expect(HystartTypeStandard.String()).To(Equal("standard"))
+		Expect(HystartTypePlusPlus.String()).To(Equal("plusplus"))
+		Expect(HystartTypeNone.String()).To(Equal("none"))
+		Expect(HystartControlType(42).String()).To(Equal("unknown"))
+	})
+
+	It("parses hystart types", func() {
+		for _, t := range []HystartControlType{HystartTypeStandard, HystartTypePlusPlus, HystartTypeNone} {
+			parsed, err := ParseHystart(t.String())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed).To(Equal(t))
+		}
+		parsed, err := ParseHystart("++")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed).To(Equal(HystartTypePlusPlus))
+		_, err = ParseHystart("foo")
+		Expect(err).To(MatchError("unknown hystart type: foo"))
+	})
+})
@@ -14,22 +14,28 @@ const maxBurstSizePackets = 10
 type pacer struct {
 	budgetAtLastSent     protocol.ByteCount
 	maxDatagramSize      protocol.ByteCount
+	maxBurst             protocol.ByteCount // if zero, the burst size depends on the pacing rate
 	lastSentTime         time.Time
 	getAdjustedBandwidth func() uint64 // in bytes/s
 }
 
 func newPacer(getBandwidth func() Bandwidth) *pacer {
+	return newPacerWithAdjustedBandwidth(func() uint64 {
+		// Bandwidth is in bits/s. We need the value in bytes/s.
+		bw := uint64(getBandwidth() / BytesPerSecond)
+		// Use a slightly higher value than the actual measured bandwidth.
+		// RTT variations then won't result in under-utilization of the congestion window.
+		// Ultimately, this will  result in sending packets as acknowledgments are received rather than when timers fire,
+		// provided the congestion window is fully utilized and acknowledgments arrive at regular intervals.
+		return bw * 5 / 4
+	})
+}
+
+// newPacerWithAdjustedBandwidth creates a pacer that paces at exactly the rate (in bytes/s) returned by getAdjustedBandwidth.
+func newPacerWithAdjustedBandwidth(getAdjustedBandwidth func() uint64) *pacer {
 	p := &pacer{
-		maxDatagramSize: initialMaxDatagramSize,
-		getAdjustedBandwidth: func() uint64 {
-			// Bandwidth is in bits/s. We need the value in bytes/s.
-			bw := uint64(getBandwidth() / BytesPerSecond)
-			// Use a slightly higher value than the actual measured bandwidth.
-			// RTT variations then won't result in under-utilization of the congestion window.
-			// Ultimately, this will  result in sending packets as acknowledgments are received rather than when timers fire,
-			// provided the congestion window is fully utilized and acknowledgments arrive at regular intervals.
-			return bw * 5 / 4
-		},
+		maxDatagramSize:      initialMaxDatagramSize,
+		getAdjustedBandwidth: getAdjustedBandwidth,
 	}
 	p.budgetAtLastSent = p.maxBurstSize()
 	return p
@@ -54,6 +60,9 @@ func (p *pacer) Budget(now time.Time) protocol.ByteCount {
 }
 
 func (p *pacer) maxBurstSize() protocol.ByteCount {
+	if p.maxBurst != 0 {
+		return utils.MaxByteCount(p.maxBurst, p.maxDatagramSize)
+	}
 	return utils.MaxByteCount(
 		protocol.ByteCount(uint64((protocol.MinPacingDelay+protocol.TimerGranularity).Nanoseconds())*p.getAdjustedBandwidth())/1e9,
 		maxBurstSizePackets*p.maxDatagramSize,
@@ -75,3 +84,12 @@ func (p *pacer) TimeUntilSend() time.Time {
 func (p *pacer) SetMaxDatagramSize(s protocol.ByteCount) {
 	p.maxDatagramSize = s
 }
+
+// SetMaxBurstSize sets the maximum number of bytes that can be sent in a burst.
+// If zero, the default burst size is used.
+func (p *pacer) SetMaxBurstSize(s protocol.ByteCount) {
+	p.maxBurst = s
+	if p.lastSentTime.IsZero() {
+		p.budgetAtLastSent = p.maxBurstSize()
+	}
+}
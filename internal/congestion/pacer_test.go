@@ -114,6 +114,24 @@ var _ = Describe("Pacer", func() {
 		Expect(p.Budget(t.Add(time.Hour))).To(BeEquivalentTo(maxBurstSizePackets * packetSize))
 	})
 
+	It("uses the configured maximum burst size", func() {
+		const maxBurst = 30 * initialMaxDatagramSize
+		p.SetMaxBurstSize(maxBurst)
+		t := time.Now()
+		Expect(p.Budget(t)).To(Equal(maxBurst))
+		sendBurst(t)
+		Expect(p.TimeUntilSend()).ToNot(BeZero())
+		// after an idle period, the configured burst is allowed again
+		t = t.Add(time.Hour)
+		Expect(p.Budget(t)).To(Equal(maxBurst))
+		for i := 0; i < 30; i++ {
+			Expect(p.Budget(t)).To(BeNumerically(">=", initialMaxDatagramSize))
+			p.SentPacket(t, initialMaxDatagramSize)
+		}
+		Expect(p.Budget(t)).To(BeZero())
+		Expect(p.TimeUntilSend()).To(BeTemporally("~", t.Add(time.Second/packetsPerSecond), time.Nanosecond))
+	})
+
 	It("changes the bandwidth", func() {
 		t := time.Now()
 		sendBurst(t)
@@ -0,0 +1,55 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// A RateLimiter limits the send rate to a fixed number of bytes per second.
+// It is independent of the congestion controller, and uses the same token bucket algorithm as the pacer.
+type RateLimiter struct {
+	clock           Clock
+	pacer           *pacer
+	maxDatagramSize protocol.ByteCount
+	bytesPerSecond  uint64
+}
+
+// NewRateLimiter creates a new RateLimiter.
+func NewRateLimiter(clock Clock, bytesPerSecond uint64, maxDatagramSize protocol.ByteCount) *RateLimiter {
+	l := &RateLimiter{
+		clock:           clock,
+		maxDatagramSize: maxDatagramSize,
+		bytesPerSecond:  bytesPerSecond,
+	}
+	l.pacer = newPacerWithAdjustedBandwidth(func() uint64 { return l.bytesPerSecond })
+	l.pacer.SetMaxDatagramSize(maxDatagramSize)
+	return l
+}
+
+// SetRate changes the send rate.
+func (l *RateLimiter) SetRate(bytesPerSecond uint64) {
+	l.bytesPerSecond = bytesPerSecond
+}
+
+// SentPacket is called for every packet sent.
+func (l *RateLimiter) SentPacket(sentTime time.Time, bytes protocol.ByteCount) {
+	l.pacer.SentPacket(sentTime, bytes)
+}
+
+// HasBudget says if a full-size packet can be sent at this moment.
+func (l *RateLimiter) HasBudget() bool {
+	return l.pacer.Budget(l.clock.Now()) >= l.maxDatagramSize
+}
+
+// TimeUntilSend returns when the next packet can be sent.
+// It returns the zero value of time.Time if a packet can be sent immediately.
+func (l *RateLimiter) TimeUntilSend() time.Time {
+	return l.pacer.TimeUntilSend()
+}
+
+// SetMaxDatagramSize sets the size of a full-size packet.
+func (l *RateLimiter) SetMaxDatagramSize(s protocol.ByteCount) {
+	l.maxDatagramSize = s
+	l.pacer.SetMaxDatagramSize(s)
+}
@@ -1,62 +1,134 @@
 package congestion
 
 import (
+	"fmt"
+
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/logging"
 )
 
-func hystartTypeToString(t HystartControlType) string {
-	switch t {
-	case HystartTypeStandard:
-		return "standard"
-	case HystartTypePlusPlus:
-		return "++"
-	case HystartTypeNone:
-		return "none"
+// initialCongestionWindowFromOptions returns the configured initial congestion window, clamped to [minWindow, maxWindow].
+func initialCongestionWindowFromOptions(options CongestionOptions, initialMaxDatagramSize, minWindow, maxWindow protocol.ByteCount) protocol.ByteCount {
+	if options.InitialCongestionWindow == 0 {
+		return utils.MinByteCount(maxWindow, initialCongestionWindow*initialMaxDatagramSize)
 	}
-	return ""
+	return utils.MinByteCount(maxWindow, utils.MaxByteCount(minWindow, options.InitialCongestionWindow))
 }
 
-func congestionTypeToString(t CongestionControlType) string {
-	switch t {
-	case CubicControlType:
-		return "Cubic"
-	case NewRenoControlType:
-		return "NewReno"
-	case BbrControlType:
-		return "Bbr"
+// NewCongestionHandlerErr creates the congestion controller selected by the options.
+// Unlike NewCongestionHandler, it returns an error if the congestion control type
+// or the hystart type is not supported, instead of falling back to Cubic.
+func NewCongestionHandlerErr(
+	rttStats *utils.RTTStats,
+	initialMaxDatagramSize protocol.ByteCount,
+	options CongestionOptions,
+	tracer logging.ConnectionTracer,
+) (ExtendedSendAlgorithm, error) {
+	switch options.ControlType {
+	case NewRenoControlType, CubicControlType, BbrControlType, Bbr2ControlType:
+	default:
+		return nil, fmt.Errorf("unsupported congestion control type: %d", options.ControlType)
 	}
-	return ""
+	switch options.Hystart {
+	case HystartTypeStandard, HystartTypePlusPlus, HystartTypeNone:
+	default:
+		return nil, fmt.Errorf("unsupported hystart type: %d", options.Hystart)
+	}
+	if options.CubicBeta < 0 || options.CubicBeta >= 1 {
+		return nil, fmt.Errorf("invalid CUBIC beta: %f", options.CubicBeta)
+	}
+	if options.RenoBeta < 0 || options.RenoBeta >= 1 {
+		return nil, fmt.Errorf("invalid Reno beta: %f", options.RenoBeta)
+	}
+	if err := options.HystartOptions.validate(); err != nil {
+		return nil, err
+	}
+	return NewCongestionHandler(rttStats, initialMaxDatagramSize, options, tracer), nil
 }
 
+// NewCongestionHandler creates the congestion controller selected by the options.
+// Unsupported congestion control types fall back to Cubic, and unsupported hystart types to the standard hystart.
 func NewCongestionHandler(
 	rttStats *utils.RTTStats,
 	initialMaxDatagramSize protocol.ByteCount,
 	options CongestionOptions,
 	tracer logging.ConnectionTracer,
-) SendAlgorithmWithDebugInfos {
+) ExtendedSendAlgorithm {
+	return NewCongestionHandlerWithClock(DefaultClock{}, rttStats, initialMaxDatagramSize, options, tracer)
+}
+
+// NewCongestionHandlerWithClock is like NewCongestionHandler, but uses the given clock.
+// This allows tests to control the time.
+func NewCongestionHandlerWithClock(
+	clock Clock,
+	rttStats *utils.RTTStats,
+	initialMaxDatagramSize protocol.ByteCount,
+	options CongestionOptions,
+	tracer logging.ConnectionTracer,
+) ExtendedSendAlgorithm {
 	logger := utils.DefaultLogger
 
+	switch options.Hystart {
+	case HystartTypeStandard, HystartTypePlusPlus, HystartTypeNone:
+	default:
+		logger.Errorf("Unsupported hystart type %d, falling back to standard hystart", options.Hystart)
+		options.Hystart = HystartTypeStandard
+	}
+	if options.CubicBeta < 0 || options.CubicBeta >= 1 {
+		logger.Errorf("Invalid CUBIC beta %f, falling back to the default", options.CubicBeta)
+		options.CubicBeta = 0
+	}
+	if options.RenoBeta < 0 || options.RenoBeta >= 1 {
+		logger.Errorf("Invalid Reno beta %f, falling back to the default", options.RenoBeta)
+		options.RenoBeta = 0
+	}
+	if err := options.HystartOptions.validate(); err != nil {
+		logger.Errorf("Invalid hystart options (%s), falling back to the default RTT thresholds", err)
+		options.HystartOptions.MinRTTThresh = 0
+		options.HystartOptions.MaxRTTThresh = 0
+	}
+
 	switch options.ControlType {
+	case BbrControlType:
+		logger.Infof("Congestion Control: BBR")
+		return NewBbrSender(
+			clock,
+			rttStats,
+			initialMaxDatagramSize,
+			options,
+			tracer,
+		)
+	case Bbr2ControlType:
+		logger.Infof("Congestion Control: BBRv2")
+		return NewBbr2Sender(
+			clock,
+			rttStats,
+			initialMaxDatagramSize,
+			options,
+			tracer,
+		)
 	case NewRenoControlType:
-		logger.Infof("Congestion Control: NewReno with hystart: %s", hystartTypeToString(options.Hystart))
+		logger.Infof("Congestion Control: NewReno with hystart: %s", options.Hystart)
 		return NewCubicSender(
-			DefaultClock{},
+			clock,
 			rttStats,
 			initialMaxDatagramSize,
 			true, // use Reno
-			options.Hystart,
+			options,
 			tracer,
 		)
 	default:
-		logger.Infof("Congestion Control: Cubic with hystart: %s", hystartTypeToString(options.Hystart))
+		if options.ControlType != CubicControlType {
+			logger.Errorf("Unsupported congestion control type %d, falling back to Cubic", options.ControlType)
+		}
+		logger.Infof("Congestion Control: Cubic with hystart: %s", options.Hystart)
 		return NewCubicSender(
-			DefaultClock{},
+			clock,
 			rttStats,
 			initialMaxDatagramSize,
 			false, // use Cubic
-			options.Hystart,
+			options,
 			tracer,
 		)
 	}
@@ -0,0 +1,121 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Congestion Handler", func() {
+	It("creates the requested congestion controller", func() {
+		cc, err := NewCongestionHandlerErr(utils.NewRTTStats(), protocol.InitialPacketSizeIPv4, CongestionOptions{ControlType: NewRenoControlType}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cc).To(BeAssignableToTypeOf(&cubicSender{}))
+		Expect(cc.(*cubicSender).reno).To(BeTrue())
+		cc, err = NewCongestionHandlerErr(utils.NewRTTStats(), protocol.InitialPacketSizeIPv4, CongestionOptions{ControlType: CubicControlType}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cc).To(BeAssignableToTypeOf(&cubicSender{}))
+		Expect(cc.(*cubicSender).reno).To(BeFalse())
+		cc, err = NewCongestionHandlerErr(utils.NewRTTStats(), protocol.InitialPacketSizeIPv4, CongestionOptions{ControlType: BbrControlType}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cc).To(BeAssignableToTypeOf(&bbrSender{}))
+		Expect(cc.(*bbrSender).v2).To(BeNil())
+		cc, err = NewCongestionHandlerErr(utils.NewRTTStats(), protocol.InitialPacketSizeIPv4, CongestionOptions{ControlType: Bbr2ControlType}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cc).To(BeAssignableToTypeOf(&bbrSender{}))
+		Expect(cc.(*bbrSender).v2).ToNot(BeNil())
+	})
+
+	It("errors for unsupported congestion control types", func() {
+		_, err := NewCongestionHandlerErr(utils.NewRTTStats(), protocol.InitialPacketSizeIPv4, CongestionOptions{ControlType: 42}, nil)
+		Expect(err).To(MatchError("unsupported congestion control type: 42"))
+	})
+
+	It("errors for unsupported hystart types", func() {
+		_, err := NewCongestionHandlerErr(utils.NewRTTStats(), protocol.InitialPacketSizeIPv4, CongestionOptions{Hystart: 42}, nil)
+		Expect(err).To(MatchError("unsupported hystart type: 42"))
+	})
+
+	It("errors for invalid CUBIC beta values", func() {
+		_, err := NewCongestionHandlerErr(utils.NewRTTStats(), protocol.InitialPacketSizeIPv4, CongestionOptions{CubicBeta: 1}, nil)
+		Expect(err).To(MatchError("invalid CUBIC beta: 1.000000"))
+		_, err = NewCongestionHandlerErr(utils.NewRTTStats(), protocol.InitialPacketSizeIPv4, CongestionOptions{CubicBeta: -0.5}, nil)
+		Expect(err).To(MatchError("invalid CUBIC beta: -0.500000"))
+	})
+
+	It("errors for invalid Reno beta values", func() {
+		_, err := NewCongestionHandlerErr(utils.NewRTTStats(), protocol.InitialPacketSizeIPv4, CongestionOptions{RenoBeta: 1}, nil)
+		Expect(err).To(MatchError("invalid Reno beta: 1.000000"))
+		_, err = NewCongestionHandlerErr(utils.NewRTTStats(), protocol.InitialPacketSizeIPv4, CongestionOptions{RenoBeta: -0.5}, nil)
+		Expect(err).To(MatchError("invalid Reno beta: -0.500000"))
+	})
+
+	It("errors for invalid hystart RTT thresholds", func() {
+		_, err := NewCongestionHandlerErr(utils.NewRTTStats(), protocol.InitialPacketSizeIPv4, CongestionOptions{
+			HystartOptions: HystartOptions{MinRTTThresh: 20 * time.Millisecond, MaxRTTThresh: 10 * time.Millisecond},
+		}, nil)
+		Expect(err).To(MatchError("hystart MinRTTThresh (20ms) exceeds MaxRTTThresh (10ms)"))
+		// the default MaxRTTThresh is 16ms
+		_, err = NewCongestionHandlerErr(utils.NewRTTStats(), protocol.InitialPacketSizeIPv4, CongestionOptions{
+			HystartOptions: HystartOptions{MinRTTThresh: 20 * time.Millisecond},
+		}, nil)
+		Expect(err).To(MatchError("hystart MinRTTThresh (20ms) exceeds MaxRTTThresh (16ms)"))
+		_, err = NewCongestionHandlerErr(utils.NewRTTStats(), protocol.InitialPacketSizeIPv4, CongestionOptions{
+			HystartOptions: HystartOptions{MinRTTThresh: -time.Millisecond},
+		}, nil)
+		Expect(err).To(MatchError("negative hystart RTT threshold: min -1ms, max 0s"))
+		_, err = NewCongestionHandlerErr(utils.NewRTTStats(), protocol.InitialPacketSizeIPv4, CongestionOptions{
+			HystartOptions: HystartOptions{MaxRTTThresh: -time.Millisecond},
+		}, nil)
+		Expect(err).To(MatchError("negative hystart RTT threshold: min 0s, max -1ms"))
+		_, err = NewCongestionHandlerErr(utils.NewRTTStats(), protocol.InitialPacketSizeIPv4, CongestionOptions{
+			HystartOptions: HystartOptions{MinRTTThresh: 20 * time.Millisecond, MaxRTTThresh: 20 * time.Millisecond},
+		}, nil)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("falls back to the default hystart RTT thresholds for invalid values", func() {
+		cc := NewCongestionHandler(utils.NewRTTStats(), protocol.InitialPacketSizeIPv4, CongestionOptions{
+			ControlType:    CubicControlType,
+			HystartOptions: HystartOptions{MinRTTThresh: 20 * time.Millisecond, MaxRTTThresh: 10 * time.Millisecond, NRTTSample: 4},
+		}, nil)
+		opts := cc.(*cubicSender).hybridSlowStart.options
+		Expect(opts.MinRTTThresh).To(BeZero())
+		Expect(opts.MaxRTTThresh).To(BeZero())
+		Expect(opts.NRTTSample).To(BeEquivalentTo(4))
+	})
+
+	It("falls back to the default Reno beta for invalid values", func() {
+		cc := NewCongestionHandler(utils.NewRTTStats(), protocol.InitialPacketSizeIPv4, CongestionOptions{ControlType: NewRenoControlType, RenoBeta: 1.5}, nil)
+		Expect(cc.(*cubicSender).renoBeta).To(Equal(renoBeta))
+	})
+
+	It("falls back to the default CUBIC beta for invalid values", func() {
+		cc := NewCongestionHandler(utils.NewRTTStats(), protocol.InitialPacketSizeIPv4, CongestionOptions{ControlType: CubicControlType, CubicBeta: 1.5}, nil)
+		Expect(cc.(*cubicSender).cubic.betaFactor).To(Equal(beta))
+	})
+
+	It("falls back to Cubic for unsupported congestion control types", func() {
+		cc := NewCongestionHandler(utils.NewRTTStats(), protocol.InitialPacketSizeIPv4, CongestionOptions{ControlType: 42, Hystart: 42}, nil)
+		Expect(cc).To(BeAssignableToTypeOf(&cubicSender{}))
+		Expect(cc.(*cubicSender).reno).To(BeFalse())
+		Expect(cc.(*cubicSender).hybridSlowStartType).To(Equal(HystartTypeStandard))
+		algorithm, hystart := cc.ControlType()
+		Expect(algorithm).To(Equal(CubicControlType))
+		Expect(hystart).To(Equal(HystartTypeStandard))
+	})
+
+	It("reports the congestion control algorithm in use", func() {
+		cc := NewCongestionHandler(utils.NewRTTStats(), protocol.InitialPacketSizeIPv4, CongestionOptions{ControlType: NewRenoControlType, Hystart: HystartTypePlusPlus}, nil)
+		algorithm, hystart := cc.ControlType()
+		Expect(algorithm.String()).To(Equal("newreno"))
+		Expect(hystart.String()).To(Equal("plusplus"))
+		cc = NewCongestionHandler(utils.NewRTTStats(), protocol.InitialPacketSizeIPv4, CongestionOptions{ControlType: Bbr2ControlType, Hystart: HystartTypePlusPlus}, nil)
+		algorithm, hystart = cc.ControlType()
+		Expect(algorithm.String()).To(Equal("bbr2"))
+		Expect(hystart.String()).To(Equal("none"))
+	})
+})
@@ -52,6 +52,19 @@ func (c *baseFlowController) UpdateSendWindow(offset protocol.ByteCount) {
 	}
 }
 
+// SendWindow returns the offset up to which the peer allows us to send.
+func (c *baseFlowController) SendWindow() protocol.ByteCount {
+	return c.sendWindow
+}
+
+// ReceiveWindow returns the highest offset received,
+// and the offset up to which the peer is allowed to send.
+func (c *baseFlowController) ReceiveWindow() (highestReceived, receiveWindow protocol.ByteCount) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.highestReceived, c.receiveWindow
+}
+
 func (c *baseFlowController) sendWindowSize() protocol.ByteCount {
 	// this only happens during connection establishment, when data is sent before we receive the peer's transport parameters
 	if c.bytesSent > c.sendWindow {
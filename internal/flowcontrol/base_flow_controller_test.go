@@ -51,6 +51,12 @@ var _ = Describe("Base Flow controller", func() {
 			Expect(controller.sendWindowSize()).To(Equal(protocol.ByteCount(15 - 5)))
 		})
 
+		It("returns the send window", func() {
+			controller.UpdateSendWindow(15)
+			controller.AddBytesSent(5)
+			Expect(controller.SendWindow()).To(Equal(protocol.ByteCount(15)))
+		})
+
 		It("says that the window size is 0 if we sent more than we were allowed to", func() {
 			controller.AddBytesSent(15)
 			controller.UpdateSendWindow(10)
@@ -100,6 +106,13 @@ var _ = Describe("Base Flow controller", func() {
 			controller.receiveWindowSize = receiveWindowSize
 		})
 
+		It("returns the receive window", func() {
+			controller.highestReceived = 1337
+			highestReceived, window := controller.ReceiveWindow()
+			Expect(highestReceived).To(Equal(protocol.ByteCount(1337)))
+			Expect(window).To(Equal(receiveWindow))
+		})
+
 		It("adds bytes read", func() {
 			controller.bytesRead = 5
 			controller.addBytesRead(6)
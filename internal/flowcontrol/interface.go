@@ -24,6 +24,9 @@ type StreamFlowController interface {
 	// Abandon should be called when reading from the stream is aborted early,
 	// and there won't be any further calls to AddBytesRead.
 	Abandon()
+	// for statistics
+	SendWindow() protocol.ByteCount
+	ReceiveWindow() (highestReceived, receiveWindow protocol.ByteCount)
 }
 
 // The ConnectionFlowController is the flow controller for the connection.
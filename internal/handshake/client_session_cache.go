@@ -0,0 +1,219 @@
+package handshake
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/qtls"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+const serializedSessionRevision = 1
+
+// A ClientSessionCache stores serialized sessions, such that they can be persisted across restarts.
+// A nil session passed to Put removes the session stored for the given key.
+type ClientSessionCache interface {
+	Get(sessionKey string) (session []byte, ok bool)
+	Put(sessionKey string, session []byte)
+}
+
+type clientSessionCache struct {
+	cache ClientSessionCache
+}
+
+var _ qtls.ClientSessionCache = &clientSessionCache{}
+
+// NewClientSessionCache creates a qtls.ClientSessionCache that serializes sessions and saves them in the given cache.
+func NewClientSessionCache(cache ClientSessionCache) qtls.ClientSessionCache {
+	return &clientSessionCache{cache: cache}
+}
+
+func (c *clientSessionCache) Get(sessionKey string) (*qtls.ClientSessionState, bool) {
+	data, ok := c.cache.Get(sessionKey)
+	if !ok || data == nil {
+		return nil, false
+	}
+	session, err := unmarshalClientSessionState(data)
+	if err != nil {
+		return nil, false
+	}
+	return session, true
+}
+
+func (c *clientSessionCache) Put(sessionKey string, session *qtls.ClientSessionState) {
+	if session == nil {
+		c.cache.Put(sessionKey, nil)
+		return
+	}
+	c.cache.Put(sessionKey, marshalClientSessionState(session))
+}
+
+// marshalClientSessionState serializes a session.
+// All integers are encoded as varints, and all byte slices are prefixed with their length.
+// The nonce contains the application data saved by the cryptoSetup (see marshalDataForSessionState):
+// the smoothed RTT, and the transport parameters, encoded using wire.TransportParameters.MarshalForSessionTicket.
+func marshalClientSessionState(s *qtls.ClientSessionState) []byte {
+	f := qtls.ToClientSessionStateFields(s)
+	b := &bytes.Buffer{}
+	quicvarint.Write(b, serializedSessionRevision)
+	writeBytes(b, f.SessionTicket)
+	quicvarint.Write(b, uint64(f.Vers))
+	quicvarint.Write(b, uint64(f.CipherSuite))
+	writeBytes(b, f.MasterSecret)
+	writeCertificates(b, f.ServerCertificates)
+	quicvarint.Write(b, uint64(len(f.VerifiedChains)))
+	for _, chain := range f.VerifiedChains {
+		writeCertificates(b, chain)
+	}
+	quicvarint.Write(b, uint64(f.ReceivedAt.UnixNano()))
+	writeBytes(b, f.OCSPResponse)
+	quicvarint.Write(b, uint64(len(f.SCTs)))
+	for _, sct := range f.SCTs {
+		writeBytes(b, sct)
+	}
+	writeBytes(b, f.Nonce)
+	quicvarint.Write(b, uint64(f.UseBy.UnixNano()))
+	quicvarint.Write(b, uint64(f.AgeAdd))
+	return b.Bytes()
+}
+
+func unmarshalClientSessionState(data []byte) (*qtls.ClientSessionState, error) {
+	r := bytes.NewReader(data)
+	rev, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, errors.New("failed to read session revision")
+	}
+	if rev != serializedSessionRevision {
+		return nil, fmt.Errorf("unknown session revision: %d", rev)
+	}
+	var f qtls.ClientSessionStateFields
+	if f.SessionTicket, err = readBytes(r); err != nil {
+		return nil, err
+	}
+	vers, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	f.Vers = uint16(vers)
+	cipherSuite, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	f.CipherSuite = uint16(cipherSuite)
+	if f.MasterSecret, err = readBytes(r); err != nil {
+		return nil, err
+	}
+	if f.ServerCertificates, err = readCertificates(r); err != nil {
+		return nil, err
+	}
+	numChains, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	if numChains > uint64(r.Len()) {
+		return nil, io.EOF
+	}
+	for i := uint64(0); i < numChains; i++ {
+		chain, err := readCertificates(r)
+		if err != nil {
+			return nil, err
+		}
+		f.VerifiedChains = append(f.VerifiedChains, chain)
+	}
+	receivedAt, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	f.ReceivedAt = time.Unix(0, int64(receivedAt))
+	if f.OCSPResponse, err = readBytes(r); err != nil {
+		return nil, err
+	}
+	numSCTs, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	if numSCTs > uint64(r.Len()) {
+		return nil, io.EOF
+	}
+	for i := uint64(0); i < numSCTs; i++ {
+		sct, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		f.SCTs = append(f.SCTs, sct)
+	}
+	if f.Nonce, err = readBytes(r); err != nil {
+		return nil, err
+	}
+	useBy, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	f.UseBy = time.Unix(0, int64(useBy))
+	ageAdd, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	f.AgeAdd = uint32(ageAdd)
+	if r.Len() > 0 {
+		return nil, fmt.Errorf("session has %d bytes of trailing data", r.Len())
+	}
+	return qtls.FromClientSessionStateFields(&f), nil
+}
+
+func writeBytes(b *bytes.Buffer, data []byte) {
+	quicvarint.Write(b, uint64(len(data)))
+	b.Write(data)
+}
+
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	l, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	if l > uint64(r.Len()) {
+		return nil, io.EOF
+	}
+	if l == 0 {
+		return nil, nil
+	}
+	data := make([]byte, l)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func writeCertificates(b *bytes.Buffer, certs []*x509.Certificate) {
+	quicvarint.Write(b, uint64(len(certs)))
+	for _, cert := range certs {
+		writeBytes(b, cert.Raw)
+	}
+}
+
+func readCertificates(r *bytes.Reader) ([]*x509.Certificate, error) {
+	num, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	if num > uint64(r.Len()) {
+		return nil, io.EOF
+	}
+	certs := make([]*x509.Certificate, 0, num)
+	for i := uint64(0); i < num; i++ {
+		raw, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
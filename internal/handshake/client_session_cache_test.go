@@ -0,0 +1,152 @@
+package handshake
+
+import (
+	"crypto/x509"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/qtls"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type mapSessionCache struct {
+	mutex    sync.Mutex
+	sessions map[string][]byte
+}
+
+func newMapSessionCache() *mapSessionCache {
+	return &mapSessionCache{sessions: make(map[string][]byte)}
+}
+
+func (c *mapSessionCache) Get(key string) ([]byte, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	session, ok := c.sessions[key]
+	return session, ok
+}
+
+func (c *mapSessionCache) Put(key string, session []byte) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if session == nil {
+		delete(c.sessions, key)
+		return
+	}
+	c.sessions[key] = session
+}
+
+func (c *mapSessionCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.sessions)
+}
+
+var _ = Describe("Client Session Cache", func() {
+	var cert *x509.Certificate
+
+	BeforeEach(func() {
+		var err error
+		cert, err = x509.ParseCertificate(testdata.GetTLSConfig().Certificates[0].Certificate[0])
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	getSession := func() *qtls.ClientSessionStateFields {
+		return &qtls.ClientSessionStateFields{
+			SessionTicket:      []byte("ticket"),
+			Vers:               0x0304,
+			CipherSuite:        0x1301,
+			MasterSecret:       []byte("secret"),
+			ServerCertificates: []*x509.Certificate{cert},
+			VerifiedChains:     [][]*x509.Certificate{{cert}},
+			ReceivedAt:         time.Now().Add(-time.Minute),
+			OCSPResponse:       []byte("ocsp"),
+			SCTs:               [][]byte{[]byte("foo"), []byte("bar")},
+			Nonce:              []byte("nonce"),
+			UseBy:              time.Now().Add(time.Hour),
+			AgeAdd:             1337,
+		}
+	}
+
+	It("serializes sessions", func() {
+		s := getSession()
+		data := marshalClientSessionState(qtls.FromClientSessionStateFields(s))
+		restored, err := unmarshalClientSessionState(data)
+		Expect(err).ToNot(HaveOccurred())
+		f := qtls.ToClientSessionStateFields(restored)
+		Expect(f.SessionTicket).To(Equal(s.SessionTicket))
+		Expect(f.Vers).To(Equal(s.Vers))
+		Expect(f.CipherSuite).To(Equal(s.CipherSuite))
+		Expect(f.MasterSecret).To(Equal(s.MasterSecret))
+		Expect(f.ServerCertificates).To(HaveLen(1))
+		Expect(f.ServerCertificates[0].Equal(cert)).To(BeTrue())
+		Expect(f.VerifiedChains).To(HaveLen(1))
+		Expect(f.VerifiedChains[0]).To(HaveLen(1))
+		Expect(f.VerifiedChains[0][0].Equal(cert)).To(BeTrue())
+		Expect(f.ReceivedAt).To(BeTemporally("==", s.ReceivedAt))
+		Expect(f.OCSPResponse).To(Equal(s.OCSPResponse))
+		Expect(f.SCTs).To(Equal(s.SCTs))
+		Expect(f.Nonce).To(Equal(s.Nonce))
+		Expect(f.UseBy).To(BeTemporally("==", s.UseBy))
+		Expect(f.AgeAdd).To(Equal(s.AgeAdd))
+	})
+
+	It("rejects sessions with an unknown revision", func() {
+		data := marshalClientSessionState(qtls.FromClientSessionStateFields(getSession()))
+		data[0] = serializedSessionRevision + 1
+		_, err := unmarshalClientSessionState(data)
+		Expect(err).To(MatchError("unknown session revision: 2"))
+	})
+
+	It("errors on EOF", func() {
+		data := marshalClientSessionState(qtls.FromClientSessionStateFields(getSession()))
+		_, err := unmarshalClientSessionState(data)
+		Expect(err).ToNot(HaveOccurred())
+		for i := range data {
+			_, err := unmarshalClientSessionState(data[:i])
+			Expect(err).To(HaveOccurred())
+		}
+	})
+
+	It("errors on trailing data", func() {
+		data := marshalClientSessionState(qtls.FromClientSessionStateFields(getSession()))
+		_, err := unmarshalClientSessionState(append(data, 0))
+		Expect(err).To(MatchError("session has 1 bytes of trailing data"))
+	})
+
+	It("saves sessions in the cache", func() {
+		cache := newMapSessionCache()
+		csc := NewClientSessionCache(cache)
+		csc.Put("foo", qtls.FromClientSessionStateFields(getSession()))
+		data, ok := cache.Get("foo")
+		Expect(ok).To(BeTrue())
+		// create a new cache, as if the client was restarted
+		restored := newMapSessionCache()
+		restored.Put("foo", data)
+		csc = NewClientSessionCache(restored)
+		session, ok := csc.Get("foo")
+		Expect(ok).To(BeTrue())
+		Expect(qtls.ToClientSessionStateFields(session).SessionTicket).To(Equal([]byte("ticket")))
+		_, ok = csc.Get("bar")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("doesn't return sessions that can't be parsed", func() {
+		cache := newMapSessionCache()
+		cache.Put("foo", []byte("foobar"))
+		csc := NewClientSessionCache(cache)
+		_, ok := csc.Get("foo")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("deletes sessions", func() {
+		cache := newMapSessionCache()
+		csc := NewClientSessionCache(cache)
+		csc.Put("foo", qtls.FromClientSessionStateFields(getSession()))
+		Expect(cache.Len()).To(Equal(1))
+		csc.Put("foo", nil)
+		Expect(cache.Len()).To(BeZero())
+	})
+})
@@ -101,8 +101,11 @@ type cryptoSetup struct {
 
 	ourParams  *wire.TransportParameters
 	peerParams *wire.TransportParameters
+	extHandler tlsExtensionHandler
 	paramsChan <-chan []byte
 
+	initialConnID protocol.ConnectionID // the connection ID used to derive the Initial keys
+
 	runner handshakeRunner
 
 	alertChan chan uint8
@@ -244,12 +247,14 @@ func newCryptoSetup(
 		initialSealer:             initialSealer,
 		initialOpener:             initialOpener,
 		handshakeStream:           handshakeStream,
-		aead:                      newUpdatableAEAD(rttStats, tracer, logger),
+		aead:                      newUpdatableAEAD(rttStats, tracer, logger, version),
 		readEncLevel:              protocol.EncryptionInitial,
 		writeEncLevel:             protocol.EncryptionInitial,
 		runner:                    runner,
 		ourParams:                 tp,
+		extHandler:                extHandler,
 		paramsChan:                extHandler.TransportParameters(),
+		initialConnID:             connID,
 		rttStats:                  rttStats,
 		tracer:                    tracer,
 		logger:                    logger,
@@ -282,6 +287,7 @@ func newCryptoSetup(
 }
 
 func (h *cryptoSetup) ChangeConnectionID(id protocol.ConnectionID) {
+	h.initialConnID = id
 	initialSealer, initialOpener := NewInitialAEAD(id, h.perspective, h.version)
 	h.initialSealer = initialSealer
 	h.initialOpener = initialOpener
@@ -291,6 +297,23 @@ func (h *cryptoSetup) ChangeConnectionID(id protocol.ConnectionID) {
 	}
 }
 
+// ChangeVersion switches to a compatible version (RFC 9368).
+// The Initial keys are derived again, and all keys derived later use the new version.
+// The client calls it when receiving the server's first packet,
+// the server calls it while handling the client's transport parameters.
+func (h *cryptoSetup) ChangeVersion(v protocol.VersionNumber) {
+	h.mutex.Lock()
+	h.version = v
+	h.aead.version = v
+	h.mutex.Unlock()
+	h.ChangeConnectionID(h.initialConnID)
+	// The server sends its chosen version in the version_information transport parameter.
+	if h.perspective == protocol.PerspectiveServer && h.ourParams.VersionInformation != nil {
+		h.ourParams.VersionInformation.ChosenVersion = v
+		h.extHandler.SetTransportParameters(h.ourParams.Marshal(h.perspective))
+	}
+}
+
 func (h *cryptoSetup) SetLargest1RTTAcked(pn protocol.PacketNumber) error {
 	return h.aead.SetLargestAcked(pn)
 }
@@ -361,6 +384,7 @@ readLoop:
 			} else {
 				h.handleTransportParameters(data)
 			}
+			h.extHandler.HandledTransportParameters()
 		case <-h.isReadingHandshakeMessage:
 			break readLoop
 		case <-h.handshakeDone:
@@ -554,8 +578,8 @@ func (h *cryptoSetup) SetReadKey(encLevel qtls.EncryptionLevel, suite *qtls.Ciph
 			panic("Received 0-RTT read key for the client")
 		}
 		h.zeroRTTOpener = newLongHeaderOpener(
-			createAEAD(suite, trafficSecret),
-			newHeaderProtector(suite, trafficSecret, true),
+			createAEAD(suite, trafficSecret, h.version),
+			newHeaderProtector(suite, trafficSecret, true, h.version),
 		)
 		h.mutex.Unlock()
 		h.logger.Debugf("Installed 0-RTT Read keys (using %s)", tls.CipherSuiteName(suite.ID))
@@ -566,8 +590,8 @@ func (h *cryptoSetup) SetReadKey(encLevel qtls.EncryptionLevel, suite *qtls.Ciph
 	case qtls.EncryptionHandshake:
 		h.readEncLevel = protocol.EncryptionHandshake
 		h.handshakeOpener = newHandshakeOpener(
-			createAEAD(suite, trafficSecret),
-			newHeaderProtector(suite, trafficSecret, true),
+			createAEAD(suite, trafficSecret, h.version),
+			newHeaderProtector(suite, trafficSecret, true, h.version),
 			h.dropInitialKeys,
 			h.perspective,
 		)
@@ -594,8 +618,8 @@ func (h *cryptoSetup) SetWriteKey(encLevel qtls.EncryptionLevel, suite *qtls.Cip
 			panic("Received 0-RTT write key for the server")
 		}
 		h.zeroRTTSealer = newLongHeaderSealer(
-			createAEAD(suite, trafficSecret),
-			newHeaderProtector(suite, trafficSecret, true),
+			createAEAD(suite, trafficSecret, h.version),
+			newHeaderProtector(suite, trafficSecret, true, h.version),
 		)
 		h.mutex.Unlock()
 		h.logger.Debugf("Installed 0-RTT Write keys (using %s)", tls.CipherSuiteName(suite.ID))
@@ -606,8 +630,8 @@ func (h *cryptoSetup) SetWriteKey(encLevel qtls.EncryptionLevel, suite *qtls.Cip
 	case qtls.EncryptionHandshake:
 		h.writeEncLevel = protocol.EncryptionHandshake
 		h.handshakeSealer = newHandshakeSealer(
-			createAEAD(suite, trafficSecret),
-			newHeaderProtector(suite, trafficSecret, true),
+			createAEAD(suite, trafficSecret, h.version),
+			newHeaderProtector(suite, trafficSecret, true, h.version),
 			h.dropInitialKeys,
 			h.perspective,
 		)
@@ -679,6 +703,10 @@ func (h *cryptoSetup) dropInitialKeys() {
 	h.logger.Debugf("Dropping Initial keys.")
 }
 
+func (h *cryptoSetup) InitiateKeyUpdate() {
+	h.aead.InitiateKeyUpdate()
+}
+
 func (h *cryptoSetup) SetHandshakeConfirmed() {
 	h.aead.SetHandshakeConfirmed()
 	// drop Handshake keys
@@ -516,6 +516,89 @@ var _ = Describe("Crypto Setup TLS", func() {
 			Expect(sTransportParametersRcvd.MaxIdleTimeout).To(Equal(sTransportParameters.MaxIdleTimeout))
 		})
 
+		It("switches to a compatible version", func() {
+			connID := protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad}
+			var sTransportParametersRcvd *wire.TransportParameters
+			cChunkChan, cInitialStream, cHandshakeStream := initStreams()
+			cRunner := NewMockHandshakeRunner(mockCtrl)
+			cRunner.EXPECT().OnReceivedParams(gomock.Any()).Do(func(tp *wire.TransportParameters) { sTransportParametersRcvd = tp })
+			cRunner.EXPECT().OnHandshakeComplete()
+			client, _ := NewCryptoSetupClient(
+				cInitialStream,
+				cHandshakeStream,
+				connID,
+				nil,
+				nil,
+				&wire.TransportParameters{},
+				cRunner,
+				clientConf,
+				false,
+				&utils.RTTStats{},
+				nil,
+				utils.DefaultLogger.WithPrefix("client"),
+				protocol.Version1,
+			)
+
+			sChunkChan, sInitialStream, sHandshakeStream := initStreams()
+			var token protocol.StatelessResetToken
+			var server CryptoSetup
+			sRunner := NewMockHandshakeRunner(mockCtrl)
+			sRunner.EXPECT().OnReceivedParams(gomock.Any()).Do(func(*wire.TransportParameters) {
+				server.ChangeVersion(protocol.Version2)
+				// The client switches when it receives the server's first packet.
+				client.ChangeVersion(protocol.Version2)
+			})
+			sRunner.EXPECT().OnHandshakeComplete()
+			server = NewCryptoSetupServer(
+				sInitialStream,
+				sHandshakeStream,
+				connID,
+				nil,
+				nil,
+				&wire.TransportParameters{
+					StatelessResetToken: &token,
+					VersionInformation: &wire.VersionInformation{
+						ChosenVersion:     protocol.Version1,
+						AvailableVersions: []protocol.VersionNumber{protocol.Version2, protocol.Version1},
+					},
+				},
+				sRunner,
+				serverConf,
+				false,
+				&utils.RTTStats{},
+				nil,
+				utils.DefaultLogger.WithPrefix("server"),
+				protocol.Version1,
+			)
+
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				handshake(client, cChunkChan, server, sChunkChan)
+				close(done)
+			}()
+			Eventually(done).Should(BeClosed())
+			// the server sent the new version in its transport parameters
+			Expect(sTransportParametersRcvd.VersionInformation.ChosenVersion).To(Equal(protocol.Version2))
+			// the Initial keys were derived using the new version
+			sealer, err := server.GetInitialSealer()
+			Expect(err).ToNot(HaveOccurred())
+			_, opener := NewInitialAEAD(connID, protocol.PerspectiveClient, protocol.Version2)
+			sealed := sealer.Seal(nil, []byte("foobar"), 1, []byte("aad"))
+			opened, err := opener.Open(nil, sealed, 1, []byte("aad"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(opened).To(Equal([]byte("foobar")))
+			// both endpoints derived the 1-RTT keys using the new version
+			cSealer, err := client.Get1RTTSealer()
+			Expect(err).ToNot(HaveOccurred())
+			sOpener, err := server.Get1RTTOpener()
+			Expect(err).ToNot(HaveOccurred())
+			sealed = cSealer.Seal(nil, []byte("foobar"), 1, []byte("aad"))
+			opened, err = sOpener.Open(nil, sealed, time.Now(), 1, protocol.KeyPhaseZero, []byte("aad"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(opened).To(Equal([]byte("foobar")))
+		})
+
 		Context("with session tickets", func() {
 			It("errors when the NewSessionTicket is sent at the wrong encryption level", func() {
 				cChunkChan, cInitialStream, cHandshakeStream := initStreams()
@@ -770,6 +853,48 @@ var _ = Describe("Crypto Setup TLS", func() {
 				Expect(client.ConnectionState().Used0RTT).To(BeTrue())
 			})
 
+			It("uses 0-RTT with a session restored from a serialized session cache", func() {
+				cache := newMapSessionCache()
+				clientConf.ClientSessionCache = NewClientSessionCache(cache)
+				const clientRTT = 30 * time.Millisecond // RTT as measured by the client. Should be restored.
+				const initialMaxData protocol.ByteCount = 1337
+				_, _, clientErr, _, serverErr := handshakeWithTLSConf(
+					clientConf, serverConf,
+					newRTTStatsWithRTT(clientRTT), &utils.RTTStats{},
+					&wire.TransportParameters{}, &wire.TransportParameters{InitialMaxData: initialMaxData},
+					true,
+				)
+				Expect(clientErr).ToNot(HaveOccurred())
+				Expect(serverErr).ToNot(HaveOccurred())
+				Eventually(cache.Len).Should(Equal(1))
+
+				// restore the sessions into a new cache, as if the client was restarted
+				restored := newMapSessionCache()
+				cache.mutex.Lock()
+				for k, v := range cache.sessions {
+					restored.sessions[k] = v
+				}
+				cache.mutex.Unlock()
+				clientConf.ClientSessionCache = NewClientSessionCache(restored)
+				clientRTTStats := &utils.RTTStats{}
+				clientHelloWrittenChan, client, clientErr, server, serverErr := handshakeWithTLSConf(
+					clientConf, serverConf,
+					clientRTTStats, &utils.RTTStats{},
+					&wire.TransportParameters{}, &wire.TransportParameters{InitialMaxData: initialMaxData},
+					true,
+				)
+				Expect(clientErr).ToNot(HaveOccurred())
+				Expect(serverErr).ToNot(HaveOccurred())
+				Expect(clientRTTStats.SmoothedRTT()).To(Equal(clientRTT))
+
+				var tp *wire.TransportParameters
+				Expect(clientHelloWrittenChan).To(Receive(&tp))
+				Expect(tp.InitialMaxData).To(Equal(initialMaxData))
+
+				Expect(client.ConnectionState().DidResume).To(BeTrue())
+				Expect(server.ConnectionState().Used0RTT).To(BeTrue())
+				Expect(client.ConnectionState().Used0RTT).To(BeTrue())
+			})
 			It("rejects 0-RTT, when the transport parameters changed", func() {
 				csc := mocktls.NewMockClientSessionCache(mockCtrl)
 				var state *tls.ClientSessionState
@@ -29,7 +29,7 @@ var _ = Describe("Initial AEAD using AES-GCM", func() {
 		It("computes the client key and IV", func() {
 			clientSecret, _ := computeSecrets(connID, version)
 			Expect(clientSecret).To(Equal(splitHexString("0088119288f1d866733ceeed15ff9d50 902cf82952eee27e9d4d4918ea371d87")))
-			key, iv := computeInitialKeyAndIV(clientSecret)
+			key, iv := computeInitialKeyAndIV(clientSecret, version)
 			Expect(key).To(Equal(splitHexString("175257a31eb09dea9366d8bb79ad80ba")))
 			Expect(iv).To(Equal(splitHexString("6b26114b9cba2b63a9e8dd4f")))
 		})
@@ -37,7 +37,7 @@ var _ = Describe("Initial AEAD using AES-GCM", func() {
 		It("computes the server key and IV", func() {
 			_, serverSecret := computeSecrets(connID, version)
 			Expect(serverSecret).To(Equal(splitHexString("006f881359244dd9ad1acf85f595bad6 7c13f9f5586f5e64e1acae1d9ea8f616")))
-			key, iv := computeInitialKeyAndIV(serverSecret)
+			key, iv := computeInitialKeyAndIV(serverSecret, version)
 			Expect(key).To(Equal(splitHexString("149d0b1662ab871fbe63c49b5e655a5d")))
 			Expect(iv).To(Equal(splitHexString("bab2b12a4c76016ace47856d")))
 		})
@@ -83,7 +83,7 @@ var _ = Describe("Initial AEAD using AES-GCM", func() {
 		It("computes the client key and IV", func() {
 			clientSecret, _ := computeSecrets(connID, version)
 			Expect(clientSecret).To(Equal(splitHexString("c00cf151ca5be075ed0ebfb5c80323c4 2d6b7db67881289af4008f1f6c357aea")))
-			key, iv := computeInitialKeyAndIV(clientSecret)
+			key, iv := computeInitialKeyAndIV(clientSecret, version)
 			Expect(key).To(Equal(splitHexString("1f369613dd76d5467730efcbe3b1a22d")))
 			Expect(iv).To(Equal(splitHexString("fa044b2f42a3fd3b46fb255c")))
 		})
@@ -91,7 +91,7 @@ var _ = Describe("Initial AEAD using AES-GCM", func() {
 		It("computes the server key and IV", func() {
 			_, serverSecret := computeSecrets(connID, version)
 			Expect(serverSecret).To(Equal(splitHexString("3c199828fd139efd216c155ad844cc81 fb82fa8d7446fa7d78be803acdda951b")))
-			key, iv := computeInitialKeyAndIV(serverSecret)
+			key, iv := computeInitialKeyAndIV(serverSecret, version)
 			Expect(key).To(Equal(splitHexString("cf3a5331653c364c88f0f379b6067e37")))
 			Expect(iv).To(Equal(splitHexString("0ac1493ca1905853b0bba03e")))
 		})
@@ -125,7 +125,46 @@ var _ = Describe("Initial AEAD using AES-GCM", func() {
 		})
 	})
 
-	for _, ver := range []protocol.VersionNumber{protocol.VersionDraft29, protocol.Version1} {
+	// values taken from the Appendix of RFC 9369
+	Context("using the test vector from the QUIC v2 RFC", func() {
+		const version = protocol.Version2
+		var connID protocol.ConnectionID
+
+		BeforeEach(func() {
+			connID = protocol.ConnectionID(splitHexString("0x8394c8f03e515708"))
+		})
+
+		It("computes the client key and IV", func() {
+			clientSecret, _ := computeSecrets(connID, version)
+			Expect(clientSecret).To(Equal(splitHexString("14ec9d6eb9fd7af83bf5a668bc17a7e2 83766aade7ecd0891f70f9ff7f4bf47b")))
+			key, iv := computeInitialKeyAndIV(clientSecret, version)
+			Expect(key).To(Equal(splitHexString("8b1a0bc121284290a29e0971b5cd045d")))
+			Expect(iv).To(Equal(splitHexString("91f73e2351d8fa91660e909f")))
+		})
+
+		It("computes the server key and IV", func() {
+			_, serverSecret := computeSecrets(connID, version)
+			Expect(serverSecret).To(Equal(splitHexString("0263db1782731bf4588e7e4d93b74639 07cb8cd8200b5da55a8bd488eafc37c1")))
+			key, iv := computeInitialKeyAndIV(serverSecret, version)
+			Expect(key).To(Equal(splitHexString("82db637861d55e1d011f19ea71d5d2a7")))
+			Expect(iv).To(Equal(splitHexString("dd13c276499c0249d3310652")))
+		})
+
+		It("encrypt the server's Initial", func() {
+			sealer, _ := NewInitialAEAD(connID, protocol.PerspectiveServer, version)
+			header := splitHexString("d16b3343cf0008f067a5502a4262b50040750001")
+			data := splitHexString("02000000000600405a020000560303ee fce7f7b37ba1d1632e96677825ddf739 88cfc79825df566dc5430b9a045a1200 130100002e00330024001d00209d3c94 0d89690b84d08a60993c144eca684d10 81287c834d5311bcf32bb9da1a002b00 020304")
+			sealed := sealer.Seal(nil, data, 1, header)
+			sample := sealed[2 : 2+16]
+			Expect(sample).To(Equal(splitHexString("6f05d8a4398c47089698baeea26b91eb")))
+			sealer.EncryptHeader(sample, &header[0], header[len(header)-2:])
+			Expect(header).To(Equal(splitHexString("dc6b3343cf0008f067a5502a4262b5004075d92f")))
+			packet := append(header, sealed...)
+			Expect(packet).To(Equal(splitHexString("dc6b3343cf0008f067a5502a4262b500 4075d92faaf16f05d8a4398c47089698 baeea26b91eb761d9b89237bbf872630 17915358230035f7fd3945d88965cf17 f9af6e16886c61bfc703106fbaf3cb4c fa52382dd16a393e42757507698075b2 c984c707f0a0812d8cd5a6881eaf21ce da98f4bd23f6fe1a3e2c43edd9ce7ca8 4bed8521e2e140")))
+		})
+	})
+
+	for _, ver := range []protocol.VersionNumber{protocol.VersionDraft29, protocol.Version1, protocol.Version2} {
 		v := ver
 
 		Context(fmt.Sprintf("using version %s", v), func() {
@@ -62,6 +62,11 @@ type tlsExtensionHandler interface {
 	GetExtensions(msgType uint8) []qtls.Extension
 	ReceivedExtensions(msgType uint8, exts []qtls.Extension)
 	TransportParameters() <-chan []byte
+	// HandledTransportParameters must be called after handling the transport parameters received on the TransportParameters channel.
+	HandledTransportParameters()
+	// SetTransportParameters replaces our transport parameters.
+	// It must be called before they are sent.
+	SetTransportParameters([]byte)
 }
 
 type handshakeRunner interface {
@@ -76,11 +81,13 @@ type CryptoSetup interface {
 	RunHandshake()
 	io.Closer
 	ChangeConnectionID(protocol.ConnectionID)
+	ChangeVersion(protocol.VersionNumber)
 	GetSessionTicket() ([]byte, error)
 
 	HandleMessage([]byte, protocol.EncryptionLevel) bool
 	SetLargest1RTTAcked(protocol.PacketNumber) error
 	SetHandshakeConfirmed()
+	InitiateKeyUpdate()
 	ConnectionState() ConnectionState
 
 	GetInitialOpener() (LongHeaderOpener, error)
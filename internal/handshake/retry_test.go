@@ -33,4 +33,10 @@ var _ = Describe("Retry Integrity Check", func() {
 		data := splitHexString("ff000000010008f067a5502a4262b574 6f6b656e04a265ba2eff4d829058fb3f 0f2496ba")
 		Expect(GetRetryIntegrityTag(data[:len(data)-16], connID, protocol.Version1)[:]).To(Equal(data[len(data)-16:]))
 	})
+
+	It("uses the test vector from the RFC, for version 2", func() {
+		connID := protocol.ConnectionID(splitHexString("0x8394c8f03e515708"))
+		data := splitHexString("cf6b3343cf0008f067a5502a4262b574 6f6b656ec8646ce8bfe33952d9555436 65dcc7b6")
+		Expect(GetRetryIntegrityTag(data[:len(data)-16], connID, protocol.Version2)[:]).To(Equal(data[len(data)-16:]))
+	})
 })
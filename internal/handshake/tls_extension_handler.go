@@ -11,8 +11,9 @@ const (
 )
 
 type extensionHandler struct {
-	ourParams  []byte
-	paramsChan chan []byte
+	ourParams     []byte
+	paramsChan    chan []byte
+	paramsHandled chan struct{}
 
 	extensionType uint16
 
@@ -24,12 +25,13 @@ var _ tlsExtensionHandler = &extensionHandler{}
 // newExtensionHandler creates a new extension handler
 func newExtensionHandler(params []byte, pers protocol.Perspective, v protocol.VersionNumber) tlsExtensionHandler {
 	et := uint16(quicTLSExtensionType)
-	if v != protocol.Version1 {
+	if v != protocol.Version1 && v != protocol.Version2 {
 		et = quicTLSExtensionTypeOldDrafts
 	}
 	return &extensionHandler{
 		ourParams:     params,
 		paramsChan:    make(chan []byte),
+		paramsHandled: make(chan struct{}),
 		perspective:   pers,
 		extensionType: et,
 	}
@@ -61,8 +63,20 @@ func (h *extensionHandler) ReceivedExtensions(msgType uint8, exts []qtls.Extensi
 	}
 
 	h.paramsChan <- data
+	// Only continue the handshake once the transport parameters were handled.
+	// This allows the server to switch to a compatible QUIC version (RFC 9368),
+	// before the Handshake keys are derived and before its transport parameters are sent.
+	<-h.paramsHandled
 }
 
 func (h *extensionHandler) TransportParameters() <-chan []byte {
 	return h.paramsChan
 }
+
+func (h *extensionHandler) HandledTransportParameters() {
+	h.paramsHandled <- struct{}{}
+}
+
+func (h *extensionHandler) SetTransportParameters(params []byte) {
+	h.ourParams = params
+}
@@ -35,7 +35,7 @@ var _ = Describe("TLS Extension Handler, for the server", func() {
 	})
 
 	Context("for the server", func() {
-		for _, ver := range []protocol.VersionNumber{protocol.VersionDraft29, protocol.Version1} {
+		for _, ver := range []protocol.VersionNumber{protocol.VersionDraft29, protocol.Version1, protocol.Version2} {
 			v := ver
 
 			Context(fmt.Sprintf("sending, for version %s", v), func() {
@@ -62,6 +62,14 @@ var _ = Describe("TLS Extension Handler, for the server", func() {
 					Expect(exts[0].Type).To(BeEquivalentTo(extensionType))
 					Expect(exts[0].Data).To(Equal([]byte("foobar")))
 				})
+
+				It("sends replaced TransportParameters", func() {
+					handlerServer.SetTransportParameters([]byte("lorem ipsum"))
+					exts := handlerServer.GetExtensions(uint8(typeEncryptedExtensions))
+					Expect(exts).To(HaveLen(1))
+					Expect(exts[0].Type).To(BeEquivalentTo(extensionType))
+					Expect(exts[0].Data).To(Equal([]byte("lorem ipsum")))
+				})
 			})
 		}
 
@@ -74,14 +82,32 @@ var _ = Describe("TLS Extension Handler, for the server", func() {
 			})
 
 			It("sends the extension on the channel", func() {
+				done := make(chan struct{})
 				go func() {
 					defer GinkgoRecover()
 					handlerServer.ReceivedExtensions(uint8(typeClientHello), chExts)
+					close(done)
 				}()
 
 				var data []byte
 				Eventually(handlerServer.TransportParameters()).Should(Receive(&data))
 				Expect(data).To(Equal([]byte("raboof")))
+				handlerServer.HandledTransportParameters()
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("waits until the transport parameters were handled", func() {
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					handlerServer.ReceivedExtensions(uint8(typeClientHello), chExts)
+					close(done)
+				}()
+
+				Eventually(handlerServer.TransportParameters()).Should(Receive())
+				Consistently(done).ShouldNot(BeClosed())
+				handlerServer.HandledTransportParameters()
+				Eventually(done).Should(BeClosed())
 			})
 
 			It("sends nil on the channel if the extension is missing", func() {
@@ -93,6 +119,7 @@ var _ = Describe("TLS Extension Handler, for the server", func() {
 				var data []byte
 				Eventually(handlerServer.TransportParameters()).Should(Receive(&data))
 				Expect(data).To(BeEmpty())
+				handlerServer.HandledTransportParameters()
 			})
 
 			It("ignores extensions with different code points", func() {
@@ -105,6 +132,7 @@ var _ = Describe("TLS Extension Handler, for the server", func() {
 				var data []byte
 				Eventually(handlerServer.TransportParameters()).Should(Receive())
 				Expect(data).To(BeEmpty())
+				handlerServer.HandledTransportParameters()
 			})
 
 			It("ignores extensions that are not sent with the ClientHello", func() {
@@ -122,7 +150,7 @@ var _ = Describe("TLS Extension Handler, for the server", func() {
 	})
 
 	Context("for the client", func() {
-		for _, ver := range []protocol.VersionNumber{protocol.VersionDraft29, protocol.Version1} {
+		for _, ver := range []protocol.VersionNumber{protocol.VersionDraft29, protocol.Version1, protocol.Version2} {
 			v := ver
 
 			Context(fmt.Sprintf("sending, for version %s", v), func() {
@@ -161,14 +189,32 @@ var _ = Describe("TLS Extension Handler, for the server", func() {
 			})
 
 			It("sends the extension on the channel", func() {
+				done := make(chan struct{})
 				go func() {
 					defer GinkgoRecover()
 					handlerClient.ReceivedExtensions(uint8(typeEncryptedExtensions), chExts)
+					close(done)
 				}()
 
 				var data []byte
 				Eventually(handlerClient.TransportParameters()).Should(Receive(&data))
 				Expect(data).To(Equal([]byte("foobar")))
+				handlerClient.HandledTransportParameters()
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("waits until the transport parameters were handled", func() {
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					handlerClient.ReceivedExtensions(uint8(typeEncryptedExtensions), chExts)
+					close(done)
+				}()
+
+				Eventually(handlerClient.TransportParameters()).Should(Receive())
+				Consistently(done).ShouldNot(BeClosed())
+				handlerClient.HandledTransportParameters()
+				Eventually(done).Should(BeClosed())
 			})
 
 			It("sends nil on the channel if the extension is missing", func() {
@@ -180,6 +226,7 @@ var _ = Describe("TLS Extension Handler, for the server", func() {
 				var data []byte
 				Eventually(handlerClient.TransportParameters()).Should(Receive(&data))
 				Expect(data).To(BeEmpty())
+				handlerClient.HandledTransportParameters()
 			})
 
 			It("ignores extensions with different code points", func() {
@@ -192,6 +239,7 @@ var _ = Describe("TLS Extension Handler, for the server", func() {
 				var data []byte
 				Eventually(handlerClient.TransportParameters()).Should(Receive())
 				Expect(data).To(BeEmpty())
+				handlerClient.HandledTransportParameters()
 			})
 
 			It("ignores extensions that are not sent with the EncryptedExtensions", func() {
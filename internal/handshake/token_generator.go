@@ -36,7 +36,7 @@ type token struct {
 
 // A TokenGenerator generates tokens
 type TokenGenerator struct {
-	tokenProtector tokenProtector
+	tokenProtector TokenProtector
 }
 
 // NewTokenGenerator initializes a new TookenGenerator
@@ -45,9 +45,12 @@ func NewTokenGenerator(rand io.Reader) (*TokenGenerator, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &TokenGenerator{
-		tokenProtector: tokenProtector,
-	}, nil
+	return NewTokenGeneratorWithProtector(tokenProtector), nil
+}
+
+// NewTokenGeneratorWithProtector initializes a new TokenGenerator that uses the given TokenProtector
+func NewTokenGeneratorWithProtector(tokenProtector TokenProtector) *TokenGenerator {
+	return &TokenGenerator{tokenProtector: tokenProtector}
 }
 
 // NewRetryToken generates a new token for a Retry for a given source address
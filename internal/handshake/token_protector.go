@@ -3,15 +3,17 @@ package handshake
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 
 	"golang.org/x/crypto/hkdf"
 )
 
-// TokenProtector is used to create and verify a token
-type tokenProtector interface {
+// A TokenProtector is used to create and verify a token
+type TokenProtector interface {
 	// NewToken creates a new token
 	NewToken([]byte) ([]byte, error)
 	// DecodeToken decodes a token
@@ -21,6 +23,8 @@ type tokenProtector interface {
 const (
 	tokenSecretSize = 32
 	tokenNonceSize  = 32
+	// minHMACTokenKeySize is the minimum size of the key of the hmacTokenProtector
+	minHMACTokenKeySize = 32
 )
 
 // tokenProtector is used to create and verify a token
@@ -30,7 +34,7 @@ type tokenProtectorImpl struct {
 }
 
 // newTokenProtector creates a source for source address tokens
-func newTokenProtector(rand io.Reader) (tokenProtector, error) {
+func newTokenProtector(rand io.Reader) (TokenProtector, error) {
 	secret := make([]byte, tokenSecretSize)
 	if _, err := rand.Read(secret); err != nil {
 		return nil, err
@@ -87,3 +91,44 @@ func (s *tokenProtectorImpl) createAEAD(nonce []byte) (cipher.AEAD, []byte, erro
 	}
 	return aead, aeadNonce, nil
 }
+
+// hmacTokenProtector authenticates tokens using HMAC-SHA256.
+// In contrast to the tokenProtectorImpl, the token data is not encrypted.
+type hmacTokenProtector struct {
+	key []byte
+}
+
+// NewHMACTokenProtector creates a TokenProtector that authenticates tokens using HMAC-SHA256.
+// Servers that use the same key accept each other's tokens.
+// The key must be at least 32 bytes long.
+func NewHMACTokenProtector(key []byte) (TokenProtector, error) {
+	if len(key) < minHMACTokenKeySize {
+		return nil, fmt.Errorf("HMAC token key too short: %d bytes (need at least %d)", len(key), minHMACTokenKeySize)
+	}
+	return &hmacTokenProtector{key: key}, nil
+}
+
+// NewToken appends the HMAC of the data to the data.
+func (p *hmacTokenProtector) NewToken(data []byte) ([]byte, error) {
+	token := make([]byte, len(data), len(data)+sha256.Size)
+	copy(token, data)
+	return append(token, p.mac(data)...), nil
+}
+
+// DecodeToken verifies the HMAC of a token.
+func (p *hmacTokenProtector) DecodeToken(token []byte) ([]byte, error) {
+	if len(token) < sha256.Size {
+		return nil, fmt.Errorf("token too short: %d", len(token))
+	}
+	data := token[:len(token)-sha256.Size]
+	if !hmac.Equal(token[len(token)-sha256.Size:], p.mac(data)) {
+		return nil, errors.New("invalid token MAC")
+	}
+	return data, nil
+}
+
+func (p *hmacTokenProtector) mac(data []byte) []byte {
+	h := hmac.New(sha256.New, p.key)
+	h.Write(data)
+	return h.Sum(nil)
+}
@@ -17,7 +17,7 @@ func (r *zeroReader) Read(b []byte) (int, error) {
 }
 
 var _ = Describe("Token Protector", func() {
-	var tp tokenProtector
+	var tp TokenProtector
 
 	BeforeEach(func() {
 		var err error
@@ -65,3 +65,58 @@ var _ = Describe("Token Protector", func() {
 		Expect(err).To(MatchError("token too short: 6"))
 	})
 })
+
+var _ = Describe("HMAC Token Protector", func() {
+	var tp TokenProtector
+
+	newHMACTokenProtector := func(key string) TokenProtector {
+		tp, err := NewHMACTokenProtector([]byte(key))
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		return tp
+	}
+
+	BeforeEach(func() {
+		tp = newHMACTokenProtector("a secret key that is long enough")
+	})
+
+	It("rejects short keys", func() {
+		_, err := NewHMACTokenProtector(make([]byte, 31))
+		Expect(err).To(MatchError("HMAC token key too short: 31 bytes (need at least 32)"))
+	})
+
+	It("encodes and decodes tokens", func() {
+		token, err := tp.NewToken([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		decoded, err := tp.DecodeToken(token)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded).To(Equal([]byte("foobar")))
+	})
+
+	It("accepts tokens created by a protector using the same key", func() {
+		token, err := newHMACTokenProtector("a secret key that is long enough").NewToken([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		decoded, err := tp.DecodeToken(token)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded).To(Equal([]byte("foobar")))
+	})
+
+	It("rejects tokens created by a protector using a different key", func() {
+		token, err := newHMACTokenProtector("another key that is long enough!").NewToken([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = tp.DecodeToken(token)
+		Expect(err).To(MatchError("invalid token MAC"))
+	})
+
+	It("rejects tampered tokens", func() {
+		token, err := tp.NewToken([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		token[0] ^= 0xff
+		_, err = tp.DecodeToken(token)
+		Expect(err).To(MatchError("invalid token MAC"))
+	})
+
+	It("errors when decoding too short tokens", func() {
+		_, err := tp.DecodeToken([]byte("foobar"))
+		Expect(err).To(MatchError("token too short: 6"))
+	})
+})
@@ -20,7 +20,7 @@ import (
 var _ = Describe("Updatable AEAD", func() {
 	It("ChaCha test vector from the draft", func() {
 		secret := splitHexString("9ac312a7f877468ebe69422748ad00a1 5443f18203a07d6060f688f30f21632b")
-		aead := newUpdatableAEAD(&utils.RTTStats{}, nil, nil)
+		aead := newUpdatableAEAD(&utils.RTTStats{}, nil, nil, protocol.Version1)
 		chacha := cipherSuites[2]
 		Expect(chacha.ID).To(Equal(tls.TLS_CHACHA20_POLY1305_SHA256))
 		aead.SetWriteKey(chacha, secret)
@@ -35,6 +35,18 @@ var _ = Describe("Updatable AEAD", func() {
 		Expect(packet).To(Equal(splitHexString("4cfe4189655e5cd55c41f69080575d7999c25a5bfb")))
 	})
 
+	It("uses different keys for QUIC v2", func() {
+		secret := make([]byte, 32)
+		rand.Read(secret)
+		v1 := newUpdatableAEAD(&utils.RTTStats{}, nil, utils.DefaultLogger, protocol.Version1)
+		v2 := newUpdatableAEAD(&utils.RTTStats{}, nil, utils.DefaultLogger, protocol.Version2)
+		v1.SetWriteKey(cipherSuites[0], secret)
+		v2.SetReadKey(cipherSuites[0], secret)
+		encrypted := v1.Seal(nil, []byte("foobar"), 0x1337, []byte("ad"))
+		_, err := v2.Open(nil, encrypted, time.Now(), 0x1337, protocol.KeyPhaseZero, []byte("ad"))
+		Expect(err).To(MatchError(ErrDecryptionFailed))
+	})
+
 	for i := range cipherSuites {
 		cs := cipherSuites[i]
 
@@ -53,8 +65,8 @@ var _ = Describe("Updatable AEAD", func() {
 				rand.Read(trafficSecret2)
 
 				rttStats = utils.NewRTTStats()
-				client = newUpdatableAEAD(rttStats, nil, utils.DefaultLogger)
-				server = newUpdatableAEAD(rttStats, serverTracer, utils.DefaultLogger)
+				client = newUpdatableAEAD(rttStats, nil, utils.DefaultLogger, protocol.Version1)
+				server = newUpdatableAEAD(rttStats, serverTracer, utils.DefaultLogger, protocol.Version1)
 				client.SetReadKey(cs, trafficSecret2)
 				client.SetWriteKey(cs, trafficSecret1)
 				server.SetReadKey(cs, trafficSecret1)
@@ -305,6 +317,39 @@ var _ = Describe("Updatable AEAD", func() {
 							Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseZero))
 						})
 
+						It("initiates a key update when requested", func() {
+							server.Seal(nil, msg, 0, ad)
+							Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseZero))
+							server.InitiateKeyUpdate()
+							serverTracer.EXPECT().UpdatedKey(protocol.KeyPhase(1), false)
+							Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
+							// subsequent packets are sent with the new key phase
+							encrypted := server.Seal(nil, msg, 1, ad)
+							Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
+							opened, err := client.Open(nil, encrypted, time.Now(), 1, protocol.KeyPhaseOne, ad)
+							Expect(err).ToNot(HaveOccurred())
+							Expect(opened).To(Equal(msg))
+						})
+
+						It("defers a requested key update until the current key phase was acknowledged", func() {
+							server.rollKeys()
+							client.rollKeys()
+							server.Seal(nil, msg, 0, ad)
+							server.InitiateKeyUpdate()
+							Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
+							// receive a packet at key phase 1, and an ACK for the packet sent at key phase 1
+							b := client.Seal(nil, msg, 1, ad)
+							_, err := server.Open(nil, b, time.Now(), 1, protocol.KeyPhaseOne, ad)
+							Expect(err).ToNot(HaveOccurred())
+							Expect(server.SetLargestAcked(0)).To(Succeed())
+							serverTracer.EXPECT().DroppedKey(protocol.KeyPhase(0))
+							serverTracer.EXPECT().UpdatedKey(protocol.KeyPhase(2), false)
+							Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseZero))
+							// the request was served, no further update is initiated
+							server.Seal(nil, msg, 2, ad)
+							Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseZero))
+						})
+
 						It("errors if the peer acknowledges a packet sent in the next key phase using the old key phase", func() {
 							// First make sure that we update our keys.
 							for i := 0; i < keyUpdateInterval; i++ {
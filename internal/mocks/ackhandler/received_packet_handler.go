@@ -90,6 +90,30 @@ func (mr *MockReceivedPacketHandlerMockRecorder) IsPotentiallyDuplicate(arg0, ar
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsPotentiallyDuplicate", reflect.TypeOf((*MockReceivedPacketHandler)(nil).IsPotentiallyDuplicate), arg0, arg1)
 }
 
+// ReceivedAckFrequency mocks base method.
+func (m *MockReceivedPacketHandler) ReceivedAckFrequency(arg0 *wire.AckFrequencyFrame) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ReceivedAckFrequency", arg0)
+}
+
+// ReceivedAckFrequency indicates an expected call of ReceivedAckFrequency.
+func (mr *MockReceivedPacketHandlerMockRecorder) ReceivedAckFrequency(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceivedAckFrequency", reflect.TypeOf((*MockReceivedPacketHandler)(nil).ReceivedAckFrequency), arg0)
+}
+
+// ReceivedImmediateAck mocks base method.
+func (m *MockReceivedPacketHandler) ReceivedImmediateAck() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ReceivedImmediateAck")
+}
+
+// ReceivedImmediateAck indicates an expected call of ReceivedImmediateAck.
+func (mr *MockReceivedPacketHandlerMockRecorder) ReceivedImmediateAck() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceivedImmediateAck", reflect.TypeOf((*MockReceivedPacketHandler)(nil).ReceivedImmediateAck))
+}
+
 // ReceivedPacket mocks base method.
 func (m *MockReceivedPacketHandler) ReceivedPacket(arg0 protocol.PacketNumber, arg1 protocol.ECN, arg2 protocol.EncryptionLevel, arg3 time.Time, arg4 bool) error {
 	m.ctrl.T.Helper()
@@ -10,6 +10,7 @@ import (
 
 	gomock "github.com/golang/mock/gomock"
 	ackhandler "github.com/lucas-clemente/quic-go/internal/ackhandler"
+	congestion "github.com/lucas-clemente/quic-go/internal/congestion"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 	wire "github.com/lucas-clemente/quic-go/internal/wire"
 )
@@ -37,6 +38,34 @@ func (m *MockSentPacketHandler) EXPECT() *MockSentPacketHandlerMockRecorder {
 	return m.recorder
 }
 
+// BytesInFlight mocks base method.
+func (m *MockSentPacketHandler) BytesInFlight() protocol.ByteCount {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BytesInFlight")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	return ret0
+}
+
+// BytesInFlight indicates an expected call of BytesInFlight.
+func (mr *MockSentPacketHandlerMockRecorder) BytesInFlight() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BytesInFlight", reflect.TypeOf((*MockSentPacketHandler)(nil).BytesInFlight))
+}
+
+// CongestionState mocks base method.
+func (m *MockSentPacketHandler) CongestionState() ackhandler.CongestionState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CongestionState")
+	ret0, _ := ret[0].(ackhandler.CongestionState)
+	return ret0
+}
+
+// CongestionState indicates an expected call of CongestionState.
+func (mr *MockSentPacketHandlerMockRecorder) CongestionState() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CongestionState", reflect.TypeOf((*MockSentPacketHandler)(nil).CongestionState))
+}
+
 // DropPackets mocks base method.
 func (m *MockSentPacketHandler) DropPackets(arg0 protocol.EncryptionLevel) {
 	m.ctrl.T.Helper()
@@ -49,6 +78,20 @@ func (mr *MockSentPacketHandlerMockRecorder) DropPackets(arg0 interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DropPackets", reflect.TypeOf((*MockSentPacketHandler)(nil).DropPackets), arg0)
 }
 
+// ECNMode mocks base method.
+func (m *MockSentPacketHandler) ECNMode() protocol.ECN {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ECNMode")
+	ret0, _ := ret[0].(protocol.ECN)
+	return ret0
+}
+
+// ECNMode indicates an expected call of ECNMode.
+func (mr *MockSentPacketHandlerMockRecorder) ECNMode() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ECNMode", reflect.TypeOf((*MockSentPacketHandler)(nil).ECNMode))
+}
+
 // GetLossDetectionTimeout mocks base method.
 func (m *MockSentPacketHandler) GetLossDetectionTimeout() time.Time {
 	m.ctrl.T.Helper()
@@ -77,6 +120,30 @@ func (mr *MockSentPacketHandlerMockRecorder) HasPacingBudget() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasPacingBudget", reflect.TypeOf((*MockSentPacketHandler)(nil).HasPacingBudget))
 }
 
+// OnAppLimited mocks base method.
+func (m *MockSentPacketHandler) OnAppLimited() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnAppLimited")
+}
+
+// OnAppLimited indicates an expected call of OnAppLimited.
+func (mr *MockSentPacketHandlerMockRecorder) OnAppLimited() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnAppLimited", reflect.TypeOf((*MockSentPacketHandler)(nil).OnAppLimited))
+}
+
+// OnConnectionMigration mocks base method.
+func (m *MockSentPacketHandler) OnConnectionMigration(arg0 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnConnectionMigration", arg0)
+}
+
+// OnConnectionMigration indicates an expected call of OnConnectionMigration.
+func (mr *MockSentPacketHandlerMockRecorder) OnConnectionMigration(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnConnectionMigration", reflect.TypeOf((*MockSentPacketHandler)(nil).OnConnectionMigration), arg0)
+}
+
 // OnLossDetectionTimeout mocks base method.
 func (m *MockSentPacketHandler) OnLossDetectionTimeout() error {
 	m.ctrl.T.Helper()
@@ -91,6 +158,20 @@ func (mr *MockSentPacketHandlerMockRecorder) OnLossDetectionTimeout() *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnLossDetectionTimeout", reflect.TypeOf((*MockSentPacketHandler)(nil).OnLossDetectionTimeout))
 }
 
+// PacketStats mocks base method.
+func (m *MockSentPacketHandler) PacketStats() ackhandler.PacketStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PacketStats")
+	ret0, _ := ret[0].(ackhandler.PacketStats)
+	return ret0
+}
+
+// PacketStats indicates an expected call of PacketStats.
+func (mr *MockSentPacketHandlerMockRecorder) PacketStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PacketStats", reflect.TypeOf((*MockSentPacketHandler)(nil).PacketStats))
+}
+
 // PeekPacketNumber mocks base method.
 func (m *MockSentPacketHandler) PeekPacketNumber(arg0 protocol.EncryptionLevel) (protocol.PacketNumber, protocol.PacketNumberLen) {
 	m.ctrl.T.Helper()
@@ -134,6 +215,20 @@ func (mr *MockSentPacketHandlerMockRecorder) QueueProbePacket(arg0 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueueProbePacket", reflect.TypeOf((*MockSentPacketHandler)(nil).QueueProbePacket), arg0)
 }
 
+// RTTStats mocks base method.
+func (m *MockSentPacketHandler) RTTStats() ackhandler.RTTStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RTTStats")
+	ret0, _ := ret[0].(ackhandler.RTTStats)
+	return ret0
+}
+
+// RTTStats indicates an expected call of RTTStats.
+func (mr *MockSentPacketHandlerMockRecorder) RTTStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RTTStats", reflect.TypeOf((*MockSentPacketHandler)(nil).RTTStats))
+}
+
 // ReceivedAck mocks base method.
 func (m *MockSentPacketHandler) ReceivedAck(arg0 *wire.AckFrame, arg1 protocol.EncryptionLevel, arg2 time.Time) (bool, error) {
 	m.ctrl.T.Helper()
@@ -201,6 +296,18 @@ func (mr *MockSentPacketHandlerMockRecorder) SentPacket(arg0 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SentPacket", reflect.TypeOf((*MockSentPacketHandler)(nil).SentPacket), arg0)
 }
 
+// SetCongestionControl mocks base method.
+func (m *MockSentPacketHandler) SetCongestionControl(arg0 congestion.CongestionOptions) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetCongestionControl", arg0)
+}
+
+// SetCongestionControl indicates an expected call of SetCongestionControl.
+func (mr *MockSentPacketHandlerMockRecorder) SetCongestionControl(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCongestionControl", reflect.TypeOf((*MockSentPacketHandler)(nil).SetCongestionControl), arg0)
+}
+
 // SetHandshakeConfirmed mocks base method.
 func (m *MockSentPacketHandler) SetHandshakeConfirmed() {
 	m.ctrl.T.Helper()
@@ -213,6 +320,18 @@ func (mr *MockSentPacketHandlerMockRecorder) SetHandshakeConfirmed() *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetHandshakeConfirmed", reflect.TypeOf((*MockSentPacketHandler)(nil).SetHandshakeConfirmed))
 }
 
+// SetMaxBytesInFlight mocks base method.
+func (m *MockSentPacketHandler) SetMaxBytesInFlight(arg0 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetMaxBytesInFlight", arg0)
+}
+
+// SetMaxBytesInFlight indicates an expected call of SetMaxBytesInFlight.
+func (mr *MockSentPacketHandlerMockRecorder) SetMaxBytesInFlight(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxBytesInFlight", reflect.TypeOf((*MockSentPacketHandler)(nil).SetMaxBytesInFlight), arg0)
+}
+
 // SetMaxDatagramSize mocks base method.
 func (m *MockSentPacketHandler) SetMaxDatagramSize(arg0 protocol.ByteCount) {
 	m.ctrl.T.Helper()
@@ -225,6 +344,18 @@ func (mr *MockSentPacketHandlerMockRecorder) SetMaxDatagramSize(arg0 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxDatagramSize", reflect.TypeOf((*MockSentPacketHandler)(nil).SetMaxDatagramSize), arg0)
 }
 
+// SetSendRateLimit mocks base method.
+func (m *MockSentPacketHandler) SetSendRateLimit(arg0 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetSendRateLimit", arg0)
+}
+
+// SetSendRateLimit indicates an expected call of SetSendRateLimit.
+func (mr *MockSentPacketHandlerMockRecorder) SetSendRateLimit(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSendRateLimit", reflect.TypeOf((*MockSentPacketHandler)(nil).SetSendRateLimit), arg0)
+}
+
 // TimeUntilSend mocks base method.
 func (m *MockSentPacketHandler) TimeUntilSend() time.Time {
 	m.ctrl.T.Helper()
@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/lucas-clemente/quic-go/internal/congestion (interfaces: SendAlgorithmWithDebugInfos)
+// Source: github.com/lucas-clemente/quic-go/internal/congestion (interfaces: ExtendedSendAlgorithm)
 
 // Package mocks is a generated GoMock package.
 package mocks
@@ -9,34 +9,49 @@ import (
 	time "time"
 
 	gomock "github.com/golang/mock/gomock"
+	congestion "github.com/lucas-clemente/quic-go/internal/congestion"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
-// MockSendAlgorithmWithDebugInfos is a mock of SendAlgorithmWithDebugInfos interface.
-type MockSendAlgorithmWithDebugInfos struct {
+// MockExtendedSendAlgorithm is a mock of ExtendedSendAlgorithm interface.
+type MockExtendedSendAlgorithm struct {
 	ctrl     *gomock.Controller
-	recorder *MockSendAlgorithmWithDebugInfosMockRecorder
+	recorder *MockExtendedSendAlgorithmMockRecorder
 }
 
-// MockSendAlgorithmWithDebugInfosMockRecorder is the mock recorder for MockSendAlgorithmWithDebugInfos.
-type MockSendAlgorithmWithDebugInfosMockRecorder struct {
-	mock *MockSendAlgorithmWithDebugInfos
+// MockExtendedSendAlgorithmMockRecorder is the mock recorder for MockExtendedSendAlgorithm.
+type MockExtendedSendAlgorithmMockRecorder struct {
+	mock *MockExtendedSendAlgorithm
 }
 
-// NewMockSendAlgorithmWithDebugInfos creates a new mock instance.
-func NewMockSendAlgorithmWithDebugInfos(ctrl *gomock.Controller) *MockSendAlgorithmWithDebugInfos {
-	mock := &MockSendAlgorithmWithDebugInfos{ctrl: ctrl}
-	mock.recorder = &MockSendAlgorithmWithDebugInfosMockRecorder{mock}
+// NewMockExtendedSendAlgorithm creates a new mock instance.
+func NewMockExtendedSendAlgorithm(ctrl *gomock.Controller) *MockExtendedSendAlgorithm {
+	mock := &MockExtendedSendAlgorithm{ctrl: ctrl}
+	mock.recorder = &MockExtendedSendAlgorithmMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockSendAlgorithmWithDebugInfos) EXPECT() *MockSendAlgorithmWithDebugInfosMockRecorder {
+func (m *MockExtendedSendAlgorithm) EXPECT() *MockExtendedSendAlgorithmMockRecorder {
 	return m.recorder
 }
 
+// BandwidthEstimate mocks base method.
+func (m *MockExtendedSendAlgorithm) BandwidthEstimate() congestion.Bandwidth {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BandwidthEstimate")
+	ret0, _ := ret[0].(congestion.Bandwidth)
+	return ret0
+}
+
+// BandwidthEstimate indicates an expected call of BandwidthEstimate.
+func (mr *MockExtendedSendAlgorithmMockRecorder) BandwidthEstimate() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BandwidthEstimate", reflect.TypeOf((*MockExtendedSendAlgorithm)(nil).BandwidthEstimate))
+}
+
 // CanSend mocks base method.
-func (m *MockSendAlgorithmWithDebugInfos) CanSend(arg0 protocol.ByteCount) bool {
+func (m *MockExtendedSendAlgorithm) CanSend(arg0 protocol.ByteCount) bool {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CanSend", arg0)
 	ret0, _ := ret[0].(bool)
@@ -44,13 +59,28 @@ func (m *MockSendAlgorithmWithDebugInfos) CanSend(arg0 protocol.ByteCount) bool
 }
 
 // CanSend indicates an expected call of CanSend.
-func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) CanSend(arg0 interface{}) *gomock.Call {
+func (mr *MockExtendedSendAlgorithmMockRecorder) CanSend(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CanSend", reflect.TypeOf((*MockExtendedSendAlgorithm)(nil).CanSend), arg0)
+}
+
+// ControlType mocks base method.
+func (m *MockExtendedSendAlgorithm) ControlType() (congestion.CongestionControlType, congestion.HystartControlType) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ControlType")
+	ret0, _ := ret[0].(congestion.CongestionControlType)
+	ret1, _ := ret[1].(congestion.HystartControlType)
+	return ret0, ret1
+}
+
+// ControlType indicates an expected call of ControlType.
+func (mr *MockExtendedSendAlgorithmMockRecorder) ControlType() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CanSend", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).CanSend), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ControlType", reflect.TypeOf((*MockExtendedSendAlgorithm)(nil).ControlType))
 }
 
 // GetCongestionWindow mocks base method.
-func (m *MockSendAlgorithmWithDebugInfos) GetCongestionWindow() protocol.ByteCount {
+func (m *MockExtendedSendAlgorithm) GetCongestionWindow() protocol.ByteCount {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetCongestionWindow")
 	ret0, _ := ret[0].(protocol.ByteCount)
@@ -58,13 +88,13 @@ func (m *MockSendAlgorithmWithDebugInfos) GetCongestionWindow() protocol.ByteCou
 }
 
 // GetCongestionWindow indicates an expected call of GetCongestionWindow.
-func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) GetCongestionWindow() *gomock.Call {
+func (mr *MockExtendedSendAlgorithmMockRecorder) GetCongestionWindow() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCongestionWindow", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).GetCongestionWindow))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCongestionWindow", reflect.TypeOf((*MockExtendedSendAlgorithm)(nil).GetCongestionWindow))
 }
 
 // HasPacingBudget mocks base method.
-func (m *MockSendAlgorithmWithDebugInfos) HasPacingBudget() bool {
+func (m *MockExtendedSendAlgorithm) HasPacingBudget() bool {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "HasPacingBudget")
 	ret0, _ := ret[0].(bool)
@@ -72,13 +102,13 @@ func (m *MockSendAlgorithmWithDebugInfos) HasPacingBudget() bool {
 }
 
 // HasPacingBudget indicates an expected call of HasPacingBudget.
-func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) HasPacingBudget() *gomock.Call {
+func (mr *MockExtendedSendAlgorithmMockRecorder) HasPacingBudget() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasPacingBudget", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).HasPacingBudget))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasPacingBudget", reflect.TypeOf((*MockExtendedSendAlgorithm)(nil).HasPacingBudget))
 }
 
 // InRecovery mocks base method.
-func (m *MockSendAlgorithmWithDebugInfos) InRecovery() bool {
+func (m *MockExtendedSendAlgorithm) InRecovery() bool {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "InRecovery")
 	ret0, _ := ret[0].(bool)
@@ -86,13 +116,13 @@ func (m *MockSendAlgorithmWithDebugInfos) InRecovery() bool {
 }
 
 // InRecovery indicates an expected call of InRecovery.
-func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) InRecovery() *gomock.Call {
+func (mr *MockExtendedSendAlgorithmMockRecorder) InRecovery() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InRecovery", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).InRecovery))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InRecovery", reflect.TypeOf((*MockExtendedSendAlgorithm)(nil).InRecovery))
 }
 
 // InSlowStart mocks base method.
-func (m *MockSendAlgorithmWithDebugInfos) InSlowStart() bool {
+func (m *MockExtendedSendAlgorithm) InSlowStart() bool {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "InSlowStart")
 	ret0, _ := ret[0].(bool)
@@ -100,85 +130,121 @@ func (m *MockSendAlgorithmWithDebugInfos) InSlowStart() bool {
 }
 
 // InSlowStart indicates an expected call of InSlowStart.
-func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) InSlowStart() *gomock.Call {
+func (mr *MockExtendedSendAlgorithmMockRecorder) InSlowStart() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InSlowStart", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).InSlowStart))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InSlowStart", reflect.TypeOf((*MockExtendedSendAlgorithm)(nil).InSlowStart))
 }
 
 // OnRttUpdated mocks base method.
-func (m *MockSendAlgorithmWithDebugInfos) OnRttUpdated() {
+func (m *MockExtendedSendAlgorithm) OnRttUpdated() {
 	m.ctrl.T.Helper()
 	m.ctrl.Call(m, "OnRttUpdated")
 }
 
 // OnRttUpdated indicates an expected call of OnRttUpdated.
-func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) OnRttUpdated() *gomock.Call {
+func (mr *MockExtendedSendAlgorithmMockRecorder) OnRttUpdated() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnRttUpdated", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).OnRttUpdated))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnRttUpdated", reflect.TypeOf((*MockExtendedSendAlgorithm)(nil).OnRttUpdated))
+}
+
+// OnAppLimited mocks base method.
+func (m *MockExtendedSendAlgorithm) OnAppLimited(arg0 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnAppLimited", arg0)
+}
+
+// OnAppLimited indicates an expected call of OnAppLimited.
+func (mr *MockExtendedSendAlgorithmMockRecorder) OnAppLimited(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnAppLimited", reflect.TypeOf((*MockExtendedSendAlgorithm)(nil).OnAppLimited), arg0)
+}
+
+// OnCongestionEvent mocks base method.
+func (m *MockExtendedSendAlgorithm) OnCongestionEvent(arg0 protocol.PacketNumber, arg1 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnCongestionEvent", arg0, arg1)
+}
+
+// OnCongestionEvent indicates an expected call of OnCongestionEvent.
+func (mr *MockExtendedSendAlgorithmMockRecorder) OnCongestionEvent(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnCongestionEvent", reflect.TypeOf((*MockExtendedSendAlgorithm)(nil).OnCongestionEvent), arg0, arg1)
 }
 
 // OnPacketAcked mocks base method.
-func (m *MockSendAlgorithmWithDebugInfos) OnPacketAcked(arg0 protocol.PacketNumber, arg1, arg2 protocol.ByteCount, arg3 time.Time) {
+func (m *MockExtendedSendAlgorithm) OnPacketAcked(arg0 protocol.PacketNumber, arg1, arg2 protocol.ByteCount, arg3 time.Time) {
 	m.ctrl.T.Helper()
 	m.ctrl.Call(m, "OnPacketAcked", arg0, arg1, arg2, arg3)
 }
 
 // OnPacketAcked indicates an expected call of OnPacketAcked.
-func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) OnPacketAcked(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+func (mr *MockExtendedSendAlgorithmMockRecorder) OnPacketAcked(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnPacketAcked", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).OnPacketAcked), arg0, arg1, arg2, arg3)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnPacketAcked", reflect.TypeOf((*MockExtendedSendAlgorithm)(nil).OnPacketAcked), arg0, arg1, arg2, arg3)
 }
 
 // OnPacketLost mocks base method.
-func (m *MockSendAlgorithmWithDebugInfos) OnPacketLost(arg0 protocol.PacketNumber, arg1, arg2 protocol.ByteCount) {
+func (m *MockExtendedSendAlgorithm) OnPacketLost(arg0 protocol.PacketNumber, arg1, arg2 protocol.ByteCount) {
 	m.ctrl.T.Helper()
 	m.ctrl.Call(m, "OnPacketLost", arg0, arg1, arg2)
 }
 
 // OnPacketLost indicates an expected call of OnPacketLost.
-func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) OnPacketLost(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockExtendedSendAlgorithmMockRecorder) OnPacketLost(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnPacketLost", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).OnPacketLost), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnPacketLost", reflect.TypeOf((*MockExtendedSendAlgorithm)(nil).OnPacketLost), arg0, arg1, arg2)
 }
 
 // OnPacketSent mocks base method.
-func (m *MockSendAlgorithmWithDebugInfos) OnPacketSent(arg0 time.Time, arg1 protocol.ByteCount, arg2 protocol.PacketNumber, arg3 protocol.ByteCount, arg4 bool) {
+func (m *MockExtendedSendAlgorithm) OnPacketSent(arg0 time.Time, arg1 protocol.ByteCount, arg2 protocol.PacketNumber, arg3 protocol.ByteCount, arg4 bool) {
 	m.ctrl.T.Helper()
 	m.ctrl.Call(m, "OnPacketSent", arg0, arg1, arg2, arg3, arg4)
 }
 
 // OnPacketSent indicates an expected call of OnPacketSent.
-func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) OnPacketSent(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+func (mr *MockExtendedSendAlgorithmMockRecorder) OnPacketSent(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnPacketSent", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).OnPacketSent), arg0, arg1, arg2, arg3, arg4)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnPacketSent", reflect.TypeOf((*MockExtendedSendAlgorithm)(nil).OnPacketSent), arg0, arg1, arg2, arg3, arg4)
 }
 
 // OnRetransmissionTimeout mocks base method.
-func (m *MockSendAlgorithmWithDebugInfos) OnRetransmissionTimeout(arg0 bool) {
+func (m *MockExtendedSendAlgorithm) OnRetransmissionTimeout(arg0 bool) {
 	m.ctrl.T.Helper()
 	m.ctrl.Call(m, "OnRetransmissionTimeout", arg0)
 }
 
 // OnRetransmissionTimeout indicates an expected call of OnRetransmissionTimeout.
-func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) OnRetransmissionTimeout(arg0 interface{}) *gomock.Call {
+func (mr *MockExtendedSendAlgorithmMockRecorder) OnRetransmissionTimeout(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnRetransmissionTimeout", reflect.TypeOf((*MockExtendedSendAlgorithm)(nil).OnRetransmissionTimeout), arg0)
+}
+
+// OnSpuriousLoss mocks base method.
+func (m *MockExtendedSendAlgorithm) OnSpuriousLoss(arg0 protocol.PacketNumber) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnSpuriousLoss", arg0)
+}
+
+// OnSpuriousLoss indicates an expected call of OnSpuriousLoss.
+func (mr *MockExtendedSendAlgorithmMockRecorder) OnSpuriousLoss(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnRetransmissionTimeout", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).OnRetransmissionTimeout), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnSpuriousLoss", reflect.TypeOf((*MockExtendedSendAlgorithm)(nil).OnSpuriousLoss), arg0)
 }
 
 // SetMaxDatagramSize mocks base method.
-func (m *MockSendAlgorithmWithDebugInfos) SetMaxDatagramSize(arg0 protocol.ByteCount) {
+func (m *MockExtendedSendAlgorithm) SetMaxDatagramSize(arg0 protocol.ByteCount) {
 	m.ctrl.T.Helper()
 	m.ctrl.Call(m, "SetMaxDatagramSize", arg0)
 }
 
 // SetMaxDatagramSize indicates an expected call of SetMaxDatagramSize.
-func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) SetMaxDatagramSize(arg0 interface{}) *gomock.Call {
+func (mr *MockExtendedSendAlgorithmMockRecorder) SetMaxDatagramSize(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxDatagramSize", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).SetMaxDatagramSize), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxDatagramSize", reflect.TypeOf((*MockExtendedSendAlgorithm)(nil).SetMaxDatagramSize), arg0)
 }
 
 // TimeUntilSend mocks base method.
-func (m *MockSendAlgorithmWithDebugInfos) TimeUntilSend(arg0 protocol.ByteCount) time.Time {
+func (m *MockExtendedSendAlgorithm) TimeUntilSend(arg0 protocol.ByteCount) time.Time {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "TimeUntilSend", arg0)
 	ret0, _ := ret[0].(time.Time)
@@ -186,7 +252,7 @@ func (m *MockSendAlgorithmWithDebugInfos) TimeUntilSend(arg0 protocol.ByteCount)
 }
 
 // TimeUntilSend indicates an expected call of TimeUntilSend.
-func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) TimeUntilSend(arg0 interface{}) *gomock.Call {
+func (mr *MockExtendedSendAlgorithmMockRecorder) TimeUntilSend(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TimeUntilSend", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).TimeUntilSend), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TimeUntilSend", reflect.TypeOf((*MockExtendedSendAlgorithm)(nil).TimeUntilSend), arg0)
 }
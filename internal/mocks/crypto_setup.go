@@ -48,6 +48,18 @@ func (mr *MockCryptoSetupMockRecorder) ChangeConnectionID(arg0 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeConnectionID", reflect.TypeOf((*MockCryptoSetup)(nil).ChangeConnectionID), arg0)
 }
 
+// ChangeVersion mocks base method.
+func (m *MockCryptoSetup) ChangeVersion(arg0 protocol.VersionNumber) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ChangeVersion", arg0)
+}
+
+// ChangeVersion indicates an expected call of ChangeVersion.
+func (mr *MockCryptoSetupMockRecorder) ChangeVersion(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeVersion", reflect.TypeOf((*MockCryptoSetup)(nil).ChangeVersion), arg0)
+}
+
 // Close mocks base method.
 func (m *MockCryptoSetup) Close() error {
 	m.ctrl.T.Helper()
@@ -225,6 +237,18 @@ func (mr *MockCryptoSetupMockRecorder) HandleMessage(arg0, arg1 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleMessage", reflect.TypeOf((*MockCryptoSetup)(nil).HandleMessage), arg0, arg1)
 }
 
+// InitiateKeyUpdate mocks base method.
+func (m *MockCryptoSetup) InitiateKeyUpdate() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "InitiateKeyUpdate")
+}
+
+// InitiateKeyUpdate indicates an expected call of InitiateKeyUpdate.
+func (mr *MockCryptoSetupMockRecorder) InitiateKeyUpdate() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitiateKeyUpdate", reflect.TypeOf((*MockCryptoSetup)(nil).InitiateKeyUpdate))
+}
+
 // RunHandshake mocks base method.
 func (m *MockCryptoSetup) RunHandshake() {
 	m.ctrl.T.Helper()
@@ -11,6 +11,8 @@ import (
 
 	gomock "github.com/golang/mock/gomock"
 	quic "github.com/lucas-clemente/quic-go"
+	congestion "github.com/lucas-clemente/quic-go/congestion"
+	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 	qerr "github.com/lucas-clemente/quic-go/internal/qerr"
 )
 
@@ -67,6 +69,20 @@ func (mr *MockEarlySessionMockRecorder) AcceptUniStream(arg0 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptUniStream", reflect.TypeOf((*MockEarlySession)(nil).AcceptUniStream), arg0)
 }
 
+// BytesInFlight mocks base method.
+func (m *MockEarlySession) BytesInFlight() protocol.ByteCount {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BytesInFlight")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	return ret0
+}
+
+// BytesInFlight indicates an expected call of BytesInFlight.
+func (mr *MockEarlySessionMockRecorder) BytesInFlight() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BytesInFlight", reflect.TypeOf((*MockEarlySession)(nil).BytesInFlight))
+}
+
 // CloseWithError mocks base method.
 func (m *MockEarlySession) CloseWithError(arg0 qerr.ApplicationErrorCode, arg1 string) error {
 	m.ctrl.T.Helper()
@@ -81,6 +97,20 @@ func (mr *MockEarlySessionMockRecorder) CloseWithError(arg0, arg1 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseWithError", reflect.TypeOf((*MockEarlySession)(nil).CloseWithError), arg0, arg1)
 }
 
+// CongestionState mocks base method.
+func (m *MockEarlySession) CongestionState() quic.CongestionState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CongestionState")
+	ret0, _ := ret[0].(quic.CongestionState)
+	return ret0
+}
+
+// CongestionState indicates an expected call of CongestionState.
+func (mr *MockEarlySessionMockRecorder) CongestionState() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CongestionState", reflect.TypeOf((*MockEarlySession)(nil).CongestionState))
+}
+
 // ConnectionState mocks base method.
 func (m *MockEarlySession) ConnectionState() quic.ConnectionState {
 	m.ctrl.T.Helper()
@@ -95,6 +125,35 @@ func (mr *MockEarlySessionMockRecorder) ConnectionState() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectionState", reflect.TypeOf((*MockEarlySession)(nil).ConnectionState))
 }
 
+// ConnectionStateErr mocks base method.
+func (m *MockEarlySession) ConnectionStateErr() (quic.ConnectionState, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConnectionStateErr")
+	ret0, _ := ret[0].(quic.ConnectionState)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConnectionStateErr indicates an expected call of ConnectionStateErr.
+func (mr *MockEarlySessionMockRecorder) ConnectionStateErr() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectionStateErr", reflect.TypeOf((*MockEarlySession)(nil).ConnectionStateErr))
+}
+
+// ConnectionStats mocks base method.
+func (m *MockEarlySession) ConnectionStats() quic.ConnectionStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConnectionStats")
+	ret0, _ := ret[0].(quic.ConnectionStats)
+	return ret0
+}
+
+// ConnectionStats indicates an expected call of ConnectionStats.
+func (mr *MockEarlySessionMockRecorder) ConnectionStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectionStats", reflect.TypeOf((*MockEarlySession)(nil).ConnectionStats))
+}
+
 // Context mocks base method.
 func (m *MockEarlySession) Context() context.Context {
 	m.ctrl.T.Helper()
@@ -123,6 +182,34 @@ func (mr *MockEarlySessionMockRecorder) HandshakeComplete() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeComplete", reflect.TypeOf((*MockEarlySession)(nil).HandshakeComplete))
 }
 
+// HandshakeConfirmed mocks base method.
+func (m *MockEarlySession) HandshakeConfirmed() context.Context {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandshakeConfirmed")
+	ret0, _ := ret[0].(context.Context)
+	return ret0
+}
+
+// HandshakeConfirmed indicates an expected call of HandshakeConfirmed.
+func (mr *MockEarlySessionMockRecorder) HandshakeConfirmed() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeConfirmed", reflect.TypeOf((*MockEarlySession)(nil).HandshakeConfirmed))
+}
+
+// InitiateKeyUpdate mocks base method.
+func (m *MockEarlySession) InitiateKeyUpdate() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InitiateKeyUpdate")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InitiateKeyUpdate indicates an expected call of InitiateKeyUpdate.
+func (mr *MockEarlySessionMockRecorder) InitiateKeyUpdate() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitiateKeyUpdate", reflect.TypeOf((*MockEarlySession)(nil).InitiateKeyUpdate))
+}
+
 // LocalAddr mocks base method.
 func (m *MockEarlySession) LocalAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -137,6 +224,20 @@ func (mr *MockEarlySessionMockRecorder) LocalAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LocalAddr", reflect.TypeOf((*MockEarlySession)(nil).LocalAddr))
 }
 
+// MigrateTo mocks base method.
+func (m *MockEarlySession) MigrateTo(arg0 net.PacketConn) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MigrateTo", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MigrateTo indicates an expected call of MigrateTo.
+func (mr *MockEarlySessionMockRecorder) MigrateTo(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigrateTo", reflect.TypeOf((*MockEarlySession)(nil).MigrateTo), arg0)
+}
+
 // NextSession mocks base method.
 func (m *MockEarlySession) NextSession() quic.Session {
 	m.ctrl.T.Helper()
@@ -211,19 +312,33 @@ func (mr *MockEarlySessionMockRecorder) OpenUniStreamSync(arg0 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenUniStreamSync", reflect.TypeOf((*MockEarlySession)(nil).OpenUniStreamSync), arg0)
 }
 
-// ReceiveMessage mocks base method.
-func (m *MockEarlySession) ReceiveMessage() ([]byte, error) {
+// RTTStats mocks base method.
+func (m *MockEarlySession) RTTStats() quic.RTTStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RTTStats")
+	ret0, _ := ret[0].(quic.RTTStats)
+	return ret0
+}
+
+// RTTStats indicates an expected call of RTTStats.
+func (mr *MockEarlySessionMockRecorder) RTTStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RTTStats", reflect.TypeOf((*MockEarlySession)(nil).RTTStats))
+}
+
+// ReceiveDatagram mocks base method.
+func (m *MockEarlySession) ReceiveDatagram(arg0 context.Context) ([]byte, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ReceiveMessage")
+	ret := m.ctrl.Call(m, "ReceiveDatagram", arg0)
 	ret0, _ := ret[0].([]byte)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ReceiveMessage indicates an expected call of ReceiveMessage.
-func (mr *MockEarlySessionMockRecorder) ReceiveMessage() *gomock.Call {
+// ReceiveDatagram indicates an expected call of ReceiveDatagram.
+func (mr *MockEarlySessionMockRecorder) ReceiveDatagram(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceiveMessage", reflect.TypeOf((*MockEarlySession)(nil).ReceiveMessage))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceiveDatagram", reflect.TypeOf((*MockEarlySession)(nil).ReceiveDatagram), arg0)
 }
 
 // RemoteAddr mocks base method.
@@ -240,16 +355,82 @@ func (mr *MockEarlySessionMockRecorder) RemoteAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteAddr", reflect.TypeOf((*MockEarlySession)(nil).RemoteAddr))
 }
 
-// SendMessage mocks base method.
-func (m *MockEarlySession) SendMessage(arg0 []byte) error {
+// RotateConnectionID mocks base method.
+func (m *MockEarlySession) RotateConnectionID() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateConnectionID")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RotateConnectionID indicates an expected call of RotateConnectionID.
+func (mr *MockEarlySessionMockRecorder) RotateConnectionID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateConnectionID", reflect.TypeOf((*MockEarlySession)(nil).RotateConnectionID))
+}
+
+// SendDatagram mocks base method.
+func (m *MockEarlySession) SendDatagram(arg0 []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendDatagram", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendDatagram indicates an expected call of SendDatagram.
+func (mr *MockEarlySessionMockRecorder) SendDatagram(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendDatagram", reflect.TypeOf((*MockEarlySession)(nil).SendDatagram), arg0)
+}
+
+// SetCongestionControl mocks base method.
+func (m *MockEarlySession) SetCongestionControl(arg0 congestion.CongestionOptions) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SendMessage", arg0)
+	ret := m.ctrl.Call(m, "SetCongestionControl", arg0)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// SendMessage indicates an expected call of SendMessage.
-func (mr *MockEarlySessionMockRecorder) SendMessage(arg0 interface{}) *gomock.Call {
+// SetCongestionControl indicates an expected call of SetCongestionControl.
+func (mr *MockEarlySessionMockRecorder) SetCongestionControl(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCongestionControl", reflect.TypeOf((*MockEarlySession)(nil).SetCongestionControl), arg0)
+}
+
+// SetMaxBytesInFlight mocks base method.
+func (m *MockEarlySession) SetMaxBytesInFlight(arg0 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetMaxBytesInFlight", arg0)
+}
+
+// SetMaxBytesInFlight indicates an expected call of SetMaxBytesInFlight.
+func (mr *MockEarlySessionMockRecorder) SetMaxBytesInFlight(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxBytesInFlight", reflect.TypeOf((*MockEarlySession)(nil).SetMaxBytesInFlight), arg0)
+}
+
+// SetSendRateLimit mocks base method.
+func (m *MockEarlySession) SetSendRateLimit(arg0 int64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetSendRateLimit", arg0)
+}
+
+// SetSendRateLimit indicates an expected call of SetSendRateLimit.
+func (mr *MockEarlySessionMockRecorder) SetSendRateLimit(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSendRateLimit", reflect.TypeOf((*MockEarlySession)(nil).SetSendRateLimit), arg0)
+}
+
+// StreamStats mocks base method.
+func (m *MockEarlySession) StreamStats() []quic.StreamStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamStats")
+	ret0, _ := ret[0].([]quic.StreamStats)
+	return ret0
+}
+
+// StreamStats indicates an expected call of StreamStats.
+func (mr *MockEarlySessionMockRecorder) StreamStats() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessage", reflect.TypeOf((*MockEarlySession)(nil).SendMessage), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamStats", reflect.TypeOf((*MockEarlySession)(nil).StreamStats))
 }
@@ -99,6 +99,35 @@ func (mr *MockStreamFlowControllerMockRecorder) IsNewlyBlocked() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsNewlyBlocked", reflect.TypeOf((*MockStreamFlowController)(nil).IsNewlyBlocked))
 }
 
+// ReceiveWindow mocks base method.
+func (m *MockStreamFlowController) ReceiveWindow() (protocol.ByteCount, protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReceiveWindow")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	ret1, _ := ret[1].(protocol.ByteCount)
+	return ret0, ret1
+}
+
+// ReceiveWindow indicates an expected call of ReceiveWindow.
+func (mr *MockStreamFlowControllerMockRecorder) ReceiveWindow() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceiveWindow", reflect.TypeOf((*MockStreamFlowController)(nil).ReceiveWindow))
+}
+
+// SendWindow mocks base method.
+func (m *MockStreamFlowController) SendWindow() protocol.ByteCount {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendWindow")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	return ret0
+}
+
+// SendWindow indicates an expected call of SendWindow.
+func (mr *MockStreamFlowControllerMockRecorder) SendWindow() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendWindow", reflect.TypeOf((*MockStreamFlowController)(nil).SendWindow))
+}
+
 // SendWindowSize mocks base method.
 func (m *MockStreamFlowController) SendWindowSize() protocol.ByteCount {
 	m.ctrl.T.Helper()
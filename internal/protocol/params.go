@@ -42,6 +42,10 @@ const DefaultMaxIncomingStreams = 100
 // DefaultMaxIncomingUniStreams is the maximum number of unidirectional streams that a peer may open
 const DefaultMaxIncomingUniStreams = 100
 
+// DefaultPacketReorderingThreshold is the packet reordering threshold used for loss detection.
+// RFC 9002 recommends this value, and doesn't allow implementations to use a lower one.
+const DefaultPacketReorderingThreshold PacketNumber = 3
+
 // MaxServerUnprocessedPackets is the max number of packets stored in the server that are not yet processed.
 const MaxServerUnprocessedPackets = 1024
 
@@ -172,12 +176,12 @@ const AckDelayExponent = 3
 // The loss detection timer will not be set to a value smaller than granularity.
 const TimerGranularity = time.Millisecond
 
-// MaxAckDelay is the maximum time by which we delay sending ACKs.
+// MaxAckDelay is the maximum time by which we delay sending ACKs, if not configured otherwise.
 const MaxAckDelay = 25 * time.Millisecond
 
-// MaxAckDelayInclGranularity is the max_ack_delay including the timer granularity.
-// This is the value that should be advertised to the peer.
-const MaxAckDelayInclGranularity = MaxAckDelay + TimerGranularity
+// AmplificationFactor limits the number of bytes the server sends before it validated the client's address,
+// to this factor times the number of bytes received from the client (RFC 9000, Section 8).
+const AmplificationFactor = 3
 
 // KeyUpdateInterval is the maximum number of packets we send or receive before initiating a key update.
 const KeyUpdateInterval = 100 * 1000
@@ -193,3 +197,12 @@ const Max0RTTQueues = 32
 // To avoid blocking, this value has to be smaller than MaxSessionUnprocessedPackets.
 // To avoid packets being dropped as undecryptable by the session, this value has to be smaller than MaxUndecryptablePackets.
 const Max0RTTQueueLen = 31
+
+// MinAckDelay is the min_ack_delay we advertise when the ACK frequency extension is enabled.
+// It is the minimum value the peer can request for our max ack delay.
+// See https://datatracker.ietf.org/doc/draft-ietf-quic-ack-frequency/.
+const MinAckDelay = TimerGranularity
+
+// MaxPathChallenges is the number of PATH_CHALLENGE frames that are sent on a new path, one every PTO,
+// before path validation fails.
+const MaxPathChallenges = 3
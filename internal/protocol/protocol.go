@@ -61,6 +61,11 @@ type StatelessResetToken [16]byte
 // Ethernet's max packet size is 1500 bytes,  1500 - 48 = 1452.
 const MaxPacketBufferSize ByteCount = 1452
 
+// MaxLargePacketBufferSize is the maximum packet size of any QUIC packet on paths that support jumbo frames.
+// It is based on the 9000 byte MTU of jumbo ethernet frames, minus the 48 bytes of IPv6 and UDP headers.
+// Packets of this size are only used if Path MTU Discovery is configured to probe above MaxPacketBufferSize.
+const MaxLargePacketBufferSize ByteCount = 8952
+
 // MinInitialPacketSize is the minimum size an Initial packet is required to have.
 const MinInitialPacketSize = 1200
 
@@ -23,11 +23,12 @@ const (
 	VersionUnknown  VersionNumber = math.MaxUint32
 	VersionDraft29  VersionNumber = 0xff00001d
 	Version1        VersionNumber = 0x1
+	Version2        VersionNumber = 0x6b3343cf
 )
 
 // SupportedVersions lists the versions that the server supports
-// must be in sorted descending order
-var SupportedVersions = []VersionNumber{Version1, VersionDraft29}
+// must be in order of preference
+var SupportedVersions = []VersionNumber{Version1, Version2, VersionDraft29}
 
 // IsValidVersion says if the version is known to quic-go
 func IsValidVersion(v VersionNumber) bool {
@@ -37,7 +38,7 @@ func IsValidVersion(v VersionNumber) bool {
 func (vn VersionNumber) String() string {
 	// For releases, VersionTLS will be set to a draft version.
 	// A switch statement can't contain duplicate cases.
-	if vn == VersionTLS && VersionTLS != VersionDraft29 && VersionTLS != Version1 {
+	if vn == VersionTLS && VersionTLS != VersionDraft29 && VersionTLS != Version1 && VersionTLS != Version2 {
 		return "TLS dev version (WIP)"
 	}
 	//nolint:exhaustive
@@ -50,6 +51,8 @@ func (vn VersionNumber) String() string {
 		return "draft-29"
 	case Version1:
 		return "v1"
+	case Version2:
+		return "v2"
 	default:
 		if vn.isGQUIC() {
 			return fmt.Sprintf("gQUIC %d", vn.toGQUICVersion())
@@ -76,6 +79,16 @@ func IsSupportedVersion(supported []VersionNumber, v VersionNumber) bool {
 	return false
 }
 
+// AreCompatibleVersions says if a connection can be switched from one version to the other
+// during the handshake, using compatible version negotiation (RFC 9368).
+// This is the case for QUIC v1 and v2 (RFC 9369, Section 4).
+func AreCompatibleVersions(v1, v2 VersionNumber) bool {
+	if v1 == v2 {
+		return true
+	}
+	return (v1 == Version1 || v1 == Version2) && (v2 == Version1 || v2 == Version2)
+}
+
 // ChooseSupportedVersion finds the best version in the overlap of ours and theirs
 // ours is a slice of versions that we support, sorted by our preference (descending)
 // theirs is a slice of versions offered by the peer. The order does not matter.
@@ -16,6 +16,7 @@ var _ = Describe("Version", func() {
 		Expect(IsValidVersion(VersionUnknown)).To(BeFalse())
 		Expect(IsValidVersion(VersionDraft29)).To(BeTrue())
 		Expect(IsValidVersion(Version1)).To(BeTrue())
+		Expect(IsValidVersion(Version2)).To(BeTrue())
 		Expect(IsValidVersion(1234)).To(BeFalse())
 	})
 
@@ -28,6 +29,7 @@ var _ = Describe("Version", func() {
 		Expect(VersionUnknown.String()).To(Equal("unknown"))
 		Expect(VersionDraft29.String()).To(Equal("draft-29"))
 		Expect(Version1.String()).To(Equal("v1"))
+		Expect(Version2.String()).To(Equal("v2"))
 		// check with unsupported version numbers from the wiki
 		Expect(VersionNumber(0x51303039).String()).To(Equal("gQUIC 9"))
 		Expect(VersionNumber(0x51303133).String()).To(Equal("gQUIC 13"))
@@ -42,11 +44,16 @@ var _ = Describe("Version", func() {
 		Expect(IsSupportedVersion(SupportedVersions, SupportedVersions[len(SupportedVersions)-1])).To(BeTrue())
 	})
 
-	It("has supported versions in sorted order", func() {
-		Expect(SupportedVersions[0]).To(Equal(Version1))
-		for i := 1; i < len(SupportedVersions)-1; i++ {
-			Expect(SupportedVersions[i]).To(BeNumerically(">", SupportedVersions[i+1]))
-		}
+	It("has supported versions in order of preference", func() {
+		Expect(SupportedVersions).To(Equal([]VersionNumber{Version1, Version2, VersionDraft29}))
+	})
+
+	It("says which versions are compatible", func() {
+		Expect(AreCompatibleVersions(Version1, Version2)).To(BeTrue())
+		Expect(AreCompatibleVersions(Version2, Version1)).To(BeTrue())
+		Expect(AreCompatibleVersions(Version2, Version2)).To(BeTrue())
+		Expect(AreCompatibleVersions(VersionDraft29, Version1)).To(BeFalse())
+		Expect(AreCompatibleVersions(Version2, VersionDraft29)).To(BeFalse())
 	})
 
 	Context("highest supported version", func() {
@@ -7,7 +7,9 @@ import (
 	"crypto"
 	"crypto/cipher"
 	"crypto/tls"
+	"crypto/x509"
 	"net"
+	"time"
 	"unsafe"
 
 	"github.com/marten-seemann/qtls-go1-16"
@@ -98,3 +100,31 @@ func CipherSuiteTLS13ByID(id uint16) *CipherSuiteTLS13 {
 		Hash:   cs.Hash,
 	}
 }
+
+// ClientSessionStateFields exposes the fields of a ClientSessionState.
+// It must have the same memory layout as the clientSessionState used by qtls.
+type ClientSessionStateFields struct {
+	SessionTicket      []uint8
+	Vers               uint16
+	CipherSuite        uint16
+	MasterSecret       []byte
+	ServerCertificates []*x509.Certificate
+	VerifiedChains     [][]*x509.Certificate
+	ReceivedAt         time.Time
+	OCSPResponse       []byte
+	SCTs               [][]byte
+
+	Nonce  []byte
+	UseBy  time.Time
+	AgeAdd uint32
+}
+
+// ToClientSessionStateFields gives access to the fields of a ClientSessionState.
+func ToClientSessionStateFields(s *ClientSessionState) *ClientSessionStateFields {
+	return (*ClientSessionStateFields)(unsafe.Pointer(s))
+}
+
+// FromClientSessionStateFields creates a ClientSessionState from its fields.
+func FromClientSessionStateFields(s *ClientSessionStateFields) *ClientSessionState {
+	return (*ClientSessionState)(unsafe.Pointer(s))
+}
@@ -94,12 +94,17 @@ func parseAckFrame(r *bytes.Reader, ackDelayExponent uint8, _ protocol.VersionNu
 		return nil, errInvalidAckRanges
 	}
 
-	// parse (and skip) the ECN section
+	// parse the ECN section
 	if ecn {
-		for i := 0; i < 3; i++ {
-			if _, err := quicvarint.Read(r); err != nil {
-				return nil, err
-			}
+		var err error
+		if frame.ECT0, err = quicvarint.Read(r); err != nil {
+			return nil, err
+		}
+		if frame.ECT1, err = quicvarint.Read(r); err != nil {
+			return nil, err
+		}
+		if frame.ECNCE, err = quicvarint.Read(r); err != nil {
+			return nil, err
 		}
 	}
 
@@ -0,0 +1,71 @@
+package wire
+
+import (
+	"bytes"
+	"errors"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+const ackFrequencyFrameType = 0xaf
+
+// An AckFrequencyFrame is an ACK_FREQUENCY frame.
+// See https://datatracker.ietf.org/doc/draft-ietf-quic-ack-frequency/.
+type AckFrequencyFrame struct {
+	SequenceNumber        uint64
+	AckElicitingThreshold uint64
+	RequestMaxAckDelay    time.Duration
+	ReorderingThreshold   protocol.PacketNumber
+}
+
+func parseAckFrequencyFrame(r *bytes.Reader, _ protocol.VersionNumber) (*AckFrequencyFrame, error) {
+	typ, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	if typ != ackFrequencyFrameType {
+		return nil, errors.New("unknown frame type")
+	}
+
+	frame := &AckFrequencyFrame{}
+	if frame.SequenceNumber, err = quicvarint.Read(r); err != nil {
+		return nil, err
+	}
+	if frame.AckElicitingThreshold, err = quicvarint.Read(r); err != nil {
+		return nil, err
+	}
+	delay, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	if delay > uint64(protocol.MaxMaxAckDelay/time.Microsecond) {
+		return nil, errors.New("invalid value for Request Max Ack Delay")
+	}
+	frame.RequestMaxAckDelay = time.Duration(delay) * time.Microsecond
+	reorderingThreshold, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	frame.ReorderingThreshold = protocol.PacketNumber(reorderingThreshold)
+	return frame, nil
+}
+
+func (f *AckFrequencyFrame) Write(b *bytes.Buffer, _ protocol.VersionNumber) error {
+	quicvarint.Write(b, ackFrequencyFrameType)
+	quicvarint.Write(b, f.SequenceNumber)
+	quicvarint.Write(b, f.AckElicitingThreshold)
+	quicvarint.Write(b, uint64(f.RequestMaxAckDelay/time.Microsecond))
+	quicvarint.Write(b, uint64(f.ReorderingThreshold))
+	return nil
+}
+
+// Length of a written frame
+func (f *AckFrequencyFrame) Length(_ protocol.VersionNumber) protocol.ByteCount {
+	return quicvarint.Len(ackFrequencyFrameType) +
+		quicvarint.Len(f.SequenceNumber) +
+		quicvarint.Len(f.AckElicitingThreshold) +
+		quicvarint.Len(uint64(f.RequestMaxAckDelay/time.Microsecond)) +
+		quicvarint.Len(uint64(f.ReorderingThreshold))
+}
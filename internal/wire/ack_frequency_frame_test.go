@@ -0,0 +1,88 @@
+package wire
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ACK_FREQUENCY frame", func() {
+	Context("when parsing", func() {
+		It("accepts sample frame", func() {
+			data := encodeVarInt(0xaf)
+			data = append(data, encodeVarInt(0xdeadbeef)...) // sequence number
+			data = append(data, encodeVarInt(0xcafe)...)     // ack-eliciting threshold
+			data = append(data, encodeVarInt(1337)...)       // request max ack delay (in microseconds)
+			data = append(data, encodeVarInt(3)...)          // reordering threshold
+			b := bytes.NewReader(data)
+			frame, err := parseAckFrequencyFrame(b, versionIETFFrames)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.SequenceNumber).To(Equal(uint64(0xdeadbeef)))
+			Expect(frame.AckElicitingThreshold).To(Equal(uint64(0xcafe)))
+			Expect(frame.RequestMaxAckDelay).To(Equal(1337 * time.Microsecond))
+			Expect(frame.ReorderingThreshold).To(Equal(protocol.PacketNumber(3)))
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("errors when the request max ack delay is too large", func() {
+			data := encodeVarInt(0xaf)
+			data = append(data, encodeVarInt(1)...)
+			data = append(data, encodeVarInt(1)...)
+			data = append(data, encodeVarInt(uint64(protocol.MaxMaxAckDelay/time.Microsecond)+1)...)
+			data = append(data, encodeVarInt(1)...)
+			_, err := parseAckFrequencyFrame(bytes.NewReader(data), versionIETFFrames)
+			Expect(err).To(MatchError("invalid value for Request Max Ack Delay"))
+		})
+
+		It("errors on EOFs", func() {
+			data := encodeVarInt(0xaf)
+			data = append(data, encodeVarInt(0xdeadbeef)...)
+			data = append(data, encodeVarInt(0xcafe)...)
+			data = append(data, encodeVarInt(1337)...)
+			data = append(data, encodeVarInt(3)...)
+			_, err := parseAckFrequencyFrame(bytes.NewReader(data), versionIETFFrames)
+			Expect(err).NotTo(HaveOccurred())
+			for i := range data {
+				_, err := parseAckFrequencyFrame(bytes.NewReader(data[0:i]), versionIETFFrames)
+				Expect(err).To(HaveOccurred())
+			}
+		})
+	})
+
+	Context("when writing", func() {
+		It("writes a sample frame", func() {
+			b := &bytes.Buffer{}
+			frame := &AckFrequencyFrame{
+				SequenceNumber:        0xdecafbad,
+				AckElicitingThreshold: 9,
+				RequestMaxAckDelay:    25 * time.Millisecond,
+				ReorderingThreshold:   0,
+			}
+			Expect(frame.Write(b, versionIETFFrames)).To(Succeed())
+			expected := []byte{0x40, 0xaf}
+			expected = append(expected, encodeVarInt(0xdecafbad)...)
+			expected = append(expected, encodeVarInt(9)...)
+			expected = append(expected, encodeVarInt(25000)...)
+			expected = append(expected, encodeVarInt(0)...)
+			Expect(b.Bytes()).To(Equal(expected))
+		})
+
+		It("has the correct length", func() {
+			frame := &AckFrequencyFrame{
+				SequenceNumber:        0xdecafbad,
+				AckElicitingThreshold: 0xdeadbeef,
+				RequestMaxAckDelay:    12345 * time.Microsecond,
+				ReorderingThreshold:   1,
+			}
+			Expect(frame.Length(versionIETFFrames)).To(Equal(2 + quicvarint.Len(0xdecafbad) + quicvarint.Len(0xdeadbeef) + quicvarint.Len(12345) + 1))
+			b := &bytes.Buffer{}
+			Expect(frame.Write(b, versionIETFFrames)).To(Succeed())
+			Expect(frame.Length(versionIETFFrames)).To(BeEquivalentTo(b.Len()))
+		})
+	})
+})
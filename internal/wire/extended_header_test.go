@@ -132,6 +132,23 @@ var _ = Describe("Header", func() {
 				expected = append(expected, token...)
 				Expect(buf.Bytes()).To(Equal(expected))
 			})
+
+			It("uses the packet types of QUIC v2", func() {
+				for pt, typ := range map[protocol.PacketType]uint8{
+					protocol.PacketTypeInitial:   0b01,
+					protocol.PacketType0RTT:      0b10,
+					protocol.PacketTypeHandshake: 0b11,
+					protocol.PacketTypeRetry:     0b00,
+				} {
+					b := &bytes.Buffer{}
+					Expect((&ExtendedHeader{Header: Header{
+						IsLongHeader: true,
+						Version:      protocol.Version2,
+						Type:         pt,
+					}, PacketNumberLen: protocol.PacketNumberLen1}).Write(b, protocol.Version2)).To(Succeed())
+					Expect(b.Bytes()[0] >> 4 & 0b11).To(Equal(typ))
+				}
+			})
 		})
 
 		Context("short header", func() {
@@ -200,6 +217,18 @@ var _ = Describe("Header", func() {
 					0x42, // packet number
 				}))
 			})
+
+			It("writes the Spin Bit", func() {
+				Expect((&ExtendedHeader{
+					SpinBit:         true,
+					PacketNumberLen: protocol.PacketNumberLen1,
+					PacketNumber:    0x42,
+				}).Write(buf, versionIETFHeader)).To(Succeed())
+				Expect(buf.Bytes()).To(Equal([]byte{
+					0x40 | 0x20,
+					0x42, // packet number
+				}))
+			})
 		})
 	})
 
@@ -416,10 +445,11 @@ var _ = Describe("Header", func() {
 					DestConnectionID: protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef, 0xca, 0xfe, 0x13, 0x37},
 				},
 				KeyPhase:        protocol.KeyPhaseOne,
+				SpinBit:         true,
 				PacketNumber:    1337,
 				PacketNumberLen: 4,
 			}).Log(logger)
-			Expect(buf.String()).To(ContainSubstring("Short Header{DestConnectionID: deadbeefcafe1337, PacketNumber: 1337, PacketNumberLen: 4, KeyPhase: 1}"))
+			Expect(buf.String()).To(ContainSubstring("Short Header{DestConnectionID: deadbeefcafe1337, PacketNumber: 1337, PacketNumberLen: 4, KeyPhase: 1, SpinBit: true}"))
 		})
 	})
 })
@@ -13,16 +13,18 @@ import (
 type frameParser struct {
 	ackDelayExponent uint8
 
-	supportsDatagrams bool
+	supportsDatagrams    bool
+	supportsAckFrequency bool
 
 	version protocol.VersionNumber
 }
 
 // NewFrameParser creates a new frame parser.
-func NewFrameParser(supportsDatagrams bool, v protocol.VersionNumber) FrameParser {
+func NewFrameParser(supportsDatagrams, supportsAckFrequency bool, v protocol.VersionNumber) FrameParser {
 	return &frameParser{
-		supportsDatagrams: supportsDatagrams,
-		version:           v,
+		supportsDatagrams:    supportsDatagrams,
+		supportsAckFrequency: supportsAckFrequency,
+		version:              v,
 	}
 }
 
@@ -96,11 +98,23 @@ func (p *frameParser) parseFrame(r *bytes.Reader, typeByte byte, encLevel protoc
 			frame, err = parseConnectionCloseFrame(r, p.version)
 		case 0x1e:
 			frame, err = parseHandshakeDoneFrame(r, p.version)
+		case 0x1f:
+			if p.supportsAckFrequency {
+				frame, err = parseImmediateAckFrame(r, p.version)
+				break
+			}
+			err = errors.New("unknown frame type")
 		case 0x30, 0x31:
 			if p.supportsDatagrams {
 				frame, err = parseDatagramFrame(r, p.version)
 				break
 			}
+			err = errors.New("unknown frame type")
+		case 0x40: // first byte of a frame type encoded as a 2 byte varint
+			if p.supportsAckFrequency {
+				frame, err = parseAckFrequencyFrame(r, p.version)
+				break
+			}
 			fallthrough
 		default:
 			err = errors.New("unknown frame type")
@@ -109,6 +109,14 @@ var _ = Describe("Header Parsing", func() {
 			Expect(Is0RTTPacket(zeroRTTHeader)).To(BeTrue())
 			Expect(Is0RTTPacket(append(zeroRTTHeader, []byte("foobar")...))).To(BeTrue())
 		})
+
+		It("recognizes 0-RTT packets, for QUIC v2", func() {
+			zeroRTTHeader[0] = 0x80 | 0b10<<4
+			binary.BigEndian.PutUint32(zeroRTTHeader[1:], uint32(protocol.Version2))
+			Expect(Is0RTTPacket(zeroRTTHeader)).To(BeTrue())
+			zeroRTTHeader[0] = 0x80 | 0b01<<4 // the 0-RTT packet type of QUIC v1 is the Initial packet type of QUIC v2
+			Expect(Is0RTTPacket(zeroRTTHeader)).To(BeFalse())
+		})
 	})
 
 	Context("Identifying Version Negotiation Packets", func() {
@@ -168,6 +176,28 @@ var _ = Describe("Header Parsing", func() {
 			Expect(extHdr.ParsedLen()).To(Equal(hdr.ParsedLen() + 4))
 		})
 
+		It("parses the packet types of QUIC v2", func() {
+			for typ, pt := range map[uint8]protocol.PacketType{
+				0b01: protocol.PacketTypeInitial,
+				0b10: protocol.PacketType0RTT,
+				0b11: protocol.PacketTypeHandshake,
+			} {
+				data := []byte{0xc0 | typ<<4}
+				data = appendVersion(data, protocol.Version2)
+				data = append(data, 0x0) // dest conn id length
+				data = append(data, 0x0) // src conn id length
+				if pt == protocol.PacketTypeInitial {
+					data = append(data, encodeVarInt(0)...) // token length
+				}
+				data = append(data, encodeVarInt(4)...) // length
+				data = append(data, []byte{0, 0, 0, 1}...)
+				hdr, _, _, err := ParsePacket(data, 0)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(hdr.Version).To(Equal(protocol.Version2))
+				Expect(hdr.Type).To(Equal(pt))
+			}
+		})
+
 		It("errors if 0x40 is not set", func() {
 			data := []byte{
 				0x80 | 0x2<<4,
@@ -491,6 +521,22 @@ var _ = Describe("Header Parsing", func() {
 			Expect(b.Len()).To(BeZero())
 		})
 
+		It("reads the Spin Bit", func() {
+			data := []byte{
+				0x40 ^ 0x20,
+				0xde, 0xad, 0xbe, 0xef, 0xca, 0xfe, // connection ID
+			}
+			data = append(data, 11) // packet number
+			hdr, _, _, err := ParsePacket(data, 6)
+			Expect(err).ToNot(HaveOccurred())
+			b := bytes.NewReader(data)
+			extHdr, err := hdr.ParseExtended(b, versionIETFFrames)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(extHdr.SpinBit).To(BeTrue())
+			Expect(extHdr.KeyPhase).To(Equal(protocol.KeyPhaseZero))
+			Expect(b.Len()).To(BeZero())
+		})
+
 		It("reads a header with a 2 byte packet number", func() {
 			data := []byte{
 				0x40 | 0x1,
@@ -6,7 +6,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
-var pool sync.Pool
+var pool, largePool sync.Pool
 
 func init() {
 	pool.New = func() interface{} {
@@ -15,6 +15,12 @@ func init() {
 			fromPool: true,
 		}
 	}
+	largePool.New = func() interface{} {
+		return &StreamFrame{
+			Data:     make([]byte, 0, protocol.MaxLargePacketBufferSize),
+			fromPool: true,
+		}
+	}
 }
 
 func GetStreamFrame() *StreamFrame {
@@ -22,12 +28,25 @@ func GetStreamFrame() *StreamFrame {
 	return f
 }
 
+// GetStreamFrameForSize gets a STREAM frame that can hold size bytes of data.
+// STREAM frames larger than protocol.MaxPacketBufferSize are only used in packets sent on paths that support jumbo frames.
+func GetStreamFrameForSize(size protocol.ByteCount) *StreamFrame {
+	if size > protocol.MaxPacketBufferSize {
+		return largePool.Get().(*StreamFrame)
+	}
+	return GetStreamFrame()
+}
+
 func putStreamFrame(f *StreamFrame) {
 	if !f.fromPool {
 		return
 	}
-	if protocol.ByteCount(cap(f.Data)) != protocol.MaxPacketBufferSize {
+	switch protocol.ByteCount(cap(f.Data)) {
+	case protocol.MaxPacketBufferSize:
+		pool.Put(f)
+	case protocol.MaxLargePacketBufferSize:
+		largePool.Put(f)
+	default:
 		panic("wire.PutStreamFrame called with packet of wrong size!")
 	}
-	pool.Put(f)
 }
@@ -1,6 +1,8 @@
 package wire
 
 import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -11,6 +13,15 @@ var _ = Describe("Pool", func() {
 		putStreamFrame(f)
 	})
 
+	It("gets and puts large STREAM frames", func() {
+		f := GetStreamFrameForSize(protocol.MaxPacketBufferSize)
+		Expect(f.Data).To(HaveCap(int(protocol.MaxPacketBufferSize)))
+		putStreamFrame(f)
+		f = GetStreamFrameForSize(protocol.MaxPacketBufferSize + 1)
+		Expect(f.Data).To(HaveCap(int(protocol.MaxLargePacketBufferSize)))
+		putStreamFrame(f)
+	})
+
 	It("panics when putting a STREAM frame with a wrong capacity", func() {
 		f := GetStreamFrame()
 		f.Data = []byte("foobar")
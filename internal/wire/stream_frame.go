@@ -58,12 +58,12 @@ func parseStreamFrame(r *bytes.Reader, _ protocol.VersionNumber) (*StreamFrame,
 	if dataLen < protocol.MinStreamFrameBufferSize {
 		frame = &StreamFrame{Data: make([]byte, dataLen)}
 	} else {
-		frame = GetStreamFrame()
-		// The STREAM frame can't be larger than the StreamFrame we obtained from the buffer,
+		// The STREAM frame can't be larger than the largest StreamFrame we can obtain from the buffer,
 		// since those StreamFrames have a buffer length of the maximum packet size.
-		if dataLen > uint64(cap(frame.Data)) {
+		if dataLen > uint64(protocol.MaxLargePacketBufferSize) {
 			return nil, io.EOF
 		}
+		frame = GetStreamFrameForSize(protocol.ByteCount(dataLen))
 		frame.Data = frame.Data[:dataLen]
 	}
 
@@ -166,7 +166,8 @@ func (f *StreamFrame) MaybeSplitOffFrame(maxSize protocol.ByteCount, version pro
 		return nil, true
 	}
 
-	new := GetStreamFrame()
+	// The data slices are swapped below, and the remaining data is copied into the buffer of the new frame.
+	new := GetStreamFrameForSize(f.DataLen())
 	new.StreamID = f.StreamID
 	new.Offset = f.Offset
 	new.Fin = false
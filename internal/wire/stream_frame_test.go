@@ -80,11 +80,24 @@ var _ = Describe("STREAM frame", func() {
 			Expect(err).To(MatchError("stream data overflows maximum offset"))
 		})
 
+		It("parses frames larger than the default packet size", func() {
+			data := []byte{0x8 ^ 0x2}
+			data = append(data, encodeVarInt(0x12345)...) // stream ID
+			data = append(data, encodeVarInt(8000)...)    // data length
+			data = append(data, bytes.Repeat([]byte{'f'}, 8000)...)
+			r := bytes.NewReader(data)
+			frame, err := parseStreamFrame(r, versionIETFFrames)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.Data).To(Equal(bytes.Repeat([]byte{'f'}, 8000)))
+			Expect(r.Len()).To(BeZero())
+			frame.PutBack()
+		})
+
 		It("rejects frames that claim to be longer than the packet size", func() {
 			data := []byte{0x8 ^ 0x2}
-			data = append(data, encodeVarInt(0x12345)...)                                // stream ID
-			data = append(data, encodeVarInt(uint64(protocol.MaxPacketBufferSize)+1)...) // data length
-			data = append(data, make([]byte, protocol.MaxPacketBufferSize+1)...)
+			data = append(data, encodeVarInt(0x12345)...)                                     // stream ID
+			data = append(data, encodeVarInt(uint64(protocol.MaxLargePacketBufferSize)+1)...) // data length
+			data = append(data, make([]byte, protocol.MaxLargePacketBufferSize+1)...)
 			r := bytes.NewReader(data)
 			_, err := parseStreamFrame(r, versionIETFFrames)
 			Expect(err).To(Equal(io.EOF))
@@ -356,6 +369,19 @@ var _ = Describe("STREAM frame", func() {
 			Expect(frame.DataLenPresent).To(BeTrue())
 		})
 
+		It("splits frames larger than the default packet size", func() {
+			f := GetStreamFrameForSize(8000)
+			f.StreamID = 0x1337
+			f.DataLenPresent = true
+			f.Data = f.Data[:8000]
+			frame, needsSplit := f.MaybeSplitOffFrame(1000, versionIETFFrames)
+			Expect(needsSplit).To(BeTrue())
+			Expect(frame.Length(versionIETFFrames)).To(Equal(protocol.ByteCount(1000)))
+			Expect(frame.DataLen() + f.DataLen()).To(Equal(protocol.ByteCount(8000)))
+			frame.PutBack()
+			f.PutBack()
+		})
+
 		It("adjusts the offset", func() {
 			f := &StreamFrame{
 				StreamID: 0x1337,
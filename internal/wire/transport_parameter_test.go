@@ -123,6 +123,58 @@ var _ = Describe("Transport Parameters", func() {
 		Expect(p.MaxDatagramFrameSize).To(Equal(params.MaxDatagramFrameSize))
 	})
 
+	It("marshals the max_udp_payload_size", func() {
+		data := (&TransportParameters{
+			StatelessResetToken: &protocol.StatelessResetToken{},
+		}).Marshal(protocol.PerspectiveServer)
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.MaxUDPPayloadSize).To(Equal(protocol.MaxPacketBufferSize))
+		data = (&TransportParameters{
+			MaxUDPPayloadSize:   protocol.MaxLargePacketBufferSize,
+			StatelessResetToken: &protocol.StatelessResetToken{},
+		}).Marshal(protocol.PerspectiveServer)
+		p = &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.MaxUDPPayloadSize).To(Equal(protocol.MaxLargePacketBufferSize))
+	})
+
+	It("marshals and unmarshals the min_ack_delay", func() {
+		minAckDelay := 1337 * time.Microsecond
+		data := (&TransportParameters{
+			MaxAckDelay:         protocol.DefaultMaxAckDelay,
+			MinAckDelay:         &minAckDelay,
+			StatelessResetToken: &protocol.StatelessResetToken{},
+		}).Marshal(protocol.PerspectiveServer)
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.MinAckDelay).ToNot(BeNil())
+		Expect(*p.MinAckDelay).To(Equal(minAckDelay))
+		Expect(p.String()).To(ContainSubstring("MinAckDelay: 1.337ms"))
+	})
+
+	It("doesn't marshal the min_ack_delay, if not set", func() {
+		data := (&TransportParameters{
+			StatelessResetToken: &protocol.StatelessResetToken{},
+		}).Marshal(protocol.PerspectiveServer)
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.MinAckDelay).To(BeNil())
+	})
+
+	It("errors when the min_ack_delay is larger than the max_ack_delay", func() {
+		minAckDelay := 30 * time.Millisecond
+		data := (&TransportParameters{
+			MaxAckDelay:         protocol.DefaultMaxAckDelay,
+			MinAckDelay:         &minAckDelay,
+			StatelessResetToken: &protocol.StatelessResetToken{},
+		}).Marshal(protocol.PerspectiveServer)
+		Expect((&TransportParameters{}).Unmarshal(data, protocol.PerspectiveServer)).To(MatchError(&qerr.TransportError{
+			ErrorCode:    qerr.TransportParameterError,
+			ErrorMessage: "min_ack_delay (30ms) is larger than max_ack_delay (25ms)",
+		}))
+	})
+
 	It("doesn't marshal a retry_source_connection_id, if no Retry was performed", func() {
 		data := (&TransportParameters{
 			StatelessResetToken: &protocol.StatelessResetToken{},
@@ -485,6 +537,82 @@ var _ = Describe("Transport Parameters", func() {
 		})
 	})
 
+	Context("version information", func() {
+		It("marshals and unmarshals", func() {
+			data := (&TransportParameters{
+				VersionInformation: &VersionInformation{
+					ChosenVersion:     protocol.Version1,
+					AvailableVersions: []protocol.VersionNumber{protocol.Version2, protocol.Version1},
+				},
+			}).Marshal(protocol.PerspectiveClient)
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(data, protocol.PerspectiveClient)).To(Succeed())
+			Expect(p.VersionInformation).To(Equal(&VersionInformation{
+				ChosenVersion:     protocol.Version1,
+				AvailableVersions: []protocol.VersionNumber{protocol.Version2, protocol.Version1},
+			}))
+		})
+
+		It("doesn't marshal the version_information, if not set", func() {
+			data := (&TransportParameters{}).Marshal(protocol.PerspectiveClient)
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(data, protocol.PerspectiveClient)).To(Succeed())
+			Expect(p.VersionInformation).To(BeNil())
+		})
+
+		It("unmarshals a version_information without available versions", func() {
+			b := &bytes.Buffer{}
+			addInitialSourceConnectionID(b)
+			quicvarint.Write(b, uint64(versionInformationParameterID))
+			quicvarint.Write(b, 4)
+			b.Write([]byte{0x6b, 0x33, 0x43, 0xcf})
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(b.Bytes(), protocol.PerspectiveClient)).To(Succeed())
+			Expect(p.VersionInformation.ChosenVersion).To(Equal(protocol.Version2))
+			Expect(p.VersionInformation.AvailableVersions).To(BeEmpty())
+		})
+
+		It("errors if the length is not a multiple of 4", func() {
+			b := &bytes.Buffer{}
+			addInitialSourceConnectionID(b)
+			quicvarint.Write(b, uint64(versionInformationParameterID))
+			quicvarint.Write(b, 6)
+			b.Write([]byte{0, 0, 0, 1, 0, 0})
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(b.Bytes(), protocol.PerspectiveClient)).To(MatchError(&qerr.TransportError{
+				ErrorCode:    qerr.TransportParameterError,
+				ErrorMessage: "invalid length for version_information: 6",
+			}))
+		})
+
+		It("errors if it contains version 0", func() {
+			data := (&TransportParameters{
+				VersionInformation: &VersionInformation{
+					ChosenVersion:     protocol.Version1,
+					AvailableVersions: []protocol.VersionNumber{protocol.Version1, 0},
+				},
+			}).Marshal(protocol.PerspectiveClient)
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(data, protocol.PerspectiveClient)).To(MatchError(&qerr.TransportError{
+				ErrorCode:    qerr.TransportParameterError,
+				ErrorMessage: "version_information contains version 0",
+			}))
+		})
+
+		It("has a string representation", func() {
+			p := &TransportParameters{
+				OriginalDestinationConnectionID: protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef},
+				InitialSourceConnectionID:       protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad},
+				MaxDatagramFrameSize:            protocol.InvalidByteCount,
+				VersionInformation: &VersionInformation{
+					ChosenVersion:     protocol.Version2,
+					AvailableVersions: []protocol.VersionNumber{protocol.Version2, protocol.Version1},
+				},
+			}
+			Expect(p.String()).To(HaveSuffix(", VersionInformation: {ChosenVersion: v2, AvailableVersions: [v2 v1]}}"))
+		})
+	})
+
 	Context("saving and retrieving from a session ticket", func() {
 		It("saves and retrieves the parameters", func() {
 			params := &TransportParameters{
@@ -42,8 +42,12 @@ const (
 	activeConnectionIDLimitParameterID         transportParameterID = 0xe
 	initialSourceConnectionIDParameterID       transportParameterID = 0xf
 	retrySourceConnectionIDParameterID         transportParameterID = 0x10
+	// RFC 9368
+	versionInformationParameterID transportParameterID = 0x11
 	// https://datatracker.ietf.org/doc/draft-ietf-quic-datagram/
 	maxDatagramFrameSizeParameterID transportParameterID = 0x20
+	// https://datatracker.ietf.org/doc/draft-ietf-quic-ack-frequency/
+	minAckDelayParameterID transportParameterID = 0xff04de1b
 )
 
 // PreferredAddress is the value encoding in the preferred_address transport parameter
@@ -56,6 +60,12 @@ type PreferredAddress struct {
 	StatelessResetToken protocol.StatelessResetToken
 }
 
+// VersionInformation is the value encoded in the version_information transport parameter (RFC 9368)
+type VersionInformation struct {
+	ChosenVersion     protocol.VersionNumber
+	AvailableVersions []protocol.VersionNumber
+}
+
 // TransportParameters are parameters sent to the peer during the handshake
 type TransportParameters struct {
 	InitialMaxStreamDataBidiLocal  protocol.ByteCount
@@ -85,6 +95,10 @@ type TransportParameters struct {
 	ActiveConnectionIDLimit uint64
 
 	MaxDatagramFrameSize protocol.ByteCount
+
+	MinAckDelay *time.Duration // use a pointer here to distinguish a zero min_ack_delay from a missing transport parameter
+
+	VersionInformation *VersionInformation
 }
 
 // Unmarshal the transport parameters
@@ -137,6 +151,7 @@ func (p *TransportParameters) unmarshal(r *bytes.Reader, sentBy protocol.Perspec
 			maxAckDelayParameterID,
 			activeConnectionIDLimitParameterID,
 			maxDatagramFrameSizeParameterID,
+			minAckDelayParameterID,
 			ackDelayExponentParameterID:
 			if err := p.readNumericTransportParameter(r, paramID, int(paramLen)); err != nil {
 				return err
@@ -178,11 +193,19 @@ func (p *TransportParameters) unmarshal(r *bytes.Reader, sentBy protocol.Perspec
 			}
 			connID, _ := protocol.ReadConnectionID(r, int(paramLen))
 			p.RetrySourceConnectionID = &connID
+		case versionInformationParameterID:
+			if err := p.readVersionInformation(r, int(paramLen)); err != nil {
+				return err
+			}
 		default:
 			r.Seek(int64(paramLen), io.SeekCurrent)
 		}
 	}
 
+	if p.MinAckDelay != nil && *p.MinAckDelay > p.MaxAckDelay {
+		return fmt.Errorf("min_ack_delay (%s) is larger than max_ack_delay (%s)", *p.MinAckDelay, p.MaxAckDelay)
+	}
+
 	if !fromSessionTicket {
 		if sentBy == protocol.PerspectiveServer && !readOriginalDestinationConnectionID {
 			return errors.New("missing original_destination_connection_id")
@@ -251,6 +274,30 @@ func (p *TransportParameters) readPreferredAddress(r *bytes.Reader, expectedLen
 	return nil
 }
 
+func (p *TransportParameters) readVersionInformation(r *bytes.Reader, length int) error {
+	if length < 4 || length%4 != 0 {
+		return fmt.Errorf("invalid length for version_information: %d", length)
+	}
+	vi := &VersionInformation{AvailableVersions: make([]protocol.VersionNumber, 0, length/4-1)}
+	for i := 0; i < length/4; i++ {
+		v, err := utils.BigEndian.ReadUint32(r)
+		if err != nil {
+			return err
+		}
+		// A version number of 0 is reserved for Version Negotiation packets (RFC 9368, Section 3).
+		if v == 0 {
+			return errors.New("version_information contains version 0")
+		}
+		if i == 0 {
+			vi.ChosenVersion = protocol.VersionNumber(v)
+			continue
+		}
+		vi.AvailableVersions = append(vi.AvailableVersions, protocol.VersionNumber(v))
+	}
+	p.VersionInformation = vi
+	return nil
+}
+
 func (p *TransportParameters) readNumericTransportParameter(
 	r *bytes.Reader,
 	paramID transportParameterID,
@@ -305,6 +352,12 @@ func (p *TransportParameters) readNumericTransportParameter(
 		p.ActiveConnectionIDLimit = val
 	case maxDatagramFrameSizeParameterID:
 		p.MaxDatagramFrameSize = protocol.ByteCount(val)
+	case minAckDelayParameterID:
+		if val > uint64(protocol.MaxMaxAckDelay/time.Microsecond) {
+			return fmt.Errorf("invalid value for min_ack_delay: %dus (maximum %dus)", val, protocol.MaxMaxAckDelay/time.Microsecond)
+		}
+		minAckDelay := time.Duration(val) * time.Microsecond
+		p.MinAckDelay = &minAckDelay
 	default:
 		return fmt.Errorf("TransportParameter BUG: transport parameter %d not found", paramID)
 	}
@@ -338,7 +391,12 @@ func (p *TransportParameters) Marshal(pers protocol.Perspective) []byte {
 	// idle_timeout
 	p.marshalVarintParam(b, maxIdleTimeoutParameterID, uint64(p.MaxIdleTimeout/time.Millisecond))
 	// max_packet_size
-	p.marshalVarintParam(b, maxUDPPayloadSizeParameterID, uint64(protocol.MaxPacketBufferSize))
+	// If not set, advertise the size of the (default) packet buffers.
+	maxUDPPayloadSize := p.MaxUDPPayloadSize
+	if maxUDPPayloadSize == 0 {
+		maxUDPPayloadSize = protocol.MaxPacketBufferSize
+	}
+	p.marshalVarintParam(b, maxUDPPayloadSizeParameterID, uint64(maxUDPPayloadSize))
 	// max_ack_delay
 	// Only send it if is different from the default value.
 	if p.MaxAckDelay != protocol.DefaultMaxAckDelay {
@@ -394,6 +452,18 @@ func (p *TransportParameters) Marshal(pers protocol.Perspective) []byte {
 	if p.MaxDatagramFrameSize != protocol.InvalidByteCount {
 		p.marshalVarintParam(b, maxDatagramFrameSizeParameterID, uint64(p.MaxDatagramFrameSize))
 	}
+	if p.MinAckDelay != nil {
+		p.marshalVarintParam(b, minAckDelayParameterID, uint64(*p.MinAckDelay/time.Microsecond))
+	}
+	// version_information
+	if p.VersionInformation != nil {
+		quicvarint.Write(b, uint64(versionInformationParameterID))
+		quicvarint.Write(b, 4*uint64(1+len(p.VersionInformation.AvailableVersions)))
+		utils.BigEndian.WriteUint32(b, uint32(p.VersionInformation.ChosenVersion))
+		for _, v := range p.VersionInformation.AvailableVersions {
+			utils.BigEndian.WriteUint32(b, uint32(v))
+		}
+	}
 	return b.Bytes()
 }
 
@@ -471,6 +541,14 @@ func (p *TransportParameters) String() string {
 		logString += ", MaxDatagramFrameSize: %d"
 		logParams = append(logParams, p.MaxDatagramFrameSize)
 	}
+	if p.MinAckDelay != nil {
+		logString += ", MinAckDelay: %s"
+		logParams = append(logParams, *p.MinAckDelay)
+	}
+	if p.VersionInformation != nil {
+		logString += ", VersionInformation: {ChosenVersion: %s, AvailableVersions: %s}"
+		logParams = append(logParams, p.VersionInformation.ChosenVersion, p.VersionInformation.AvailableVersions)
+	}
 	logString += "}"
 	return fmt.Sprintf(logString, logParams...)
 }
@@ -0,0 +1,70 @@
+package logging
+
+import (
+	"net"
+	"time"
+)
+
+// A FrameTracer is a ConnectionTracer that reports every frame sent and received on a connection.
+// ACK frames are reported like all other frames.
+// The frames are the same values that are passed to SentPacket and ReceivedPacket:
+// STREAM, CRYPTO and DATAGRAM frames don't contain any data, only the length.
+// All other events are ignored. Use NewMultiplexedConnectionTracer to combine it with other tracers.
+type FrameTracer struct {
+	// SentFrame is called for every frame sent, in the order the frames appear in the packet.
+	SentFrame func(hdr *ExtendedHeader, f Frame)
+	// ReceivedFrame is called for every frame received, in the order the frames appear in the packet.
+	ReceivedFrame func(hdr *ExtendedHeader, f Frame)
+}
+
+var _ ConnectionTracer = &FrameTracer{}
+
+func (t *FrameTracer) SentPacket(hdr *ExtendedHeader, _ ByteCount, ack *AckFrame, frames []Frame) {
+	if t.SentFrame == nil {
+		return
+	}
+	if ack != nil {
+		t.SentFrame(hdr, ack)
+	}
+	for _, f := range frames {
+		t.SentFrame(hdr, f)
+	}
+}
+
+func (t *FrameTracer) ReceivedPacket(hdr *ExtendedHeader, _ ByteCount, frames []Frame) {
+	if t.ReceivedFrame == nil {
+		return
+	}
+	for _, f := range frames {
+		t.ReceivedFrame(hdr, f)
+	}
+}
+
+func (t *FrameTracer) StartedConnection(net.Addr, net.Addr, ConnectionID, ConnectionID)    {}
+func (t *FrameTracer) NegotiatedVersion(VersionNumber, []VersionNumber, []VersionNumber)   {}
+func (t *FrameTracer) ClosedConnection(error)                                              {}
+func (t *FrameTracer) SentTransportParameters(*TransportParameters)                        {}
+func (t *FrameTracer) ReceivedTransportParameters(*TransportParameters)                    {}
+func (t *FrameTracer) RestoredTransportParameters(*TransportParameters)                    {}
+func (t *FrameTracer) ReceivedVersionNegotiationPacket(*Header, []VersionNumber)           {}
+func (t *FrameTracer) ReceivedRetry(*Header)                                               {}
+func (t *FrameTracer) BufferedPacket(PacketType)                                           {}
+func (t *FrameTracer) DroppedPacket(PacketType, ByteCount, PacketDropReason)               {}
+func (t *FrameTracer) UpdatedMetrics(*RTTStats, ByteCount, ByteCount, int)                 {}
+func (t *FrameTracer) UpdatedCongestionMetrics(*RTTStats, ByteCount, ByteCount, ByteCount) {}
+func (t *FrameTracer) AcknowledgedPacket(EncryptionLevel, PacketNumber)                    {}
+func (t *FrameTracer) LostPacket(EncryptionLevel, PacketNumber, PacketLossReason)          {}
+func (t *FrameTracer) UpdatedCongestionState(CongestionState)                              {}
+func (t *FrameTracer) UpdatedCongestionControl(string, string)                             {}
+func (t *FrameTracer) UpdatedPTOCount(uint32)                                              {}
+func (t *FrameTracer) UpdatedMTU(ByteCount)                                                {}
+func (t *FrameTracer) UpdatedSpinBitRTT(time.Duration)                                     {}
+func (t *FrameTracer) UpdatedKeyFromTLS(EncryptionLevel, Perspective)                      {}
+func (t *FrameTracer) UpdatedKey(KeyPhase, bool)                                           {}
+func (t *FrameTracer) DroppedEncryptionLevel(EncryptionLevel)                              {}
+func (t *FrameTracer) DroppedKey(KeyPhase)                                                 {}
+func (t *FrameTracer) SetLossTimer(TimerType, EncryptionLevel, time.Time)                  {}
+func (t *FrameTracer) LossTimerExpired(TimerType, EncryptionLevel)                         {}
+func (t *FrameTracer) LossTimerCanceled()                                                  {}
+func (t *FrameTracer) Close()                                                              {}
+func (t *FrameTracer) Debug(string, string)                                                {}
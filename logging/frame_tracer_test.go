@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Frame Tracer", func() {
+	hdr := &ExtendedHeader{PacketNumber: 42}
+
+	It("reports sent frames, starting with the ACK frame", func() {
+		var frames []Frame
+		t := &FrameTracer{
+			SentFrame: func(h *ExtendedHeader, f Frame) {
+				Expect(h).To(Equal(hdr))
+				frames = append(frames, f)
+			},
+		}
+		ack := &AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 10}}}
+		t.SentPacket(hdr, 1234, ack, []Frame{
+			&StreamFrame{StreamID: 4, Offset: 100, Length: 200},
+			&MaxDataFrame{MaximumData: 1337},
+		})
+		Expect(frames).To(Equal([]Frame{
+			ack,
+			&StreamFrame{StreamID: 4, Offset: 100, Length: 200},
+			&MaxDataFrame{MaximumData: 1337},
+		}))
+	})
+
+	It("reports received frames", func() {
+		var frames []Frame
+		t := &FrameTracer{
+			ReceivedFrame: func(h *ExtendedHeader, f Frame) {
+				Expect(h).To(Equal(hdr))
+				frames = append(frames, f)
+			},
+		}
+		t.ReceivedPacket(hdr, 1234, []Frame{
+			&AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 10}}},
+			&MaxStreamDataFrame{StreamID: 4, MaximumStreamData: protocol.ByteCount(1 << 20)},
+		})
+		Expect(frames).To(HaveLen(2))
+		Expect(frames[0]).To(BeAssignableToTypeOf(&AckFrame{}))
+		Expect(frames[1]).To(Equal(&MaxStreamDataFrame{StreamID: 4, MaximumStreamData: 1 << 20}))
+	})
+
+	It("doesn't require the callbacks to be set", func() {
+		t := &FrameTracer{}
+		t.SentPacket(hdr, 1234, &AckFrame{}, []Frame{&PingFrame{}})
+		t.ReceivedPacket(hdr, 1234, []Frame{&PingFrame{}})
+	})
+})
@@ -117,10 +117,20 @@ type ConnectionTracer interface {
 	BufferedPacket(PacketType)
 	DroppedPacket(PacketType, ByteCount, PacketDropReason)
 	UpdatedMetrics(rttStats *RTTStats, cwnd, bytesInFlight ByteCount, packetsInFlight int)
+	// UpdatedCongestionMetrics is called by the congestion controller when the congestion window or the slow start threshold changes.
+	UpdatedCongestionMetrics(rttStats *RTTStats, cwnd, ssthresh, bytesInFlight ByteCount)
 	AcknowledgedPacket(EncryptionLevel, PacketNumber)
 	LostPacket(EncryptionLevel, PacketNumber, PacketLossReason)
 	UpdatedCongestionState(CongestionState)
+	// UpdatedCongestionControl is called when the congestion controller is created, and when it is replaced.
+	// The algorithm and the hystart variant are the names used by congestion.ParseCongestionControl and congestion.ParseHystart.
+	UpdatedCongestionControl(algorithm, hystart string)
 	UpdatedPTOCount(value uint32)
+	// UpdatedMTU is called when Path MTU Discovery increases the maximum datagram size.
+	UpdatedMTU(mtu ByteCount)
+	// UpdatedSpinBitRTT is called when the spin bit of received packets flips.
+	// The time between two flips is an estimate of the RTT, as seen by an on-path observer.
+	UpdatedSpinBitRTT(rtt time.Duration)
 	UpdatedKeyFromTLS(EncryptionLevel, Perspective)
 	UpdatedKey(generation KeyPhase, remote bool)
 	DroppedEncryptionLevel(EncryptionLevel)
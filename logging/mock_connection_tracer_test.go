@@ -290,6 +290,30 @@ func (mr *MockConnectionTracerMockRecorder) StartedConnection(arg0, arg1, arg2,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartedConnection", reflect.TypeOf((*MockConnectionTracer)(nil).StartedConnection), arg0, arg1, arg2, arg3)
 }
 
+// UpdatedCongestionMetrics mocks base method.
+func (m *MockConnectionTracer) UpdatedCongestionMetrics(arg0 *utils.RTTStats, arg1, arg2, arg3 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdatedCongestionMetrics", arg0, arg1, arg2, arg3)
+}
+
+// UpdatedCongestionMetrics indicates an expected call of UpdatedCongestionMetrics.
+func (mr *MockConnectionTracerMockRecorder) UpdatedCongestionMetrics(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatedCongestionMetrics", reflect.TypeOf((*MockConnectionTracer)(nil).UpdatedCongestionMetrics), arg0, arg1, arg2, arg3)
+}
+
+// UpdatedCongestionControl mocks base method.
+func (m *MockConnectionTracer) UpdatedCongestionControl(arg0, arg1 string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdatedCongestionControl", arg0, arg1)
+}
+
+// UpdatedCongestionControl indicates an expected call of UpdatedCongestionControl.
+func (mr *MockConnectionTracerMockRecorder) UpdatedCongestionControl(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatedCongestionControl", reflect.TypeOf((*MockConnectionTracer)(nil).UpdatedCongestionControl), arg0, arg1)
+}
+
 // UpdatedCongestionState mocks base method.
 func (m *MockConnectionTracer) UpdatedCongestionState(arg0 CongestionState) {
 	m.ctrl.T.Helper()
@@ -326,6 +350,18 @@ func (mr *MockConnectionTracerMockRecorder) UpdatedKeyFromTLS(arg0, arg1 interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatedKeyFromTLS", reflect.TypeOf((*MockConnectionTracer)(nil).UpdatedKeyFromTLS), arg0, arg1)
 }
 
+// UpdatedMTU mocks base method.
+func (m *MockConnectionTracer) UpdatedMTU(arg0 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdatedMTU", arg0)
+}
+
+// UpdatedMTU indicates an expected call of UpdatedMTU.
+func (mr *MockConnectionTracerMockRecorder) UpdatedMTU(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatedMTU", reflect.TypeOf((*MockConnectionTracer)(nil).UpdatedMTU), arg0)
+}
+
 // UpdatedMetrics mocks base method.
 func (m *MockConnectionTracer) UpdatedMetrics(arg0 *utils.RTTStats, arg1, arg2 protocol.ByteCount, arg3 int) {
 	m.ctrl.T.Helper()
@@ -349,3 +385,15 @@ func (mr *MockConnectionTracerMockRecorder) UpdatedPTOCount(arg0 interface{}) *g
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatedPTOCount", reflect.TypeOf((*MockConnectionTracer)(nil).UpdatedPTOCount), arg0)
 }
+
+// UpdatedSpinBitRTT mocks base method.
+func (m *MockConnectionTracer) UpdatedSpinBitRTT(arg0 time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdatedSpinBitRTT", arg0)
+}
+
+// UpdatedSpinBitRTT indicates an expected call of UpdatedSpinBitRTT.
+func (mr *MockConnectionTracerMockRecorder) UpdatedSpinBitRTT(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatedSpinBitRTT", reflect.TypeOf((*MockConnectionTracer)(nil).UpdatedSpinBitRTT), arg0)
+}
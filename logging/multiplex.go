@@ -140,12 +140,24 @@ func (m *connTracerMultiplexer) UpdatedCongestionState(state CongestionState) {
 	}
 }
 
+func (m *connTracerMultiplexer) UpdatedCongestionControl(algorithm, hystart string) {
+	for _, t := range m.tracers {
+		t.UpdatedCongestionControl(algorithm, hystart)
+	}
+}
+
 func (m *connTracerMultiplexer) UpdatedMetrics(rttStats *RTTStats, cwnd, bytesInFLight ByteCount, packetsInFlight int) {
 	for _, t := range m.tracers {
 		t.UpdatedMetrics(rttStats, cwnd, bytesInFLight, packetsInFlight)
 	}
 }
 
+func (m *connTracerMultiplexer) UpdatedCongestionMetrics(rttStats *RTTStats, cwnd, ssthresh, bytesInFlight ByteCount) {
+	for _, t := range m.tracers {
+		t.UpdatedCongestionMetrics(rttStats, cwnd, ssthresh, bytesInFlight)
+	}
+}
+
 func (m *connTracerMultiplexer) AcknowledgedPacket(encLevel EncryptionLevel, pn PacketNumber) {
 	for _, t := range m.tracers {
 		t.AcknowledgedPacket(encLevel, pn)
@@ -164,6 +176,18 @@ func (m *connTracerMultiplexer) UpdatedPTOCount(value uint32) {
 	}
 }
 
+func (m *connTracerMultiplexer) UpdatedMTU(mtu ByteCount) {
+	for _, t := range m.tracers {
+		t.UpdatedMTU(mtu)
+	}
+}
+
+func (m *connTracerMultiplexer) UpdatedSpinBitRTT(rtt time.Duration) {
+	for _, t := range m.tracers {
+		t.UpdatedSpinBitRTT(rtt)
+	}
+}
+
 func (m *connTracerMultiplexer) UpdatedKeyFromTLS(encLevel EncryptionLevel, perspective Perspective) {
 	for _, t := range m.tracers {
 		t.UpdatedKeyFromTLS(encLevel, perspective)
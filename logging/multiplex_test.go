@@ -188,6 +188,12 @@ var _ = Describe("Tracing", func() {
 			tracer.UpdatedCongestionState(CongestionStateRecovery)
 		})
 
+		It("traces the UpdatedCongestionControl event", func() {
+			tr1.EXPECT().UpdatedCongestionControl("cubic", "standard")
+			tr2.EXPECT().UpdatedCongestionControl("cubic", "standard")
+			tracer.UpdatedCongestionControl("cubic", "standard")
+		})
+
 		It("traces the UpdatedMetrics event", func() {
 			rttStats := &RTTStats{}
 			rttStats.UpdateRTT(time.Second, 0, time.Now())
@@ -196,6 +202,14 @@ var _ = Describe("Tracing", func() {
 			tracer.UpdatedMetrics(rttStats, 1337, 42, 13)
 		})
 
+		It("traces the UpdatedCongestionMetrics event", func() {
+			rttStats := &RTTStats{}
+			rttStats.UpdateRTT(time.Second, 0, time.Now())
+			tr1.EXPECT().UpdatedCongestionMetrics(rttStats, ByteCount(1337), ByteCount(1000), ByteCount(42))
+			tr2.EXPECT().UpdatedCongestionMetrics(rttStats, ByteCount(1337), ByteCount(1000), ByteCount(42))
+			tracer.UpdatedCongestionMetrics(rttStats, 1337, 1000, 42)
+		})
+
 		It("traces the AcknowledgedPacket event", func() {
 			tr1.EXPECT().AcknowledgedPacket(EncryptionHandshake, PacketNumber(42))
 			tr2.EXPECT().AcknowledgedPacket(EncryptionHandshake, PacketNumber(42))
@@ -214,6 +228,18 @@ var _ = Describe("Tracing", func() {
 			tracer.UpdatedPTOCount(88)
 		})
 
+		It("traces the UpdatedMTU event", func() {
+			tr1.EXPECT().UpdatedMTU(ByteCount(1400))
+			tr2.EXPECT().UpdatedMTU(ByteCount(1400))
+			tracer.UpdatedMTU(1400)
+		})
+
+		It("traces the UpdatedSpinBitRTT event", func() {
+			tr1.EXPECT().UpdatedSpinBitRTT(42 * time.Millisecond)
+			tr2.EXPECT().UpdatedSpinBitRTT(42 * time.Millisecond)
+			tracer.UpdatedSpinBitRTT(42 * time.Millisecond)
+		})
+
 		It("traces the UpdatedKeyFromTLS event", func() {
 			tr1.EXPECT().UpdatedKeyFromTLS(EncryptionHandshake, PerspectiveClient)
 			tr2.EXPECT().UpdatedKeyFromTLS(EncryptionHandshake, PerspectiveClient)
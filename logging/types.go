@@ -29,6 +29,8 @@ const (
 	PacketLossReorderingThreshold PacketLossReason = iota
 	// PacketLossTimeThreshold: when a packet is deemed lost due to time threshold
 	PacketLossTimeThreshold
+	// PacketLossPTO: when a packet is deemed lost because it is retransmitted in a PTO probe packet
+	PacketLossPTO
 )
 
 type PacketDropReason uint8
@@ -93,4 +95,10 @@ const (
 	CongestionStateRecovery
 	// CongestionStateApplicationLimited means that the congestion controller is application limited
 	CongestionStateApplicationLimited
+	// CongestionStateBBRDrain is the drain phase of BBR, entered after the startup phase
+	CongestionStateBBRDrain
+	// CongestionStateBBRProbeBW is the bandwidth probing phase of BBR
+	CongestionStateBBRProbeBW
+	// CongestionStateBBRProbeRTT is the phase of BBR that measures the minimum RTT
+	CongestionStateBBRProbeRTT
 )
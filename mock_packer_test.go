@@ -37,6 +37,20 @@ func (m *MockPacker) EXPECT() *MockPackerMockRecorder {
 	return m.recorder
 }
 
+// BlockedFrameCounts mocks base method.
+func (m *MockPacker) BlockedFrameCounts() blockedFrameCounts {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BlockedFrameCounts")
+	ret0, _ := ret[0].(blockedFrameCounts)
+	return ret0
+}
+
+// BlockedFrameCounts indicates an expected call of BlockedFrameCounts.
+func (mr *MockPackerMockRecorder) BlockedFrameCounts() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlockedFrameCounts", reflect.TypeOf((*MockPacker)(nil).BlockedFrameCounts))
+}
+
 // HandleTransportParameters mocks base method.
 func (m *MockPacker) HandleTransportParameters(arg0 *wire.TransportParameters) {
 	m.ctrl.T.Helper()
@@ -139,6 +153,21 @@ func (mr *MockPackerMockRecorder) PackMTUProbePacket(ping, size interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PackMTUProbePacket", reflect.TypeOf((*MockPacker)(nil).PackMTUProbePacket), ping, size)
 }
 
+// PackPathProbePacket mocks base method.
+func (m *MockPacker) PackPathProbePacket(f ackhandler.Frame, size protocol.ByteCount) (*packedPacket, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PackPathProbePacket", f, size)
+	ret0, _ := ret[0].(*packedPacket)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PackPathProbePacket indicates an expected call of PackPathProbePacket.
+func (mr *MockPackerMockRecorder) PackPathProbePacket(f, size interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PackPathProbePacket", reflect.TypeOf((*MockPacker)(nil).PackPathProbePacket), f, size)
+}
+
 // PackPacket mocks base method.
 func (m *MockPacker) PackPacket() (*packedPacket, error) {
 	m.ctrl.T.Helper()
@@ -166,6 +195,18 @@ func (mr *MockPackerMockRecorder) SetMaxPacketSize(arg0 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxPacketSize", reflect.TypeOf((*MockPacker)(nil).SetMaxPacketSize), arg0)
 }
 
+// SetSpinBit mocks base method.
+func (m *MockPacker) SetSpinBit(arg0 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetSpinBit", arg0)
+}
+
+// SetSpinBit indicates an expected call of SetSpinBit.
+func (mr *MockPackerMockRecorder) SetSpinBit(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSpinBit", reflect.TypeOf((*MockPacker)(nil).SetSpinBit), arg0)
+}
+
 // SetToken mocks base method.
 func (m *MockPacker) SetToken(arg0 []byte) {
 	m.ctrl.T.Helper()
@@ -177,3 +218,15 @@ func (mr *MockPackerMockRecorder) SetToken(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetToken", reflect.TypeOf((*MockPacker)(nil).SetToken), arg0)
 }
+
+// SetVersion mocks base method.
+func (m *MockPacker) SetVersion(arg0 protocol.VersionNumber) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetVersion", arg0)
+}
+
+// SetVersion indicates an expected call of SetVersion.
+func (mr *MockPackerMockRecorder) SetVersion(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetVersion", reflect.TypeOf((*MockPacker)(nil).SetVersion), arg0)
+}
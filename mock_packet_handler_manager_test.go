@@ -100,6 +100,18 @@ func (mr *MockPacketHandlerManagerMockRecorder) Destroy() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Destroy", reflect.TypeOf((*MockPacketHandlerManager)(nil).Destroy))
 }
 
+// EnableLargePackets mocks base method.
+func (m *MockPacketHandlerManager) EnableLargePackets() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "EnableLargePackets")
+}
+
+// EnableLargePackets indicates an expected call of EnableLargePackets.
+func (mr *MockPacketHandlerManagerMockRecorder) EnableLargePackets() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableLargePackets", reflect.TypeOf((*MockPacketHandlerManager)(nil).EnableLargePackets))
+}
+
 // GetStatelessResetToken mocks base method.
 func (m *MockPacketHandlerManager) GetStatelessResetToken(arg0 protocol.ConnectionID) protocol.StatelessResetToken {
 	m.ctrl.T.Helper()
@@ -10,6 +10,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	congestion "github.com/lucas-clemente/quic-go/congestion"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
@@ -66,6 +67,20 @@ func (mr *MockQuicSessionMockRecorder) AcceptUniStream(arg0 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptUniStream", reflect.TypeOf((*MockQuicSession)(nil).AcceptUniStream), arg0)
 }
 
+// BytesInFlight mocks base method.
+func (m *MockQuicSession) BytesInFlight() protocol.ByteCount {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BytesInFlight")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	return ret0
+}
+
+// BytesInFlight indicates an expected call of BytesInFlight.
+func (mr *MockQuicSessionMockRecorder) BytesInFlight() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BytesInFlight", reflect.TypeOf((*MockQuicSession)(nil).BytesInFlight))
+}
+
 // CloseWithError mocks base method.
 func (m *MockQuicSession) CloseWithError(arg0 ApplicationErrorCode, arg1 string) error {
 	m.ctrl.T.Helper()
@@ -80,6 +95,20 @@ func (mr *MockQuicSessionMockRecorder) CloseWithError(arg0, arg1 interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseWithError", reflect.TypeOf((*MockQuicSession)(nil).CloseWithError), arg0, arg1)
 }
 
+// CongestionState mocks base method.
+func (m *MockQuicSession) CongestionState() CongestionState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CongestionState")
+	ret0, _ := ret[0].(CongestionState)
+	return ret0
+}
+
+// CongestionState indicates an expected call of CongestionState.
+func (mr *MockQuicSessionMockRecorder) CongestionState() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CongestionState", reflect.TypeOf((*MockQuicSession)(nil).CongestionState))
+}
+
 // ConnectionState mocks base method.
 func (m *MockQuicSession) ConnectionState() ConnectionState {
 	m.ctrl.T.Helper()
@@ -94,6 +123,35 @@ func (mr *MockQuicSessionMockRecorder) ConnectionState() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectionState", reflect.TypeOf((*MockQuicSession)(nil).ConnectionState))
 }
 
+// ConnectionStateErr mocks base method.
+func (m *MockQuicSession) ConnectionStateErr() (ConnectionState, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConnectionStateErr")
+	ret0, _ := ret[0].(ConnectionState)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConnectionStateErr indicates an expected call of ConnectionStateErr.
+func (mr *MockQuicSessionMockRecorder) ConnectionStateErr() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectionStateErr", reflect.TypeOf((*MockQuicSession)(nil).ConnectionStateErr))
+}
+
+// ConnectionStats mocks base method.
+func (m *MockQuicSession) ConnectionStats() ConnectionStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConnectionStats")
+	ret0, _ := ret[0].(ConnectionStats)
+	return ret0
+}
+
+// ConnectionStats indicates an expected call of ConnectionStats.
+func (mr *MockQuicSessionMockRecorder) ConnectionStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectionStats", reflect.TypeOf((*MockQuicSession)(nil).ConnectionStats))
+}
+
 // Context mocks base method.
 func (m *MockQuicSession) Context() context.Context {
 	m.ctrl.T.Helper()
@@ -136,6 +194,34 @@ func (mr *MockQuicSessionMockRecorder) HandshakeComplete() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeComplete", reflect.TypeOf((*MockQuicSession)(nil).HandshakeComplete))
 }
 
+// HandshakeConfirmed mocks base method.
+func (m *MockQuicSession) HandshakeConfirmed() context.Context {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandshakeConfirmed")
+	ret0, _ := ret[0].(context.Context)
+	return ret0
+}
+
+// HandshakeConfirmed indicates an expected call of HandshakeConfirmed.
+func (mr *MockQuicSessionMockRecorder) HandshakeConfirmed() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeConfirmed", reflect.TypeOf((*MockQuicSession)(nil).HandshakeConfirmed))
+}
+
+// InitiateKeyUpdate mocks base method.
+func (m *MockQuicSession) InitiateKeyUpdate() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InitiateKeyUpdate")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InitiateKeyUpdate indicates an expected call of InitiateKeyUpdate.
+func (mr *MockQuicSessionMockRecorder) InitiateKeyUpdate() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitiateKeyUpdate", reflect.TypeOf((*MockQuicSession)(nil).InitiateKeyUpdate))
+}
+
 // LocalAddr mocks base method.
 func (m *MockQuicSession) LocalAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -150,6 +236,20 @@ func (mr *MockQuicSessionMockRecorder) LocalAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LocalAddr", reflect.TypeOf((*MockQuicSession)(nil).LocalAddr))
 }
 
+// MigrateTo mocks base method.
+func (m *MockQuicSession) MigrateTo(arg0 net.PacketConn) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MigrateTo", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MigrateTo indicates an expected call of MigrateTo.
+func (mr *MockQuicSessionMockRecorder) MigrateTo(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigrateTo", reflect.TypeOf((*MockQuicSession)(nil).MigrateTo), arg0)
+}
+
 // NextSession mocks base method.
 func (m *MockQuicSession) NextSession() Session {
 	m.ctrl.T.Helper()
@@ -224,19 +324,33 @@ func (mr *MockQuicSessionMockRecorder) OpenUniStreamSync(arg0 interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenUniStreamSync", reflect.TypeOf((*MockQuicSession)(nil).OpenUniStreamSync), arg0)
 }
 
-// ReceiveMessage mocks base method.
-func (m *MockQuicSession) ReceiveMessage() ([]byte, error) {
+// RTTStats mocks base method.
+func (m *MockQuicSession) RTTStats() RTTStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RTTStats")
+	ret0, _ := ret[0].(RTTStats)
+	return ret0
+}
+
+// RTTStats indicates an expected call of RTTStats.
+func (mr *MockQuicSessionMockRecorder) RTTStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RTTStats", reflect.TypeOf((*MockQuicSession)(nil).RTTStats))
+}
+
+// ReceiveDatagram mocks base method.
+func (m *MockQuicSession) ReceiveDatagram(arg0 context.Context) ([]byte, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ReceiveMessage")
+	ret := m.ctrl.Call(m, "ReceiveDatagram", arg0)
 	ret0, _ := ret[0].([]byte)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ReceiveMessage indicates an expected call of ReceiveMessage.
-func (mr *MockQuicSessionMockRecorder) ReceiveMessage() *gomock.Call {
+// ReceiveDatagram indicates an expected call of ReceiveDatagram.
+func (mr *MockQuicSessionMockRecorder) ReceiveDatagram(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceiveMessage", reflect.TypeOf((*MockQuicSession)(nil).ReceiveMessage))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceiveDatagram", reflect.TypeOf((*MockQuicSession)(nil).ReceiveDatagram), arg0)
 }
 
 // RemoteAddr mocks base method.
@@ -253,18 +367,84 @@ func (mr *MockQuicSessionMockRecorder) RemoteAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteAddr", reflect.TypeOf((*MockQuicSession)(nil).RemoteAddr))
 }
 
-// SendMessage mocks base method.
-func (m *MockQuicSession) SendMessage(arg0 []byte) error {
+// RotateConnectionID mocks base method.
+func (m *MockQuicSession) RotateConnectionID() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateConnectionID")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RotateConnectionID indicates an expected call of RotateConnectionID.
+func (mr *MockQuicSessionMockRecorder) RotateConnectionID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateConnectionID", reflect.TypeOf((*MockQuicSession)(nil).RotateConnectionID))
+}
+
+// SendDatagram mocks base method.
+func (m *MockQuicSession) SendDatagram(arg0 []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendDatagram", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendDatagram indicates an expected call of SendDatagram.
+func (mr *MockQuicSessionMockRecorder) SendDatagram(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendDatagram", reflect.TypeOf((*MockQuicSession)(nil).SendDatagram), arg0)
+}
+
+// SetCongestionControl mocks base method.
+func (m *MockQuicSession) SetCongestionControl(arg0 congestion.CongestionOptions) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SendMessage", arg0)
+	ret := m.ctrl.Call(m, "SetCongestionControl", arg0)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// SendMessage indicates an expected call of SendMessage.
-func (mr *MockQuicSessionMockRecorder) SendMessage(arg0 interface{}) *gomock.Call {
+// SetCongestionControl indicates an expected call of SetCongestionControl.
+func (mr *MockQuicSessionMockRecorder) SetCongestionControl(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCongestionControl", reflect.TypeOf((*MockQuicSession)(nil).SetCongestionControl), arg0)
+}
+
+// SetMaxBytesInFlight mocks base method.
+func (m *MockQuicSession) SetMaxBytesInFlight(arg0 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetMaxBytesInFlight", arg0)
+}
+
+// SetMaxBytesInFlight indicates an expected call of SetMaxBytesInFlight.
+func (mr *MockQuicSessionMockRecorder) SetMaxBytesInFlight(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxBytesInFlight", reflect.TypeOf((*MockQuicSession)(nil).SetMaxBytesInFlight), arg0)
+}
+
+// SetSendRateLimit mocks base method.
+func (m *MockQuicSession) SetSendRateLimit(arg0 int64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetSendRateLimit", arg0)
+}
+
+// SetSendRateLimit indicates an expected call of SetSendRateLimit.
+func (mr *MockQuicSessionMockRecorder) SetSendRateLimit(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSendRateLimit", reflect.TypeOf((*MockQuicSession)(nil).SetSendRateLimit), arg0)
+}
+
+// StreamStats mocks base method.
+func (m *MockQuicSession) StreamStats() []StreamStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamStats")
+	ret0, _ := ret[0].([]StreamStats)
+	return ret0
+}
+
+// StreamStats indicates an expected call of StreamStats.
+func (mr *MockQuicSessionMockRecorder) StreamStats() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessage", reflect.TypeOf((*MockQuicSession)(nil).SendMessage), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamStats", reflect.TypeOf((*MockQuicSession)(nil).StreamStats))
 }
 
 // destroy mocks base method.
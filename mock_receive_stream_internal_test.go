@@ -144,3 +144,17 @@ func (mr *MockReceiveStreamIMockRecorder) handleStreamFrame(arg0 interface{}) *g
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleStreamFrame", reflect.TypeOf((*MockReceiveStreamI)(nil).handleStreamFrame), arg0)
 }
+
+// stats mocks base method.
+func (m *MockReceiveStreamI) stats() StreamStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "stats")
+	ret0, _ := ret[0].(StreamStats)
+	return ret0
+}
+
+// stats indicates an expected call of stats.
+func (mr *MockReceiveStreamIMockRecorder) stats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "stats", reflect.TypeOf((*MockReceiveStreamI)(nil).stats))
+}
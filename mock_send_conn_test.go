@@ -9,6 +9,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
 // MockSendConn is a mock of SendConn interface.
@@ -76,16 +77,44 @@ func (mr *MockSendConnMockRecorder) RemoteAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteAddr", reflect.TypeOf((*MockSendConn)(nil).RemoteAddr))
 }
 
+// SupportsGSO mocks base method.
+func (m *MockSendConn) SupportsGSO() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SupportsGSO")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// SupportsGSO indicates an expected call of SupportsGSO.
+func (mr *MockSendConnMockRecorder) SupportsGSO() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SupportsGSO", reflect.TypeOf((*MockSendConn)(nil).SupportsGSO))
+}
+
 // Write mocks base method.
-func (m *MockSendConn) Write(arg0 []byte) error {
+func (m *MockSendConn) Write(arg0 []byte, arg1 protocol.ECN) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Write", arg0)
+	ret := m.ctrl.Call(m, "Write", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Write indicates an expected call of Write.
-func (mr *MockSendConnMockRecorder) Write(arg0 interface{}) *gomock.Call {
+func (mr *MockSendConnMockRecorder) Write(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockSendConn)(nil).Write), arg0, arg1)
+}
+
+// WriteGSO mocks base method.
+func (m *MockSendConn) WriteGSO(arg0 []byte, arg1 uint16, arg2 protocol.ECN) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteGSO", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteGSO indicates an expected call of WriteGSO.
+func (mr *MockSendConnMockRecorder) WriteGSO(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockSendConn)(nil).Write), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteGSO", reflect.TypeOf((*MockSendConn)(nil).WriteGSO), arg0, arg1, arg2)
 }
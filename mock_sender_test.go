@@ -8,6 +8,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
 // MockSender is a mock of Sender interface.
@@ -74,15 +75,15 @@ func (mr *MockSenderMockRecorder) Run() *gomock.Call {
 }
 
 // Send mocks base method.
-func (m *MockSender) Send(p *packetBuffer) {
+func (m *MockSender) Send(p *packetBuffer, ecn protocol.ECN) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "Send", p)
+	m.ctrl.Call(m, "Send", p, ecn)
 }
 
 // Send indicates an expected call of Send.
-func (mr *MockSenderMockRecorder) Send(p interface{}) *gomock.Call {
+func (mr *MockSenderMockRecorder) Send(p, ecn interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockSender)(nil).Send), p)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockSender)(nil).Send), p, ecn)
 }
 
 // WouldBlock mocks base method.
@@ -119,6 +119,18 @@ func (mr *MockStreamIMockRecorder) SetDeadline(t interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDeadline", reflect.TypeOf((*MockStreamI)(nil).SetDeadline), t)
 }
 
+// SetPriority mocks base method.
+func (m *MockStreamI) SetPriority(weight int, incremental bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPriority", weight, incremental)
+}
+
+// SetPriority indicates an expected call of SetPriority.
+func (mr *MockStreamIMockRecorder) SetPriority(weight, incremental interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPriority", reflect.TypeOf((*MockStreamI)(nil).SetPriority), weight, incremental)
+}
+
 // SetReadDeadline mocks base method.
 func (m *MockStreamI) SetReadDeadline(t time.Time) error {
 	m.ctrl.T.Helper()
@@ -271,6 +283,20 @@ func (mr *MockStreamIMockRecorder) popStreamFrame(maxBytes interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "popStreamFrame", reflect.TypeOf((*MockStreamI)(nil).popStreamFrame), maxBytes)
 }
 
+// stats mocks base method.
+func (m *MockStreamI) stats() StreamStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "stats")
+	ret0, _ := ret[0].(StreamStats)
+	return ret0
+}
+
+// stats indicates an expected call of stats.
+func (mr *MockStreamIMockRecorder) stats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "stats", reflect.TypeOf((*MockStreamI)(nil).stats))
+}
+
 // updateSendWindow mocks base method.
 func (m *MockStreamI) updateSendWindow(arg0 protocol.ByteCount) {
 	m.ctrl.T.Helper()
@@ -206,6 +206,20 @@ func (mr *MockStreamManagerMockRecorder) ResetFor0RTT() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetFor0RTT", reflect.TypeOf((*MockStreamManager)(nil).ResetFor0RTT))
 }
 
+// Stats mocks base method.
+func (m *MockStreamManager) Stats() []StreamStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats")
+	ret0, _ := ret[0].([]StreamStats)
+	return ret0
+}
+
+// Stats indicates an expected call of Stats.
+func (mr *MockStreamManagerMockRecorder) Stats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockStreamManager)(nil).Stats))
+}
+
 // UpdateLimits mocks base method.
 func (m *MockStreamManager) UpdateLimits(arg0 *wire.TransportParameters) {
 	m.ctrl.T.Helper()
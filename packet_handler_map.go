@@ -45,6 +45,11 @@ func (h *zeroRTTQueue) Clear() {
 	}
 }
 
+// zeroLengthConnIDKey is the key used to store the session that uses a zero-length connection ID with a peer.
+func zeroLengthConnIDKey(remoteAddr string) protocol.ConnectionID {
+	return protocol.ConnectionID("remote:" + remoteAddr)
+}
+
 type packetHandlerMapEntry struct {
 	packetHandler packetHandler
 	is0RTTQueue   bool
@@ -54,6 +59,8 @@ type packetHandlerMapEntry struct {
 // It is used:
 // * by the server to store sessions
 // * when multiplexing outgoing connections to store clients
+// When using zero-length connection IDs, packets can't be demultiplexed on the connection ID.
+// Sessions are then identified by the remote address (see zeroLengthConnIDRunner).
 type packetHandlerMap struct {
 	mutex sync.Mutex
 
@@ -288,6 +295,10 @@ func (h *packetHandlerMap) CloseServer() {
 	wg.Wait()
 }
 
+func (h *packetHandlerMap) EnableLargePackets() {
+	h.conn.EnableLargePackets()
+}
+
 // Destroy closes the underlying connection and waits until listen() has returned.
 // It does not close active sessions.
 func (h *packetHandlerMap) Destroy() error {
@@ -357,7 +368,11 @@ func (h *packetHandlerMap) handlePacket(p *receivedPacket) {
 		return
 	}
 
-	if entry, ok := h.handlers[string(connID)]; ok {
+	key := string(connID)
+	if connID.Len() == 0 {
+		key = string(zeroLengthConnIDKey(p.remoteAddr.String()))
+	}
+	if entry, ok := h.handlers[key]; ok {
 		if entry.is0RTTQueue { // only enqueue 0-RTT packets in the 0-RTT queue
 			if wire.Is0RTTPacket(p.data) {
 				entry.packetHandler.handlePacket(p)
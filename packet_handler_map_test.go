@@ -150,6 +150,31 @@ var _ = Describe("Packet Handler Map", func() {
 				Eventually(handledPacket2).Should(BeClosed())
 			})
 
+			It("routes packets for zero-length connection IDs on the remote address", func() {
+				handler.connIDLen = 0
+				addr1 := &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234}
+				addr2 := &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 4321}
+				packetHandler1 := NewMockPacketHandler(mockCtrl)
+				packetHandler2 := NewMockPacketHandler(mockCtrl)
+				handledPacket1 := make(chan struct{})
+				handledPacket2 := make(chan struct{})
+				packetHandler1.EXPECT().handlePacket(gomock.Any()).Do(func(p *receivedPacket) {
+					Expect(p.remoteAddr).To(Equal(addr1))
+					close(handledPacket1)
+				})
+				packetHandler2.EXPECT().handlePacket(gomock.Any()).Do(func(p *receivedPacket) {
+					Expect(p.remoteAddr).To(Equal(addr2))
+					close(handledPacket2)
+				})
+				Expect(newZeroLengthConnIDRunner(handler, addr1).Add(protocol.ConnectionID{}, packetHandler1)).To(BeTrue())
+				Expect(newZeroLengthConnIDRunner(handler, addr2).Add(protocol.ConnectionID{}, packetHandler2)).To(BeTrue())
+				shortHeaderPacket := append([]byte{0x40}, make([]byte, 20)...)
+				packetChan <- packetToRead{addr: addr1, data: shortHeaderPacket}
+				packetChan <- packetToRead{addr: addr2, data: shortHeaderPacket}
+				Eventually(handledPacket1).Should(BeClosed())
+				Eventually(handledPacket2).Should(BeClosed())
+			})
+
 			It("drops unparseable packets", func() {
 				addr := &net.UDPAddr{IP: net.IPv4(9, 8, 7, 6), Port: 1234}
 				tracer.EXPECT().DroppedPacket(addr, logging.PacketTypeNotDetermined, protocol.ByteCount(4), logging.PacketDropHeaderParseError)
@@ -429,7 +454,10 @@ var _ = Describe("Packet Handler Map", func() {
 					packetHandler.EXPECT().destroy(gomock.Any()).Do(func(error) {
 						close(done)
 					}).AnyTimes()
-					packetChan <- packetToRead{data: append([]byte{0x40} /* short header packet */, token[:15]...)}
+					packetChan <- packetToRead{
+						addr: &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337},
+						data: append([]byte{0x40} /* short header packet */, token[:15]...),
+					}
 					Consistently(done).ShouldNot(BeClosed())
 				})
 			})
@@ -447,6 +475,36 @@ var _ = Describe("Packet Handler Map", func() {
 					Expect(handler.GetStatelessResetToken(connID1)).ToNot(Equal(handler.GetStatelessResetToken(connID2)))
 				})
 
+				newHandlerWithKey := func(key []byte) *packetHandlerMap {
+					conn := NewMockPacketConn(mockCtrl)
+					conn.EXPECT().LocalAddr().Return(&net.UDPAddr{}).AnyTimes()
+					conn.EXPECT().ReadFrom(gomock.Any()).Return(0, nil, errors.New("closed")).AnyTimes()
+					phm, err := newPacketHandlerMap(conn, connIDLen, key, nil, utils.DefaultLogger)
+					Expect(err).ToNot(HaveOccurred())
+					return phm.(*packetHandlerMap)
+				}
+
+				It("generates the same stateless reset tokens when using the same key", func() {
+					connID := []byte{0xde, 0xad, 0xbe, 0xef}
+					token := handler.GetStatelessResetToken(connID)
+					Expect(newHandlerWithKey(statelessResetKey).GetStatelessResetToken(connID)).To(Equal(token))
+					otherKey := make([]byte, 32)
+					rand.Read(otherKey)
+					Expect(newHandlerWithKey(otherKey).GetStatelessResetToken(connID)).ToNot(Equal(token))
+				})
+
+				It("derives stateless reset tokens from the key", func() {
+					// The derivation must not change, otherwise servers restarted with a new version
+					// wouldn't be able to reset connections of the old version.
+					key := make([]byte, 32)
+					for i := range key {
+						key[i] = byte(i)
+					}
+					Expect(newHandlerWithKey(key).GetStatelessResetToken([]byte{0xde, 0xad, 0xbe, 0xef})).To(Equal(protocol.StatelessResetToken{
+						0x50, 0x0b, 0xa3, 0x75, 0x91, 0x03, 0x10, 0xf6, 0xef, 0xf7, 0xde, 0x3d, 0x6a, 0x42, 0xdf, 0x5d,
+					}))
+				})
+
 				It("sends stateless resets", func() {
 					addr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}
 					p := append([]byte{40}, make([]byte, 100)...)
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sync/atomic"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/ackhandler"
@@ -25,9 +26,22 @@ type packer interface {
 
 	SetMaxPacketSize(protocol.ByteCount)
 	PackMTUProbePacket(ping ackhandler.Frame, size protocol.ByteCount) (*packedPacket, error)
+	PackPathProbePacket(f ackhandler.Frame, size protocol.ByteCount) (*packedPacket, error)
 
 	HandleTransportParameters(*wire.TransportParameters)
 	SetToken([]byte)
+	SetSpinBit(bool)
+	SetVersion(protocol.VersionNumber)
+
+	BlockedFrameCounts() blockedFrameCounts
+}
+
+// blockedFrameCounts counts the DATA_BLOCKED, STREAM_DATA_BLOCKED and STREAMS_BLOCKED frames packed,
+// including retransmissions.
+type blockedFrameCounts struct {
+	DataBlocked       uint64
+	StreamDataBlocked uint64
+	StreamsBlocked    uint64
 }
 
 type sealer interface {
@@ -111,6 +125,15 @@ func (p *packetContents) ToAckHandlerPacket(now time.Time, q *retransmissionQueu
 	}
 }
 
+// getInitialPacketSize returns the max packet size used before Path MTU Discovery raises it.
+// Unless it was configured, it is determined from the remote address.
+func getInitialPacketSize(addr net.Addr, configured uint16) protocol.ByteCount {
+	if configured != 0 {
+		return protocol.ByteCount(configured)
+	}
+	return getMaxPacketSize(addr)
+}
+
 func getMaxPacketSize(addr net.Addr) protocol.ByteCount {
 	maxSize := protocol.ByteCount(protocol.MinInitialPacketSize)
 	// If this is not a UDP address, we don't know anything about the MTU.
@@ -147,7 +170,15 @@ type ackFrameSource interface {
 	GetAckFrame(encLevel protocol.EncryptionLevel, onlyIfQueued bool) *wire.AckFrame
 }
 
+// paddingBytes is used to write PADDING frames, without allocating a new slice for every packet.
+// Packet buffers are reused, so the padding has to be written explicitly.
+var paddingBytes [protocol.MaxLargePacketBufferSize]byte
+
 type packetPacker struct {
+	// accessed atomically, since they are read from outside the run loop.
+	// This must be the first field, so that the uint64s are 64-bit aligned on 32-bit platforms.
+	blockedFrames blockedFrameCounts
+
 	srcConnID     protocol.ConnectionID
 	getDestConnID func() protocol.ConnectionID
 
@@ -158,7 +189,8 @@ type packetPacker struct {
 	initialStream   cryptoStream
 	handshakeStream cryptoStream
 
-	token []byte
+	token   []byte
+	spinBit bool
 
 	pnManager           packetNumberManager
 	framer              frameSource
@@ -167,7 +199,9 @@ type packetPacker struct {
 	retransmissionQueue *retransmissionQueue
 
 	maxPacketSize          protocol.ByteCount
+	disable1RTTCoalescing  bool
 	numNonAckElicitingAcks int
+	datagramInLastPacket   bool
 }
 
 var _ packer = &packetPacker{}
@@ -179,7 +213,8 @@ func newPacketPacker(
 	handshakeStream cryptoStream,
 	packetNumberManager packetNumberManager,
 	retransmissionQueue *retransmissionQueue,
-	remoteAddr net.Addr, // only used for determining the max packet size
+	maxPacketSize protocol.ByteCount,
+	disable1RTTCoalescing bool,
 	cryptoSetup sealingManager,
 	framer frameSource,
 	acks ackFrameSource,
@@ -188,19 +223,20 @@ func newPacketPacker(
 	version protocol.VersionNumber,
 ) *packetPacker {
 	return &packetPacker{
-		cryptoSetup:         cryptoSetup,
-		getDestConnID:       getDestConnID,
-		srcConnID:           srcConnID,
-		initialStream:       initialStream,
-		handshakeStream:     handshakeStream,
-		retransmissionQueue: retransmissionQueue,
-		datagramQueue:       datagramQueue,
-		perspective:         perspective,
-		version:             version,
-		framer:              framer,
-		acks:                acks,
-		pnManager:           packetNumberManager,
-		maxPacketSize:       getMaxPacketSize(remoteAddr),
+		cryptoSetup:           cryptoSetup,
+		getDestConnID:         getDestConnID,
+		srcConnID:             srcConnID,
+		initialStream:         initialStream,
+		handshakeStream:       handshakeStream,
+		retransmissionQueue:   retransmissionQueue,
+		datagramQueue:         datagramQueue,
+		perspective:           perspective,
+		version:               version,
+		framer:                framer,
+		acks:                  acks,
+		pnManager:             packetNumberManager,
+		maxPacketSize:         maxPacketSize,
+		disable1RTTCoalescing: disable1RTTCoalescing,
 	}
 }
 
@@ -289,7 +325,7 @@ func (p *packetPacker) packConnectionClose(
 		numPackets++
 	}
 	contents := make([]*packetContents, 0, numPackets)
-	buffer := getPacketBuffer()
+	buffer := getPacketBufferForSize(p.maxPacketSize)
 	for i, encLevel := range encLevels {
 		if sealers[i] == nil {
 			continue
@@ -410,7 +446,13 @@ func (p *packetPacker) PackCoalescedPacket() (*coalescedPacket, error) {
 	// Add a 0-RTT / 1-RTT packet.
 	var appDataSealer sealer
 	appDataEncLevel := protocol.Encryption1RTT
-	if size < maxPacketSize-protocol.MinCoalescedPacketSize {
+	coalesceAppData := true
+	if p.disable1RTTCoalescing && numPackets > 0 {
+		// 0-RTT packets are still coalesced, 1-RTT packets are sent in a separate datagram
+		_, err := p.cryptoSetup.Get1RTTSealer()
+		coalesceAppData = err != nil
+	}
+	if coalesceAppData && size < maxPacketSize-protocol.MinCoalescedPacketSize {
 		var err error
 		appDataSealer, appDataHdr, appDataPayload = p.maybeGetAppDataPacket(maxPacketSize-size, size)
 		if err != nil {
@@ -431,7 +473,7 @@ func (p *packetPacker) PackCoalescedPacket() (*coalescedPacket, error) {
 		return nil, nil
 	}
 
-	buffer := getPacketBuffer()
+	buffer := getPacketBufferForSize(p.maxPacketSize)
 	packet := &coalescedPacket{
 		buffer:  buffer,
 		packets: make([]*packetContents, 0, numPackets),
@@ -468,7 +510,7 @@ func (p *packetPacker) PackPacket() (*packedPacket, error) {
 	if payload == nil {
 		return nil, nil
 	}
-	buffer := getPacketBuffer()
+	buffer := getPacketBufferForSize(p.maxPacketSize)
 	encLevel := protocol.Encryption1RTT
 	if hdr.IsLongHeader {
 		encLevel = protocol.Encryption0RTT
@@ -594,8 +636,13 @@ func (p *packetPacker) maybeGetAppDataPacketWithEncLevel(maxPayloadSize protocol
 func (p *packetPacker) composeNextPacket(maxFrameSize protocol.ByteCount, ackAllowed bool) *payload {
 	payload := &payload{frames: make([]ackhandler.Frame, 0, 1)}
 
+	hasData := p.framer.HasData()
+	hasRetransmission := p.retransmissionQueue.HasAppData()
+
 	var hasDatagram bool
-	if p.datagramQueue != nil {
+	// If the last packet contained a DATAGRAM frame, give precedence to stream data and retransmissions.
+	// Otherwise a flood of DATAGRAMs would starve the streams.
+	if p.datagramQueue != nil && (!p.datagramInLastPacket || (!hasData && !hasRetransmission)) {
 		if datagram := p.datagramQueue.Get(); datagram != nil {
 			payload.frames = append(payload.frames, ackhandler.Frame{
 				Frame: datagram,
@@ -606,10 +653,9 @@ func (p *packetPacker) composeNextPacket(maxFrameSize protocol.ByteCount, ackAll
 			hasDatagram = true
 		}
 	}
+	p.datagramInLastPacket = hasDatagram
 
 	var ack *wire.AckFrame
-	hasData := p.framer.HasData()
-	hasRetransmission := p.retransmissionQueue.HasAppData()
 	// TODO: make sure ACKs are sent when a lot of DATAGRAMs are queued
 	if !hasDatagram && ackAllowed {
 		ack = p.acks.GetAckFrame(protocol.Encryption1RTT, !hasRetransmission && !hasData)
@@ -640,8 +686,10 @@ func (p *packetPacker) composeNextPacket(maxFrameSize protocol.ByteCount, ackAll
 
 	if hasData {
 		var lengthAdded protocol.ByteCount
+		numFrames := len(payload.frames)
 		payload.frames, lengthAdded = p.framer.AppendControlFrames(payload.frames, maxFrameSize-payload.length)
 		payload.length += lengthAdded
+		p.countBlockedFrames(payload.frames[numFrames:])
 
 		payload.frames, lengthAdded = p.framer.AppendStreamFrames(payload.frames, maxFrameSize-payload.length)
 		payload.length += lengthAdded
@@ -688,7 +736,7 @@ func (p *packetPacker) MaybePackProbePacket(encLevel protocol.EncryptionLevel) (
 	if encLevel == protocol.EncryptionInitial {
 		padding = p.initialPaddingLen(payload.frames, size)
 	}
-	buffer := getPacketBuffer()
+	buffer := getPacketBufferForSize(p.maxPacketSize)
 	cont, err := p.appendPacket(buffer, hdr, payload, padding, encLevel, sealer, false)
 	if err != nil {
 		return nil, err
@@ -700,22 +748,35 @@ func (p *packetPacker) MaybePackProbePacket(encLevel protocol.EncryptionLevel) (
 }
 
 func (p *packetPacker) PackMTUProbePacket(ping ackhandler.Frame, size protocol.ByteCount) (*packedPacket, error) {
+	return p.packPaddedPacket(ping, size, true)
+}
+
+// PackPathProbePacket packs a packet containing a PATH_CHALLENGE or a PATH_RESPONSE frame, padded to size.
+// It is sent on a path that is not (or not yet) the path used for all other packets.
+func (p *packetPacker) PackPathProbePacket(f ackhandler.Frame, size protocol.ByteCount) (*packedPacket, error) {
+	return p.packPaddedPacket(f, size, false)
+}
+
+func (p *packetPacker) packPaddedPacket(f ackhandler.Frame, size protocol.ByteCount, isMTUProbePacket bool) (*packedPacket, error) {
 	payload := &payload{
-		frames: []ackhandler.Frame{ping},
-		length: ping.Length(p.version),
+		frames: []ackhandler.Frame{f},
+		length: f.Length(p.version),
 	}
-	buffer := getPacketBuffer()
+	buffer := getPacketBufferForSize(size)
 	sealer, err := p.cryptoSetup.Get1RTTSealer()
 	if err != nil {
 		return nil, err
 	}
 	hdr := p.getShortHeader(sealer.KeyPhase())
-	padding := size - p.packetLength(hdr, payload) - protocol.ByteCount(sealer.Overhead())
-	contents, err := p.appendPacket(buffer, hdr, payload, padding, protocol.Encryption1RTT, sealer, true)
+	var padding protocol.ByteCount
+	if l := p.packetLength(hdr, payload) + protocol.ByteCount(sealer.Overhead()); size > l {
+		padding = size - l
+	}
+	contents, err := p.appendPacket(buffer, hdr, payload, padding, protocol.Encryption1RTT, sealer, isMTUProbePacket)
 	if err != nil {
 		return nil, err
 	}
-	contents.isMTUProbePacket = true
+	contents.isMTUProbePacket = isMTUProbePacket
 	return &packedPacket{
 		buffer:         buffer,
 		packetContents: contents,
@@ -764,6 +825,7 @@ func (p *packetPacker) getShortHeader(kp protocol.KeyPhaseBit) *wire.ExtendedHea
 	hdr.PacketNumberLen = pnLen
 	hdr.DestConnectionID = p.getDestConnID()
 	hdr.KeyPhase = kp
+	hdr.SpinBit = p.spinBit
 	return hdr
 }
 
@@ -798,7 +860,7 @@ func (p *packetPacker) writeSinglePacket(
 	encLevel protocol.EncryptionLevel,
 	sealer sealer,
 ) (*packedPacket, error) {
-	buffer := getPacketBuffer()
+	buffer := getPacketBufferForSize(p.maxPacketSize)
 	var paddingLen protocol.ByteCount
 	if encLevel == protocol.EncryptionInitial {
 		paddingLen = p.initialPaddingLen(payload.frames, hdr.GetLength(p.version)+payload.length+protocol.ByteCount(sealer.Overhead()))
@@ -837,7 +899,7 @@ func (p *packetPacker) appendPacket(buffer *packetBuffer, header *wire.ExtendedH
 		}
 	}
 	if paddingLen > 0 {
-		buf.Write(make([]byte, paddingLen))
+		buf.Write(paddingBytes[:paddingLen])
 	}
 	for _, frame := range payload.frames {
 		if err := frame.Write(buf, p.version); err != nil {
@@ -880,9 +942,42 @@ func (p *packetPacker) SetToken(token []byte) {
 	p.token = token
 }
 
+// SetSpinBit sets the value of the spin bit sent in short header packets.
+func (p *packetPacker) SetSpinBit(spin bool) {
+	p.spinBit = spin
+}
+
+// SetVersion sets the version used for all packets packed from now on.
+// It is used when switching to a compatible version.
+func (p *packetPacker) SetVersion(v protocol.VersionNumber) {
+	p.version = v
+}
+
+func (p *packetPacker) countBlockedFrames(frames []ackhandler.Frame) {
+	for _, f := range frames {
+		switch f.Frame.(type) {
+		case *wire.DataBlockedFrame:
+			atomic.AddUint64(&p.blockedFrames.DataBlocked, 1)
+		case *wire.StreamDataBlockedFrame:
+			atomic.AddUint64(&p.blockedFrames.StreamDataBlocked, 1)
+		case *wire.StreamsBlockedFrame:
+			atomic.AddUint64(&p.blockedFrames.StreamsBlocked, 1)
+		}
+	}
+}
+
+func (p *packetPacker) BlockedFrameCounts() blockedFrameCounts {
+	return blockedFrameCounts{
+		DataBlocked:       atomic.LoadUint64(&p.blockedFrames.DataBlocked),
+		StreamDataBlocked: atomic.LoadUint64(&p.blockedFrames.StreamDataBlocked),
+		StreamsBlocked:    atomic.LoadUint64(&p.blockedFrames.StreamsBlocked),
+	}
+}
+
 // When a higher MTU is discovered, use it.
+// The size is limited to the size of the largest packet buffer.
 func (p *packetPacker) SetMaxPacketSize(s protocol.ByteCount) {
-	p.maxPacketSize = s
+	p.maxPacketSize = utils.MinByteCount(s, protocol.MaxLargePacketBufferSize)
 }
 
 // If the peer sets a max_packet_size that's smaller than the size we're currently using,
@@ -91,7 +91,7 @@ var _ = Describe("Packet packer", func() {
 		ackFramer = NewMockAckFrameSource(mockCtrl)
 		sealingManager = NewMockSealingManager(mockCtrl)
 		pnManager = mockackhandler.NewMockSentPacketHandler(mockCtrl)
-		datagramQueue = newDatagramQueue(func() {}, utils.DefaultLogger)
+		datagramQueue = newDatagramQueue(func() {}, 0, utils.DefaultLogger)
 
 		packer = newPacketPacker(
 			protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8},
@@ -100,7 +100,8 @@ var _ = Describe("Packet packer", func() {
 			handshakeStream,
 			pnManager,
 			retransmissionQueue,
-			&net.TCPAddr{},
+			maxPacketSize,
+			false,
 			sealingManager,
 			framer,
 			ackFramer,
@@ -109,7 +110,6 @@ var _ = Describe("Packet packer", func() {
 			version,
 		)
 		packer.version = version
-		packer.maxPacketSize = maxPacketSize
 	})
 
 	Context("determining the maximum packet size", func() {
@@ -127,6 +127,12 @@ var _ = Describe("Packet packer", func() {
 			addr := &net.UDPAddr{IP: ip, Port: 1337}
 			Expect(getMaxPacketSize(addr)).To(BeEquivalentTo(protocol.InitialPacketSizeIPv6))
 		})
+
+		It("uses the configured initial packet size", func() {
+			addr := &net.UDPAddr{IP: net.IPv4(11, 12, 13, 14), Port: 1337}
+			Expect(getInitialPacketSize(addr, 0)).To(BeEquivalentTo(protocol.InitialPacketSizeIPv4))
+			Expect(getInitialPacketSize(addr, 1300)).To(BeEquivalentTo(1300))
+		})
 	})
 
 	Context("generating a packet header", func() {
@@ -157,6 +163,15 @@ var _ = Describe("Packet packer", func() {
 			Expect(h.PacketNumber).To(Equal(protocol.PacketNumber(0x1337)))
 			Expect(h.PacketNumberLen).To(Equal(protocol.PacketNumberLen4))
 			Expect(h.KeyPhase).To(Equal(protocol.KeyPhaseOne))
+			Expect(h.SpinBit).To(BeFalse())
+		})
+
+		It("sets the spin bit in short headers", func() {
+			pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x1337), protocol.PacketNumberLen4).Times(2)
+			packer.SetSpinBit(true)
+			Expect(packer.getShortHeader(protocol.KeyPhaseZero).SpinBit).To(BeTrue())
+			packer.SetSpinBit(false)
+			Expect(packer.getShortHeader(protocol.KeyPhaseZero).SpinBit).To(BeFalse())
 		})
 	})
 
@@ -553,6 +568,33 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.buffer.Len()).ToNot(BeZero())
 			})
 
+			It("counts the BLOCKED frames it packs", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2).Times(2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42)).Times(2)
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil).Times(2)
+				framer.EXPECT().HasData().Return(true).Times(2)
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, false).Times(2)
+				Expect(packer.BlockedFrameCounts()).To(BeZero())
+				expectAppendControlFrames(
+					ackhandler.Frame{Frame: &wire.DataBlockedFrame{MaximumData: 1000}},
+					ackhandler.Frame{Frame: &wire.StreamDataBlockedFrame{StreamID: 4, MaximumStreamData: 100}},
+					ackhandler.Frame{Frame: &wire.StreamDataBlockedFrame{StreamID: 8, MaximumStreamData: 200}},
+					ackhandler.Frame{Frame: &wire.MaxDataFrame{}},
+				)
+				expectAppendStreamFrames()
+				_, err := packer.PackPacket()
+				Expect(err).ToNot(HaveOccurred())
+				expectAppendControlFrames(ackhandler.Frame{Frame: &wire.StreamsBlockedFrame{Type: protocol.StreamTypeBidi, StreamLimit: 10}})
+				expectAppendStreamFrames()
+				_, err = packer.PackPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(packer.BlockedFrameCounts()).To(Equal(blockedFrameCounts{
+					DataBlocked:       1,
+					StreamDataBlocked: 2,
+					StreamsBlocked:    1,
+				}))
+			})
+
 			It("packs DATAGRAM frames", func() {
 				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
 				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
@@ -580,6 +622,54 @@ var _ = Describe("Packet packer", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			It("doesn't send DATAGRAM frames in consecutive packets if there's stream data", func() {
+				packer.datagramQueue = newDatagramQueue(func() {}, 10, utils.DefaultLogger)
+				for i := 0; i < 3; i++ {
+					Expect(packer.datagramQueue.Add(&wire.DatagramFrame{DataLenPresent: true, Data: make([]byte, 1000)})).To(Succeed())
+				}
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2).Times(3)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42)).Times(3)
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil).Times(3)
+				framer.EXPECT().HasData().Return(true).Times(3)
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, false)
+				sf := &wire.StreamFrame{StreamID: 5, Data: []byte("foobar"), DataLenPresent: true}
+				for i := 0; i < 3; i++ {
+					expectAppendControlFrames()
+					expectAppendStreamFrames(ackhandler.Frame{Frame: sf})
+				}
+				p, err := packer.PackPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.frames).To(HaveLen(2))
+				Expect(p.frames[0].Frame).To(BeAssignableToTypeOf(&wire.DatagramFrame{}))
+				// the next packet only contains stream data
+				p, err = packer.PackPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.frames).To(HaveLen(1))
+				Expect(p.frames[0].Frame).To(Equal(sf))
+				p, err = packer.PackPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.frames).To(HaveLen(2))
+				Expect(p.frames[0].Frame).To(BeAssignableToTypeOf(&wire.DatagramFrame{}))
+				Expect(packer.datagramQueue.QueueLen()).To(Equal(1))
+			})
+
+			It("sends DATAGRAM frames in consecutive packets if there's no stream data", func() {
+				packer.datagramQueue = newDatagramQueue(func() {}, 10, utils.DefaultLogger)
+				for i := 0; i < 2; i++ {
+					Expect(packer.datagramQueue.Add(&wire.DatagramFrame{DataLenPresent: true, Data: make([]byte, 1000)})).To(Succeed())
+				}
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2).Times(2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42)).Times(2)
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil).Times(2)
+				framer.EXPECT().HasData().Times(2)
+				for i := 0; i < 2; i++ {
+					p, err := packer.PackPacket()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(p.frames).To(HaveLen(1))
+					Expect(p.frames[0].Frame).To(BeAssignableToTypeOf(&wire.DatagramFrame{}))
+				}
+			})
+
 			It("accounts for the space consumed by control frames", func() {
 				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
 				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
@@ -631,7 +721,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(secondPayloadByte).To(Equal(byte(0)))
 				// ... followed by the PING
-				frameParser := wire.NewFrameParser(false, packer.version)
+				frameParser := wire.NewFrameParser(false, false, packer.version)
 				frame, err := frameParser.ParseNext(r, protocol.Encryption1RTT)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(frame).To(BeAssignableToTypeOf(&wire.PingFrame{}))
@@ -668,7 +758,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(firstPayloadByte).To(Equal(byte(0)))
 				// ... followed by the STREAM frame
-				frameParser := wire.NewFrameParser(true, packer.version)
+				frameParser := wire.NewFrameParser(true, false, packer.version)
 				frame, err := frameParser.ParseNext(r, protocol.Encryption1RTT)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(frame).To(BeAssignableToTypeOf(&wire.StreamFrame{}))
@@ -893,6 +983,28 @@ var _ = Describe("Packet packer", func() {
 					_, err = packer.PackPacket()
 					Expect(err).ToNot(HaveOccurred())
 				})
+
+				It("packs packets larger than the default packet size", func() {
+					packer.SetMaxPacketSize(8000)
+					pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+					pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+					sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+					framer.EXPECT().HasData().Return(true)
+					ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, false)
+					expectAppendControlFrames()
+					f := &wire.StreamFrame{StreamID: 5, Data: make([]byte, 7000), DataLenPresent: true}
+					expectAppendStreamFrames(ackhandler.Frame{Frame: f})
+					p, err := packer.PackPacket()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(p.buffer.Data).To(HaveLen(int(p.length)))
+					Expect(p.length).To(BeNumerically(">", 7000))
+					Expect(p.buffer.Data).To(HaveCap(int(protocol.MaxLargePacketBufferSize)))
+				})
+
+				It("doesn't increase the packet size beyond the size of the largest packet buffer", func() {
+					packer.SetMaxPacketSize(20000)
+					Expect(packer.maxPacketSize).To(Equal(protocol.MaxLargePacketBufferSize))
+				})
 			})
 		})
 
@@ -1167,6 +1279,76 @@ var _ = Describe("Packet packer", func() {
 				Expect(rest).To(BeEmpty())
 			})
 
+			It("doesn't coalesce 1-RTT packets with Handshake packets, if disabled", func() {
+				packer.disable1RTTCoalescing = true
+				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(0x24), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(0x24))
+				sealingManager.EXPECT().GetInitialSealer().Return(nil, handshake.ErrKeysDropped)
+				sealingManager.EXPECT().GetHandshakeSealer().Return(getSealer(), nil)
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				ackFramer.EXPECT().GetAckFrame(protocol.EncryptionHandshake, false)
+				handshakeStream.EXPECT().HasData().Return(true).Times(2)
+				handshakeStream.EXPECT().PopCryptoFrame(gomock.Any()).DoAndReturn(func(size protocol.ByteCount) *wire.CryptoFrame {
+					return &wire.CryptoFrame{Offset: 0x1337, Data: []byte("handshake")}
+				})
+				// don't EXPECT any calls to the framer
+				p, err := packer.PackCoalescedPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.packets).To(HaveLen(1))
+				Expect(p.packets[0].EncryptionLevel()).To(Equal(protocol.EncryptionHandshake))
+				hdr, _, rest, err := wire.ParsePacket(p.buffer.Data, 0)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(hdr.Type).To(Equal(protocol.PacketTypeHandshake))
+				Expect(rest).To(BeEmpty())
+			})
+
+			It("still coalesces 0-RTT packets with Initial packets, if 1-RTT coalescing is disabled", func() {
+				packer.disable1RTTCoalescing = true
+				packer.perspective = protocol.PerspectiveClient
+				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24))
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption0RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption0RTT).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().GetInitialSealer().Return(getSealer(), nil)
+				sealingManager.EXPECT().GetHandshakeSealer().Return(nil, handshake.ErrKeysNotYetAvailable)
+				sealingManager.EXPECT().Get0RTTSealer().Return(getSealer(), nil)
+				sealingManager.EXPECT().Get1RTTSealer().Return(nil, handshake.ErrKeysNotYetAvailable).Times(2)
+				framer.EXPECT().HasData().Return(true)
+				ackFramer.EXPECT().GetAckFrame(protocol.EncryptionInitial, false)
+				initialStream.EXPECT().HasData().Return(true).Times(2)
+				initialStream.EXPECT().PopCryptoFrame(gomock.Any()).DoAndReturn(func(size protocol.ByteCount) *wire.CryptoFrame {
+					return &wire.CryptoFrame{Offset: 0x42, Data: []byte("initial")}
+				})
+				expectAppendControlFrames()
+				expectAppendStreamFrames(ackhandler.Frame{Frame: &wire.StreamFrame{Data: []byte("foobar")}})
+				p, err := packer.PackCoalescedPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.buffer.Len()).To(BeEquivalentTo(maxPacketSize))
+				Expect(p.packets).To(HaveLen(2))
+				Expect(p.packets[0].EncryptionLevel()).To(Equal(protocol.EncryptionInitial))
+				Expect(p.packets[1].EncryptionLevel()).To(Equal(protocol.Encryption0RTT))
+			})
+
+			It("pads an Initial packet if 1-RTT coalescing is disabled", func() {
+				packer.disable1RTTCoalescing = true
+				packer.perspective = protocol.PerspectiveClient
+				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24))
+				sealingManager.EXPECT().GetInitialSealer().Return(getSealer(), nil)
+				sealingManager.EXPECT().GetHandshakeSealer().Return(nil, handshake.ErrKeysNotYetAvailable)
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				ackFramer.EXPECT().GetAckFrame(protocol.EncryptionInitial, false)
+				initialStream.EXPECT().HasData().Return(true).Times(2)
+				initialStream.EXPECT().PopCryptoFrame(gomock.Any()).DoAndReturn(func(size protocol.ByteCount) *wire.CryptoFrame {
+					return &wire.CryptoFrame{Offset: 0x42, Data: []byte("initial")}
+				})
+				p, err := packer.PackCoalescedPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.packets).To(HaveLen(1))
+				Expect(p.packets[0].EncryptionLevel()).To(Equal(protocol.EncryptionInitial))
+				Expect(p.buffer.Len()).To(BeNumerically(">=", protocol.MinInitialPacketSize))
+			})
+
 			It("doesn't add a coalesced packet if the remaining size is smaller than MaxCoalescedPacketSize", func() {
 				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(0x24), protocol.PacketNumberLen2)
 				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(0x24))
@@ -1221,7 +1403,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(secondPayloadByte).To(Equal(byte(0)))
 				// ... followed by the PING
-				frameParser := wire.NewFrameParser(false, packer.version)
+				frameParser := wire.NewFrameParser(false, false, packer.version)
 				frame, err := frameParser.ParseNext(r, protocol.Encryption1RTT)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(frame).To(BeAssignableToTypeOf(&wire.PingFrame{}))
@@ -1319,6 +1501,24 @@ var _ = Describe("Packet packer", func() {
 				})
 			}
 
+			It("pads Initial packets to the configured initial packet size", func() {
+				const initialPacketSize = 1300
+				packer.maxPacketSize = getInitialPacketSize(&net.UDPAddr{IP: net.IPv4(11, 12, 13, 14)}, initialPacketSize)
+				packer.perspective = protocol.PerspectiveClient
+				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().GetInitialSealer().Return(getSealer(), nil)
+				sealingManager.EXPECT().GetHandshakeSealer().Return(nil, handshake.ErrKeysNotYetAvailable)
+				sealingManager.EXPECT().Get0RTTSealer().Return(nil, handshake.ErrKeysNotYetAvailable)
+				sealingManager.EXPECT().Get1RTTSealer().Return(nil, handshake.ErrKeysNotYetAvailable)
+				ackFramer.EXPECT().GetAckFrame(protocol.EncryptionInitial, false)
+				initialStream.EXPECT().HasData().Return(true).Times(2)
+				initialStream.EXPECT().PopCryptoFrame(gomock.Any()).Return(&wire.CryptoFrame{Data: []byte("foobar")})
+				p, err := packer.PackCoalescedPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.buffer.Len()).To(BeEquivalentTo(initialPacketSize))
+			})
+
 			It("adds an ACK frame", func() {
 				f := &wire.CryptoFrame{Data: []byte("foobar")}
 				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 42, Largest: 1337}}}
@@ -1494,6 +1694,44 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.buffer.Data).To(HaveLen(int(probePacketSize)))
 				Expect(p.packetContents.isMTUProbePacket).To(BeTrue())
 			})
+
+			It("packs an MTU probe packet larger than the default packet size", func() {
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x43), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x43))
+				ping := ackhandler.Frame{Frame: &wire.PingFrame{}}
+				p, err := packer.PackMTUProbePacket(ping, 8000)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.length).To(BeEquivalentTo(8000))
+				Expect(p.buffer.Data).To(HaveLen(8000))
+				Expect(p.buffer.Data).To(HaveCap(int(protocol.MaxLargePacketBufferSize)))
+			})
+
+			It("packs a path probe packet", func() {
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x43), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x43))
+				challenge := ackhandler.Frame{Frame: &wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}}
+				p, err := packer.PackPathProbePacket(challenge, protocol.MinInitialPacketSize)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.length).To(BeEquivalentTo(protocol.MinInitialPacketSize))
+				Expect(p.header.IsLongHeader).To(BeFalse())
+				Expect(p.frames).To(Equal([]ackhandler.Frame{challenge}))
+				Expect(p.buffer.Data).To(HaveLen(protocol.MinInitialPacketSize))
+				Expect(p.packetContents.isMTUProbePacket).To(BeFalse())
+			})
+
+			It("doesn't pad a path probe packet if it is larger than the requested size", func() {
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x43), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x43))
+				challenge := ackhandler.Frame{Frame: &wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}}
+				p, err := packer.PackPathProbePacket(challenge, 10)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.length).To(BeNumerically(">", 10))
+				Expect(p.buffer.Data).To(HaveLen(int(p.length)))
+				Expect(p.frames).To(Equal([]ackhandler.Frame{challenge}))
+			})
 		})
 	})
 })
@@ -0,0 +1,140 @@
+package qlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+)
+
+// A Combiner writes the qlogs of multiple connections into a single file, using the (non-streaming) JSON format.
+// Every connection is recorded as a separate trace, with its own vantage point.
+// This is useful when a process acts as the client and as the server of a connection:
+// Tools like qvis can then line up the client's and the server's trace.
+// The traces are buffered in memory, and the file is written once all traces are complete.
+type Combiner struct {
+	mutex sync.Mutex
+
+	w       io.WriteCloser
+	traces  []*combinedTrace
+	open    int  // the number of traces that were not closed yet
+	closing bool // set when Close is called
+	done    bool
+}
+
+// NewCombiner creates a Combiner that writes to w.
+func NewCombiner(w io.WriteCloser) *Combiner {
+	return &Combiner{w: w}
+}
+
+// Writer returns an io.WriteCloser for the qlog of a single connection.
+// It can be returned from the callback passed to NewTracer.
+func (c *Combiner) Writer() io.WriteCloser {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	t := &combinedTrace{combiner: c}
+	c.traces = append(c.traces, t)
+	c.open++
+	return t
+}
+
+// Close says that no more traces will be added.
+// The combined qlog is written as soon as all traces are closed.
+// If that's already the case, it is written right away.
+func (c *Combiner) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.closing = true
+	return c.maybeWrite()
+}
+
+func (c *Combiner) closeTrace() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.open--
+	return c.maybeWrite()
+}
+
+func (c *Combiner) maybeWrite() error {
+	if !c.closing || c.open > 0 || c.done {
+		return nil
+	}
+	c.done = true
+	if err := c.write(); err != nil {
+		c.w.Close()
+		return err
+	}
+	return c.w.Close()
+}
+
+func (c *Combiner) write() error {
+	codeVersion, err := json.Marshal(quicGoVersion)
+	if err != nil {
+		return err
+	}
+	buf := &bytes.Buffer{}
+	buf.WriteString(`{"qlog_format":"JSON","qlog_version":"draft-02","title":"quic-go qlog","code_version":`)
+	buf.Write(codeVersion)
+	buf.WriteString(`,"traces":[`)
+	var numTraces int
+	for _, t := range c.traces {
+		if t.buf.Len() == 0 {
+			continue
+		}
+		if numTraces > 0 {
+			buf.WriteByte(',')
+		}
+		if err := t.writeJSON(buf); err != nil {
+			return err
+		}
+		numTraces++
+	}
+	buf.WriteString("]}\n")
+	_, err = c.w.Write(buf.Bytes())
+	return err
+}
+
+type combinedTrace struct {
+	combiner *Combiner
+	buf      bytes.Buffer
+	closed   bool
+}
+
+var _ io.WriteCloser = &combinedTrace{}
+
+func (t *combinedTrace) Write(p []byte) (int, error) {
+	return t.buf.Write(p)
+}
+
+func (t *combinedTrace) Close() error {
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+	return t.combiner.closeTrace()
+}
+
+// writeJSON converts the NDJSON written by the connection tracer into a JSON trace.
+// The first record contains the trace, all following records are events.
+func (t *combinedTrace) writeJSON(buf *bytes.Buffer) error {
+	records := bytes.Split(bytes.TrimSpace(t.buf.Bytes()), []byte{'\n'})
+	var header struct {
+		Trace json.RawMessage `json:"trace"`
+	}
+	if err := json.Unmarshal(records[0], &header); err != nil {
+		return err
+	}
+	trace := bytes.TrimSpace(header.Trace)
+	if len(trace) < 2 || trace[len(trace)-1] != '}' {
+		return errors.New("qlog: invalid trace")
+	}
+	buf.Write(trace[:len(trace)-1])
+	buf.WriteString(`,"events":[`)
+	buf.Write(bytes.Join(records[1:], []byte{','}))
+	buf.WriteString("]}")
+	return nil
+}
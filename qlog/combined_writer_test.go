@@ -0,0 +1,85 @@
+package qlog
+
+import (
+	"encoding/json"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Combiner", func() {
+	type combinedQlog struct {
+		Format string `json:"qlog_format"`
+		Traces []struct {
+			VantagePoint struct {
+				Type string `json:"type"`
+			} `json:"vantage_point"`
+			CommonFields struct {
+				ODCID string `json:"ODCID"`
+			} `json:"common_fields"`
+			Events []struct {
+				Name string                 `json:"name"`
+				Data map[string]interface{} `json:"data"`
+			} `json:"events"`
+		} `json:"traces"`
+	}
+
+	odcid := protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef}
+
+	It("writes the client's and the server's trace into one file", func() {
+		f := &closeRecorder{}
+		c := NewCombiner(f)
+		client := NewConnectionTracer(c.Writer(), protocol.PerspectiveClient, odcid)
+		server := NewConnectionTracer(c.Writer(), protocol.PerspectiveServer, odcid)
+		client.UpdatedMTU(1337)
+		server.UpdatedMTU(1400)
+		server.UpdatedPTOCount(1)
+		Expect(c.Close()).To(Succeed())
+		client.Close()
+		Expect(f.Len()).To(BeZero())
+		Expect(f.closed).To(BeFalse())
+		server.Close()
+		Expect(f.closed).To(BeTrue())
+
+		var q combinedQlog
+		Expect(json.Unmarshal(f.Bytes(), &q)).To(Succeed())
+		Expect(q.Format).To(Equal("JSON"))
+		Expect(q.Traces).To(HaveLen(2))
+		Expect(q.Traces[0].VantagePoint.Type).To(Equal("client"))
+		Expect(q.Traces[1].VantagePoint.Type).To(Equal("server"))
+		for _, t := range q.Traces {
+			Expect(t.CommonFields.ODCID).To(Equal("deadbeef"))
+		}
+		Expect(q.Traces[0].Events).To(HaveLen(1))
+		Expect(q.Traces[0].Events[0].Name).To(Equal("connectivity:mtu_updated"))
+		Expect(q.Traces[0].Events[0].Data).To(HaveKeyWithValue("new", float64(1337)))
+		Expect(q.Traces[1].Events).To(HaveLen(2))
+		Expect(q.Traces[1].Events[0].Data).To(HaveKeyWithValue("new", float64(1400)))
+	})
+
+	It("writes the file when closed after all traces", func() {
+		f := &closeRecorder{}
+		c := NewCombiner(f)
+		t := NewConnectionTracer(c.Writer(), protocol.PerspectiveServer, odcid)
+		t.Close()
+		Expect(f.closed).To(BeFalse())
+		Expect(c.Close()).To(Succeed())
+		Expect(f.closed).To(BeTrue())
+		var q combinedQlog
+		Expect(json.Unmarshal(f.Bytes(), &q)).To(Succeed())
+		Expect(q.Traces).To(HaveLen(1))
+		Expect(q.Traces[0].Events).To(BeEmpty())
+	})
+
+	It("skips traces that are empty", func() {
+		f := &closeRecorder{}
+		c := NewCombiner(f)
+		Expect(c.Writer().Close()).To(Succeed())
+		Expect(c.Close()).To(Succeed())
+		var q combinedQlog
+		Expect(json.Unmarshal(f.Bytes(), &q)).To(Succeed())
+		Expect(q.Traces).To(BeEmpty())
+	})
+})
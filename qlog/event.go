@@ -17,7 +17,7 @@ import (
 func milliseconds(dur time.Duration) float64 { return float64(dur.Nanoseconds()) / 1e6 }
 
 type eventDetails interface {
-	Category() category
+	Category() Category
 	Name() string
 	gojay.MarshalerJSONObject
 }
@@ -66,7 +66,7 @@ type eventConnectionStarted struct {
 
 var _ eventDetails = &eventConnectionStarted{}
 
-func (e eventConnectionStarted) Category() category { return categoryTransport }
+func (e eventConnectionStarted) Category() Category { return CategoryTransport }
 func (e eventConnectionStarted) Name() string       { return "connection_started" }
 func (e eventConnectionStarted) IsNil() bool        { return false }
 
@@ -89,7 +89,7 @@ type eventVersionNegotiated struct {
 	chosenVersion                  versionNumber
 }
 
-func (e eventVersionNegotiated) Category() category { return categoryTransport }
+func (e eventVersionNegotiated) Category() Category { return CategoryTransport }
 func (e eventVersionNegotiated) Name() string       { return "version_information" }
 func (e eventVersionNegotiated) IsNil() bool        { return false }
 
@@ -107,7 +107,7 @@ type eventConnectionClosed struct {
 	e error
 }
 
-func (e eventConnectionClosed) Category() category { return categoryTransport }
+func (e eventConnectionClosed) Category() Category { return CategoryTransport }
 func (e eventConnectionClosed) Name() string       { return "connection_closed" }
 func (e eventConnectionClosed) IsNil() bool        { return false }
 
@@ -164,7 +164,7 @@ type eventPacketSent struct {
 
 var _ eventDetails = eventPacketSent{}
 
-func (e eventPacketSent) Category() category { return categoryTransport }
+func (e eventPacketSent) Category() Category { return CategoryTransport }
 func (e eventPacketSent) Name() string       { return "packet_sent" }
 func (e eventPacketSent) IsNil() bool        { return false }
 
@@ -187,7 +187,7 @@ type eventPacketReceived struct {
 
 var _ eventDetails = eventPacketReceived{}
 
-func (e eventPacketReceived) Category() category { return categoryTransport }
+func (e eventPacketReceived) Category() Category { return CategoryTransport }
 func (e eventPacketReceived) Name() string       { return "packet_received" }
 func (e eventPacketReceived) IsNil() bool        { return false }
 
@@ -203,7 +203,7 @@ type eventRetryReceived struct {
 	Header packetHeader
 }
 
-func (e eventRetryReceived) Category() category { return categoryTransport }
+func (e eventRetryReceived) Category() Category { return CategoryTransport }
 func (e eventRetryReceived) Name() string       { return "packet_received" }
 func (e eventRetryReceived) IsNil() bool        { return false }
 
@@ -216,7 +216,7 @@ type eventVersionNegotiationReceived struct {
 	SupportedVersions []versionNumber
 }
 
-func (e eventVersionNegotiationReceived) Category() category { return categoryTransport }
+func (e eventVersionNegotiationReceived) Category() Category { return CategoryTransport }
 func (e eventVersionNegotiationReceived) Name() string       { return "packet_received" }
 func (e eventVersionNegotiationReceived) IsNil() bool        { return false }
 
@@ -229,7 +229,7 @@ type eventPacketBuffered struct {
 	PacketType logging.PacketType
 }
 
-func (e eventPacketBuffered) Category() category { return categoryTransport }
+func (e eventPacketBuffered) Category() Category { return CategoryTransport }
 func (e eventPacketBuffered) Name() string       { return "packet_buffered" }
 func (e eventPacketBuffered) IsNil() bool        { return false }
 
@@ -245,7 +245,7 @@ type eventPacketDropped struct {
 	Trigger    packetDropReason
 }
 
-func (e eventPacketDropped) Category() category { return categoryTransport }
+func (e eventPacketDropped) Category() Category { return CategoryTransport }
 func (e eventPacketDropped) Name() string       { return "packet_dropped" }
 func (e eventPacketDropped) IsNil() bool        { return false }
 
@@ -261,9 +261,10 @@ type metrics struct {
 	LatestRTT   time.Duration
 	RTTVariance time.Duration
 
-	CongestionWindow protocol.ByteCount
-	BytesInFlight    protocol.ByteCount
-	PacketsInFlight  int
+	CongestionWindow   protocol.ByteCount
+	SlowStartThreshold protocol.ByteCount
+	BytesInFlight      protocol.ByteCount
+	PacketsInFlight    int
 }
 
 type eventMetricsUpdated struct {
@@ -271,7 +272,7 @@ type eventMetricsUpdated struct {
 	Current *metrics
 }
 
-func (e eventMetricsUpdated) Category() category { return categoryRecovery }
+func (e eventMetricsUpdated) Category() Category { return CategoryRecovery }
 func (e eventMetricsUpdated) Name() string       { return "metrics_updated" }
 func (e eventMetricsUpdated) IsNil() bool        { return false }
 
@@ -292,6 +293,9 @@ func (e eventMetricsUpdated) MarshalJSONObject(enc *gojay.Encoder) {
 	if e.Last == nil || e.Last.CongestionWindow != e.Current.CongestionWindow {
 		enc.Uint64Key("congestion_window", uint64(e.Current.CongestionWindow))
 	}
+	if e.Current.SlowStartThreshold != 0 && (e.Last == nil || e.Last.SlowStartThreshold != e.Current.SlowStartThreshold) {
+		enc.Uint64Key("ssthresh", uint64(e.Current.SlowStartThreshold))
+	}
 	if e.Last == nil || e.Last.BytesInFlight != e.Current.BytesInFlight {
 		enc.Uint64Key("bytes_in_flight", uint64(e.Current.BytesInFlight))
 	}
@@ -304,7 +308,7 @@ type eventUpdatedPTO struct {
 	Value uint32
 }
 
-func (e eventUpdatedPTO) Category() category { return categoryRecovery }
+func (e eventUpdatedPTO) Category() Category { return CategoryRecovery }
 func (e eventUpdatedPTO) Name() string       { return "metrics_updated" }
 func (e eventUpdatedPTO) IsNil() bool        { return false }
 
@@ -312,13 +316,37 @@ func (e eventUpdatedPTO) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.Uint32Key("pto_count", e.Value)
 }
 
+type eventMTUUpdated struct {
+	Value protocol.ByteCount
+}
+
+func (e eventMTUUpdated) Category() Category { return CategoryConnectivity }
+func (e eventMTUUpdated) Name() string       { return "mtu_updated" }
+func (e eventMTUUpdated) IsNil() bool        { return false }
+
+func (e eventMTUUpdated) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.Int64Key("new", int64(e.Value))
+}
+
+type eventSpinBitRTTUpdated struct {
+	RTT time.Duration
+}
+
+func (e eventSpinBitRTTUpdated) Category() Category { return CategoryRecovery }
+func (e eventSpinBitRTTUpdated) Name() string       { return "spin_bit_rtt_updated" }
+func (e eventSpinBitRTTUpdated) IsNil() bool        { return false }
+
+func (e eventSpinBitRTTUpdated) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.FloatKey("rtt", milliseconds(e.RTT))
+}
+
 type eventPacketLost struct {
 	PacketType   logging.PacketType
 	PacketNumber protocol.PacketNumber
 	Trigger      packetLossReason
 }
 
-func (e eventPacketLost) Category() category { return categoryRecovery }
+func (e eventPacketLost) Category() Category { return CategoryRecovery }
 func (e eventPacketLost) Name() string       { return "packet_lost" }
 func (e eventPacketLost) IsNil() bool        { return false }
 
@@ -337,7 +365,7 @@ type eventKeyUpdated struct {
 	// we don't log the keys here, so we don't need `old` and `new`.
 }
 
-func (e eventKeyUpdated) Category() category { return categorySecurity }
+func (e eventKeyUpdated) Category() Category { return CategorySecurity }
 func (e eventKeyUpdated) Name() string       { return "key_updated" }
 func (e eventKeyUpdated) IsNil() bool        { return false }
 
@@ -354,7 +382,7 @@ type eventKeyRetired struct {
 	Generation protocol.KeyPhase
 }
 
-func (e eventKeyRetired) Category() category { return categorySecurity }
+func (e eventKeyRetired) Category() Category { return CategorySecurity }
 func (e eventKeyRetired) Name() string       { return "key_retired" }
 func (e eventKeyRetired) IsNil() bool        { return false }
 
@@ -395,9 +423,11 @@ type eventTransportParameters struct {
 	PreferredAddress *preferredAddress
 
 	MaxDatagramFrameSize protocol.ByteCount
+
+	MinAckDelay *time.Duration
 }
 
-func (e eventTransportParameters) Category() category { return categoryTransport }
+func (e eventTransportParameters) Category() Category { return CategoryTransport }
 func (e eventTransportParameters) Name() string {
 	if e.Restore {
 		return "parameters_restored"
@@ -440,6 +470,9 @@ func (e eventTransportParameters) MarshalJSONObject(enc *gojay.Encoder) {
 	if e.MaxDatagramFrameSize != protocol.InvalidByteCount {
 		enc.Int64Key("max_datagram_frame_size", int64(e.MaxDatagramFrameSize))
 	}
+	if e.MinAckDelay != nil {
+		enc.FloatKey("min_ack_delay", milliseconds(*e.MinAckDelay))
+	}
 }
 
 type preferredAddress struct {
@@ -467,7 +500,7 @@ type eventLossTimerSet struct {
 	Delta     time.Duration
 }
 
-func (e eventLossTimerSet) Category() category { return categoryRecovery }
+func (e eventLossTimerSet) Category() Category { return CategoryRecovery }
 func (e eventLossTimerSet) Name() string       { return "loss_timer_updated" }
 func (e eventLossTimerSet) IsNil() bool        { return false }
 
@@ -483,7 +516,7 @@ type eventLossTimerExpired struct {
 	EncLevel  protocol.EncryptionLevel
 }
 
-func (e eventLossTimerExpired) Category() category { return categoryRecovery }
+func (e eventLossTimerExpired) Category() Category { return CategoryRecovery }
 func (e eventLossTimerExpired) Name() string       { return "loss_timer_updated" }
 func (e eventLossTimerExpired) IsNil() bool        { return false }
 
@@ -495,7 +528,7 @@ func (e eventLossTimerExpired) MarshalJSONObject(enc *gojay.Encoder) {
 
 type eventLossTimerCanceled struct{}
 
-func (e eventLossTimerCanceled) Category() category { return categoryRecovery }
+func (e eventLossTimerCanceled) Category() Category { return CategoryRecovery }
 func (e eventLossTimerCanceled) Name() string       { return "loss_timer_updated" }
 func (e eventLossTimerCanceled) IsNil() bool        { return false }
 
@@ -507,7 +540,7 @@ type eventCongestionStateUpdated struct {
 	state congestionState
 }
 
-func (e eventCongestionStateUpdated) Category() category { return categoryTransport }
+func (e eventCongestionStateUpdated) Category() Category { return CategoryRecovery }
 func (e eventCongestionStateUpdated) Name() string       { return "congestion_state_updated" }
 func (e eventCongestionStateUpdated) IsNil() bool        { return false }
 
@@ -515,12 +548,26 @@ func (e eventCongestionStateUpdated) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.StringKey("new", e.state.String())
 }
 
+type eventCongestionControlSet struct {
+	algorithm string
+	hystart   string
+}
+
+func (e eventCongestionControlSet) Category() Category { return CategoryRecovery }
+func (e eventCongestionControlSet) Name() string       { return "parameters_set" }
+func (e eventCongestionControlSet) IsNil() bool        { return false }
+
+func (e eventCongestionControlSet) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("congestion_control", e.algorithm)
+	enc.StringKey("hystart", e.hystart)
+}
+
 type eventGeneric struct {
 	name string
 	msg  string
 }
 
-func (e eventGeneric) Category() category { return categoryTransport }
+func (e eventGeneric) Category() Category { return CategoryTransport }
 func (e eventGeneric) Name() string       { return e.name }
 func (e eventGeneric) IsNil() bool        { return false }
 
@@ -15,7 +15,7 @@ type mevent struct{}
 
 var _ eventDetails = mevent{}
 
-func (mevent) Category() category                   { return categoryConnectivity }
+func (mevent) Category() Category                   { return CategoryConnectivity }
 func (mevent) Name() string                         { return "mevent" }
 func (mevent) IsNil() bool                          { return false }
 func (mevent) MarshalJSONObject(enc *gojay.Encoder) { enc.StringKey("event", "details") }
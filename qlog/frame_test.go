@@ -365,6 +365,33 @@ var _ = Describe("Frames", func() {
 		)
 	})
 
+	It("marshals ACK_FREQUENCY frames", func() {
+		check(
+			&logging.AckFrequencyFrame{
+				SequenceNumber:        3,
+				AckElicitingThreshold: 9,
+				RequestMaxAckDelay:    25 * time.Millisecond,
+				ReorderingThreshold:   2,
+			},
+			map[string]interface{}{
+				"frame_type":              "ack_frequency",
+				"sequence_number":         3,
+				"ack_eliciting_threshold": 9,
+				"request_max_ack_delay":   25,
+				"reordering_threshold":    2,
+			},
+		)
+	})
+
+	It("marshals IMMEDIATE_ACK frames", func() {
+		check(
+			&logging.ImmediateAckFrame{},
+			map[string]interface{}{
+				"frame_type": "immediate_ack",
+			},
+		)
+	})
+
 	It("marshals DATAGRAM frames", func() {
 		check(
 			&logging.DatagramFrame{Length: 1337},
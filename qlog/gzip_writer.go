@@ -0,0 +1,58 @@
+package qlog
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// the gzip stream is flushed at the next record boundary once this many (uncompressed) bytes were written
+const gzipFlushSize = 64 << 10
+
+type gzipWriteCloser struct {
+	w         io.WriteCloser
+	gz        *gzip.Writer
+	unflushed int
+}
+
+var _ io.WriteCloser = &gzipWriteCloser{}
+
+// NewGzipWriteCloser creates an io.WriteCloser that compresses a qlog using gzip, and writes it to w.
+// The gzip stream is regularly flushed at record boundaries (as is w, if it has a Flush() error method),
+// so that most of the qlog can be recovered from a file that wasn't closed, e.g. after a crash.
+// Closing the gzipWriteCloser closes w.
+func NewGzipWriteCloser(w io.WriteCloser) io.WriteCloser {
+	return &gzipWriteCloser{w: w, gz: gzip.NewWriter(w)}
+}
+
+func (g *gzipWriteCloser) Write(p []byte) (int, error) {
+	n, err := g.gz.Write(p)
+	if err != nil {
+		return n, err
+	}
+	g.unflushed += n
+	if g.unflushed >= gzipFlushSize && n > 0 && p[n-1] == '\n' {
+		if err := g.flush(); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+func (g *gzipWriteCloser) flush() error {
+	g.unflushed = 0
+	if err := g.gz.Flush(); err != nil {
+		return err
+	}
+	if f, ok := g.w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+func (g *gzipWriteCloser) Close() error {
+	if err := g.gz.Close(); err != nil {
+		g.w.Close()
+		return err
+	}
+	return g.w.Close()
+}
@@ -0,0 +1,64 @@
+package qlog
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("gzip Writer", func() {
+	It("compresses a qlog", func() {
+		f := &closeRecorder{}
+		tracer := NewConnectionTracer(NewGzipWriteCloser(f), protocol.PerspectiveServer, protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef})
+		for i := uint32(0); i < 100; i++ {
+			tracer.UpdatedPTOCount(i)
+		}
+		tracer.Close()
+		Expect(f.closed).To(BeTrue())
+
+		r, err := gzip.NewReader(f)
+		Expect(err).ToNot(HaveOccurred())
+		data, err := io.ReadAll(r)
+		Expect(err).ToNot(HaveOccurred())
+		lines := bytes.Split(bytes.TrimSuffix(data, []byte{'\n'}), []byte{'\n'})
+		Expect(lines).To(HaveLen(101))
+		m := make(map[string]interface{})
+		Expect(json.Unmarshal(lines[0], &m)).To(Succeed())
+		Expect(m).To(HaveKey("trace"))
+		for _, l := range lines[1:] {
+			ev := make(map[string]interface{})
+			Expect(json.Unmarshal(l, &ev)).To(Succeed())
+			Expect(ev).To(HaveKeyWithValue("name", "recovery:metrics_updated"))
+		}
+	})
+
+	It("flushes at record boundaries", func() {
+		f := &closeRecorder{}
+		w := NewGzipWriteCloser(utils.NewBufferedWriteCloser(bufio.NewWriter(f), f))
+		record := append(bytes.Repeat([]byte{'a'}, 1000), '\n')
+		var written int
+		for written < gzipFlushSize {
+			_, err := w.Write(record[:500])
+			Expect(err).ToNot(HaveOccurred())
+			_, err = w.Write(record[500:])
+			Expect(err).ToNot(HaveOccurred())
+			written += len(record)
+		}
+		// the file wasn't closed, but all records can be decompressed
+		r, err := gzip.NewReader(bytes.NewReader(f.Bytes()))
+		Expect(err).ToNot(HaveOccurred())
+		data, err := io.ReadAll(r)
+		Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+		Expect(data).To(Equal(bytes.Repeat(record, written/len(record))))
+		Expect(w.Close()).To(Succeed())
+		Expect(f.closed).To(BeTrue())
+	})
+})
@@ -49,20 +49,69 @@ func init() {
 
 const eventChanSize = 50
 
+// Options are the options of a qlog tracer.
+type Options struct {
+	// Categories are the event categories that are recorded.
+	// Events of all other categories are dropped before they are serialized.
+	// If empty, events of all categories are recorded.
+	Categories []Category
+}
+
+// categoryFilter is a bit set of the event categories that are recorded.
+type categoryFilter uint32
+
+const allCategories categoryFilter = 1<<32 - 1
+
+func newCategoryFilter(categories []Category) categoryFilter {
+	if len(categories) == 0 {
+		return allCategories
+	}
+	var f categoryFilter
+	for _, c := range categories {
+		f |= 1 << c
+	}
+	return f
+}
+
+func (f categoryFilter) has(c Category) bool { return f&(1<<c) != 0 }
+
 type tracer struct {
 	getLogWriter func(p logging.Perspective, connectionID []byte) io.WriteCloser
+	categories   categoryFilter
 }
 
 var _ logging.Tracer = &tracer{}
 
 // NewTracer creates a new qlog tracer.
+// It records events of all categories.
 func NewTracer(getLogWriter func(p logging.Perspective, connectionID []byte) io.WriteCloser) logging.Tracer {
-	return &tracer{getLogWriter: getLogWriter}
+	return NewTracerWithOptions(getLogWriter, nil)
+}
+
+// NewTracerWithOptions creates a new qlog tracer.
+// If opts is nil, events of all categories are recorded.
+func NewTracerWithOptions(getLogWriter func(p logging.Perspective, connectionID []byte) io.WriteCloser, opts *Options) logging.Tracer {
+	t := &tracer{getLogWriter: getLogWriter, categories: allCategories}
+	if opts != nil {
+		t.categories = newCategoryFilter(opts.Categories)
+	}
+	return t
+}
+
+// FileName returns a file name for the qlog of a connection, e.g. "server_deadbeef.qlog".
+// It is derived from the perspective and the original destination connection ID,
+// which is the same for the client's and the server's trace of a connection.
+func FileName(p logging.Perspective, odcid []byte) string {
+	perspective := "server"
+	if p == logging.PerspectiveClient {
+		perspective = "client"
+	}
+	return fmt.Sprintf("%s_%x.qlog", perspective, odcid)
 }
 
 func (t *tracer) TracerForConnection(_ context.Context, p logging.Perspective, odcid protocol.ConnectionID) logging.ConnectionTracer {
 	if w := t.getLogWriter(p, odcid.Bytes()); w != nil {
-		return NewConnectionTracer(w, p, odcid)
+		return newConnectionTracer(w, p, odcid, t.categories)
 	}
 	return nil
 }
@@ -78,6 +127,7 @@ type connectionTracer struct {
 	odcid         protocol.ConnectionID
 	perspective   protocol.Perspective
 	referenceTime time.Time
+	categories    categoryFilter
 
 	events     chan event
 	encodeErr  error
@@ -89,11 +139,17 @@ type connectionTracer struct {
 var _ logging.ConnectionTracer = &connectionTracer{}
 
 // NewConnectionTracer creates a new tracer to record a qlog for a connection.
+// It records events of all categories.
 func NewConnectionTracer(w io.WriteCloser, p protocol.Perspective, odcid protocol.ConnectionID) logging.ConnectionTracer {
+	return newConnectionTracer(w, p, odcid, allCategories)
+}
+
+func newConnectionTracer(w io.WriteCloser, p protocol.Perspective, odcid protocol.ConnectionID, categories categoryFilter) *connectionTracer {
 	t := &connectionTracer{
 		w:             w,
 		perspective:   p,
 		odcid:         odcid,
+		categories:    categories,
 		runStopped:    make(chan struct{}),
 		events:        make(chan event, eventChanSize),
 		referenceTime: time.Now(),
@@ -157,6 +213,9 @@ func (t *connectionTracer) export() error {
 }
 
 func (t *connectionTracer) recordEvent(eventTime time.Time, details eventDetails) {
+	if !t.categories.has(details.Category()) {
+		return
+	}
 	t.events <- event{
 		RelativeTime: eventTime.Sub(t.referenceTime),
 		eventDetails: details,
@@ -273,10 +332,15 @@ func (t *connectionTracer) toTransportParameters(tp *wire.TransportParameters) *
 		InitialMaxStreamsUni:            int64(tp.MaxUniStreamNum),
 		PreferredAddress:                pa,
 		MaxDatagramFrameSize:            tp.MaxDatagramFrameSize,
+		MinAckDelay:                     tp.MinAckDelay,
 	}
 }
 
 func (t *connectionTracer) SentPacket(hdr *wire.ExtendedHeader, packetSize logging.ByteCount, ack *logging.AckFrame, frames []logging.Frame) {
+	// don't bother converting the frames if the event is dropped anyway
+	if !t.categories.has(CategoryTransport) {
+		return
+	}
 	numFrames := len(frames)
 	if ack != nil {
 		numFrames++
@@ -300,6 +364,9 @@ func (t *connectionTracer) SentPacket(hdr *wire.ExtendedHeader, packetSize loggi
 }
 
 func (t *connectionTracer) ReceivedPacket(hdr *wire.ExtendedHeader, packetSize logging.ByteCount, frames []logging.Frame) {
+	if !t.categories.has(CategoryTransport) {
+		return
+	}
 	fs := make([]frame, len(frames))
 	for i, f := range frames {
 		fs[i] = frame{Frame: f}
@@ -353,6 +420,9 @@ func (t *connectionTracer) DroppedPacket(pt logging.PacketType, size protocol.By
 }
 
 func (t *connectionTracer) UpdatedMetrics(rttStats *utils.RTTStats, cwnd, bytesInFlight protocol.ByteCount, packetsInFlight int) {
+	if !t.categories.has(CategoryRecovery) {
+		return
+	}
 	m := &metrics{
 		MinRTT:           rttStats.MinRTT(),
 		SmoothedRTT:      rttStats.SmoothedRTT(),
@@ -363,6 +433,46 @@ func (t *connectionTracer) UpdatedMetrics(rttStats *utils.RTTStats, cwnd, bytesI
 		PacketsInFlight:  packetsInFlight,
 	}
 	t.mutex.Lock()
+	// the slow start threshold is only reported by the congestion controller
+	if t.lastMetrics != nil {
+		m.SlowStartThreshold = t.lastMetrics.SlowStartThreshold
+	}
+	if t.lastMetrics != nil && *t.lastMetrics == *m {
+		// nothing changed, don't record an empty event
+		t.mutex.Unlock()
+		return
+	}
+	t.recordEvent(time.Now(), &eventMetricsUpdated{
+		Last:    t.lastMetrics,
+		Current: m,
+	})
+	t.lastMetrics = m
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) UpdatedCongestionMetrics(rttStats *utils.RTTStats, cwnd, ssthresh, bytesInFlight protocol.ByteCount) {
+	if !t.categories.has(CategoryRecovery) {
+		return
+	}
+	m := &metrics{
+		MinRTT:             rttStats.MinRTT(),
+		SmoothedRTT:        rttStats.SmoothedRTT(),
+		LatestRTT:          rttStats.LatestRTT(),
+		RTTVariance:        rttStats.MeanDeviation(),
+		CongestionWindow:   cwnd,
+		SlowStartThreshold: ssthresh,
+		BytesInFlight:      bytesInFlight,
+	}
+	t.mutex.Lock()
+	// the number of packets in flight is only reported by the sent packet handler
+	if t.lastMetrics != nil {
+		m.PacketsInFlight = t.lastMetrics.PacketsInFlight
+	}
+	if t.lastMetrics != nil && *t.lastMetrics == *m {
+		// nothing changed, don't record an empty event
+		t.mutex.Unlock()
+		return
+	}
 	t.recordEvent(time.Now(), &eventMetricsUpdated{
 		Last:    t.lastMetrics,
 		Current: m,
@@ -389,12 +499,30 @@ func (t *connectionTracer) UpdatedCongestionState(state logging.CongestionState)
 	t.mutex.Unlock()
 }
 
+func (t *connectionTracer) UpdatedCongestionControl(algorithm, hystart string) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventCongestionControlSet{algorithm: algorithm, hystart: hystart})
+	t.mutex.Unlock()
+}
+
 func (t *connectionTracer) UpdatedPTOCount(value uint32) {
 	t.mutex.Lock()
 	t.recordEvent(time.Now(), &eventUpdatedPTO{Value: value})
 	t.mutex.Unlock()
 }
 
+func (t *connectionTracer) UpdatedMTU(mtu protocol.ByteCount) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventMTUUpdated{Value: mtu})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) UpdatedSpinBitRTT(rtt time.Duration) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventSpinBitRTTUpdated{RTT: rtt})
+	t.mutex.Unlock()
+}
+
 func (t *connectionTracer) UpdatedKeyFromTLS(encLevel protocol.EncryptionLevel, pers protocol.Perspective) {
 	t.mutex.Lock()
 	t.recordEvent(time.Now(), &eventKeyUpdated{
@@ -56,6 +56,78 @@ var _ = Describe("Tracing", func() {
 			t := NewTracer(func(logging.Perspective, []byte) io.WriteCloser { return nil })
 			Expect(t.TracerForConnection(context.Background(), logging.PerspectiveClient, logging.ConnectionID{1, 2, 3, 4})).To(BeNil())
 		})
+
+		It("derives the file name from the perspective and the ODCID", func() {
+			Expect(FileName(logging.PerspectiveServer, []byte{0xde, 0xad, 0xbe, 0xef})).To(Equal("server_deadbeef.qlog"))
+			Expect(FileName(logging.PerspectiveClient, []byte{0xde, 0xad, 0xbe, 0xef})).To(Equal("client_deadbeef.qlog"))
+		})
+
+		Context("filtering event categories", func() {
+			// recordEvents records an event of every category, and returns the names of the events written
+			recordEvents := func(opts *Options) []string {
+				buf := &bytes.Buffer{}
+				t := NewTracerWithOptions(func(logging.Perspective, []byte) io.WriteCloser { return nopWriteCloser(buf) }, opts)
+				ct := t.TracerForConnection(context.Background(), logging.PerspectiveServer, logging.ConnectionID{0xde, 0xad, 0xbe, 0xef})
+				ct.UpdatedMTU(1337)
+				ct.SentPacket(
+					&logging.ExtendedHeader{
+						Header:       logging.Header{DestConnectionID: protocol.ConnectionID{1, 2, 3, 4}},
+						PacketNumber: 1337,
+					},
+					123,
+					nil,
+					[]logging.Frame{&logging.PingFrame{}},
+				)
+				ct.ReceivedPacket(
+					&logging.ExtendedHeader{
+						Header:       logging.Header{DestConnectionID: protocol.ConnectionID{1, 2, 3, 4}},
+						PacketNumber: 1337,
+					},
+					123,
+					[]logging.Frame{&logging.PingFrame{}},
+				)
+				ct.UpdatedKeyFromTLS(protocol.EncryptionHandshake, protocol.PerspectiveClient)
+				ct.UpdatedPTOCount(1)
+				ct.UpdatedMetrics(&utils.RTTStats{}, 1000, 500, 1)
+				ct.Close()
+
+				_, err := buf.ReadBytes('\n') // the trace header
+				Expect(err).ToNot(HaveOccurred())
+				var names []string
+				for buf.Len() > 0 {
+					line, err := buf.ReadBytes('\n')
+					Expect(err).ToNot(HaveOccurred())
+					ev := make(map[string]interface{})
+					Expect(json.Unmarshal(line, &ev)).To(Succeed())
+					names = append(names, ev["name"].(string))
+				}
+				return names
+			}
+
+			It("records all categories by default", func() {
+				Expect(recordEvents(nil)).To(Equal([]string{
+					"connectivity:mtu_updated",
+					"transport:packet_sent",
+					"transport:packet_received",
+					"security:key_updated",
+					"recovery:metrics_updated",
+					"recovery:metrics_updated",
+				}))
+				Expect(recordEvents(&Options{})).To(HaveLen(6))
+			})
+
+			It("only records the selected categories", func() {
+				Expect(recordEvents(&Options{Categories: []Category{CategoryRecovery, CategorySecurity}})).To(Equal([]string{
+					"security:key_updated",
+					"recovery:metrics_updated",
+					"recovery:metrics_updated",
+				}))
+			})
+
+			It("records no events if only HTTP events are selected", func() {
+				Expect(recordEvents(&Options{Categories: []Category{CategoryHTTP}})).To(BeEmpty())
+			})
+		})
 	})
 
 	It("stops writing when encountering an error", func() {
@@ -377,6 +449,18 @@ var _ = Describe("Tracing", func() {
 				Expect(ev).To(HaveKeyWithValue("max_datagram_frame_size", float64(1337)))
 			})
 
+			It("records transport parameters that enable the ACK frequency extension", func() {
+				minAckDelay := 1500 * time.Microsecond
+				tracer.SentTransportParameters(&logging.TransportParameters{
+					MaxDatagramFrameSize: protocol.InvalidByteCount,
+					MinAckDelay:          &minAckDelay,
+				})
+				entry := exportAndParseSingle()
+				Expect(entry.Name).To(Equal("transport:parameters_set"))
+				ev := entry.Event
+				Expect(ev).To(HaveKeyWithValue("min_ack_delay", 1.5))
+			})
+
 			It("records received transport parameters", func() {
 				tracer.ReceivedTransportParameters(&logging.TransportParameters{})
 				entry := exportAndParseSingle()
@@ -675,6 +759,47 @@ var _ = Describe("Tracing", func() {
 				Expect(ev).To(HaveKeyWithValue("smoothed_rtt", float64(15)))
 			})
 
+			It("records congestion metrics updates", func() {
+				rttStats := utils.NewRTTStats()
+				rttStats.UpdateRTT(15*time.Millisecond, 0, time.Now())
+				tracer.UpdatedMetrics(rttStats, 4321, 1234, 42)
+				tracer.UpdatedCongestionMetrics(rttStats, 5000, 3000, 1000)
+				entries := exportAndParse()
+				Expect(entries).To(HaveLen(2))
+				Expect(entries[0].Event).ToNot(HaveKey("ssthresh"))
+				Expect(entries[1].Name).To(Equal("recovery:metrics_updated"))
+				ev := entries[1].Event
+				Expect(ev).To(HaveLen(3))
+				Expect(ev).To(HaveKeyWithValue("congestion_window", float64(5000)))
+				Expect(ev).To(HaveKeyWithValue("ssthresh", float64(3000)))
+				Expect(ev).To(HaveKeyWithValue("bytes_in_flight", float64(1000)))
+			})
+
+			It("doesn't record metrics updates if nothing changed", func() {
+				rttStats := utils.NewRTTStats()
+				rttStats.UpdateRTT(15*time.Millisecond, 0, time.Now())
+				tracer.UpdatedMetrics(rttStats, 4321, 1234, 42)
+				tracer.UpdatedMetrics(rttStats, 4321, 1234, 42)
+				tracer.UpdatedCongestionMetrics(rttStats, 4321, 0, 1234)
+				Expect(exportAndParse()).To(HaveLen(1))
+			})
+
+			It("records congestion metrics updates of congestion controllers without a slow start threshold", func() {
+				rttStats := utils.NewRTTStats()
+				rttStats.UpdateRTT(15*time.Millisecond, 0, time.Now())
+				tracer.UpdatedCongestionMetrics(rttStats, 5000, 0, 1000)
+				tracer.UpdatedMetrics(rttStats, 5000, 2000, 2)
+				entries := exportAndParse()
+				Expect(entries).To(HaveLen(2))
+				Expect(entries[0].Event).To(HaveKeyWithValue("congestion_window", float64(5000)))
+				Expect(entries[0].Event).To(HaveKeyWithValue("bytes_in_flight", float64(1000)))
+				Expect(entries[0].Event).ToNot(HaveKey("ssthresh"))
+				Expect(entries[1].Event).To(Equal(map[string]interface{}{
+					"bytes_in_flight":   float64(2000),
+					"packets_in_flight": float64(2),
+				}))
+			})
+
 			It("records lost packets", func() {
 				tracer.LostPacket(protocol.EncryptionHandshake, 42, logging.PacketLossReorderingThreshold)
 				entry := exportAndParseSingle()
@@ -689,6 +814,13 @@ var _ = Describe("Tracing", func() {
 				Expect(ev).To(HaveKeyWithValue("trigger", "reordering_threshold"))
 			})
 
+			It("records lost packets, for packets declared lost when sending a PTO probe", func() {
+				tracer.LostPacket(protocol.Encryption1RTT, 42, logging.PacketLossPTO)
+				entry := exportAndParseSingle()
+				Expect(entry.Name).To(Equal("recovery:packet_lost"))
+				Expect(entry.Event).To(HaveKeyWithValue("trigger", "pto_expired"))
+			})
+
 			It("records congestion state updates", func() {
 				tracer.UpdatedCongestionState(logging.CongestionStateCongestionAvoidance)
 				entry := exportAndParseSingle()
@@ -698,6 +830,16 @@ var _ = Describe("Tracing", func() {
 				Expect(ev).To(HaveKeyWithValue("new", "congestion_avoidance"))
 			})
 
+			It("records the congestion controller", func() {
+				tracer.UpdatedCongestionControl("bbr", "none")
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("recovery:parameters_set"))
+				ev := entry.Event
+				Expect(ev).To(HaveKeyWithValue("congestion_control", "bbr"))
+				Expect(ev).To(HaveKeyWithValue("hystart", "none"))
+			})
+
 			It("records PTO changes", func() {
 				tracer.UpdatedPTOCount(42)
 				entry := exportAndParseSingle()
@@ -706,6 +848,22 @@ var _ = Describe("Tracing", func() {
 				Expect(entry.Event).To(HaveKeyWithValue("pto_count", float64(42)))
 			})
 
+			It("records MTU updates", func() {
+				tracer.UpdatedMTU(1400)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("connectivity:mtu_updated"))
+				Expect(entry.Event).To(HaveKeyWithValue("new", float64(1400)))
+			})
+
+			It("records RTT estimates derived from the spin bit", func() {
+				tracer.UpdatedSpinBitRTT(25 * time.Millisecond)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("recovery:spin_bit_rtt_updated"))
+				Expect(entry.Event).To(HaveKeyWithValue("rtt", float64(25)))
+			})
+
 			It("records TLS key updates", func() {
 				tracer.UpdatedKeyFromTLS(protocol.EncryptionHandshake, protocol.PerspectiveClient)
 				entry := exportAndParseSingle()
@@ -0,0 +1,92 @@
+package qlog
+
+import (
+	"bytes"
+	"io"
+)
+
+type rotatingWriter struct {
+	createFile func(index int) (io.WriteCloser, error)
+	maxSize    int64
+
+	current    io.WriteCloser
+	index      int
+	written    int64 // bytes written to the current file
+	atBoundary bool  // true if the last byte written was the end of a record
+
+	header         []byte // the first record, i.e. the trace, which is repeated at the start of every file
+	headerComplete bool
+}
+
+var _ io.WriteCloser = &rotatingWriter{}
+
+// NewRotatingWriter creates an io.WriteCloser that splits a qlog into multiple files.
+// Once more than maxSize bytes were written to a file, the file is closed, and the next record is written to a new file.
+// Files are only rotated at record boundaries, and every file starts with the trace,
+// so that each file is a valid qlog on its own.
+// createFile is called to create the files, with the index of the file, starting at 0.
+// The returned io.WriteCloser can be returned from the callback passed to NewTracer.
+// It is not safe for concurrent use.
+func NewRotatingWriter(createFile func(index int) (io.WriteCloser, error), maxSize int64) io.WriteCloser {
+	return &rotatingWriter{createFile: createFile, maxSize: maxSize}
+}
+
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	if w.current == nil {
+		f, err := w.createFile(w.index)
+		if err != nil {
+			return 0, err
+		}
+		w.current = f
+	} else if w.atBoundary && w.written >= w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	if !w.headerComplete {
+		w.recordHeader(p)
+	}
+	n, err := w.current.Write(p)
+	w.written += int64(n)
+	if n > 0 {
+		w.atBoundary = p[n-1] == '\n'
+	}
+	return n, err
+}
+
+// recordHeader saves the bytes of the first record.
+func (w *rotatingWriter) recordHeader(p []byte) {
+	if i := bytes.IndexByte(p, '\n'); i >= 0 {
+		p = p[:i+1]
+		w.headerComplete = true
+	}
+	w.header = append(w.header, p...)
+}
+
+// rotate closes the current file, and starts the next one.
+func (w *rotatingWriter) rotate() error {
+	f := w.current
+	w.current = nil
+	if err := f.Close(); err != nil {
+		return err
+	}
+	w.index++
+	f, err := w.createFile(w.index)
+	if err != nil {
+		return err
+	}
+	w.current = f
+	w.written = 0
+	n, err := f.Write(w.header)
+	w.written += int64(n)
+	return err
+}
+
+func (w *rotatingWriter) Close() error {
+	if w.current == nil {
+		return nil
+	}
+	err := w.current.Close()
+	w.current = nil
+	return err
+}
@@ -0,0 +1,126 @@
+package qlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type closeRecorder struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+var _ = Describe("Rotating Writer", func() {
+	var files []*closeRecorder
+
+	createFile := func(index int) (io.WriteCloser, error) {
+		Expect(index).To(Equal(len(files)))
+		f := &closeRecorder{}
+		files = append(files, f)
+		return f, nil
+	}
+
+	BeforeEach(func() {
+		files = nil
+	})
+
+	It("doesn't create a file before anything is written", func() {
+		w := NewRotatingWriter(createFile, 10)
+		Expect(w.Close()).To(Succeed())
+		Expect(files).To(BeEmpty())
+	})
+
+	It("writes to a single file, if the limit is not exceeded", func() {
+		w := NewRotatingWriter(createFile, 100)
+		_, err := w.Write([]byte("header\n"))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = w.Write([]byte("foo\n"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(w.Close()).To(Succeed())
+		Expect(files).To(HaveLen(1))
+		Expect(files[0].String()).To(Equal("header\nfoo\n"))
+		Expect(files[0].closed).To(BeTrue())
+	})
+
+	It("rotates files, repeating the header", func() {
+		w := NewRotatingWriter(createFile, 10)
+		for _, s := range []string{"header\n", "foo\n", "bar\n", "baz\n"} {
+			n, err := w.Write([]byte(s))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(len(s)))
+		}
+		Expect(w.Close()).To(Succeed())
+		Expect(files).To(HaveLen(3))
+		Expect(files[0].String()).To(Equal("header\nfoo\n"))
+		Expect(files[1].String()).To(Equal("header\nbar\n"))
+		Expect(files[2].String()).To(Equal("header\nbaz\n"))
+		for _, f := range files {
+			Expect(f.closed).To(BeTrue())
+		}
+	})
+
+	It("only rotates at record boundaries", func() {
+		w := NewRotatingWriter(createFile, 5)
+		for _, s := range []string{"head", "er\n", "foo", "bar", "\n", "baz", "\n"} {
+			_, err := w.Write([]byte(s))
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(w.Close()).To(Succeed())
+		Expect(files).To(HaveLen(3))
+		Expect(files[0].String()).To(Equal("header\n"))
+		Expect(files[1].String()).To(Equal("header\nfoobar\n"))
+		Expect(files[2].String()).To(Equal("header\nbaz\n"))
+	})
+
+	It("returns the error when creating a file fails", func() {
+		testErr := errors.New("test err")
+		w := NewRotatingWriter(func(index int) (io.WriteCloser, error) {
+			if index > 0 {
+				return nil, testErr
+			}
+			return createFile(index)
+		}, 5)
+		_, err := w.Write([]byte("header\n"))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = w.Write([]byte("foo\n"))
+		Expect(err).To(MatchError(testErr))
+		Expect(files[0].closed).To(BeTrue())
+	})
+
+	It("splits a qlog into valid files", func() {
+		w := NewRotatingWriter(createFile, 500)
+		tracer := NewConnectionTracer(w, protocol.PerspectiveServer, protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef})
+		for i := uint32(0); i < 100; i++ {
+			tracer.UpdatedPTOCount(i)
+		}
+		tracer.Close()
+		Expect(len(files)).To(BeNumerically(">", 1))
+		var numEvents int
+		for _, f := range files {
+			Expect(f.closed).To(BeTrue())
+			lines := bytes.Split(bytes.TrimSuffix(f.Bytes(), []byte{'\n'}), []byte{'\n'})
+			m := make(map[string]interface{})
+			Expect(json.Unmarshal(lines[0], &m)).To(Succeed())
+			Expect(m).To(HaveKey("trace"))
+			for _, l := range lines[1:] {
+				ev := make(map[string]interface{})
+				Expect(json.Unmarshal(l, &ev)).To(Succeed())
+				Expect(ev).To(HaveKeyWithValue("name", "recovery:metrics_updated"))
+				numEvents++
+			}
+		}
+		Expect(numEvents).To(Equal(100))
+	})
+})
@@ -0,0 +1,144 @@
+package qlog
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"sync"
+)
+
+// A SharedWriter writes the qlogs of multiple connections into a single NDJSON file, as they are recorded.
+// Every record is prefixed with the group_id of the connection it belongs to,
+// i.e. the hex-encoded original destination connection ID.
+// Records are only written once they are complete, so the records of concurrent connections are never interleaved.
+// Unlike the Combiner, it doesn't buffer the traces in memory, which makes it suitable for servers handling many connections.
+type SharedWriter struct {
+	mutex sync.Mutex
+
+	w       io.WriteCloser
+	err     error // the first error that occurred when writing to w
+	open    int   // the number of connection writers that were not closed yet
+	closing bool  // set when Close is called
+	done    bool
+}
+
+// NewSharedWriter creates a SharedWriter that writes to w.
+func NewSharedWriter(w io.WriteCloser) *SharedWriter {
+	return &SharedWriter{w: w}
+}
+
+// Writer returns an io.WriteCloser for the qlog of a single connection.
+// It can be returned from the callback passed to NewTracer, or be passed to NewConnectionTracer.
+func (s *SharedWriter) Writer(odcid []byte) io.WriteCloser {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.open++
+	return &sharedTrace{
+		writer: s,
+		prefix: []byte(`{"group_id":"` + hex.EncodeToString(odcid) + `",`),
+	}
+}
+
+// Close says that no more connection writers will be added.
+// The underlying io.WriteCloser is closed as soon as all connection writers are closed.
+// If that's already the case, it is closed right away.
+func (s *SharedWriter) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.closing = true
+	return s.maybeClose()
+}
+
+func (s *SharedWriter) writeRecords(p []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	if _, err := s.w.Write(p); err != nil {
+		s.err = err
+	}
+	return s.err
+}
+
+func (s *SharedWriter) closeTrace() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.open--
+	return s.maybeClose()
+}
+
+func (s *SharedWriter) maybeClose() error {
+	if !s.closing || s.open > 0 || s.done {
+		return nil
+	}
+	s.done = true
+	if err := s.w.Close(); err != nil {
+		return err
+	}
+	return s.err
+}
+
+type sharedTrace struct {
+	writer *SharedWriter
+	prefix []byte       // the beginning of every record, including the group_id
+	buf    bytes.Buffer // the part of the current record that was written so far
+	out    []byte       // used to assemble the records that are written to the SharedWriter
+	closed bool
+}
+
+var _ io.WriteCloser = &sharedTrace{}
+
+func (t *sharedTrace) Write(p []byte) (int, error) {
+	t.buf.Write(p)
+	data := t.buf.Bytes()
+	end := bytes.LastIndexByte(data, '\n')
+	if end < 0 {
+		return len(p), nil
+	}
+	t.out = t.out[:0]
+	for _, record := range bytes.Split(data[:end], []byte{'\n'}) {
+		t.out = t.appendRecord(t.out, record)
+	}
+	t.buf.Next(end + 1)
+	if err := t.writer.writeRecords(t.out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// appendRecord adds the group_id to a record, and appends it to b.
+func (t *sharedTrace) appendRecord(b, record []byte) []byte {
+	record = bytes.TrimSpace(record)
+	if len(record) < 2 || record[0] != '{' {
+		return b
+	}
+	if len(bytes.TrimSpace(record[1:len(record)-1])) == 0 { // an empty object
+		b = append(b, t.prefix[:len(t.prefix)-1]...)
+		return append(b, "}\n"...)
+	}
+	b = append(b, t.prefix...)
+	b = append(b, record[1:]...)
+	return append(b, '\n')
+}
+
+func (t *sharedTrace) Close() error {
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+	var err error
+	// write a trailing record that wasn't terminated by a newline
+	if t.buf.Len() > 0 {
+		err = t.writer.writeRecords(t.appendRecord(nil, t.buf.Bytes()))
+		t.buf.Reset()
+	}
+	if cerr := t.writer.closeTrace(); cerr != nil {
+		return cerr
+	}
+	return err
+}
@@ -0,0 +1,142 @@
+package qlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SharedWriter", func() {
+	type record struct {
+		GroupID string                 `json:"group_id"`
+		Name    string                 `json:"name"`
+		Data    map[string]interface{} `json:"data"`
+		Trace   *struct {
+			VantagePoint struct {
+				Type string `json:"type"`
+			} `json:"vantage_point"`
+		} `json:"trace"`
+	}
+
+	parse := func(b []byte) []record {
+		var records []record
+		for _, line := range bytes.Split(bytes.TrimSpace(b), []byte{'\n'}) {
+			var r record
+			ExpectWithOffset(1, json.Unmarshal(line, &r)).To(Succeed())
+			records = append(records, r)
+		}
+		return records
+	}
+
+	It("writes the qlogs of multiple connections into one file, tagging every record with the group_id", func() {
+		f := &closeRecorder{}
+		s := NewSharedWriter(f)
+		odcid1 := protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef}
+		odcid2 := protocol.ConnectionID{0xca, 0xfe}
+		client := NewConnectionTracer(s.Writer(odcid1), protocol.PerspectiveClient, odcid1)
+		server := NewConnectionTracer(s.Writer(odcid2), protocol.PerspectiveServer, odcid2)
+		client.UpdatedMTU(1337)
+		server.UpdatedMTU(1400)
+		client.Close()
+		server.Close()
+		Expect(f.closed).To(BeFalse())
+		Expect(s.Close()).To(Succeed())
+		Expect(f.closed).To(BeTrue())
+
+		records := parse(f.Bytes())
+		Expect(records).To(HaveLen(4))
+		var clientRecords, serverRecords []record
+		for _, r := range records {
+			switch r.GroupID {
+			case "deadbeef":
+				clientRecords = append(clientRecords, r)
+			case "cafe":
+				serverRecords = append(serverRecords, r)
+			default:
+				Fail(fmt.Sprintf("unexpected group_id: %s", r.GroupID))
+			}
+		}
+		Expect(clientRecords).To(HaveLen(2))
+		Expect(clientRecords[0].Trace).ToNot(BeNil())
+		Expect(clientRecords[0].Trace.VantagePoint.Type).To(Equal("client"))
+		Expect(clientRecords[1].Name).To(Equal("connectivity:mtu_updated"))
+		Expect(clientRecords[1].Data).To(HaveKeyWithValue("new", float64(1337)))
+		Expect(serverRecords).To(HaveLen(2))
+		Expect(serverRecords[0].Trace.VantagePoint.Type).To(Equal("server"))
+		Expect(serverRecords[1].Data).To(HaveKeyWithValue("new", float64(1400)))
+	})
+
+	It("closes the file when closed after all connection writers", func() {
+		f := &closeRecorder{}
+		s := NewSharedWriter(f)
+		w := s.Writer([]byte{1, 2, 3})
+		Expect(s.Close()).To(Succeed())
+		Expect(f.closed).To(BeFalse())
+		Expect(w.Close()).To(Succeed())
+		Expect(f.closed).To(BeTrue())
+		Expect(f.Len()).To(BeZero())
+	})
+
+	It("only writes complete records", func() {
+		f := &closeRecorder{}
+		s := NewSharedWriter(f)
+		w := s.Writer([]byte{0x42})
+		_, err := w.Write([]byte(`{"name":"foo`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(f.Len()).To(BeZero())
+		_, err = w.Write([]byte("\"}\n{}\n{\"name\":"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(f.String()).To(Equal("{\"group_id\":\"42\",\"name\":\"foo\"}\n{\"group_id\":\"42\"}\n"))
+		// the last record is written when the writer is closed
+		_, err = w.Write([]byte(`"bar"}`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(w.Close()).To(Succeed())
+		Expect(parse(f.Bytes())).To(HaveLen(3))
+	})
+
+	It("doesn't interleave the records of concurrent connections", func() {
+		f := &closeRecorder{}
+		s := NewSharedWriter(f)
+		const num = 10
+		var wg sync.WaitGroup
+		wg.Add(num)
+		for i := 0; i < num; i++ {
+			w := s.Writer([]byte{byte(i)})
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					// write every record in multiple chunks
+					rec := []byte(fmt.Sprintf(`{"name":"event","data":{"conn":%d,"num":%d}}`+"\n", i, j))
+					for len(rec) > 0 {
+						n := 7
+						if n > len(rec) {
+							n = len(rec)
+						}
+						_, err := w.Write(rec[:n])
+						Expect(err).ToNot(HaveOccurred())
+						rec = rec[n:]
+					}
+				}
+				Expect(w.Close()).To(Succeed())
+			}(i)
+		}
+		wg.Wait()
+		Expect(s.Close()).To(Succeed())
+		records := parse(f.Bytes())
+		Expect(records).To(HaveLen(num * 100))
+		next := make(map[string]float64)
+		for _, r := range records {
+			Expect(r.GroupID).To(Equal(fmt.Sprintf("%02x", int(r.Data["conn"].(float64)))))
+			// the records of every connection are written in order
+			Expect(r.Data["num"]).To(Equal(next[r.GroupID]))
+			next[r.GroupID]++
+		}
+	})
+})
@@ -2,6 +2,7 @@ package qlog
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
@@ -45,31 +46,59 @@ func (c connectionID) String() string {
 	return fmt.Sprintf("%x", []byte(c))
 }
 
-// category is the qlog event category.
-type category uint8
+// A Category is a qlog event category.
+type Category uint8
 
 const (
-	categoryConnectivity category = iota
-	categoryTransport
-	categorySecurity
-	categoryRecovery
+	// CategoryConnectivity contains events related to the network path, e.g. MTU updates.
+	CategoryConnectivity Category = iota
+	// CategoryTransport contains events related to the QUIC connection, e.g. sent and received packets.
+	CategoryTransport
+	// CategorySecurity contains events related to the TLS handshake and key updates.
+	CategorySecurity
+	// CategoryRecovery contains events related to loss recovery and congestion control.
+	CategoryRecovery
+	// CategoryHTTP contains HTTP/3 events.
+	// The tracer doesn't record any HTTP/3 events yet.
+	CategoryHTTP
 )
 
-func (c category) String() string {
+func (c Category) String() string {
 	switch c {
-	case categoryConnectivity:
+	case CategoryConnectivity:
 		return "connectivity"
-	case categoryTransport:
+	case CategoryTransport:
 		return "transport"
-	case categorySecurity:
+	case CategorySecurity:
 		return "security"
-	case categoryRecovery:
+	case CategoryRecovery:
 		return "recovery"
+	case CategoryHTTP:
+		return "http"
 	default:
 		return "unknown category"
 	}
 }
 
+// ParseCategory parses the name of an event category.
+// It accepts the names returned by Category.String, ignoring case.
+func ParseCategory(s string) (Category, error) {
+	switch strings.ToLower(s) {
+	case "connectivity":
+		return CategoryConnectivity, nil
+	case "transport":
+		return CategoryTransport, nil
+	case "security":
+		return CategorySecurity, nil
+	case "recovery":
+		return CategoryRecovery, nil
+	case "http":
+		return CategoryHTTP, nil
+	default:
+		return 0, fmt.Errorf("unknown qlog event category: %s", s)
+	}
+}
+
 type versionNumber protocol.VersionNumber
 
 func (v versionNumber) String() string {
@@ -215,6 +244,8 @@ func (e transportError) String() string {
 		return "aead_limit_reached"
 	case qerr.NoViablePathError:
 		return "no_viable_path"
+	case qerr.VersionNegotiationErrorErrorCode:
+		return "version_negotiation_error"
 	default:
 		return ""
 	}
@@ -253,6 +284,8 @@ func (r packetLossReason) String() string {
 		return "reordering_threshold"
 	case logging.PacketLossTimeThreshold:
 		return "time_threshold"
+	case logging.PacketLossPTO:
+		return "pto_expired"
 	default:
 		return "unknown loss reason"
 	}
@@ -316,6 +349,12 @@ func (s congestionState) String() string {
 		return "recovery"
 	case logging.CongestionStateApplicationLimited:
 		return "application_limited"
+	case logging.CongestionStateBBRDrain:
+		return "bbr_drain"
+	case logging.CongestionStateBBRProbeBW:
+		return "bbr_probe_bw"
+	case logging.CongestionStateBBRProbeRTT:
+		return "bbr_probe_rtt"
 	default:
 		return "unknown congestion state"
 	}
@@ -23,10 +23,24 @@ var _ = Describe("Types", func() {
 	})
 
 	It("has a string representation for the category", func() {
-		Expect(categoryConnectivity.String()).To(Equal("connectivity"))
-		Expect(categoryTransport.String()).To(Equal("transport"))
-		Expect(categoryRecovery.String()).To(Equal("recovery"))
-		Expect(categorySecurity.String()).To(Equal("security"))
+		Expect(CategoryConnectivity.String()).To(Equal("connectivity"))
+		Expect(CategoryTransport.String()).To(Equal("transport"))
+		Expect(CategoryRecovery.String()).To(Equal("recovery"))
+		Expect(CategorySecurity.String()).To(Equal("security"))
+		Expect(CategoryHTTP.String()).To(Equal("http"))
+	})
+
+	It("parses categories", func() {
+		for _, c := range []Category{CategoryConnectivity, CategoryTransport, CategorySecurity, CategoryRecovery, CategoryHTTP} {
+			parsed, err := ParseCategory(c.String())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed).To(Equal(c))
+		}
+		parsed, err := ParseCategory("Recovery")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed).To(Equal(CategoryRecovery))
+		_, err = ParseCategory("foobar")
+		Expect(err).To(MatchError("unknown qlog event category: foobar"))
 	})
 
 	It("has a string representation for the packet type", func() {
@@ -117,6 +131,7 @@ var _ = Describe("Types", func() {
 			Expect(transportError(qerr.ApplicationErrorErrorCode).String()).To(Equal("application_error"))
 			Expect(transportError(qerr.CryptoBufferExceeded).String()).To(Equal("crypto_buffer_exceeded"))
 			Expect(transportError(qerr.NoViablePathError).String()).To(Equal("no_viable_path"))
+			Expect(transportError(qerr.VersionNegotiationErrorErrorCode).String()).To(Equal("version_negotiation_error"))
 			Expect(transportError(1337).String()).To(BeEmpty())
 		})
 	})
@@ -126,5 +141,8 @@ var _ = Describe("Types", func() {
 		Expect(congestionState(logging.CongestionStateCongestionAvoidance).String()).To(Equal("congestion_avoidance"))
 		Expect(congestionState(logging.CongestionStateApplicationLimited).String()).To(Equal("application_limited"))
 		Expect(congestionState(logging.CongestionStateRecovery).String()).To(Equal("recovery"))
+		Expect(congestionState(logging.CongestionStateBBRDrain).String()).To(Equal("bbr_drain"))
+		Expect(congestionState(logging.CongestionStateBBRProbeBW).String()).To(Equal("bbr_probe_bw"))
+		Expect(congestionState(logging.CongestionStateBBRProbeRTT).String()).To(Equal("bbr_probe_rtt"))
 	})
 })
@@ -20,6 +20,7 @@ type receiveStreamI interface {
 	handleResetStreamFrame(*wire.ResetStreamFrame) error
 	closeForShutdown(error)
 	getWindowUpdate() protocol.ByteCount
+	stats() StreamStats
 }
 
 type receiveStream struct {
@@ -209,7 +210,7 @@ func (s *receiveStream) CancelRead(errorCode StreamErrorCode) {
 }
 
 func (s *receiveStream) cancelReadImpl(errorCode qerr.StreamErrorCode) bool /* completed */ {
-	if s.finRead || s.canceledRead || s.resetRemotely {
+	if s.finRead || s.canceledRead || s.resetRemotely || s.closedForShutdown {
 		return false
 	}
 	s.canceledRead = true
@@ -305,6 +306,15 @@ func (s *receiveStream) SetReadDeadline(t time.Time) error {
 // CloseForShutdown closes a stream abruptly.
 // It makes Read unblock (and return the error) immediately.
 // The peer will NOT be informed about this: the stream is closed without sending a FIN or RESET.
+func (s *receiveStream) stats() StreamStats {
+	highestReceived, receiveWindow := s.flowController.ReceiveWindow()
+	return StreamStats{
+		StreamID:      s.streamID,
+		BytesReceived: highestReceived,
+		ReceiveWindow: receiveWindow,
+	}
+}
+
 func (s *receiveStream) closeForShutdown(err error) {
 	s.mutex.Lock()
 	s.closedForShutdown = true
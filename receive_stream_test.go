@@ -443,6 +443,14 @@ var _ = Describe("Receive Stream", func() {
 				Expect(n).To(BeZero())
 				Expect(err).To(MatchError(testErr))
 			})
+
+			It("doesn't send a STOP_SENDING frame when canceled after it was closed for shutdown", func() {
+				str.closeForShutdown(testErr)
+				// don't EXPECT any calls to queueControlFrame
+				str.CancelRead(1234)
+				_, err := strWithTimeout.Read([]byte{0})
+				Expect(err).To(MatchError(testErr))
+			})
 		})
 	})
 
@@ -638,6 +646,15 @@ var _ = Describe("Receive Stream", func() {
 		})
 	})
 
+	It("returns the stats", func() {
+		mockFC.EXPECT().ReceiveWindow().Return(protocol.ByteCount(100), protocol.ByteCount(1000))
+		Expect(str.stats()).To(Equal(StreamStats{
+			StreamID:      streamID,
+			BytesReceived: 100,
+			ReceiveWindow: 1000,
+		}))
+	})
+
 	Context("flow control", func() {
 		It("errors when a STREAM frame causes a flow control violation", func() {
 			testErr := errors.New("flow control violation")
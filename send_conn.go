@@ -2,44 +2,95 @@ package quic
 
 import (
 	"net"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
 // A sendConn allows sending using a simple Write() on a non-connected packet conn.
 type sendConn interface {
-	Write([]byte) error
+	Write([]byte, protocol.ECN) error
+	// WriteGSO sends multiple packets in a single syscall, using UDP generic segmentation offload (GSO).
+	// All packets have the size segmentSize, except for the last one, which may be shorter.
+	// It must only be called if SupportsGSO returns true.
+	WriteGSO(b []byte, segmentSize uint16, ecn protocol.ECN) error
+	SupportsGSO() bool
 	Close() error
 	LocalAddr() net.Addr
 	RemoteAddr() net.Addr
 }
 
+// A pathConn is a sendConn that can send packets to a different remote address, using the same socket.
+type pathConn interface {
+	sendConn
+	withRemoteAddr(net.Addr) sendConn
+}
+
+// ecnOOBs holds the control messages used to send packets marked with each of the ECN codepoints.
+type ecnOOBs [4][]byte
+
+// newECNOOBs creates the control messages for the ECN codepoints.
+// Packets that are not ECN-marked get the DSCP from the socket option (see setDSCP).
+func newECNOOBs(oob []byte, dscp uint8, remote net.Addr) ecnOOBs {
+	var oobs ecnOOBs
+	oobs[protocol.ECNNon] = oob
+	for _, ecn := range []protocol.ECN{protocol.ECT1, protocol.ECT0, protocol.ECNCE} {
+		oobs[ecn] = appendECNControlMessage(append([]byte{}, oob...), ecn, dscp, remote)
+	}
+	return oobs
+}
+
 type sconn struct {
 	connection
 
 	remoteAddr net.Addr
 	info       *packetInfo
-	oob        []byte
+	dscp       uint8
+	oobs       ecnOOBs
+	gso        bool
 }
 
-var _ sendConn = &sconn{}
+var _ pathConn = &sconn{}
 
-func newSendConn(c connection, remote net.Addr, info *packetInfo) sendConn {
+func newSendConn(c connection, remote net.Addr, info *packetInfo, dscp uint8) sendConn {
+	var gso bool
+	if gc, ok := c.(gsoCapableConn); ok {
+		gso = gc.supportsGSO()
+	}
 	return &sconn{
 		connection: c,
 		remoteAddr: remote,
 		info:       info,
-		oob:        info.OOB(),
+		dscp:       dscp,
+		oobs:       newECNOOBs(info.OOB(), dscp, remote),
+		gso:        gso,
 	}
 }
 
-func (c *sconn) Write(p []byte) error {
-	_, err := c.WritePacket(p, c.remoteAddr, c.oob)
+func (c *sconn) Write(p []byte, ecn protocol.ECN) error {
+	_, err := c.WritePacket(p, c.remoteAddr, c.oobs[ecn])
+	return err
+}
+
+func (c *sconn) WriteGSO(p []byte, segmentSize uint16, ecn protocol.ECN) error {
+	oob := appendUDPSegmentSizeMsg(append([]byte{}, c.oobs[ecn]...), segmentSize)
+	_, err := c.WritePacket(p, c.remoteAddr, oob)
 	return err
 }
 
+func (c *sconn) SupportsGSO() bool {
+	return c.gso
+}
+
 func (c *sconn) RemoteAddr() net.Addr {
 	return c.remoteAddr
 }
 
+func (c *sconn) withRemoteAddr(remote net.Addr) sendConn {
+	return newSendConn(c.connection, remote, c.info, c.dscp)
+}
+
 func (c *sconn) LocalAddr() net.Addr {
 	addr := c.connection.LocalAddr()
 	if c.info != nil {
@@ -56,19 +107,123 @@ type spconn struct {
 	net.PacketConn
 
 	remoteAddr net.Addr
+	dscp       uint8
+	oobs       ecnOOBs
+	gso        bool
 }
 
-var _ sendConn = &spconn{}
+var _ pathConn = &spconn{}
 
-func newSendPconn(c net.PacketConn, remote net.Addr) sendConn {
-	return &spconn{PacketConn: c, remoteAddr: remote}
+func newSendPconn(c net.PacketConn, remote net.Addr, dscp uint8) sendConn {
+	// GSO requires passing a control message, so it can only be used with an OOBCapablePacketConn.
+	var gso bool
+	if _, ok := remote.(*net.UDPAddr); ok {
+		if oobConn, ok := c.(OOBCapablePacketConn); ok {
+			if rawConn, err := oobConn.SyscallConn(); err == nil {
+				gso = isGSOSupported(rawConn)
+			}
+		}
+	}
+	return &spconn{
+		PacketConn: c,
+		remoteAddr: remote,
+		dscp:       dscp,
+		oobs:       newECNOOBs(nil, dscp, remote),
+		gso:        gso,
+	}
 }
 
-func (c *spconn) Write(p []byte) error {
+func (c *spconn) Write(p []byte, ecn protocol.ECN) error {
+	// ECN marks can only be set if the packet conn allows us to pass control messages.
+	if oob := c.oobs[ecn]; len(oob) > 0 {
+		if oobConn, ok := c.PacketConn.(OOBCapablePacketConn); ok {
+			if udpAddr, ok := c.remoteAddr.(*net.UDPAddr); ok {
+				_, _, err := oobConn.WriteMsgUDP(p, oob, udpAddr)
+				return err
+			}
+		}
+	}
 	_, err := c.WriteTo(p, c.remoteAddr)
 	return err
 }
 
+func (c *spconn) WriteGSO(p []byte, segmentSize uint16, ecn protocol.ECN) error {
+	oob := appendUDPSegmentSizeMsg(append([]byte{}, c.oobs[ecn]...), segmentSize)
+	_, _, err := c.PacketConn.(OOBCapablePacketConn).WriteMsgUDP(p, oob, c.remoteAddr.(*net.UDPAddr))
+	return err
+}
+
+func (c *spconn) SupportsGSO() bool {
+	return c.gso
+}
+
 func (c *spconn) RemoteAddr() net.Addr {
 	return c.remoteAddr
 }
+
+func (c *spconn) withRemoteAddr(remote net.Addr) sendConn {
+	return newSendPconn(c.PacketConn, remote, c.dscp)
+}
+
+// A migratableConn is a sendConn that can be switched to a new network path when the connection migrates.
+// It is safe to switch the path while packets are being sent.
+type migratableConn struct {
+	mutex sync.RWMutex
+	conn  sendConn
+}
+
+var _ sendConn = &migratableConn{}
+
+func newMigratableConn(c sendConn) *migratableConn {
+	return &migratableConn{conn: c}
+}
+
+func (c *migratableConn) Write(p []byte, ecn protocol.ECN) error {
+	return c.Path().Write(p, ecn)
+}
+
+func (c *migratableConn) WriteGSO(p []byte, segmentSize uint16, ecn protocol.ECN) error {
+	path := c.Path()
+	if !path.SupportsGSO() {
+		// The connection switched to a path that doesn't support GSO after SupportsGSO was called.
+		for len(p) > 0 {
+			n := utils.Min(int(segmentSize), len(p))
+			if err := path.Write(p[:n], ecn); err != nil {
+				return err
+			}
+			p = p[n:]
+		}
+		return nil
+	}
+	return path.WriteGSO(p, segmentSize, ecn)
+}
+
+func (c *migratableConn) SupportsGSO() bool {
+	return c.Path().SupportsGSO()
+}
+
+func (c *migratableConn) Close() error {
+	return c.Path().Close()
+}
+
+func (c *migratableConn) LocalAddr() net.Addr {
+	return c.Path().LocalAddr()
+}
+
+func (c *migratableConn) RemoteAddr() net.Addr {
+	return c.Path().RemoteAddr()
+}
+
+// Path returns the sendConn of the current path.
+func (c *migratableConn) Path() sendConn {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.conn
+}
+
+// SetPath switches to a new path.
+func (c *migratableConn) SetPath(conn sendConn) {
+	c.mutex.Lock()
+	c.conn = conn
+	c.mutex.Unlock()
+}
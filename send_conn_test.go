@@ -3,6 +3,9 @@ package quic
 import (
 	"net"
 
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -17,12 +20,21 @@ var _ = Describe("Connection (for sending packets)", func() {
 	BeforeEach(func() {
 		addr = &net.UDPAddr{IP: net.IPv4(192, 168, 100, 200), Port: 1337}
 		packetConn = NewMockPacketConn(mockCtrl)
-		c = newSendPconn(packetConn, addr)
+		c = newSendPconn(packetConn, addr, 0)
 	})
 
 	It("writes", func() {
 		packetConn.EXPECT().WriteTo([]byte("foobar"), addr)
-		Expect(c.Write([]byte("foobar"))).To(Succeed())
+		Expect(c.Write([]byte("foobar"), protocol.ECNNon)).To(Succeed())
+	})
+
+	It("writes ECN-marked packets, if the packet conn doesn't support setting ECN marks", func() {
+		packetConn.EXPECT().WriteTo([]byte("foobar"), addr)
+		Expect(c.Write([]byte("foobar"), protocol.ECT0)).To(Succeed())
+	})
+
+	It("doesn't use GSO, if the packet conn doesn't support it", func() {
+		Expect(c.SupportsGSO()).To(BeFalse())
 	})
 
 	It("gets the remote address", func() {
@@ -42,4 +54,48 @@ var _ = Describe("Connection (for sending packets)", func() {
 		packetConn.EXPECT().Close()
 		Expect(c.Close()).To(Succeed())
 	})
+
+	It("uses a new remote address", func() {
+		remote := &net.UDPAddr{IP: net.IPv4(192, 168, 100, 201), Port: 4242}
+		conn := c.(pathConn).withRemoteAddr(remote)
+		Expect(conn.RemoteAddr()).To(Equal(remote))
+		packetConn.EXPECT().WriteTo([]byte("foobar"), remote)
+		Expect(conn.Write([]byte("foobar"), protocol.ECNNon)).To(Succeed())
+	})
+})
+
+var _ = Describe("Migratable Connection", func() {
+	It("switches to a new path", func() {
+		addr1 := &net.UDPAddr{IP: net.IPv4(192, 168, 100, 200), Port: 1337}
+		addr2 := &net.UDPAddr{IP: net.IPv4(192, 168, 100, 201), Port: 1337}
+		conn1 := NewMockPacketConn(mockCtrl)
+		conn2 := NewMockPacketConn(mockCtrl)
+		path1 := newSendPconn(conn1, addr1, 0)
+		path2 := newSendPconn(conn2, addr2, 0)
+		c := newMigratableConn(path1)
+		Expect(c.Path()).To(Equal(path1))
+		Expect(c.RemoteAddr()).To(Equal(addr1))
+		conn1.EXPECT().WriteTo([]byte("foo"), addr1)
+		Expect(c.Write([]byte("foo"), protocol.ECNNon)).To(Succeed())
+		c.SetPath(path2)
+		Expect(c.Path()).To(Equal(path2))
+		Expect(c.RemoteAddr()).To(Equal(addr2))
+		conn2.EXPECT().WriteTo([]byte("bar"), addr2)
+		Expect(c.Write([]byte("bar"), protocol.ECNNon)).To(Succeed())
+		conn2.EXPECT().Close()
+		Expect(c.Close()).To(Succeed())
+	})
+
+	It("sends packets one by one, if the path doesn't support GSO", func() {
+		addr := &net.UDPAddr{IP: net.IPv4(192, 168, 100, 200), Port: 1337}
+		conn := NewMockPacketConn(mockCtrl)
+		c := newMigratableConn(newSendPconn(conn, addr, 0))
+		Expect(c.SupportsGSO()).To(BeFalse())
+		gomock.InOrder(
+			conn.EXPECT().WriteTo([]byte("foo"), addr),
+			conn.EXPECT().WriteTo([]byte("bar"), addr),
+			conn.EXPECT().WriteTo([]byte("ba"), addr),
+		)
+		Expect(c.WriteGSO([]byte("foobarba"), 3, protocol.ECNNon)).To(Succeed())
+	})
 })
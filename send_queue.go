@@ -1,41 +1,65 @@
 package quic
 
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
 type sender interface {
-	Send(p *packetBuffer)
+	Send(p *packetBuffer, ecn protocol.ECN)
 	Run() error
 	WouldBlock() bool
 	Available() <-chan struct{}
 	Close()
 }
 
+type queueEntry struct {
+	buf *packetBuffer
+	ecn protocol.ECN
+}
+
 type sendQueue struct {
-	queue       chan *packetBuffer
+	queue       chan queueEntry
 	closeCalled chan struct{} // runStopped when Close() is called
 	runStopped  chan struct{} // runStopped when the run loop returns
 	available   chan struct{}
 	conn        sendConn
+
+	gso    bool // use GSO, if the sendConn supports it
+	batch  []queueEntry
+	gsoBuf []byte
 }
 
 var _ sender = &sendQueue{}
 
 const sendQueueCapacity = 8
 
-func newSendQueue(conn sendConn) sender {
-	return &sendQueue{
+// The maximum number of packets sent in a single GSO syscall.
+// The kernel allows up to 64 segments, but the total size must not exceed the maximum size of a UDP datagram.
+const maxGSOSegments = 32
+
+func newSendQueue(conn sendConn, enableGSO bool) sender {
+	q := &sendQueue{
 		conn:        conn,
 		runStopped:  make(chan struct{}),
 		closeCalled: make(chan struct{}),
 		available:   make(chan struct{}, 1),
-		queue:       make(chan *packetBuffer, sendQueueCapacity),
+		queue:       make(chan queueEntry, sendQueueCapacity),
+		gso:         enableGSO,
+	}
+	if enableGSO {
+		q.batch = make([]queueEntry, 0, maxGSOSegments)
+		q.gsoBuf = make([]byte, 0, maxGSOSegments*protocol.MaxPacketBufferSize)
 	}
+	return q
 }
 
 // Send sends out a packet. It's guaranteed to not block.
 // Callers need to make sure that there's actually space in the send queue by calling WouldBlock.
 // Otherwise Send will panic.
-func (h *sendQueue) Send(p *packetBuffer) {
+func (h *sendQueue) Send(p *packetBuffer, ecn protocol.ECN) {
 	select {
-	case h.queue <- p:
+	case h.queue <- queueEntry{buf: p, ecn: ecn}:
 	case <-h.runStopped:
 	default:
 		panic("sendQueue.Send would have blocked")
@@ -62,11 +86,10 @@ func (h *sendQueue) Run() error {
 			h.closeCalled = nil // prevent this case from being selected again
 			// make sure that all queued packets are actually sent out
 			shouldClose = true
-		case p := <-h.queue:
-			if err := h.conn.Write(p.Data); err != nil {
+		case e := <-h.queue:
+			if err := h.send(e); err != nil {
 				return err
 			}
-			p.Release()
 			select {
 			case h.available <- struct{}{}:
 			default:
@@ -75,6 +98,75 @@ func (h *sendQueue) Run() error {
 	}
 }
 
+// send sends a packet.
+// When using GSO, the packets queued after it are sent in the same syscall,
+// as long as they have the same size and ECN marking.
+func (h *sendQueue) send(e queueEntry) error {
+	for {
+		if !h.gso || !h.conn.SupportsGSO() {
+			err := h.conn.Write(e.buf.Data, e.ecn)
+			e.buf.Release()
+			return err
+		}
+		batch := append(h.batch[:0], e)
+		segmentSize := len(e.buf.Data)
+		var next *queueEntry
+	dequeue:
+		// Only the last packet of a batch may be shorter than the segment size.
+		// The size of the batch is limited by the size of the GSO buffer, which limits the number of large packets.
+		for len(batch) < maxGSOSegments && (len(batch)+1)*segmentSize <= cap(h.gsoBuf) && len(batch[len(batch)-1].buf.Data) == segmentSize {
+			select {
+			case n := <-h.queue:
+				if n.ecn != e.ecn || len(n.buf.Data) > segmentSize {
+					next = &n
+					break dequeue
+				}
+				batch = append(batch, n)
+			default:
+				break dequeue
+			}
+		}
+		if err := h.sendBatch(batch); err != nil {
+			if next != nil {
+				next.buf.Release()
+			}
+			return err
+		}
+		if next == nil {
+			return nil
+		}
+		e = *next
+	}
+}
+
+func (h *sendQueue) sendBatch(batch []queueEntry) error {
+	defer func() {
+		for _, e := range batch {
+			e.buf.Release()
+		}
+	}()
+	if len(batch) == 1 {
+		return h.conn.Write(batch[0].buf.Data, batch[0].ecn)
+	}
+	b := h.gsoBuf[:0]
+	for _, e := range batch {
+		b = append(b, e.buf.Data...)
+	}
+	err := h.conn.WriteGSO(b, uint16(len(batch[0].buf.Data)), batch[0].ecn)
+	if err == nil || !isGSOError(err) {
+		return err
+	}
+	// The network interface doesn't support GSO. Send the packets one by one from now on.
+	utils.DefaultLogger.Debugf("Disabling GSO: %s", err)
+	h.gso = false
+	for _, e := range batch {
+		if err := h.conn.Write(e.buf.Data, e.ecn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (h *sendQueue) Close() {
 	close(h.closeCalled)
 	// wait until the run loop returned
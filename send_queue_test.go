@@ -2,8 +2,17 @@ package quic
 
 import (
 	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -14,11 +23,11 @@ var _ = Describe("Send Queue", func() {
 
 	BeforeEach(func() {
 		c = NewMockSendConn(mockCtrl)
-		q = newSendQueue(c)
+		q = newSendQueue(c, false)
 	})
 
 	getPacket := func(b []byte) *packetBuffer {
-		buf := getPacketBuffer()
+		buf := getPacketBufferForSize(protocol.ByteCount(len(b)))
 		buf.Data = buf.Data[:len(b)]
 		copy(buf.Data, b)
 		return buf
@@ -26,10 +35,10 @@ var _ = Describe("Send Queue", func() {
 
 	It("sends a packet", func() {
 		p := getPacket([]byte("foobar"))
-		q.Send(p)
+		q.Send(p, protocol.ECNNon)
 
 		written := make(chan struct{})
-		c.EXPECT().Write([]byte("foobar")).Do(func([]byte) { close(written) })
+		c.EXPECT().Write([]byte("foobar"), gomock.Any()).Do(func([]byte, protocol.ECN) { close(written) })
 		done := make(chan struct{})
 		go func() {
 			defer GinkgoRecover()
@@ -45,19 +54,19 @@ var _ = Describe("Send Queue", func() {
 	It("panics when Send() is called although there's no space in the queue", func() {
 		for i := 0; i < sendQueueCapacity; i++ {
 			Expect(q.WouldBlock()).To(BeFalse())
-			q.Send(getPacket([]byte("foobar")))
+			q.Send(getPacket([]byte("foobar")), protocol.ECNNon)
 		}
 		Expect(q.WouldBlock()).To(BeTrue())
-		Expect(func() { q.Send(getPacket([]byte("raboof"))) }).To(Panic())
+		Expect(func() { q.Send(getPacket([]byte("raboof")), protocol.ECNNon) }).To(Panic())
 	})
 
 	It("signals when sending is possible again", func() {
 		Expect(q.WouldBlock()).To(BeFalse())
-		q.Send(getPacket([]byte("foobar1")))
+		q.Send(getPacket([]byte("foobar1")), protocol.ECNNon)
 		Consistently(q.Available()).ShouldNot(Receive())
 
 		// now start sending out packets. This should free up queue space.
-		c.EXPECT().Write(gomock.Any()).MinTimes(1).MaxTimes(2)
+		c.EXPECT().Write(gomock.Any(), gomock.Any()).MinTimes(1).MaxTimes(2)
 		done := make(chan struct{})
 		go func() {
 			defer GinkgoRecover()
@@ -67,7 +76,7 @@ var _ = Describe("Send Queue", func() {
 
 		Eventually(q.Available()).Should(Receive())
 		Expect(q.WouldBlock()).To(BeFalse())
-		Expect(func() { q.Send(getPacket([]byte("foobar2"))) }).ToNot(Panic())
+		Expect(func() { q.Send(getPacket([]byte("foobar2")), protocol.ECNNon) }).ToNot(Panic())
 
 		q.Close()
 		Eventually(done).Should(BeClosed())
@@ -83,15 +92,15 @@ var _ = Describe("Send Queue", func() {
 
 		// the run loop exits if there is a write error
 		testErr := errors.New("test error")
-		c.EXPECT().Write(gomock.Any()).Return(testErr)
-		q.Send(getPacket([]byte("foobar")))
+		c.EXPECT().Write(gomock.Any(), gomock.Any()).Return(testErr)
+		q.Send(getPacket([]byte("foobar")), protocol.ECNNon)
 		Eventually(done).Should(BeClosed())
 
 		sent := make(chan struct{})
 		go func() {
 			defer GinkgoRecover()
-			q.Send(getPacket([]byte("raboof")))
-			q.Send(getPacket([]byte("quux")))
+			q.Send(getPacket([]byte("raboof")), protocol.ECNNon)
+			q.Send(getPacket([]byte("quux")), protocol.ECNNon)
 			close(sent)
 		}()
 
@@ -100,7 +109,7 @@ var _ = Describe("Send Queue", func() {
 
 	It("blocks Close() until the packet has been sent out", func() {
 		written := make(chan []byte)
-		c.EXPECT().Write(gomock.Any()).Do(func(p []byte) { written <- p })
+		c.EXPECT().Write(gomock.Any(), gomock.Any()).Do(func(p []byte, _ protocol.ECN) { written <- p })
 		done := make(chan struct{})
 		go func() {
 			defer GinkgoRecover()
@@ -108,7 +117,7 @@ var _ = Describe("Send Queue", func() {
 			close(done)
 		}()
 
-		q.Send(getPacket([]byte("foobar")))
+		q.Send(getPacket([]byte("foobar")), protocol.ECNNon)
 
 		closed := make(chan struct{})
 		go func() {
@@ -123,4 +132,173 @@ var _ = Describe("Send Queue", func() {
 		Eventually(done).Should(BeClosed())
 		Eventually(closed).Should(BeClosed())
 	})
+
+	Context("using GSO", func() {
+		BeforeEach(func() {
+			q = newSendQueue(c, true)
+		})
+
+		run := func() (done <-chan struct{}) {
+			d := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				q.Run()
+				close(d)
+			}()
+			return d
+		}
+
+		It("sends packets in a single syscall", func() {
+			q.Send(getPacket([]byte("foobar")), protocol.ECT0)
+			q.Send(getPacket([]byte("raboof")), protocol.ECT0)
+			q.Send(getPacket([]byte("foo")), protocol.ECT0)
+			c.EXPECT().SupportsGSO().Return(true).AnyTimes()
+			written := make(chan struct{})
+			c.EXPECT().WriteGSO([]byte("foobarrabooffoo"), uint16(6), protocol.ECT0).Do(func([]byte, uint16, protocol.ECN) { close(written) })
+			done := run()
+			Eventually(written).Should(BeClosed())
+			q.Close()
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("only sends packets with the same size and ECN marking in a single syscall", func() {
+			q.Send(getPacket([]byte("foobar")), protocol.ECT0)
+			q.Send(getPacket([]byte("raboof")), protocol.ECT0)
+			q.Send(getPacket([]byte("foobar")), protocol.ECNNon)  // different ECN marking
+			q.Send(getPacket([]byte("foobar!")), protocol.ECNNon) // larger than the previous packet
+			q.Send(getPacket([]byte("foo")), protocol.ECNNon)
+			q.Send(getPacket([]byte("bar")), protocol.ECNNon) // follows a shorter packet
+			c.EXPECT().SupportsGSO().Return(true).AnyTimes()
+			written := make(chan struct{})
+			gomock.InOrder(
+				c.EXPECT().WriteGSO([]byte("foobarraboof"), uint16(6), protocol.ECT0),
+				c.EXPECT().Write([]byte("foobar"), protocol.ECNNon),
+				c.EXPECT().WriteGSO([]byte("foobar!foo"), uint16(7), protocol.ECNNon),
+				c.EXPECT().Write([]byte("bar"), protocol.ECNNon).Do(func([]byte, protocol.ECN) { close(written) }),
+			)
+			done := run()
+			Eventually(written).Should(BeClosed())
+			q.Close()
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("limits the total size of a batch of large packets", func() {
+			// 5 packets of 8000 bytes fit into the GSO buffer, 6 packets don't
+			for i := 0; i < 7; i++ {
+				q.Send(getPacket(make([]byte, 8000)), protocol.ECNNon)
+			}
+			c.EXPECT().SupportsGSO().Return(true).AnyTimes()
+			written := make(chan struct{})
+			gomock.InOrder(
+				c.EXPECT().WriteGSO(gomock.Any(), uint16(8000), protocol.ECNNon).Do(func(b []byte, _ uint16, _ protocol.ECN) {
+					Expect(b).To(HaveLen(5 * 8000))
+				}),
+				c.EXPECT().WriteGSO(gomock.Any(), uint16(8000), protocol.ECNNon).Do(func(b []byte, _ uint16, _ protocol.ECN) {
+					Expect(b).To(HaveLen(2 * 8000))
+					close(written)
+				}),
+			)
+			done := run()
+			Eventually(written).Should(BeClosed())
+			q.Close()
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("doesn't use GSO if the connection doesn't support it", func() {
+			q.Send(getPacket([]byte("foobar")), protocol.ECNNon)
+			q.Send(getPacket([]byte("raboof")), protocol.ECNNon)
+			c.EXPECT().SupportsGSO().Return(false).AnyTimes()
+			written := make(chan struct{})
+			gomock.InOrder(
+				c.EXPECT().Write([]byte("foobar"), protocol.ECNNon),
+				c.EXPECT().Write([]byte("raboof"), protocol.ECNNon).Do(func([]byte, protocol.ECN) { close(written) }),
+			)
+			done := run()
+			Eventually(written).Should(BeClosed())
+			q.Close()
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("disables GSO if the network interface doesn't support it", func() {
+			gsoErr := &net.OpError{Op: "write", Net: "udp", Err: &os.SyscallError{Syscall: "sendmsg", Err: syscall.EIO}}
+			if !isGSOError(gsoErr) {
+				Skip("GSO is only supported on Linux")
+			}
+			q.Send(getPacket([]byte("foobar")), protocol.ECNNon)
+			q.Send(getPacket([]byte("raboof")), protocol.ECNNon)
+			c.EXPECT().SupportsGSO().Return(true).AnyTimes()
+			fellBack := make(chan struct{})
+			written := make(chan struct{})
+			gomock.InOrder(
+				c.EXPECT().WriteGSO([]byte("foobarraboof"), uint16(6), protocol.ECNNon).Return(gsoErr),
+				c.EXPECT().Write([]byte("foobar"), protocol.ECNNon),
+				c.EXPECT().Write([]byte("raboof"), protocol.ECNNon).Do(func([]byte, protocol.ECN) { close(fellBack) }),
+				c.EXPECT().Write([]byte("foo"), protocol.ECNNon),
+				c.EXPECT().Write([]byte("bar"), protocol.ECNNon).Do(func([]byte, protocol.ECN) { close(written) }),
+			)
+			done := run()
+			Eventually(fellBack).Should(BeClosed())
+			// GSO is not used for subsequent packets
+			q.Send(getPacket([]byte("foo")), protocol.ECNNon)
+			q.Send(getPacket([]byte("bar")), protocol.ECNNon)
+			Eventually(written).Should(BeClosed())
+			q.Close()
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("returns write errors", func() {
+			q.Send(getPacket([]byte("foobar")), protocol.ECNNon)
+			q.Send(getPacket([]byte("raboof")), protocol.ECNNon)
+			c.EXPECT().SupportsGSO().Return(true).AnyTimes()
+			testErr := errors.New("test error")
+			c.EXPECT().WriteGSO(gomock.Any(), gomock.Any(), gomock.Any()).Return(testErr)
+			Expect(q.Run()).To(MatchError(testErr))
+		})
+	})
 })
+
+// BenchmarkSendQueue measures how many packets per second can be sent on a UDP socket, with and without GSO.
+// Run it using go test -run='^$' -bench=SendQueue.
+func BenchmarkSendQueue(b *testing.B) {
+	for _, gso := range []bool{false, true} {
+		b.Run(fmt.Sprintf("GSO: %t", gso), func(b *testing.B) {
+			server, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer server.Close()
+			udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer udpConn.Close()
+			c, err := newConn(udpConn)
+			if err != nil {
+				b.Fatal(err)
+			}
+			conn := newSendConn(c, server.LocalAddr(), nil, 0)
+			if gso && !conn.SupportsGSO() {
+				b.Skip("GSO not supported")
+			}
+			q := newSendQueue(conn, gso)
+			runErr := make(chan error, 1)
+			go func() { runErr <- q.Run() }()
+
+			b.ResetTimer()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				for q.WouldBlock() {
+					<-q.Available()
+				}
+				buf := getPacketBuffer()
+				buf.Data = buf.Data[:1200]
+				q.Send(buf, protocol.ECNNon)
+			}
+			q.Close()
+			b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "packets/s")
+			if err := <-runErr; err != nil {
+				b.Fatal(err)
+			}
+		})
+	}
+}
@@ -21,6 +21,7 @@ type sendStreamI interface {
 	popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Frame, bool)
 	closeForShutdown(error)
 	updateSendWindow(protocol.ByteCount)
+	stats() StreamStats
 }
 
 type sendStream struct {
@@ -284,7 +285,7 @@ func (s *sendStream) popNewStreamFrame(maxBytes, sendWindow protocol.ByteCount)
 		return nextFrame, s.nextFrame != nil || s.dataForWriting != nil
 	}
 
-	f := wire.GetStreamFrame()
+	f := wire.GetStreamFrameForSize(maxBytes)
 	f.Fin = false
 	f.StreamID = s.streamID
 	f.Offset = s.writeOffset
@@ -414,7 +415,7 @@ func (s *sendStream) CancelWrite(errorCode StreamErrorCode) {
 // must be called after locking the mutex
 func (s *sendStream) cancelWriteImpl(errorCode qerr.StreamErrorCode, writeErr error) {
 	s.mutex.Lock()
-	if s.canceledWrite {
+	if s.canceledWrite || s.closedForShutdown {
 		s.mutex.Unlock()
 		return
 	}
@@ -448,6 +449,19 @@ func (s *sendStream) updateSendWindow(limit protocol.ByteCount) {
 	}
 }
 
+func (s *sendStream) stats() StreamStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	hasStreamData := s.dataForWriting != nil || s.nextFrame != nil
+	sendWindow := s.flowController.SendWindow()
+	return StreamStats{
+		StreamID:             s.streamID,
+		BytesSent:            s.writeOffset,
+		SendWindow:           sendWindow,
+		BlockedOnFlowControl: hasStreamData && s.writeOffset >= sendWindow,
+	}
+}
+
 func (s *sendStream) handleStopSendingFrame(frame *wire.StopSendingFrame) {
 	s.cancelWriteImpl(frame.ErrorCode, &StreamError{
 		StreamID:  s.streamID,
@@ -459,6 +473,16 @@ func (s *sendStream) Context() context.Context {
 	return s.ctx
 }
 
+func (s *sendStream) SetPriority(weight int, incremental bool) {
+	s.mutex.Lock()
+	completed := s.completed || s.closedForShutdown
+	s.mutex.Unlock()
+	if completed {
+		return
+	}
+	s.sender.onStreamPriorityChanged(s.streamID, weight, incremental) // must be called without holding the mutex
+}
+
 func (s *sendStream) SetWriteDeadline(t time.Time) error {
 	s.mutex.Lock()
 	s.deadline = t
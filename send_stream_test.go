@@ -174,6 +174,24 @@ var _ = Describe("Send Stream", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		It("pops STREAM frames larger than the default packet size", func() {
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(5000))
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err := strWithTimeout.Write(getData(5000))
+				Expect(err).ToNot(HaveOccurred())
+				close(done)
+			}()
+			waitForWrite()
+			frame, _ := str.popStreamFrame(protocol.MaxLargePacketBufferSize)
+			f := frame.Frame.(*wire.StreamFrame)
+			Expect(f.Data).To(Equal(getData(5000)))
+			Eventually(done).Should(BeClosed())
+		})
+
 		It("unblocks Write as soon as a STREAM frame can be buffered", func() {
 			done := make(chan struct{})
 			go func() {
@@ -646,6 +664,73 @@ var _ = Describe("Send Stream", func() {
 				str.closeForShutdown(testErr)
 				Expect(str.Context().Done()).To(BeClosed())
 			})
+
+			It("doesn't send a RESET_STREAM frame when canceled after it was closed for shutdown", func() {
+				str.closeForShutdown(testErr)
+				// don't EXPECT any calls to queueControlFrame
+				str.CancelWrite(1234)
+				_, err := strWithTimeout.Write([]byte("foo"))
+				Expect(err).To(MatchError(testErr))
+			})
+		})
+	})
+
+	Context("stats", func() {
+		It("returns the number of bytes sent and the send window", func() {
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err := strWithTimeout.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+			}()
+			waitForWrite()
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame).ToNot(BeNil())
+			Eventually(done).Should(BeClosed())
+			mockFC.EXPECT().SendWindow().Return(protocol.ByteCount(1000))
+			Expect(str.stats()).To(Equal(StreamStats{
+				StreamID:   streamID,
+				BytesSent:  6,
+				SendWindow: 1000,
+			}))
+		})
+
+		It("says when the stream is blocked by flow control", func() {
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err := strWithTimeout.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+			}()
+			waitForWrite()
+			mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(3))
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(3))
+			frame, hasMoreData := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foo")))
+			Expect(hasMoreData).To(BeTrue())
+			Eventually(done).Should(BeClosed())
+			mockFC.EXPECT().SendWindow().Return(protocol.ByteCount(3))
+			stats := str.stats()
+			Expect(stats.BytesSent).To(Equal(protocol.ByteCount(3)))
+			Expect(stats.BlockedOnFlowControl).To(BeTrue())
+		})
+	})
+
+	Context("priorities", func() {
+		It("informs the sender when the priority is set", func() {
+			mockSender.EXPECT().onStreamPriorityChanged(streamID, 5, false)
+			str.SetPriority(5, false)
+		})
+
+		It("doesn't set the priority after the stream was closed for shutdown", func() {
+			str.closeForShutdown(errors.New("shutdown"))
+			str.SetPriority(5, false)
 		})
 	})
 
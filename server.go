@@ -39,6 +39,8 @@ type packetHandlerManager interface {
 	sessionRunner
 	SetServer(unknownPacketHandler)
 	CloseServer()
+	// EnableLargePackets enables receiving packets larger than protocol.MaxPacketBufferSize on the connection.
+	EnableLargePackets()
 }
 
 type quicSession interface {
@@ -187,13 +189,26 @@ func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config, acceptEarl
 		}
 	}
 
+	if config.DSCP != 0 {
+		if err := setDSCP(conn, uint8(config.DSCP)); err != nil {
+			return nil, err
+		}
+	}
 	sessionHandler, err := getMultiplexer().AddConn(conn, config.ConnectionIDLength, config.StatelessResetKey, config.Tracer)
 	if err != nil {
 		return nil, err
 	}
-	tokenGenerator, err := handshake.NewTokenGenerator(rand.Reader)
-	if err != nil {
-		return nil, err
+	if config.usesLargePackets() {
+		sessionHandler.EnableLargePackets()
+	}
+	var tokenGenerator *handshake.TokenGenerator
+	if config.TokenProtector != nil {
+		tokenGenerator = handshake.NewTokenGeneratorWithProtector(config.TokenProtector)
+	} else {
+		tokenGenerator, err = handshake.NewTokenGenerator(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
 	}
 	c, err := wrapConn(conn)
 	if err != nil {
@@ -421,19 +436,25 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 		}
 	}
 	if !s.config.AcceptToken(p.remoteAddr, token) {
-		go func() {
-			defer p.buffer.Release()
-			if token != nil && token.IsRetryToken {
+		if token != nil && token.IsRetryToken {
+			go func() {
+				defer p.buffer.Release()
 				if err := s.maybeSendInvalidToken(p, hdr); err != nil {
 					s.logger.Debugf("Error sending INVALID_TOKEN error: %s", err)
 				}
-				return
-			}
-			if err := s.sendRetry(p.remoteAddr, hdr, p.info); err != nil {
-				s.logger.Debugf("Error sending Retry: %s", err)
-			}
-		}()
-		return nil
+			}()
+			return nil
+		}
+		if s.config.RequireAddressValidation == nil || s.config.RequireAddressValidation(p.remoteAddr) {
+			go func() {
+				defer p.buffer.Release()
+				if err := s.sendRetry(p.remoteAddr, hdr, p.info); err != nil {
+					s.logger.Debugf("Error sending Retry: %s", err)
+				}
+			}()
+			return nil
+		}
+		// Continue the handshake without validating the client's address.
 	}
 
 	if queueLen := atomic.LoadInt32(&s.sessionQueueLen); queueLen >= protocol.MaxAcceptQueueSize {
@@ -447,7 +468,7 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 		return nil
 	}
 
-	connID, err := protocol.GenerateConnectionID(s.config.ConnectionIDLength)
+	connID, err := s.config.generateConnectionID()
 	if err != nil {
 		return err
 	}
@@ -469,7 +490,7 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 			)
 		}
 		sess = s.newSession(
-			newSendConn(s.conn, p.remoteAddr, p.info),
+			newSendConn(s.conn, p.remoteAddr, p.info, uint8(s.config.DSCP)),
 			s.sessionHandler,
 			origDestConnID,
 			retrySrcConnID,
@@ -480,7 +501,7 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 			s.config,
 			s.tlsConf,
 			s.tokenGenerator,
-			s.acceptEarlySessions,
+			s.acceptEarlySessions && (s.config.Allow0RTT == nil || s.config.Allow0RTT(p.remoteAddr)),
 			tracer,
 			tracingID,
 			s.logger,
@@ -532,7 +553,7 @@ func (s *baseServer) sendRetry(remoteAddr net.Addr, hdr *wire.Header, info *pack
 	// Log the Initial packet now.
 	// If no Retry is sent, the packet will be logged by the session.
 	(&wire.ExtendedHeader{Header: *hdr}).Log(s.logger)
-	srcConnID, err := protocol.GenerateConnectionID(s.config.ConnectionIDLength)
+	srcConnID, err := s.config.generateConnectionID()
 	if err != nil {
 		return err
 	}
@@ -157,6 +157,32 @@ var _ = Describe("Server", func() {
 		Expect(ln.Close()).To(Succeed())
 	})
 
+	It("uses the TokenProtector from the Config", func() {
+		key := bytes.Repeat([]byte{'a'}, 32)
+		tp, err := NewHMACTokenProtector(key)
+		Expect(err).ToNot(HaveOccurred())
+		ln, err := Listen(conn, tlsConf, &Config{TokenProtector: tp})
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		server := ln.(*baseServer)
+		addr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}
+		// a server using the same key can decode the token
+		tp, err = handshake.NewHMACTokenProtector(key)
+		Expect(err).ToNot(HaveOccurred())
+		token, err := handshake.NewTokenGeneratorWithProtector(tp).NewToken(addr)
+		Expect(err).ToNot(HaveOccurred())
+		decoded, err := server.tokenGenerator.DecodeToken(token)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded.RemoteAddr).To(Equal("192.168.0.1"))
+		// a server using a different key can't
+		tp, err = handshake.NewHMACTokenProtector(bytes.Repeat([]byte{'b'}, 32))
+		Expect(err).ToNot(HaveOccurred())
+		token, err = handshake.NewTokenGeneratorWithProtector(tp).NewToken(addr)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = server.tokenGenerator.DecodeToken(token)
+		Expect(err).To(HaveOccurred())
+	})
+
 	It("listens on a given address", func() {
 		addr := "127.0.0.1:13579"
 		ln, err := ListenAddr(addr, tlsConf, &Config{})
@@ -503,6 +529,93 @@ var _ = Describe("Server", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			It("replies with a Retry packet, if address validation is required for this client", func() {
+				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return false }
+				raddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
+				serv.config.RequireAddressValidation = func(addr net.Addr) bool {
+					Expect(addr).To(Equal(raddr))
+					return true
+				}
+				hdr := &wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeInitial,
+					SrcConnectionID:  protocol.ConnectionID{5, 4, 3, 2, 1},
+					DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+					Version:          protocol.VersionTLS,
+				}
+				packet := getPacket(hdr, make([]byte, protocol.MinInitialPacketSize))
+				packet.remoteAddr = raddr
+				tracer.EXPECT().SentPacket(packet.remoteAddr, gomock.Any(), gomock.Any(), nil).Do(func(_ net.Addr, replyHdr *logging.Header, _ logging.ByteCount, _ []logging.Frame) {
+					Expect(replyHdr.Type).To(Equal(protocol.PacketTypeRetry))
+				})
+				done := make(chan struct{})
+				conn.EXPECT().WriteTo(gomock.Any(), raddr).DoAndReturn(func(b []byte, _ net.Addr) (int, error) {
+					defer close(done)
+					Expect(parseHeader(b).Type).To(Equal(protocol.PacketTypeRetry))
+					return len(b), nil
+				})
+				serv.handlePacket(packet)
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("creates a session without sending a Retry, if address validation isn't required for this client", func() {
+				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return false }
+				serv.config.RequireAddressValidation = func(net.Addr) bool { return false }
+				hdr := &wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeInitial,
+					SrcConnectionID:  protocol.ConnectionID{5, 4, 3, 2, 1},
+					DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+					Version:          protocol.VersionTLS,
+				}
+				p := getPacket(hdr, make([]byte, protocol.MinInitialPacketSize))
+				run := make(chan struct{})
+				phm.EXPECT().AddWithConnID(protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, gomock.Any(), gomock.Any()).DoAndReturn(func(_, _ protocol.ConnectionID, fn func() packetHandler) bool {
+					phm.EXPECT().GetStatelessResetToken(gomock.Any())
+					fn()
+					return true
+				})
+				tracer.EXPECT().TracerForConnection(gomock.Any(), protocol.PerspectiveServer, protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+
+				sess := NewMockQuicSession(mockCtrl)
+				serv.newSession = func(
+					_ sendConn,
+					_ sessionRunner,
+					origDestConnID protocol.ConnectionID,
+					retrySrcConnID *protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.StatelessResetToken,
+					_ *Config,
+					_ *tls.Config,
+					_ *handshake.TokenGenerator,
+					_ bool,
+					_ logging.ConnectionTracer,
+					_ uint64,
+					_ utils.Logger,
+					_ protocol.VersionNumber,
+				) quicSession {
+					Expect(origDestConnID).To(Equal(hdr.DestConnectionID))
+					Expect(retrySrcConnID).To(BeNil())
+					sess.EXPECT().handlePacket(p)
+					sess.EXPECT().run().Do(func() { close(run) })
+					sess.EXPECT().Context().Return(context.Background())
+					sess.EXPECT().HandshakeComplete().Return(context.Background())
+					return sess
+				}
+
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					serv.handlePacket(p)
+					// make sure there are no Write calls on the packet conn
+					time.Sleep(50 * time.Millisecond)
+					close(done)
+				}()
+				Eventually(run).Should(BeClosed())
+				Eventually(done).Should(BeClosed())
+			})
 			It("sends an INVALID_TOKEN error, if an invalid retry token is received", func() {
 				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return false }
 				token, err := serv.tokenGenerator.NewRetryToken(&net.UDPAddr{}, nil, nil)
@@ -541,7 +654,7 @@ var _ = Describe("Server", func() {
 					Expect(err).ToNot(HaveOccurred())
 					data, err := opener.Open(nil, b[extHdr.ParsedLen():], extHdr.PacketNumber, b[:extHdr.ParsedLen()])
 					Expect(err).ToNot(HaveOccurred())
-					f, err := wire.NewFrameParser(false, hdr.Version).ParseNext(bytes.NewReader(data), protocol.EncryptionInitial)
+					f, err := wire.NewFrameParser(false, false, hdr.Version).ParseNext(bytes.NewReader(data), protocol.EncryptionInitial)
 					Expect(err).ToNot(HaveOccurred())
 					Expect(f).To(BeAssignableToTypeOf(&wire.ConnectionCloseFrame{}))
 					ccf := f.(*wire.ConnectionCloseFrame)
@@ -1045,6 +1158,59 @@ var _ = Describe("Server", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		It("disables 0-RTT for clients rejected by Allow0RTT", func() {
+			senderAddr := &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 42}
+			var allow0RTTAddr net.Addr
+			serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return true }
+			serv.config.Allow0RTT = func(addr net.Addr) bool {
+				allow0RTTAddr = addr
+				return false
+			}
+			running := make(chan struct{})
+			waitingForReady := make(chan struct{})
+			serv.newSession = func(
+				_ sendConn,
+				runner sessionRunner,
+				_ protocol.ConnectionID,
+				_ *protocol.ConnectionID,
+				_ protocol.ConnectionID,
+				_ protocol.ConnectionID,
+				_ protocol.ConnectionID,
+				_ protocol.StatelessResetToken,
+				_ *Config,
+				_ *tls.Config,
+				_ *handshake.TokenGenerator,
+				enable0RTT bool,
+				_ logging.ConnectionTracer,
+				_ uint64,
+				_ utils.Logger,
+				_ protocol.VersionNumber,
+			) quicSession {
+				Expect(enable0RTT).To(BeFalse())
+				sess := NewMockQuicSession(mockCtrl)
+				sess.EXPECT().handlePacket(gomock.Any())
+				sess.EXPECT().run().Do(func() { close(running) })
+				sess.EXPECT().Context().Return(context.Background())
+				sess.EXPECT().earlySessionReady().DoAndReturn(func() <-chan struct{} {
+					close(waitingForReady)
+					return make(chan struct{})
+				})
+				return sess
+			}
+			phm.EXPECT().AddWithConnID(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_, _ protocol.ConnectionID, fn func() packetHandler) bool {
+				phm.EXPECT().GetStatelessResetToken(gomock.Any())
+				fn()
+				return true
+			})
+			serv.handleInitialImpl(
+				&receivedPacket{buffer: getPacketBuffer(), remoteAddr: senderAddr},
+				&wire.Header{DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}},
+			)
+			Eventually(running).Should(BeClosed())
+			Eventually(waitingForReady).Should(BeClosed())
+			Expect(allow0RTTAddr).To(Equal(senderAddr))
+		})
+
 		It("rejects new connection attempts if the accept queue is full", func() {
 			serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return true }
 			senderAddr := &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 42}
@@ -3,6 +3,7 @@ package quic
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"crypto/tls"
 	"errors"
 	"fmt"
@@ -13,6 +14,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/lucas-clemente/quic-go/congestion"
 	"github.com/lucas-clemente/quic-go/internal/ackhandler"
 	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/handshake"
@@ -48,13 +50,16 @@ type streamManager interface {
 	CloseWithError(error)
 	ResetFor0RTT()
 	UseResetMaps()
+	Stats() []StreamStats
 }
 
 type cryptoStreamHandler interface {
 	RunHandshake()
 	ChangeConnectionID(protocol.ConnectionID)
+	ChangeVersion(protocol.VersionNumber)
 	SetLargest1RTTAcked(protocol.PacketNumber) error
 	SetHandshakeConfirmed()
+	InitiateKeyUpdate()
 	GetSessionTicket() ([]byte, error)
 	io.Closer
 	ConnectionState() handshake.ConnectionState
@@ -100,6 +105,136 @@ type sessionRunner interface {
 	RemoveResetToken(protocol.StatelessResetToken)
 }
 
+// sessionRunners forwards calls to the packet handler maps of multiple sockets.
+// It is used by the client: While migrating to a new socket, the session is registered with the packet
+// handler maps of both the old and the new socket.
+// The first runner is used for generating stateless reset tokens.
+// It is only used from the run loop.
+type sessionRunners struct {
+	runners []sessionRunner
+	// Retired connection IDs are only removed from the packet handler maps after a delay.
+	// We need to remove them right away when we stop using a socket.
+	retired []retiredConnID
+}
+
+type retiredConnID struct {
+	connID    protocol.ConnectionID
+	retiredAt time.Time
+}
+
+var _ sessionRunner = &sessionRunners{}
+
+func (r *sessionRunners) Add(connID protocol.ConnectionID, handler packetHandler) bool {
+	added := true
+	for _, runner := range r.runners {
+		if !runner.Add(connID, handler) {
+			added = false
+		}
+	}
+	return added
+}
+
+func (r *sessionRunners) GetStatelessResetToken(connID protocol.ConnectionID) protocol.StatelessResetToken {
+	return r.runners[0].GetStatelessResetToken(connID)
+}
+
+func (r *sessionRunners) Retire(connID protocol.ConnectionID) {
+	now := time.Now()
+	for len(r.retired) > 0 && now.Sub(r.retired[0].retiredAt) > protocol.RetiredConnectionIDDeleteTimeout {
+		r.retired = r.retired[1:]
+	}
+	r.retired = append(r.retired, retiredConnID{connID: connID, retiredAt: now})
+	for _, runner := range r.runners {
+		runner.Retire(connID)
+	}
+}
+
+func (r *sessionRunners) Remove(connID protocol.ConnectionID) {
+	for _, runner := range r.runners {
+		runner.Remove(connID)
+	}
+}
+
+func (r *sessionRunners) ReplaceWithClosed(connID protocol.ConnectionID, handler packetHandler) {
+	for _, runner := range r.runners {
+		runner.ReplaceWithClosed(connID, handler)
+	}
+}
+
+func (r *sessionRunners) AddResetToken(token protocol.StatelessResetToken, handler packetHandler) {
+	for _, runner := range r.runners {
+		runner.AddResetToken(token, handler)
+	}
+}
+
+func (r *sessionRunners) RemoveResetToken(token protocol.StatelessResetToken) {
+	for _, runner := range r.runners {
+		runner.RemoveResetToken(token)
+	}
+}
+
+func (r *sessionRunners) add(runner sessionRunner) {
+	r.runners = append(r.runners, runner)
+}
+
+func (r *sessionRunners) remove(runner sessionRunner) {
+	for _, c := range r.retired {
+		runner.Remove(c.connID)
+	}
+	for i, ru := range r.runners {
+		if ru == runner {
+			r.runners = append(r.runners[:i], r.runners[i+1:]...)
+			return
+		}
+	}
+}
+
+// zeroLengthConnIDRunner registers a client session that uses a zero-length connection ID.
+// Packets sent to a zero-length connection ID can't be demultiplexed on the connection ID,
+// so the packet handler map routes them on the remote address (and the socket they were received on).
+// This allows multiple sessions with zero-length connection IDs to share a packet conn, as long as they use different peers.
+type zeroLengthConnIDRunner struct {
+	sessionRunner
+	remoteAddr string // must be comparable, since runners are compared when migrating
+}
+
+var _ sessionRunner = zeroLengthConnIDRunner{}
+
+func newZeroLengthConnIDRunner(runner sessionRunner, remoteAddr net.Addr) zeroLengthConnIDRunner {
+	return zeroLengthConnIDRunner{sessionRunner: runner, remoteAddr: remoteAddr.String()}
+}
+
+func (r zeroLengthConnIDRunner) key(connID protocol.ConnectionID) protocol.ConnectionID {
+	if connID.Len() > 0 {
+		return connID
+	}
+	return zeroLengthConnIDKey(r.remoteAddr)
+}
+
+func (r zeroLengthConnIDRunner) Add(connID protocol.ConnectionID, handler packetHandler) bool {
+	return r.sessionRunner.Add(r.key(connID), handler)
+}
+
+func (r zeroLengthConnIDRunner) Retire(connID protocol.ConnectionID) {
+	r.sessionRunner.Retire(r.key(connID))
+}
+
+func (r zeroLengthConnIDRunner) Remove(connID protocol.ConnectionID) {
+	r.sessionRunner.Remove(r.key(connID))
+}
+
+func (r zeroLengthConnIDRunner) ReplaceWithClosed(connID protocol.ConnectionID, handler packetHandler) {
+	r.sessionRunner.ReplaceWithClosed(r.key(connID), handler)
+}
+
+// runnerFor returns the sessionRunner used to register the session with the packet handler map of a socket.
+func (s *session) runnerFor(manager packetHandlerManager) sessionRunner {
+	if s.srcConnIDLen == 0 {
+		return newZeroLengthConnIDRunner(manager, s.conn.RemoteAddr())
+	}
+	return manager
+}
+
 type handshakeRunner struct {
 	onReceivedParams    func(*wire.TransportParameters)
 	onError             func(error)
@@ -132,6 +267,10 @@ func nextSessionTracingID() uint64 { return atomic.AddUint64(&sessionTracingID,
 
 // A Session is a QUIC session
 type session struct {
+	// The number of packets that were successfully unpacked, to be accessed atomically.
+	// This must be the first field, so that it is 64-bit aligned on 32-bit platforms.
+	packetsReceived uint64
+
 	// Destination connection ID used during the handshake.
 	// Used to check source connection ID on incoming packets.
 	handshakeDestConnID protocol.ConnectionID
@@ -145,8 +284,9 @@ type session struct {
 	version     protocol.VersionNumber
 	config      *Config
 
-	conn      sendConn
+	conn      *migratableConn
 	sendQueue sender
+	runner    *sessionRunners // only set for the client
 
 	streamsMap      streamManager
 	connIDManager   *connIDManager
@@ -183,6 +323,10 @@ type session struct {
 	ctxCancel          context.CancelFunc
 	handshakeCtx       context.Context
 	handshakeCtxCancel context.CancelFunc
+	// handshakeConfirmedCtx is cancelled when the handshake is confirmed, or when the session is closed.
+	// The cause is nil in the first case, and the error that closed the session in the second case.
+	handshakeConfirmedCtx       context.Context
+	handshakeConfirmedCtxCancel func(cause error)
 
 	undecryptablePackets          []*receivedPacket // undecryptable packets, waiting for a change in encryption level
 	undecryptablePacketsToProcess []*receivedPacket
@@ -194,7 +338,9 @@ type session struct {
 	handshakeConfirmed    bool
 
 	receivedRetry       bool
+	enable0RTT          bool
 	versionNegotiated   bool
+	versionUpgraded     bool // switched to a compatible version (RFC 9368)
 	receivedFirstPacket bool
 
 	idleTimeout         time.Duration
@@ -213,9 +359,27 @@ type session struct {
 	// It is reset as soon as we receive a packet from the peer.
 	keepAlivePingSent bool
 	keepAliveInterval time.Duration
+	// the last time stream data or a datagram was sent or received, used for Config.OnApplicationIdle
+	lastApplicationActivityTime time.Time
+	// applicationIdleNotified is set when Config.OnApplicationIdle was called.
+	// It is reset as soon as stream data or a datagram is sent or received.
+	applicationIdleNotified bool
 
 	datagramQueue *datagramQueue
 
+	migrationRequests      chan migrationRequest // only set for the client
+	streamStatsRequests    chan chan<- []StreamStats
+	connIDRotationRequests chan chan<- error
+	// the validation of a new path that is in progress (RFC 9000, Section 8.2), if any
+	pathValidation *pathValidation
+	// The largest packet number of all 1-RTT packets received.
+	// Used to detect that the client migrated to a new address, and for the spin bit.
+	largestRcvdPacketNumber protocol.PacketNumber
+
+	spinBit         bool      // the spin bit we're sending
+	rcvdSpinBit     bool      // the spin bit of the 1-RTT packet with the largest packet number
+	lastSpinBitFlip time.Time // when the spin bit of received packets last flipped
+
 	logID  string
 	tracer logging.ConnectionTracer
 	logger utils.Logger
@@ -247,7 +411,7 @@ var newSession = func(
 	v protocol.VersionNumber,
 ) quicSession {
 	s := &session{
-		conn:                  conn,
+		conn:                  newMigratableConn(conn),
 		config:                conf,
 		handshakeDestConnID:   destConnID,
 		srcConnIDLen:          srcConnID.Len(),
@@ -258,6 +422,7 @@ var newSession = func(
 		tracer:                tracer,
 		logger:                logger,
 		version:               v,
+		enable0RTT:            enable0RTT,
 	}
 	if origDestConnID != nil {
 		s.logID = origDestConnID.String()
@@ -273,6 +438,7 @@ var newSession = func(
 	s.connIDGenerator = newConnIDGenerator(
 		srcConnID,
 		clientDestConnID,
+		s.config.generateConnectionID,
 		func(connID protocol.ConnectionID) { runner.Add(connID, s) },
 		runner.GetStatelessResetToken,
 		runner.Remove,
@@ -285,13 +451,18 @@ var newSession = func(
 	s.ctx, s.ctxCancel = context.WithCancel(context.WithValue(context.Background(), SessionTracingKey, tracingID))
 	s.sentPacketHandler, s.receivedPacketHandler = ackhandler.NewAckHandler(
 		0,
-		getMaxPacketSize(s.conn.RemoteAddr()),
+		getInitialPacketSize(s.conn.RemoteAddr(), s.config.InitialPacketSize),
 		s.rttStats,
 		s.perspective,
 		s.tracer,
 		s.logger,
 		s.version,
+		s.config.PTOMultiplier,
+		protocol.PacketNumber(s.config.PacketReorderingThreshold),
+		s.config.MaxAckDelay,
+		s.config.EnableECN,
 		s.config.Congestion,
+		s.config.NewCongestionControl,
 	)
 	initialStream := newCryptoStream()
 	handshakeStream := newCryptoStream()
@@ -303,18 +474,29 @@ var newSession = func(
 		MaxIdleTimeout:                  s.config.MaxIdleTimeout,
 		MaxBidiStreamNum:                protocol.StreamNum(s.config.MaxIncomingStreams),
 		MaxUniStreamNum:                 protocol.StreamNum(s.config.MaxIncomingUniStreams),
-		MaxAckDelay:                     protocol.MaxAckDelayInclGranularity,
+		MaxAckDelay:                     s.config.maxAckDelayInclGranularity(),
 		AckDelayExponent:                protocol.AckDelayExponent,
-		DisableActiveMigration:          true,
+		DisableActiveMigration:          !s.config.EnableActiveMigration,
 		StatelessResetToken:             &statelessResetToken,
 		OriginalDestinationConnectionID: origDestConnID,
 		ActiveConnectionIDLimit:         protocol.MaxActiveConnectionIDs,
 		InitialSourceConnectionID:       srcConnID,
 		RetrySourceConnectionID:         retrySrcConnID,
+		VersionInformation: &wire.VersionInformation{
+			ChosenVersion:     s.version,
+			AvailableVersions: s.config.Versions,
+		},
 	}
 	if s.config.EnableDatagrams {
 		params.MaxDatagramFrameSize = protocol.MaxDatagramFrameSize
 	}
+	if s.config.usesLargePackets() {
+		params.MaxUDPPayloadSize = protocol.MaxLargePacketBufferSize
+	}
+	if s.config.EnableAckFrequency {
+		minAckDelay := protocol.MinAckDelay
+		params.MinAckDelay = &minAckDelay
+	}
 	if s.tracer != nil {
 		s.tracer.SentTransportParameters(params)
 	}
@@ -349,7 +531,8 @@ var newSession = func(
 		handshakeStream,
 		s.sentPacketHandler,
 		s.retransmissionQueue,
-		s.RemoteAddr(),
+		getInitialPacketSize(s.conn.RemoteAddr(), s.config.InitialPacketSize),
+		s.config.Disable1RTTCoalescing,
 		cs,
 		s.framer,
 		s.receivedPacketHandler,
@@ -359,6 +542,7 @@ var newSession = func(
 	)
 	s.unpacker = newPacketUnpacker(cs, s.version)
 	s.cryptoStreamManager = newCryptoStreamManager(cs, initialStream, handshakeStream, s.oneRTTStream)
+	s.initSpinBit()
 	return s
 }
 
@@ -379,7 +563,8 @@ var newClientSession = func(
 	v protocol.VersionNumber,
 ) quicSession {
 	s := &session{
-		conn:                  conn,
+		conn:                  newMigratableConn(conn),
+		runner:                &sessionRunners{runners: []sessionRunner{runner}},
 		config:                conf,
 		origDestConnID:        destConnID,
 		handshakeDestConnID:   destConnID,
@@ -394,18 +579,19 @@ var newClientSession = func(
 	}
 	s.connIDManager = newConnIDManager(
 		destConnID,
-		func(token protocol.StatelessResetToken) { runner.AddResetToken(token, s) },
-		runner.RemoveResetToken,
+		func(token protocol.StatelessResetToken) { s.runner.AddResetToken(token, s) },
+		s.runner.RemoveResetToken,
 		s.queueControlFrame,
 	)
 	s.connIDGenerator = newConnIDGenerator(
 		srcConnID,
 		nil,
-		func(connID protocol.ConnectionID) { runner.Add(connID, s) },
-		runner.GetStatelessResetToken,
-		runner.Remove,
-		runner.Retire,
-		runner.ReplaceWithClosed,
+		s.config.generateConnectionID,
+		func(connID protocol.ConnectionID) { s.runner.Add(connID, s) },
+		s.runner.GetStatelessResetToken,
+		s.runner.Remove,
+		s.runner.Retire,
+		s.runner.ReplaceWithClosed,
 		s.queueControlFrame,
 		s.version,
 	)
@@ -413,13 +599,18 @@ var newClientSession = func(
 	s.ctx, s.ctxCancel = context.WithCancel(context.WithValue(context.Background(), SessionTracingKey, tracingID))
 	s.sentPacketHandler, s.receivedPacketHandler = ackhandler.NewAckHandler(
 		initialPacketNumber,
-		getMaxPacketSize(s.conn.RemoteAddr()),
+		getInitialPacketSize(s.conn.RemoteAddr(), s.config.InitialPacketSize),
 		s.rttStats,
 		s.perspective,
 		s.tracer,
 		s.logger,
 		s.version,
+		s.config.PTOMultiplier,
+		protocol.PacketNumber(s.config.PacketReorderingThreshold),
+		s.config.MaxAckDelay,
+		s.config.EnableECN,
 		s.config.Congestion,
+		s.config.NewCongestionControl,
 	)
 	initialStream := newCryptoStream()
 	handshakeStream := newCryptoStream()
@@ -431,18 +622,33 @@ var newClientSession = func(
 		MaxIdleTimeout:                 s.config.MaxIdleTimeout,
 		MaxBidiStreamNum:               protocol.StreamNum(s.config.MaxIncomingStreams),
 		MaxUniStreamNum:                protocol.StreamNum(s.config.MaxIncomingUniStreams),
-		MaxAckDelay:                    protocol.MaxAckDelayInclGranularity,
+		MaxAckDelay:                    s.config.maxAckDelayInclGranularity(),
 		AckDelayExponent:               protocol.AckDelayExponent,
 		DisableActiveMigration:         true,
 		ActiveConnectionIDLimit:        protocol.MaxActiveConnectionIDs,
 		InitialSourceConnectionID:      srcConnID,
+		VersionInformation: &wire.VersionInformation{
+			ChosenVersion:     s.version,
+			AvailableVersions: s.config.Versions,
+		},
 	}
 	if s.config.EnableDatagrams {
 		params.MaxDatagramFrameSize = protocol.MaxDatagramFrameSize
 	}
+	if s.config.usesLargePackets() {
+		params.MaxUDPPayloadSize = protocol.MaxLargePacketBufferSize
+	}
+	if s.config.EnableAckFrequency {
+		minAckDelay := protocol.MinAckDelay
+		params.MinAckDelay = &minAckDelay
+	}
 	if s.tracer != nil {
 		s.tracer.SentTransportParameters(params)
 	}
+	if s.config.ClientSessionCache != nil {
+		tlsConf = tlsConf.Clone()
+		tlsConf.ClientSessionCache = handshake.NewClientSessionCache(s.config.ClientSessionCache)
+	}
 	cs, clientHelloWritten := handshake.NewCryptoSetupClient(
 		initialStream,
 		handshakeStream,
@@ -474,7 +680,8 @@ var newClientSession = func(
 		handshakeStream,
 		s.sentPacketHandler,
 		s.retransmissionQueue,
-		s.RemoteAddr(),
+		getInitialPacketSize(s.conn.RemoteAddr(), s.config.InitialPacketSize),
+		s.config.Disable1RTTCoalescing,
 		cs,
 		s.framer,
 		s.receivedPacketHandler,
@@ -482,6 +689,7 @@ var newClientSession = func(
 		s.perspective,
 		s.version,
 	)
+	s.initSpinBit()
 	if len(tlsConf.ServerName) > 0 {
 		s.tokenStoreKey = tlsConf.ServerName
 	} else {
@@ -496,9 +704,9 @@ var newClientSession = func(
 }
 
 func (s *session) preSetup() {
-	s.sendQueue = newSendQueue(s.conn)
+	s.sendQueue = newSendQueue(s.conn, !s.config.DisableGSO)
 	s.retransmissionQueue = newRetransmissionQueue(s.version)
-	s.frameParser = wire.NewFrameParser(s.config.EnableDatagrams, s.version)
+	s.frameParser = wire.NewFrameParser(s.config.EnableDatagrams, s.config.EnableAckFrequency, s.version)
 	s.rttStats = &utils.RTTStats{}
 	s.connFlowController = flowcontrol.NewConnectionFlowController(
 		protocol.ByteCount(s.config.InitialConnectionReceiveWindow),
@@ -513,6 +721,7 @@ func (s *session) preSetup() {
 		s.newFlowController,
 		uint64(s.config.MaxIncomingStreams),
 		uint64(s.config.MaxIncomingUniStreams),
+		s.config.MaxStreamsIncrement,
 		s.perspective,
 		s.version,
 	)
@@ -520,15 +729,23 @@ func (s *session) preSetup() {
 	s.receivedPackets = make(chan *receivedPacket, protocol.MaxSessionUnprocessedPackets)
 	s.closeChan = make(chan closeError, 1)
 	s.sendingScheduled = make(chan struct{}, 1)
+	s.streamStatsRequests = make(chan chan<- []StreamStats)
+	s.connIDRotationRequests = make(chan chan<- error)
+	if s.perspective == protocol.PerspectiveClient {
+		s.migrationRequests = make(chan migrationRequest)
+	}
+	s.largestRcvdPacketNumber = protocol.InvalidPacketNumber
 	s.handshakeCtx, s.handshakeCtxCancel = context.WithCancel(context.Background())
+	s.handshakeConfirmedCtx, s.handshakeConfirmedCtxCancel = withCancelCause(context.Background())
 
 	now := time.Now()
 	s.lastPacketReceivedTime = now
+	s.lastApplicationActivityTime = now
 	s.sessionCreationTime = now
 
 	s.windowUpdateQueue = newWindowUpdateQueue(s.streamsMap, s.connFlowController, s.framer.QueueControlFrame)
 	if s.config.EnableDatagrams {
-		s.datagramQueue = newDatagramQueue(s.scheduleSending, s.logger)
+		s.datagramQueue = newDatagramQueue(s.scheduleSending, s.config.DatagramSendQueueLen, s.logger)
 	}
 }
 
@@ -604,6 +821,14 @@ runLoop:
 				// We do all the interesting stuff after the switch statement, so
 				// nothing to see here.
 			case <-sendQueueAvailable:
+			case req := <-s.migrationRequests:
+				if err := s.startMigration(req); err != nil {
+					req.result <- err
+				}
+			case result := <-s.streamStatsRequests:
+				result <- s.streamsMap.Stats()
+			case result := <-s.connIDRotationRequests:
+				result <- s.rotateConnectionID()
 			case firstPacket := <-s.receivedPackets:
 				wasProcessed := s.handlePacketImpl(firstPacket)
 				// Don't set timers and send packets if the packet made us close the session.
@@ -653,6 +878,16 @@ runLoop:
 				s.closeLocal(err)
 			}
 		}
+		if s.pathValidation != nil && !now.Before(s.pathValidation.nextChallenge) {
+			s.onPathValidationTimeout(now)
+		}
+
+		if idleTime := s.nextApplicationIdleTime(); !idleTime.IsZero() && !now.Before(idleTime) {
+			s.logger.Debugf("No stream activity for %s. Calling the application idle callback.", s.config.ApplicationIdleTimeout)
+			s.applicationIdleNotified = true
+			// call it on a separate go routine, so that the callback can close the session
+			go s.config.OnApplicationIdle(s)
+		}
 
 		if keepAliveTime := s.nextKeepAliveTime(); !keepAliveTime.IsZero() && !now.Before(keepAliveTime) {
 			// send a PING frame since there is no activity in the session
@@ -707,6 +942,10 @@ func (s *session) HandshakeComplete() context.Context {
 	return s.handshakeCtx
 }
 
+func (s *session) HandshakeConfirmed() context.Context {
+	return s.handshakeConfirmedCtx
+}
+
 func (s *session) Context() context.Context {
 	return s.ctx
 }
@@ -716,21 +955,168 @@ func (s *session) supportsDatagrams() bool {
 }
 
 func (s *session) ConnectionState() ConnectionState {
+	tlsState := s.cryptoStreamHandler.ConnectionState()
 	return ConnectionState{
-		TLS:               s.cryptoStreamHandler.ConnectionState(),
+		TLS:               tlsState,
 		SupportsDatagrams: s.supportsDatagrams(),
+		Used0RTT:          tlsState.Used0RTT,
+		Version:           s.version,
+	}
+}
+
+func (s *session) ConnectionStateErr() (ConnectionState, error) {
+	// The TLS connection state and the peer's transport parameters are only final once the handshake completes.
+	select {
+	case <-s.handshakeCtx.Done():
+	default:
+		return ConnectionState{}, ErrHandshakeNotComplete
+	}
+	return s.ConnectionState(), nil
+}
+
+func (s *session) CongestionState() CongestionState {
+	return CongestionState(s.sentPacketHandler.CongestionState())
+}
+
+func (s *session) RTTStats() RTTStats {
+	return RTTStats(s.sentPacketHandler.RTTStats())
+}
+
+func (s *session) StreamStats() []StreamStats {
+	// The stats are collected by the run loop, which is the only place where the flow controllers' send windows are updated.
+	result := make(chan []StreamStats, 1)
+	select {
+	case s.streamStatsRequests <- result:
+	case <-s.ctx.Done():
+		return nil
+	}
+	return <-result
+}
+
+func (s *session) ConnectionStats() ConnectionStats {
+	counts := s.packer.BlockedFrameCounts()
+	packetStats := s.sentPacketHandler.PacketStats()
+	stats := ConnectionStats{
+		DataBlockedFramesSent:       counts.DataBlocked,
+		StreamDataBlockedFramesSent: counts.StreamDataBlocked,
+		StreamsBlockedFramesSent:    counts.StreamsBlocked,
+		PacketsSent:                 packetStats.PacketsSent,
+		PacketsReceived:             atomic.LoadUint64(&s.packetsReceived),
+		PacketsLost:                 packetStats.PacketsLost,
+		PacketsRetransmitted:        packetStats.PacketsRetransmitted,
+		LossRate:                    packetStats.LossRate,
+	}
+	if s.datagramQueue != nil {
+		stats.DatagramSendQueueLen = s.datagramQueue.QueueLen()
+		stats.DatagramsDropped = s.datagramQueue.NumDropped()
+	}
+	return stats
+}
+
+func (s *session) SetCongestionControl(opts congestion.CongestionOptions) error {
+	select {
+	case <-s.ctx.Done():
+		return errors.New("session closed")
+	default:
 	}
+	if s.config.NewCongestionControl != nil {
+		return errors.New("can't replace a congestion controller created by Config.NewCongestionControl")
+	}
+	s.sentPacketHandler.SetCongestionControl(opts)
+	// the new congestion controller might allow us to send more
+	s.scheduleSending()
+	return nil
+}
+
+func (s *session) SetSendRateLimit(bytesPerSec int64) {
+	if bytesPerSec < 0 {
+		bytesPerSec = 0
+	}
+	s.sentPacketHandler.SetSendRateLimit(uint64(bytesPerSec))
+	// removing (or raising) the limit might allow us to send more
+	s.scheduleSending()
+}
+
+func (s *session) BytesInFlight() logging.ByteCount {
+	return s.sentPacketHandler.BytesInFlight()
+}
+
+func (s *session) SetMaxBytesInFlight(n logging.ByteCount) {
+	s.sentPacketHandler.SetMaxBytesInFlight(n)
+	// removing (or raising) the limit might allow us to send more
+	s.scheduleSending()
+}
+
+func (s *session) InitiateKeyUpdate() error {
+	select {
+	case <-s.ctx.Done():
+		return errors.New("session closed")
+	default:
+	}
+	s.cryptoStreamHandler.InitiateKeyUpdate()
+	// make sure that a packet is sent using the new keys
+	s.framer.QueueControlFrame(&wire.PingFrame{})
+	s.scheduleSending()
+	return nil
+}
+
+func (s *session) RotateConnectionID() error {
+	result := make(chan error, 1)
+	select {
+	case s.connIDRotationRequests <- result:
+	case <-s.ctx.Done():
+		return errors.New("session closed")
+	}
+	select {
+	case err := <-result:
+		return err
+	case <-s.ctx.Done():
+		return errors.New("session closed")
+	}
+}
+
+func (s *session) rotateConnectionID() error {
+	if !s.handshakeComplete {
+		return errors.New("the handshake hasn't completed yet")
+	}
+	return s.connIDGenerator.Rotate()
 }
 
 // Time when the next keep-alive packet should be sent.
 // It returns a zero time if no keep-alive should be sent.
 func (s *session) nextKeepAliveTime() time.Time {
-	if !s.config.KeepAlive || s.keepAlivePingSent || !s.firstAckElicitingPacketAfterIdleSentTime.IsZero() {
+	if (!s.config.KeepAlive && s.config.KeepAlivePeriod == 0) || s.keepAlivePingSent || !s.firstAckElicitingPacketAfterIdleSentTime.IsZero() {
 		return time.Time{}
 	}
 	return s.lastPacketReceivedTime.Add(s.keepAliveInterval)
 }
 
+// Time when Config.OnApplicationIdle should be called.
+// It returns a zero time if it shouldn't be called.
+func (s *session) nextApplicationIdleTime() time.Time {
+	if s.perspective != protocol.PerspectiveServer || s.config.ApplicationIdleTimeout == 0 || s.config.OnApplicationIdle == nil ||
+		!s.handshakeComplete || s.applicationIdleNotified {
+		return time.Time{}
+	}
+	return s.lastApplicationActivityTime.Add(s.config.ApplicationIdleTimeout)
+}
+
+func (s *session) onApplicationActivity(now time.Time) {
+	s.lastApplicationActivityTime = now
+	s.applicationIdleNotified = false
+}
+
+// onSentFrames records application activity, if the frames sent contain stream data or a datagram.
+func (s *session) onSentFrames(frames []ackhandler.Frame, now time.Time) {
+	for _, f := range frames {
+		switch f.Frame.(type) {
+		case *wire.StreamFrame, *wire.DatagramFrame:
+			s.onApplicationActivity(now)
+			return
+		}
+	}
+}
+
 func (s *session) maybeResetTimer() {
 	var deadline time.Time
 	if !s.handshakeComplete {
@@ -744,6 +1130,9 @@ func (s *session) maybeResetTimer() {
 		} else {
 			deadline = s.idleTimeoutStartTime().Add(s.idleTimeout)
 		}
+		if idleTime := s.nextApplicationIdleTime(); !idleTime.IsZero() {
+			deadline = utils.MinTime(deadline, idleTime)
+		}
 	}
 	if s.handshakeConfirmed && !s.config.DisablePathMTUDiscovery {
 		if probeTime := s.mtuDiscoverer.NextProbeTime(); !probeTime.IsZero() {
@@ -760,6 +1149,9 @@ func (s *session) maybeResetTimer() {
 	if !s.pacingDeadline.IsZero() {
 		deadline = utils.MinTime(deadline, s.pacingDeadline)
 	}
+	if s.pathValidation != nil {
+		deadline = utils.MinTime(deadline, s.pathValidation.nextChallenge)
+	}
 
 	s.timer.Reset(deadline)
 }
@@ -806,6 +1198,7 @@ func (s *session) handleHandshakeComplete() {
 
 func (s *session) handleHandshakeConfirmed() {
 	s.handshakeConfirmed = true
+	defer s.handshakeConfirmedCtxCancel(nil)
 	s.sentPacketHandler.SetHandshakeConfirmed()
 	s.cryptoStreamHandler.SetHandshakeConfirmed()
 
@@ -814,14 +1207,24 @@ func (s *session) handleHandshakeConfirmed() {
 		if maxPacketSize == 0 {
 			maxPacketSize = protocol.MaxByteCount
 		}
-		maxPacketSize = utils.MinByteCount(maxPacketSize, protocol.MaxPacketBufferSize)
+		if s.config.usesLargePackets() {
+			maxPacketSize = utils.MinByteCount(maxPacketSize, protocol.MaxLargePacketBufferSize)
+		} else {
+			maxPacketSize = utils.MinByteCount(maxPacketSize, protocol.MaxPacketBufferSize)
+		}
+		if s.config.MaxPathMTUProbeSize != 0 {
+			maxPacketSize = utils.MinByteCount(maxPacketSize, protocol.ByteCount(s.config.MaxPathMTUProbeSize))
+		}
 		s.mtuDiscoverer = newMTUDiscoverer(
 			s.rttStats,
-			getMaxPacketSize(s.conn.RemoteAddr()),
+			getInitialPacketSize(s.conn.RemoteAddr(), s.config.InitialPacketSize),
 			maxPacketSize,
 			func(size protocol.ByteCount) {
 				s.sentPacketHandler.SetMaxDatagramSize(size)
 				s.packer.SetMaxPacketSize(size)
+				if s.tracer != nil {
+					s.tracer.UpdatedMTU(size)
+				}
 			},
 		)
 	}
@@ -860,11 +1263,15 @@ func (s *session) handlePacketImpl(rp *receivedPacket) bool {
 		}
 
 		if hdr.IsLongHeader && hdr.Version != s.version {
-			if s.tracer != nil {
-				s.tracer.DroppedPacket(logging.PacketTypeFromHeader(hdr), protocol.ByteCount(len(data)), logging.PacketDropUnexpectedVersion)
+			if !s.acceptsCompatibleVersion(hdr.Version) {
+				if s.tracer != nil {
+					s.tracer.DroppedPacket(logging.PacketTypeFromHeader(hdr), protocol.ByteCount(len(data)), logging.PacketDropUnexpectedVersion)
+				}
+				s.logger.Debugf("Dropping packet with version %x. Expected %x.", hdr.Version, s.version)
+				break
 			}
-			s.logger.Debugf("Dropping packet with version %x. Expected %x.", hdr.Version, s.version)
-			break
+			s.logger.Debugf("Server switched to compatible version %s.", hdr.Version)
+			s.switchVersion(hdr.Version)
 		}
 
 		if counter > 0 && !hdr.DestConnectionID.Equal(lastConnID) {
@@ -980,7 +1387,7 @@ func (s *session) handleSinglePacket(p *receivedPacket, hdr *wire.Header) bool /
 		return false
 	}
 
-	if err := s.handleUnpackedPacket(packet, p.ecn, p.rcvTime, p.Size()); err != nil {
+	if err := s.handleUnpackedPacket(packet, p.ecn, p.rcvTime, p.Size(), p.remoteAddr); err != nil {
 		s.closeLocal(err)
 		return false
 	}
@@ -1107,7 +1514,8 @@ func (s *session) handleUnpackedPacket(
 	packet *unpackedPacket,
 	ecn protocol.ECN,
 	rcvTime time.Time,
-	packetSize protocol.ByteCount, // only for logging
+	packetSize protocol.ByteCount,
+	remoteAddr net.Addr,
 ) error {
 	if len(packet.data) == 0 {
 		return &qerr.TransportError{
@@ -1118,15 +1526,10 @@ func (s *session) handleUnpackedPacket(
 
 	if !s.receivedFirstPacket {
 		s.receivedFirstPacket = true
-		if !s.versionNegotiated && s.tracer != nil {
-			var clientVersions, serverVersions []protocol.VersionNumber
-			switch s.perspective {
-			case protocol.PerspectiveClient:
-				clientVersions = s.config.Versions
-			case protocol.PerspectiveServer:
-				serverVersions = s.config.Versions
-			}
-			s.tracer.NegotiatedVersion(s.version, clientVersions, serverVersions)
+		// The server might still switch to a compatible version when handling the client's transport parameters.
+		// It traces the version after that.
+		if !s.versionNegotiated && s.perspective == protocol.PerspectiveClient && s.tracer != nil {
+			s.tracer.NegotiatedVersion(s.version, s.config.Versions, nil)
 		}
 		// The server can change the source connection ID with the first Handshake packet.
 		if s.perspective == protocol.PerspectiveClient && packet.hdr.IsLongHeader && !packet.hdr.SrcConnectionID.Equal(s.handshakeDestConnID) {
@@ -1156,15 +1559,30 @@ func (s *session) handleUnpackedPacket(
 		}
 	}
 
+	atomic.AddUint64(&s.packetsReceived, 1)
 	s.lastPacketReceivedTime = rcvTime
 	s.firstAckElicitingPacketAfterIdleSentTime = time.Time{}
 	s.keepAlivePingSent = false
 
+	// The client might have migrated to a new address.
+	fromNewPath := s.perspective == protocol.PerspectiveServer &&
+		packet.encryptionLevel == protocol.Encryption1RTT &&
+		remoteAddr != nil && remoteAddr.String() != s.conn.RemoteAddr().String()
+	handleFrame := func(frame wire.Frame) error {
+		// PATH_RESPONSE frames need to be sent on the path that the PATH_CHALLENGE was received on.
+		if f, ok := frame.(*wire.PathChallengeFrame); ok && fromNewPath {
+			wire.LogFrame(s.logger, f, false)
+			return s.sendPathResponse(f, remoteAddr)
+		}
+		return s.handleFrame(frame, packet.encryptionLevel, packet.hdr.DestConnectionID)
+	}
+
 	// Only used for tracing.
 	// If we're not tracing, this slice will always remain empty.
 	var frames []wire.Frame
 	r := bytes.NewReader(packet.data)
 	var isAckEliciting bool
+	isProbing := true
 	for {
 		frame, err := s.frameParser.ParseNext(r, packet.encryptionLevel)
 		if err != nil {
@@ -1176,10 +1594,13 @@ func (s *session) handleUnpackedPacket(
 		if ackhandler.IsFrameAckEliciting(frame) {
 			isAckEliciting = true
 		}
+		if !isProbingFrame(frame) {
+			isProbing = false
+		}
 		// Only process frames now if we're not logging.
 		// If we're logging, we need to make sure that the packet_received event is logged first.
 		if s.tracer == nil {
-			if err := s.handleFrame(frame, packet.encryptionLevel, packet.hdr.DestConnectionID); err != nil {
+			if err := handleFrame(frame); err != nil {
 				return err
 			}
 		} else {
@@ -1194,12 +1615,27 @@ func (s *session) handleUnpackedPacket(
 		}
 		s.tracer.ReceivedPacket(packet.hdr, packetSize, fs)
 		for _, frame := range frames {
-			if err := s.handleFrame(frame, packet.encryptionLevel, packet.hdr.DestConnectionID); err != nil {
+			if err := handleFrame(frame); err != nil {
 				return err
 			}
 		}
 	}
 
+	// The data received on an unvalidated path determines how much the server may send on it.
+	if pv := s.pathValidation; pv != nil && pv.previous != nil && remoteAddr != nil && remoteAddr.String() == pv.conn.RemoteAddr().String() {
+		pv.bytesReceived += packetSize
+	}
+	if packet.encryptionLevel == protocol.Encryption1RTT {
+		// Only the highest-numbered non-probing packet indicates that the client migrated (RFC 9000, Section 9.3).
+		if fromNewPath && !isProbing && packet.packetNumber > s.largestRcvdPacketNumber {
+			s.handlePeerMigration(remoteAddr, packetSize)
+		}
+		if packet.packetNumber > s.largestRcvdPacketNumber && !s.config.DisableSpinBit {
+			s.handleSpinBit(packet.hdr.SpinBit, rcvTime)
+		}
+		s.largestRcvdPacketNumber = utils.MaxPacketNumber(s.largestRcvdPacketNumber, packet.packetNumber)
+	}
+
 	return s.receivedPacketHandler.ReceivedPacket(packet.packetNumber, ecn, packet.encryptionLevel, rcvTime, isAckEliciting)
 }
 
@@ -1210,6 +1646,7 @@ func (s *session) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel, d
 	case *wire.CryptoFrame:
 		err = s.handleCryptoFrame(frame, encLevel)
 	case *wire.StreamFrame:
+		s.onApplicationActivity(s.lastPacketReceivedTime)
 		err = s.handleStreamFrame(frame)
 	case *wire.AckFrame:
 		err = s.handleAckFrame(frame, encLevel)
@@ -1232,8 +1669,7 @@ func (s *session) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel, d
 	case *wire.PathChallengeFrame:
 		s.handlePathChallengeFrame(frame)
 	case *wire.PathResponseFrame:
-		// since we don't send PATH_CHALLENGEs, we don't expect PATH_RESPONSEs
-		err = errors.New("unexpected PATH_RESPONSE frame")
+		s.handlePathResponseFrame(frame)
 	case *wire.NewTokenFrame:
 		err = s.handleNewTokenFrame(frame)
 	case *wire.NewConnectionIDFrame:
@@ -1243,7 +1679,12 @@ func (s *session) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel, d
 	case *wire.HandshakeDoneFrame:
 		err = s.handleHandshakeDoneFrame()
 	case *wire.DatagramFrame:
+		s.onApplicationActivity(s.lastPacketReceivedTime)
 		err = s.handleDatagramFrame(frame)
+	case *wire.AckFrequencyFrame:
+		err = s.handleAckFrequencyFrame(frame)
+	case *wire.ImmediateAckFrame:
+		s.receivedPacketHandler.ReceivedImmediateAck()
 	default:
 		err = fmt.Errorf("unexpected frame type: %s", reflect.ValueOf(&frame).Elem().Type().Name())
 	}
@@ -1263,6 +1704,17 @@ func (s *session) handlePacket(p *receivedPacket) {
 	}
 }
 
+func (s *session) handleAckFrequencyFrame(frame *wire.AckFrequencyFrame) error {
+	if frame.RequestMaxAckDelay < protocol.MinAckDelay {
+		return &qerr.TransportError{
+			ErrorCode:    qerr.ProtocolViolation,
+			ErrorMessage: fmt.Sprintf("requested max ack delay (%s) smaller than min_ack_delay (%s)", frame.RequestMaxAckDelay, protocol.MinAckDelay),
+		}
+	}
+	s.receivedPacketHandler.ReceivedAckFrequency(frame)
+	return nil
+}
+
 func (s *session) handleConnectionCloseFrame(frame *wire.ConnectionCloseFrame) {
 	if frame.IsApplicationError {
 		s.closeRemote(&qerr.ApplicationError{
@@ -1356,6 +1808,30 @@ func (s *session) handlePathChallengeFrame(frame *wire.PathChallengeFrame) {
 	s.queueControlFrame(&wire.PathResponseFrame{Data: frame.Data})
 }
 
+func (s *session) handlePathResponseFrame(frame *wire.PathResponseFrame) {
+	// PATH_RESPONSE frames that don't belong to the path validation in progress are ignored.
+	// They might be late responses to a PATH_CHALLENGE sent during a previous path validation.
+	if s.pathValidation == nil || !s.pathValidation.sentChallenge(frame.Data) {
+		return
+	}
+	pv := s.pathValidation
+	s.pathValidation = nil
+	s.logger.Debugf("Validated path to %s.", pv.conn.RemoteAddr())
+	if s.perspective == protocol.PerspectiveClient {
+		// The new path works. Switch to it, and stop receiving packets on the old socket.
+		oldLocalAddr := s.conn.LocalAddr()
+		s.conn.SetPath(pv.conn)
+		for _, r := range append([]sessionRunner{}, s.runner.runners...) {
+			if r != sessionRunner(pv.runner) {
+				s.unregisterFrom(r)
+			}
+		}
+		s.sentPacketHandler.OnConnectionMigration(!isSameIP(oldLocalAddr, pv.conn.LocalAddr()))
+		s.logger.Infof("Migrated connection from %s to %s.", oldLocalAddr, pv.conn.LocalAddr())
+		pv.result <- nil
+	}
+}
+
 func (s *session) handleNewTokenFrame(frame *wire.NewTokenFrame) error {
 	if s.perspective == protocol.PerspectiveServer {
 		return &qerr.TransportError{
@@ -1499,6 +1975,8 @@ func (s *session) handleCloseError(closeErr *closeError) {
 		}
 	}
 
+	// This is a no-op if the handshake was already confirmed.
+	s.handshakeConfirmedCtxCancel(e)
 	s.streamsMap.CloseWithError(e)
 	s.connIDManager.Close()
 	if s.datagramQueue != nil {
@@ -1548,6 +2026,9 @@ func (s *session) restoreTransportParameters(params *wire.TransportParameters) {
 	if s.logger.Debug() {
 		s.logger.Debugf("Restoring Transport Parameters: %s", params)
 	}
+	if s.tracer != nil {
+		s.tracer.RestoredTransportParameters(params)
+	}
 
 	s.peerParams = params
 	s.connIDGenerator.SetMaxActiveConnIDs(params.ActiveConnectionIDLimit)
@@ -1555,6 +2036,24 @@ func (s *session) restoreTransportParameters(params *wire.TransportParameters) {
 	s.streamsMap.UpdateLimits(params)
 }
 
+// acceptsCompatibleVersion says if the client switches to version v when receiving a packet of that version.
+// The server can only switch to a compatible version with its first packet.
+func (s *session) acceptsCompatibleVersion(v protocol.VersionNumber) bool {
+	return s.perspective == protocol.PerspectiveClient &&
+		!s.receivedFirstPacket &&
+		protocol.AreCompatibleVersions(s.version, v) &&
+		protocol.IsSupportedVersion(s.config.Versions, v)
+}
+
+// switchVersion switches to a compatible version.
+// Compatible versions use the same frame encoding, so only the packet headers and the packet protection change.
+func (s *session) switchVersion(v protocol.VersionNumber) {
+	s.version = v
+	s.versionUpgraded = true
+	s.packer.SetVersion(v)
+	s.cryptoStreamHandler.ChangeVersion(v)
+}
+
 func (s *session) handleTransportParameters(params *wire.TransportParameters) {
 	if err := s.checkTransportParameters(params); err != nil {
 		s.closeLocal(&qerr.TransportError{
@@ -1562,6 +2061,15 @@ func (s *session) handleTransportParameters(params *wire.TransportParameters) {
 			ErrorMessage: err.Error(),
 		})
 	}
+	if err := s.handleVersionInformation(params.VersionInformation); err != nil {
+		s.closeLocal(&qerr.TransportError{
+			ErrorCode:    qerr.VersionNegotiationErrorErrorCode,
+			ErrorMessage: err.Error(),
+		})
+	}
+	if s.perspective == protocol.PerspectiveServer && s.tracer != nil {
+		s.tracer.NegotiatedVersion(s.version, nil, s.config.Versions)
+	}
 	s.peerParams = params
 	// On the client side we have to wait for handshake completion.
 	// During a 0-RTT connection, we are only allowed to use the new transport parameters for 1-RTT packets.
@@ -1606,11 +2114,44 @@ func (s *session) checkTransportParameters(params *wire.TransportParameters) err
 	return nil
 }
 
+// handleVersionInformation handles the peer's version_information transport parameter (RFC 9368).
+// The server switches to the most preferred version that is compatible with the current version and supported by the client.
+func (s *session) handleVersionInformation(vi *wire.VersionInformation) error {
+	if vi == nil {
+		if s.versionUpgraded {
+			return errors.New("server switched versions without sending version_information")
+		}
+		return nil
+	}
+	if vi.ChosenVersion != s.version {
+		return fmt.Errorf("expected chosen version to equal %s, is %s", s.version, vi.ChosenVersion)
+	}
+	// 0-RTT packets are protected using the original version.
+	if s.perspective == protocol.PerspectiveClient || s.enable0RTT {
+		return nil
+	}
+	for _, v := range s.config.Versions {
+		if v == s.version {
+			return nil
+		}
+		if protocol.AreCompatibleVersions(s.version, v) && protocol.IsSupportedVersion(vi.AvailableVersions, v) {
+			s.logger.Debugf("Switching to compatible version %s.", v)
+			s.switchVersion(v)
+			return nil
+		}
+	}
+	return nil
+}
+
 func (s *session) applyTransportParameters() {
 	params := s.peerParams
 	// Our local idle timeout will always be > 0.
 	s.idleTimeout = utils.MinNonZeroDuration(s.config.MaxIdleTimeout, params.MaxIdleTimeout)
-	s.keepAliveInterval = utils.MinDuration(s.idleTimeout/2, protocol.MaxKeepAliveInterval)
+	if s.config.KeepAlivePeriod > 0 {
+		s.keepAliveInterval = utils.MinDuration(s.config.KeepAlivePeriod, s.idleTimeout/2)
+	} else {
+		s.keepAliveInterval = utils.MinDuration(s.idleTimeout/2, protocol.MaxKeepAliveInterval)
+	}
 	s.streamsMap.UpdateLimits(params)
 	s.packer.HandleTransportParameters(params)
 	s.frameParser.SetAckDelayExponent(params.AckDelayExponent)
@@ -1632,6 +2173,9 @@ func (s *session) sendPackets() error {
 
 	var sentPacket bool // only used in for packets sent in send mode SendAny
 	for {
+		if s.pathValidation != nil && s.pathValidation.isAmplificationLimited() {
+			return nil
+		}
 		sendMode := s.sentPacketHandler.SendMode()
 		if sendMode == ackhandler.SendAny && s.handshakeComplete && !s.sentPacketHandler.HasPacingBudget() {
 			deadline := s.sentPacketHandler.TimeUntilSend()
@@ -1675,9 +2219,14 @@ func (s *session) sendPackets() error {
 			}
 		case ackhandler.SendAny:
 			sent, err := s.sendPacket()
-			if err != nil || !sent {
+			if err != nil {
 				return err
 			}
+			if !sent {
+				// We ran out of data to send before the congestion window was full.
+				s.sentPacketHandler.OnAppLimited()
+				return nil
+			}
 			sentPacket = true
 		default:
 			return fmt.Errorf("BUG: invalid send mode %d", sendMode)
@@ -1764,10 +2313,11 @@ func (s *session) sendPacket() (bool, error) {
 			if s.firstAckElicitingPacketAfterIdleSentTime.IsZero() && p.IsAckEliciting() {
 				s.firstAckElicitingPacketAfterIdleSentTime = now
 			}
+			s.onSentFrames(p.frames, now)
 			s.sentPacketHandler.SentPacket(p.ToAckHandlerPacket(now, s.retransmissionQueue))
 		}
 		s.connIDManager.SentPacket()
-		s.sendQueue.Send(packet.buffer)
+		s.sendQueue.Send(packet.buffer, protocol.ECNNon)
 		return true, nil
 	}
 	if !s.config.DisablePathMTUDiscovery && s.mtuDiscoverer.ShouldSendProbe(now) {
@@ -1790,10 +2340,20 @@ func (s *session) sendPackedPacket(packet *packedPacket, now time.Time) {
 	if s.firstAckElicitingPacketAfterIdleSentTime.IsZero() && packet.IsAckEliciting() {
 		s.firstAckElicitingPacketAfterIdleSentTime = now
 	}
+	s.onSentFrames(packet.frames, now)
 	s.logPacket(packet)
-	s.sentPacketHandler.SentPacket(packet.ToAckHandlerPacket(now, s.retransmissionQueue))
+	ecn := protocol.ECNNon
+	if s.config.EnableECN {
+		ecn = s.sentPacketHandler.ECNMode()
+	}
+	p := packet.ToAckHandlerPacket(now, s.retransmissionQueue)
+	p.ECN = ecn
+	s.sentPacketHandler.SentPacket(p)
 	s.connIDManager.SentPacket()
-	s.sendQueue.Send(packet.buffer)
+	if s.pathValidation != nil {
+		s.pathValidation.onSent(packet.buffer.Len())
+	}
+	s.sendQueue.Send(packet.buffer, ecn)
 }
 
 func (s *session) sendConnectionClose(e error) ([]byte, error) {
@@ -1815,7 +2375,8 @@ func (s *session) sendConnectionClose(e error) ([]byte, error) {
 		return nil, err
 	}
 	s.logCoalescedPacket(packet)
-	return packet.buffer.Data, s.conn.Write(packet.buffer.Data)
+	// The packet buffer is not released, since the closed session retransmits the packet.
+	return packet.buffer.Data, s.conn.Write(packet.buffer.Data, protocol.ECNNon)
 }
 
 func (s *session) logPacketContents(p *packetContents) {
@@ -1958,20 +2519,34 @@ func (s *session) onStreamCompleted(id protocol.StreamID) {
 	if err := s.streamsMap.DeleteStream(id); err != nil {
 		s.closeLocal(err)
 	}
+	s.framer.RemoveStream(id)
 }
 
-func (s *session) SendMessage(p []byte) error {
+func (s *session) onStreamPriorityChanged(id protocol.StreamID, weight int, incremental bool) {
+	s.framer.SetStreamPriority(id, weight, incremental)
+}
+
+func (s *session) SendDatagram(p []byte) error {
+	if !s.config.EnableDatagrams || !s.supportsDatagrams() {
+		return errors.New("datagram support disabled")
+	}
 	f := &wire.DatagramFrame{DataLenPresent: true}
-	if protocol.ByteCount(len(p)) > f.MaxDataLen(s.peerParams.MaxDatagramFrameSize, s.version) {
-		return errors.New("message too large")
+	if maxDataLen := f.MaxDataLen(s.peerParams.MaxDatagramFrameSize, s.version); protocol.ByteCount(len(p)) > maxDataLen {
+		return &DatagramTooLargeError{MaxDataLen: int64(maxDataLen)}
 	}
 	f.Data = make([]byte, len(p))
 	copy(f.Data, p)
+	if s.config.DatagramSendQueueLen > 0 {
+		return s.datagramQueue.Add(f)
+	}
 	return s.datagramQueue.AddAndWait(f)
 }
 
-func (s *session) ReceiveMessage() ([]byte, error) {
-	return s.datagramQueue.Receive()
+func (s *session) ReceiveDatagram(ctx context.Context) ([]byte, error) {
+	if !s.config.EnableDatagrams {
+		return nil, errors.New("datagram support disabled")
+	}
+	return s.datagramQueue.Receive(ctx)
 }
 
 func (s *session) LocalAddr() net.Addr {
@@ -1982,6 +2557,298 @@ func (s *session) RemoteAddr() net.Addr {
 	return s.conn.RemoteAddr()
 }
 
+func (s *session) MigrateTo(conn net.PacketConn) error {
+	if s.perspective == protocol.PerspectiveServer {
+		return errors.New("only the client can migrate a connection")
+	}
+	result := make(chan error, 1)
+	select {
+	case s.migrationRequests <- migrationRequest{conn: conn, result: result}:
+	case <-s.ctx.Done():
+		return errors.New("session closed")
+	}
+	select {
+	case err := <-result:
+		return err
+	case <-s.ctx.Done():
+		return errors.New("session closed")
+	}
+}
+
+// A migrationRequest is a request to migrate the session to a new socket, made by MigrateTo.
+type migrationRequest struct {
+	conn   net.PacketConn
+	result chan<- error
+}
+
+// A pathValidation is the validation of a new network path (RFC 9000, Section 8.2).
+type pathValidation struct {
+	conn          sendConn  // the path that is validated
+	challenges    [][8]byte // the data of all PATH_CHALLENGE frames sent on this path
+	nextChallenge time.Time
+
+	// only set for the client
+	runner sessionRunner // the packet handler map of the new socket
+	result chan<- error  // receives the outcome of the migration
+
+	// only set for the server
+	previous sendConn // the last validated path, used when path validation fails
+	// Until the path is validated, the server sends at most ackhandler.AmplificationFactor (but at least 3) times
+	// the amount of data received on it (RFC 9000, Section 9.3).
+	bytesReceived protocol.ByteCount
+	bytesSent     protocol.ByteCount
+}
+
+// sendAllowance is the number of bytes the server may still send on the unvalidated path.
+func (p *pathValidation) sendAllowance() protocol.ByteCount {
+	factor := utils.MaxByteCount(ackhandler.AmplificationFactor, protocol.AmplificationFactor)
+	if limit := factor * p.bytesReceived; limit > p.bytesSent {
+		return limit - p.bytesSent
+	}
+	return 0
+}
+
+func (p *pathValidation) isAmplificationLimited() bool {
+	return p.previous != nil && p.sendAllowance() == 0
+}
+
+func (p *pathValidation) onSent(size protocol.ByteCount) {
+	if p.previous != nil {
+		p.bytesSent += size
+	}
+}
+
+func (p *pathValidation) sentChallenge(data [8]byte) bool {
+	for _, c := range p.challenges {
+		if c == data {
+			return true
+		}
+	}
+	return false
+}
+
+// startMigration starts migrating the session to a new socket.
+// The session only switches to the new socket once path validation succeeds.
+func (s *session) startMigration(req migrationRequest) error {
+	if !s.handshakeConfirmed {
+		return errors.New("can't migrate before the handshake is confirmed")
+	}
+	if s.peerParams.DisableActiveMigration {
+		return errors.New("the peer disabled active connection migration")
+	}
+	if s.pathValidation != nil {
+		return errors.New("a migration is already in progress")
+	}
+	if s.config.DSCP != 0 {
+		if err := setDSCP(req.conn, uint8(s.config.DSCP)); err != nil {
+			return err
+		}
+	}
+	manager, err := getMultiplexer().AddConn(req.conn, s.srcConnIDLen, s.config.StatelessResetKey, s.config.Tracer)
+	if err != nil {
+		return err
+	}
+	if s.config.usesLargePackets() {
+		manager.EnableLargePackets()
+	}
+	runner := s.runnerFor(manager)
+	for _, r := range s.runner.runners {
+		if r == runner {
+			return errors.New("the session is already using this packet conn")
+		}
+	}
+	// A connection ID must not be used from more than one local address.
+	if !s.connIDManager.SwitchForMigration() {
+		return errors.New("the peer didn't provide an unused connection ID")
+	}
+	// Packets might arrive on both sockets until we switch to the new path.
+	for _, connID := range s.connIDGenerator.ConnectionIDs() {
+		runner.Add(connID, s)
+	}
+	if token := s.connIDManager.ActiveStatelessResetToken(); token != nil {
+		runner.AddResetToken(*token, s)
+	}
+	s.runner.add(runner)
+
+	s.logger.Debugf("Migrating connection from %s to %s.", s.conn.LocalAddr(), req.conn.LocalAddr())
+	s.pathValidation = &pathValidation{
+		conn:   newSendPconn(req.conn, s.conn.RemoteAddr(), uint8(s.config.DSCP)),
+		runner: runner,
+		result: req.result,
+	}
+	s.sendPathChallenge(time.Now())
+	return nil
+}
+
+// handlePeerMigration is called by the server when the client migrated to a new address.
+// We switch to the new path immediately, and revert to the old path if path validation fails.
+// Until the new path is validated, the amount of data sent on it is limited by the anti-amplification limit.
+func (s *session) handlePeerMigration(remoteAddr net.Addr, packetSize protocol.ByteCount) {
+	if !s.config.EnableActiveMigration || !s.handshakeConfirmed {
+		return
+	}
+	conn, ok := s.conn.Path().(pathConn)
+	if !ok {
+		return
+	}
+	previous := s.conn.Path()
+	if s.pathValidation != nil {
+		// The client migrated again, before the previous migration was validated.
+		previous = s.pathValidation.previous
+	}
+	s.logger.Debugf("Client migrated from %s to %s.", s.conn.RemoteAddr(), remoteAddr)
+	s.pathValidation = &pathValidation{
+		conn:          conn.withRemoteAddr(remoteAddr),
+		previous:      previous,
+		bytesReceived: packetSize,
+	}
+	s.conn.SetPath(s.pathValidation.conn)
+	// Use a new connection ID on the new path, if the client provided one.
+	s.connIDManager.SwitchForMigration()
+	s.sentPacketHandler.OnConnectionMigration(!isSameIP(conn.RemoteAddr(), remoteAddr))
+	s.sendPathChallenge(time.Now())
+}
+
+func (s *session) sendPathChallenge(now time.Time) {
+	var data [8]byte
+	rand.Read(data[:])
+	pv := s.pathValidation
+	pv.challenges = append(pv.challenges, data)
+	pv.nextChallenge = now.Add(s.rttStats.PTO(true))
+	size := protocol.ByteCount(protocol.MinInitialPacketSize)
+	if pv.previous != nil {
+		// The server only pads the PATH_CHALLENGE as far as the anti-amplification limit allows (RFC 9000, Section 8.2.1).
+		if pv.isAmplificationLimited() {
+			return
+		}
+		size = utils.MinByteCount(size, pv.sendAllowance())
+	}
+	// PATH_CHALLENGE frames are not retransmitted. We send a new one after a PTO instead.
+	n, err := s.sendPathProbePacket(&wire.PathChallengeFrame{Data: data}, pv.conn, size, now)
+	if err != nil {
+		s.failPathValidation(err)
+		return
+	}
+	pv.onSent(n)
+}
+
+// PATH_RESPONSE frames are sent on the path that the PATH_CHALLENGE frame was received on.
+func (s *session) sendPathResponse(frame *wire.PathChallengeFrame, remoteAddr net.Addr) error {
+	conn, ok := s.conn.Path().(pathConn)
+	if !ok {
+		s.handlePathChallengeFrame(frame)
+		return nil
+	}
+	if _, err := s.sendPathProbePacket(&wire.PathResponseFrame{Data: frame.Data}, conn.withRemoteAddr(remoteAddr), protocol.MinInitialPacketSize, time.Now()); err != nil {
+		s.logger.Debugf("Sending PATH_RESPONSE to %s failed: %s", remoteAddr, err)
+	}
+	return nil
+}
+
+// sendPathProbePacket sends a packet containing a PATH_CHALLENGE or a PATH_RESPONSE frame on a path other than the current path.
+// Datagrams containing these frames have to be padded to at least 1200 bytes (RFC 9000, Section 8.2),
+// unless the anti-amplification limit doesn't allow sending that much.
+// It returns the size of the packet.
+func (s *session) sendPathProbePacket(f wire.Frame, conn sendConn, size protocol.ByteCount, now time.Time) (protocol.ByteCount, error) {
+	packet, err := s.packer.PackPathProbePacket(ackhandler.Frame{Frame: f, OnLost: func(wire.Frame) {}}, size)
+	if err != nil {
+		return 0, err
+	}
+	s.logPacket(packet)
+	s.sentPacketHandler.SentPacket(packet.ToAckHandlerPacket(now, s.retransmissionQueue))
+	s.connIDManager.SentPacket()
+	defer packet.buffer.Release()
+	return packet.buffer.Len(), conn.Write(packet.buffer.Data, protocol.ECNNon)
+}
+
+func (s *session) onPathValidationTimeout(now time.Time) {
+	if len(s.pathValidation.challenges) >= protocol.MaxPathChallenges {
+		s.failPathValidation(errors.New("path validation timed out"))
+		return
+	}
+	s.sendPathChallenge(now)
+}
+
+func (s *session) failPathValidation(err error) {
+	pv := s.pathValidation
+	s.pathValidation = nil
+	s.logger.Debugf("Validating path to %s failed: %s", pv.conn.RemoteAddr(), err)
+	switch s.perspective {
+	case protocol.PerspectiveClient:
+		s.unregisterFrom(pv.runner)
+		pv.result <- err
+	case protocol.PerspectiveServer:
+		s.conn.SetPath(pv.previous)
+	}
+}
+
+// unregisterFrom removes the session from the packet handler map of a socket that we migrated away from.
+func (s *session) unregisterFrom(r sessionRunner) {
+	for _, connID := range s.connIDGenerator.ConnectionIDs() {
+		r.Remove(connID)
+	}
+	if token := s.connIDManager.ActiveStatelessResetToken(); token != nil {
+		r.RemoveResetToken(*token)
+	}
+	s.runner.remove(r)
+}
+
+// If the spin bit is disabled, a random value is sent for the whole connection (RFC 9000, Section 17.4).
+func (s *session) initSpinBit() {
+	if !s.config.DisableSpinBit {
+		return
+	}
+	var b [1]byte
+	rand.Read(b[:])
+	s.spinBit = b[0]&1 > 0
+	s.packer.SetSpinBit(s.spinBit)
+}
+
+// handleSpinBit handles the spin bit of the 1-RTT packet with the largest packet number received so far.
+// The server reflects the spin bit, the client inverts it (RFC 9000, Section 17.4).
+// This way, the spin bit flips once per round trip.
+func (s *session) handleSpinBit(spin bool, rcvTime time.Time) {
+	if spin != s.rcvdSpinBit {
+		if !s.lastSpinBitFlip.IsZero() && s.tracer != nil {
+			s.tracer.UpdatedSpinBitRTT(rcvTime.Sub(s.lastSpinBitFlip))
+		}
+		s.lastSpinBitFlip = rcvTime
+	}
+	s.rcvdSpinBit = spin
+	if s.perspective == protocol.PerspectiveClient {
+		spin = !spin
+	}
+	if spin != s.spinBit {
+		s.spinBit = spin
+		s.packer.SetSpinBit(spin)
+	}
+}
+
+// isProbingFrame says if a frame is a probing frame.
+// Packets that only contain probing frames don't indicate that the peer migrated (RFC 9000, Section 9.1).
+func isProbingFrame(f wire.Frame) bool {
+	switch f.(type) {
+	case *wire.PathChallengeFrame, *wire.PathResponseFrame, *wire.NewConnectionIDFrame:
+		return true
+	default:
+		return false
+	}
+}
+
+// isSameIP says if two addresses have the same (specified) IP address.
+// If so, it's likely that the connection still uses the same network path, e.g. after a NAT rebinding.
+func isSameIP(a, b net.Addr) bool {
+	udpA, ok := a.(*net.UDPAddr)
+	if !ok {
+		return false
+	}
+	udpB, ok := b.(*net.UDPAddr)
+	if !ok {
+		return false
+	}
+	return !udpA.IP.IsUnspecified() && udpA.IP.Equal(udpB.IP)
+}
+
 func (s *session) getPerspective() protocol.Perspective {
 	return s.perspective
 }
@@ -11,8 +11,10 @@ import (
 	"net"
 	"runtime/pprof"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/lucas-clemente/quic-go/congestion"
 	"github.com/lucas-clemente/quic-go/internal/ackhandler"
 	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
@@ -87,6 +89,7 @@ var _ = Describe("Session", func() {
 		mconn = NewMockSendConn(mockCtrl)
 		mconn.EXPECT().RemoteAddr().Return(remoteAddr).AnyTimes()
 		mconn.EXPECT().LocalAddr().Return(localAddr).AnyTimes()
+		mconn.EXPECT().SupportsGSO().AnyTimes()
 		tokenGenerator, err := handshake.NewTokenGenerator(rand.Reader)
 		Expect(err).ToNot(HaveOccurred())
 		tracer = mocklogging.NewMockConnectionTracer(mockCtrl)
@@ -94,6 +97,7 @@ var _ = Describe("Session", func() {
 		tracer.EXPECT().SentTransportParameters(gomock.Any())
 		tracer.EXPECT().UpdatedKeyFromTLS(gomock.Any(), gomock.Any()).AnyTimes()
 		tracer.EXPECT().UpdatedCongestionState(gomock.Any())
+		tracer.EXPECT().UpdatedCongestionControl("newreno", "standard")
 		sess = newSession(
 			mconn,
 			sessionRunner,
@@ -287,9 +291,47 @@ var _ = Describe("Session", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
-		It("rejects PATH_RESPONSE frames", func() {
-			err := sess.handleFrame(&wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}, protocol.Encryption1RTT, protocol.ConnectionID{})
-			Expect(err).To(MatchError("unexpected PATH_RESPONSE frame"))
+		It("handles ACK_FREQUENCY frames", func() {
+			rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
+			sess.receivedPacketHandler = rph
+			f := &wire.AckFrequencyFrame{
+				SequenceNumber:        1,
+				AckElicitingThreshold: 5,
+				RequestMaxAckDelay:    50 * time.Millisecond,
+				ReorderingThreshold:   3,
+			}
+			rph.EXPECT().ReceivedAckFrequency(f)
+			Expect(sess.handleFrame(f, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+		})
+
+		It("rejects ACK_FREQUENCY frames requesting a max ack delay smaller than the min_ack_delay", func() {
+			err := sess.handleFrame(&wire.AckFrequencyFrame{RequestMaxAckDelay: protocol.MinAckDelay - 1}, protocol.Encryption1RTT, protocol.ConnectionID{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.ProtocolViolation))
+		})
+
+		It("handles IMMEDIATE_ACK frames", func() {
+			rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
+			sess.receivedPacketHandler = rph
+			rph.EXPECT().ReceivedImmediateAck()
+			Expect(sess.handleFrame(&wire.ImmediateAckFrame{}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+		})
+
+		It("ignores PATH_RESPONSE frames that don't belong to a path validation", func() {
+			Expect(sess.handleFrame(&wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			sess.pathValidation = &pathValidation{conn: mconn, challenges: [][8]byte{{8, 7, 6, 5, 4, 3, 2, 1}}, previous: mconn}
+			Expect(sess.handleFrame(&wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			Expect(sess.pathValidation).ToNot(BeNil())
+		})
+
+		It("completes path validation when receiving a matching PATH_RESPONSE frame", func() {
+			sess.pathValidation = &pathValidation{
+				conn:       mconn,
+				challenges: [][8]byte{{8, 7, 6, 5, 4, 3, 2, 1}, {1, 2, 3, 4, 5, 6, 7, 8}},
+				previous:   mconn,
+			}
+			Expect(sess.handleFrame(&wire.PathResponseFrame{Data: [8]byte{8, 7, 6, 5, 4, 3, 2, 1}}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			Expect(sess.pathValidation).To(BeNil())
 		})
 
 		It("handles PATH_CHALLENGE frames", func() {
@@ -394,6 +436,151 @@ var _ = Describe("Session", func() {
 		})
 	})
 
+	It("returns the congestion state", func() {
+		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+		sph.EXPECT().CongestionState().Return(ackhandler.CongestionState{
+			CongestionControl: "bbr",
+			Hystart:           "none",
+			CongestionWindow:  1000,
+			BytesInFlight:     500,
+			SmoothedRTT:       time.Second,
+			MinRTT:            time.Millisecond,
+			Phase:             logging.CongestionStateRecovery,
+			SpuriousLosses:    2,
+			BandwidthEstimate: 1e6,
+		})
+		sess.sentPacketHandler = sph
+		Expect(sess.CongestionState()).To(Equal(CongestionState{
+			CongestionControl: "bbr",
+			Hystart:           "none",
+			CongestionWindow:  1000,
+			BytesInFlight:     500,
+			SmoothedRTT:       time.Second,
+			MinRTT:            time.Millisecond,
+			Phase:             logging.CongestionStateRecovery,
+			SpuriousLosses:    2,
+			BandwidthEstimate: 1e6,
+		}))
+	})
+
+	It("returns the connection state", func() {
+		tlsState := handshake.ConnectionState{Used0RTT: true}
+		tlsState.NegotiatedProtocol = "h3"
+		tlsState.HandshakeComplete = true
+		cryptoSetup.EXPECT().ConnectionState().Return(tlsState)
+		sess.peerParams = &wire.TransportParameters{MaxDatagramFrameSize: 1000}
+		sess.handshakeCtxCancel()
+		state, err := sess.ConnectionStateErr()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(state.TLS.NegotiatedProtocol).To(Equal("h3"))
+		Expect(state.TLS.HandshakeComplete).To(BeTrue())
+		Expect(state.SupportsDatagrams).To(BeTrue())
+		Expect(state.Used0RTT).To(BeTrue())
+		Expect(state.Version).To(Equal(sess.version))
+	})
+
+	It("doesn't return the connection state before the handshake completes", func() {
+		// don't EXPECT any calls to cryptoSetup.ConnectionState()
+		_, err := sess.ConnectionStateErr()
+		Expect(err).To(MatchError(ErrHandshakeNotComplete))
+	})
+
+	It("doesn't rotate the connection ID before the handshake completes", func() {
+		sess.handshakeComplete = false
+		Expect(sess.rotateConnectionID()).To(MatchError("the handshake hasn't completed yet"))
+	})
+
+	It("returns the connection statistics", func() {
+		packer.EXPECT().BlockedFrameCounts().Return(blockedFrameCounts{
+			DataBlocked:       1,
+			StreamDataBlocked: 2,
+			StreamsBlocked:    3,
+		})
+		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+		sph.EXPECT().PacketStats().Return(ackhandler.PacketStats{
+			PacketsSent:          100,
+			PacketsLost:          5,
+			PacketsRetransmitted: 4,
+			LossRate:             0.1,
+		})
+		sess.sentPacketHandler = sph
+		sess.packetsReceived = 42
+		Expect(sess.ConnectionStats()).To(Equal(ConnectionStats{
+			DataBlockedFramesSent:       1,
+			StreamDataBlockedFramesSent: 2,
+			StreamsBlockedFramesSent:    3,
+			PacketsSent:                 100,
+			PacketsReceived:             42,
+			PacketsLost:                 5,
+			PacketsRetransmitted:        4,
+			LossRate:                    0.1,
+		}))
+	})
+
+	It("returns the RTT statistics", func() {
+		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+		sph.EXPECT().RTTStats().Return(ackhandler.RTTStats{
+			SmoothedRTT: time.Second,
+			RTTVar:      100 * time.Millisecond,
+			MinRTT:      time.Millisecond,
+			LatestRTT:   2 * time.Second,
+		})
+		sess.sentPacketHandler = sph
+		Expect(sess.RTTStats()).To(Equal(RTTStats{
+			SmoothedRTT: time.Second,
+			RTTVar:      100 * time.Millisecond,
+			MinRTT:      time.Millisecond,
+			LatestRTT:   2 * time.Second,
+		}))
+	})
+
+	It("switches the congestion controller", func() {
+		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+		opts := congestion.CongestionOptions{ControlType: congestion.BbrControlType}
+		sph.EXPECT().SetCongestionControl(opts)
+		sess.sentPacketHandler = sph
+		Expect(sess.SetCongestionControl(opts)).To(Succeed())
+		sess.ctxCancel()
+		Expect(sess.SetCongestionControl(opts)).To(MatchError("session closed"))
+	})
+
+	It("refuses to replace a congestion controller created by Config.NewCongestionControl", func() {
+		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+		sess.sentPacketHandler = sph
+		sess.config.NewCongestionControl = func(*congestion.RTTStats, congestion.ByteCount, logging.ConnectionTracer) congestion.SendAlgorithmWithDebugInfos {
+			return nil
+		}
+		Expect(sess.SetCongestionControl(congestion.CongestionOptions{})).To(MatchError("can't replace a congestion controller created by Config.NewCongestionControl"))
+	})
+
+	It("limits the send rate", func() {
+		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+		sess.sentPacketHandler = sph
+		sph.EXPECT().SetSendRateLimit(uint64(1250000))
+		sess.SetSendRateLimit(1250000)
+		sph.EXPECT().SetSendRateLimit(uint64(0))
+		sess.SetSendRateLimit(-1)
+	})
+
+	It("limits the bytes in flight", func() {
+		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+		sess.sentPacketHandler = sph
+		sph.EXPECT().BytesInFlight().Return(protocol.ByteCount(1234))
+		Expect(sess.BytesInFlight()).To(Equal(protocol.ByteCount(1234)))
+		sph.EXPECT().SetMaxBytesInFlight(protocol.ByteCount(10000))
+		sess.SetMaxBytesInFlight(10000)
+	})
+
+	It("initiates a key update", func() {
+		cryptoSetup.EXPECT().InitiateKeyUpdate()
+		Expect(sess.InitiateKeyUpdate()).To(Succeed())
+		// a PING frame is queued, such that a packet using the new keys is sent
+		frames, _ := sess.framer.AppendControlFrames(nil, protocol.MaxByteCount)
+		Expect(frames).To(Equal([]ackhandler.Frame{{Frame: &wire.PingFrame{}}}))
+		sess.ctxCancel()
+		Expect(sess.InitiateKeyUpdate()).To(MatchError("session closed"))
+	})
+
 	It("tells its versions", func() {
 		sess.version = 4242
 		Expect(sess.GetVersion()).To(Equal(protocol.VersionNumber(4242)))
@@ -440,7 +627,7 @@ var _ = Describe("Session", func() {
 				Expect(e.ErrorMessage).To(BeEmpty())
 				return &coalescedPacket{buffer: buffer}, nil
 			})
-			mconn.EXPECT().Write([]byte("connection close"))
+			mconn.EXPECT().Write([]byte("connection close"), gomock.Any())
 			gomock.InOrder(
 				tracer.EXPECT().ClosedConnection(gomock.Any()).Do(func(e error) {
 					var appErr *ApplicationError
@@ -461,7 +648,7 @@ var _ = Describe("Session", func() {
 			expectReplaceWithClosed()
 			cryptoSetup.EXPECT().Close()
 			packer.EXPECT().PackApplicationClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			tracer.EXPECT().ClosedConnection(gomock.Any())
 			tracer.EXPECT().Close()
 			sess.shutdown()
@@ -470,6 +657,50 @@ var _ = Describe("Session", func() {
 			Expect(sess.Context().Done()).To(BeClosed())
 		})
 
+		It("returns the stream statistics, until it is closed", func() {
+			runSession()
+			stats := []StreamStats{{StreamID: 4, BytesSent: 1337}}
+			streamManager.EXPECT().Stats().Return(stats)
+			Expect(sess.StreamStats()).To(Equal(stats))
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackApplicationClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
+			tracer.EXPECT().ClosedConnection(gomock.Any())
+			tracer.EXPECT().Close()
+			sess.shutdown()
+			Eventually(areSessionsRunning).Should(BeFalse())
+			Expect(sess.StreamStats()).To(BeNil())
+		})
+
+		It("rotates the connection ID", func() {
+			var queued []wire.Frame
+			sess.connIDGenerator.queueControlFrame = func(f wire.Frame) { queued = append(queued, f) }
+			var newConnID protocol.ConnectionID
+			sessionRunner.EXPECT().Add(gomock.Any(), sess).Do(func(c protocol.ConnectionID, _ packetHandler) { newConnID = c }).Return(true)
+			sessionRunner.EXPECT().GetStatelessResetToken(gomock.Any())
+			runSession()
+			Expect(sess.RotateConnectionID()).To(Succeed())
+			Expect(queued).To(HaveLen(1))
+			Expect(queued[0]).To(BeAssignableToTypeOf(&wire.NewConnectionIDFrame{}))
+			nf := queued[0].(*wire.NewConnectionIDFrame)
+			Expect(nf.ConnectionID).To(Equal(newConnID))
+			Expect(nf.SequenceNumber).To(BeEquivalentTo(1))
+			Expect(nf.RetirePriorTo).To(BeEquivalentTo(1))
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			expectReplaceWithClosed()
+			sessionRunner.EXPECT().ReplaceWithClosed(newConnID, gomock.Any())
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackApplicationClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
+			tracer.EXPECT().ClosedConnection(gomock.Any())
+			tracer.EXPECT().Close()
+			sess.shutdown()
+			Eventually(areSessionsRunning).Should(BeFalse())
+			Expect(sess.RotateConnectionID()).To(MatchError("session closed"))
+		})
+
 		It("closes with an error", func() {
 			runSession()
 			expectedErr := &qerr.ApplicationError{
@@ -480,7 +711,7 @@ var _ = Describe("Session", func() {
 			expectReplaceWithClosed()
 			cryptoSetup.EXPECT().Close()
 			packer.EXPECT().PackApplicationClose(expectedErr).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			gomock.InOrder(
 				tracer.EXPECT().ClosedConnection(expectedErr),
 				tracer.EXPECT().Close(),
@@ -501,7 +732,7 @@ var _ = Describe("Session", func() {
 			expectReplaceWithClosed()
 			cryptoSetup.EXPECT().Close()
 			packer.EXPECT().PackConnectionClose(expectedErr).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			gomock.InOrder(
 				tracer.EXPECT().ClosedConnection(expectedErr),
 				tracer.EXPECT().Close(),
@@ -550,7 +781,7 @@ var _ = Describe("Session", func() {
 				close(returned)
 			}()
 			Consistently(returned).ShouldNot(BeClosed())
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			tracer.EXPECT().ClosedConnection(gomock.Any())
 			tracer.EXPECT().Close()
 			sess.shutdown()
@@ -600,11 +831,12 @@ var _ = Describe("Session", func() {
 		It("closes when the sendQueue encounters an error", func() {
 			sess.handshakeConfirmed = true
 			conn := NewMockSendConn(mockCtrl)
-			conn.EXPECT().Write(gomock.Any()).Return(io.ErrClosedPipe).AnyTimes()
-			sess.sendQueue = newSendQueue(conn)
+			conn.EXPECT().Write(gomock.Any(), gomock.Any()).Return(io.ErrClosedPipe).AnyTimes()
+			sess.sendQueue = newSendQueue(conn, false)
 			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
 			sph.EXPECT().GetLossDetectionTimeout().Return(time.Now().Add(time.Hour)).AnyTimes()
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().OnAppLimited().AnyTimes()
 			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
 			// only expect a single SentPacket() call
 			sph.EXPECT().SentPacket(gomock.Any())
@@ -836,7 +1068,7 @@ var _ = Describe("Session", func() {
 			// make the go routine return
 			tracer.EXPECT().ClosedConnection(gomock.Any())
 			tracer.EXPECT().Close()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			sess.closeLocal(errors.New("close"))
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
@@ -880,7 +1112,7 @@ var _ = Describe("Session", func() {
 			expectReplaceWithClosed()
 			tracer.EXPECT().ClosedConnection(gomock.Any())
 			tracer.EXPECT().Close()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			sess.closeLocal(errors.New("close"))
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
@@ -925,7 +1157,7 @@ var _ = Describe("Session", func() {
 			expectReplaceWithClosed()
 			tracer.EXPECT().ClosedConnection(gomock.Any())
 			tracer.EXPECT().Close()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			sess.closeLocal(errors.New("close"))
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
@@ -946,7 +1178,7 @@ var _ = Describe("Session", func() {
 				close(done)
 			}()
 			expectReplaceWithClosed()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			packet := getPacket(&wire.ExtendedHeader{
 				Header:          wire.Header{DestConnectionID: srcConnID},
 				PacketNumberLen: protocol.PacketNumberLen1,
@@ -979,7 +1211,7 @@ var _ = Describe("Session", func() {
 			packer.EXPECT().PackApplicationClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 			tracer.EXPECT().ClosedConnection(gomock.Any())
 			tracer.EXPECT().Close()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			sess.shutdown()
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
@@ -1000,7 +1232,7 @@ var _ = Describe("Session", func() {
 				close(done)
 			}()
 			expectReplaceWithClosed()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			packet := getPacket(&wire.ExtendedHeader{
 				Header:          wire.Header{DestConnectionID: srcConnID},
 				PacketNumberLen: protocol.PacketNumberLen1,
@@ -1031,7 +1263,7 @@ var _ = Describe("Session", func() {
 				close(done)
 			}()
 			expectReplaceWithClosed()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			tracer.EXPECT().ClosedConnection(gomock.Any())
 			tracer.EXPECT().Close()
 			sess.handlePacket(getPacket(&wire.ExtendedHeader{
@@ -1106,7 +1338,7 @@ var _ = Describe("Session", func() {
 		})
 
 		Context("updating the remote address", func() {
-			It("doesn't support connection migration", func() {
+			It("doesn't follow the client to a new address before the handshake is confirmed", func() {
 				unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(&unpackedPacket{
 					encryptionLevel: protocol.Encryption1RTT,
 					hdr:             &wire.ExtendedHeader{},
@@ -1120,6 +1352,202 @@ var _ = Describe("Session", func() {
 				tracer.EXPECT().StartedConnection(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 				tracer.EXPECT().ReceivedPacket(gomock.Any(), protocol.ByteCount(len(packet.data)), gomock.Any())
 				Expect(sess.handlePacketImpl(packet)).To(BeTrue())
+				Expect(sess.RemoteAddr()).To(Equal(remoteAddr))
+			})
+
+			Context("after the handshake is confirmed", func() {
+				var pconn *MockPacketConn
+				newAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 100), Port: 1234}
+
+				BeforeEach(func() {
+					pconn = NewMockPacketConn(mockCtrl)
+					pconn.EXPECT().LocalAddr().Return(localAddr).AnyTimes()
+					sess.conn = newMigratableConn(newSendPconn(pconn, remoteAddr, 0))
+					sess.config.EnableActiveMigration = true
+					sess.handshakeConfirmed = true
+					tracer.EXPECT().StartedConnection(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+					tracer.EXPECT().UpdatedCongestionState(gomock.Any()).AnyTimes()
+					tracer.EXPECT().UpdatedCongestionControl("newreno", "standard").AnyTimes() // the congestion controller is reset when migrating
+					tracer.EXPECT().UpdatedMetrics(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+				})
+
+				receivePacket := func(pn protocol.PacketNumber, data []byte) protocol.ByteCount {
+					unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(&unpackedPacket{
+						packetNumber:    pn,
+						encryptionLevel: protocol.Encryption1RTT,
+						hdr:             &wire.ExtendedHeader{PacketNumber: pn},
+						data:            data,
+					}, nil)
+					packet := getPacket(&wire.ExtendedHeader{
+						Header:          wire.Header{DestConnectionID: srcConnID},
+						PacketNumber:    pn,
+						PacketNumberLen: protocol.PacketNumberLen1,
+					}, nil)
+					packet.remoteAddr = newAddr
+					size := protocol.ByteCount(len(packet.data))
+					tracer.EXPECT().ReceivedPacket(gomock.Any(), size, gomock.Any())
+					Expect(sess.handlePacketImpl(packet)).To(BeTrue())
+					return size
+				}
+
+				var lastPN protocol.PacketNumber
+				var challengeSize protocol.ByteCount // the size the last PATH_CHALLENGE was padded to
+				expectPathChallenge := func() {
+					lastPN++
+					pn := lastPN
+					buffer := getPacketBuffer()
+					buffer.Data = append(buffer.Data, []byte("foobar")...)
+					packer.EXPECT().PackPathProbePacket(gomock.Any(), gomock.Any()).DoAndReturn(func(f ackhandler.Frame, size protocol.ByteCount) (*packedPacket, error) {
+						Expect(f.Frame).To(BeAssignableToTypeOf(&wire.PathChallengeFrame{}))
+						challengeSize = size
+						return &packedPacket{
+							buffer: buffer,
+							packetContents: &packetContents{
+								header: &wire.ExtendedHeader{PacketNumber: pn},
+								frames: []ackhandler.Frame{f},
+								length: 6,
+							},
+						}, nil
+					})
+					tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+					pconn.EXPECT().WriteTo([]byte("foobar"), newAddr)
+				}
+
+				It("doesn't follow the client if active migration is not enabled", func() {
+					sess.config.EnableActiveMigration = false
+					receivePacket(10, []byte{0x1}) // one PING frame
+					Expect(sess.RemoteAddr()).To(Equal(remoteAddr))
+					Expect(sess.pathValidation).To(BeNil())
+				})
+
+				It("follows the client to a new address, and validates the new path", func() {
+					expectPathChallenge()
+					size := receivePacket(10, []byte{0x1}) // one PING frame
+					// The packet is smaller than 400 bytes, so the PATH_CHALLENGE is only padded to 3x its size.
+					Expect(challengeSize).To(Equal(3 * size))
+					Expect(sess.RemoteAddr()).To(Equal(newAddr))
+					Expect(sess.pathValidation).ToNot(BeNil())
+					Expect(sess.pathValidation.challenges).To(HaveLen(1))
+					Expect(sess.handleFrame(&wire.PathResponseFrame{Data: sess.pathValidation.challenges[0]}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+					Expect(sess.pathValidation).To(BeNil())
+					Expect(sess.RemoteAddr()).To(Equal(newAddr))
+				})
+
+				It("limits the amount of data sent before the new path is validated", func() {
+					expectPathChallenge()
+					size := receivePacket(10, []byte{0x1}) // one PING frame
+					pv := sess.pathValidation
+					Expect(pv).ToNot(BeNil())
+					Expect(pv.bytesReceived).To(Equal(size))
+					Expect(pv.bytesSent).To(BeEquivalentTo(6))
+					pv.bytesSent = 3 * size
+					Expect(pv.isAmplificationLimited()).To(BeTrue())
+					// no packets are packed
+					Expect(sess.sendPackets()).To(Succeed())
+					// no PATH_CHALLENGE is sent when the PTO expires
+					sess.onPathValidationTimeout(time.Now())
+					Expect(pv.challenges).To(HaveLen(2))
+					// receiving more data on the new path increases the limit
+					receivePacket(11, []byte{0x1}) // one PING frame
+					Expect(pv.bytesReceived).To(Equal(2 * size))
+					Expect(pv.sendAllowance()).To(Equal(3 * size))
+					Expect(sess.handleFrame(&wire.PathResponseFrame{Data: pv.challenges[0]}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+					Expect(sess.pathValidation).To(BeNil())
+				})
+
+				It("uses the configured amplification factor for the new path", func() {
+					defer func(f protocol.ByteCount) { ackhandler.AmplificationFactor = f }(ackhandler.AmplificationFactor)
+					ackhandler.AmplificationFactor = 5
+					expectPathChallenge()
+					size := receivePacket(10, []byte{0x1}) // one PING frame
+					pv := sess.pathValidation
+					Expect(pv).ToNot(BeNil())
+					Expect(pv.sendAllowance()).To(Equal(5*size - pv.bytesSent))
+				})
+
+				It("doesn't follow the client when receiving a probing packet", func() {
+					buffer := getPacketBuffer()
+					buffer.Data = append(buffer.Data, []byte("foobar")...)
+					packer.EXPECT().PackPathProbePacket(gomock.Any(), protocol.ByteCount(protocol.MinInitialPacketSize)).DoAndReturn(func(f ackhandler.Frame, _ protocol.ByteCount) (*packedPacket, error) {
+						Expect(f.Frame).To(Equal(&wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}))
+						return &packedPacket{
+							buffer: buffer,
+							packetContents: &packetContents{
+								header: &wire.ExtendedHeader{PacketNumber: 1},
+								frames: []ackhandler.Frame{f},
+								length: 6,
+							},
+						}, nil
+					})
+					tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+					pconn.EXPECT().WriteTo([]byte("foobar"), newAddr)
+					receivePacket(10, []byte{0x1a, 1, 2, 3, 4, 5, 6, 7, 8}) // one PATH_CHALLENGE frame
+					Expect(sess.RemoteAddr()).To(Equal(remoteAddr))
+					Expect(sess.pathValidation).To(BeNil())
+				})
+
+				It("reverts to the previous path when path validation fails", func() {
+					for i := 0; i < protocol.MaxPathChallenges; i++ {
+						expectPathChallenge()
+					}
+					receivePacket(10, []byte{0x1}) // one PING frame
+					Expect(sess.RemoteAddr()).To(Equal(newAddr))
+					for sess.pathValidation != nil {
+						sess.onPathValidationTimeout(time.Now())
+					}
+					Expect(sess.RemoteAddr()).To(Equal(remoteAddr))
+				})
+			})
+		})
+
+		Context("spin bit", func() {
+			receivePacket := func(pn protocol.PacketNumber, spin bool, rcvTime time.Time) {
+				unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(&unpackedPacket{
+					packetNumber:    pn,
+					encryptionLevel: protocol.Encryption1RTT,
+					hdr:             &wire.ExtendedHeader{PacketNumber: pn, SpinBit: spin},
+					data:            []byte{0}, // one PADDING frame
+				}, nil)
+				packet := getPacket(&wire.ExtendedHeader{
+					Header:          wire.Header{DestConnectionID: srcConnID},
+					PacketNumber:    pn,
+					PacketNumberLen: protocol.PacketNumberLen1,
+				}, nil)
+				packet.rcvTime = rcvTime
+				tracer.EXPECT().ReceivedPacket(gomock.Any(), gomock.Any(), gomock.Any())
+				Expect(sess.handlePacketImpl(packet)).To(BeTrue())
+			}
+
+			BeforeEach(func() {
+				tracer.EXPECT().StartedConnection(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			})
+
+			It("reflects the spin bit, and traces the RTT", func() {
+				now := time.Now()
+				gomock.InOrder(
+					packer.EXPECT().SetSpinBit(true),
+					tracer.EXPECT().UpdatedSpinBitRTT(20*time.Millisecond),
+					packer.EXPECT().SetSpinBit(false),
+					tracer.EXPECT().UpdatedSpinBitRTT(25*time.Millisecond),
+					packer.EXPECT().SetSpinBit(true),
+				)
+				receivePacket(1, false, now)
+				receivePacket(2, true, now.Add(10*time.Millisecond))
+				receivePacket(3, true, now.Add(15*time.Millisecond))
+				receivePacket(4, false, now.Add(30*time.Millisecond))
+				receivePacket(5, true, now.Add(55*time.Millisecond))
+			})
+
+			It("ignores the spin bit of reordered packets", func() {
+				now := time.Now()
+				packer.EXPECT().SetSpinBit(true)
+				receivePacket(2, true, now)
+				receivePacket(1, false, now.Add(time.Millisecond))
+			})
+
+			It("ignores the spin bit, if disabled", func() {
+				sess.config.DisableSpinBit = true
+				receivePacket(1, true, time.Now())
 			})
 		})
 
@@ -1258,7 +1686,7 @@ var _ = Describe("Session", func() {
 			packer.EXPECT().PackApplicationClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 			expectReplaceWithClosed()
 			cryptoSetup.EXPECT().Close()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			tracer.EXPECT().ClosedConnection(gomock.Any())
 			tracer.EXPECT().Close()
 			sender.EXPECT().Close()
@@ -1282,6 +1710,7 @@ var _ = Describe("Session", func() {
 			sph.EXPECT().TimeUntilSend().AnyTimes()
 			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().OnAppLimited().AnyTimes()
 			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
 			sph.EXPECT().SentPacket(gomock.Any())
 			sess.sentPacketHandler = sph
@@ -1291,7 +1720,33 @@ var _ = Describe("Session", func() {
 			packer.EXPECT().PackPacket().Return(nil, nil).AnyTimes()
 			sent := make(chan struct{})
 			sender.EXPECT().WouldBlock().AnyTimes()
-			sender.EXPECT().Send(gomock.Any()).Do(func(packet *packetBuffer) { close(sent) })
+			sender.EXPECT().Send(gomock.Any(), protocol.ECNNon).Do(func(packet *packetBuffer, _ protocol.ECN) { close(sent) })
+			tracer.EXPECT().SentPacket(p.header, p.buffer.Len(), nil, []logging.Frame{})
+			sess.scheduleSending()
+			Eventually(sent).Should(BeClosed())
+		})
+
+		It("marks packets with the ECN codepoint requested by the sent packet handler, if ECN is enabled", func() {
+			sess.handshakeConfirmed = true
+			sess.config.EnableECN = true
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sph.EXPECT().TimeUntilSend().AnyTimes()
+			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().OnAppLimited().AnyTimes()
+			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
+			sph.EXPECT().ECNMode().Return(protocol.ECT0)
+			sph.EXPECT().SentPacket(gomock.Any()).Do(func(p *ackhandler.Packet) {
+				Expect(p.ECN).To(Equal(protocol.ECT0))
+			})
+			sess.sentPacketHandler = sph
+			runSession()
+			p := getPacket(1)
+			packer.EXPECT().PackPacket().Return(p, nil)
+			packer.EXPECT().PackPacket().Return(nil, nil).AnyTimes()
+			sent := make(chan struct{})
+			sender.EXPECT().WouldBlock().AnyTimes()
+			sender.EXPECT().Send(gomock.Any(), protocol.ECT0).Do(func(*packetBuffer, protocol.ECN) { close(sent) })
 			tracer.EXPECT().SentPacket(p.header, p.buffer.Len(), nil, []logging.Frame{})
 			sess.scheduleSending()
 			Eventually(sent).Should(BeClosed())
@@ -1306,6 +1761,28 @@ var _ = Describe("Session", func() {
 			time.Sleep(50 * time.Millisecond) // make sure there are no calls to mconn.Write()
 		})
 
+		It("tells the sent packet handler when it runs out of data to send", func() {
+			sess.handshakeConfirmed = true
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sph.EXPECT().TimeUntilSend().AnyTimes()
+			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
+			sph.EXPECT().SentPacket(gomock.Any()).Times(2)
+			sess.sentPacketHandler = sph
+			packer.EXPECT().PackPacket().Return(getPacket(1), nil)
+			packer.EXPECT().PackPacket().Return(getPacket(2), nil)
+			packer.EXPECT().PackPacket().Return(nil, nil)
+			appLimited := make(chan struct{})
+			sph.EXPECT().OnAppLimited().Do(func() { close(appLimited) })
+			sender.EXPECT().WouldBlock().AnyTimes()
+			sender.EXPECT().Send(gomock.Any(), gomock.Any()).Times(2)
+			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+			runSession()
+			sess.scheduleSending()
+			Eventually(appLimited).Should(BeClosed())
+		})
+
 		It("sends ACK only packets", func() {
 			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
 			sph.EXPECT().TimeUntilSend().AnyTimes()
@@ -1325,6 +1802,7 @@ var _ = Describe("Session", func() {
 			sph.EXPECT().TimeUntilSend().AnyTimes()
 			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().OnAppLimited().AnyTimes()
 			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
 			sph.EXPECT().SentPacket(gomock.Any())
 			sess.sentPacketHandler = sph
@@ -1337,7 +1815,7 @@ var _ = Describe("Session", func() {
 			sess.connFlowController = fc
 			runSession()
 			sent := make(chan struct{})
-			sender.EXPECT().Send(gomock.Any()).Do(func(packet *packetBuffer) { close(sent) })
+			sender.EXPECT().Send(gomock.Any(), gomock.Any()).Do(func(packet *packetBuffer, _ protocol.ECN) { close(sent) })
 			tracer.EXPECT().SentPacket(p.header, p.length, nil, []logging.Frame{})
 			sess.scheduleSending()
 			Eventually(sent).Should(BeClosed())
@@ -1393,7 +1871,7 @@ var _ = Describe("Session", func() {
 					sess.sentPacketHandler = sph
 					runSession()
 					sent := make(chan struct{})
-					sender.EXPECT().Send(gomock.Any()).Do(func(packet *packetBuffer) { close(sent) })
+					sender.EXPECT().Send(gomock.Any(), gomock.Any()).Do(func(packet *packetBuffer, _ protocol.ECN) { close(sent) })
 					tracer.EXPECT().SentPacket(p.header, p.length, gomock.Any(), gomock.Any())
 					sess.scheduleSending()
 					Eventually(sent).Should(BeClosed())
@@ -1414,7 +1892,7 @@ var _ = Describe("Session", func() {
 					sess.sentPacketHandler = sph
 					runSession()
 					sent := make(chan struct{})
-					sender.EXPECT().Send(gomock.Any()).Do(func(packet *packetBuffer) { close(sent) })
+					sender.EXPECT().Send(gomock.Any(), gomock.Any()).Do(func(packet *packetBuffer, _ protocol.ECN) { close(sent) })
 					tracer.EXPECT().SentPacket(p.header, p.length, gomock.Any(), gomock.Any())
 					sess.scheduleSending()
 					Eventually(sent).Should(BeClosed())
@@ -1450,7 +1928,7 @@ var _ = Describe("Session", func() {
 			packer.EXPECT().PackApplicationClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 			expectReplaceWithClosed()
 			cryptoSetup.EXPECT().Close()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			tracer.EXPECT().ClosedConnection(gomock.Any())
 			tracer.EXPECT().Close()
 			sender.EXPECT().Close()
@@ -1467,7 +1945,7 @@ var _ = Describe("Session", func() {
 			packer.EXPECT().PackPacket().Return(getPacket(10), nil)
 			packer.EXPECT().PackPacket().Return(getPacket(11), nil)
 			sender.EXPECT().WouldBlock().AnyTimes()
-			sender.EXPECT().Send(gomock.Any()).Times(2)
+			sender.EXPECT().Send(gomock.Any(), gomock.Any()).Times(2)
 			go func() {
 				defer GinkgoRecover()
 				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
@@ -1483,8 +1961,9 @@ var _ = Describe("Session", func() {
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny).Times(2)
 			packer.EXPECT().PackPacket().Return(getPacket(10), nil)
 			packer.EXPECT().PackPacket().Return(nil, nil)
+			sph.EXPECT().OnAppLimited()
 			sender.EXPECT().WouldBlock().AnyTimes()
-			sender.EXPECT().Send(gomock.Any())
+			sender.EXPECT().Send(gomock.Any(), gomock.Any())
 			go func() {
 				defer GinkgoRecover()
 				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
@@ -1501,7 +1980,7 @@ var _ = Describe("Session", func() {
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny)
 			packer.EXPECT().MaybePackAckPacket(gomock.Any()).Return(getPacket(10), nil)
 			sender.EXPECT().WouldBlock().AnyTimes()
-			sender.EXPECT().Send(gomock.Any())
+			sender.EXPECT().Send(gomock.Any(), gomock.Any())
 			go func() {
 				defer GinkgoRecover()
 				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
@@ -1520,7 +1999,7 @@ var _ = Describe("Session", func() {
 			sph.EXPECT().SendMode().Return(ackhandler.SendAck)
 			packer.EXPECT().PackPacket().Return(getPacket(100), nil)
 			sender.EXPECT().WouldBlock().AnyTimes()
-			sender.EXPECT().Send(gomock.Any())
+			sender.EXPECT().Send(gomock.Any(), gomock.Any())
 			go func() {
 				defer GinkgoRecover()
 				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
@@ -1533,6 +2012,7 @@ var _ = Describe("Session", func() {
 		It("paces packets", func() {
 			pacingDelay := scaleDuration(100 * time.Millisecond)
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().OnAppLimited().AnyTimes()
 			gomock.InOrder(
 				sph.EXPECT().HasPacingBudget().Return(true),
 				packer.EXPECT().PackPacket().Return(getPacket(100), nil),
@@ -1547,7 +2027,7 @@ var _ = Describe("Session", func() {
 			)
 			written := make(chan struct{}, 2)
 			sender.EXPECT().WouldBlock().AnyTimes()
-			sender.EXPECT().Send(gomock.Any()).DoAndReturn(func(p *packetBuffer) { written <- struct{}{} }).Times(2)
+			sender.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(func(p *packetBuffer, _ protocol.ECN) { written <- struct{}{} }).Times(2)
 			go func() {
 				defer GinkgoRecover()
 				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
@@ -1570,7 +2050,7 @@ var _ = Describe("Session", func() {
 			packer.EXPECT().PackPacket().Return(getPacket(1002), nil)
 			written := make(chan struct{}, 3)
 			sender.EXPECT().WouldBlock().AnyTimes()
-			sender.EXPECT().Send(gomock.Any()).DoAndReturn(func(p *packetBuffer) { written <- struct{}{} }).Times(3)
+			sender.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(func(p *packetBuffer, _ protocol.ECN) { written <- struct{}{} }).Times(3)
 			go func() {
 				defer GinkgoRecover()
 				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
@@ -1597,9 +2077,10 @@ var _ = Describe("Session", func() {
 			sph.EXPECT().SentPacket(gomock.Any())
 			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().OnAppLimited().AnyTimes()
 			packer.EXPECT().PackPacket().Return(getPacket(1000), nil)
 			packer.EXPECT().PackPacket().Return(nil, nil)
-			sender.EXPECT().Send(gomock.Any()).DoAndReturn(func(p *packetBuffer) { close(written) })
+			sender.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(func(p *packetBuffer, _ protocol.ECN) { close(written) })
 			available <- struct{}{}
 			Eventually(written).Should(BeClosed())
 		})
@@ -1620,9 +2101,10 @@ var _ = Describe("Session", func() {
 			})
 			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().OnAppLimited().AnyTimes()
 			packer.EXPECT().PackPacket().Return(getPacket(1000), nil)
 			packer.EXPECT().PackPacket().Return(nil, nil)
-			sender.EXPECT().Send(gomock.Any()).DoAndReturn(func(p *packetBuffer) { close(written) })
+			sender.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(func(p *packetBuffer, _ protocol.ECN) { close(written) })
 
 			sess.scheduleSending()
 			time.Sleep(scaleDuration(50 * time.Millisecond))
@@ -1638,7 +2120,7 @@ var _ = Describe("Session", func() {
 			written := make(chan struct{}, 1)
 			sender.EXPECT().WouldBlock()
 			sender.EXPECT().WouldBlock().Return(true).Times(2)
-			sender.EXPECT().Send(gomock.Any()).DoAndReturn(func(p *packetBuffer) { written <- struct{}{} })
+			sender.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(func(p *packetBuffer, _ protocol.ECN) { written <- struct{}{} })
 			go func() {
 				defer GinkgoRecover()
 				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
@@ -1654,10 +2136,11 @@ var _ = Describe("Session", func() {
 			sph.EXPECT().SentPacket(gomock.Any())
 			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().OnAppLimited().AnyTimes()
 			sender.EXPECT().WouldBlock().AnyTimes()
 			packer.EXPECT().PackPacket().Return(getPacket(1001), nil)
 			packer.EXPECT().PackPacket().Return(nil, nil)
-			sender.EXPECT().Send(gomock.Any()).DoAndReturn(func(p *packetBuffer) { written <- struct{}{} })
+			sender.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(func(p *packetBuffer, _ protocol.ECN) { written <- struct{}{} })
 			available <- struct{}{}
 			Eventually(written).Should(Receive())
 
@@ -1669,6 +2152,7 @@ var _ = Describe("Session", func() {
 		It("doesn't set a pacing timer when there is no data to send", func() {
 			sph.EXPECT().HasPacingBudget().Return(true)
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().OnAppLimited().AnyTimes()
 			sender.EXPECT().WouldBlock().AnyTimes()
 			packer.EXPECT().PackPacket()
 			// don't EXPECT any calls to mconn.Write()
@@ -1691,7 +2175,7 @@ var _ = Describe("Session", func() {
 			sph.EXPECT().SendMode().Return(ackhandler.SendNone)
 			written := make(chan struct{}, 1)
 			sender.EXPECT().WouldBlock().AnyTimes()
-			sender.EXPECT().Send(gomock.Any()).DoAndReturn(func(p *packetBuffer) { written <- struct{}{} })
+			sender.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(func(p *packetBuffer, _ protocol.ECN) { written <- struct{}{} })
 			gomock.InOrder(
 				mtuDiscoverer.EXPECT().NextProbeTime(),
 				mtuDiscoverer.EXPECT().ShouldSendProbe(gomock.Any()).Return(true),
@@ -1727,7 +2211,7 @@ var _ = Describe("Session", func() {
 			streamManager.EXPECT().CloseWithError(gomock.Any())
 			packer.EXPECT().PackApplicationClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 			cryptoSetup.EXPECT().Close()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			sender.EXPECT().Close()
 			tracer.EXPECT().ClosedConnection(gomock.Any())
 			tracer.EXPECT().Close()
@@ -1740,6 +2224,7 @@ var _ = Describe("Session", func() {
 			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
 			sph.EXPECT().TimeUntilSend().AnyTimes()
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().OnAppLimited().AnyTimes()
 			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
 			sph.EXPECT().SentPacket(gomock.Any())
 			sess.sentPacketHandler = sph
@@ -1755,7 +2240,7 @@ var _ = Describe("Session", func() {
 			time.Sleep(50 * time.Millisecond)
 			// only EXPECT calls after scheduleSending is called
 			written := make(chan struct{})
-			sender.EXPECT().Send(gomock.Any()).Do(func(*packetBuffer) { close(written) })
+			sender.EXPECT().Send(gomock.Any(), gomock.Any()).Do(func(*packetBuffer, protocol.ECN) { close(written) })
 			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 			sess.scheduleSending()
 			Eventually(written).Should(BeClosed())
@@ -1767,6 +2252,7 @@ var _ = Describe("Session", func() {
 			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
 			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().OnAppLimited().AnyTimes()
 			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
 			sph.EXPECT().SentPacket(gomock.Any()).Do(func(p *ackhandler.Packet) {
 				Expect(p.PacketNumber).To(Equal(protocol.PacketNumber(1234)))
@@ -1779,7 +2265,7 @@ var _ = Describe("Session", func() {
 			sess.receivedPacketHandler = rph
 
 			written := make(chan struct{})
-			sender.EXPECT().Send(gomock.Any()).Do(func(*packetBuffer) { close(written) })
+			sender.EXPECT().Send(gomock.Any(), gomock.Any()).Do(func(*packetBuffer, protocol.ECN) { close(written) })
 			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 			go func() {
 				defer GinkgoRecover()
@@ -1826,6 +2312,7 @@ var _ = Describe("Session", func() {
 
 		sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
 		sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+		sph.EXPECT().OnAppLimited().AnyTimes()
 		sph.EXPECT().TimeUntilSend().Return(time.Now()).AnyTimes()
 		gomock.InOrder(
 			sph.EXPECT().SentPacket(gomock.Any()).Do(func(p *ackhandler.Packet) {
@@ -1849,7 +2336,7 @@ var _ = Describe("Session", func() {
 		)
 
 		sent := make(chan struct{})
-		mconn.EXPECT().Write([]byte("foobar")).Do(func([]byte) { close(sent) })
+		mconn.EXPECT().Write([]byte("foobar"), gomock.Any()).Do(func([]byte, protocol.ECN) { close(sent) })
 
 		go func() {
 			defer GinkgoRecover()
@@ -1865,7 +2352,7 @@ var _ = Describe("Session", func() {
 		expectReplaceWithClosed()
 		packer.EXPECT().PackApplicationClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 		cryptoSetup.EXPECT().Close()
-		mconn.EXPECT().Write(gomock.Any())
+		mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 		tracer.EXPECT().ClosedConnection(gomock.Any())
 		tracer.EXPECT().Close()
 		sess.shutdown()
@@ -1892,19 +2379,26 @@ var _ = Describe("Session", func() {
 			sess.run()
 		}()
 		handshakeCtx := sess.HandshakeComplete()
+		confirmedCtx := sess.HandshakeConfirmed()
 		Consistently(handshakeCtx.Done()).ShouldNot(BeClosed())
+		Expect(confirmedCtx.Done()).ToNot(BeClosed())
 		close(finishHandshake)
 		Eventually(handshakeCtx.Done()).Should(BeClosed())
+		// for the server, the handshake is confirmed as soon as it completes
+		Eventually(confirmedCtx.Done()).Should(BeClosed())
+		Expect(contextCause(confirmedCtx)).To(Equal(context.Canceled))
 		// make sure the go routine returns
 		streamManager.EXPECT().CloseWithError(gomock.Any())
 		expectReplaceWithClosed()
 		packer.EXPECT().PackApplicationClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 		cryptoSetup.EXPECT().Close()
-		mconn.EXPECT().Write(gomock.Any())
+		mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 		tracer.EXPECT().ClosedConnection(gomock.Any())
 		tracer.EXPECT().Close()
 		sess.shutdown()
 		Eventually(sess.Context().Done()).Should(BeClosed())
+		// closing the session doesn't change the cause
+		Expect(contextCause(confirmedCtx)).To(Equal(context.Canceled))
 	})
 
 	It("sends a session ticket when the handshake completes", func() {
@@ -1945,7 +2439,7 @@ var _ = Describe("Session", func() {
 		expectReplaceWithClosed()
 		packer.EXPECT().PackApplicationClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 		cryptoSetup.EXPECT().Close()
-		mconn.EXPECT().Write(gomock.Any())
+		mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 		tracer.EXPECT().ClosedConnection(gomock.Any())
 		tracer.EXPECT().Close()
 		sess.shutdown()
@@ -1967,21 +2461,47 @@ var _ = Describe("Session", func() {
 		}()
 		handshakeCtx := sess.HandshakeComplete()
 		Consistently(handshakeCtx.Done()).ShouldNot(BeClosed())
-		mconn.EXPECT().Write(gomock.Any())
+		mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 		sess.closeLocal(errors.New("handshake error"))
 		Consistently(handshakeCtx.Done()).ShouldNot(BeClosed())
 		Eventually(sess.Context().Done()).Should(BeClosed())
 	})
 
+	It("cancels the HandshakeConfirmed context when the session is closed before the handshake is confirmed", func() {
+		packer.EXPECT().PackCoalescedPacket().AnyTimes()
+		streamManager.EXPECT().CloseWithError(gomock.Any())
+		expectReplaceWithClosed()
+		packer.EXPECT().PackApplicationClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+		cryptoSetup.EXPECT().Close()
+		tracer.EXPECT().ClosedConnection(gomock.Any())
+		tracer.EXPECT().Close()
+		go func() {
+			defer GinkgoRecover()
+			cryptoSetup.EXPECT().RunHandshake()
+			sess.run()
+		}()
+		confirmedCtx := sess.HandshakeConfirmed()
+		Consistently(confirmedCtx.Done()).ShouldNot(BeClosed())
+		mconn.EXPECT().Write(gomock.Any(), gomock.Any())
+		sess.closeLocal(&qerr.ApplicationError{ErrorCode: 0x1337, ErrorMessage: "foobar"})
+		Eventually(confirmedCtx.Done()).Should(BeClosed())
+		Eventually(sess.Context().Done()).Should(BeClosed())
+		if !contextCauseSupported {
+			Skip("context.Cause requires Go 1.20")
+		}
+		Expect(contextCause(confirmedCtx)).To(MatchError(&qerr.ApplicationError{ErrorCode: 0x1337, ErrorMessage: "foobar"}))
+	})
+
 	It("sends a HANDSHAKE_DONE frame when the handshake completes", func() {
 		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
 		sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+		sph.EXPECT().OnAppLimited().AnyTimes()
 		sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
 		sph.EXPECT().TimeUntilSend().AnyTimes()
 		sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
 		sph.EXPECT().SetHandshakeConfirmed()
 		sph.EXPECT().SentPacket(gomock.Any())
-		mconn.EXPECT().Write(gomock.Any())
+		mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 		tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 		sess.sentPacketHandler = sph
 		done := make(chan struct{})
@@ -2004,7 +2524,7 @@ var _ = Describe("Session", func() {
 			cryptoSetup.EXPECT().RunHandshake()
 			cryptoSetup.EXPECT().SetHandshakeConfirmed()
 			cryptoSetup.EXPECT().GetSessionTicket()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			close(sess.handshakeCompleteChan)
 			sess.run()
 		}()
@@ -2032,7 +2552,7 @@ var _ = Describe("Session", func() {
 		expectReplaceWithClosed()
 		packer.EXPECT().PackApplicationClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 		cryptoSetup.EXPECT().Close()
-		mconn.EXPECT().Write(gomock.Any())
+		mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 		tracer.EXPECT().ClosedConnection(gomock.Any())
 		tracer.EXPECT().Close()
 		sess.shutdown()
@@ -2056,7 +2576,7 @@ var _ = Describe("Session", func() {
 		expectReplaceWithClosed()
 		packer.EXPECT().PackApplicationClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 		cryptoSetup.EXPECT().Close()
-		mconn.EXPECT().Write(gomock.Any())
+		mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 		tracer.EXPECT().ClosedConnection(gomock.Any())
 		tracer.EXPECT().Close()
 		Expect(sess.CloseWithError(0x1337, testErr.Error())).To(Succeed())
@@ -2084,6 +2604,63 @@ var _ = Describe("Session", func() {
 			sess.handleTransportParameters(params)
 			Expect(sess.earlySessionReady()).To(BeClosed())
 		})
+
+		Context("compatible version negotiation", func() {
+			BeforeEach(func() {
+				sess.version = protocol.Version1
+				sess.config.Versions = []protocol.VersionNumber{protocol.Version2, protocol.Version1}
+			})
+
+			getParams := func(available ...protocol.VersionNumber) *wire.TransportParameters {
+				return &wire.TransportParameters{
+					InitialSourceConnectionID: destConnID,
+					VersionInformation: &wire.VersionInformation{
+						ChosenVersion:     protocol.Version1,
+						AvailableVersions: available,
+					},
+				}
+			}
+
+			expectApply := func(params *wire.TransportParameters) {
+				streamManager.EXPECT().UpdateLimits(params)
+				packer.EXPECT().HandleTransportParameters(params)
+				sessionRunner.EXPECT().GetStatelessResetToken(gomock.Any()).AnyTimes()
+				sessionRunner.EXPECT().Add(gomock.Any(), sess).AnyTimes()
+				tracer.EXPECT().ReceivedTransportParameters(params)
+			}
+
+			It("switches to the preferred compatible version", func() {
+				params := getParams(protocol.Version1, protocol.Version2)
+				expectApply(params)
+				packer.EXPECT().SetVersion(protocol.Version2)
+				cryptoSetup.EXPECT().ChangeVersion(protocol.Version2)
+				sess.handleTransportParameters(params)
+				Expect(sess.GetVersion()).To(Equal(protocol.Version2))
+			})
+
+			It("doesn't switch if the client doesn't support the version", func() {
+				params := getParams(protocol.Version1)
+				expectApply(params)
+				sess.handleTransportParameters(params)
+				Expect(sess.GetVersion()).To(Equal(protocol.Version1))
+			})
+
+			It("doesn't switch if it prefers the current version", func() {
+				sess.config.Versions = []protocol.VersionNumber{protocol.Version1, protocol.Version2}
+				params := getParams(protocol.Version1, protocol.Version2)
+				expectApply(params)
+				sess.handleTransportParameters(params)
+				Expect(sess.GetVersion()).To(Equal(protocol.Version1))
+			})
+
+			It("doesn't switch if 0-RTT is enabled", func() {
+				sess.enable0RTT = true
+				params := getParams(protocol.Version1, protocol.Version2)
+				expectApply(params)
+				sess.handleTransportParameters(params)
+				Expect(sess.GetVersion()).To(Equal(protocol.Version1))
+			})
+		})
 	})
 
 	Context("keep-alives", func() {
@@ -2117,7 +2694,7 @@ var _ = Describe("Session", func() {
 			streamManager.EXPECT().CloseWithError(gomock.Any())
 			packer.EXPECT().PackApplicationClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 			cryptoSetup.EXPECT().Close()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			tracer.EXPECT().ClosedConnection(gomock.Any())
 			tracer.EXPECT().Close()
 			sess.shutdown()
@@ -2149,6 +2726,37 @@ var _ = Describe("Session", func() {
 			Eventually(sent).Should(BeClosed())
 		})
 
+		It("sends a PING after the configured keep-alive period", func() {
+			sess.config.KeepAlive = false
+			sess.config.KeepAlivePeriod = 3 * time.Second
+			setRemoteIdleTimeout(time.Minute)
+			sess.lastPacketReceivedTime = time.Now().Add(-3 * time.Second)
+			sent := make(chan struct{})
+			packer.EXPECT().PackCoalescedPacket().Do(func() (*packedPacket, error) {
+				close(sent)
+				return nil, nil
+			})
+			runSession()
+			Eventually(sent).Should(BeClosed())
+		})
+
+		It("limits the keep-alive period to half the idle timeout", func() {
+			sess.config.KeepAlivePeriod = time.Minute
+			setRemoteIdleTimeout(5 * time.Second)
+			Expect(sess.keepAliveInterval).To(Equal(5 * time.Second / 2))
+			sess.lastPacketReceivedTime = time.Now()
+			runSession()
+		})
+
+		It("sends keep-alives at the configured period, even if it's longer than protocol.MaxKeepAliveInterval", func() {
+			sess.config.MaxIdleTimeout = time.Hour
+			sess.config.KeepAlivePeriod = time.Minute
+			setRemoteIdleTimeout(time.Hour)
+			Expect(sess.keepAliveInterval).To(Equal(time.Minute))
+			sess.lastPacketReceivedTime = time.Now()
+			runSession()
+		})
+
 		It("doesn't send a PING packet if keep-alive is disabled", func() {
 			setRemoteIdleTimeout(5 * time.Second)
 			sess.config.KeepAlive = false
@@ -2170,6 +2778,65 @@ var _ = Describe("Session", func() {
 		})
 	})
 
+	Context("application idle timeout", func() {
+		BeforeEach(func() {
+			sess.config.MaxIdleTimeout = time.Hour
+			sess.idleTimeout = time.Hour
+			sess.config.ApplicationIdleTimeout = time.Minute
+		})
+
+		It("calls the callback once when there's no stream activity", func() {
+			var called int32
+			sess.config.OnApplicationIdle = func(s Session) {
+				defer GinkgoRecover()
+				Expect(s).To(Equal(sess))
+				atomic.AddInt32(&called, 1)
+			}
+			sess.config.ApplicationIdleTimeout = 25 * time.Millisecond
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				sess.run()
+			}()
+			Eventually(func() int32 { return atomic.LoadInt32(&called) }).Should(BeEquivalentTo(1))
+			Consistently(func() int32 { return atomic.LoadInt32(&called) }, 100*time.Millisecond).Should(BeEquivalentTo(1))
+			// make the go routine return
+			expectReplaceWithClosed()
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			packer.EXPECT().PackApplicationClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			cryptoSetup.EXPECT().Close()
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
+			tracer.EXPECT().ClosedConnection(gomock.Any())
+			tracer.EXPECT().Close()
+			sess.shutdown()
+			Eventually(sess.Context().Done()).Should(BeClosed())
+		})
+
+		It("resets the idle time when stream data or a datagram is sent", func() {
+			sess.config.OnApplicationIdle = func(Session) {}
+			now := time.Now()
+			sess.applicationIdleNotified = true
+			Expect(sess.nextApplicationIdleTime()).To(BeZero())
+			sess.onSentFrames([]ackhandler.Frame{{Frame: &wire.PingFrame{}}, {Frame: &wire.MaxDataFrame{}}}, now)
+			Expect(sess.nextApplicationIdleTime()).To(BeZero())
+			sess.onSentFrames([]ackhandler.Frame{{Frame: &wire.PingFrame{}}, {Frame: &wire.StreamFrame{}}}, now)
+			Expect(sess.nextApplicationIdleTime()).To(Equal(now.Add(time.Minute)))
+			sess.onSentFrames([]ackhandler.Frame{{Frame: &wire.DatagramFrame{}}}, now.Add(time.Second))
+			Expect(sess.nextApplicationIdleTime()).To(Equal(now.Add(time.Second + time.Minute)))
+		})
+
+		It("doesn't call the callback before the handshake completes", func() {
+			sess.config.OnApplicationIdle = func(Session) {}
+			Expect(sess.nextApplicationIdleTime()).ToNot(BeZero())
+			sess.handshakeComplete = false
+			Expect(sess.nextApplicationIdleTime()).To(BeZero())
+		})
+
+		It("doesn't call the callback if none is set", func() {
+			Expect(sess.nextApplicationIdleTime()).To(BeZero())
+		})
+	})
+
 	Context("timeouts", func() {
 		BeforeEach(func() {
 			streamManager.EXPECT().CloseWithError(gomock.Any())
@@ -2253,7 +2920,7 @@ var _ = Describe("Session", func() {
 			// make the go routine return
 			expectReplaceWithClosed()
 			cryptoSetup.EXPECT().Close()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			sess.shutdown()
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
@@ -2330,7 +2997,7 @@ var _ = Describe("Session", func() {
 			packer.EXPECT().PackApplicationClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 			expectReplaceWithClosed()
 			cryptoSetup.EXPECT().Close()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			tracer.EXPECT().ClosedConnection(gomock.Any())
 			tracer.EXPECT().Close()
 			sess.shutdown()
@@ -2404,6 +3071,66 @@ var _ = Describe("Session", func() {
 		})
 	})
 
+	Context("datagrams", func() {
+		It("errors when sending datagrams if datagram support is disabled", func() {
+			sess.peerParams = &wire.TransportParameters{MaxDatagramFrameSize: protocol.MaxDatagramFrameSize}
+			Expect(sess.SendDatagram([]byte("foobar"))).To(MatchError("datagram support disabled"))
+			_, err := sess.ReceiveDatagram(context.Background())
+			Expect(err).To(MatchError("datagram support disabled"))
+		})
+
+		It("errors when sending datagrams if the peer didn't enable datagram support", func() {
+			sess.config.EnableDatagrams = true
+			sess.datagramQueue = newDatagramQueue(func() {}, 0, utils.DefaultLogger)
+			sess.peerParams = &wire.TransportParameters{MaxDatagramFrameSize: protocol.InvalidByteCount}
+			Expect(sess.SendDatagram([]byte("foobar"))).To(MatchError("datagram support disabled"))
+		})
+
+		It("errors when the datagram is too large", func() {
+			sess.config.EnableDatagrams = true
+			sess.datagramQueue = newDatagramQueue(func() {}, 0, utils.DefaultLogger)
+			sess.peerParams = &wire.TransportParameters{MaxDatagramFrameSize: 100}
+			f := &wire.DatagramFrame{DataLenPresent: true}
+			maxLen := f.MaxDataLen(100, sess.version)
+			err := sess.SendDatagram(make([]byte, maxLen+1))
+			Expect(err).To(HaveOccurred())
+			var tooLargeErr *DatagramTooLargeError
+			Expect(errors.As(err, &tooLargeErr)).To(BeTrue())
+			Expect(tooLargeErr.MaxDataLen).To(BeEquivalentTo(maxLen))
+		})
+
+		It("queues datagrams without blocking, if the send queue is bounded", func() {
+			sess.config.EnableDatagrams = true
+			sess.config.DatagramSendQueueLen = 2
+			sess.datagramQueue = newDatagramQueue(func() {}, 2, utils.DefaultLogger)
+			sess.peerParams = &wire.TransportParameters{MaxDatagramFrameSize: protocol.MaxDatagramFrameSize}
+			Expect(sess.SendDatagram([]byte("foo"))).To(Succeed())
+			Expect(sess.SendDatagram([]byte("bar"))).To(Succeed())
+			Expect(sess.SendDatagram([]byte("baz"))).To(MatchError(ErrDatagramQueueOverflow))
+			packer.EXPECT().BlockedFrameCounts()
+			stats := sess.ConnectionStats()
+			Expect(stats.DatagramSendQueueLen).To(Equal(2))
+			Expect(stats.DatagramsDropped).To(BeEquivalentTo(1))
+		})
+
+		It("receives datagrams", func() {
+			sess.config.EnableDatagrams = true
+			sess.datagramQueue = newDatagramQueue(func() {}, 0, utils.DefaultLogger)
+			sess.datagramQueue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("foobar")})
+			data, err := sess.ReceiveDatagram(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foobar")))
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err = sess.ReceiveDatagram(ctx)
+			Expect(err).To(MatchError(context.Canceled))
+		})
+	})
+
+	It("doesn't allow the server to migrate", func() {
+		Expect(sess.MigrateTo(nil)).To(MatchError("only the client can migrate a connection"))
+	})
+
 	It("returns the local address", func() {
 		Expect(sess.LocalAddr()).To(Equal(localAddr))
 	})
@@ -2454,6 +3181,7 @@ var _ = Describe("Client Session", func() {
 		mconn = NewMockSendConn(mockCtrl)
 		mconn.EXPECT().RemoteAddr().Return(&net.UDPAddr{}).AnyTimes()
 		mconn.EXPECT().LocalAddr().Return(&net.UDPAddr{}).AnyTimes()
+		mconn.EXPECT().SupportsGSO().AnyTimes()
 		if tlsConf == nil {
 			tlsConf = &tls.Config{}
 		}
@@ -2463,6 +3191,7 @@ var _ = Describe("Client Session", func() {
 		tracer.EXPECT().SentTransportParameters(gomock.Any())
 		tracer.EXPECT().UpdatedKeyFromTLS(gomock.Any(), gomock.Any()).AnyTimes()
 		tracer.EXPECT().UpdatedCongestionState(gomock.Any())
+		tracer.EXPECT().UpdatedCongestionControl("newreno", "standard")
 		sess = newClientSession(
 			mconn,
 			sessionRunner,
@@ -2512,18 +3241,76 @@ var _ = Describe("Client Session", func() {
 			PacketNumberLen: protocol.PacketNumberLen2,
 		}, []byte("foobar"))
 		tracer.EXPECT().ReceivedPacket(gomock.Any(), p.Size(), []logging.Frame{})
+		packer.EXPECT().SetSpinBit(true) // the client inverts the spin bit
 		Expect(sess.handlePacketImpl(p)).To(BeTrue())
 		// make sure the go routine returns
 		packer.EXPECT().PackApplicationClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 		expectReplaceWithClosed()
 		cryptoSetup.EXPECT().Close()
-		mconn.EXPECT().Write(gomock.Any())
+		mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 		tracer.EXPECT().ClosedConnection(gomock.Any())
 		tracer.EXPECT().Close()
 		sess.shutdown()
 		Eventually(sess.Context().Done()).Should(BeClosed())
 	})
 
+	Context("compatible version negotiation", func() {
+		BeforeEach(func() {
+			quicConf.Versions = []protocol.VersionNumber{protocol.Version1, protocol.Version2}
+		})
+
+		getV2Packet := func() *receivedPacket {
+			return getPacket(&wire.ExtendedHeader{
+				Header: wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeHandshake,
+					SrcConnectionID:  destConnID,
+					DestConnectionID: srcConnID,
+					Length:           2 + 6,
+					Version:          protocol.Version2,
+				},
+				PacketNumberLen: protocol.PacketNumberLen2,
+			}, []byte("foobar"))
+		}
+
+		It("switches to a compatible version when receiving the first packet from the server", func() {
+			Expect(sess.version).To(Equal(protocol.Version1))
+			unpacker := NewMockUnpacker(mockCtrl)
+			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(hdr *wire.Header, _ time.Time, data []byte) (*unpackedPacket, error) {
+				return &unpackedPacket{
+					encryptionLevel: protocol.EncryptionHandshake,
+					hdr:             &wire.ExtendedHeader{Header: *hdr},
+					data:            []byte{0}, // one PADDING frame
+				}, nil
+			})
+			sess.unpacker = unpacker
+			p := getV2Packet()
+			gomock.InOrder(
+				packer.EXPECT().SetVersion(protocol.Version2),
+				cryptoSetup.EXPECT().ChangeVersion(protocol.Version2),
+				tracer.EXPECT().ReceivedPacket(gomock.Any(), p.Size(), []logging.Frame{}),
+			)
+			Expect(sess.handlePacketImpl(p)).To(BeTrue())
+			Expect(sess.version).To(Equal(protocol.Version2))
+		})
+
+		It("doesn't switch versions after receiving the first packet", func() {
+			sess.receivedFirstPacket = true
+			p := getV2Packet()
+			tracer.EXPECT().DroppedPacket(logging.PacketTypeHandshake, p.Size(), logging.PacketDropUnexpectedVersion)
+			Expect(sess.handlePacketImpl(p)).To(BeFalse())
+			Expect(sess.version).To(Equal(protocol.Version1))
+		})
+
+		It("doesn't switch to a version it didn't offer", func() {
+			sess.config.Versions = []protocol.VersionNumber{protocol.Version1}
+			p := getV2Packet()
+			tracer.EXPECT().DroppedPacket(logging.PacketTypeHandshake, p.Size(), logging.PacketDropUnexpectedVersion)
+			Expect(sess.handlePacketImpl(p)).To(BeFalse())
+			Expect(sess.version).To(Equal(protocol.Version1))
+		})
+	})
+
 	It("continues accepting Long Header packets after using a new connection ID", func() {
 		unpacker := NewMockUnpacker(mockCtrl)
 		sess.unpacker = unpacker
@@ -2558,7 +3345,47 @@ var _ = Describe("Client Session", func() {
 		sess.sentPacketHandler = sph
 		sph.EXPECT().SetHandshakeConfirmed()
 		cryptoSetup.EXPECT().SetHandshakeConfirmed()
+		Expect(sess.HandshakeConfirmed().Done()).ToNot(BeClosed())
+		Expect(sess.handleHandshakeDoneFrame()).To(Succeed())
+		Expect(sess.HandshakeConfirmed().Done()).To(BeClosed())
+	})
+
+	It("limits Path MTU Discovery to the configured maximum probe size, and traces MTU updates", func() {
+		sess.config.MaxPathMTUProbeSize = 1300
+		sess.peerParams = &wire.TransportParameters{MaxUDPPayloadSize: 1500}
+		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+		sess.sentPacketHandler = sph
+		sph.EXPECT().SetHandshakeConfirmed()
+		cryptoSetup.EXPECT().SetHandshakeConfirmed()
+		Expect(sess.handleHandshakeDoneFrame()).To(Succeed())
+		Expect(sess.mtuDiscoverer).ToNot(BeNil())
+		Expect(sess.mtuDiscoverer.(*mtuFinder).max).To(Equal(protocol.ByteCount(1300)))
+
+		ping, size := sess.mtuDiscoverer.GetPing()
+		Expect(size).To(BeNumerically("<=", 1300))
+		sph.EXPECT().SetMaxDatagramSize(size)
+		packer.EXPECT().SetMaxPacketSize(size)
+		tracer.EXPECT().UpdatedMTU(size)
+		ping.OnAcked(ping.Frame)
+	})
+
+	It("probes for jumbo datagrams, if configured", func() {
+		sess.config.MaxPathMTUProbeSize = 8952
+		sess.peerParams = &wire.TransportParameters{MaxUDPPayloadSize: 9000}
+		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+		sess.sentPacketHandler = sph
+		sph.EXPECT().SetHandshakeConfirmed()
+		cryptoSetup.EXPECT().SetHandshakeConfirmed()
 		Expect(sess.handleHandshakeDoneFrame()).To(Succeed())
+		Expect(sess.mtuDiscoverer).ToNot(BeNil())
+		Expect(sess.mtuDiscoverer.(*mtuFinder).max).To(Equal(protocol.MaxLargePacketBufferSize))
+
+		ping, size := sess.mtuDiscoverer.GetPing()
+		Expect(size).To(BeNumerically(">", protocol.MaxPacketBufferSize))
+		sph.EXPECT().SetMaxDatagramSize(size)
+		packer.EXPECT().SetMaxPacketSize(size)
+		tracer.EXPECT().UpdatedMTU(size)
+		ping.OnAcked(ping.Frame)
 	})
 
 	It("interprets an ACK for 1-RTT packets as confirmation of the handshake", func() {
@@ -2590,6 +3417,71 @@ var _ = Describe("Client Session", func() {
 		})
 	})
 
+	It("inverts the spin bit", func() {
+		unpacker := NewMockUnpacker(mockCtrl)
+		sess.unpacker = unpacker
+		now := time.Now()
+		gomock.InOrder(
+			packer.EXPECT().SetSpinBit(true),
+			packer.EXPECT().SetSpinBit(false),
+			tracer.EXPECT().UpdatedSpinBitRTT(30*time.Millisecond),
+			packer.EXPECT().SetSpinBit(true),
+		)
+		for i, spin := range []bool{false, true, false} {
+			pn := protocol.PacketNumber(i + 1)
+			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(&unpackedPacket{
+				packetNumber:    pn,
+				encryptionLevel: protocol.Encryption1RTT,
+				hdr:             &wire.ExtendedHeader{PacketNumber: pn, SpinBit: spin},
+				data:            []byte{0}, // one PADDING frame
+			}, nil)
+			p := getPacket(&wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: srcConnID},
+				PacketNumber:    pn,
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}, nil)
+			p.rcvTime = now.Add(time.Duration(i) * 30 * time.Millisecond)
+			tracer.EXPECT().ReceivedPacket(gomock.Any(), gomock.Any(), gomock.Any())
+			Expect(sess.handlePacketImpl(p)).To(BeTrue())
+		}
+	})
+
+	Context("migrating", func() {
+		It("refuses to migrate before the handshake is confirmed", func() {
+			Expect(sess.startMigration(migrationRequest{})).To(MatchError("can't migrate before the handshake is confirmed"))
+		})
+
+		It("refuses to migrate if the server disabled active migration", func() {
+			sess.handshakeConfirmed = true
+			sess.peerParams = &wire.TransportParameters{DisableActiveMigration: true}
+			Expect(sess.startMigration(migrationRequest{})).To(MatchError("the peer disabled active connection migration"))
+		})
+
+		It("switches to the new path when path validation succeeds", func() {
+			tracer.EXPECT().UpdatedCongestionState(gomock.Any()).AnyTimes()
+			tracer.EXPECT().UpdatedCongestionControl("newreno", "standard") // the congestion controller is reset
+			newRunner := NewMockSessionRunner(mockCtrl)
+			sess.runner.add(newRunner)
+			sessionRunner.EXPECT().Remove(gomock.Any()).AnyTimes()
+			newConn := NewMockSendConn(mockCtrl)
+			newConn.EXPECT().LocalAddr().Return(&net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1234}).AnyTimes()
+			newConn.EXPECT().RemoteAddr().Return(&net.UDPAddr{}).AnyTimes()
+			newConn.EXPECT().SupportsGSO().AnyTimes()
+			result := make(chan error, 1)
+			sess.pathValidation = &pathValidation{
+				conn:       newConn,
+				challenges: [][8]byte{{1, 2, 3, 4, 5, 6, 7, 8}},
+				runner:     newRunner,
+				result:     result,
+			}
+			Expect(sess.handleFrame(&wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			Expect(result).To(Receive(BeNil()))
+			Expect(sess.conn.Path()).To(Equal(newConn))
+			Expect(sess.runner.runners).To(HaveLen(1))
+			Expect(sess.runner.runners[0]).To(Equal(newRunner))
+		})
+	})
+
 	Context("handling Version Negotiation", func() {
 		getVNP := func(versions ...protocol.VersionNumber) *receivedPacket {
 			b, err := wire.ComposeVersionNegotiation(srcConnID, destConnID, versions)
@@ -2762,7 +3654,7 @@ var _ = Describe("Client Session", func() {
 					packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil).MaxTimes(1)
 				}
 				cryptoSetup.EXPECT().Close()
-				mconn.EXPECT().Write(gomock.Any())
+				mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 				gomock.InOrder(
 					tracer.EXPECT().ClosedConnection(gomock.Any()),
 					tracer.EXPECT().Close(),
@@ -2898,6 +3790,53 @@ var _ = Describe("Client Session", func() {
 				ErrorMessage: "expected original_destination_connection_id to equal deadbeef, is decafbad",
 			})))
 		})
+
+		It("errors if the server chose a different version", func() {
+			params := &wire.TransportParameters{
+				OriginalDestinationConnectionID: destConnID,
+				InitialSourceConnectionID:       destConnID,
+				StatelessResetToken:             &protocol.StatelessResetToken{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+				VersionInformation: &wire.VersionInformation{
+					ChosenVersion:     protocol.Version2,
+					AvailableVersions: []protocol.VersionNumber{protocol.Version2, protocol.Version1},
+				},
+			}
+			expectClose(false)
+			tracer.EXPECT().ReceivedTransportParameters(params)
+			sess.handleTransportParameters(params)
+			Eventually(errChan).Should(Receive(MatchError(&qerr.TransportError{
+				ErrorCode:    qerr.VersionNegotiationErrorErrorCode,
+				ErrorMessage: "expected chosen version to equal v1, is v2",
+			})))
+		})
+
+		It("errors if the server switched versions without sending version_information", func() {
+			sess.versionUpgraded = true
+			params := &wire.TransportParameters{
+				OriginalDestinationConnectionID: destConnID,
+				InitialSourceConnectionID:       destConnID,
+				StatelessResetToken:             &protocol.StatelessResetToken{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+			}
+			expectClose(false)
+			tracer.EXPECT().ReceivedTransportParameters(params)
+			sess.handleTransportParameters(params)
+			Eventually(errChan).Should(Receive(MatchError(&qerr.TransportError{
+				ErrorCode:    qerr.VersionNegotiationErrorErrorCode,
+				ErrorMessage: "server switched versions without sending version_information",
+			})))
+		})
+
+		It("traces the transport parameters restored for 0-RTT", func() {
+			params := &wire.TransportParameters{
+				InitialMaxData:          0x1337,
+				MaxIdleTimeout:          42 * time.Second,
+				ActiveConnectionIDLimit: 1,
+			}
+			tracer.EXPECT().RestoredTransportParameters(params)
+			sess.restoreTransportParameters(params)
+			Expect(sess.peerParams).To(Equal(params))
+			expectClose(true)
+		})
 	})
 
 	Context("handling potentially injected packets", func() {
@@ -3022,3 +3961,19 @@ var _ = Describe("Client Session", func() {
 		})
 	})
 })
+
+var _ = Describe("Session Runners", func() {
+	It("removes retired connection IDs when removing a runner", func() {
+		runner1 := NewMockSessionRunner(mockCtrl)
+		runner2 := NewMockSessionRunner(mockCtrl)
+		r := &sessionRunners{runners: []sessionRunner{runner1, runner2}}
+		connID := protocol.ConnectionID{1, 2, 3, 4}
+		runner1.EXPECT().Retire(connID)
+		runner2.EXPECT().Retire(connID)
+		r.Retire(connID)
+		runner1.EXPECT().Remove(connID)
+		r.remove(runner1)
+		Expect(r.runners).To(HaveLen(1))
+		Expect(r.runners[0]).To(Equal(runner2))
+	})
+})
@@ -27,6 +27,8 @@ type streamSender interface {
 	onHasStreamData(protocol.StreamID)
 	// must be called without holding the mutex that is acquired by closeForShutdown
 	onStreamCompleted(protocol.StreamID)
+	// must be called without holding the mutex that is acquired by popStreamFrame
+	onStreamPriorityChanged(id protocol.StreamID, weight int, incremental bool)
 }
 
 // Each of the both stream halves gets its own uniStreamSender.
@@ -62,6 +64,8 @@ type streamI interface {
 	handleStopSendingFrame(*wire.StopSendingFrame)
 	popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Frame, bool)
 	updateSendWindow(protocol.ByteCount)
+	// for statistics
+	stats() StreamStats
 }
 
 var (
@@ -140,6 +144,14 @@ func (s *stream) closeForShutdown(err error) {
 	s.receiveStream.closeForShutdown(err)
 }
 
+func (s *stream) stats() StreamStats {
+	stats := s.sendStream.stats()
+	rcvStats := s.receiveStream.stats()
+	stats.BytesReceived = rcvStats.BytesReceived
+	stats.ReceiveWindow = rcvStats.ReceiveWindow
+	return stats
+}
+
 // checkIfCompleted is called from the uniStreamSender, when one of the stream halves is completed.
 // It makes sure that the onStreamCompleted callback is only called if both receive and send side have completed.
 func (s *stream) checkIfCompleted() {
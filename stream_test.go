@@ -77,6 +77,17 @@ var _ = Describe("Stream", func() {
 		})
 	})
 
+	It("returns the stats for both directions", func() {
+		mockFC.EXPECT().SendWindow().Return(protocol.ByteCount(2000))
+		mockFC.EXPECT().ReceiveWindow().Return(protocol.ByteCount(100), protocol.ByteCount(1000))
+		Expect(str.stats()).To(Equal(StreamStats{
+			StreamID:      streamID,
+			SendWindow:    2000,
+			BytesReceived: 100,
+			ReceiveWindow: 1000,
+		}))
+	})
+
 	Context("completing", func() {
 		It("is not completed when only the receive side is completed", func() {
 			// don't EXPECT a call to mockSender.onStreamCompleted()
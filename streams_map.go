@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sort"
 	"sync"
 
 	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
@@ -16,6 +17,8 @@ import (
 type streamError struct {
 	message string
 	nums    []protocol.StreamNum
+	// If set, the error is converted to a transport error with this error code.
+	code qerr.TransportErrorCode
 }
 
 func (e streamError) Error() string {
@@ -31,6 +34,12 @@ func convertStreamError(err error, stype protocol.StreamType, pers protocol.Pers
 	for i, num := range strError.nums {
 		ids[i] = num.StreamID(stype, pers)
 	}
+	if strError.code != 0 {
+		return &qerr.TransportError{
+			ErrorCode:    strError.code,
+			ErrorMessage: fmt.Sprintf(strError.Error(), ids...),
+		}
+	}
 	return fmt.Errorf(strError.Error(), ids...)
 }
 
@@ -50,6 +59,7 @@ type streamsMap struct {
 
 	maxIncomingBidiStreams uint64
 	maxIncomingUniStreams  uint64
+	maxStreamsIncrement    int64 // see Config.MaxStreamsIncrement
 
 	sender            streamSender
 	newFlowController func(protocol.StreamID) flowcontrol.StreamFlowController
@@ -69,6 +79,7 @@ func newStreamsMap(
 	newFlowController func(protocol.StreamID) flowcontrol.StreamFlowController,
 	maxIncomingBidiStreams uint64,
 	maxIncomingUniStreams uint64,
+	maxStreamsIncrement int64,
 	perspective protocol.Perspective,
 	version protocol.VersionNumber,
 ) streamManager {
@@ -77,6 +88,7 @@ func newStreamsMap(
 		newFlowController:      newFlowController,
 		maxIncomingBidiStreams: maxIncomingBidiStreams,
 		maxIncomingUniStreams:  maxIncomingUniStreams,
+		maxStreamsIncrement:    maxStreamsIncrement,
 		sender:                 sender,
 		version:                version,
 	}
@@ -98,6 +110,7 @@ func (m *streamsMap) initMaps() {
 			return newStream(id, m.sender, m.newFlowController(id), m.version)
 		},
 		m.maxIncomingBidiStreams,
+		m.streamCreditIncrement(m.maxIncomingBidiStreams),
 		m.sender.queueControlFrame,
 	)
 	m.outgoingUniStreams = newOutgoingUniStreamsMap(
@@ -113,10 +126,20 @@ func (m *streamsMap) initMaps() {
 			return newReceiveStream(id, m.sender, m.newFlowController(id), m.version)
 		},
 		m.maxIncomingUniStreams,
+		m.streamCreditIncrement(m.maxIncomingUniStreams),
 		m.sender.queueControlFrame,
 	)
 }
 
+// streamCreditIncrement returns the number of streams that need to be closed
+// before new stream credit is granted to the peer.
+func (m *streamsMap) streamCreditIncrement(maxStreams uint64) uint64 {
+	if m.maxStreamsIncrement < 0 {
+		return maxStreams / 2
+	}
+	return uint64(m.maxStreamsIncrement)
+}
+
 func (m *streamsMap) OpenStream() (Stream, error) {
 	m.mutex.Lock()
 	reset := m.reset
@@ -209,6 +232,9 @@ func (m *streamsMap) DeleteStream(id protocol.StreamID) error {
 func (m *streamsMap) GetOrOpenReceiveStream(id protocol.StreamID) (receiveStreamI, error) {
 	str, err := m.getOrOpenReceiveStream(id)
 	if err != nil {
+		if _, ok := err.(*qerr.TransportError); ok {
+			return nil, err
+		}
 		return nil, &qerr.TransportError{
 			ErrorCode:    qerr.StreamStateError,
 			ErrorMessage: err.Error(),
@@ -226,7 +252,7 @@ func (m *streamsMap) getOrOpenReceiveStream(id protocol.StreamID) (receiveStream
 			return nil, fmt.Errorf("peer attempted to open receive stream %d", id)
 		}
 		str, err := m.incomingUniStreams.GetOrOpenStream(num)
-		return str, convertStreamError(err, protocol.StreamTypeUni, m.perspective)
+		return str, convertStreamError(err, protocol.StreamTypeUni, id.InitiatedBy())
 	case protocol.StreamTypeBidi:
 		var str receiveStreamI
 		var err error
@@ -243,6 +269,9 @@ func (m *streamsMap) getOrOpenReceiveStream(id protocol.StreamID) (receiveStream
 func (m *streamsMap) GetOrOpenSendStream(id protocol.StreamID) (sendStreamI, error) {
 	str, err := m.getOrOpenSendStream(id)
 	if err != nil {
+		if _, ok := err.(*qerr.TransportError); ok {
+			return nil, err
+		}
 		return nil, &qerr.TransportError{
 			ErrorCode:    qerr.StreamStateError,
 			ErrorMessage: err.Error(),
@@ -290,6 +319,16 @@ func (m *streamsMap) UpdateLimits(p *wire.TransportParameters) {
 	m.outgoingUniStreams.SetMaxStream(p.MaxUniStreamNum)
 }
 
+// Stats returns the statistics of all open streams, sorted by stream ID.
+func (m *streamsMap) Stats() []StreamStats {
+	stats := m.outgoingBidiStreams.Stats()
+	stats = append(stats, m.outgoingUniStreams.Stats()...)
+	stats = append(stats, m.incomingBidiStreams.Stats()...)
+	stats = append(stats, m.incomingUniStreams.Stats()...)
+	sort.Slice(stats, func(i, j int) bool { return stats[i].StreamID < stats[j].StreamID })
+	return stats
+}
+
 func (m *streamsMap) CloseWithError(err error) {
 	m.outgoingBidiStreams.CloseWithError(err)
 	m.outgoingUniStreams.CloseWithError(err)
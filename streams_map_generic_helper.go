@@ -6,13 +6,14 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
-// In the auto-generated streams maps, we need to be able to close the streams.
-// Therefore, extend the generic.Type with the stream close method.
+// In the auto-generated streams maps, we need to be able to close the streams, and to get their statistics.
+// Therefore, extend the generic.Type with these methods.
 // This definition must be in a file that Genny doesn't process.
 type item interface {
 	generic.Type
 	updateSendWindow(protocol.ByteCount)
 	closeForShutdown(error)
+	stats() StreamStats
 }
 
 const streamTypeGeneric protocol.StreamType = protocol.StreamTypeUni
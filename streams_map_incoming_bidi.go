@@ -9,6 +9,7 @@ import (
 	"sync"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
@@ -29,6 +30,8 @@ type incomingBidiStreamsMap struct {
 	nextStreamToOpen   protocol.StreamNum // the highest stream that the peer opened
 	maxStream          protocol.StreamNum // the highest stream that the peer is allowed to open
 	maxNumStreams      uint64             // maximum number of streams
+	// A MAX_STREAMS frame is only sent once the peer can open at least this many new streams.
+	maxStreamsIncrement uint64
 
 	newStream        func(protocol.StreamNum) streamI
 	queueMaxStreamID func(*wire.MaxStreamsFrame)
@@ -39,17 +42,26 @@ type incomingBidiStreamsMap struct {
 func newIncomingBidiStreamsMap(
 	newStream func(protocol.StreamNum) streamI,
 	maxStreams uint64,
+	maxStreamsIncrement uint64,
 	queueControlFrame func(wire.Frame),
 ) *incomingBidiStreamsMap {
+	if maxStreamsIncrement == 0 {
+		maxStreamsIncrement = 1
+	}
+	// Make sure that the peer receives new credit once all its streams are closed.
+	if maxStreamsIncrement > maxStreams {
+		maxStreamsIncrement = maxStreams
+	}
 	return &incomingBidiStreamsMap{
-		newStreamChan:      make(chan struct{}, 1),
-		streams:            make(map[protocol.StreamNum]streamIEntry),
-		maxStream:          protocol.StreamNum(maxStreams),
-		maxNumStreams:      maxStreams,
-		newStream:          newStream,
-		nextStreamToOpen:   1,
-		nextStreamToAccept: 1,
-		queueMaxStreamID:   func(f *wire.MaxStreamsFrame) { queueControlFrame(f) },
+		newStreamChan:       make(chan struct{}, 1),
+		streams:             make(map[protocol.StreamNum]streamIEntry),
+		maxStream:           protocol.StreamNum(maxStreams),
+		maxNumStreams:       maxStreams,
+		maxStreamsIncrement: maxStreamsIncrement,
+		newStream:           newStream,
+		nextStreamToOpen:    1,
+		nextStreamToAccept:  1,
+		queueMaxStreamID:    func(f *wire.MaxStreamsFrame) { queueControlFrame(f) },
 	}
 }
 
@@ -102,6 +114,7 @@ func (m *incomingBidiStreamsMap) GetOrOpenStream(num protocol.StreamNum) (stream
 		return nil, streamError{
 			message: "peer tried to open stream %d (current limit: %d)",
 			nums:    []protocol.StreamNum{num, m.maxStream},
+			code:    qerr.StreamLimitError,
 		}
 	}
 	// if the num is smaller than the highest we accepted
@@ -170,7 +183,8 @@ func (m *incomingBidiStreamsMap) deleteStream(num protocol.StreamNum) error {
 	if m.maxNumStreams > uint64(len(m.streams)) {
 		maxStream := m.nextStreamToOpen + protocol.StreamNum(m.maxNumStreams-uint64(len(m.streams))) - 1
 		// Never send a value larger than protocol.MaxStreamCount.
-		if maxStream <= protocol.MaxStreamCount {
+		// Wait until enough streams were closed to grant the configured increment.
+		if maxStream <= protocol.MaxStreamCount && uint64(maxStream-m.maxStream) >= m.maxStreamsIncrement {
 			m.maxStream = maxStream
 			m.queueMaxStreamID(&wire.MaxStreamsFrame{
 				Type:         protocol.StreamTypeBidi,
@@ -181,6 +195,20 @@ func (m *incomingBidiStreamsMap) deleteStream(num protocol.StreamNum) error {
 	return nil
 }
 
+// Stats returns the statistics of all streams in the map.
+func (m *incomingBidiStreamsMap) Stats() []StreamStats {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	stats := make([]StreamStats, 0, len(m.streams))
+	for _, entry := range m.streams {
+		if entry.shouldDelete {
+			continue
+		}
+		stats = append(stats, entry.stream.stats())
+	}
+	return stats
+}
+
 func (m *incomingBidiStreamsMap) CloseWithError(err error) {
 	m.mutex.Lock()
 	m.closeErr = err
@@ -32,24 +32,32 @@ func (s *mockGenericStream) updateSendWindow(limit protocol.ByteCount) {
 	s.sendWindow = limit
 }
 
+func (s *mockGenericStream) stats() StreamStats {
+	return StreamStats{StreamID: protocol.StreamID(s.num), SendWindow: s.sendWindow}
+}
+
 var _ = Describe("Streams Map (incoming)", func() {
 	var (
-		m              *incomingItemsMap
-		newItemCounter int
-		mockSender     *MockStreamSender
-		maxNumStreams  uint64
+		m                   *incomingItemsMap
+		newItemCounter      int
+		mockSender          *MockStreamSender
+		maxNumStreams       uint64
+		maxStreamsIncrement uint64
 	)
 
 	// check that the frame can be serialized and deserialized
 	checkFrameSerialization := func(f wire.Frame) {
 		b := &bytes.Buffer{}
 		ExpectWithOffset(1, f.Write(b, protocol.VersionTLS)).To(Succeed())
-		frame, err := wire.NewFrameParser(false, protocol.VersionTLS).ParseNext(bytes.NewReader(b.Bytes()), protocol.Encryption1RTT)
+		frame, err := wire.NewFrameParser(false, false, protocol.VersionTLS).ParseNext(bytes.NewReader(b.Bytes()), protocol.Encryption1RTT)
 		ExpectWithOffset(1, err).ToNot(HaveOccurred())
 		Expect(f).To(Equal(frame))
 	}
 
-	BeforeEach(func() { maxNumStreams = 5 })
+	BeforeEach(func() {
+		maxNumStreams = 5
+		maxStreamsIncrement = 0
+	})
 
 	JustBeforeEach(func() {
 		newItemCounter = 0
@@ -60,6 +68,7 @@ var _ = Describe("Streams Map (incoming)", func() {
 				return &mockGenericStream{num: num}
 			},
 			maxNumStreams,
+			maxStreamsIncrement,
 			mockSender.queueControlFrame,
 		)
 	})
@@ -134,6 +143,47 @@ var _ = Describe("Streams Map (incoming)", func() {
 		Eventually(done).Should(BeClosed())
 	})
 
+	It("doesn't consume a stream when AcceptStream is canceled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			_, err := m.AcceptStream(ctx)
+			Expect(err).To(MatchError(context.Canceled))
+			close(done)
+		}()
+		Consistently(done).ShouldNot(BeClosed())
+		cancel()
+		Eventually(done).Should(BeClosed())
+		_, err := m.GetOrOpenStream(1)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := m.AcceptStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.(*mockGenericStream).num).To(Equal(protocol.StreamNum(1)))
+	})
+
+	It("unblocks multiple AcceptStream calls when multiple streams are opened at once", func() {
+		strChan := make(chan protocol.StreamNum, 2)
+		for i := 0; i < 2; i++ {
+			go func() {
+				defer GinkgoRecover()
+				str, err := m.AcceptStream(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				strChan <- str.(*mockGenericStream).num
+			}()
+		}
+		Consistently(strChan).ShouldNot(Receive())
+		_, err := m.GetOrOpenStream(2)
+		Expect(err).ToNot(HaveOccurred())
+		var nums []protocol.StreamNum
+		for i := 0; i < 2; i++ {
+			var num protocol.StreamNum
+			Eventually(strChan).Should(Receive(&num))
+			nums = append(nums, num)
+		}
+		Expect(nums).To(ConsistOf(protocol.StreamNum(1), protocol.StreamNum(2)))
+	})
+
 	It("unblocks AcceptStream when it is closed", func() {
 		testErr := errors.New("test error")
 		done := make(chan struct{})
@@ -210,6 +260,15 @@ var _ = Describe("Streams Map (incoming)", func() {
 		Expect(str).ToNot(BeNil())
 	})
 
+	It("returns the stats of all streams, except for the streams queued for deleting", func() {
+		_, err := m.GetOrOpenStream(3)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.DeleteStream(2)).To(Succeed())
+		stats := m.Stats()
+		Expect(stats).To(HaveLen(2))
+		Expect([]protocol.StreamID{stats[0].StreamID, stats[1].StreamID}).To(ConsistOf(protocol.StreamID(1), protocol.StreamID(3)))
+	})
+
 	It("errors when deleting a non-existing stream", func() {
 		err := m.DeleteStream(1337)
 		Expect(err).To(HaveOccurred())
@@ -237,6 +296,69 @@ var _ = Describe("Streams Map (incoming)", func() {
 		Expect(m.DeleteStream(4)).To(Succeed())
 	})
 
+	Context("granting stream credit in increments", func() {
+		BeforeEach(func() {
+			maxNumStreams = 10
+			maxStreamsIncrement = 5
+		})
+
+		It("waits until enough streams were closed", func() {
+			_, err := m.GetOrOpenStream(10)
+			Expect(err).ToNot(HaveOccurred())
+			for i := 0; i < 10; i++ {
+				_, err := m.AcceptStream(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+			}
+			for i := 1; i < 5; i++ {
+				Expect(m.DeleteStream(protocol.StreamNum(i))).To(Succeed())
+			}
+			mockSender.EXPECT().queueControlFrame(gomock.Any()).Do(func(f wire.Frame) {
+				Expect(f.(*wire.MaxStreamsFrame).MaxStreamNum).To(Equal(protocol.StreamNum(15)))
+				checkFrameSerialization(f)
+			})
+			Expect(m.DeleteStream(5)).To(Succeed())
+		})
+
+		It("never waits for more streams than the stream limit", func() {
+			maxStreamsIncrement = 100
+			m = newIncomingItemsMap(
+				func(num protocol.StreamNum) item { return &mockGenericStream{num: num} },
+				maxNumStreams,
+				maxStreamsIncrement,
+				mockSender.queueControlFrame,
+			)
+			_, err := m.GetOrOpenStream(10)
+			Expect(err).ToNot(HaveOccurred())
+			for i := 0; i < 10; i++ {
+				_, err := m.AcceptStream(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+			}
+			for i := 1; i < 10; i++ {
+				Expect(m.DeleteStream(protocol.StreamNum(i))).To(Succeed())
+			}
+			mockSender.EXPECT().queueControlFrame(&wire.MaxStreamsFrame{Type: streamTypeGeneric, MaxStreamNum: 20})
+			Expect(m.DeleteStream(10)).To(Succeed())
+		})
+
+		It("keeps granting credit to a peer that opens and closes streams in a loop", func() {
+			var frames []*wire.MaxStreamsFrame
+			mockSender.EXPECT().queueControlFrame(gomock.Any()).Do(func(f wire.Frame) {
+				frames = append(frames, f.(*wire.MaxStreamsFrame))
+			}).AnyTimes()
+			for num := protocol.StreamNum(1); num <= 1000; num++ {
+				// the peer is never blocked for more than the increment
+				Expect(num).To(BeNumerically("<=", m.maxStream))
+				_, err := m.GetOrOpenStream(num)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = m.AcceptStream(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(m.DeleteStream(num)).To(Succeed())
+			}
+			Expect(frames).To(HaveLen(200))
+			Expect(frames[len(frames)-1].MaxStreamNum).To(Equal(protocol.StreamNum(1000 + maxNumStreams)))
+		})
+	})
+
 	Context("using high stream limits", func() {
 		BeforeEach(func() { maxNumStreams = uint64(protocol.MaxStreamCount) - 2 })
 
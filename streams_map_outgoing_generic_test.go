@@ -122,6 +122,19 @@ var _ = Describe("Streams Map (outgoing)", func() {
 			Expect(str1.(*mockGenericStream).sendWindow).To(BeEquivalentTo(1337))
 			Expect(str2.(*mockGenericStream).sendWindow).To(BeEquivalentTo(1337))
 		})
+
+		It("returns the stats of all streams", func() {
+			_, err := m.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = m.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = m.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.DeleteStream(2)).To(Succeed())
+			stats := m.Stats()
+			Expect(stats).To(HaveLen(2))
+			Expect([]protocol.StreamID{stats[0].StreamID, stats[1].StreamID}).To(ConsistOf(protocol.StreamID(1), protocol.StreamID(3)))
+		})
 	})
 
 	Context("with stream ID limits", func() {
@@ -244,6 +257,39 @@ var _ = Describe("Streams Map (outgoing)", func() {
 			Eventually(done3).Should(BeClosed())
 		})
 
+		It("unblocks the next OpenStreamSync call when a call that was just unblocked is canceled", func() {
+			mockSender.EXPECT().queueControlFrame(gomock.Any()).AnyTimes()
+			ctx, cancel := context.WithCancel(context.Background())
+			done1 := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				_, err := m.OpenStreamSync(ctx)
+				Expect(err).To(MatchError(context.Canceled))
+				close(done1)
+			}()
+			waitForEnqueued(1)
+			done2 := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				str, err := m.OpenStreamSync(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.(*mockGenericStream).num).To(Equal(protocol.StreamNum(1)))
+				close(done2)
+			}()
+			waitForEnqueued(2)
+
+			// Cancel the context of the first call, and unblock it before it gets a chance to dequeue itself.
+			m.mutex.Lock()
+			cancel()
+			time.Sleep(scaleDuration(5 * time.Millisecond)) // wait for the first call to wake up
+			m.maxStream = 1
+			m.unblockOpenSync()
+			m.mutex.Unlock()
+			Eventually(done1).Should(BeClosed())
+			// the stream credit is passed on to the second call
+			Eventually(done2).Should(BeClosed())
+		})
+
 		It("unblocks multiple OpenStreamSync calls at the same time", func() {
 			mockSender.EXPECT().queueControlFrame(gomock.Any()).AnyTimes()
 			done := make(chan struct{})
@@ -92,6 +92,11 @@ func (m *outgoingUniStreamsMap) OpenStreamSync(ctx context.Context) (sendStreamI
 		case <-ctx.Done():
 			m.mutex.Lock()
 			delete(m.openQueue, queuePos)
+			// We might have been unblocked at the same time as the context was canceled.
+			// Pass the stream credit on to the next OpenStreamSync call.
+			if m.closeErr == nil && m.nextStream <= m.maxStream {
+				m.unblockOpenSync()
+			}
 			return nil, ctx.Err()
 		case <-waitChan:
 		}
@@ -211,6 +216,17 @@ func (m *outgoingUniStreamsMap) unblockOpenSync() {
 	}
 }
 
+// Stats returns the statistics of all streams in the map.
+func (m *outgoingUniStreamsMap) Stats() []StreamStats {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	stats := make([]StreamStats, 0, len(m.streams))
+	for _, str := range m.streams {
+		stats = append(stats, str.stats())
+	}
+	return stats
+}
+
 func (m *outgoingUniStreamsMap) CloseWithError(err error) {
 	m.mutex.Lock()
 	m.closeErr = err
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sort"
 
 	"github.com/golang/mock/gomock"
 
@@ -60,6 +61,12 @@ var _ = Describe("Streams Map", func() {
 		firstOutgoingUniStream:  2,
 	}
 
+	It("grants new stream credit once half of the stream limit was consumed, in auto mode", func() {
+		m := newStreamsMap(NewMockStreamSender(mockCtrl), newFlowController, 100, 10, -1, protocol.PerspectiveServer, protocol.VersionWhatever).(*streamsMap)
+		Expect(m.incomingBidiStreams.maxStreamsIncrement).To(BeEquivalentTo(50))
+		Expect(m.incomingUniStreams.maxStreamsIncrement).To(BeEquivalentTo(5))
+	})
+
 	for _, p := range []protocol.Perspective{protocol.PerspectiveServer, protocol.PerspectiveClient} {
 		perspective := p
 		var ids streamMapping
@@ -89,7 +96,7 @@ var _ = Describe("Streams Map", func() {
 
 			BeforeEach(func() {
 				mockSender = NewMockStreamSender(mockCtrl)
-				m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, perspective, protocol.VersionWhatever).(*streamsMap)
+				m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, 0, perspective, protocol.VersionWhatever).(*streamsMap)
 			})
 
 			Context("opening", func() {
@@ -335,6 +342,31 @@ var _ = Describe("Streams Map", func() {
 						}))
 					})
 				})
+
+				Context("stream limits", func() {
+					It("errors when the peer exceeds the limit for bidirectional streams", func() {
+						id := ids.firstIncomingBidiStream + 4*MaxBidiStreamNum
+						_, err := m.GetOrOpenSendStream(id)
+						Expect(err).To(MatchError(&qerr.TransportError{
+							ErrorCode:    qerr.StreamLimitError,
+							ErrorMessage: fmt.Sprintf("peer tried to open stream %d (current limit: %d)", id, id-4),
+						}))
+						_, err = m.GetOrOpenReceiveStream(id)
+						Expect(err).To(MatchError(&qerr.TransportError{
+							ErrorCode:    qerr.StreamLimitError,
+							ErrorMessage: fmt.Sprintf("peer tried to open stream %d (current limit: %d)", id, id-4),
+						}))
+					})
+
+					It("errors when the peer exceeds the limit for unidirectional streams", func() {
+						id := ids.firstIncomingUniStream + 4*MaxUniStreamNum
+						_, err := m.GetOrOpenReceiveStream(id)
+						Expect(err).To(MatchError(&qerr.TransportError{
+							ErrorCode:    qerr.StreamLimitError,
+							ErrorMessage: fmt.Sprintf("peer tried to open stream %d (current limit: %d)", id, id-4),
+						}))
+					})
+				})
 			})
 
 			It("processes the parameter for outgoing streams", func() {
@@ -457,6 +489,46 @@ var _ = Describe("Streams Map", func() {
 				})
 			})
 
+			It("returns the stats of all streams, sorted by stream ID", func() {
+				m = newStreamsMap(
+					mockSender,
+					func(protocol.StreamID) flowcontrol.StreamFlowController {
+						fc := mocks.NewMockStreamFlowController(mockCtrl)
+						fc.EXPECT().SendWindow().AnyTimes()
+						fc.EXPECT().ReceiveWindow().AnyTimes()
+						return fc
+					},
+					MaxBidiStreamNum,
+					MaxUniStreamNum,
+					0,
+					perspective,
+					protocol.VersionWhatever,
+				).(*streamsMap)
+				allowUnlimitedStreams()
+				_, err := m.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = m.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = m.GetOrOpenReceiveStream(ids.firstIncomingBidiStream + 4) // opens 2 streams
+				Expect(err).ToNot(HaveOccurred())
+				_, err = m.GetOrOpenReceiveStream(ids.firstIncomingUniStream)
+				Expect(err).ToNot(HaveOccurred())
+				stats := m.Stats()
+				Expect(stats).To(HaveLen(5))
+				streamIDs := make([]protocol.StreamID, 0, len(stats))
+				for _, s := range stats {
+					streamIDs = append(streamIDs, s.StreamID)
+				}
+				Expect(streamIDs).To(ConsistOf(
+					ids.firstOutgoingBidiStream,
+					ids.firstOutgoingUniStream,
+					ids.firstIncomingBidiStream,
+					ids.firstIncomingBidiStream+4,
+					ids.firstIncomingUniStream,
+				))
+				Expect(sort.SliceIsSorted(streamIDs, func(i, j int) bool { return streamIDs[i] < streamIDs[j] })).To(BeTrue())
+			})
+
 			It("closes", func() {
 				testErr := errors.New("test error")
 				m.CloseWithError(testErr)
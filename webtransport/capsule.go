@@ -0,0 +1,132 @@
+package webtransport
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+type capsuleType uint64
+
+// CLOSE_WEBTRANSPORT_SESSION, see section 5 of draft-ietf-webtrans-http3
+const closeSessionCapsuleType capsuleType = 0x2843
+
+// the maximum length of the error message in the CLOSE_WEBTRANSPORT_SESSION capsule
+const maxCloseMessageLen = 1024
+
+// parseCapsule parses the header of the next capsule (RFC 9297, section 3.2).
+// The capsule value can be read from the returned io.Reader.
+func parseCapsule(r quicvarint.Reader) (capsuleType, io.Reader, error) {
+	t, err := quicvarint.Read(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	l, err := quicvarint.Read(r)
+	if err != nil {
+		if err == io.EOF {
+			return 0, nil, io.ErrUnexpectedEOF
+		}
+		return 0, nil, err
+	}
+	return capsuleType(t), &exactReader{R: &io.LimitedReader{R: r, N: int64(l)}}, nil
+}
+
+func writeCapsule(b *bytes.Buffer, t capsuleType, value []byte) {
+	quicvarint.Write(b, uint64(t))
+	quicvarint.Write(b, uint64(len(value)))
+	b.Write(value)
+}
+
+func parseCloseSessionCapsule(r io.Reader) (SessionErrorCode, string, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r, 4+maxCloseMessageLen+1))
+	if err != nil {
+		return 0, "", err
+	}
+	if len(data) < 4 {
+		return 0, "", errors.New("CLOSE_WEBTRANSPORT_SESSION capsule too short")
+	}
+	if len(data) > 4+maxCloseMessageLen {
+		return 0, "", fmt.Errorf("CLOSE_WEBTRANSPORT_SESSION capsule: error message too long (max: %d bytes)", maxCloseMessageLen)
+	}
+	return SessionErrorCode(binary.BigEndian.Uint32(data)), string(data[4:]), nil
+}
+
+func writeCloseSessionCapsule(b *bytes.Buffer, code SessionErrorCode, msg string) {
+	if len(msg) > maxCloseMessageLen {
+		msg = msg[:maxCloseMessageLen]
+	}
+	value := make([]byte, 4, 4+len(msg))
+	binary.BigEndian.PutUint32(value, uint32(code))
+	value = append(value, msg...)
+	writeCapsule(b, closeSessionCapsuleType, value)
+}
+
+// exactReader returns io.ErrUnexpectedEOF if the underlying reader ends before the capsule value was read completely.
+type exactReader struct {
+	R *io.LimitedReader
+}
+
+func (r *exactReader) Read(b []byte) (int, error) {
+	n, err := r.R.Read(b)
+	if err == io.EOF && r.R.N > 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// A dataFrameReader reads the payload of the HTTP/3 DATA frames sent on the CONNECT stream.
+// All other frames are skipped.
+type dataFrameReader struct {
+	r         quicvarint.Reader
+	remaining uint64 // the number of bytes remaining in the current DATA frame
+}
+
+func newDataFrameReader(r io.Reader) *dataFrameReader {
+	return &dataFrameReader{r: quicvarint.NewReader(r)}
+}
+
+func (r *dataFrameReader) Read(b []byte) (int, error) {
+	for r.remaining == 0 {
+		t, err := quicvarint.Read(r.r)
+		if err != nil {
+			return 0, err
+		}
+		l, err := quicvarint.Read(r.r)
+		if err != nil {
+			return 0, err
+		}
+		if t == 0x0 { // DATA frame
+			r.remaining = l
+			continue
+		}
+		if _, err := io.CopyN(ioutil.Discard, r.r, int64(l)); err != nil {
+			return 0, err
+		}
+	}
+	if uint64(len(b)) > r.remaining {
+		b = b[:r.remaining]
+	}
+	n, err := r.r.Read(b)
+	r.remaining -= uint64(n)
+	return n, err
+}
+
+func (r *dataFrameReader) ReadByte() (byte, error) {
+	var b [1]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// writeDataFrame writes data as a HTTP/3 DATA frame.
+func writeDataFrame(b *bytes.Buffer, data []byte) {
+	quicvarint.Write(b, 0x0)
+	quicvarint.Write(b, uint64(len(data)))
+	b.Write(data)
+}
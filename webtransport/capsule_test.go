@@ -0,0 +1,110 @@
+package webtransport
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/lucas-clemente/quic-go/quicvarint"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Capsules", func() {
+	It("writes and parses capsules", func() {
+		buf := &bytes.Buffer{}
+		writeCapsule(buf, 1337, []byte("foobar"))
+		writeCapsule(buf, 42, nil)
+		t, r, err := parseCapsule(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(t).To(Equal(capsuleType(1337)))
+		data, err := ioutil.ReadAll(r)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("foobar")))
+		t, r, err = parseCapsule(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(t).To(Equal(capsuleType(42)))
+		data, err = ioutil.ReadAll(r)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(BeEmpty())
+		_, _, err = parseCapsule(buf)
+		Expect(err).To(Equal(io.EOF))
+	})
+
+	It("errors on truncated capsules", func() {
+		buf := &bytes.Buffer{}
+		writeCapsule(buf, 1337, []byte("foobar"))
+		data := buf.Bytes()
+		_, _, err := parseCapsule(bytes.NewReader(data[:2]))
+		Expect(err).To(Equal(io.ErrUnexpectedEOF))
+		_, r, err := parseCapsule(bytes.NewReader(data[:len(data)-1]))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = ioutil.ReadAll(r)
+		Expect(err).To(Equal(io.ErrUnexpectedEOF))
+	})
+
+	Context("CLOSE_WEBTRANSPORT_SESSION", func() {
+		It("writes and parses", func() {
+			buf := &bytes.Buffer{}
+			writeCloseSessionCapsule(buf, 0xdeadbeef, "foobar")
+			t, r, err := parseCapsule(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(t).To(Equal(closeSessionCapsuleType))
+			code, msg, err := parseCloseSessionCapsule(r)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(code).To(Equal(SessionErrorCode(0xdeadbeef)))
+			Expect(msg).To(Equal("foobar"))
+		})
+
+		It("truncates long messages", func() {
+			buf := &bytes.Buffer{}
+			writeCloseSessionCapsule(buf, 1, strings.Repeat("a", maxCloseMessageLen+10))
+			_, r, err := parseCapsule(buf)
+			Expect(err).ToNot(HaveOccurred())
+			_, msg, err := parseCloseSessionCapsule(r)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(msg).To(HaveLen(maxCloseMessageLen))
+		})
+
+		It("errors if the capsule is too short", func() {
+			_, _, err := parseCloseSessionCapsule(bytes.NewReader([]byte{1, 2, 3}))
+			Expect(err).To(MatchError("CLOSE_WEBTRANSPORT_SESSION capsule too short"))
+		})
+
+		It("errors if the message is too long", func() {
+			data := append([]byte{0, 0, 0, 1}, bytes.Repeat([]byte{'a'}, maxCloseMessageLen+1)...)
+			_, _, err := parseCloseSessionCapsule(bytes.NewReader(data))
+			Expect(err).To(MatchError("CLOSE_WEBTRANSPORT_SESSION capsule: error message too long (max: 1024 bytes)"))
+		})
+	})
+
+	It("reads the payload of DATA frames, skipping other frames", func() {
+		buf := &bytes.Buffer{}
+		writeDataFrame(buf, []byte("foo"))
+		// an unknown frame
+		quicvarint.Write(buf, 0x21)
+		quicvarint.Write(buf, 3)
+		buf.Write([]byte("baz"))
+		writeDataFrame(buf, nil)
+		writeDataFrame(buf, []byte("bar"))
+		data, err := ioutil.ReadAll(newDataFrameReader(buf))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("foobar")))
+	})
+
+	It("parses capsules spanning multiple DATA frames", func() {
+		capsule := &bytes.Buffer{}
+		writeCapsule(capsule, 1337, []byte("foobar"))
+		buf := &bytes.Buffer{}
+		writeDataFrame(buf, capsule.Bytes()[:4])
+		writeDataFrame(buf, capsule.Bytes()[4:])
+		t, r, err := parseCapsule(newDataFrameReader(buf))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(t).To(Equal(capsuleType(1337)))
+		data, err := ioutil.ReadAll(r)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("foobar")))
+	})
+})
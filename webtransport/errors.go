@@ -0,0 +1,33 @@
+package webtransport
+
+import (
+	"fmt"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+// SessionErrorCode is the application error code used when closing a WebTransport session.
+type SessionErrorCode uint32
+
+// SessionError is returned by the methods of a Session after the session was closed.
+type SessionError struct {
+	Remote    bool
+	ErrorCode SessionErrorCode
+	Message   string
+}
+
+var _ error = &SessionError{}
+
+func (e *SessionError) Error() string {
+	if len(e.Message) == 0 {
+		return fmt.Sprintf("webtransport: session closed (code: %d)", e.ErrorCode)
+	}
+	return fmt.Sprintf("webtransport: session closed (code: %d): %s", e.ErrorCode, e.Message)
+}
+
+const (
+	// WEBTRANSPORT_BUFFERED_STREAM_REJECTED, used to reset streams that can't be buffered until the session is established
+	errorBufferedStreamRejected quic.StreamErrorCode = 0x3994bd84
+	// WEBTRANSPORT_SESSION_GONE, used to reset all streams associated with a session when the session is closed
+	errorSessionGone quic.StreamErrorCode = 0x170d7b68
+)
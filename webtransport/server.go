@@ -0,0 +1,210 @@
+// Package webtransport implements WebTransport over HTTP/3 (draft-ietf-webtrans-http3-02) on top of an http3.Server.
+package webtransport
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/http3"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+const (
+	// SETTINGS_ENABLE_CONNECT_PROTOCOL, see RFC 9220
+	settingExtendedConnect = 0x8
+	// SETTINGS_ENABLE_WEBTRANSPORT
+	settingEnableWebTransport = 0x2b603742
+)
+
+const (
+	// the frame type used to associate bidirectional streams with a session
+	webTransportFrameType = 0x41
+	// the stream type used to associate unidirectional streams with a session
+	webTransportUniStreamType = 0x54
+)
+
+const (
+	protocolHeader    = "webtransport"
+	draft02VersionHdr = "Sec-Webtransport-Http3-Draft02"
+)
+
+// Server is a WebTransport server.
+// WebTransport sessions are established by calling Upgrade from a http.Handler.
+// The http3.Server's StreamHijacker, UniStreamHijacker and AdditionalSettings are set by the Server,
+// and datagram support is enabled.
+type Server struct {
+	H3 http3.Server
+
+	// CheckOrigin is used to validate the Origin header of WebTransport requests.
+	// If nil, only requests without an Origin header, or with an Origin header that matches the Host, are accepted.
+	CheckOrigin func(r *http.Request) bool
+
+	initOnce sync.Once
+	sessions *sessionManager
+}
+
+func (s *Server) initialize() {
+	s.initOnce.Do(func() {
+		s.sessions = newSessionManager()
+		s.H3.EnableDatagrams = true
+		settings := make(map[uint64]uint64, len(s.H3.AdditionalSettings)+2)
+		for id, val := range s.H3.AdditionalSettings {
+			settings[id] = val
+		}
+		settings[settingExtendedConnect] = 1
+		settings[settingEnableWebTransport] = 1
+		s.H3.AdditionalSettings = settings
+		s.H3.StreamHijacker = s.hijackStream
+		s.H3.UniStreamHijacker = s.hijackUniStream
+	})
+}
+
+// Serve an existing UDP connection.
+func (s *Server) Serve(conn net.PacketConn) error {
+	s.initialize()
+	return s.H3.Serve(conn)
+}
+
+// ListenAndServe listens on the UDP address s.H3.Addr.
+func (s *Server) ListenAndServe() error {
+	s.initialize()
+	return s.H3.ListenAndServe()
+}
+
+// ListenAndServeTLS listens on the UDP address s.H3.Addr.
+func (s *Server) ListenAndServeTLS(certFile, keyFile string) error {
+	s.initialize()
+	return s.H3.ListenAndServeTLS(certFile, keyFile)
+}
+
+// Close closes the server, including all WebTransport sessions.
+func (s *Server) Close() error {
+	return s.H3.Close()
+}
+
+// Upgrade establishes a WebTransport session for an extended CONNECT request.
+// It must be called from the handler serving r.
+// If the request is not a valid WebTransport request, an error response is sent, and an error is returned.
+func (s *Server) Upgrade(w http.ResponseWriter, r *http.Request) (*Session, error) {
+	s.initialize()
+	if r.Method != http.MethodConnect {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return nil, fmt.Errorf("webtransport: expected CONNECT request, got %s", r.Method)
+	}
+	if r.Proto != protocolHeader {
+		w.WriteHeader(http.StatusBadRequest)
+		return nil, fmt.Errorf("webtransport: unexpected protocol: %s", r.Proto)
+	}
+	checkOrigin := s.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = checkSameOrigin
+	}
+	if !checkOrigin(r) {
+		w.WriteHeader(http.StatusForbidden)
+		return nil, errors.New("webtransport: request origin not allowed")
+	}
+	hijacker, ok := w.(http3.Hijacker)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, errors.New("webtransport: response writer doesn't implement http3.Hijacker")
+	}
+	w.Header().Set(draft02VersionHdr, "1")
+	w.WriteHeader(http.StatusOK)
+	datagrams := w.(http3.Datagrammer)
+	str := w.(http3.DataStreamer).DataStream()
+
+	sess := s.sessions.get(hijacker.Session(), str.StreamID())
+	sess.start(str, datagrams)
+	return sess, nil
+}
+
+func checkSameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+func (s *Server) hijackStream(ft http3.FrameType, qsess quic.Session, str quic.Stream) (bool, error) {
+	if ft != webTransportFrameType {
+		return false, nil
+	}
+	id, err := readSessionID(str)
+	if err != nil {
+		return false, err
+	}
+	s.sessions.get(qsess, id).addIncomingStream(str)
+	return true, nil
+}
+
+func (s *Server) hijackUniStream(st http3.StreamType, qsess quic.Session, str quic.ReceiveStream) bool {
+	if st != webTransportUniStreamType {
+		return false
+	}
+	id, err := readSessionID(str)
+	if err != nil {
+		return false
+	}
+	s.sessions.get(qsess, id).addIncomingUniStream(str)
+	return true
+}
+
+// readSessionID reads the session ID, which is the stream ID of the CONNECT stream.
+func readSessionID(str quic.ReceiveStream) (quic.StreamID, error) {
+	id, err := quicvarint.Read(quicvarint.NewReader(str))
+	if err != nil {
+		return 0, err
+	}
+	// CONNECT requests are sent on client-initiated bidirectional streams
+	if id%4 != 0 {
+		return 0, fmt.Errorf("webtransport: invalid session ID %d", id)
+	}
+	return quic.StreamID(id), nil
+}
+
+type sessionKey struct {
+	qsess quic.Session
+	id    quic.StreamID
+}
+
+// The sessionManager associates streams with WebTransport sessions.
+// Streams can arrive before the CONNECT request was handled, so sessions are created when they're first referenced,
+// and the streams are queued until the session is established.
+// Sessions are removed when the QUIC session is closed.
+type sessionManager struct {
+	mutex    sync.Mutex
+	sessions map[sessionKey]*Session
+}
+
+func newSessionManager() *sessionManager {
+	return &sessionManager{sessions: make(map[sessionKey]*Session)}
+}
+
+func (m *sessionManager) get(qsess quic.Session, id quic.StreamID) *Session {
+	key := sessionKey{qsess: qsess, id: id}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if sess, ok := m.sessions[key]; ok {
+		return sess
+	}
+	sess := newSession(id, qsess)
+	m.sessions[key] = sess
+	go func() {
+		<-qsess.Context().Done()
+		m.mutex.Lock()
+		delete(m.sessions, key)
+		m.mutex.Unlock()
+	}()
+	return sess
+}
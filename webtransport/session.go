@@ -0,0 +1,336 @@
+package webtransport
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/http3"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+// the number of streams that are queued for every session, before we start rejecting streams
+const maxQueuedStreams = 16
+
+// A Session is a WebTransport session.
+// All streams are opened on the QUIC session that the CONNECT request was received on,
+// multiple WebTransport sessions can share the same QUIC session.
+type Session struct {
+	sessionID quic.StreamID // the stream ID of the CONNECT stream
+	qsess     quic.Session
+
+	ctx    context.Context // canceled when the session is closed
+	cancel context.CancelFunc
+
+	// set when the CONNECT request is accepted
+	requestStr quic.Stream
+	datagrams  http3.Datagrammer
+
+	bidiAcceptQueue chan quic.Stream
+	uniAcceptQueue  chan quic.ReceiveStream
+
+	mutex          sync.Mutex
+	closeErr       error
+	sendStreams    []quic.SendStream // all streams associated with this session, they're reset when the session is closed
+	receiveStreams []quic.ReceiveStream
+}
+
+func newSession(id quic.StreamID, qsess quic.Session) *Session {
+	ctx, cancel := context.WithCancel(qsess.Context())
+	return &Session{
+		sessionID:       id,
+		qsess:           qsess,
+		ctx:             ctx,
+		cancel:          cancel,
+		bidiAcceptQueue: make(chan quic.Stream, maxQueuedStreams),
+		uniAcceptQueue:  make(chan quic.ReceiveStream, maxQueuedStreams),
+	}
+}
+
+// start is called when the CONNECT request was accepted.
+func (s *Session) start(str quic.Stream, datagrams http3.Datagrammer) {
+	s.requestStr = str
+	s.datagrams = datagrams
+	go s.handleRequestStream()
+}
+
+// handleRequestStream reads the capsules sent on the CONNECT stream, until the session is closed.
+func (s *Session) handleRequestStream() {
+	r := newDataFrameReader(s.requestStr)
+	for {
+		t, value, err := parseCapsule(r)
+		if err != nil {
+			if err == io.EOF {
+				// The peer closed the CONNECT stream without sending a CLOSE_WEBTRANSPORT_SESSION capsule.
+				err = &SessionError{Remote: true}
+			}
+			s.closeWithError(err)
+			return
+		}
+		if t != closeSessionCapsuleType {
+			// skip over unknown capsules
+			if _, err := io.Copy(ioutil.Discard, value); err != nil {
+				s.closeWithError(err)
+				return
+			}
+			continue
+		}
+		code, msg, err := parseCloseSessionCapsule(value)
+		if err != nil {
+			s.closeWithError(err)
+			return
+		}
+		s.closeWithError(&SessionError{Remote: true, ErrorCode: code, Message: msg})
+		// We're done sending as well.
+		s.requestStr.Close()
+		return
+	}
+}
+
+// addIncomingStream is called for every bidirectional stream opened by the peer for this session.
+func (s *Session) addIncomingStream(str quic.Stream) {
+	if !s.trackStream(str, str) {
+		return
+	}
+	select {
+	case s.bidiAcceptQueue <- str:
+	default:
+		str.CancelRead(errorBufferedStreamRejected)
+		str.CancelWrite(errorBufferedStreamRejected)
+	}
+}
+
+// addIncomingUniStream is called for every unidirectional stream opened by the peer for this session.
+func (s *Session) addIncomingUniStream(str quic.ReceiveStream) {
+	if !s.trackStream(nil, str) {
+		return
+	}
+	select {
+	case s.uniAcceptQueue <- str:
+	default:
+		str.CancelRead(errorBufferedStreamRejected)
+	}
+}
+
+// trackStream remembers a stream, such that it can be reset when the session is closed.
+// If the session is already closed, the stream is reset right away, and false is returned.
+func (s *Session) trackStream(sendStr quic.SendStream, recvStr quic.ReceiveStream) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closeErr != nil {
+		if sendStr != nil {
+			sendStr.CancelWrite(errorSessionGone)
+		}
+		if recvStr != nil {
+			recvStr.CancelRead(errorSessionGone)
+		}
+		return false
+	}
+	if sendStr != nil {
+		s.sendStreams = append(s.sendStreams, sendStr)
+	}
+	if recvStr != nil {
+		s.receiveStreams = append(s.receiveStreams, recvStr)
+	}
+	return true
+}
+
+// AcceptStream returns the next bidirectional stream opened by the peer, blocking until one is available.
+func (s *Session) AcceptStream(ctx context.Context) (quic.Stream, error) {
+	select {
+	case str := <-s.bidiAcceptQueue:
+		return str, nil
+	case <-s.ctx.Done():
+		return nil, s.closeError()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// AcceptUniStream returns the next unidirectional stream opened by the peer, blocking until one is available.
+func (s *Session) AcceptUniStream(ctx context.Context) (quic.ReceiveStream, error) {
+	select {
+	case str := <-s.uniAcceptQueue:
+		return str, nil
+	case <-s.ctx.Done():
+		return nil, s.closeError()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// OpenStream opens a new bidirectional stream.
+// It returns an error if the QUIC session's stream limit prevents opening the stream.
+func (s *Session) OpenStream() (quic.Stream, error) {
+	if err := s.closeError(); err != nil {
+		return nil, err
+	}
+	str, err := s.qsess.OpenStream()
+	if err != nil {
+		return nil, err
+	}
+	return s.setupStream(str)
+}
+
+// OpenStreamSync opens a new bidirectional stream.
+// It blocks until a new stream can be opened, or ctx is canceled.
+func (s *Session) OpenStreamSync(ctx context.Context) (quic.Stream, error) {
+	if err := s.closeError(); err != nil {
+		return nil, err
+	}
+	str, err := s.qsess.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.setupStream(str)
+}
+
+func (s *Session) setupStream(str quic.Stream) (quic.Stream, error) {
+	if err := s.writeStreamHeader(str, webTransportFrameType); err != nil {
+		return nil, err
+	}
+	if !s.trackStream(str, str) {
+		return nil, s.closeError()
+	}
+	return str, nil
+}
+
+// OpenUniStream opens a new unidirectional stream.
+// It returns an error if the QUIC session's stream limit prevents opening the stream.
+func (s *Session) OpenUniStream() (quic.SendStream, error) {
+	if err := s.closeError(); err != nil {
+		return nil, err
+	}
+	str, err := s.qsess.OpenUniStream()
+	if err != nil {
+		return nil, err
+	}
+	return s.setupUniStream(str)
+}
+
+// OpenUniStreamSync opens a new unidirectional stream.
+// It blocks until a new stream can be opened, or ctx is canceled.
+func (s *Session) OpenUniStreamSync(ctx context.Context) (quic.SendStream, error) {
+	if err := s.closeError(); err != nil {
+		return nil, err
+	}
+	str, err := s.qsess.OpenUniStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.setupUniStream(str)
+}
+
+func (s *Session) setupUniStream(str quic.SendStream) (quic.SendStream, error) {
+	if err := s.writeStreamHeader(str, webTransportUniStreamType); err != nil {
+		return nil, err
+	}
+	if !s.trackStream(str, nil) {
+		return nil, s.closeError()
+	}
+	return str, nil
+}
+
+// writeStreamHeader writes the frame type (for bidirectional streams) or stream type (for unidirectional streams),
+// followed by the session ID.
+func (s *Session) writeStreamHeader(str io.Writer, t uint64) error {
+	buf := &bytes.Buffer{}
+	quicvarint.Write(buf, t)
+	quicvarint.Write(buf, uint64(s.sessionID))
+	_, err := str.Write(buf.Bytes())
+	return err
+}
+
+// SendDatagram sends a datagram associated with this session.
+func (s *Session) SendDatagram(b []byte) error {
+	return s.datagrams.SendDatagram(b)
+}
+
+// ReceiveDatagram receives a datagram associated with this session.
+func (s *Session) ReceiveDatagram(ctx context.Context) ([]byte, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-s.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	data, err := s.datagrams.ReceiveDatagram(ctx)
+	if err != nil && s.ctx.Err() != nil {
+		return nil, s.closeError()
+	}
+	return data, err
+}
+
+// LocalAddr returns the local address.
+func (s *Session) LocalAddr() net.Addr {
+	return s.qsess.LocalAddr()
+}
+
+// RemoteAddr returns the address of the peer.
+func (s *Session) RemoteAddr() net.Addr {
+	return s.qsess.RemoteAddr()
+}
+
+// Context returns a context that is canceled when the session is closed.
+func (s *Session) Context() context.Context {
+	return s.ctx
+}
+
+// CloseWithError closes the session.
+// It sends a CLOSE_WEBTRANSPORT_SESSION capsule containing the error code and the message on the CONNECT stream,
+// and resets all streams associated with the session.
+// Messages longer than 1024 bytes are truncated.
+func (s *Session) CloseWithError(code SessionErrorCode, msg string) error {
+	if !s.closeWithError(&SessionError{ErrorCode: code, Message: msg}) {
+		return nil
+	}
+	capsule := &bytes.Buffer{}
+	writeCloseSessionCapsule(capsule, code, msg)
+	buf := &bytes.Buffer{}
+	writeDataFrame(buf, capsule.Bytes())
+	if _, err := s.requestStr.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	// We're not interested in anything the peer sends after the capsule.
+	s.requestStr.CancelRead(errorSessionGone)
+	return s.requestStr.Close()
+}
+
+// closeWithError closes the session.
+// It returns false if the session was already closed.
+func (s *Session) closeWithError(e error) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closeErr != nil {
+		return false
+	}
+	s.closeErr = e
+	s.cancel()
+	for _, str := range s.sendStreams {
+		str.CancelWrite(errorSessionGone)
+	}
+	for _, str := range s.receiveStreams {
+		str.CancelRead(errorSessionGone)
+	}
+	s.sendStreams = nil
+	s.receiveStreams = nil
+	return true
+}
+
+// closeError returns the error that the session was closed with, or nil if it is not closed.
+// If the underlying QUIC session was closed, the QUIC session's context error is returned.
+func (s *Session) closeError() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closeErr != nil {
+		return s.closeErr
+	}
+	return s.ctx.Err()
+}
@@ -0,0 +1,375 @@
+package webtransport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/http3"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+
+	"github.com/golang/mock/gomock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type mockResponseWriter struct {
+	*httptest.ResponseRecorder
+	sess quic.Session
+	str  quic.Stream
+
+	sentDatagrams     [][]byte
+	receivedDatagrams chan []byte
+}
+
+var (
+	_ http3.Hijacker     = &mockResponseWriter{}
+	_ http3.DataStreamer = &mockResponseWriter{}
+	_ http3.Datagrammer  = &mockResponseWriter{}
+)
+
+func (w *mockResponseWriter) Session() quic.Session   { return w.sess }
+func (w *mockResponseWriter) DataStream() quic.Stream { return w.str }
+
+func (w *mockResponseWriter) SendDatagram(b []byte) error {
+	w.sentDatagrams = append(w.sentDatagrams, b)
+	return nil
+}
+
+func (w *mockResponseWriter) ReceiveDatagram(ctx context.Context) ([]byte, error) {
+	select {
+	case b := <-w.receivedDatagrams:
+		return b, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+var _ = Describe("Session", func() {
+	var (
+		s            *Server
+		qsess        *mockquic.MockEarlySession
+		qsessCancel  context.CancelFunc
+		requestStr   *mockquic.MockStream
+		requestData  *io.PipeWriter // data sent by the client on the CONNECT stream
+		responseData *bytes.Buffer  // data sent by the server on the CONNECT stream
+		w            *mockResponseWriter
+		req          *http.Request
+	)
+
+	BeforeEach(func() {
+		s = &Server{}
+		s.initialize()
+		qsess = mockquic.NewMockEarlySession(mockCtrl)
+		var ctx context.Context
+		ctx, qsessCancel = context.WithCancel(context.Background())
+		qsess.EXPECT().Context().Return(ctx).AnyTimes()
+
+		requestStr = mockquic.NewMockStream(mockCtrl)
+		requestStr.EXPECT().StreamID().Return(quic.StreamID(4)).AnyTimes()
+		var pr *io.PipeReader
+		pr, requestData = io.Pipe()
+		requestStr.EXPECT().Read(gomock.Any()).DoAndReturn(pr.Read).AnyTimes()
+		responseData = &bytes.Buffer{}
+		requestStr.EXPECT().Write(gomock.Any()).DoAndReturn(responseData.Write).AnyTimes()
+
+		w = &mockResponseWriter{
+			ResponseRecorder:  httptest.NewRecorder(),
+			sess:              qsess,
+			str:               requestStr,
+			receivedDatagrams: make(chan []byte, 10),
+		}
+		var err error
+		req, err = http.NewRequest(http.MethodConnect, "https://www.example.com/wt", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Proto = protocolHeader
+	})
+
+	AfterEach(func() {
+		qsessCancel()
+		requestData.Close()
+	})
+
+	upgrade := func() *Session {
+		sess, err := s.Upgrade(w, req)
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		return sess
+	}
+
+	sendToServer := func(data []byte) {
+		buf := &bytes.Buffer{}
+		writeDataFrame(buf, data)
+		go requestData.Write(buf.Bytes())
+	}
+
+	// newStream creates a stream that is reset when the session is closed at the end of the test
+	newStream := func() *mockquic.MockStream {
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().CancelRead(errorSessionGone).AnyTimes()
+		str.EXPECT().CancelWrite(errorSessionGone).AnyTimes()
+		return str
+	}
+
+	streamHeader := func(t uint64, id quic.StreamID) []byte {
+		buf := &bytes.Buffer{}
+		quicvarint.Write(buf, t)
+		quicvarint.Write(buf, uint64(id))
+		return buf.Bytes()
+	}
+
+	It("accepts the CONNECT request", func() {
+		upgrade()
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get(draft02VersionHdr)).To(Equal("1"))
+	})
+
+	It("opens bidirectional streams", func() {
+		sess := upgrade()
+		str := newStream()
+		qsess.EXPECT().OpenStream().Return(str, nil)
+		str.EXPECT().Write(streamHeader(webTransportFrameType, 4))
+		s, err := sess.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s).To(Equal(str))
+
+		qsess.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+		str.EXPECT().Write(streamHeader(webTransportFrameType, 4))
+		s, err = sess.OpenStreamSync(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s).To(Equal(str))
+	})
+
+	It("opens unidirectional streams", func() {
+		sess := upgrade()
+		str := newStream()
+		qsess.EXPECT().OpenUniStream().Return(str, nil)
+		str.EXPECT().Write(streamHeader(webTransportUniStreamType, 4))
+		s, err := sess.OpenUniStream()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s).To(Equal(str))
+
+		qsess.EXPECT().OpenUniStreamSync(gomock.Any()).Return(str, nil)
+		str.EXPECT().Write(streamHeader(webTransportUniStreamType, 4))
+		s, err = sess.OpenUniStreamSync(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s).To(Equal(str))
+	})
+
+	It("returns errors when opening streams fails", func() {
+		sess := upgrade()
+		testErr := errors.New("too many streams")
+		qsess.EXPECT().OpenStream().Return(nil, testErr)
+		_, err := sess.OpenStream()
+		Expect(err).To(MatchError(testErr))
+		qsess.EXPECT().OpenUniStream().Return(nil, testErr)
+		_, err = sess.OpenUniStream()
+		Expect(err).To(MatchError(testErr))
+	})
+
+	It("accepts streams, including streams that arrived before the session was established", func() {
+		bidiStr := newStream()
+		bidiStr.EXPECT().Read(gomock.Any()).DoAndReturn(bytes.NewReader(streamHeader(4, 4)[1:]).Read).AnyTimes()
+		hijacked, err := s.hijackStream(webTransportFrameType, qsess, bidiStr)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hijacked).To(BeTrue())
+
+		sess := upgrade()
+		str, err := sess.AcceptStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str).To(Equal(bidiStr))
+
+		uniStr := newStream()
+		uniStr.EXPECT().Read(gomock.Any()).DoAndReturn(bytes.NewReader(streamHeader(4, 4)[1:]).Read).AnyTimes()
+		Expect(s.hijackUniStream(webTransportUniStreamType, qsess, uniStr)).To(BeTrue())
+		rstr, err := sess.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rstr).To(Equal(uniStr))
+	})
+
+	It("rejects streams if the queue is full", func() {
+		sess := upgrade()
+		for i := 0; i < maxQueuedStreams; i++ {
+			sess.addIncomingUniStream(newStream())
+		}
+		str := newStream()
+		str.EXPECT().CancelRead(errorBufferedStreamRejected)
+		sess.addIncomingUniStream(str)
+	})
+
+	It("doesn't hijack streams of other types", func() {
+		hijacked, err := s.hijackStream(0x1337, qsess, mockquic.NewMockStream(mockCtrl))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hijacked).To(BeFalse())
+		Expect(s.hijackUniStream(0x1337, qsess, mockquic.NewMockStream(mockCtrl))).To(BeFalse())
+	})
+
+	It("errors on invalid session IDs", func() {
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().Read(gomock.Any()).DoAndReturn(bytes.NewReader([]byte{5}).Read).AnyTimes()
+		_, err := s.hijackStream(webTransportFrameType, qsess, str)
+		Expect(err).To(MatchError("webtransport: invalid session ID 5"))
+	})
+
+	It("sends and receives datagrams", func() {
+		sess := upgrade()
+		Expect(sess.SendDatagram([]byte("foobar"))).To(Succeed())
+		Expect(w.sentDatagrams).To(Equal([][]byte{[]byte("foobar")}))
+		w.receivedDatagrams <- []byte("raboof")
+		data, err := sess.ReceiveDatagram(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("raboof")))
+	})
+
+	Context("closing", func() {
+		It("closes the session, sending a CLOSE_WEBTRANSPORT_SESSION capsule", func() {
+			sess := upgrade()
+			str := mockquic.NewMockStream(mockCtrl)
+			qsess.EXPECT().OpenStream().Return(str, nil)
+			str.EXPECT().Write(gomock.Any())
+			_, err := sess.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+
+			responseData.Reset()
+			str.EXPECT().CancelRead(errorSessionGone)
+			str.EXPECT().CancelWrite(errorSessionGone)
+			requestStr.EXPECT().CancelRead(errorSessionGone).Do(func(quic.StreamErrorCode) { requestData.CloseWithError(errors.New("canceled")) })
+			requestStr.EXPECT().Close()
+			Expect(sess.CloseWithError(1337, "foobar")).To(Succeed())
+			Eventually(sess.Context().Done()).Should(BeClosed())
+
+			t, r, err := parseCapsule(newDataFrameReader(responseData))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(t).To(Equal(closeSessionCapsuleType))
+			code, msg, err := parseCloseSessionCapsule(r)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(code).To(BeEquivalentTo(1337))
+			Expect(msg).To(Equal("foobar"))
+
+			_, err = sess.AcceptStream(context.Background())
+			Expect(err).To(MatchError(&SessionError{ErrorCode: 1337, Message: "foobar"}))
+			_, err = sess.OpenStream()
+			Expect(err).To(MatchError(&SessionError{ErrorCode: 1337, Message: "foobar"}))
+			// closing a second time is a no-op
+			Expect(sess.CloseWithError(42, "")).To(Succeed())
+		})
+
+		It("handles a CLOSE_WEBTRANSPORT_SESSION capsule sent by the peer", func() {
+			sess := upgrade()
+			closed := make(chan struct{})
+			requestStr.EXPECT().Close().Do(func() { close(closed) })
+			buf := &bytes.Buffer{}
+			writeCapsule(buf, 0x1337, []byte("unknown capsule"))
+			writeCloseSessionCapsule(buf, 42, "bye")
+			sendToServer(buf.Bytes())
+			_, err := sess.AcceptUniStream(context.Background())
+			Expect(err).To(MatchError(&SessionError{Remote: true, ErrorCode: 42, Message: "bye"}))
+			Eventually(closed).Should(BeClosed())
+		})
+
+		It("closes the session when the peer closes the CONNECT stream", func() {
+			sess := upgrade()
+			requestData.Close()
+			_, err := sess.AcceptStream(context.Background())
+			Expect(err).To(MatchError(&SessionError{Remote: true}))
+		})
+
+		It("resets streams that arrive after the session was closed", func() {
+			sess := upgrade()
+			requestData.Close()
+			Eventually(sess.Context().Done()).Should(BeClosed())
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().CancelRead(errorSessionGone)
+			str.EXPECT().CancelWrite(errorSessionGone)
+			sess.addIncomingStream(str)
+		})
+
+		It("is closed when the QUIC session is closed", func() {
+			sess := upgrade()
+			qsessCancel()
+			_, err := sess.AcceptStream(context.Background())
+			Expect(err).To(MatchError(context.Canceled))
+		})
+	})
+})
+
+var _ = Describe("Upgrading", func() {
+	var s *Server
+
+	BeforeEach(func() {
+		s = &Server{}
+	})
+
+	It("sets the HTTP/3 settings", func() {
+		s.H3.AdditionalSettings = map[uint64]uint64{1337: 42}
+		s.initialize()
+		Expect(s.H3.EnableDatagrams).To(BeTrue())
+		Expect(s.H3.AdditionalSettings).To(Equal(map[uint64]uint64{
+			1337:                      42,
+			settingExtendedConnect:    1,
+			settingEnableWebTransport: 1,
+		}))
+		Expect(s.H3.StreamHijacker).ToNot(BeNil())
+		Expect(s.H3.UniStreamHijacker).ToNot(BeNil())
+	})
+
+	It("rejects requests that are not CONNECT requests", func() {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "https://www.example.com/wt", nil)
+		_, err := s.Upgrade(w, req)
+		Expect(err).To(MatchError("webtransport: expected CONNECT request, got GET"))
+		Expect(w.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	It("rejects requests for other protocols", func() {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodConnect, "https://www.example.com/wt", nil)
+		req.Proto = "websocket"
+		_, err := s.Upgrade(w, req)
+		Expect(err).To(MatchError("webtransport: unexpected protocol: websocket"))
+		Expect(w.Code).To(Equal(http.StatusBadRequest))
+	})
+
+	Context("checking the origin", func() {
+		var req *http.Request
+
+		BeforeEach(func() {
+			var err error
+			req, err = http.NewRequest(http.MethodConnect, "https://www.example.com/wt", nil)
+			Expect(err).ToNot(HaveOccurred())
+			req.Proto = protocolHeader
+		})
+
+		It("rejects requests from other origins", func() {
+			req.Header.Set("Origin", "https://evil.com")
+			w := httptest.NewRecorder()
+			_, err := s.Upgrade(w, req)
+			Expect(err).To(MatchError("webtransport: request origin not allowed"))
+			Expect(w.Code).To(Equal(http.StatusForbidden))
+		})
+
+		It("accepts requests from the same origin", func() {
+			req.Header.Set("Origin", "https://www.example.com")
+			Expect(checkSameOrigin(req)).To(BeTrue())
+			req.Header.Del("Origin")
+			Expect(checkSameOrigin(req)).To(BeTrue())
+		})
+
+		It("uses the custom origin check", func() {
+			req.Header.Set("Origin", "https://evil.com")
+			var checked *http.Request
+			s.CheckOrigin = func(r *http.Request) bool {
+				checked = r
+				return false
+			}
+			_, err := s.Upgrade(httptest.NewRecorder(), req)
+			Expect(err).To(MatchError("webtransport: request origin not allowed"))
+			Expect(checked).To(Equal(req))
+		})
+	})
+})
@@ -0,0 +1,25 @@
+package webtransport
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestWebTransport(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "WebTransport Suite")
+}
+
+var mockCtrl *gomock.Controller
+
+var _ = BeforeEach(func() {
+	mockCtrl = gomock.NewController(GinkgoT())
+})
+
+var _ = AfterEach(func() {
+	mockCtrl.Finish()
+})